@@ -1,7 +1,40 @@
 package config
 
+import "path/filepath"
+
 // 实现 EndpointConfig 接口，用于统一验证
 func (e EndpointConfig) GetName() string     { return e.Name }
 func (e EndpointConfig) GetURL() string      { return e.URL }
 func (e EndpointConfig) GetAuthType() string { return e.AuthType }
-func (e EndpointConfig) GetAuthValue() string { return e.AuthValue }
\ No newline at end of file
+func (e EndpointConfig) GetAuthValue() string {
+	if credentials := e.Credentials(); len(credentials) > 0 {
+		return credentials[0]
+	}
+	return ""
+}
+
+// Credentials 返回端点的所有凭据：auth_value 在前，然后是 auth_values，去除空值和重复值
+func (e EndpointConfig) Credentials() []string {
+	seen := make(map[string]bool)
+	var credentials []string
+	for _, value := range append([]string{e.AuthValue}, e.AuthValues...) {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		credentials = append(credentials, value)
+	}
+	return credentials
+}
+// FindModelPrice 返回第一个匹配模型名的价格，没有匹配时返回nil
+func (p PricingConfig) FindModelPrice(model string) *ModelPriceConfig {
+	if model == "" {
+		return nil
+	}
+	for i, price := range p.Models {
+		if matched, err := filepath.Match(price.Model, model); err == nil && matched {
+			return &p.Models[i]
+		}
+	}
+	return nil
+}
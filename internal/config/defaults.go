@@ -47,6 +47,7 @@ type DefaultValues struct {
 		LogResponseBody  string
 		LogDirectory     string
 		BodyTruncateSize int
+		FullTextSearch   bool
 	}
 
 	// 端点配置默认值
@@ -117,6 +118,138 @@ type DefaultValues struct {
 		Enabled       bool
 		RequiredToken string
 	}
+
+	// 本地 count_tokens 估算默认值
+	TokenCount struct {
+		Estimator           string
+		CalibrationSamples  int
+		CalibrationInterval string
+	}
+
+	// 对冲请求默认值
+	Hedging struct {
+		Enabled bool
+		Delay   string
+	}
+
+	// 断流续传默认值
+	StreamResume struct {
+		Enabled     bool
+		MaxAttempts int
+	}
+
+	// 重试策略默认值
+	RetryPolicy struct {
+		MaxAttempts       int
+		InitialBackoff    string
+		MaxBackoff        string
+		BackoffMultiplier float64
+		Jitter            float64
+		RespectRetryAfter bool
+		MaxRetryAfter     string
+	}
+
+	// 负载均衡默认值
+	LoadBalancing struct {
+		Strategy       string
+		EWMAAlpha      float64
+		FailurePenalty string
+		Weight         int
+	}
+
+	// 会话粘性路由默认值
+	SessionAffinity struct {
+		Enabled bool
+		TTL     string
+	}
+
+	// 上游速率限制响应头跟踪默认值
+	RateLimitTracking struct {
+		SkipNearExhaustion bool
+		MinRemainingRatio  float64
+	}
+
+	// 模型发现默认值
+	ModelDiscovery struct {
+		RefreshInterval string
+		RetryInterval   time.Duration
+		CheckInterval   time.Duration
+		MaxPages        int
+	}
+
+	// 熔断器默认值
+	CircuitBreaker struct {
+		Enabled           bool
+		OpenDuration      string
+		MaxOpenDuration   string
+		Multiplier        float64
+		HalfOpenRatio     float64
+		HalfOpenSuccesses int
+		HistorySize       int
+	}
+
+	// 端点健康检查默认值
+	EndpointHealthCheck struct {
+		Probe       string
+		Prompt      string
+		HistorySize int
+	}
+
+	// 端点事件时间线默认值
+	EndpointEvents struct {
+		RetentionDays   int
+		QueueSize       int
+		CleanupInterval time.Duration
+	}
+
+	// 用量分析汇总默认值
+	Analytics struct {
+		FlushInterval       string
+		HourlyRetentionDays int
+		DailyRetentionDays  int
+		CleanupInterval     time.Duration
+	}
+
+	// Prometheus 指标导出默认值
+	Metrics struct {
+		Path string
+	}
+
+	// OpenTelemetry 链路追踪默认值
+	Tracing struct {
+		Exporter    string
+		Endpoint    string
+		FilePath    string
+		SampleRatio float64
+		ServiceName string
+	}
+
+	// OAuth 登录与后台刷新默认值
+	OAuth struct {
+		FlowTimeout          time.Duration
+		RefreshCheckInterval time.Duration
+		RefreshAhead         time.Duration
+		RefreshRetryInterval time.Duration
+		CallbackPath         string
+	}
+
+	// 多凭据轮换默认值
+	KeyRotation struct {
+		Strategy          string
+		Cooldown          string
+		RotateStatusCodes []int
+	}
+
+	// 端点并发与速率限制默认值
+	EndpointLimits struct {
+		QueueTimeout string
+	}
+
+	// 端点预算默认值
+	Budget struct {
+		CacheReadPriceRatio  float64
+		CacheWritePriceRatio float64
+	}
 }
 
 // Default 全局默认值实例
@@ -199,6 +332,7 @@ var Default = DefaultValues{
 		LogResponseBody  string
 		LogDirectory     string
 		BodyTruncateSize int
+		FullTextSearch   bool
 	}{
 		Level:            "info",
 		LogRequestTypes:  "all",
@@ -206,6 +340,7 @@ var Default = DefaultValues{
 		LogResponseBody:  "none",
 		LogDirectory:     "./logs",
 		BodyTruncateSize: 1000,
+		FullTextSearch:   false, // 默认关闭，全文索引会增加每次写日志的开销和数据库体积
 	},
 
 	Endpoint: struct {
@@ -313,6 +448,198 @@ var Default = DefaultValues{
 		Enabled:       true, // 默认启用客户端认证
 		RequiredToken: "",   // 默认无令牌，需要生成
 	},
+
+	TokenCount: struct {
+		Estimator           string
+		CalibrationSamples  int
+		CalibrationInterval string
+	}{
+		Estimator:           "heuristic",
+		CalibrationSamples:  1000,
+		CalibrationInterval: "1h",
+	},
+
+	Hedging: struct {
+		Enabled bool
+		Delay   string
+	}{
+		Enabled: false, // 默认关闭，对冲会增加上游请求量
+		Delay:   "3s",
+	},
+
+	StreamResume: struct {
+		Enabled     bool
+		MaxAttempts int
+	}{
+		Enabled:     false,
+		MaxAttempts: 2,
+	},
+
+	RetryPolicy: struct {
+		MaxAttempts       int
+		InitialBackoff    string
+		MaxBackoff        string
+		BackoffMultiplier float64
+		Jitter            float64
+		RespectRetryAfter bool
+		MaxRetryAfter     string
+	}{
+		MaxAttempts:       2,
+		InitialBackoff:    "0s", // 默认与之前一样立即重试，配置 initial_backoff 后才启用退避
+		MaxBackoff:        "10s",
+		BackoffMultiplier: 2.0,
+		Jitter:            0.2,
+		RespectRetryAfter: true,
+		MaxRetryAfter:     "30s",
+	},
+
+	LoadBalancing: struct {
+		Strategy       string
+		EWMAAlpha      float64
+		FailurePenalty string
+		Weight         int
+	}{
+		Strategy:       "priority", // 默认保持严格优先级
+		EWMAAlpha:      0.3,
+		FailurePenalty: "10s",
+		Weight:         1,
+	},
+
+	SessionAffinity: struct {
+		Enabled bool
+		TTL     string
+	}{
+		Enabled: false,
+		TTL:     "1h", // 与 Anthropic 扩展提示词缓存的最长时间一致
+	},
+
+	RateLimitTracking: struct {
+		SkipNearExhaustion bool
+		MinRemainingRatio  float64
+	}{
+		SkipNearExhaustion: false,
+		MinRemainingRatio:  0.05,
+	},
+
+	ModelDiscovery: struct {
+		RefreshInterval string
+		RetryInterval   time.Duration
+		CheckInterval   time.Duration
+		MaxPages        int
+	}{
+		RefreshInterval: "1h",
+		RetryInterval:   5 * time.Minute, // 获取失败的端点的重试间隔
+		CheckInterval:   time.Minute,     // 检查哪些端点的模型列表需要刷新
+		MaxPages:        10,              // 分页的模型列表最多读取的页数
+	},
+
+	CircuitBreaker: struct {
+		Enabled           bool
+		OpenDuration      string
+		MaxOpenDuration   string
+		Multiplier        float64
+		HalfOpenRatio     float64
+		HalfOpenSuccesses int
+		HistorySize       int
+	}{
+		Enabled:           false,
+		OpenDuration:      "30s",
+		MaxOpenDuration:   "10m",
+		Multiplier:        2.0,
+		HalfOpenRatio:     0.1,
+		HalfOpenSuccesses: 2,
+		HistorySize:       50,
+	},
+
+	EndpointHealthCheck: struct {
+		Probe       string
+		Prompt      string
+		HistorySize int
+	}{
+		Probe:       "messages",
+		Prompt:      "hello",
+		HistorySize: 20, // 每个端点保留的健康检查结果数
+	},
+
+	EndpointEvents: struct {
+		RetentionDays   int
+		QueueSize       int
+		CleanupInterval time.Duration
+	}{
+		RetentionDays:   30,   // 与请求日志相同的保留天数
+		QueueSize:       1000, // 写入 statistics.db 前的异步队列长度
+		CleanupInterval: 24 * time.Hour,
+	},
+
+	Analytics: struct {
+		FlushInterval       string
+		HourlyRetentionDays int
+		DailyRetentionDays  int
+		CleanupInterval     time.Duration
+	}{
+		FlushInterval:       "1m",
+		HourlyRetentionDays: 14,  // 小时粒度用于最近两周的图表
+		DailyRetentionDays:  400, // 天粒度保留一年以上，便于同比
+		CleanupInterval:     24 * time.Hour,
+	},
+
+	Metrics: struct {
+		Path string
+	}{
+		Path: "/metrics",
+	},
+
+	Tracing: struct {
+		Exporter    string
+		Endpoint    string
+		FilePath    string
+		SampleRatio float64
+		ServiceName string
+	}{
+		Exporter:    "otlp",
+		Endpoint:    "http://localhost:4318/v1/traces", // 本地 OpenTelemetry Collector 的 OTLP/HTTP 端口
+		FilePath:    "logs/traces.jsonl",
+		SampleRatio: 1.0,
+		ServiceName: "claude-code-companion",
+	},
+
+	OAuth: struct {
+		FlowTimeout          time.Duration
+		RefreshCheckInterval time.Duration
+		RefreshAhead         time.Duration
+		RefreshRetryInterval time.Duration
+		CallbackPath         string
+	}{
+		FlowTimeout:          10 * time.Minute, // 发起授权后等待回调的最长时间
+		RefreshCheckInterval: time.Minute,
+		RefreshAhead:         10 * time.Minute, // 早于请求时的5分钟刷新窗口，通常由后台完成刷新
+		RefreshRetryInterval: 5 * time.Minute,  // 后台刷新失败后的重试间隔
+		CallbackPath:         "/admin/oauth/callback",
+	},
+
+	KeyRotation: struct {
+		Strategy          string
+		Cooldown          string
+		RotateStatusCodes []int
+	}{
+		Strategy:          "round_robin",
+		Cooldown:          "5m",
+		RotateStatusCodes: []int{401, 429}, // 凭据无效或超出配额：立即拉黑该凭据并换下一个凭据重试
+	},
+
+	EndpointLimits: struct {
+		QueueTimeout string
+	}{
+		QueueTimeout: "2s",
+	},
+
+	Budget: struct {
+		CacheReadPriceRatio  float64
+		CacheWritePriceRatio float64
+	}{
+		CacheReadPriceRatio:  0.1,  // 与 Anthropic 缓存读取定价一致
+		CacheWritePriceRatio: 1.25, // 与 Anthropic 5分钟缓存写入定价一致
+	},
 }
 
 // GetTimeoutDuration 获取超时配置的Duration值，如果配置为空则返回默认值
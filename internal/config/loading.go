@@ -46,6 +46,7 @@ func LoadConfig(filename string) (*Config, error) {
 
 // generateDefaultConfig 生成默认配置文件
 func generateDefaultConfig(filename string) error {
+	jitter := Default.RetryPolicy.Jitter
 	defaultConfig := &Config{
 		Server: ServerConfig{
 			Host: "127.0.0.1",
@@ -98,6 +99,7 @@ func generateDefaultConfig(filename string) error {
 			LogRequestBody:  "truncated",
 			LogResponseBody: "truncated",
 			LogDirectory:    "./logs",
+			FullTextSearch:  Default.Logging.FullTextSearch,
 		},
 		Validation: ValidationConfig{},
 		Tagging: TaggingConfig{
@@ -121,6 +123,33 @@ func generateDefaultConfig(filename string) error {
 			Enabled:       Default.ClientAuth.Enabled,
 			RequiredToken: Default.ClientAuth.RequiredToken,
 		},
+		TokenCount: TokenCountConfig{
+			Estimator: Default.TokenCount.Estimator,
+		},
+		Hedging: HedgingConfig{
+			Enabled: Default.Hedging.Enabled,
+			Delay:   Default.Hedging.Delay,
+		},
+		StreamResume: StreamResumeConfig{
+			Enabled:     Default.StreamResume.Enabled,
+			MaxAttempts: Default.StreamResume.MaxAttempts,
+		},
+		RetryPolicy: RetryPolicyConfig{
+			MaxAttempts:       Default.RetryPolicy.MaxAttempts,
+			InitialBackoff:    Default.RetryPolicy.InitialBackoff,
+			MaxBackoff:        Default.RetryPolicy.MaxBackoff,
+			BackoffMultiplier: Default.RetryPolicy.BackoffMultiplier,
+			Jitter:            &jitter,
+			MaxRetryAfter:     Default.RetryPolicy.MaxRetryAfter,
+		},
+		LoadBalancing: LoadBalancingConfig{
+			Strategy:  Default.LoadBalancing.Strategy,
+			EWMAAlpha: Default.LoadBalancing.EWMAAlpha,
+		},
+		SessionAffinity: SessionAffinityConfig{
+			Enabled: Default.SessionAffinity.Enabled,
+			TTL:     Default.SessionAffinity.TTL,
+		},
 	}
 
 	// 序列化为YAML
@@ -1,15 +1,28 @@
 package config
 
 type Config struct {
-	Server     ServerConfig     `yaml:"server"`
-	Endpoints  []EndpointConfig `yaml:"endpoints"`
-	Logging    LoggingConfig    `yaml:"logging"`
-	Validation ValidationConfig `yaml:"validation"`
-	Tagging    TaggingConfig    `yaml:"tagging"`     // 标签系统配置（永远启用）
-	Timeouts   TimeoutConfig    `yaml:"timeouts"`    // 超时配置
-	I18n       I18nConfig       `yaml:"i18n"`        // 国际化配置
-	Auth       AuthConfig       `yaml:"auth"`        // 身份验证配置
-	ClientAuth ClientAuthConfig `yaml:"client_auth"` // 客户端认证配置
+	Server            ServerConfig            `yaml:"server"`
+	Endpoints         []EndpointConfig        `yaml:"endpoints"`
+	Logging           LoggingConfig           `yaml:"logging"`
+	Validation        ValidationConfig        `yaml:"validation"`
+	Tagging           TaggingConfig           `yaml:"tagging"`             // 标签系统配置（永远启用）
+	Timeouts          TimeoutConfig           `yaml:"timeouts"`            // 超时配置
+	I18n              I18nConfig              `yaml:"i18n"`                // 国际化配置
+	Auth              AuthConfig              `yaml:"auth"`                // 身份验证配置
+	ClientAuth        ClientAuthConfig        `yaml:"client_auth"`         // 客户端认证配置
+	TokenCount        TokenCountConfig        `yaml:"token_count"`         // 本地 count_tokens 估算配置
+	Hedging           HedgingConfig           `yaml:"hedging"`             // 对冲请求配置
+	StreamResume      StreamResumeConfig      `yaml:"stream_resume"`       // 流式响应断流续传配置
+	RetryPolicy       RetryPolicyConfig       `yaml:"retry_policy"`        // 全局重试策略
+	LoadBalancing     LoadBalancingConfig     `yaml:"load_balancing"`      // 负载均衡策略配置
+	SessionAffinity   SessionAffinityConfig   `yaml:"session_affinity"`    // 会话粘性路由配置
+	RateLimitTracking RateLimitTrackingConfig `yaml:"rate_limit_tracking"` // 上游速率限制响应头跟踪配置
+	CircuitBreaker    CircuitBreakerConfig    `yaml:"circuit_breaker"`     // 端点熔断器配置
+	ModelDiscovery    ModelDiscoveryConfig    `yaml:"model_discovery"`     // 上游模型发现配置
+	Pricing           PricingConfig           `yaml:"pricing"`             // 请求费用计算的模型价格表
+	Analytics         AnalyticsConfig         `yaml:"analytics"`           // 用量分析汇总配置
+	Metrics           MetricsConfig           `yaml:"metrics"`             // Prometheus 指标导出配置
+	Tracing           TracingConfig           `yaml:"tracing"`             // OpenTelemetry 链路追踪配置
 }
 
 // I18nConfig 国际化配置
@@ -27,10 +40,12 @@ type ServerConfig struct {
 type EndpointConfig struct {
 	Name               string              `yaml:"name"`
 	URL                string              `yaml:"url"`
-	EndpointType       string              `yaml:"endpoint_type"`         // "anthropic" | "openai" 等
+	EndpointType       string              `yaml:"endpoint_type"`         // "anthropic" | "openai" | "openai_responses" | "gemini" 等
 	PathPrefix         string              `yaml:"path_prefix,omitempty"` // OpenAI端点的路径前缀，如 "/v1/chat/completions"
 	AuthType           string              `yaml:"auth_type"`
 	AuthValue          string              `yaml:"auth_value"`
+	AuthValues         []string            `yaml:"auth_values,omitempty" json:"auth_values,omitempty"`               // 同一 URL 的多个凭据，与 auth_value 合并后按 key_rotation 轮换
+	KeyRotation        *KeyRotationConfig  `yaml:"key_rotation,omitempty" json:"key_rotation,omitempty"`             // 多凭据的轮换策略
 	Enabled            bool                `yaml:"enabled"`
 	Priority           int                 `yaml:"priority"`
 	Tags               []string            `yaml:"tags"`                                                               // 新增：支持的tag列表
@@ -43,6 +58,31 @@ type EndpointConfig struct {
 	RateLimitReset     *int64              `yaml:"rate_limit_reset,omitempty" json:"rate_limit_reset,omitempty"`       // Anthropic-Ratelimit-Unified-Reset
 	RateLimitStatus    *string             `yaml:"rate_limit_status,omitempty" json:"rate_limit_status,omitempty"`     // Anthropic-Ratelimit-Unified-Status
 	EnhancedProtection bool                `yaml:"enhanced_protection,omitempty" json:"enhanced_protection,omitempty"` // 官方帐号增强保护：allowed_warning时即禁用端点
+	RetryPolicy        *RetryPolicyConfig  `yaml:"retry_policy,omitempty" json:"retry_policy,omitempty"`               // 端点级重试策略，覆盖全局 retry_policy
+	Weight             int                 `yaml:"weight,omitempty" json:"weight,omitempty"`                           // weighted 负载均衡策略中的权重，默认1
+	Budget             *BudgetConfig       `yaml:"budget,omitempty" json:"budget,omitempty"`                           // 端点预算，达到后端点被拉黑直到窗口重置
+	Limits             *EndpointLimitsConfig `yaml:"limits,omitempty" json:"limits,omitempty"`                         // 客户端并发与速率限制
+	HealthCheck        *EndpointHealthCheckConfig `yaml:"health_check,omitempty" json:"health_check,omitempty"`           // 端点健康检查探测方式，未配置时使用默认的 messages 探测
+	RateLimitCapacity  *RateLimitCapacityConfig `yaml:"rate_limit_capacity,omitempty" json:"rate_limit_capacity,omitempty"` // 通用速率限制响应头解析出的耗尽状态，由代理自动维护
+}
+
+// EndpointHealthCheckConfig 端点健康检查配置，决定端点不可用后如何主动探测恢复
+type EndpointHealthCheckConfig struct {
+	Disabled       bool   `yaml:"disabled,omitempty" json:"disabled,omitempty"`               // 关闭主动探测（按量计费帐号），端点在一个 check_interval 后直接恢复，由实际请求检验
+	Probe          string `yaml:"probe,omitempty" json:"probe,omitempty"`                     // messages | models，默认 messages；models 发送 GET /v1/models，不消耗token
+	Model          string `yaml:"model,omitempty" json:"model,omitempty"`                     // messages 探测使用的模型，默认使用从实际请求中提取的模型
+	Prompt         string `yaml:"prompt,omitempty" json:"prompt,omitempty"`                   // messages 探测的用户消息，默认 "hello"
+	SystemPrompt   string `yaml:"system_prompt,omitempty" json:"system_prompt,omitempty"`     // messages 探测的系统提示词，设置了 prompt 时默认不带系统提示词
+	MaxTokens      int    `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`           // messages 探测的 max_tokens
+	ExpectContains string `yaml:"expect_contains,omitempty" json:"expect_contains,omitempty"` // 响应中必须包含的文本，否则视为不健康
+	LatencySLO     string `yaml:"latency_slo,omitempty" json:"latency_slo,omitempty"`         // 探测耗时超过该值视为不健康，如 "10s"
+}
+
+// KeyRotationConfig 多个凭据的轮换策略
+type KeyRotationConfig struct {
+	Strategy          string `yaml:"strategy,omitempty" json:"strategy,omitempty"`                       // round_robin（每个请求轮换）| failover（使用第一个可用的凭据），默认 round_robin
+	Cooldown          string `yaml:"cooldown,omitempty" json:"cooldown,omitempty"`                       // 被拉黑的凭据多久后重新尝试，默认 5m；429 响应的 Retry-After 更长时以其为准
+	RotateStatusCodes []int  `yaml:"rotate_status_codes,omitempty" json:"rotate_status_codes,omitempty"` // 立即拉黑凭据并换下一个凭据重试的状态码，默认 [401, 429]
 }
 
 // 新增：代理配置结构
@@ -62,6 +102,8 @@ type OAuthConfig struct {
 	ClientID     string   `yaml:"client_id,omitempty" json:"client_id,omitempty"` // 客户端ID
 	Scopes       []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`       // 权限范围
 	AutoRefresh  bool     `yaml:"auto_refresh" json:"auto_refresh"`               // 是否自动刷新
+	AuthorizeURL string   `yaml:"authorize_url,omitempty" json:"authorize_url,omitempty"` // 授权页面URL，设置后可在管理界面通过 PKCE 登录获取token
+	RedirectURI  string   `yaml:"redirect_uri,omitempty" json:"redirect_uri,omitempty"`   // 授权回调地址，默认为管理界面的 /admin/oauth/callback
 }
 
 // 新增：模型重写配置结构
@@ -82,6 +124,7 @@ type LoggingConfig struct {
 	LogRequestBody  string `yaml:"log_request_body"`
 	LogResponseBody string `yaml:"log_response_body"`
 	LogDirectory    string `yaml:"log_directory"`
+	FullTextSearch  bool   `yaml:"full_text_search"` // 为请求体/响应体建立全文索引，默认关闭
 }
 
 type ValidationConfig struct {
@@ -172,6 +215,204 @@ type AuthConfig struct {
 	SessionTimeout string `yaml:"session_timeout"` // 会话超时时间，如 "24h"
 }
 
+// TokenCountConfig 本地 count_tokens 估算配置
+// 没有 Anthropic 端点能处理 /v1/messages/count_tokens 时，由代理在本地估算 token 数
+type TokenCountConfig struct {
+	Estimator           string `yaml:"estimator" json:"estimator"`                                           // "heuristic" | "bpe" | "calibrated" | "disabled"，默认 heuristic
+	BPEVocabFile        string `yaml:"bpe_vocab_file,omitempty" json:"bpe_vocab_file,omitempty"`             // tiktoken 格式的 BPE 词表文件（bpe 必填，calibrated 可选）
+	CalibrationSamples  int    `yaml:"calibration_samples,omitempty" json:"calibration_samples,omitempty"`   // calibrated：学习比例时读取的最近日志条数
+	CalibrationInterval string `yaml:"calibration_interval,omitempty" json:"calibration_interval,omitempty"` // calibrated：重新学习比例的间隔
+}
+
+// HedgingConfig 对冲请求配置
+// 当前端点在 delay 内没有返回响应头（流式请求为第一个SSE事件）时，同时向下一个可用端点发送同一请求，
+// 先返回有效响应的端点获胜，另一个请求被取消
+type HedgingConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"` // 是否启用对冲请求，默认关闭
+	Delay   string `yaml:"delay" json:"delay"`     // 触发对冲的等待时间，如 "3s"、"1500ms"
+}
+
+// StreamResumeConfig 流式响应断流续传配置
+// 上游在流式响应中途断开时，把已经发送给客户端的内容作为预填充，让其他端点续写并拼接进客户端的流
+type StreamResumeConfig struct {
+	Enabled     bool `yaml:"enabled" json:"enabled"`           // 是否启用断流续传，默认关闭
+	MaxAttempts int  `yaml:"max_attempts" json:"max_attempts"` // 最多尝试续写的端点数，默认2
+}
+
+// RetryPolicyConfig 重试策略配置
+// 规则按状态码、响应体正则和错误类别把失败映射为动作：return（立即返回错误）、retry（在当前端点重试）、switch（切换到下一个端点）。
+// 端点级规则优先匹配，未命中时依次使用全局规则和内置分类；端点级未设置的数值字段继承全局配置
+type RetryPolicyConfig struct {
+	MaxAttempts       int               `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"`               // 单个端点最多尝试次数（含首次），默认2
+	InitialBackoff    string            `yaml:"initial_backoff,omitempty" json:"initial_backoff,omitempty"`         // 第一次原地重试前的等待时间，默认 0s（不等待，立即重试）
+	MaxBackoff        string            `yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`                 // 指数退避的等待上限，默认 10s
+	BackoffMultiplier float64           `yaml:"backoff_multiplier,omitempty" json:"backoff_multiplier,omitempty"`   // 每次重试等待时间的倍数，默认2
+	Jitter            *float64          `yaml:"jitter,omitempty" json:"jitter,omitempty"`                           // 随机抖动比例（0-1），默认0.2；端点级设置为0时关闭抖动
+	RespectRetryAfter *bool             `yaml:"respect_retry_after,omitempty" json:"respect_retry_after,omitempty"` // 原地重试时是否遵循上游的 Retry-After，默认true
+	MaxRetryAfter     string            `yaml:"max_retry_after,omitempty" json:"max_retry_after,omitempty"`         // Retry-After 超过该值时不再等待，直接切换端点，默认 30s
+	Rules             []RetryRuleConfig `yaml:"rules,omitempty" json:"rules,omitempty"`                             // 重试规则，按顺序匹配
+}
+
+// SessionAffinityConfig 会话粘性路由配置
+// 按请求 metadata.user_id 中的 Claude Code 会话ID，把会话固定到最后一次成功处理它的端点，保持上游的提示词缓存；
+// 只有该端点不可用（被拉黑、禁用或不再匹配请求标签）时才迁移到其他端点
+type SessionAffinityConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"` // 是否启用会话粘性路由，默认关闭
+	TTL     string `yaml:"ttl" json:"ttl"`         // 会话最后一次请求后绑定的保留时间，默认 1h
+}
+
+// RateLimitTrackingConfig 上游速率限制响应头跟踪配置
+// 所有端点响应中的 x-ratelimit-*、anthropic-ratelimit-* 和 Retry-After 头部都会被解析为剩余额度，
+// 启用 skip_near_exhaustion 后剩余额度低于阈值的端点在额度重置之前被跳过
+type RateLimitTrackingConfig struct {
+	SkipNearExhaustion bool    `yaml:"skip_near_exhaustion" json:"skip_near_exhaustion"` // 是否跳过接近耗尽的端点，默认关闭
+	MinRemainingRatio  float64 `yaml:"min_remaining_ratio" json:"min_remaining_ratio"`   // 剩余额度低于上限的该比例时视为接近耗尽，默认 0.05
+}
+
+// ModelDiscoveryConfig 上游模型发现配置
+// 启用后定期调用每个端点的模型列表API（Anthropic 与 OpenAI 类型为 /v1/models）并缓存结果，
+// 用于校验模型重写的目标模型、管理界面的模型自动补全，以及用合并后的模型目录直接应答客户端的 GET /v1/models
+type ModelDiscoveryConfig struct {
+	Enabled         bool   `yaml:"enabled" json:"enabled"`                   // 是否启用模型发现，默认关闭
+	RefreshInterval string `yaml:"refresh_interval" json:"refresh_interval"` // 每个端点模型列表的刷新间隔，默认 1h
+	ServeModelList  bool   `yaml:"serve_model_list" json:"serve_model_list"` // 用合并的模型目录应答 GET /v1/models，默认关闭（转发到上游）
+}
+
+// PricingConfig 模型价格表，用于计算每个请求的费用并记录到请求日志。
+// 按上游实际使用的模型（模型重写后的模型）查找第一个匹配的价格；没有匹配时使用端点 budget 中的价格，仍没有则费用为 0
+type PricingConfig struct {
+	Models []ModelPriceConfig `yaml:"models,omitempty" json:"models,omitempty"`
+}
+
+// ModelPriceConfig 单个模型的价格（美元/百万token）
+type ModelPriceConfig struct {
+	Model           string  `yaml:"model" json:"model"`                                             // 模型名，支持与模型重写 source_pattern 相同的通配符，如 claude-*sonnet*
+	InputPrice      float64 `yaml:"input_price" json:"input_price"`                                 // 不含缓存的输入token价格
+	OutputPrice     float64 `yaml:"output_price" json:"output_price"`                               // 输出token价格
+	CacheReadPrice  float64 `yaml:"cache_read_price,omitempty" json:"cache_read_price,omitempty"`   // 默认 input_price 的 0.1 倍
+	CacheWritePrice float64 `yaml:"cache_write_price,omitempty" json:"cache_write_price,omitempty"` // 默认 input_price 的 1.25 倍
+}
+
+// AnalyticsConfig 用量分析汇总配置
+// 每个客户端请求按端点、模型、标签、会话和客户端令牌汇总到小时和天的时间桶中，保存在 statistics.db，
+// 与请求日志的清理相互独立，由 /admin/api/analytics 和仪表盘图表使用
+type AnalyticsConfig struct {
+	Enabled             *bool  `yaml:"enabled,omitempty" json:"enabled,omitempty"`         // 是否启用用量汇总，默认true
+	FlushInterval       string `yaml:"flush_interval" json:"flush_interval"`               // 内存中的汇总写入数据库的间隔，默认 1m
+	HourlyRetentionDays int    `yaml:"hourly_retention_days" json:"hourly_retention_days"` // 小时汇总的保留天数，默认 14
+	DailyRetentionDays  int    `yaml:"daily_retention_days" json:"daily_retention_days"`   // 天汇总的保留天数，默认 400
+}
+
+// IsEnabled 返回是否启用用量汇总
+func (a AnalyticsConfig) IsEnabled() bool {
+	return a.Enabled == nil || *a.Enabled
+}
+
+// MetricsConfig Prometheus 指标导出配置
+// 启用后以 Prometheus 文本格式在 path 上导出请求、延迟、端点状态、重试与故障转移、token 用量和 OAuth 刷新指标
+type MetricsConfig struct {
+	Enabled     bool   `yaml:"enabled" json:"enabled"`           // 是否启用 /metrics，默认关闭
+	Path        string `yaml:"path" json:"path"`                 // 指标路径，默认 /metrics，修改后需要重启
+	RequireAuth bool   `yaml:"require_auth" json:"require_auth"` // 是否要求管理界面的凭据（HTTP Basic 或已登录的会话），仅在启用 auth 时生效
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置
+// 每个客户端请求生成一条链路：根 span 对应客户端请求，子 span 对应每次端点尝试和各处理阶段，
+// 并通过 W3C traceparent 头部传播到上游；修改后需要重启
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled" json:"enabled"`                     // 是否启用链路追踪，默认关闭
+	Exporter    string            `yaml:"exporter" json:"exporter"`                   // otlp（OTLP/HTTP 发送到收集器）或 file（JSON 写入文件），默认 otlp
+	Endpoint    string            `yaml:"endpoint" json:"endpoint"`                   // OTLP/HTTP 收集器地址，默认 http://localhost:4318/v1/traces
+	Headers     map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"` // 发送到收集器时附加的头部，如认证信息
+	FilePath    string            `yaml:"file_path" json:"file_path"`                 // file 导出器的输出文件，默认 logs/traces.jsonl
+	SampleRatio float64           `yaml:"sample_ratio" json:"sample_ratio"`           // 采样比例（0-1]，默认 1；客户端传入的 traceparent 采样标记优先
+	ServiceName string            `yaml:"service_name" json:"service_name"`           // 上报的 service.name，默认 claude-code-companion
+}
+
+// RateLimitCapacityConfig 端点接近耗尽时写回配置文件的额度快照，重启后在重置时间之前继续生效
+type RateLimitCapacityConfig struct {
+	Source         string                    `yaml:"source" json:"source"`
+	Reason         string                    `yaml:"reason" json:"reason"`
+	ExhaustedUntil int64                     `yaml:"exhausted_until" json:"exhausted_until"` // Unix秒
+	UpdatedAt      int64                     `yaml:"updated_at" json:"updated_at"`           // 解析出该额度的响应时间，Unix秒
+	Requests       *RateLimitDimensionConfig `yaml:"requests,omitempty" json:"requests,omitempty"`
+	Tokens         *RateLimitDimensionConfig `yaml:"tokens,omitempty" json:"tokens,omitempty"`
+}
+
+// RateLimitDimensionConfig 单个维度（请求数或token数）的额度快照
+type RateLimitDimensionConfig struct {
+	Limit     int64 `yaml:"limit,omitempty" json:"limit,omitempty"`
+	Remaining int64 `yaml:"remaining" json:"remaining"`
+	ResetAt   int64 `yaml:"reset_at" json:"reset_at"` // Unix秒
+}
+
+// CircuitBreakerConfig 端点熔断器配置
+// 端点连续失败时熔断器打开，打开时长结束后进入半开状态，按比例放行少量实时请求试探：
+// 连续成功 half_open_successes 次后关闭，试探失败则重新打开且时长按 multiplier 倍增
+type CircuitBreakerConfig struct {
+	Enabled           bool    `yaml:"enabled" json:"enabled"`                         // 是否启用熔断器，默认关闭（使用健康检查恢复）
+	OpenDuration      string  `yaml:"open_duration" json:"open_duration"`             // 第一次打开的时长，默认 30s
+	MaxOpenDuration   string  `yaml:"max_open_duration" json:"max_open_duration"`     // 打开时长上限，默认 10m
+	Multiplier        float64 `yaml:"multiplier" json:"multiplier"`                   // 连续打开时时长的倍数，默认 2
+	HalfOpenRatio     float64 `yaml:"half_open_ratio" json:"half_open_ratio"`         // 半开状态下交给端点试探的请求比例，默认 0.1
+	HalfOpenSuccesses int     `yaml:"half_open_successes" json:"half_open_successes"` // 关闭熔断器所需的连续成功试探次数，默认 2
+	HistorySize       int     `yaml:"history_size" json:"history_size"`               // 每个端点保留的状态转换记录数，默认 50
+}
+
+// LoadBalancingConfig 负载均衡配置
+// 策略只决定首选端点：在标签匹配层级最高的可用端点中挑选，首选端点失败后仍按优先级顺序回退
+type LoadBalancingConfig struct {
+	Strategy       string                     `yaml:"strategy" json:"strategy"`                                   // priority | weighted | least_inflight | ewma，默认 priority
+	EWMAAlpha      float64                    `yaml:"ewma_alpha,omitempty" json:"ewma_alpha,omitempty"`           // ewma：新延迟样本的权重 (0-1]，默认0.3
+	FailurePenalty string                     `yaml:"failure_penalty,omitempty" json:"failure_penalty,omitempty"` // ewma：失败或超时的请求计入的最小延迟，默认 10s
+	Groups         []LoadBalancingGroupConfig `yaml:"groups,omitempty" json:"groups,omitempty"`                   // 按标签组覆盖默认策略，按顺序匹配
+}
+
+// LoadBalancingGroupConfig 标签组负载均衡策略
+type LoadBalancingGroupConfig struct {
+	Tags     []string `yaml:"tags" json:"tags"`         // 请求包含全部这些标签时使用该策略；空列表匹配无标签请求
+	Strategy string   `yaml:"strategy" json:"strategy"` // priority | weighted | least_inflight | ewma
+}
+
+// BudgetConfig 端点预算配置
+// 按响应中的 usage 累计每个窗口的输入/输出 token 和费用，窗口按本地时间的自然小时、天、月对齐；
+// 任一限制达到后端点像被拉黑一样不再接收请求，直到该窗口重置
+type BudgetConfig struct {
+	Hourly  *BudgetLimitConfig `yaml:"hourly,omitempty" json:"hourly,omitempty"`
+	Daily   *BudgetLimitConfig `yaml:"daily,omitempty" json:"daily,omitempty"`
+	Monthly *BudgetLimitConfig `yaml:"monthly,omitempty" json:"monthly,omitempty"`
+
+	// 费用计算使用的价格（美元/百万token），设置 max_cost 时必填 input/output 价格
+	InputPrice      float64 `yaml:"input_price,omitempty" json:"input_price,omitempty"`
+	OutputPrice     float64 `yaml:"output_price,omitempty" json:"output_price,omitempty"`
+	CacheReadPrice  float64 `yaml:"cache_read_price,omitempty" json:"cache_read_price,omitempty"`   // 默认 input_price 的 0.1 倍
+	CacheWritePrice float64 `yaml:"cache_write_price,omitempty" json:"cache_write_price,omitempty"` // 默认 input_price 的 1.25 倍
+}
+
+// EndpointLimitsConfig 端点的客户端并发与速率限制，0 表示不限制
+// 端点饱和时请求最多排队 queue_timeout，仍未获得许可则溢出到下一个端点，不计入端点健康统计
+type EndpointLimitsConfig struct {
+	MaxConcurrency int    `yaml:"max_concurrency,omitempty" json:"max_concurrency,omitempty"` // 同时进行的上游请求数上限
+	RPM            int    `yaml:"rpm,omitempty" json:"rpm,omitempty"`                         // 每分钟请求数（令牌桶，允许一分钟的突发）
+	TPM            int    `yaml:"tpm,omitempty" json:"tpm,omitempty"`                         // 每分钟token数（输入+输出），按响应 usage 事后扣除，令牌耗尽时暂停放行
+	QueueTimeout   string `yaml:"queue_timeout,omitempty" json:"queue_timeout,omitempty"`     // 饱和时的最长排队时间，默认 2s，"0s" 表示立即溢出
+}
+
+// BudgetLimitConfig 单个预算窗口的限制，0 表示不限制
+type BudgetLimitConfig struct {
+	MaxInputTokens  int64   `yaml:"max_input_tokens,omitempty" json:"max_input_tokens,omitempty"`   // 输入token（含缓存读写）
+	MaxOutputTokens int64   `yaml:"max_output_tokens,omitempty" json:"max_output_tokens,omitempty"` // 输出token
+	MaxCost         float64 `yaml:"max_cost,omitempty" json:"max_cost,omitempty"`                   // 费用（美元）
+}
+
+// RetryRuleConfig 重试规则：所有已设置的条件都满足时命中，同一条件的列表中任一值匹配即可
+type RetryRuleConfig struct {
+	StatusCodes     []int    `yaml:"status_codes,omitempty" json:"status_codes,omitempty"`         // 上游HTTP状态码，如 [403, 529]
+	BodyPattern     string   `yaml:"body_pattern,omitempty" json:"body_pattern,omitempty"`         // 匹配上游错误响应体（没有响应时匹配错误信息）的正则
+	ErrorCategories []string `yaml:"error_categories,omitempty" json:"error_categories,omitempty"` // client_error | server_error | network_error | usage_validation | sse_validation | other_validation | response_timeout
+	Action          string   `yaml:"action" json:"action"`                                         // return | retry | switch
+}
+
 // ClientAuthConfig 客户端认证配置
 type ClientAuthConfig struct {
 	Enabled       bool   `yaml:"enabled"`        // 是否启用客户端认证
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"net"
+	"net/url"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -117,6 +119,94 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("client auth configuration error: %v", err)
 	}
 
+	// 验证本地 count_tokens 估算配置
+	if err := validateTokenCountConfig(&config.TokenCount); err != nil {
+		return fmt.Errorf("token count configuration error: %v", err)
+	}
+
+	// 验证对冲请求配置
+	if err := validateHedgingConfig(&config.Hedging); err != nil {
+		return fmt.Errorf("hedging configuration error: %v", err)
+	}
+
+	// 验证断流续传配置
+	if err := validateStreamResumeConfig(&config.StreamResume); err != nil {
+		return fmt.Errorf("stream resume configuration error: %v", err)
+	}
+
+	// 验证重试策略配置
+	if err := validateRetryPolicyConfig(&config.RetryPolicy); err != nil {
+		return fmt.Errorf("retry policy configuration error: %v", err)
+	}
+	if err := validateEndpointRetryPolicies(config.Endpoints); err != nil {
+		return fmt.Errorf("retry policy configuration error: %v", err)
+	}
+
+	// 验证端点并发与速率限制配置
+	if err := validateEndpointLimits(config.Endpoints); err != nil {
+		return fmt.Errorf("endpoint limits configuration error: %v", err)
+	}
+
+	// 验证端点健康检查配置
+	if err := validateEndpointHealthChecks(config.Endpoints); err != nil {
+		return fmt.Errorf("health check configuration error: %v", err)
+	}
+
+	// 验证多凭据轮换配置
+	if err := validateEndpointKeyRotation(config.Endpoints); err != nil {
+		return fmt.Errorf("key rotation configuration error: %v", err)
+	}
+
+	// 验证端点预算配置
+	if err := validateEndpointBudgets(config.Endpoints); err != nil {
+		return fmt.Errorf("budget configuration error: %v", err)
+	}
+
+	// 验证负载均衡配置
+	if err := validateLoadBalancingConfig(&config.LoadBalancing, config.Endpoints); err != nil {
+		return fmt.Errorf("load balancing configuration error: %v", err)
+	}
+
+	// 验证会话粘性路由配置
+	if err := validateSessionAffinityConfig(&config.SessionAffinity); err != nil {
+		return fmt.Errorf("session affinity configuration error: %v", err)
+	}
+
+	// 验证速率限制响应头跟踪配置
+	if err := validateRateLimitTrackingConfig(&config.RateLimitTracking); err != nil {
+		return fmt.Errorf("rate limit tracking configuration error: %v", err)
+	}
+
+	// 验证熔断器配置
+	if err := validateCircuitBreakerConfig(&config.CircuitBreaker); err != nil {
+		return fmt.Errorf("circuit breaker configuration error: %v", err)
+	}
+
+	// 验证模型发现配置
+	if err := validateModelDiscoveryConfig(&config.ModelDiscovery); err != nil {
+		return fmt.Errorf("model discovery configuration error: %v", err)
+	}
+
+	// 验证模型价格表
+	if err := validatePricingConfig(&config.Pricing); err != nil {
+		return fmt.Errorf("pricing configuration error: %v", err)
+	}
+
+	// 验证用量分析汇总配置
+	if err := validateAnalyticsConfig(&config.Analytics); err != nil {
+		return fmt.Errorf("analytics configuration error: %v", err)
+	}
+
+	// 验证 Prometheus 指标导出配置
+	if err := validateMetricsConfig(&config.Metrics); err != nil {
+		return fmt.Errorf("metrics configuration error: %v", err)
+	}
+
+	// 验证 OpenTelemetry 链路追踪配置
+	if err := validateTracingConfig(&config.Tracing); err != nil {
+		return fmt.Errorf("tracing configuration error: %v", err)
+	}
+
 	return nil
 }
 
@@ -249,12 +339,21 @@ func ValidateOAuthConfig(config *OAuthConfig, context string) error {
 
 // validateOAuthConfig 验证单个OAuth配置
 func validateOAuthConfig(config *OAuthConfig, context string) error {
-	if config.AccessToken == "" {
-		return fmt.Errorf("%s: oauth access_token is required", context)
+	// 设置了 authorize_url 的端点可以先保存，再在管理界面登录获取token
+	if config.AuthorizeURL != "" {
+		if u, err := url.Parse(config.AuthorizeURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s: oauth authorize_url must be a valid http(s) URL", context)
+		}
+	} else if config.AccessToken == "" {
+		return fmt.Errorf("%s: oauth access_token is required (or set authorize_url to log in from the admin UI)", context)
+	} else if config.RefreshToken == "" {
+		return fmt.Errorf("%s: oauth refresh_token is required", context)
 	}
 	
-	if config.RefreshToken == "" {
-		return fmt.Errorf("%s: oauth refresh_token is required", context)
+	if config.RedirectURI != "" {
+		if u, err := url.Parse(config.RedirectURI); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s: oauth redirect_uri must be an absolute URL", context)
+		}
 	}
 	
 	// ExpiresAt can be 0 to trigger automatic refresh, or positive timestamp
@@ -338,10 +437,11 @@ func validateModelRewriteConfig(config *ModelRewriteConfig, context string) erro
 	return nil
 }
 
-// validateOpenAIEndpoints 验证 OpenAI 端点配置
+// validateOpenAIEndpoints 验证 OpenAI / Gemini 等非 Anthropic 端点配置
 func validateOpenAIEndpoints(endpoints []EndpointConfig) error {
 	for i, endpoint := range endpoints {
-		if endpoint.EndpointType == "openai" {
+		// openai_responses（Responses API）与 openai（Chat Completions）的认证和路径要求相同
+		if endpoint.EndpointType == "openai" || endpoint.EndpointType == "openai_responses" {
 			// OpenAI 端点不能使用 api_key 认证类型
 			if endpoint.AuthType == "api_key" {
 				return fmt.Errorf("endpoint[%d] '%s': OpenAI endpoints cannot use auth_type 'api_key', use 'auth_token' instead", i, endpoint.Name)
@@ -361,13 +461,33 @@ func validateOpenAIEndpoints(endpoints []EndpointConfig) error {
 				if endpoint.OAuthConfig == nil {
 					return fmt.Errorf("endpoint[%d] '%s': OpenAI endpoints with oauth auth_type require oauth_config", i, endpoint.Name)
 				}
-			} else if endpoint.AuthValue == "" {
+			} else if len(endpoint.Credentials()) == 0 {
 				return fmt.Errorf("endpoint[%d] '%s': OpenAI endpoints with auth_token require auth_value to be specified", i, endpoint.Name)
 			}
 			
 			// OpenAI 端点必须配置 path_prefix
 			if endpoint.PathPrefix == "" {
-				return fmt.Errorf("endpoint[%d] '%s': OpenAI endpoints require path_prefix to be specified (e.g., '/v1/chat/completions')", i, endpoint.Name)
+				return fmt.Errorf("endpoint[%d] '%s': OpenAI endpoints require path_prefix to be specified (e.g., '/v1/chat/completions' or '/v1/responses')", i, endpoint.Name)
+			}
+		}
+		
+		// Gemini 端点支持 api_key（x-goog-api-key）、auth_token 和 oauth；path_prefix 可选，默认 /v1beta/models
+		if endpoint.EndpointType == "gemini" {
+			if endpoint.AuthType != "api_key" && endpoint.AuthType != "auth_token" && endpoint.AuthType != "oauth" {
+				return fmt.Errorf("endpoint[%d] '%s': Gemini endpoints should use auth_type 'api_key', 'auth_token' or 'oauth'", i, endpoint.Name)
+			}
+			
+			if endpoint.AuthType == "oauth" {
+				if endpoint.OAuthConfig == nil {
+					return fmt.Errorf("endpoint[%d] '%s': Gemini endpoints with oauth auth_type require oauth_config", i, endpoint.Name)
+				}
+			} else if len(endpoint.Credentials()) == 0 {
+				return fmt.Errorf("endpoint[%d] '%s': Gemini endpoints with %s require auth_value to be specified", i, endpoint.Name, endpoint.AuthType)
+			}
+			
+			// 模型名和方法由代理拼接，path_prefix 只能到 models 这一级
+			if strings.Contains(endpoint.PathPrefix, ":") {
+				return fmt.Errorf("endpoint[%d] '%s': Gemini path_prefix must not contain the model or method (e.g., use '/v1beta/models')", i, endpoint.Name)
 			}
 		}
 		
@@ -475,8 +595,8 @@ func validateEndpoint(endpoint EndpointConfig, index int) error {
 	}
 	
 	// OAuth 认证不需要 auth_value，其他认证类型需要
-	if endpoint.AuthType != "oauth" && endpoint.AuthValue == "" {
-		return fmt.Errorf("endpoint %d: auth_value cannot be empty for non-oauth authentication", index)
+	if endpoint.AuthType != "oauth" && len(endpoint.Credentials()) == 0 {
+		return fmt.Errorf("endpoint %d: auth_value or auth_values cannot be empty for non-oauth authentication", index)
 	}
 	
 	return nil
@@ -497,4 +617,557 @@ func validateClientAuthConfig(config *ClientAuthConfig) error {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// validateTokenCountConfig 验证本地 count_tokens 估算配置并填充默认值
+func validateTokenCountConfig(config *TokenCountConfig) error {
+	if config.Estimator == "" {
+		config.Estimator = Default.TokenCount.Estimator
+	}
+
+	switch config.Estimator {
+	case "heuristic", "calibrated", "disabled":
+	case "bpe":
+		if config.BPEVocabFile == "" {
+			return fmt.Errorf("estimator 'bpe' requires bpe_vocab_file to be specified")
+		}
+	default:
+		return fmt.Errorf("invalid estimator '%s', must be one of: heuristic, bpe, calibrated, disabled", config.Estimator)
+	}
+
+	if config.CalibrationSamples < 0 {
+		return fmt.Errorf("calibration_samples cannot be negative")
+	}
+	if config.CalibrationSamples == 0 {
+		config.CalibrationSamples = Default.TokenCount.CalibrationSamples
+	}
+
+	if config.CalibrationInterval == "" {
+		config.CalibrationInterval = Default.TokenCount.CalibrationInterval
+	}
+	if _, err := time.ParseDuration(config.CalibrationInterval); err != nil {
+		return fmt.Errorf("invalid calibration_interval '%s': %v", config.CalibrationInterval, err)
+	}
+
+	return nil
+}
+
+// validateHedgingConfig 验证对冲请求配置并填充默认值
+func validateHedgingConfig(config *HedgingConfig) error {
+	if config.Delay == "" {
+		config.Delay = Default.Hedging.Delay
+	}
+
+	delay, err := time.ParseDuration(config.Delay)
+	if err != nil {
+		return fmt.Errorf("invalid delay '%s': %v", config.Delay, err)
+	}
+	if delay <= 0 {
+		return fmt.Errorf("delay must be positive, got '%s'", config.Delay)
+	}
+
+	return nil
+}
+
+// validateStreamResumeConfig 验证断流续传配置并填充默认值
+func validateStreamResumeConfig(config *StreamResumeConfig) error {
+	if config.MaxAttempts < 0 {
+		return fmt.Errorf("max_attempts cannot be negative")
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = Default.StreamResume.MaxAttempts
+	}
+	return nil
+}
+
+// RetryErrorCategories 重试规则中可用的错误类别
+var RetryErrorCategories = []string{
+	"client_error", "server_error", "network_error", "usage_validation", "sse_validation", "other_validation", "response_timeout",
+}
+
+// validateRetryPolicyConfig 验证全局重试策略并填充默认值
+func validateRetryPolicyConfig(config *RetryPolicyConfig) error {
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = Default.RetryPolicy.MaxAttempts
+	}
+	if config.InitialBackoff == "" {
+		config.InitialBackoff = Default.RetryPolicy.InitialBackoff
+	}
+	if config.MaxBackoff == "" {
+		config.MaxBackoff = Default.RetryPolicy.MaxBackoff
+	}
+	if config.BackoffMultiplier == 0 {
+		config.BackoffMultiplier = Default.RetryPolicy.BackoffMultiplier
+	}
+	if config.Jitter == nil {
+		jitter := Default.RetryPolicy.Jitter
+		config.Jitter = &jitter
+	}
+	if config.RespectRetryAfter == nil {
+		respect := Default.RetryPolicy.RespectRetryAfter
+		config.RespectRetryAfter = &respect
+	}
+	if config.MaxRetryAfter == "" {
+		config.MaxRetryAfter = Default.RetryPolicy.MaxRetryAfter
+	}
+
+	return validateRetryPolicyValues(config)
+}
+
+// validateEndpointRetryPolicies 验证端点级重试策略，未设置的字段运行时继承全局配置
+func validateEndpointRetryPolicies(endpoints []EndpointConfig) error {
+	for i, endpoint := range endpoints {
+		if endpoint.RetryPolicy == nil {
+			continue
+		}
+		if err := validateRetryPolicyValues(endpoint.RetryPolicy); err != nil {
+			return fmt.Errorf("endpoint[%d] '%s': %v", i, endpoint.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateRetryPolicyValues 验证重试策略中已设置的字段
+func validateRetryPolicyValues(config *RetryPolicyConfig) error {
+	if config.MaxAttempts < 0 {
+		return fmt.Errorf("max_attempts cannot be negative")
+	}
+	if config.BackoffMultiplier < 0 {
+		return fmt.Errorf("backoff_multiplier cannot be negative")
+	}
+	if config.Jitter != nil && (*config.Jitter < 0 || *config.Jitter > 1) {
+		return fmt.Errorf("jitter must be between 0 and 1, got %v", *config.Jitter)
+	}
+
+	durations := map[string]string{
+		"initial_backoff": config.InitialBackoff,
+		"max_backoff":     config.MaxBackoff,
+		"max_retry_after": config.MaxRetryAfter,
+	}
+	for name, value := range durations {
+		if value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s '%s': %v", name, value, err)
+		}
+		if duration < 0 {
+			return fmt.Errorf("%s cannot be negative, got '%s'", name, value)
+		}
+	}
+
+	for i, rule := range config.Rules {
+		switch rule.Action {
+		case "return", "retry", "switch":
+		default:
+			return fmt.Errorf("rule %d: invalid action '%s', must be one of: return, retry, switch", i, rule.Action)
+		}
+
+		if len(rule.StatusCodes) == 0 && rule.BodyPattern == "" && len(rule.ErrorCategories) == 0 {
+			return fmt.Errorf("rule %d: at least one of status_codes, body_pattern or error_categories must be specified", i)
+		}
+
+		for _, code := range rule.StatusCodes {
+			if code < 100 || code > 599 {
+				return fmt.Errorf("rule %d: invalid status code %d", i, code)
+			}
+		}
+
+		if rule.BodyPattern != "" {
+			if _, err := regexp.Compile(rule.BodyPattern); err != nil {
+				return fmt.Errorf("rule %d: invalid body_pattern '%s': %v", i, rule.BodyPattern, err)
+			}
+		}
+
+		for _, category := range rule.ErrorCategories {
+			valid := false
+			for _, known := range RetryErrorCategories {
+				if category == known {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("rule %d: invalid error category '%s', must be one of: %s", i, category, strings.Join(RetryErrorCategories, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateEndpointLimits 验证端点并发与速率限制并填充默认值
+func validateEndpointLimits(endpoints []EndpointConfig) error {
+	for i := range endpoints {
+		limits := endpoints[i].Limits
+		if limits == nil {
+			continue
+		}
+		if limits.MaxConcurrency < 0 || limits.RPM < 0 || limits.TPM < 0 {
+			return fmt.Errorf("endpoint[%d] '%s': max_concurrency, rpm and tpm cannot be negative", i, endpoints[i].Name)
+		}
+		if limits.QueueTimeout == "" {
+			limits.QueueTimeout = Default.EndpointLimits.QueueTimeout
+		}
+		timeout, err := time.ParseDuration(limits.QueueTimeout)
+		if err != nil {
+			return fmt.Errorf("endpoint[%d] '%s': invalid queue_timeout '%s': %v", i, endpoints[i].Name, limits.QueueTimeout, err)
+		}
+		if timeout < 0 {
+			return fmt.Errorf("endpoint[%d] '%s': queue_timeout cannot be negative", i, endpoints[i].Name)
+		}
+	}
+	return nil
+}
+
+// validateEndpointHealthChecks 验证端点健康检查配置并填充默认探测方式
+func validateEndpointHealthChecks(endpoints []EndpointConfig) error {
+	for i := range endpoints {
+		healthCheck := endpoints[i].HealthCheck
+		if healthCheck == nil {
+			continue
+		}
+		if healthCheck.Probe == "" {
+			healthCheck.Probe = Default.EndpointHealthCheck.Probe
+		}
+		if healthCheck.Probe != "messages" && healthCheck.Probe != "models" {
+			return fmt.Errorf("endpoint[%d] '%s': invalid probe '%s', must be 'messages' or 'models'", i, endpoints[i].Name, healthCheck.Probe)
+		}
+		if healthCheck.MaxTokens < 0 {
+			return fmt.Errorf("endpoint[%d] '%s': max_tokens cannot be negative", i, endpoints[i].Name)
+		}
+		if healthCheck.LatencySLO != "" {
+			slo, err := time.ParseDuration(healthCheck.LatencySLO)
+			if err != nil {
+				return fmt.Errorf("endpoint[%d] '%s': invalid latency_slo '%s': %v", i, endpoints[i].Name, healthCheck.LatencySLO, err)
+			}
+			if slo <= 0 {
+				return fmt.Errorf("endpoint[%d] '%s': latency_slo must be positive", i, endpoints[i].Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEndpointKeyRotation 验证多凭据轮换配置并填充默认值
+func validateEndpointKeyRotation(endpoints []EndpointConfig) error {
+	for i := range endpoints {
+		if len(endpoints[i].AuthValues) > 0 && endpoints[i].AuthType == "oauth" {
+			return fmt.Errorf("endpoint[%d] '%s': auth_values cannot be used with oauth", i, endpoints[i].Name)
+		}
+		rotation := endpoints[i].KeyRotation
+		if rotation == nil {
+			continue
+		}
+		if rotation.Strategy == "" {
+			rotation.Strategy = Default.KeyRotation.Strategy
+		}
+		if rotation.Strategy != "round_robin" && rotation.Strategy != "failover" {
+			return fmt.Errorf("endpoint[%d] '%s': invalid key_rotation strategy '%s', must be 'round_robin' or 'failover'", i, endpoints[i].Name, rotation.Strategy)
+		}
+		if rotation.Cooldown == "" {
+			rotation.Cooldown = Default.KeyRotation.Cooldown
+		}
+		if cooldown, err := time.ParseDuration(rotation.Cooldown); err != nil || cooldown <= 0 {
+			return fmt.Errorf("endpoint[%d] '%s': invalid key_rotation cooldown '%s', must be a positive duration", i, endpoints[i].Name, rotation.Cooldown)
+		}
+		for _, code := range rotation.RotateStatusCodes {
+			if code < 400 || code > 599 {
+				return fmt.Errorf("endpoint[%d] '%s': invalid key_rotation rotate_status_codes entry %d, must be a 4xx or 5xx status code", i, endpoints[i].Name, code)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEndpointBudgets 验证端点预算并填充缓存价格默认值
+func validateEndpointBudgets(endpoints []EndpointConfig) error {
+	for i := range endpoints {
+		budget := endpoints[i].Budget
+		if budget == nil {
+			continue
+		}
+		if err := validateBudgetConfig(budget); err != nil {
+			return fmt.Errorf("endpoint[%d] '%s': %v", i, endpoints[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func validateBudgetConfig(config *BudgetConfig) error {
+	if config.InputPrice < 0 || config.OutputPrice < 0 || config.CacheReadPrice < 0 || config.CacheWritePrice < 0 {
+		return fmt.Errorf("prices cannot be negative")
+	}
+	if config.CacheReadPrice == 0 {
+		config.CacheReadPrice = config.InputPrice * Default.Budget.CacheReadPriceRatio
+	}
+	if config.CacheWritePrice == 0 {
+		config.CacheWritePrice = config.InputPrice * Default.Budget.CacheWritePriceRatio
+	}
+
+	limits := map[string]*BudgetLimitConfig{
+		"hourly":  config.Hourly,
+		"daily":   config.Daily,
+		"monthly": config.Monthly,
+	}
+	hasLimit := false
+	for name, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		if limit.MaxInputTokens < 0 || limit.MaxOutputTokens < 0 || limit.MaxCost < 0 {
+			return fmt.Errorf("%s: limits cannot be negative", name)
+		}
+		if limit.MaxCost > 0 && config.InputPrice == 0 && config.OutputPrice == 0 {
+			return fmt.Errorf("%s: max_cost requires input_price or output_price", name)
+		}
+		if limit.MaxInputTokens > 0 || limit.MaxOutputTokens > 0 || limit.MaxCost > 0 {
+			hasLimit = true
+		}
+	}
+	if !hasLimit {
+		return fmt.Errorf("at least one of hourly, daily or monthly limits must be specified")
+	}
+	return nil
+}
+
+// LoadBalancingStrategies 可用的负载均衡策略
+var LoadBalancingStrategies = []string{"priority", "weighted", "least_inflight", "ewma"}
+
+func isLoadBalancingStrategy(strategy string) bool {
+	for _, known := range LoadBalancingStrategies {
+		if strategy == known {
+			return true
+		}
+	}
+	return false
+}
+
+// validateLoadBalancingConfig 验证负载均衡配置和端点权重并填充默认值
+func validateLoadBalancingConfig(config *LoadBalancingConfig, endpoints []EndpointConfig) error {
+	if config.Strategy == "" {
+		config.Strategy = Default.LoadBalancing.Strategy
+	}
+	if !isLoadBalancingStrategy(config.Strategy) {
+		return fmt.Errorf("invalid strategy '%s', must be one of: %s", config.Strategy, strings.Join(LoadBalancingStrategies, ", "))
+	}
+
+	if config.EWMAAlpha == 0 {
+		config.EWMAAlpha = Default.LoadBalancing.EWMAAlpha
+	}
+	if config.EWMAAlpha < 0 || config.EWMAAlpha > 1 {
+		return fmt.Errorf("ewma_alpha must be between 0 and 1, got %v", config.EWMAAlpha)
+	}
+
+	if config.FailurePenalty == "" {
+		config.FailurePenalty = Default.LoadBalancing.FailurePenalty
+	}
+	if penalty, err := time.ParseDuration(config.FailurePenalty); err != nil || penalty < 0 {
+		return fmt.Errorf("invalid failure_penalty '%s'", config.FailurePenalty)
+	}
+
+	for i, group := range config.Groups {
+		if !isLoadBalancingStrategy(group.Strategy) {
+			return fmt.Errorf("group %d: invalid strategy '%s', must be one of: %s", i, group.Strategy, strings.Join(LoadBalancingStrategies, ", "))
+		}
+	}
+
+	for i, endpoint := range endpoints {
+		if endpoint.Weight < 0 {
+			return fmt.Errorf("endpoint[%d] '%s': weight cannot be negative", i, endpoint.Name)
+		}
+	}
+
+	return nil
+}
+
+// validateSessionAffinityConfig 验证会话粘性路由配置并填充默认值
+func validateSessionAffinityConfig(config *SessionAffinityConfig) error {
+	if config.TTL == "" {
+		config.TTL = Default.SessionAffinity.TTL
+	}
+
+	ttl, err := time.ParseDuration(config.TTL)
+	if err != nil {
+		return fmt.Errorf("invalid ttl '%s': %v", config.TTL, err)
+	}
+	if ttl <= 0 {
+		return fmt.Errorf("ttl must be positive, got '%s'", config.TTL)
+	}
+
+	return nil
+}
+
+// validateRateLimitTrackingConfig 验证速率限制响应头跟踪配置并填充默认值
+func validateRateLimitTrackingConfig(config *RateLimitTrackingConfig) error {
+	if config.MinRemainingRatio == 0 {
+		config.MinRemainingRatio = Default.RateLimitTracking.MinRemainingRatio
+	}
+	if config.MinRemainingRatio < 0 || config.MinRemainingRatio >= 1 {
+		return fmt.Errorf("min_remaining_ratio must be in [0, 1), got %v", config.MinRemainingRatio)
+	}
+	return nil
+}
+
+// validateModelDiscoveryConfig 验证模型发现配置并填充默认值
+func validateModelDiscoveryConfig(config *ModelDiscoveryConfig) error {
+	if config.RefreshInterval == "" {
+		config.RefreshInterval = Default.ModelDiscovery.RefreshInterval
+	}
+	interval, err := time.ParseDuration(config.RefreshInterval)
+	if err != nil {
+		return fmt.Errorf("invalid refresh_interval '%s': %v", config.RefreshInterval, err)
+	}
+	if interval < time.Minute {
+		return fmt.Errorf("refresh_interval must be at least 1m, got '%s'", config.RefreshInterval)
+	}
+	return nil
+}
+
+// validatePricingConfig 验证模型价格表并填充缓存价格的默认值
+func validatePricingConfig(config *PricingConfig) error {
+	for i := range config.Models {
+		price := &config.Models[i]
+		if price.Model == "" {
+			return fmt.Errorf("models[%d]: model cannot be empty", i)
+		}
+		if _, err := filepath.Match(price.Model, ""); err != nil {
+			return fmt.Errorf("models[%d]: invalid model pattern '%s': %v", i, price.Model, err)
+		}
+		if price.InputPrice < 0 || price.OutputPrice < 0 || price.CacheReadPrice < 0 || price.CacheWritePrice < 0 {
+			return fmt.Errorf("models[%d] (%s): prices cannot be negative", i, price.Model)
+		}
+		if price.CacheReadPrice == 0 {
+			price.CacheReadPrice = price.InputPrice * Default.Budget.CacheReadPriceRatio
+		}
+		if price.CacheWritePrice == 0 {
+			price.CacheWritePrice = price.InputPrice * Default.Budget.CacheWritePriceRatio
+		}
+	}
+	return nil
+}
+
+// validateAnalyticsConfig 验证用量分析汇总配置并填充默认值
+func validateAnalyticsConfig(config *AnalyticsConfig) error {
+	if config.FlushInterval == "" {
+		config.FlushInterval = Default.Analytics.FlushInterval
+	}
+	interval, err := time.ParseDuration(config.FlushInterval)
+	if err != nil {
+		return fmt.Errorf("invalid flush_interval '%s': %v", config.FlushInterval, err)
+	}
+	if interval < time.Second {
+		return fmt.Errorf("flush_interval must be at least 1s, got '%s'", config.FlushInterval)
+	}
+	if config.HourlyRetentionDays == 0 {
+		config.HourlyRetentionDays = Default.Analytics.HourlyRetentionDays
+	}
+	if config.DailyRetentionDays == 0 {
+		config.DailyRetentionDays = Default.Analytics.DailyRetentionDays
+	}
+	if config.HourlyRetentionDays < 0 || config.DailyRetentionDays < 0 {
+		return fmt.Errorf("retention days cannot be negative")
+	}
+	return nil
+}
+
+// validateMetricsConfig 验证 Prometheus 指标导出配置并填充默认值
+func validateMetricsConfig(config *MetricsConfig) error {
+	if config.Path == "" {
+		config.Path = Default.Metrics.Path
+	}
+	if !strings.HasPrefix(config.Path, "/") || config.Path == "/" {
+		return fmt.Errorf("path must start with '/' and cannot be the root path, got '%s'", config.Path)
+	}
+	for _, reserved := range []string{"/v1", "/admin", "/static"} {
+		if config.Path == reserved || strings.HasPrefix(config.Path, reserved+"/") {
+			return fmt.Errorf("path '%s' conflicts with the %s routes", config.Path, reserved)
+		}
+	}
+	return nil
+}
+
+// validateTracingConfig 验证 OpenTelemetry 链路追踪配置并填充默认值
+func validateTracingConfig(config *TracingConfig) error {
+	if config.Exporter == "" {
+		config.Exporter = Default.Tracing.Exporter
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = Default.Tracing.Endpoint
+	}
+	if config.FilePath == "" {
+		config.FilePath = Default.Tracing.FilePath
+	}
+	if config.SampleRatio == 0 {
+		config.SampleRatio = Default.Tracing.SampleRatio
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = Default.Tracing.ServiceName
+	}
+
+	switch config.Exporter {
+	case "otlp":
+		u, err := url.Parse(config.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint must be an http(s) URL such as %s, got '%s'", Default.Tracing.Endpoint, config.Endpoint)
+		}
+	case "file":
+	default:
+		return fmt.Errorf("invalid exporter '%s', must be 'otlp' or 'file'", config.Exporter)
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return fmt.Errorf("sample_ratio must be between 0 and 1, got %v", config.SampleRatio)
+	}
+	return nil
+}
+
+// validateCircuitBreakerConfig 验证熔断器配置并填充默认值
+func validateCircuitBreakerConfig(config *CircuitBreakerConfig) error {
+	if config.OpenDuration == "" {
+		config.OpenDuration = Default.CircuitBreaker.OpenDuration
+	}
+	if config.MaxOpenDuration == "" {
+		config.MaxOpenDuration = Default.CircuitBreaker.MaxOpenDuration
+	}
+	if config.Multiplier == 0 {
+		config.Multiplier = Default.CircuitBreaker.Multiplier
+	}
+	if config.HalfOpenRatio == 0 {
+		config.HalfOpenRatio = Default.CircuitBreaker.HalfOpenRatio
+	}
+	if config.HalfOpenSuccesses == 0 {
+		config.HalfOpenSuccesses = Default.CircuitBreaker.HalfOpenSuccesses
+	}
+	if config.HistorySize == 0 {
+		config.HistorySize = Default.CircuitBreaker.HistorySize
+	}
+
+	openDuration, err := time.ParseDuration(config.OpenDuration)
+	if err != nil {
+		return fmt.Errorf("invalid open_duration '%s': %v", config.OpenDuration, err)
+	}
+	if openDuration <= 0 {
+		return fmt.Errorf("open_duration must be positive, got '%s'", config.OpenDuration)
+	}
+	maxOpenDuration, err := time.ParseDuration(config.MaxOpenDuration)
+	if err != nil {
+		return fmt.Errorf("invalid max_open_duration '%s': %v", config.MaxOpenDuration, err)
+	}
+	if maxOpenDuration < openDuration {
+		return fmt.Errorf("max_open_duration '%s' must not be less than open_duration '%s'", config.MaxOpenDuration, config.OpenDuration)
+	}
+	if config.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1, got %v", config.Multiplier)
+	}
+	if config.HalfOpenRatio <= 0 || config.HalfOpenRatio > 1 {
+		return fmt.Errorf("half_open_ratio must be in (0, 1], got %v", config.HalfOpenRatio)
+	}
+	if config.HalfOpenSuccesses < 0 {
+		return fmt.Errorf("half_open_successes must not be negative, got %d", config.HalfOpenSuccesses)
+	}
+	if config.HistorySize < 0 {
+		return fmt.Errorf("history_size must not be negative, got %d", config.HistorySize)
+	}
+	return nil
+}
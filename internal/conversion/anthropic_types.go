@@ -28,6 +28,9 @@ type AnthropicThinking struct {
 	BudgetTokens int    `json:"budget_tokens,omitempty"` // 思考模式的token预算
 }
 
+// thinkingSignaturePlaceholder OpenAI 兼容上游不提供 thinking 签名，转换出的 thinking 块统一使用该占位签名
+const thinkingSignaturePlaceholder = "openai-reasoning-no-signature"
+
 // AnthropicMessage 消息体
 type AnthropicMessage struct {
 	Role    string      `json:"role"` // "user" | "assistant"
@@ -36,11 +39,16 @@ type AnthropicMessage struct {
 
 // AnthropicContentBlock 内容块（Claude Code 会混用 text / image / tool_use / tool_result）
 type AnthropicContentBlock struct {
-	Type string `json:"type"` // "text" | "image" | "tool_use" | "tool_result" | "text_delta" | "input_json_delta"
+	Type string `json:"type"` // "text" | "image" | "tool_use" | "tool_result" | "thinking" | "text_delta" | "input_json_delta" | "thinking_delta" | "signature_delta"
 
 	// text
 	Text string `json:"text,omitempty"`
 
+	// thinking（由 OpenAI 兼容上游的 reasoning_content 转换而来）
+	// Anthropic: {type:"thinking", thinking:"...", signature:"..."}
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+
 	// image (仅支持 base64)
 	// Anthropic: {type:"image", source:{type:"base64", media_type:"image/png", data:"..."}}
 	Source *AnthropicImageSource `json:"source,omitempty"`
@@ -96,8 +104,9 @@ type AnthropicResponse struct {
 
 // AnthropicUsage 使用统计
 type AnthropicUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens          int `json:"input_tokens"` // 不含缓存读取的 token
+	OutputTokens         int `json:"output_tokens"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
 }
 
 // AnthropicStreamEvent 流式事件
@@ -115,9 +124,13 @@ type AnthropicMessageStart struct {
 // AnthropicContentBlockForStart 专门用于 content_block_start 事件的结构体
 // 确保 text 字段始终被序列化，即使为空
 type AnthropicContentBlockForStart struct {
-	Type string `json:"type"` // "text" | "tool_use"
+	Type string `json:"type"` // "text" | "tool_use" | "thinking"
 	Text string `json:"-"`    // 使用自定义序列化
 
+	// thinking 字段（当 Type 为 "thinking" 时使用）
+	Thinking  string `json:"-"` // 使用自定义序列化
+	Signature string `json:"-"` // 使用自定义序列化
+
 	// tool_use 字段（当 Type 为 "tool_use" 时使用）
 	ID    string          `json:"id,omitempty"`
 	Name  string          `json:"name,omitempty"`
@@ -125,11 +138,14 @@ type AnthropicContentBlockForStart struct {
 }
 
 // MarshalJSON 自定义 JSON 序列化
-// 对于 "text" 类型，始终包含 text 字段；对于 "tool_use" 类型，省略 text 字段
+// 对于 "text" 类型，始终包含 text 字段；对于 "thinking" 类型，始终包含 thinking 和 signature 字段；
+// 对于 "tool_use" 类型，省略 text 字段
 func (c AnthropicContentBlockForStart) MarshalJSON() ([]byte, error) {
 	type Alias AnthropicContentBlockForStart
 	aux := &struct {
-		Text *string `json:"text,omitempty"`
+		Text      *string `json:"text,omitempty"`
+		Thinking  *string `json:"thinking,omitempty"`
+		Signature *string `json:"signature,omitempty"`
 		*Alias
 	}{
 		Alias: (*Alias)(&c),
@@ -139,6 +155,11 @@ func (c AnthropicContentBlockForStart) MarshalJSON() ([]byte, error) {
 	if c.Type == "text" {
 		aux.Text = &c.Text
 	}
+	// 只有当 Type 为 "thinking" 时才包含 thinking/signature 字段
+	if c.Type == "thinking" {
+		aux.Thinking = &c.Thinking
+		aux.Signature = &c.Signature
+	}
 	
 	return json.Marshal(aux)
 }
@@ -22,7 +22,7 @@ func NewConverter(logger *logger.Logger) Converter {
 
 // ShouldConvert 检查是否需要转换
 func (c *DefaultConverter) ShouldConvert(endpointType string) bool {
-	return endpointType == "openai"
+	return endpointType == "openai" || endpointType == "openai_responses" || endpointType == "gemini"
 }
 
 // ConvertRequest 转换请求
@@ -31,9 +31,19 @@ func (c *DefaultConverter) ConvertRequest(anthropicReq []byte, endpointInfo *End
 		return anthropicReq, nil, nil
 	}
 
-	c.logger.Debug("Starting request conversion for OpenAI endpoint")
+	c.logger.Debug("Starting request conversion for " + endpointInfo.Type + " endpoint")
 	
-	convertedReq, ctx, err := c.requestConverter.Convert(anthropicReq, endpointInfo)
+	var convertedReq []byte
+	var ctx *ConversionContext
+	var err error
+	switch endpointInfo.Type {
+	case "openai_responses":
+		convertedReq, ctx, err = c.requestConverter.ConvertToResponses(anthropicReq, endpointInfo)
+	case "gemini":
+		convertedReq, ctx, err = c.requestConverter.ConvertToGemini(anthropicReq, endpointInfo)
+	default:
+		convertedReq, ctx, err = c.requestConverter.Convert(anthropicReq, endpointInfo)
+	}
 	if err != nil {
 		c.logger.Error("Request conversion failed", err)
 		return nil, nil, err
@@ -51,9 +61,18 @@ func (c *DefaultConverter) ConvertResponse(openaiResp []byte, ctx *ConversionCon
 		return openaiResp, nil
 	}
 
-	c.logger.Debug("Starting response conversion from OpenAI format")
+	c.logger.Debug("Starting response conversion from " + ctx.EndpointType + " format")
 	
-	convertedResp, err := c.responseConverter.Convert(openaiResp, ctx, isStreaming)
+	var convertedResp []byte
+	var err error
+	switch ctx.EndpointType {
+	case "openai_responses":
+		convertedResp, err = c.responseConverter.ConvertResponses(openaiResp, ctx, isStreaming)
+	case "gemini":
+		convertedResp, err = c.responseConverter.ConvertGemini(openaiResp, ctx, isStreaming)
+	default:
+		convertedResp, err = c.responseConverter.Convert(openaiResp, ctx, isStreaming)
+	}
 	if err != nil {
 		c.logger.Error("Response conversion failed", err)
 		return nil, err
@@ -62,4 +81,18 @@ func (c *DefaultConverter) ConvertResponse(openaiResp []byte, ctx *ConversionCon
 	c.logger.Debug("Response conversion completed successfully")
 	
 	return convertedResp, nil
-}
\ No newline at end of file
+}
+
+// NewStreamConverter 创建增量流式响应转换器
+func (c *DefaultConverter) NewStreamConverter(ctx *ConversionContext) StreamConverter {
+	if ctx == nil || !c.ShouldConvert(ctx.EndpointType) {
+		return nil
+	}
+	switch ctx.EndpointType {
+	case "openai_responses":
+		return NewResponsesStreamConverter(c.logger, ctx)
+	case "gemini":
+		return NewGeminiStreamConverter(c.logger, ctx)
+	}
+	return NewOpenAIStreamConverter(c.logger, ctx)
+}
@@ -0,0 +1,230 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConvertAnthropicRequestToGemini(t *testing.T) {
+	converter := NewRequestConverter(getTestLogger())
+
+	anthReq := `{
+		"model": "gemini-2.5-pro",
+		"max_tokens": 4096,
+		"stream": true,
+		"system": [{"type": "text", "text": "You are helpful."}],
+		"thinking": {"type": "enabled", "budget_tokens": 8000},
+		"tools": [{"name": "get_weather", "description": "Get weather", "input_schema": {"$schema": "http://json-schema.org/draft-07/schema#", "type": "object", "additionalProperties": false}}],
+		"tool_choice": {"type": "tool", "name": "get_weather"},
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "Weather here?"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "Checking."},
+				{"type": "tool_use", "id": "call_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "call_1", "content": "Sunny"}
+			]}
+		]
+	}`
+
+	result, ctx, err := converter.ConvertToGemini([]byte(anthReq), &EndpointInfo{Type: "gemini"})
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+	if ctx == nil || !ctx.IsStreaming || ctx.Model != "gemini-2.5-pro" {
+		t.Errorf("Unexpected conversion context: %+v", ctx)
+	}
+
+	if strings.Contains(string(result), `"model":`) {
+		t.Errorf("Gemini request body must not contain a model field: %s", result)
+	}
+
+	var req GeminiRequest
+	if err := json.Unmarshal(result, &req); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+
+	if req.SystemInstruction == nil || len(req.SystemInstruction.Parts) != 1 || req.SystemInstruction.Parts[0].Text != "You are helpful." {
+		t.Errorf("Unexpected systemInstruction: %+v", req.SystemInstruction)
+	}
+
+	genConfig := req.GenerationConfig
+	if genConfig == nil || genConfig.MaxOutputTokens == nil || *genConfig.MaxOutputTokens != 4096 {
+		t.Fatalf("Unexpected generationConfig: %+v", genConfig)
+	}
+	if genConfig.ThinkingConfig == nil || genConfig.ThinkingConfig.ThinkingBudget == nil ||
+		*genConfig.ThinkingConfig.ThinkingBudget != 8000 || !genConfig.ThinkingConfig.IncludeThoughts {
+		t.Errorf("Unexpected thinkingConfig: %+v", genConfig.ThinkingConfig)
+	}
+
+	if len(req.Tools) != 1 || len(req.Tools[0].FunctionDeclarations) != 1 {
+		t.Fatalf("Unexpected tools: %+v", req.Tools)
+	}
+	if declaration := req.Tools[0].FunctionDeclarations[0]; declaration.Name != "get_weather" || declaration.ParametersJsonSchema["type"] != "object" {
+		t.Errorf("Unexpected function declaration: %+v", declaration)
+	}
+
+	if req.ToolConfig == nil || req.ToolConfig.FunctionCallingConfig == nil ||
+		req.ToolConfig.FunctionCallingConfig.Mode != "ANY" ||
+		len(req.ToolConfig.FunctionCallingConfig.AllowedFunctionNames) != 1 {
+		t.Errorf("Unexpected toolConfig: %+v", req.ToolConfig)
+	}
+
+	// contents 角色交替：user(image+text) → model(text+functionCall) → user(functionResponse)
+	expectedRoles := []string{"user", "model", "user"}
+	if len(req.Contents) != len(expectedRoles) {
+		t.Fatalf("Expected %d contents, got %d: %s", len(expectedRoles), len(req.Contents), result)
+	}
+	for i, role := range expectedRoles {
+		if req.Contents[i].Role != role {
+			t.Errorf("Content %d: expected role '%s', got '%s'", i, role, req.Contents[i].Role)
+		}
+	}
+
+	// 图片在文本之前（与 OpenAI 转换保持一致）
+	userParts := req.Contents[0].Parts
+	if len(userParts) != 2 || userParts[0].InlineData == nil || userParts[0].InlineData.MimeType != "image/png" ||
+		userParts[0].InlineData.Data != "iVBORw0KGgo=" || userParts[1].Text != "Weather here?" {
+		t.Errorf("Unexpected user parts: %+v", userParts)
+	}
+
+	modelParts := req.Contents[1].Parts
+	if len(modelParts) != 2 || modelParts[0].Text != "Checking." || modelParts[1].FunctionCall == nil ||
+		modelParts[1].FunctionCall.Name != "get_weather" || modelParts[1].FunctionCall.Args["city"] != "Paris" {
+		t.Errorf("Unexpected model parts: %+v", modelParts)
+	}
+
+	functionResponse := req.Contents[2].Parts[0].FunctionResponse
+	if functionResponse == nil || functionResponse.Name != "get_weather" || functionResponse.Response["content"] != "Sunny" {
+		t.Errorf("Unexpected functionResponse: %+v", functionResponse)
+	}
+}
+
+func TestConvertGeminiResponseToAnthropic(t *testing.T) {
+	converter := NewResponseConverter(getTestLogger())
+
+	respBody := `{
+		"candidates": [{
+			"content": {"role": "model", "parts": [
+				{"text": "Need the weather tool.", "thought": true},
+				{"text": "Let me check."},
+				{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}}
+			]},
+			"finishReason": "STOP",
+			"index": 0
+		}],
+		"usageMetadata": {"promptTokenCount": 30, "candidatesTokenCount": 12, "thoughtsTokenCount": 8, "totalTokenCount": 50},
+		"modelVersion": "gemini-2.5-pro",
+		"responseId": "abc123"
+	}`
+
+	result, err := converter.ConvertGemini([]byte(respBody), &ConversionContext{EndpointType: "gemini"}, false)
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	var anthResp AnthropicResponse
+	if err := json.Unmarshal(result, &anthResp); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+
+	if anthResp.ID != "msg_abc123" || anthResp.Model != "gemini-2.5-pro" {
+		t.Errorf("Unexpected id/model: %s %s", anthResp.ID, anthResp.Model)
+	}
+
+	if anthResp.StopReason != "tool_use" {
+		t.Errorf("Expected stop_reason 'tool_use', got '%s'", anthResp.StopReason)
+	}
+
+	if len(anthResp.Content) != 3 {
+		t.Fatalf("Expected 3 content blocks, got %d", len(anthResp.Content))
+	}
+
+	if anthResp.Content[0].Type != "thinking" || anthResp.Content[0].Thinking != "Need the weather tool." {
+		t.Errorf("Unexpected thinking block: %+v", anthResp.Content[0])
+	}
+
+	if anthResp.Content[1].Type != "text" || anthResp.Content[1].Text != "Let me check." {
+		t.Errorf("Unexpected text block: %+v", anthResp.Content[1])
+	}
+
+	toolUse := anthResp.Content[2]
+	if toolUse.Type != "tool_use" || toolUse.ID != "call_abc123_0" || toolUse.Name != "get_weather" || string(toolUse.Input) != `{"city":"Paris"}` {
+		t.Errorf("Unexpected tool_use block: %+v", toolUse)
+	}
+
+	// 输出 token 包含推理 token
+	if anthResp.Usage == nil || anthResp.Usage.InputTokens != 30 || anthResp.Usage.OutputTokens != 20 {
+		t.Errorf("Unexpected usage: %+v", anthResp.Usage)
+	}
+}
+
+func TestGeminiStreamConverter_ThinkingTextAndFunctionCall(t *testing.T) {
+	converter := NewGeminiStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "gemini", Model: "gemini-2.5-flash"})
+
+	rawEvents := []string{
+		"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Plan.\",\"thought\":true}]},\"index\":0}],\"usageMetadata\":{\"promptTokenCount\":5,\"thoughtsTokenCount\":2},\"responseId\":\"r9\"}\r\n\r\n",
+		"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Hi\"}]},\"index\":0}],\"usageMetadata\":{\"promptTokenCount\":5,\"candidatesTokenCount\":1,\"thoughtsTokenCount\":2},\"responseId\":\"r9\"}\r\n\r\n",
+		"data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"functionCall\":{\"name\":\"get_time\",\"args\":{}}}]},\"finishReason\":\"STOP\",\"index\":0}],\"usageMetadata\":{\"promptTokenCount\":5,\"candidatesTokenCount\":3,\"thoughtsTokenCount\":2,\"totalTokenCount\":10},\"responseId\":\"r9\"}\r\n\r\n",
+	}
+
+	var events []AnthropicSSEEvent
+	for i, raw := range rawEvents {
+		out, err := converter.ProcessEvent([]byte(raw))
+		if err != nil {
+			t.Fatalf("ProcessEvent failed: %v", err)
+		}
+		// 第一个事件必须立即产生 message_start 和 thinking 增量
+		if i == 0 && (len(out) == 0 || out[0].Type != "message_start") {
+			t.Fatalf("Expected message_start for the first event, got %v", collectEventTypes(out))
+		}
+		events = append(events, out...)
+	}
+	final, err := converter.Finish()
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	events = append(events, final...)
+
+	expected := []string{
+		"message_start",
+		"content_block_start", "ping", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}
+	if got := strings.Join(collectEventTypes(events), ","); got != strings.Join(expected, ",") {
+		t.Fatalf("Unexpected event sequence:\n got: %s\nwant: %s", got, strings.Join(expected, ","))
+	}
+
+	if start := events[0].Data.(*AnthropicMessageStart); start.Message.ID != "msg_r9" || start.Message.Model != "gemini-2.5-flash" {
+		t.Errorf("Unexpected message_start: %+v", start.Message)
+	}
+
+	if block := events[9].Data.(*AnthropicContentBlockStart).ContentBlock; block.ID != "call_r9_0" || block.Name != "get_time" {
+		t.Errorf("Unexpected tool block: %+v", block)
+	}
+
+	// usageMetadata 是累计值，只取最后一次
+	messageDelta := events[12].Data.(*AnthropicMessageDelta)
+	if messageDelta.Delta.StopReason != "tool_use" {
+		t.Errorf("Expected stop_reason tool_use, got %s", messageDelta.Delta.StopReason)
+	}
+	if messageDelta.Usage == nil || messageDelta.Usage.InputTokens != 5 || messageDelta.Usage.OutputTokens != 5 {
+		t.Errorf("Unexpected usage: %+v", messageDelta.Usage)
+	}
+}
+
+func TestGeminiStreamConverter_Error(t *testing.T) {
+	converter := NewGeminiStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "gemini"})
+
+	_, err := converter.ProcessEvent([]byte("data: {\"error\":{\"code\":429,\"message\":\"quota exceeded\",\"status\":\"RESOURCE_EXHAUSTED\"}}\n\n"))
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("Expected upstream error containing 'quota exceeded', got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ConvertToGemini 转换 Anthropic 请求为 Gemini generateContent 格式
+// 先复用 Chat Completions 的转换逻辑（system、tool_result、图片、thinking 等），再映射为 contents/parts
+func (c *RequestConverter) ConvertToGemini(anthropicReq []byte, endpointInfo *EndpointInfo) ([]byte, *ConversionContext, error) {
+	chatReq, ctx, err := c.buildOpenAIRequest(anthropicReq, endpointInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Gemini 的模型名位于URL路径中，由代理根据上下文构造请求地址
+	ctx.Model = chatReq.Model
+
+	out := GeminiRequest{
+		Contents: []GeminiContent{},
+	}
+
+	// 生成参数：Gemini 只有 maxOutputTokens 一个字段，忽略 max_tokens_field_name 配置
+	genConfig := &GeminiGenerationConfig{
+		Temperature:   chatReq.Temperature,
+		TopP:          chatReq.TopP,
+		StopSequences: chatReq.Stop,
+	}
+	switch {
+	case chatReq.MaxOutputTokens != nil:
+		genConfig.MaxOutputTokens = chatReq.MaxOutputTokens
+	case chatReq.MaxCompletionTokens != nil:
+		genConfig.MaxOutputTokens = chatReq.MaxCompletionTokens
+	default:
+		genConfig.MaxOutputTokens = chatReq.MaxTokens
+	}
+
+	// 推理配置：直接使用 Anthropic 的 budget_tokens，未指定时由模型动态决定
+	if chatReq.ReasoningEffort != nil {
+		budget := -1
+		if chatReq.MaxReasoningTokens != nil {
+			budget = *chatReq.MaxReasoningTokens
+		}
+		genConfig.ThinkingConfig = &GeminiThinkingConfig{
+			ThinkingBudget:  &budget,
+			IncludeThoughts: true,
+		}
+	}
+	out.GenerationConfig = genConfig
+
+	// 工具定义
+	if len(chatReq.Tools) > 0 {
+		tool := GeminiTool{}
+		for _, t := range chatReq.Tools {
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, GeminiFunctionDeclaration{
+				Name:                 t.Function.Name,
+				Description:          t.Function.Description,
+				ParametersJsonSchema: t.Function.Parameters,
+			})
+		}
+		out.Tools = []GeminiTool{tool}
+	}
+
+	// tool_choice：auto → AUTO，required → ANY，none → NONE，指定工具 → ANY + allowedFunctionNames
+	switch tc := chatReq.ToolChoice.(type) {
+	case string:
+		mode := map[string]string{"auto": "AUTO", "required": "ANY", "none": "NONE"}[tc]
+		if mode != "" {
+			out.ToolConfig = &GeminiToolConfig{FunctionCallingConfig: &GeminiFunctionCallingConfig{Mode: mode}}
+		}
+	case map[string]interface{}:
+		if function, ok := tc["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				out.ToolConfig = &GeminiToolConfig{FunctionCallingConfig: &GeminiFunctionCallingConfig{
+					Mode:                 "ANY",
+					AllowedFunctionNames: []string{name},
+				}}
+			}
+		}
+	}
+
+	// functionResponse 需要函数名，而 tool 消息只有调用ID，这里记录 ID -> 函数名
+	toolNames := make(map[string]string)
+
+	for _, m := range chatReq.Messages {
+		switch m.Role {
+		case "system":
+			if text := openAIContentToText(m.Content); text != "" {
+				if out.SystemInstruction == nil {
+					out.SystemInstruction = &GeminiContent{}
+				}
+				out.SystemInstruction.Parts = append(out.SystemInstruction.Parts, GeminiPart{Text: text})
+			}
+
+		case "user":
+			out.Contents = appendGeminiContent(out.Contents, "user", c.userContentToGeminiParts(m.Content))
+
+		case "assistant":
+			var parts []GeminiPart
+			if text := openAIContentToText(m.Content); text != "" {
+				parts = append(parts, GeminiPart{Text: text})
+			}
+			for _, tc := range m.ToolCalls {
+				toolNames[tc.ID] = tc.Function.Name
+				parts = append(parts, GeminiPart{FunctionCall: &GeminiFunctionCall{
+					Name: tc.Function.Name,
+					Args: geminiArgsFromJSON(tc.Function.Arguments),
+				}})
+			}
+			out.Contents = appendGeminiContent(out.Contents, "model", parts)
+
+		case "tool":
+			name := toolNames[m.ToolCallID]
+			if name == "" {
+				// 找不到对应调用时退回使用调用ID，避免生成无名的 functionResponse
+				name = m.ToolCallID
+			}
+			out.Contents = appendGeminiContent(out.Contents, "user", []GeminiPart{{FunctionResponse: &GeminiFunctionResponse{
+				Name:     name,
+				Response: map[string]interface{}{"content": openAIContentToText(m.Content)},
+			}}})
+		}
+	}
+
+	result, err := json.Marshal(out)
+	if err != nil {
+		return nil, nil, NewConversionError("marshal_error", "Failed to marshal Gemini request", err)
+	}
+
+	if c.logger != nil {
+		c.logger.Debug("Gemini request conversion completed", map[string]interface{}{
+			"contents": len(out.Contents),
+			"tools":    len(chatReq.Tools),
+			"model":    ctx.Model,
+		})
+	}
+
+	return result, ctx, nil
+}
+
+// appendGeminiContent 追加一轮内容；与上一轮角色相同时合并 parts（并行工具调用的多个结果必须放在同一轮中）
+func appendGeminiContent(contents []GeminiContent, role string, parts []GeminiPart) []GeminiContent {
+	if len(parts) == 0 {
+		return contents
+	}
+	if n := len(contents); n > 0 && contents[n-1].Role == role {
+		contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+		return contents
+	}
+	return append(contents, GeminiContent{Role: role, Parts: parts})
+}
+
+// userContentToGeminiParts 将 Chat Completions 的 user content 转换为 text / inlineData 片段
+func (c *RequestConverter) userContentToGeminiParts(content interface{}) []GeminiPart {
+	var parts []GeminiPart
+	switch v := content.(type) {
+	case string:
+		if v != "" {
+			parts = append(parts, GeminiPart{Text: v})
+		}
+	case []OpenAIMessageContent:
+		for _, p := range v {
+			switch p.Type {
+			case "text":
+				if p.Text != "" {
+					parts = append(parts, GeminiPart{Text: p.Text})
+				}
+			case "image_url":
+				if p.ImageURL == nil {
+					continue
+				}
+				if inline := geminiInlineDataFromURL(p.ImageURL.URL); inline != nil {
+					parts = append(parts, GeminiPart{InlineData: inline})
+				} else if c.logger != nil {
+					c.logger.Debug("Skipping non-inline image (Gemini only accepts base64 inline data)")
+				}
+			}
+		}
+	}
+	return parts
+}
+
+// geminiInlineDataFromURL 解析 "data:image/png;base64,..." 形式的图片
+func geminiInlineDataFromURL(url string) *GeminiInlineData {
+	if !strings.HasPrefix(url, "data:") {
+		return nil
+	}
+	header, data, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !found || !strings.HasSuffix(header, ";base64") {
+		return nil
+	}
+	return &GeminiInlineData{
+		MimeType: strings.TrimSuffix(header, ";base64"),
+		Data:     data,
+	}
+}
+
+// geminiArgsFromJSON 将工具调用的 JSON 参数字符串转换为 args 对象
+func geminiArgsFromJSON(arguments string) map[string]interface{} {
+	args := make(map[string]interface{})
+	if strings.TrimSpace(arguments) == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return map[string]interface{}{}
+	}
+	return args
+}
@@ -0,0 +1,149 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConvertGemini 转换 Gemini generateContent / streamGenerateContent 响应为 Anthropic 格式
+func (c *ResponseConverter) ConvertGemini(geminiResp []byte, ctx *ConversionContext, isStreaming bool) ([]byte, error) {
+	if isStreaming {
+		return c.convertGeminiStreaming(geminiResp, ctx)
+	}
+	return c.convertGeminiNonStreaming(geminiResp, ctx)
+}
+
+// convertGeminiNonStreaming 转换非流式 Gemini 响应，按 parts 顺序生成 thinking / text / tool_use 块
+func (c *ResponseConverter) convertGeminiNonStreaming(geminiResp []byte, ctx *ConversionContext) ([]byte, error) {
+	var in GeminiResponse
+	if err := json.Unmarshal(geminiResp, &in); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse Gemini response", err)
+	}
+
+	if in.Error != nil {
+		return nil, NewConversionError("upstream_error", in.Error.Message, nil)
+	}
+	if len(in.Candidates) == 0 {
+		if in.PromptFeedback != nil && in.PromptFeedback.BlockReason != "" {
+			return nil, NewConversionError("upstream_error", fmt.Sprintf("prompt blocked by Gemini: %s", in.PromptFeedback.BlockReason), nil)
+		}
+		return nil, errors.New("no candidates in Gemini response")
+	}
+
+	candidate := in.Candidates[0]
+	var blocks []AnthropicContentBlock
+	toolCalls := 0
+	if candidate.Content != nil {
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				arguments, err := json.Marshal(part.FunctionCall.Args)
+				if err != nil || part.FunctionCall.Args == nil {
+					arguments = []byte("{}")
+				}
+				id := part.FunctionCall.ID
+				if id == "" {
+					id = fmt.Sprintf("call_%s_%d", in.ResponseID, toolCalls)
+				}
+				toolCalls++
+				blocks = append(blocks, AnthropicContentBlock{
+					Type:  "tool_use",
+					ID:    id,
+					Name:  part.FunctionCall.Name,
+					Input: json.RawMessage(arguments),
+				})
+
+			case part.Thought:
+				// 相邻的推理片段合并为一个 thinking 块
+				if n := len(blocks); n > 0 && blocks[n-1].Type == "thinking" {
+					blocks[n-1].Thinking += part.Text
+				} else if strings.TrimSpace(part.Text) != "" {
+					blocks = append(blocks, AnthropicContentBlock{
+						Type:      "thinking",
+						Thinking:  part.Text,
+						Signature: thinkingSignaturePlaceholder,
+					})
+				}
+
+			case part.Text != "":
+				if n := len(blocks); n > 0 && blocks[n-1].Type == "text" {
+					blocks[n-1].Text += part.Text
+				} else {
+					blocks = append(blocks, AnthropicContentBlock{
+						Type: "text",
+						Text: part.Text,
+					})
+				}
+			}
+		}
+	}
+
+	model := in.ModelVersion
+	if model == "" && ctx != nil {
+		model = ctx.Model
+	}
+	messageID := in.ResponseID
+	if messageID != "" && !strings.HasPrefix(messageID, "msg_") {
+		messageID = "msg_" + messageID
+	}
+
+	// 复用 Chat Completions 的 finish_reason 映射
+	aggregator := NewMessageAggregator(c.logger)
+	out := AnthropicResponse{
+		ID:         messageID,
+		Type:       "message",
+		Role:       "assistant",
+		Model:      model,
+		Content:    blocks,
+		StopReason: aggregator.mapFinishReason(geminiFinishReason(candidate.FinishReason, toolCalls > 0)),
+	}
+	if in.UsageMetadata != nil {
+		out.Usage = &AnthropicUsage{
+			InputTokens:  in.UsageMetadata.PromptTokenCount,
+			OutputTokens: in.UsageMetadata.CandidatesTokenCount + in.UsageMetadata.ThoughtsTokenCount,
+		}
+	}
+
+	result, err := json.Marshal(out)
+	if err != nil {
+		return nil, NewConversionError("marshal_error", "Failed to marshal Anthropic response", err)
+	}
+
+	if c.logger != nil {
+		c.logger.Debug("Gemini conversion completed", map[string]interface{}{
+			"finish_reason": candidate.FinishReason,
+			"blocks":        len(blocks),
+		})
+	}
+
+	return result, nil
+}
+
+// convertGeminiStreaming 转换已完整读取的 Gemini SSE 流，逐个事件交给 GeminiStreamConverter
+func (c *ResponseConverter) convertGeminiStreaming(geminiResp []byte, ctx *ConversionContext) ([]byte, error) {
+	converter := NewGeminiStreamConverter(c.logger, ctx)
+
+	var events []AnthropicSSEEvent
+	normalized := bytes.ReplaceAll(geminiResp, []byte("\r\n"), []byte("\n"))
+	for _, rawEvent := range bytes.Split(normalized, []byte("\n\n")) {
+		if len(bytes.TrimSpace(rawEvent)) == 0 {
+			continue
+		}
+		eventOut, err := converter.ProcessEvent(rawEvent)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, eventOut...)
+	}
+
+	finalEvents, err := converter.Finish()
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, finalEvents...)
+
+	return c.sseParser.BuildAnthropicSSEFromEvents(events), nil
+}
@@ -0,0 +1,227 @@
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/logger"
+)
+
+// GeminiStreamConverter Gemini streamGenerateContent (alt=sse) 增量流式转换器
+//
+// Gemini 的每个SSE事件都是一个完整的 GenerateContentResponse，携带本次新增的 parts。
+// 这里把每个 part 翻译成等价的 Chat Completions chunk，再交给 OpenAIStreamConverter 处理，
+// 从而复用内容块管理、thinking 占位签名和 TodoWrite 参数修复逻辑：
+//   - thought=true 的 text → delta.reasoning_content
+//   - text → delta.content
+//   - functionCall（一次性给出完整参数）→ delta.tool_calls，Gemini 不一定返回调用ID，缺失时生成
+//   - finishReason → finish_reason；usageMetadata 是累计值，只在流结束时取最后一次
+//   - error / promptFeedback.blockReason → 转换错误
+type GeminiStreamConverter struct {
+	logger *logger.Logger
+	inner  *OpenAIStreamConverter
+	ctx    *ConversionContext
+
+	responseID   string
+	model        string
+	toolCalls    int
+	finishReason string
+	usage        *GeminiUsageMetadata
+	received     bool
+}
+
+// NewGeminiStreamConverter 创建 Gemini 增量流式转换器
+func NewGeminiStreamConverter(logger *logger.Logger, ctx *ConversionContext) *GeminiStreamConverter {
+	return &GeminiStreamConverter{
+		logger: logger,
+		inner:  NewOpenAIStreamConverter(logger, ctx),
+		ctx:    ctx,
+	}
+}
+
+// ProcessEvent 处理一个上游SSE事件，返回需要立即发送给客户端的 Anthropic 事件
+func (s *GeminiStreamConverter) ProcessEvent(event []byte) ([]AnthropicSSEEvent, error) {
+	var events []AnthropicSSEEvent
+
+	for _, rawLine := range strings.Split(string(event), "\n") {
+		line := strings.TrimSpace(rawLine)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		dataContent := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if dataContent == "" || dataContent == "[DONE]" {
+			continue
+		}
+
+		var resp GeminiResponse
+		if err := json.Unmarshal([]byte(dataContent), &resp); err != nil {
+			if s.logger != nil {
+				s.logger.Debug("Failed to parse Gemini SSE data, skipping", map[string]interface{}{
+					"data":  dataContent,
+					"error": err.Error(),
+				})
+			}
+			continue
+		}
+
+		chunkEvents, err := s.processResponse(&resp, dataContent)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, chunkEvents...)
+	}
+
+	return events, nil
+}
+
+// Finish 上游流结束，补充 finish_reason 和 usage 后关闭所有内容块并输出 message_delta/message_stop
+func (s *GeminiStreamConverter) Finish() ([]AnthropicSSEEvent, error) {
+	var events []AnthropicSSEEvent
+	if s.received {
+		chunk := OpenAIStreamChunk{ID: s.responseID, Model: s.model}
+		if s.finishReason != "" {
+			chunk.Choices = []OpenAIStreamChoice{{FinishReason: geminiFinishReason(s.finishReason, s.toolCalls > 0)}}
+		}
+		if s.usage != nil {
+			chunk.Usage = &OpenAIUsage{
+				PromptTokens:     s.usage.PromptTokenCount,
+				CompletionTokens: s.usage.CandidatesTokenCount + s.usage.ThoughtsTokenCount,
+				TotalTokens:      s.usage.TotalTokenCount,
+			}
+		}
+		finalChunk, err := s.inner.ProcessChunk(chunk)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, finalChunk...)
+	}
+
+	finalEvents, err := s.inner.Finish()
+	if err != nil {
+		return nil, err
+	}
+	return append(events, finalEvents...), nil
+}
+
+// processResponse 将单个 Gemini 响应中的 parts 逐个翻译为 Chat Completions chunk
+func (s *GeminiStreamConverter) processResponse(resp *GeminiResponse, raw string) ([]AnthropicSSEEvent, error) {
+	if resp.Error != nil {
+		if s.logger != nil {
+			s.logger.Info("Found error in Gemini SSE stream", map[string]interface{}{
+				"error_line": raw,
+			})
+		}
+		return nil, NewConversionError("upstream_error", fmt.Sprintf("error found in stream: %s", resp.Error.Message), nil)
+	}
+	if len(resp.Candidates) == 0 && resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		return nil, NewConversionError("upstream_error", fmt.Sprintf("prompt blocked by Gemini: %s", resp.PromptFeedback.BlockReason), nil)
+	}
+
+	if s.responseID == "" {
+		s.responseID = resp.ResponseID
+	}
+	if s.model == "" {
+		s.model = resp.ModelVersion
+		if s.model == "" && s.ctx != nil {
+			s.model = s.ctx.Model
+		}
+	}
+	if resp.UsageMetadata != nil {
+		s.usage = resp.UsageMetadata
+	}
+
+	var events []AnthropicSSEEvent
+	if !s.received {
+		// 第一个事件立即输出 message_start
+		s.received = true
+		startEvents, err := s.emit(OpenAIMessage{})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, startEvents...)
+	}
+
+	if len(resp.Candidates) == 0 {
+		return events, nil
+	}
+	candidate := resp.Candidates[0]
+	if candidate.FinishReason != "" {
+		s.finishReason = candidate.FinishReason
+	}
+	if candidate.Content == nil {
+		return events, nil
+	}
+
+	for _, part := range candidate.Content.Parts {
+		var delta OpenAIMessage
+		switch {
+		case part.FunctionCall != nil:
+			arguments, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil || part.FunctionCall.Args == nil {
+				arguments = []byte("{}")
+			}
+			delta.ToolCalls = []OpenAIToolCall{{
+				Index: s.toolCalls,
+				ID:    s.toolCallID(part.FunctionCall.ID),
+				Type:  "function",
+				Function: OpenAIToolCallDetail{
+					Name:      part.FunctionCall.Name,
+					Arguments: string(arguments),
+				},
+			}}
+			s.toolCalls++
+		case part.Thought:
+			delta.ReasoningContent = part.Text
+		case part.Text != "":
+			delta.Content = part.Text
+		default:
+			continue
+		}
+
+		partEvents, err := s.emit(delta)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, partEvents...)
+	}
+
+	return events, nil
+}
+
+// emit 构造一个 Chat Completions chunk 并交给内部转换器；空 delta 只用于触发 message_start
+func (s *GeminiStreamConverter) emit(delta OpenAIMessage) ([]AnthropicSSEEvent, error) {
+	chunk := OpenAIStreamChunk{
+		ID:    s.responseID,
+		Model: s.model,
+	}
+	if delta.Content != nil || delta.ReasoningContent != "" || len(delta.ToolCalls) > 0 {
+		delta.Role = "assistant"
+		chunk.Choices = []OpenAIStreamChoice{{Delta: delta}}
+	}
+	return s.inner.ProcessChunk(chunk)
+}
+
+// toolCallID 返回工具调用ID；Gemini 未返回时基于 responseId 生成，保证同一会话中不重复
+func (s *GeminiStreamConverter) toolCallID(id string) string {
+	if id != "" {
+		return id
+	}
+	if s.responseID != "" {
+		return fmt.Sprintf("call_%s_%d", s.responseID, s.toolCalls)
+	}
+	return fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), s.toolCalls)
+}
+
+// geminiFinishReason 将 Gemini 的 finishReason 映射为 Chat Completions 的 finish_reason
+func geminiFinishReason(reason string, hasToolCalls bool) string {
+	if reason == "MAX_TOKENS" {
+		return "length"
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
@@ -0,0 +1,124 @@
+package conversion
+
+// Google Gemini generateContent / streamGenerateContent 结构定义
+
+// GeminiRequest generateContent 请求（模型名位于URL路径中，请求体不包含 model 字段）
+type GeminiRequest struct {
+	Contents          []GeminiContent         `json:"contents"`
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"` // 对应 Anthropic system
+	Tools             []GeminiTool            `json:"tools,omitempty"`
+	ToolConfig        *GeminiToolConfig       `json:"toolConfig,omitempty"`
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// GeminiContent 一轮对话内容
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"` // "user" | "model"
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart 内容片段：text / inlineData / functionCall / functionResponse，每个片段只设置其中一种
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`          // 为 true 时 text 是推理摘要
+	ThoughtSignature string                  `json:"thoughtSignature,omitempty"` // 仅响应中出现
+	InlineData       *GeminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiInlineData 内联二进制数据（base64）
+type GeminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// GeminiFunctionCall 模型发起的函数调用
+type GeminiFunctionCall struct {
+	ID   string                 `json:"id,omitempty"` // 部分模型会返回调用ID，没有时由转换器生成
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse 函数执行结果，通过 name 与调用对应
+type GeminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+// GeminiTool 工具定义
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiFunctionDeclaration 函数声明
+// 使用 parametersJsonSchema 而不是 parameters：后者只接受 OpenAPI 子集，
+// 会拒绝 Claude Code 工具定义中常见的 $schema、additionalProperties 等字段
+type GeminiFunctionDeclaration struct {
+	Name                 string                 `json:"name"`
+	Description          string                 `json:"description,omitempty"`
+	ParametersJsonSchema map[string]interface{} `json:"parametersJsonSchema,omitempty"`
+}
+
+// GeminiToolConfig 工具调用配置
+type GeminiToolConfig struct {
+	FunctionCallingConfig *GeminiFunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+// GeminiFunctionCallingConfig 函数调用模式
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"` // "AUTO" | "ANY" | "NONE"
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+// GeminiGenerationConfig 生成参数
+type GeminiGenerationConfig struct {
+	MaxOutputTokens *int                  `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64              `json:"temperature,omitempty"`
+	TopP            *float64              `json:"topP,omitempty"`
+	StopSequences   []string              `json:"stopSequences,omitempty"`
+	ThinkingConfig  *GeminiThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+// GeminiThinkingConfig 推理配置
+type GeminiThinkingConfig struct {
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"` // -1 表示由模型动态决定
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+// GeminiResponse generateContent 响应，也是 streamGenerateContent 每个SSE事件的结构
+type GeminiResponse struct {
+	Candidates     []GeminiCandidate     `json:"candidates"`
+	UsageMetadata  *GeminiUsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion   string                `json:"modelVersion,omitempty"`
+	ResponseID     string                `json:"responseId,omitempty"`
+	PromptFeedback *GeminiPromptFeedback `json:"promptFeedback,omitempty"`
+	Error          *GeminiError          `json:"error,omitempty"`
+}
+
+// GeminiCandidate 候选结果
+type GeminiCandidate struct {
+	Content      *GeminiContent `json:"content,omitempty"`
+	FinishReason string         `json:"finishReason,omitempty"` // "STOP" | "MAX_TOKENS" | "SAFETY" | ...
+	Index        int            `json:"index"`
+}
+
+// GeminiUsageMetadata 使用统计（流式响应中每个事件都是累计值）
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// GeminiPromptFeedback 输入被拦截时的反馈
+type GeminiPromptFeedback struct {
+	BlockReason string `json:"blockReason,omitempty"`
+}
+
+// GeminiError Google API 错误
+type GeminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
@@ -42,6 +42,7 @@ func (a *MessageAggregator) AggregateChunks(chunks []OpenAIStreamChunk) (*Aggreg
 		a.logger.Debug("Message aggregation completed", map[string]interface{}{
 			"chunk_count": len(chunks),
 			"text_length": len(aggregated.TextContent),
+			"thinking_length": len(aggregated.ThinkingContent),
 			"tool_calls": len(aggregated.ToolCalls),
 			"finish_reason": aggregated.FinishReason,
 		})
@@ -53,6 +54,11 @@ func (a *MessageAggregator) AggregateChunks(chunks []OpenAIStreamChunk) (*Aggreg
 // processChunk processes a single chunk and updates the aggregated message
 func (a *MessageAggregator) processChunk(chunk OpenAIStreamChunk, aggregated *AggregatedMessage, toolCallStates map[string]*AggregatedToolCall) error {
 	for _, choice := range chunk.Choices {
+		// Process reasoning content (reasoning_content / reasoning)
+		if reasoning := choice.Delta.GetReasoningText(); reasoning != "" {
+			aggregated.ThinkingContent += reasoning
+		}
+
 		// Process text content
 		if choice.Delta.Content != nil {
 			if contentStr, ok := choice.Delta.Content.(string); ok {
@@ -95,12 +101,20 @@ func (a *MessageAggregator) updateUsageInfo(aggregated *AggregatedMessage, usage
 			CompletionTokens: usage.CompletionTokens,
 			TotalTokens:      usage.TotalTokens,
 		}
+		if usage.PromptTokensDetails != nil {
+			details := *usage.PromptTokensDetails
+			aggregated.Usage.PromptTokensDetails = &details
+		}
 	} else {
 		// Accumulate usage info
 		// For prompt_tokens: use the latest non-zero value (usually consistent across chunks)
 		if usage.PromptTokens > 0 {
 			aggregated.Usage.PromptTokens = usage.PromptTokens
 		}
+		if usage.PromptTokensDetails != nil {
+			details := *usage.PromptTokensDetails
+			aggregated.Usage.PromptTokensDetails = &details
+		}
 		// For completion_tokens: accumulate (sum up incremental tokens)
 		aggregated.Usage.CompletionTokens += usage.CompletionTokens
 		// For total_tokens: use the latest non-zero value or calculate if needed
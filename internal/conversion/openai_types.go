@@ -31,6 +31,17 @@ type OpenAIMessage struct {
 	ToolCallID string `json:"tool_call_id,omitempty"`
 	// 仅 assistant 会用到
 	ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
+	// 推理内容（仅响应中出现）：DeepSeek/GLM/Qwen 使用 reasoning_content，OpenRouter 使用 reasoning
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	Reasoning        string `json:"reasoning,omitempty"`
+}
+
+// GetReasoningText 返回推理内容，兼容 reasoning_content 和 reasoning 两种字段名
+func (m *OpenAIMessage) GetReasoningText() string {
+	if m.ReasoningContent != "" {
+		return m.ReasoningContent
+	}
+	return m.Reasoning
 }
 
 // OpenAIMessageContent 复合内容：text / image_url
@@ -88,11 +99,33 @@ type OpenAIChoice struct {
 	Message      OpenAIMessage `json:"message"`
 }
 
-// OpenAIUsage 使用统计
+// OpenAIUsage 使用统计，prompt_tokens 含缓存读取的 token
 type OpenAIUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens        int                        `json:"prompt_tokens"`
+	CompletionTokens    int                        `json:"completion_tokens"`
+	TotalTokens         int                        `json:"total_tokens"`
+	PromptTokensDetails *OpenAIPromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+// OpenAIPromptTokensDetails 输入 token 明细
+type OpenAIPromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// toAnthropicUsage 转换为 Anthropic usage：缓存读取的 token 从 input_tokens 中拆出到 cache_read_input_tokens
+func (u *OpenAIUsage) toAnthropicUsage() *AnthropicUsage {
+	usage := &AnthropicUsage{
+		InputTokens:  u.PromptTokens,
+		OutputTokens: u.CompletionTokens,
+	}
+	if u.PromptTokensDetails != nil && u.PromptTokensDetails.CachedTokens > 0 {
+		usage.CacheReadInputTokens = u.PromptTokensDetails.CachedTokens
+		usage.InputTokens -= usage.CacheReadInputTokens
+		if usage.InputTokens < 0 {
+			usage.InputTokens = 0
+		}
+	}
+	return usage
 }
 
 // OpenAIStreamChunk OpenAI 流式片段（SSE 的 delta 合并结果；这里假定你已收集完所有 chunk）
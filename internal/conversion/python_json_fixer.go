@@ -550,4 +550,18 @@ func (f *PythonJSONFixer) ShouldApplyFix(toolName string, content string) bool {
 	}
 	
 	return false
-}
\ No newline at end of file
+}
+
+// IsTargetTool checks if the tool is configured for Python JSON fixing,
+// in which case streaming converters buffer its arguments until the tool call completes
+func (f *PythonJSONFixer) IsTargetTool(toolName string) bool {
+	if !f.config.Enabled {
+		return false
+	}
+	for _, targetTool := range f.config.TargetTools {
+		if targetTool == toolName {
+			return true
+		}
+	}
+	return false
+}
@@ -24,6 +24,27 @@ func NewRequestConverter(logger *logger.Logger) *RequestConverter {
 
 // Convert 转换 Anthropic 请求为 OpenAI 格式 - 基于参考实现
 func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointInfo) ([]byte, *ConversionContext, error) {
+	out, ctx, err := c.buildOpenAIRequest(anthropicReq, endpointInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 序列化结果
+	result, err := json.Marshal(out)
+	if err != nil {
+		return nil, nil, NewConversionError("marshal_error", "Failed to marshal OpenAI request", err)
+	}
+
+	if c.logger != nil {
+		c.logger.Debug("Request conversion completed")
+	}
+
+	return result, ctx, nil
+}
+
+// buildOpenAIRequest 将 Anthropic 请求转换为 OpenAI Chat Completions 请求结构
+// Responses API 的请求转换也基于该结果进行二次映射
+func (c *RequestConverter) buildOpenAIRequest(anthropicReq []byte, endpointInfo *EndpointInfo) (*OpenAIRequest, *ConversionContext, error) {
 	// 解析 Anthropic 请求
 	var anthReq AnthropicRequest
 	if err := json.Unmarshal(anthropicReq, &anthReq); err != nil {
@@ -331,17 +352,7 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 		}
 	}
 
-	// 序列化结果
-	result, err := json.Marshal(out)
-	if err != nil {
-		return nil, nil, NewConversionError("marshal_error", "Failed to marshal OpenAI request", err)
-	}
-
-	if c.logger != nil {
-		c.logger.Debug("Request conversion completed")
-	}
-
-	return result, ctx, nil
+	return &out, ctx, nil
 }
 
 // boolPtr 返回bool指针
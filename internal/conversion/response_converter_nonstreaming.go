@@ -24,6 +24,15 @@ func (c *ResponseConverter) convertNonStreamingResponse(openaiResp []byte, ctx *
 	msg := choice.Message
 	var blocks []AnthropicContentBlock
 
+	// 推理内容 -> thinking 块（放在文本之前，与 Anthropic 原生响应一致）
+	if reasoning := msg.GetReasoningText(); strings.TrimSpace(reasoning) != "" {
+		blocks = append(blocks, AnthropicContentBlock{
+			Type:      "thinking",
+			Thinking:  reasoning,
+			Signature: thinkingSignaturePlaceholder,
+		})
+	}
+
 	// 文本
 	switch ct := msg.Content.(type) {
 	case string:
@@ -81,10 +90,7 @@ func (c *ResponseConverter) convertNonStreamingResponse(openaiResp []byte, ctx *
 		StopReason: stopReason,
 	}
 	if in.Usage != nil {
-		out.Usage = &AnthropicUsage{
-			InputTokens:  in.Usage.PromptTokens,
-			OutputTokens: in.Usage.CompletionTokens,
-		}
+		out.Usage = in.Usage.toAnthropicUsage()
 	}
 
 	// 序列化结果
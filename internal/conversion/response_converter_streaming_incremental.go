@@ -0,0 +1,548 @@
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"claude-code-companion/internal/logger"
+)
+
+// OpenAIStreamConverter 增量流式转换器：逐个接收 OpenAI chunk，立即产生对应的 Anthropic SSE 事件
+//
+// 与 convertStreamingResponseRefactored（先聚合完整响应再转换）不同，这里每个 chunk 到达就输出事件：
+//   - 第一个 chunk 到达时输出 message_start
+//   - 推理片段（reasoning_content/reasoning）输出 thinking_delta，thinking 块结束时补占位签名
+//   - 文本片段直接输出 text_delta
+//   - 工具调用参数片段通过 SimpleJSONBuffer 输出 input_json_delta；
+//     需要 Python JSON 修复的工具（如 TodoWrite）参数会缓冲到工具调用结束，修复后一次性输出
+//   - 上游结束时（Finish）关闭内容块并输出 message_delta/message_stop
+//
+// Anthropic 的内容块必须依次开始和结束，因此新的内容块开始时会关闭上一个内容块。
+// 上游可能交错发送多个工具调用的参数：当前工具调用的参数还不是完整的JSON时，其他工具调用的片段
+// 先缓冲在各自的状态中，等当前工具调用完成后（或上游结束时）再一次性输出；
+// 这期间到达的文本和推理片段同样按顺序缓冲，当前工具调用的参数完整后再输出；
+// 已经关闭的工具调用仍然收到参数片段时转换失败，不会丢弃数据。
+type OpenAIStreamConverter struct {
+	logger      *logger.Logger
+	ctx         *ConversionContext
+	aggregator  *MessageAggregator // 复用 usage 累加和 finish_reason 映射逻辑
+	pythonFixer *PythonJSONFixer
+
+	message    *AggregatedMessage           // 已收到的消息元数据（ID、Model、FinishReason、Usage）
+	started    bool                         // 是否已输出 message_start
+	finished   bool                         // 是否已输出 message_stop
+	chunkCount int                          // 已处理的 chunk 数量
+	nextIndex  int                          // 下一个内容块的 index
+	openBlock  *streamContentBlock          // 当前未关闭的内容块
+	toolCalls  map[int]*streamToolCallState // key: OpenAI tool_call index
+	pingSent   bool
+
+	pendingDeltas []pendingBlockDelta // 工具调用参数不完整时缓冲的文本/推理片段
+}
+
+// pendingBlockDelta 等待输出的文本或推理片段
+type pendingBlockDelta struct {
+	blockType string
+	delta     *AnthropicContentBlock
+}
+
+// streamContentBlock 当前打开的内容块
+type streamContentBlock struct {
+	blockType string // "thinking" | "text" | "tool_use"
+	index     int
+	toolCall  *streamToolCallState
+}
+
+// streamToolCallState 单个工具调用的增量状态
+type streamToolCallState struct {
+	id              string
+	name            string
+	buffer          *SimpleJSONBuffer
+	bufferUntilStop bool // 参数需要在工具调用结束时修复后一次性输出
+	started         bool
+	stopped         bool
+}
+
+// NewOpenAIStreamConverter 创建 OpenAI 增量流式转换器
+func NewOpenAIStreamConverter(logger *logger.Logger, ctx *ConversionContext) *OpenAIStreamConverter {
+	return &OpenAIStreamConverter{
+		logger:      logger,
+		ctx:         ctx,
+		aggregator:  NewMessageAggregator(logger),
+		pythonFixer: NewPythonJSONFixer(logger),
+		message:     &AggregatedMessage{},
+		toolCalls:   make(map[int]*streamToolCallState),
+	}
+}
+
+// ProcessEvent 处理一个上游SSE事件，返回需要立即发送给客户端的 Anthropic 事件
+func (s *OpenAIStreamConverter) ProcessEvent(event []byte) ([]AnthropicSSEEvent, error) {
+	var events []AnthropicSSEEvent
+
+	for _, rawLine := range strings.Split(string(event), "\n") {
+		line := strings.TrimSpace(rawLine)
+
+		// 跳过空行和注释行
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "data:") {
+			// 检查非标准行是否包含错误信息
+			if strings.HasPrefix(line, "{") && strings.Contains(line, "error") {
+				if err := s.checkErrorPayload(line); err != nil {
+					return events, err
+				}
+			}
+			// 其他 SSE 字段 (event:, id:, retry:) 在 OpenAI 流中不常用，继续忽略
+			continue
+		}
+
+		dataContent := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		// [DONE] 标记由调用方在流结束时通过 Finish 处理
+		if dataContent == "" || dataContent == "[DONE]" {
+			continue
+		}
+
+		if strings.Contains(dataContent, "\"error\"") {
+			if err := s.checkErrorPayload(dataContent); err != nil {
+				return events, err
+			}
+		}
+
+		chunk, ok := s.parseChunk(dataContent)
+		if !ok {
+			continue
+		}
+
+		chunkEvents, err := s.ProcessChunk(chunk)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, chunkEvents...)
+	}
+
+	return events, nil
+}
+
+// ProcessChunk 处理单个 OpenAI chunk
+func (s *OpenAIStreamConverter) ProcessChunk(chunk OpenAIStreamChunk) ([]AnthropicSSEEvent, error) {
+	if s.finished {
+		return nil, nil
+	}
+
+	var events []AnthropicSSEEvent
+	s.chunkCount++
+
+	if !s.started {
+		if s.message.ID == "" {
+			s.message.ID = chunk.ID
+		}
+		if s.message.Model == "" {
+			s.message.Model = chunk.Model
+		}
+		events = append(events, s.messageStartEvent())
+		s.started = true
+	}
+
+	for _, choice := range chunk.Choices {
+		// 推理内容（DeepSeek/GLM/Qwen 的 reasoning_content，OpenRouter 的 reasoning）
+		if reasoning := choice.Delta.GetReasoningText(); reasoning != "" {
+			events = append(events, s.appendThinking(reasoning)...)
+		}
+
+		// 文本内容
+		if contentStr, ok := choice.Delta.Content.(string); ok && contentStr != "" {
+			events = append(events, s.appendText(contentStr)...)
+		}
+
+		// 工具调用
+		for _, toolCall := range choice.Delta.ToolCalls {
+			toolEvents, err := s.appendToolCall(toolCall)
+			if err != nil {
+				return events, err
+			}
+			events = append(events, toolEvents...)
+		}
+
+		// finish_reason（最后一个非空值生效）
+		if choice.FinishReason != "" {
+			s.message.FinishReason = choice.FinishReason
+		}
+
+		// 部分 API 把 usage 放在 choice 中
+		if choice.Usage != nil {
+			s.aggregator.updateUsageInfo(s.message, choice.Usage)
+		}
+	}
+
+	if chunk.Usage != nil {
+		s.aggregator.updateUsageInfo(s.message, chunk.Usage)
+	}
+
+	return events, nil
+}
+
+// Finish 上游流结束，关闭所有内容块并输出 message_delta/message_stop
+func (s *OpenAIStreamConverter) Finish() ([]AnthropicSSEEvent, error) {
+	if s.finished {
+		return nil, nil
+	}
+	if !s.started {
+		return nil, NewConversionError("empty_stream", "No valid chunks found in SSE stream", nil)
+	}
+
+	var events []AnthropicSSEEvent
+	events = append(events, s.closeOpenBlock()...)
+	events = append(events, s.flushPendingDeltas()...)
+
+	// 一直没有收到工具名称、或因交错发送一直缓冲的工具调用，在结束时按 index 顺序补发
+	var pending []int
+	for index, state := range s.toolCalls {
+		if !state.started {
+			pending = append(pending, index)
+		}
+	}
+	sort.Ints(pending)
+	for _, index := range pending {
+		state := s.toolCalls[index]
+		if s.logger != nil {
+			s.logger.Debug("Emitting buffered tool call at end of stream", map[string]interface{}{
+				"tool_id": state.id,
+				"index":   index,
+			})
+		}
+		events = append(events, s.startToolBlock(state)...)
+		events = append(events, s.closeOpenBlock()...)
+	}
+
+	messageDelta := &AnthropicMessageDelta{
+		Type: "message_delta",
+		Delta: &AnthropicMessageDeltaContent{
+			StopReason: s.aggregator.mapFinishReason(s.message.FinishReason),
+		},
+	}
+	if s.message.Usage != nil {
+		messageDelta.Usage = s.message.Usage.toAnthropicUsage()
+	}
+	events = append(events, AnthropicSSEEvent{Type: "message_delta", Data: messageDelta})
+	events = append(events, AnthropicSSEEvent{Type: "message_stop", Data: &AnthropicMessageStop{Type: "message_stop"}})
+	s.finished = true
+
+	if s.logger != nil {
+		s.logger.Debug("Incremental streaming conversion completed", map[string]interface{}{
+			"chunk_count":   s.chunkCount,
+			"block_count":   s.nextIndex,
+			"tool_calls":    len(s.toolCalls),
+			"finish_reason": s.message.FinishReason,
+		})
+	}
+
+	return events, nil
+}
+
+// checkErrorPayload 检查上游是否在流中返回了错误对象
+func (s *OpenAIStreamConverter) checkErrorPayload(data string) error {
+	var errorObj map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &errorObj); err != nil {
+		return nil
+	}
+	if errorInfo, exists := errorObj["error"]; exists && errorInfo != nil {
+		if s.logger != nil {
+			s.logger.Info("Found error in SSE stream", map[string]interface{}{
+				"error_line": data,
+				"error_info": errorInfo,
+			})
+		}
+		return NewConversionError("upstream_error", fmt.Sprintf("error found in stream: %s", data), nil)
+	}
+	return nil
+}
+
+// parseChunk 解析单个 chunk，失败时尝试 Python JSON 修复
+func (s *OpenAIStreamConverter) parseChunk(dataContent string) (OpenAIStreamChunk, bool) {
+	var chunk OpenAIStreamChunk
+	err := json.Unmarshal([]byte(dataContent), &chunk)
+	if err == nil {
+		return chunk, true
+	}
+
+	if fixedData, wasFixed := s.pythonFixer.FixPythonStyleJSON(dataContent); wasFixed {
+		if fixErr := json.Unmarshal([]byte(fixedData), &chunk); fixErr == nil {
+			return chunk, true
+		}
+	}
+
+	if s.logger != nil {
+		s.logger.Debug("Failed to parse SSE data chunk, skipping", map[string]interface{}{
+			"data":  dataContent,
+			"error": err.Error(),
+		})
+	}
+	return chunk, false
+}
+
+// messageStartEvent 生成 message_start 事件（output_tokens 在 message_delta 中给出）
+func (s *OpenAIStreamConverter) messageStartEvent() AnthropicSSEEvent {
+	messageID := s.message.ID
+	if messageID != "" && !strings.HasPrefix(messageID, "msg_") {
+		messageID = "msg_" + messageID
+	}
+	s.message.ID = messageID
+
+	return AnthropicSSEEvent{
+		Type: "message_start",
+		Data: &AnthropicMessageStart{
+			Type: "message_start",
+			Message: &AnthropicResponse{
+				ID:      messageID,
+				Type:    "message",
+				Role:    "assistant",
+				Model:   s.message.Model,
+				Content: []AnthropicContentBlock{},
+				Usage: &AnthropicUsage{
+					InputTokens:  0,
+					OutputTokens: 0,
+				},
+			},
+		},
+	}
+}
+
+// appendText 输出文本片段，必要时开始新的文本块
+func (s *OpenAIStreamConverter) appendText(text string) []AnthropicSSEEvent {
+	return s.appendBlockDelta("text", &AnthropicContentBlock{
+		Type: "text_delta",
+		Text: text,
+	})
+}
+
+// appendThinking 输出推理片段（reasoning_content/reasoning），必要时开始新的 thinking 块
+func (s *OpenAIStreamConverter) appendThinking(thinking string) []AnthropicSSEEvent {
+	return s.appendBlockDelta("thinking", &AnthropicContentBlock{
+		Type:     "thinking_delta",
+		Thinking: thinking,
+	})
+}
+
+// appendBlockDelta 向指定类型的内容块追加增量，当前打开的块类型不同时先关闭它再开始新块；
+// 当前工具调用的参数还不是完整的JSON时先缓冲，避免关闭它之后再收到它的片段
+func (s *OpenAIStreamConverter) appendBlockDelta(blockType string, delta *AnthropicContentBlock) []AnthropicSSEEvent {
+	open := s.openBlock
+	if len(s.pendingDeltas) > 0 || (open != nil && open.toolCall != nil && !open.toolCall.argumentsComplete()) {
+		s.pendingDeltas = append(s.pendingDeltas, pendingBlockDelta{blockType: blockType, delta: delta})
+		return nil
+	}
+
+	var events []AnthropicSSEEvent
+
+	if s.openBlock == nil || s.openBlock.blockType != blockType {
+		events = append(events, s.closeOpenBlock()...)
+		s.openBlock = &streamContentBlock{blockType: blockType, index: s.nextIndex}
+		s.nextIndex++
+		events = append(events, AnthropicSSEEvent{
+			Type: "content_block_start",
+			Data: &AnthropicContentBlockStart{
+				Type:         "content_block_start",
+				Index:        s.openBlock.index,
+				ContentBlock: &AnthropicContentBlockForStart{Type: blockType},
+			},
+		})
+		events = append(events, s.pingAfterFirstBlock()...)
+	}
+
+	events = append(events, AnthropicSSEEvent{
+		Type: "content_block_delta",
+		Data: &AnthropicContentBlockDelta{
+			Type:  "content_block_delta",
+			Index: s.openBlock.index,
+			Delta: delta,
+		},
+	})
+	return events
+}
+
+// appendToolCall 处理工具调用增量
+func (s *OpenAIStreamConverter) appendToolCall(toolCall OpenAIToolCall) ([]AnthropicSSEEvent, error) {
+	state, exists := s.toolCalls[toolCall.Index]
+	if !exists {
+		toolID := toolCall.ID
+		if toolID == "" {
+			toolID = fmt.Sprintf("tool_call_%d", toolCall.Index)
+		}
+		state = &streamToolCallState{
+			id:     toolID,
+			buffer: NewSimpleJSONBufferWithFixer(s.logger),
+		}
+		s.toolCalls[toolCall.Index] = state
+	}
+
+	if toolCall.Function.Name != "" {
+		state.name = toolCall.Function.Name
+		state.buffer.SetToolName(state.name)
+	}
+
+	if state.stopped {
+		if toolCall.Function.Arguments == "" {
+			return nil, nil
+		}
+		// 工具块已经发送 content_block_stop，无法再追加参数
+		return nil, NewConversionError("tool_call_interleaved",
+			fmt.Sprintf("received arguments for tool call %s after its content block was closed", state.id), nil)
+	}
+	state.buffer.AppendFragment(toolCall.Function.Arguments)
+
+	var events []AnthropicSSEEvent
+	if !state.started {
+		// content_block_start 需要工具名称，收到名称之前先缓冲参数；
+		// 另一个工具调用的参数还不完整时（上游交错发送）也先缓冲，避免关闭它之后再收到它的片段
+		if state.name == "" {
+			return nil, nil
+		}
+		if open := s.openBlock; open != nil && open.toolCall != nil && !open.toolCall.argumentsComplete() {
+			return nil, nil
+		}
+		events = append(events, s.startToolBlock(state)...)
+	}
+
+	if !state.bufferUntilStop {
+		if increment, hasNew := state.buffer.GetIncrementalOutput(); hasNew {
+			events = append(events, s.inputJSONDelta(increment))
+		}
+	}
+	if state.argumentsComplete() {
+		events = append(events, s.flushPendingDeltas()...)
+	}
+	return events, nil
+}
+
+// flushPendingDeltas 按到达顺序输出工具调用参数不完整期间缓冲的文本/推理片段
+func (s *OpenAIStreamConverter) flushPendingDeltas() []AnthropicSSEEvent {
+	pending := s.pendingDeltas
+	s.pendingDeltas = nil
+
+	var events []AnthropicSSEEvent
+	for _, item := range pending {
+		events = append(events, s.appendBlockDelta(item.blockType, item.delta)...)
+	}
+	return events
+}
+
+// argumentsComplete 工具调用已收到的参数是否已经是完整的JSON
+func (s *streamToolCallState) argumentsComplete() bool {
+	content := s.buffer.GetBufferedContent()
+	if s.bufferUntilStop {
+		content = s.buffer.GetFixedBufferedContent()
+	}
+	return json.Valid([]byte(content))
+}
+
+// startToolBlock 关闭当前内容块并开始新的 tool_use 块
+func (s *OpenAIStreamConverter) startToolBlock(state *streamToolCallState) []AnthropicSSEEvent {
+	events := s.closeOpenBlock()
+
+	state.started = true
+	state.bufferUntilStop = s.pythonFixer.IsTargetTool(state.name)
+	s.openBlock = &streamContentBlock{blockType: "tool_use", index: s.nextIndex, toolCall: state}
+	s.nextIndex++
+
+	events = append(events, AnthropicSSEEvent{
+		Type: "content_block_start",
+		Data: &AnthropicContentBlockStart{
+			Type:  "content_block_start",
+			Index: s.openBlock.index,
+			ContentBlock: &AnthropicContentBlockForStart{
+				Type:  "tool_use",
+				ID:    state.id,
+				Name:  state.name,
+				Input: json.RawMessage("{}"), // 参数通过 input_json_delta 流式发送
+			},
+		},
+	})
+	events = append(events, s.pingAfterFirstBlock()...)
+	return events
+}
+
+// closeOpenBlock 关闭当前内容块，输出缓冲中剩余的工具参数
+func (s *OpenAIStreamConverter) closeOpenBlock() []AnthropicSSEEvent {
+	if s.openBlock == nil {
+		return nil
+	}
+
+	var events []AnthropicSSEEvent
+	if s.openBlock.blockType == "thinking" {
+		// 上游没有签名，thinking 块结束前补一个占位签名
+		events = append(events, AnthropicSSEEvent{
+			Type: "content_block_delta",
+			Data: &AnthropicContentBlockDelta{
+				Type:  "content_block_delta",
+				Index: s.openBlock.index,
+				Delta: &AnthropicContentBlock{
+					Type:      "signature_delta",
+					Signature: thinkingSignaturePlaceholder,
+				},
+			},
+		})
+	}
+	if state := s.openBlock.toolCall; state != nil {
+		if state.bufferUntilStop {
+			original := state.buffer.GetBufferedContent()
+			fixed := state.buffer.GetFixedBufferedContent()
+			if fixed != original && s.logger != nil {
+				s.logger.Debug("Applied Python JSON fix to tool arguments", map[string]interface{}{
+					"tool_name": state.name,
+					"tool_id":   state.id,
+					"original":  original,
+					"fixed":     fixed,
+				})
+			}
+			if fixed != "" {
+				events = append(events, s.inputJSONDelta(fixed))
+			}
+		} else if increment, hasNew := state.buffer.GetIncrementalOutput(); hasNew {
+			events = append(events, s.inputJSONDelta(increment))
+		}
+		state.stopped = true
+	}
+
+	events = append(events, AnthropicSSEEvent{
+		Type: "content_block_stop",
+		Data: &AnthropicContentBlockStop{
+			Type:  "content_block_stop",
+			Index: s.openBlock.index,
+		},
+	})
+	s.openBlock = nil
+	return events
+}
+
+// inputJSONDelta 生成当前工具块的 input_json_delta 事件
+func (s *OpenAIStreamConverter) inputJSONDelta(partialJSON string) AnthropicSSEEvent {
+	return AnthropicSSEEvent{
+		Type: "content_block_delta",
+		Data: &AnthropicContentBlockDelta{
+			Type:  "content_block_delta",
+			Index: s.openBlock.index,
+			Delta: &AnthropicContentBlock{
+				Type:        "input_json_delta",
+				PartialJSON: partialJSON,
+			},
+		},
+	}
+}
+
+// pingAfterFirstBlock 在第一个 content_block_start 之后发送 ping（与 UnifiedConverter 保持一致）
+func (s *OpenAIStreamConverter) pingAfterFirstBlock() []AnthropicSSEEvent {
+	if s.pingSent {
+		return nil
+	}
+	s.pingSent = true
+	return []AnthropicSSEEvent{{
+		Type: "ping",
+		Data: map[string]interface{}{
+			"type": "ping",
+		},
+	}}
+}
@@ -0,0 +1,353 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// collectEventTypes returns the event type sequence for assertions
+func collectEventTypes(events []AnthropicSSEEvent) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+// feedSSE feeds raw OpenAI SSE events one at a time and returns all produced events
+func feedSSE(t *testing.T, converter *OpenAIStreamConverter, rawEvents []string) []AnthropicSSEEvent {
+	var all []AnthropicSSEEvent
+	for _, raw := range rawEvents {
+		events, err := converter.ProcessEvent([]byte(raw))
+		if err != nil {
+			t.Fatalf("ProcessEvent failed: %v", err)
+		}
+		all = append(all, events...)
+	}
+	final, err := converter.Finish()
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	return append(all, final...)
+}
+
+func TestOpenAIStreamConverter_TextIsEmittedPerChunk(t *testing.T) {
+	converter := NewOpenAIStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai"})
+
+	// The first chunk must produce message_start and the first text delta immediately
+	events, err := converter.ProcessEvent([]byte(`data: {"id":"chatcmpl-1","model":"gpt-4","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}` + "\n\n"))
+	if err != nil {
+		t.Fatalf("ProcessEvent failed: %v", err)
+	}
+	got := strings.Join(collectEventTypes(events), ",")
+	if got != "message_start,content_block_start,ping,content_block_delta" {
+		t.Fatalf("Unexpected events for first chunk: %s", got)
+	}
+
+	start := events[0].Data.(*AnthropicMessageStart)
+	if start.Message.ID != "msg_chatcmpl-1" || start.Message.Model != "gpt-4" {
+		t.Errorf("Unexpected message_start: id=%s model=%s", start.Message.ID, start.Message.Model)
+	}
+
+	events, err = converter.ProcessEvent([]byte(`data: {"id":"chatcmpl-1","model":"gpt-4","choices":[{"index":0,"delta":{"content":"lo"}}]}` + "\n\n"))
+	if err != nil {
+		t.Fatalf("ProcessEvent failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != "content_block_delta" {
+		t.Fatalf("Expected a single text delta, got %v", collectEventTypes(events))
+	}
+	if delta := events[0].Data.(*AnthropicContentBlockDelta).Delta; delta.Type != "text_delta" || delta.Text != "lo" {
+		t.Errorf("Unexpected delta: %+v", delta)
+	}
+
+	events, err = converter.ProcessEvent([]byte(`data: {"id":"chatcmpl-1","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"stop"}],"usage":{"prompt_tokens":7,"completion_tokens":2,"total_tokens":9}}` + "\n\ndata: [DONE]\n\n"))
+	if err != nil {
+		t.Fatalf("ProcessEvent failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Finish reason chunk should not emit events before Finish, got %v", collectEventTypes(events))
+	}
+
+	final, err := converter.Finish()
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	got = strings.Join(collectEventTypes(final), ",")
+	if got != "content_block_stop,message_delta,message_stop" {
+		t.Fatalf("Unexpected final events: %s", got)
+	}
+	messageDelta := final[1].Data.(*AnthropicMessageDelta)
+	if messageDelta.Delta.StopReason != "end_turn" {
+		t.Errorf("Expected stop_reason end_turn, got %s", messageDelta.Delta.StopReason)
+	}
+	if messageDelta.Usage == nil || messageDelta.Usage.InputTokens != 7 || messageDelta.Usage.OutputTokens != 2 {
+		t.Errorf("Unexpected usage: %+v", messageDelta.Usage)
+	}
+}
+
+func TestOpenAIStreamConverter_ToolCalls(t *testing.T) {
+	converter := NewOpenAIStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai"})
+
+	events := feedSSE(t, converter, []string{
+		`data: {"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{"content":"Let me check."}}]}` + "\n\n",
+		`data: {"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}` + "\n\n",
+		`data: {"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}` + "\n\n",
+		`data: {"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}` + "\n\n",
+		`data: {"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_b","type":"function","function":{"name":"get_time","arguments":"{}"}}]}}]}` + "\n\n",
+		`data: {"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n",
+		"data: [DONE]\n\n",
+	})
+
+	expected := []string{
+		"message_start",
+		"content_block_start", "ping", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}
+	if got := strings.Join(collectEventTypes(events), ","); got != strings.Join(expected, ",") {
+		t.Fatalf("Unexpected event sequence:\n got: %s\nwant: %s", got, strings.Join(expected, ","))
+	}
+
+	toolStart := events[5].Data.(*AnthropicContentBlockStart)
+	if toolStart.Index != 1 || toolStart.ContentBlock.ID != "call_a" || toolStart.ContentBlock.Name != "get_weather" {
+		t.Errorf("Unexpected tool block start: %+v", toolStart.ContentBlock)
+	}
+
+	var arguments strings.Builder
+	for _, event := range events[6:8] {
+		arguments.WriteString(event.Data.(*AnthropicContentBlockDelta).Delta.PartialJSON)
+	}
+	if arguments.String() != `{"city":"Paris"}` {
+		t.Errorf("Unexpected streamed arguments: %s", arguments.String())
+	}
+
+	if stopReason := events[12].Data.(*AnthropicMessageDelta).Delta.StopReason; stopReason != "tool_use" {
+		t.Errorf("Expected stop_reason tool_use, got %s", stopReason)
+	}
+}
+
+func TestOpenAIStreamConverter_PythonStyleArgumentsAreFixed(t *testing.T) {
+	converter := NewOpenAIStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai"})
+
+	events := feedSSE(t, converter, []string{
+		`data: {"id":"c2","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_t","type":"function","function":{"name":"TodoWrite","arguments":"{\"todos\": [{'content': 'Write tests', "}}]}}]}` + "\n\n",
+		`data: {"id":"c2","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"'status': 'pending', 'id': '1'}]}"}}]}}]}` + "\n\n",
+		`data: {"id":"c2","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n",
+		"data: [DONE]\n\n",
+	})
+
+	// TodoWrite arguments are buffered until the block closes, then emitted once after fixing
+	var deltas []string
+	for _, event := range events {
+		if event.Type == "content_block_delta" {
+			deltas = append(deltas, event.Data.(*AnthropicContentBlockDelta).Delta.PartialJSON)
+		}
+	}
+	if len(deltas) != 1 {
+		t.Fatalf("Expected a single buffered input_json_delta, got %d", len(deltas))
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(deltas[0]), &parsed); err != nil {
+		t.Fatalf("Fixed arguments are not valid JSON: %v (%s)", err, deltas[0])
+	}
+	if _, ok := parsed["todos"]; !ok {
+		t.Errorf("Fixed arguments lost the todos field: %s", deltas[0])
+	}
+}
+
+func TestOpenAIStreamConverter_ReasoningBecomesThinkingBlock(t *testing.T) {
+	converter := NewOpenAIStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai"})
+
+	events := feedSSE(t, converter, []string{
+		`data: {"id":"c3","model":"glm-4.6","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Thinking "}}]}` + "\n\n",
+		`data: {"id":"c3","model":"glm-4.6","choices":[{"index":0,"delta":{"reasoning_content":"hard."}}]}` + "\n\n",
+		`data: {"id":"c3","model":"glm-4.6","choices":[{"index":0,"delta":{"content":"Done"}}]}` + "\n\n",
+		`data: {"id":"c3","model":"glm-4.6","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}` + "\n\n",
+		"data: [DONE]\n\n",
+	})
+
+	expected := []string{
+		"message_start",
+		"content_block_start", "ping", "content_block_delta", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}
+	if got := strings.Join(collectEventTypes(events), ","); got != strings.Join(expected, ",") {
+		t.Fatalf("Unexpected event sequence:\n got: %s\nwant: %s", got, strings.Join(expected, ","))
+	}
+
+	if block := events[1].Data.(*AnthropicContentBlockStart).ContentBlock; block.Type != "thinking" {
+		t.Errorf("Expected first block to be thinking, got %s", block.Type)
+	}
+
+	// Each reasoning fragment is forwarded as its own thinking_delta
+	for i, want := range []string{"Thinking ", "hard."} {
+		delta := events[3+i].Data.(*AnthropicContentBlockDelta).Delta
+		if delta.Type != "thinking_delta" || delta.Thinking != want {
+			t.Errorf("Unexpected thinking delta %d: %+v", i, delta)
+		}
+	}
+
+	// The thinking block is closed with a placeholder signature before the text block starts
+	if delta := events[5].Data.(*AnthropicContentBlockDelta).Delta; delta.Type != "signature_delta" || delta.Signature != thinkingSignaturePlaceholder {
+		t.Errorf("Unexpected signature delta: %+v", delta)
+	}
+
+	startJSON, err := json.Marshal(events[1].Data)
+	if err != nil {
+		t.Fatalf("Failed to marshal content_block_start: %v", err)
+	}
+	if !strings.Contains(string(startJSON), `"content_block":{"thinking":"","signature":"","type":"thinking"}`) {
+		t.Errorf("Unexpected thinking content_block_start JSON: %s", startJSON)
+	}
+}
+
+func TestOpenAIStreamConverter_ErrorsAndEmptyStream(t *testing.T) {
+	converter := NewOpenAIStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai"})
+	if _, err := converter.ProcessEvent([]byte(`data: {"error":{"message":"rate limited","type":"rate_limit"}}` + "\n\n")); err == nil {
+		t.Error("Expected an error for an in-stream error payload")
+	}
+
+	empty := NewOpenAIStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai"})
+	if _, err := empty.Finish(); err == nil {
+		t.Error("Expected an error when finishing a stream without chunks")
+	}
+}
+
+func TestOpenAIStreamConverter_InterleavedToolCalls(t *testing.T) {
+	converter := NewOpenAIStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai"})
+
+	events := feedSSE(t, converter, []string{
+		`data: {"id":"c5","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"Read","arguments":"{\"file_path\":"}}]}}]}` + "\n\n",
+		`data: {"id":"c5","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_b","type":"function","function":{"name":"Grep","arguments":"{\"pattern\":"}}]}}]}` + "\n\n",
+		`data: {"id":"c5","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"a.go\"}"}}]}}]}` + "\n\n",
+		`data: {"id":"c5","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"arguments":"\"TODO\","}}]}}]}` + "\n\n",
+		`data: {"id":"c5","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":2,"id":"call_c","type":"function","function":{"name":"LS","arguments":"{\"path\":"}}]}}]}` + "\n\n",
+		`data: {"id":"c5","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"arguments":"\"path\":\"src\"}"}}]}}]}` + "\n\n",
+		`data: {"id":"c5","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":2,"function":{"arguments":"\".\"}"}}]}}]}` + "\n\n",
+		`data: {"id":"c5","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n",
+		"data: [DONE]\n\n",
+	})
+
+	// Collect tool blocks by content block index and concatenate their arguments
+	type toolBlock struct {
+		id        string
+		arguments strings.Builder
+		stopped   bool
+	}
+	blocks := make(map[int]*toolBlock)
+	open := -1
+	for _, event := range events {
+		switch data := event.Data.(type) {
+		case *AnthropicContentBlockStart:
+			if open != -1 {
+				t.Fatalf("content block %d started while block %d is still open", data.Index, open)
+			}
+			open = data.Index
+			blocks[data.Index] = &toolBlock{id: data.ContentBlock.ID}
+		case *AnthropicContentBlockDelta:
+			if data.Index != open {
+				t.Fatalf("delta for block %d while block %d is open", data.Index, open)
+			}
+			blocks[data.Index].arguments.WriteString(data.Delta.PartialJSON)
+		case *AnthropicContentBlockStop:
+			blocks[data.Index].stopped = true
+			open = -1
+		}
+	}
+
+	expected := map[string]string{
+		"call_a": `{"file_path":"a.go"}`,
+		"call_b": `{"pattern":"TODO","path":"src"}`,
+		"call_c": `{"path":"."}`,
+	}
+	if len(blocks) != len(expected) {
+		t.Fatalf("Expected %d tool blocks, got %d", len(expected), len(blocks))
+	}
+	for _, block := range blocks {
+		want, ok := expected[block.id]
+		if !ok {
+			t.Errorf("Unexpected tool block %s", block.id)
+			continue
+		}
+		if !block.stopped {
+			t.Errorf("Tool block %s was not closed", block.id)
+		}
+		if got := block.arguments.String(); got != want {
+			t.Errorf("Tool call %s arguments: got %s, want %s", block.id, got, want)
+		}
+	}
+}
+
+func TestOpenAIStreamConverter_TextBetweenArgumentFragments(t *testing.T) {
+	converter := NewOpenAIStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai"})
+
+	events := feedSSE(t, converter, []string{
+		`data: {"id":"c7","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"Read","arguments":"{\"file_path\":"}}]}}]}` + "\n\n",
+		`data: {"id":"c7","model":"gpt-4","choices":[{"index":0,"delta":{"reasoning_content":"Reading the file. "}}]}` + "\n\n",
+		`data: {"id":"c7","model":"gpt-4","choices":[{"index":0,"delta":{"content":"Let me check."}}]}` + "\n\n",
+		`data: {"id":"c7","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"a.go\"}"}}]}}]}` + "\n\n",
+		`data: {"id":"c7","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n",
+		"data: [DONE]\n\n",
+	})
+
+	// The tool block stays open until its arguments are complete, then the buffered text follows in order
+	var blockTypes []string
+	var arguments, thinking, text strings.Builder
+	open := -1
+	for _, event := range events {
+		switch data := event.Data.(type) {
+		case *AnthropicContentBlockStart:
+			if open != -1 {
+				t.Fatalf("content block %d started while block %d is still open", data.Index, open)
+			}
+			open = data.Index
+			blockTypes = append(blockTypes, data.ContentBlock.Type)
+		case *AnthropicContentBlockDelta:
+			if data.Index != open {
+				t.Fatalf("delta for block %d while block %d is open", data.Index, open)
+			}
+			arguments.WriteString(data.Delta.PartialJSON)
+			thinking.WriteString(data.Delta.Thinking)
+			text.WriteString(data.Delta.Text)
+		case *AnthropicContentBlockStop:
+			open = -1
+		}
+	}
+
+	if got := strings.Join(blockTypes, ","); got != "tool_use,thinking,text" {
+		t.Errorf("Expected blocks tool_use,thinking,text, got %s", got)
+	}
+	if got := arguments.String(); got != `{"file_path":"a.go"}` {
+		t.Errorf("Tool arguments: got %s", got)
+	}
+	if got := thinking.String(); got != "Reading the file. " {
+		t.Errorf("Thinking: got %q", got)
+	}
+	if got := text.String(); got != "Let me check." {
+		t.Errorf("Text: got %q", got)
+	}
+}
+
+func TestOpenAIStreamConverter_ArgumentsAfterClosedToolBlockFail(t *testing.T) {
+	converter := NewOpenAIStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai"})
+
+	rawEvents := []string{
+		`data: {"id":"c6","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"Read","arguments":"{}"}}]}}]}` + "\n\n",
+		`data: {"id":"c6","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_b","type":"function","function":{"name":"LS","arguments":"{}"}}]}}]}` + "\n\n",
+	}
+	for _, raw := range rawEvents {
+		if _, err := converter.ProcessEvent([]byte(raw)); err != nil {
+			t.Fatalf("ProcessEvent failed: %v", err)
+		}
+	}
+
+	// call_a is already closed, so a later fragment must not be dropped silently
+	_, err := converter.ProcessEvent([]byte(`data: {"id":"c6","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"x\":1}"}}]}}]}` + "\n\n"))
+	if err == nil {
+		t.Fatal("Expected an error for arguments received after the tool block was closed")
+	}
+}
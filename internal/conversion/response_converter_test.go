@@ -165,4 +165,41 @@ func TestConvertOpenAIResponseToAnthropic_WithToolCalls(t *testing.T) {
 	if anthResp.StopReason != "tool_use" {
 		t.Errorf("Expected stop_reason 'tool_use', got '%s'", anthResp.StopReason)
 	}
-}
\ No newline at end of file
+}
+func TestConvertOpenAIResponseToAnthropic_ReasoningContent(t *testing.T) {
+	converter := NewResponseConverter(getTestLogger())
+
+	oaResp := `{"id":"chatcmpl-r1","model":"deepseek-reasoner","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","reasoning_content":"The user greets me.","content":"Hello!"}}]}`
+
+	result, err := converter.convertNonStreamingResponse([]byte(oaResp), &ConversionContext{})
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	var anthResp AnthropicResponse
+	if err := json.Unmarshal(result, &anthResp); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+
+	// 验证 thinking 块位于文本之前
+	if len(anthResp.Content) != 2 {
+		t.Fatalf("Expected 2 content blocks, got %d", len(anthResp.Content))
+	}
+
+	thinking := anthResp.Content[0]
+	if thinking.Type != "thinking" {
+		t.Errorf("Expected first block type 'thinking', got '%s'", thinking.Type)
+	}
+
+	if thinking.Thinking != "The user greets me." {
+		t.Errorf("Expected thinking 'The user greets me.', got '%s'", thinking.Thinking)
+	}
+
+	if thinking.Signature != thinkingSignaturePlaceholder {
+		t.Errorf("Expected placeholder signature, got '%s'", thinking.Signature)
+	}
+
+	if anthResp.Content[1].Type != "text" || anthResp.Content[1].Text != "Hello!" {
+		t.Errorf("Expected text block 'Hello!', got %+v", anthResp.Content[1])
+	}
+}
@@ -0,0 +1,313 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConvertAnthropicRequestToResponses(t *testing.T) {
+	converter := NewRequestConverter(getTestLogger())
+
+	anthReq := `{
+		"model": "gpt-5",
+		"max_tokens": 2048,
+		"stream": true,
+		"system": [{"type": "text", "text": "You are helpful."}],
+		"thinking": {"type": "enabled", "budget_tokens": 20000},
+		"tools": [{"name": "get_weather", "description": "Get weather", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "tool", "name": "get_weather"},
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "Checking."},
+				{"type": "tool_use", "id": "call_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "call_1", "content": "Sunny"}
+			]}
+		]
+	}`
+
+	result, ctx, err := converter.ConvertToResponses([]byte(anthReq), &EndpointInfo{Type: "openai_responses"})
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+	if ctx == nil || !ctx.IsStreaming {
+		t.Error("Expected streaming conversion context")
+	}
+
+	var req ResponsesRequest
+	if err := json.Unmarshal(result, &req); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+
+	if req.Instructions != "You are helpful." {
+		t.Errorf("Expected system prompt as instructions, got '%s'", req.Instructions)
+	}
+
+	if req.MaxOutputTokens == nil || *req.MaxOutputTokens != 2048 {
+		t.Errorf("Expected max_output_tokens 2048, got %v", req.MaxOutputTokens)
+	}
+
+	if req.Reasoning == nil || req.Reasoning.Effort != "high" || req.Reasoning.Summary != "auto" {
+		t.Errorf("Unexpected reasoning config: %+v", req.Reasoning)
+	}
+
+	if len(req.Tools) != 1 || req.Tools[0].Name != "get_weather" || req.Tools[0].Type != "function" {
+		t.Errorf("Unexpected tools: %+v", req.Tools)
+	}
+
+	toolChoice, ok := req.ToolChoice.(map[string]interface{})
+	if !ok || toolChoice["type"] != "function" || toolChoice["name"] != "get_weather" {
+		t.Errorf("Unexpected tool_choice: %v", req.ToolChoice)
+	}
+
+	// 验证 input 项顺序：user message, assistant message, function_call, function_call_output
+	expectedTypes := []string{"message", "message", "function_call", "function_call_output"}
+	if len(req.Input) != len(expectedTypes) {
+		t.Fatalf("Expected %d input items, got %d: %s", len(expectedTypes), len(req.Input), result)
+	}
+	for i, expectedType := range expectedTypes {
+		if req.Input[i].Type != expectedType {
+			t.Errorf("Input %d: expected type '%s', got '%s'", i, expectedType, req.Input[i].Type)
+		}
+	}
+
+	if req.Input[0].Content[0].Type != "input_text" || req.Input[0].Content[0].Text != "Weather in Paris?" {
+		t.Errorf("Unexpected user content: %+v", req.Input[0].Content)
+	}
+
+	if req.Input[1].Content[0].Type != "output_text" || req.Input[1].Content[0].Text != "Checking." {
+		t.Errorf("Unexpected assistant content: %+v", req.Input[1].Content)
+	}
+
+	if req.Input[2].CallID != "call_1" || req.Input[2].Arguments != `{"city":"Paris"}` {
+		t.Errorf("Unexpected function_call: %+v", req.Input[2])
+	}
+
+	if req.Input[3].CallID != "call_1" || req.Input[3].Output != "Sunny" {
+		t.Errorf("Unexpected function_call_output: %+v", req.Input[3])
+	}
+}
+
+func TestConvertAnthropicRequestToResponses_EmptyToolResult(t *testing.T) {
+	converter := NewRequestConverter(getTestLogger())
+
+	anthReq := `{
+		"model": "gpt-5",
+		"max_tokens": 1024,
+		"messages": [
+			{"role": "user", "content": "Run it"},
+			{"role": "assistant", "content": [{"type": "tool_use", "id": "call_1", "name": "bash", "input": {"command": "true"}}]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "call_1", "content": ""}]}
+		]
+	}`
+
+	result, _, err := converter.ConvertToResponses([]byte(anthReq), &EndpointInfo{Type: "openai_responses"})
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	// Responses API 拒绝缺少 output 字段的 function_call_output
+	if !strings.Contains(string(result), `{"type":"function_call_output","call_id":"call_1","output":""}`) {
+		t.Errorf("Expected empty output field in function_call_output, got %s", result)
+	}
+}
+
+func TestConvertResponsesResponseToAnthropic(t *testing.T) {
+	converter := NewResponseConverter(getTestLogger())
+
+	respBody := `{
+		"id": "resp_123",
+		"object": "response",
+		"model": "gpt-5",
+		"status": "completed",
+		"output": [
+			{"type": "reasoning", "id": "rs_1", "summary": [{"type": "summary_text", "text": "Need the weather tool."}]},
+			{"type": "message", "id": "msg_1", "role": "assistant", "content": [{"type": "output_text", "text": "Let me check."}]},
+			{"type": "function_call", "id": "fc_1", "call_id": "call_1", "name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}
+		],
+		"usage": {"input_tokens": 30, "output_tokens": 12, "total_tokens": 42}
+	}`
+
+	result, err := converter.ConvertResponses([]byte(respBody), &ConversionContext{EndpointType: "openai_responses"}, false)
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	var anthResp AnthropicResponse
+	if err := json.Unmarshal(result, &anthResp); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+
+	if anthResp.ID != "msg_resp_123" {
+		t.Errorf("Expected ID 'msg_resp_123', got '%s'", anthResp.ID)
+	}
+
+	if anthResp.StopReason != "tool_use" {
+		t.Errorf("Expected stop_reason 'tool_use', got '%s'", anthResp.StopReason)
+	}
+
+	if len(anthResp.Content) != 3 {
+		t.Fatalf("Expected 3 content blocks, got %d", len(anthResp.Content))
+	}
+
+	if anthResp.Content[0].Type != "thinking" || anthResp.Content[0].Thinking != "Need the weather tool." {
+		t.Errorf("Unexpected thinking block: %+v", anthResp.Content[0])
+	}
+
+	if anthResp.Content[1].Type != "text" || anthResp.Content[1].Text != "Let me check." {
+		t.Errorf("Unexpected text block: %+v", anthResp.Content[1])
+	}
+
+	toolUse := anthResp.Content[2]
+	if toolUse.Type != "tool_use" || toolUse.ID != "call_1" || toolUse.Name != "get_weather" || string(toolUse.Input) != `{"city":"Paris"}` {
+		t.Errorf("Unexpected tool_use block: %+v", toolUse)
+	}
+
+	if anthResp.Usage == nil || anthResp.Usage.InputTokens != 30 || anthResp.Usage.OutputTokens != 12 {
+		t.Errorf("Unexpected usage: %+v", anthResp.Usage)
+	}
+}
+
+func TestConvertResponsesResponseToAnthropic_Incomplete(t *testing.T) {
+	converter := NewResponseConverter(getTestLogger())
+
+	respBody := `{"id":"resp_1","object":"response","model":"gpt-5","status":"incomplete","incomplete_details":{"reason":"max_output_tokens"},"output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Partial"}]}]}`
+
+	result, err := converter.ConvertResponses([]byte(respBody), &ConversionContext{EndpointType: "openai_responses"}, false)
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	var anthResp AnthropicResponse
+	if err := json.Unmarshal(result, &anthResp); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+
+	if anthResp.StopReason != "max_tokens" {
+		t.Errorf("Expected stop_reason 'max_tokens', got '%s'", anthResp.StopReason)
+	}
+}
+
+func TestResponsesStreamConverter_ReasoningTextAndToolCall(t *testing.T) {
+	converter := NewResponsesStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai_responses"})
+
+	rawEvents := []string{
+		"event: response.created\ndata: {\"type\":\"response.created\",\"response\":{\"id\":\"resp_9\",\"model\":\"gpt-5\",\"status\":\"in_progress\",\"output\":[]}}\n\n",
+		"event: response.output_item.added\ndata: {\"type\":\"response.output_item.added\",\"output_index\":0,\"item\":{\"type\":\"reasoning\",\"id\":\"rs_1\"}}\n\n",
+		"event: response.reasoning_summary_text.delta\ndata: {\"type\":\"response.reasoning_summary_text.delta\",\"output_index\":0,\"summary_index\":0,\"delta\":\"Plan.\"}\n\n",
+		"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"output_index\":1,\"delta\":\"Hi\"}\n\n",
+		"event: response.output_item.added\ndata: {\"type\":\"response.output_item.added\",\"output_index\":2,\"item\":{\"type\":\"function_call\",\"call_id\":\"call_7\",\"name\":\"get_time\",\"arguments\":\"\"}}\n\n",
+		"event: response.function_call_arguments.delta\ndata: {\"type\":\"response.function_call_arguments.delta\",\"output_index\":2,\"delta\":\"{}\"}\n\n",
+		"event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_9\",\"model\":\"gpt-5\",\"status\":\"completed\",\"output\":[],\"usage\":{\"input_tokens\":5,\"output_tokens\":3,\"total_tokens\":8}}}\n\n",
+	}
+
+	var events []AnthropicSSEEvent
+	for i, raw := range rawEvents {
+		out, err := converter.ProcessEvent([]byte(raw))
+		if err != nil {
+			t.Fatalf("ProcessEvent failed: %v", err)
+		}
+		// response.created 必须立即产生 message_start
+		if i == 0 && (len(out) != 1 || out[0].Type != "message_start") {
+			t.Fatalf("Expected message_start for response.created, got %v", collectEventTypes(out))
+		}
+		events = append(events, out...)
+	}
+	final, err := converter.Finish()
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	events = append(events, final...)
+
+	expected := []string{
+		"message_start",
+		"content_block_start", "ping", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}
+	if got := strings.Join(collectEventTypes(events), ","); got != strings.Join(expected, ",") {
+		t.Fatalf("Unexpected event sequence:\n got: %s\nwant: %s", got, strings.Join(expected, ","))
+	}
+
+	if start := events[0].Data.(*AnthropicMessageStart); start.Message.ID != "msg_resp_9" || start.Message.Model != "gpt-5" {
+		t.Errorf("Unexpected message_start: %+v", start.Message)
+	}
+
+	if block := events[9].Data.(*AnthropicContentBlockStart).ContentBlock; block.ID != "call_7" || block.Name != "get_time" {
+		t.Errorf("Unexpected tool block: %+v", block)
+	}
+
+	messageDelta := events[12].Data.(*AnthropicMessageDelta)
+	if messageDelta.Delta.StopReason != "tool_use" {
+		t.Errorf("Expected stop_reason tool_use, got %s", messageDelta.Delta.StopReason)
+	}
+	if messageDelta.Usage == nil || messageDelta.Usage.InputTokens != 5 || messageDelta.Usage.OutputTokens != 3 {
+		t.Errorf("Unexpected usage: %+v", messageDelta.Usage)
+	}
+}
+
+func TestResponsesStreamConverter_Failed(t *testing.T) {
+	converter := NewResponsesStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai_responses"})
+
+	_, err := converter.ProcessEvent([]byte("event: response.failed\ndata: {\"type\":\"response.failed\",\"response\":{\"id\":\"resp_1\",\"status\":\"failed\",\"error\":{\"code\":\"server_error\",\"message\":\"boom\"}}}\n\n"))
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected upstream error containing 'boom', got %v", err)
+	}
+}
+
+func TestResponsesCachedTokensUsage(t *testing.T) {
+	usage := `{"input_tokens":1000,"output_tokens":20,"total_tokens":1020,"input_tokens_details":{"cached_tokens":800}}`
+
+	// 非流式：cached_tokens 从 input_tokens 中拆出到 cache_read_input_tokens
+	converter := NewResponseConverter(getTestLogger())
+	respBody := `{"id":"resp_1","object":"response","model":"gpt-5","status":"completed","output":[{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Hi"}]}],"usage":` + usage + `}`
+	result, err := converter.ConvertResponses([]byte(respBody), &ConversionContext{EndpointType: "openai_responses"}, false)
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+	var anthResp AnthropicResponse
+	if err := json.Unmarshal(result, &anthResp); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if anthResp.Usage == nil || anthResp.Usage.InputTokens != 200 || anthResp.Usage.CacheReadInputTokens != 800 || anthResp.Usage.OutputTokens != 20 {
+		t.Errorf("Unexpected non-streaming usage: %+v", anthResp.Usage)
+	}
+
+	// 流式：message_delta 中同样拆出缓存读取
+	streamConverter := NewResponsesStreamConverter(getTestLogger(), &ConversionContext{EndpointType: "openai_responses"})
+	rawEvents := []string{
+		"event: response.created\ndata: {\"type\":\"response.created\",\"response\":{\"id\":\"resp_2\",\"model\":\"gpt-5\",\"status\":\"in_progress\",\"output\":[]}}\n\n",
+		"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"output_index\":0,\"delta\":\"Hi\"}\n\n",
+		"event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"id\":\"resp_2\",\"model\":\"gpt-5\",\"status\":\"completed\",\"output\":[],\"usage\":" + usage + "}}\n\n",
+	}
+	var events []AnthropicSSEEvent
+	for _, raw := range rawEvents {
+		out, err := streamConverter.ProcessEvent([]byte(raw))
+		if err != nil {
+			t.Fatalf("ProcessEvent failed: %v", err)
+		}
+		events = append(events, out...)
+	}
+	final, err := streamConverter.Finish()
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	events = append(events, final...)
+
+	var messageDelta *AnthropicMessageDelta
+	for _, event := range events {
+		if delta, ok := event.Data.(*AnthropicMessageDelta); ok {
+			messageDelta = delta
+		}
+	}
+	if messageDelta == nil || messageDelta.Usage == nil {
+		t.Fatalf("Expected message_delta with usage, got %v", collectEventTypes(events))
+	}
+	if messageDelta.Usage.InputTokens != 200 || messageDelta.Usage.CacheReadInputTokens != 800 || messageDelta.Usage.OutputTokens != 20 {
+		t.Errorf("Unexpected streaming usage: %+v", messageDelta.Usage)
+	}
+}
@@ -0,0 +1,173 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ConvertToResponses 转换 Anthropic 请求为 OpenAI Responses API 格式
+// 先复用 Chat Completions 的转换逻辑（system、tool_result、图片、thinking 等），再映射为 Responses 的 input 项
+func (c *RequestConverter) ConvertToResponses(anthropicReq []byte, endpointInfo *EndpointInfo) ([]byte, *ConversionContext, error) {
+	chatReq, ctx, err := c.buildOpenAIRequest(anthropicReq, endpointInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := ResponsesRequest{
+		Model:             chatReq.Model,
+		Input:             []ResponsesInputItem{},
+		Temperature:       chatReq.Temperature,
+		TopP:              chatReq.TopP,
+		Stream:            chatReq.Stream,
+		User:              chatReq.User,
+		ParallelToolCalls: chatReq.ParallelToolCalls,
+	}
+
+	// Responses API 只有 max_output_tokens 一个字段，忽略 max_tokens_field_name 配置
+	switch {
+	case chatReq.MaxOutputTokens != nil:
+		out.MaxOutputTokens = chatReq.MaxOutputTokens
+	case chatReq.MaxCompletionTokens != nil:
+		out.MaxOutputTokens = chatReq.MaxCompletionTokens
+	default:
+		out.MaxOutputTokens = chatReq.MaxTokens
+	}
+
+	// 推理强度；summary 设为 auto 以便在响应中拿到可转换为 thinking 块的推理摘要
+	if chatReq.ReasoningEffort != nil {
+		out.Reasoning = &ResponsesReasoning{
+			Effort:  *chatReq.ReasoningEffort,
+			Summary: "auto",
+		}
+	}
+
+	// 工具定义：function 字段扁平化
+	for _, t := range chatReq.Tools {
+		out.Tools = append(out.Tools, ResponsesTool{
+			Type:        "function",
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.Parameters,
+		})
+	}
+
+	// tool_choice：指定工具时 {"type":"function","name":...}，其余字符串值保持不变
+	switch tc := chatReq.ToolChoice.(type) {
+	case map[string]interface{}:
+		if function, ok := tc["function"].(map[string]interface{}); ok {
+			out.ToolChoice = map[string]interface{}{
+				"type": "function",
+				"name": function["name"],
+			}
+		}
+	default:
+		out.ToolChoice = tc
+	}
+
+	for _, m := range chatReq.Messages {
+		switch m.Role {
+		case "system":
+			// 第一条 system 消息作为 instructions，其余的拼接在后面
+			if text := openAIContentToText(m.Content); text != "" {
+				if out.Instructions != "" {
+					out.Instructions += "\n"
+				}
+				out.Instructions += text
+			}
+
+		case "user":
+			if parts := c.userContentToResponsesParts(m.Content); len(parts) > 0 {
+				out.Input = append(out.Input, ResponsesInputItem{
+					Type:    "message",
+					Role:    "user",
+					Content: parts,
+				})
+			}
+
+		case "assistant":
+			if text := openAIContentToText(m.Content); text != "" {
+				out.Input = append(out.Input, ResponsesInputItem{
+					Type:    "message",
+					Role:    "assistant",
+					Content: []ResponsesContentPart{{Type: "output_text", Text: text}},
+				})
+			}
+			for _, tc := range m.ToolCalls {
+				out.Input = append(out.Input, ResponsesInputItem{
+					Type:      "function_call",
+					CallID:    tc.ID,
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				})
+			}
+
+		case "tool":
+			out.Input = append(out.Input, ResponsesInputItem{
+				Type:   "function_call_output",
+				CallID: m.ToolCallID,
+				Output: openAIContentToText(m.Content),
+			})
+		}
+	}
+
+	// 记录忽略的字段
+	if c.logger != nil && len(chatReq.Stop) > 0 {
+		c.logger.Debug("Ignoring stop_sequences (not supported by OpenAI Responses API)")
+	}
+
+	result, err := json.Marshal(out)
+	if err != nil {
+		return nil, nil, NewConversionError("marshal_error", "Failed to marshal OpenAI Responses request", err)
+	}
+
+	if c.logger != nil {
+		c.logger.Debug("Responses request conversion completed", map[string]interface{}{
+			"input_items": len(out.Input),
+			"tools":       len(out.Tools),
+		})
+	}
+
+	return result, ctx, nil
+}
+
+// userContentToResponsesParts 将 Chat Completions 的 user content 转换为 input_text / input_image 片段
+func (c *RequestConverter) userContentToResponsesParts(content interface{}) []ResponsesContentPart {
+	var parts []ResponsesContentPart
+	switch v := content.(type) {
+	case string:
+		if v != "" {
+			parts = append(parts, ResponsesContentPart{Type: "input_text", Text: v})
+		}
+	case []OpenAIMessageContent:
+		for _, p := range v {
+			switch p.Type {
+			case "text":
+				if p.Text != "" {
+					parts = append(parts, ResponsesContentPart{Type: "input_text", Text: p.Text})
+				}
+			case "image_url":
+				if p.ImageURL != nil {
+					parts = append(parts, ResponsesContentPart{Type: "input_image", ImageURL: p.ImageURL.URL})
+				}
+			}
+		}
+	}
+	return parts
+}
+
+// openAIContentToText 提取 Chat Completions content 中的纯文本
+func openAIContentToText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []OpenAIMessageContent:
+		var sb strings.Builder
+		for _, p := range v {
+			if p.Type == "text" {
+				sb.WriteString(p.Text)
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ConvertResponses 转换 OpenAI Responses API 响应为 Anthropic 格式
+func (c *ResponseConverter) ConvertResponses(responsesResp []byte, ctx *ConversionContext, isStreaming bool) ([]byte, error) {
+	if isStreaming {
+		return c.convertResponsesStreaming(responsesResp, ctx)
+	}
+	return c.convertResponsesNonStreaming(responsesResp, ctx)
+}
+
+// convertResponsesNonStreaming 转换非流式 Responses 响应，按 output 顺序生成 thinking / text / tool_use 块
+func (c *ResponseConverter) convertResponsesNonStreaming(responsesResp []byte, ctx *ConversionContext) ([]byte, error) {
+	var in ResponsesResponse
+	if err := json.Unmarshal(responsesResp, &in); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse OpenAI Responses response", err)
+	}
+
+	if in.Status == "failed" {
+		message := "response failed"
+		if in.Error != nil && in.Error.Message != "" {
+			message = in.Error.Message
+		}
+		return nil, NewConversionError("upstream_error", message, nil)
+	}
+
+	if len(in.Output) == 0 {
+		return nil, errors.New("no output in OpenAI Responses response")
+	}
+
+	var blocks []AnthropicContentBlock
+	hasToolCalls := false
+	for _, item := range in.Output {
+		switch item.Type {
+		case "reasoning":
+			// 优先使用推理摘要，没有摘要时使用原始推理文本（gpt-oss 等开源实现）
+			parts := item.Summary
+			if len(parts) == 0 {
+				parts = item.Content
+			}
+			var texts []string
+			for _, p := range parts {
+				if strings.TrimSpace(p.Text) != "" {
+					texts = append(texts, p.Text)
+				}
+			}
+			if len(texts) > 0 {
+				blocks = append(blocks, AnthropicContentBlock{
+					Type:      "thinking",
+					Thinking:  strings.Join(texts, "\n\n"),
+					Signature: thinkingSignaturePlaceholder,
+				})
+			}
+
+		case "message":
+			var sb strings.Builder
+			for _, p := range item.Content {
+				if p.Type == "output_text" {
+					sb.WriteString(p.Text)
+				}
+			}
+			if strings.TrimSpace(sb.String()) != "" {
+				blocks = append(blocks, AnthropicContentBlock{
+					Type: "text",
+					Text: sb.String(),
+				})
+			}
+
+		case "function_call":
+			hasToolCalls = true
+			arguments := item.Arguments
+			if strings.TrimSpace(arguments) == "" {
+				arguments = "{}"
+			}
+			blocks = append(blocks, AnthropicContentBlock{
+				Type:  "tool_use",
+				ID:    item.CallID,
+				Name:  item.Name,
+				Input: json.RawMessage(arguments),
+			})
+		}
+	}
+
+	// 复用 Chat Completions 的 finish_reason 映射
+	aggregator := NewMessageAggregator(c.logger)
+	messageID := in.ID
+	if messageID != "" && !strings.HasPrefix(messageID, "msg_") {
+		messageID = "msg_" + messageID
+	}
+	out := AnthropicResponse{
+		ID:         messageID,
+		Type:       "message",
+		Role:       "assistant",
+		Model:      in.Model,
+		Content:    blocks,
+		StopReason: aggregator.mapFinishReason(responsesFinishReason(&in, hasToolCalls)),
+	}
+	if in.Usage != nil {
+		out.Usage = (&OpenAIUsage{
+			PromptTokens:        in.Usage.InputTokens,
+			CompletionTokens:    in.Usage.OutputTokens,
+			PromptTokensDetails: &OpenAIPromptTokensDetails{CachedTokens: in.Usage.cachedTokens()},
+		}).toAnthropicUsage()
+	}
+
+	result, err := json.Marshal(out)
+	if err != nil {
+		return nil, NewConversionError("marshal_error", "Failed to marshal Anthropic response", err)
+	}
+
+	if c.logger != nil {
+		c.logger.Debug("Responses conversion completed", map[string]interface{}{
+			"output_items": len(in.Output),
+			"blocks":       len(blocks),
+		})
+	}
+
+	return result, nil
+}
+
+// convertResponsesStreaming 转换已完整读取的 Responses SSE 流，逐个事件交给 ResponsesStreamConverter
+func (c *ResponseConverter) convertResponsesStreaming(responsesResp []byte, ctx *ConversionContext) ([]byte, error) {
+	converter := NewResponsesStreamConverter(c.logger, ctx)
+
+	var events []AnthropicSSEEvent
+	normalized := bytes.ReplaceAll(responsesResp, []byte("\r\n"), []byte("\n"))
+	for _, rawEvent := range bytes.Split(normalized, []byte("\n\n")) {
+		if len(bytes.TrimSpace(rawEvent)) == 0 {
+			continue
+		}
+		eventOut, err := converter.ProcessEvent(rawEvent)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, eventOut...)
+	}
+
+	finalEvents, err := converter.Finish()
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, finalEvents...)
+
+	return c.sseParser.BuildAnthropicSSEFromEvents(events), nil
+}
@@ -0,0 +1,239 @@
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"claude-code-companion/internal/logger"
+)
+
+// ResponsesStreamConverter OpenAI Responses API 增量流式转换器
+//
+// Responses API 的流式事件是按输出项组织的（response.output_text.delta、
+// response.function_call_arguments.delta 等），这里把每个事件翻译成等价的 Chat Completions chunk，
+// 再交给 OpenAIStreamConverter 处理，从而复用内容块管理、thinking 占位签名和 TodoWrite 参数修复逻辑：
+//   - response.created → 空 chunk（立即输出 message_start）
+//   - response.output_text.delta → delta.content
+//   - response.reasoning_summary_text.delta / response.reasoning_text.delta → delta.reasoning_content
+//   - response.output_item.added(function_call) 和 response.function_call_arguments.delta → delta.tool_calls（index 使用 output_index）
+//   - response.completed / response.incomplete → finish_reason 和 usage
+//   - response.failed / error → 转换错误
+type ResponsesStreamConverter struct {
+	logger *logger.Logger
+	inner  *OpenAIStreamConverter
+
+	responseID   string
+	model        string
+	hasToolCalls bool
+	argsStreamed map[int]bool // output_index -> 是否已收到参数
+}
+
+// NewResponsesStreamConverter 创建 Responses API 增量流式转换器
+func NewResponsesStreamConverter(logger *logger.Logger, ctx *ConversionContext) *ResponsesStreamConverter {
+	return &ResponsesStreamConverter{
+		logger:       logger,
+		inner:        NewOpenAIStreamConverter(logger, ctx),
+		argsStreamed: make(map[int]bool),
+	}
+}
+
+// ProcessEvent 处理一个上游SSE事件，返回需要立即发送给客户端的 Anthropic 事件
+func (s *ResponsesStreamConverter) ProcessEvent(event []byte) ([]AnthropicSSEEvent, error) {
+	var events []AnthropicSSEEvent
+
+	for _, rawLine := range strings.Split(string(event), "\n") {
+		line := strings.TrimSpace(rawLine)
+		// 事件类型以 data 中的 type 字段为准，event: 行和注释行忽略
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		dataContent := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if dataContent == "" || dataContent == "[DONE]" {
+			continue
+		}
+
+		var streamEvent ResponsesStreamEvent
+		if err := json.Unmarshal([]byte(dataContent), &streamEvent); err != nil {
+			if s.logger != nil {
+				s.logger.Debug("Failed to parse Responses SSE data, skipping", map[string]interface{}{
+					"data":  dataContent,
+					"error": err.Error(),
+				})
+			}
+			continue
+		}
+
+		chunkEvents, err := s.processStreamEvent(&streamEvent, dataContent)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, chunkEvents...)
+	}
+
+	return events, nil
+}
+
+// Finish 上游流结束，关闭所有内容块并输出 message_delta/message_stop
+func (s *ResponsesStreamConverter) Finish() ([]AnthropicSSEEvent, error) {
+	return s.inner.Finish()
+}
+
+// processStreamEvent 将单个 Responses 事件翻译为 Chat Completions chunk 并交给内部转换器
+func (s *ResponsesStreamConverter) processStreamEvent(event *ResponsesStreamEvent, raw string) ([]AnthropicSSEEvent, error) {
+	switch event.Type {
+	case "response.created", "response.in_progress":
+		s.captureResponseMeta(event.Response)
+		return s.emit(responsesDelta{})
+
+	case "response.output_text.delta":
+		return s.emit(responsesDelta{Content: event.Delta})
+
+	case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+		return s.emit(responsesDelta{Reasoning: event.Delta})
+
+	case "response.reasoning_summary_part.added":
+		// 多段推理摘要之间用空行分隔
+		if event.SummaryIndex > 0 {
+			return s.emit(responsesDelta{Reasoning: "\n\n"})
+		}
+		return nil, nil
+
+	case "response.output_item.added":
+		if event.Item == nil || event.Item.Type != "function_call" {
+			return nil, nil
+		}
+		s.hasToolCalls = true
+		return s.emit(responsesDelta{ToolCall: &OpenAIToolCall{
+			Index:    event.OutputIndex,
+			ID:       event.Item.CallID,
+			Type:     "function",
+			Function: OpenAIToolCallDetail{Name: event.Item.Name},
+		}})
+
+	case "response.function_call_arguments.delta":
+		s.argsStreamed[event.OutputIndex] = true
+		return s.emit(responsesDelta{ToolCall: &OpenAIToolCall{
+			Index:    event.OutputIndex,
+			Function: OpenAIToolCallDetail{Arguments: event.Delta},
+		}})
+
+	case "response.output_item.done":
+		// 部分兼容实现只在 output_item.done 中给出完整参数，没有 delta
+		if event.Item == nil || event.Item.Type != "function_call" || s.argsStreamed[event.OutputIndex] {
+			return nil, nil
+		}
+		s.hasToolCalls = true
+		s.argsStreamed[event.OutputIndex] = true
+		return s.emit(responsesDelta{ToolCall: &OpenAIToolCall{
+			Index: event.OutputIndex,
+			ID:    event.Item.CallID,
+			Type:  "function",
+			Function: OpenAIToolCallDetail{
+				Name:      event.Item.Name,
+				Arguments: event.Item.Arguments,
+			},
+		}})
+
+	case "response.completed", "response.incomplete":
+		s.captureResponseMeta(event.Response)
+		return s.emitFinish(event.Response)
+
+	case "response.failed", "error":
+		if s.logger != nil {
+			s.logger.Info("Found error in Responses SSE stream", map[string]interface{}{
+				"error_line": raw,
+			})
+		}
+		message := event.Message
+		if event.Response != nil && event.Response.Error != nil {
+			message = event.Response.Error.Message
+		}
+		if message == "" {
+			message = raw
+		}
+		return nil, NewConversionError("upstream_error", fmt.Sprintf("error found in stream: %s", message), nil)
+	}
+
+	// 其他事件（content_part.added、output_text.done 等）不携带新增内容
+	return nil, nil
+}
+
+// responsesDelta 构造 Chat Completions chunk 时使用的增量描述
+type responsesDelta struct {
+	Content   string
+	Reasoning string
+	ToolCall  *OpenAIToolCall
+}
+
+// emit 构造一个 Chat Completions chunk 并交给内部转换器
+func (s *ResponsesStreamConverter) emit(delta responsesDelta) ([]AnthropicSSEEvent, error) {
+	chunk := OpenAIStreamChunk{
+		ID:    s.responseID,
+		Model: s.model,
+	}
+
+	if delta.Content != "" || delta.Reasoning != "" || delta.ToolCall != nil {
+		choice := OpenAIStreamChoice{
+			Delta: OpenAIMessage{
+				Role:             "assistant",
+				ReasoningContent: delta.Reasoning,
+			},
+		}
+		if delta.Content != "" {
+			choice.Delta.Content = delta.Content
+		}
+		if delta.ToolCall != nil {
+			choice.Delta.ToolCalls = []OpenAIToolCall{*delta.ToolCall}
+		}
+		chunk.Choices = []OpenAIStreamChoice{choice}
+	}
+
+	return s.inner.ProcessChunk(chunk)
+}
+
+// emitFinish 根据最终 response 的状态生成 finish_reason 和 usage
+func (s *ResponsesStreamConverter) emitFinish(response *ResponsesResponse) ([]AnthropicSSEEvent, error) {
+	chunk := OpenAIStreamChunk{
+		ID:    s.responseID,
+		Model: s.model,
+		Choices: []OpenAIStreamChoice{{
+			FinishReason: responsesFinishReason(response, s.hasToolCalls),
+		}},
+	}
+	if response != nil && response.Usage != nil {
+		chunk.Usage = &OpenAIUsage{
+			PromptTokens:        response.Usage.InputTokens,
+			CompletionTokens:    response.Usage.OutputTokens,
+			TotalTokens:         response.Usage.TotalTokens,
+			PromptTokensDetails: &OpenAIPromptTokensDetails{CachedTokens: response.Usage.cachedTokens()},
+		}
+	}
+	return s.inner.ProcessChunk(chunk)
+}
+
+// captureResponseMeta 记录 response 的 ID 和模型名
+func (s *ResponsesStreamConverter) captureResponseMeta(response *ResponsesResponse) {
+	if response == nil {
+		return
+	}
+	if s.responseID == "" {
+		s.responseID = response.ID
+	}
+	if s.model == "" {
+		s.model = response.Model
+	}
+}
+
+// responsesFinishReason 将 Responses 的状态映射为 Chat Completions 的 finish_reason
+func responsesFinishReason(response *ResponsesResponse, hasToolCalls bool) string {
+	if response != nil && response.Status == "incomplete" &&
+		response.IncompleteDetails != nil && response.IncompleteDetails.Reason == "max_output_tokens" {
+		return "length"
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
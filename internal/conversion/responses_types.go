@@ -0,0 +1,128 @@
+package conversion
+
+// OpenAI Responses API (/v1/responses) 结构定义
+
+// ResponsesRequest Responses API 请求
+type ResponsesRequest struct {
+	Model             string               `json:"model"`
+	Instructions      string               `json:"instructions,omitempty"` // 对应 Anthropic system
+	Input             []ResponsesInputItem `json:"input"`
+	Tools             []ResponsesTool      `json:"tools,omitempty"`
+	ToolChoice        interface{}          `json:"tool_choice,omitempty"` // "none"|"auto"|"required"|{"type":"function","name":...}
+	Temperature       *float64             `json:"temperature,omitempty"`
+	TopP              *float64             `json:"top_p,omitempty"`
+	MaxOutputTokens   *int                 `json:"max_output_tokens,omitempty"`
+	Stream            *bool                `json:"stream,omitempty"`
+	User              string               `json:"user,omitempty"`
+	ParallelToolCalls *bool                `json:"parallel_tool_calls,omitempty"`
+	Reasoning         *ResponsesReasoning  `json:"reasoning,omitempty"`
+}
+
+// ResponsesReasoning 推理配置
+type ResponsesReasoning struct {
+	Effort  string `json:"effort,omitempty"`  // "minimal"|"low"|"medium"|"high"
+	Summary string `json:"summary,omitempty"` // "auto"|"concise"|"detailed"，用于获取可展示的推理摘要
+}
+
+// ResponsesInputItem 输入项：message / function_call / function_call_output
+type ResponsesInputItem struct {
+	Type string `json:"type"`
+
+	// message
+	Role    string                 `json:"role,omitempty"` // "user" | "assistant"
+	Content []ResponsesContentPart `json:"content,omitempty"`
+
+	// function_call / function_call_output
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output"` // function_call_output 必须带 output，空的工具结果也不能省略
+}
+
+// ResponsesContentPart 内容片段：input_text / input_image / output_text / summary_text / reasoning_text
+type ResponsesContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"` // 仅 input_image
+}
+
+// ResponsesTool 工具定义（Responses API 中 function 字段是扁平的）
+type ResponsesTool struct {
+	Type        string                 `json:"type"` // "function"
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// ResponsesResponse Responses API 响应（非流式，也是流式事件中 response 字段的结构）
+type ResponsesResponse struct {
+	ID                string                      `json:"id"`
+	Object            string                      `json:"object"` // "response"
+	Model             string                      `json:"model"`
+	Status            string                      `json:"status"` // "completed"|"incomplete"|"failed"|"in_progress"
+	Output            []ResponsesOutputItem       `json:"output"`
+	Usage             *ResponsesUsage             `json:"usage,omitempty"`
+	IncompleteDetails *ResponsesIncompleteDetails `json:"incomplete_details,omitempty"`
+	Error             *ResponsesError             `json:"error,omitempty"`
+}
+
+// ResponsesOutputItem 输出项：message / reasoning / function_call
+type ResponsesOutputItem struct {
+	Type   string `json:"type"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	// message / reasoning
+	Role    string                 `json:"role,omitempty"`
+	Content []ResponsesContentPart `json:"content,omitempty"`
+	Summary []ResponsesContentPart `json:"summary,omitempty"` // 仅 reasoning
+
+	// function_call
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// ResponsesUsage 使用统计，input_tokens 含缓存读取的 token
+type ResponsesUsage struct {
+	InputTokens        int                          `json:"input_tokens"`
+	OutputTokens       int                          `json:"output_tokens"`
+	TotalTokens        int                          `json:"total_tokens"`
+	InputTokensDetails *ResponsesInputTokensDetails `json:"input_tokens_details,omitempty"`
+}
+
+// ResponsesInputTokensDetails 输入 token 明细
+type ResponsesInputTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// cachedTokens 返回缓存读取的 token 数
+func (u *ResponsesUsage) cachedTokens() int {
+	if u.InputTokensDetails == nil {
+		return 0
+	}
+	return u.InputTokensDetails.CachedTokens
+}
+
+// ResponsesIncompleteDetails 响应未完成的原因
+type ResponsesIncompleteDetails struct {
+	Reason string `json:"reason"` // "max_output_tokens" | "content_filter"
+}
+
+// ResponsesError 响应错误
+type ResponsesError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ResponsesStreamEvent Responses API 流式事件（各事件类型共用一个结构，按需取字段）
+type ResponsesStreamEvent struct {
+	Type         string               `json:"type"`
+	Response     *ResponsesResponse   `json:"response,omitempty"` // response.created / response.completed 等
+	OutputIndex  int                  `json:"output_index"`
+	SummaryIndex int                  `json:"summary_index"`
+	Item         *ResponsesOutputItem `json:"item,omitempty"`    // response.output_item.added / done
+	Delta        string               `json:"delta,omitempty"`   // *.delta
+	Code         string               `json:"code,omitempty"`    // error
+	Message      string               `json:"message,omitempty"` // error
+}
@@ -552,4 +552,40 @@ func TestMessageAggregator_FinishReasonMapping(t *testing.T) {
 				tc.openaiReason, tc.expectedAnthropic, result.FinishReason)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestRefactoredStreamingConversion_ReasoningContent(t *testing.T) {
+	converter := NewResponseConverter(getTestLogger())
+
+	// DeepSeek 风格的 reasoning_content 与 OpenRouter 风格的 reasoning 应一起聚合为 thinking 块
+	openaiSSE := `data: {"id":"chatcmpl-r1","model":"deepseek-reasoner","choices":[{"delta":{"role":"assistant","reasoning_content":"Let me "},"index":0}]}
+
+data: {"id":"chatcmpl-r1","model":"deepseek-reasoner","choices":[{"delta":{"reasoning":"think."},"index":0}]}
+
+data: {"id":"chatcmpl-r1","model":"deepseek-reasoner","choices":[{"delta":{"content":"Answer"},"index":0}]}
+
+data: {"id":"chatcmpl-r1","model":"deepseek-reasoner","choices":[{"delta":{},"index":0,"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+
+	result, err := converter.convertStreamingResponseRefactored([]byte(openaiSSE), &ConversionContext{EndpointType: "openai"})
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	resultStr := string(result)
+	thinkingStart := strings.Index(resultStr, `"type":"thinking"`)
+	textStart := strings.Index(resultStr, `"type":"text"`)
+	if thinkingStart < 0 || textStart < 0 || thinkingStart > textStart {
+		t.Fatalf("Expected a thinking block before the text block:\n%s", resultStr)
+	}
+
+	if !strings.Contains(resultStr, `"type":"thinking_delta","thinking":"Let me think."`) {
+		t.Errorf("Expected aggregated thinking_delta:\n%s", resultStr)
+	}
+
+	if !strings.Contains(resultStr, `"type":"signature_delta","signature":"`+thinkingSignaturePlaceholder+`"`) {
+		t.Errorf("Expected signature_delta placeholder:\n%s", resultStr)
+	}
+}
@@ -6,12 +6,13 @@ import (
 
 // AggregatedMessage represents a complete message after aggregating all OpenAI SSE chunks
 type AggregatedMessage struct {
-	ID           string                  `json:"id"`
-	Model        string                  `json:"model"`
-	TextContent  string                  `json:"text_content"`
-	ToolCalls    []AggregatedToolCall    `json:"tool_calls"`
-	FinishReason string                  `json:"finish_reason"`
-	Usage        *OpenAIUsage            `json:"usage,omitempty"`
+	ID              string               `json:"id"`
+	Model           string               `json:"model"`
+	TextContent     string               `json:"text_content"`
+	ThinkingContent string               `json:"thinking_content,omitempty"` // reasoning_content/reasoning 聚合结果
+	ToolCalls       []AggregatedToolCall `json:"tool_calls"`
+	FinishReason    string               `json:"finish_reason"`
+	Usage           *OpenAIUsage         `json:"usage,omitempty"`
 }
 
 // AggregatedToolCall represents a complete tool call after aggregation
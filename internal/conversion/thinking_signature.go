@@ -0,0 +1,164 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// PlaceholderThinkingStrip StripPlaceholderThinking 的处理结果
+type PlaceholderThinkingStrip struct {
+	Changed          bool // 请求是否被修改
+	RemovedBlocks    int  // 删除的占位签名 thinking 块数量
+	ThinkingDisabled bool // 是否因此关闭了本次请求的 thinking
+}
+
+// StripPlaceholderThinking 处理发往 Anthropic 端点的请求中带占位签名的 thinking 块
+//
+// 这类 thinking 块由 OpenAI 兼容上游的 reasoning 转换而来，客户端在后续轮次会原样发回；
+// 故障转移、会话粘性、对冲或流恢复把这样的历史发给 Anthropic 端点时，签名校验失败会返回 400。
+// 处理方式：
+//   - 删除 assistant 消息中带占位签名的 thinking 块，思考内容不会以任何形式进入对话内容
+//   - 删除后没有内容的 assistant 消息整条删除
+//   - 启用 thinking 时 Anthropic 要求最后一条 assistant 消息以 thinking 块开头，
+//     删除后保留的最后一条 assistant 消息开头的 thinking 块被删除时关闭本次请求的 thinking，并删除其余所有 thinking 块，
+//     保证请求中不再有任何 thinking 相关内容；调用方根据返回结果记录这一变化
+//
+// 没有占位签名时原样返回
+func StripPlaceholderThinking(body []byte) ([]byte, PlaceholderThinkingStrip, error) {
+	var result PlaceholderThinkingStrip
+	if !bytes.Contains(body, []byte(thinkingSignaturePlaceholder)) {
+		return body, result, nil
+	}
+
+	var request map[string]json.RawMessage
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, result, fmt.Errorf("failed to parse request: %v", err)
+	}
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(request["messages"], &messages); err != nil {
+		return nil, result, fmt.Errorf("failed to parse messages: %v", err)
+	}
+
+	// 以删除后仍然保留的最后一条 assistant 消息为准
+	lastAssistantLostThinking := false
+	for _, message := range messages {
+		if !isAssistantMessage(message) {
+			continue
+		}
+		blocks, ok := contentBlocks(message)
+		if !ok || len(blocks) == 0 {
+			// 字符串内容不包含 thinking 块
+			lastAssistantLostThinking = false
+			continue
+		}
+		for _, block := range blocks {
+			if !isPlaceholderThinking(block) {
+				lastAssistantLostThinking = isPlaceholderThinking(blocks[0])
+				break
+			}
+		}
+	}
+	disableThinking := lastAssistantLostThinking && thinkingEnabled(request["thinking"])
+
+	kept := make([]map[string]json.RawMessage, 0, len(messages))
+	for _, message := range messages {
+		blocks, ok := contentBlocks(message)
+		if !isAssistantMessage(message) || !ok {
+			kept = append(kept, message)
+			continue
+		}
+
+		keptBlocks := make([]map[string]json.RawMessage, 0, len(blocks))
+		for _, block := range blocks {
+			if isPlaceholderThinking(block) {
+				result.RemovedBlocks++
+				continue
+			}
+			if disableThinking && isThinkingBlock(block) {
+				continue
+			}
+			keptBlocks = append(keptBlocks, block)
+		}
+		if len(keptBlocks) == len(blocks) {
+			kept = append(kept, message)
+			continue
+		}
+		if len(keptBlocks) == 0 {
+			// 只有 thinking 块的消息没有可保留的内容
+			continue
+		}
+		content, err := json.Marshal(keptBlocks)
+		if err != nil {
+			return nil, result, fmt.Errorf("failed to marshal content: %v", err)
+		}
+		message["content"] = content
+		kept = append(kept, message)
+	}
+
+	if result.RemovedBlocks == 0 {
+		return body, result, nil
+	}
+
+	messagesJSON, err := json.Marshal(kept)
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to marshal messages: %v", err)
+	}
+	request["messages"] = messagesJSON
+	if disableThinking {
+		delete(request, "thinking")
+		result.ThinkingDisabled = true
+	}
+
+	stripped, err := json.Marshal(request)
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to marshal request: %v", err)
+	}
+	result.Changed = true
+	return stripped, result, nil
+}
+
+// isAssistantMessage 判断消息的 role 是否为 assistant
+func isAssistantMessage(message map[string]json.RawMessage) bool {
+	var role string
+	json.Unmarshal(message["role"], &role)
+	return role == "assistant"
+}
+
+// contentBlocks 解析消息的内容块数组，字符串内容返回 false
+func contentBlocks(message map[string]json.RawMessage) ([]map[string]json.RawMessage, bool) {
+	var blocks []map[string]json.RawMessage
+	if err := json.Unmarshal(message["content"], &blocks); err != nil {
+		return nil, false
+	}
+	return blocks, true
+}
+
+// thinkingEnabled 判断请求的 thinking 参数是否启用
+func thinkingEnabled(raw json.RawMessage) bool {
+	if len(raw) == 0 {
+		return false
+	}
+	var thinking struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &thinking); err != nil {
+		return false
+	}
+	return thinking.Type != "" && thinking.Type != "disabled"
+}
+
+// isThinkingBlock 判断内容块是否为 thinking 或 redacted_thinking 块
+func isThinkingBlock(block map[string]json.RawMessage) bool {
+	var blockType string
+	json.Unmarshal(block["type"], &blockType)
+	return blockType == "thinking" || blockType == "redacted_thinking"
+}
+
+// isPlaceholderThinking 判断内容块是否为带占位签名的 thinking 块
+func isPlaceholderThinking(block map[string]json.RawMessage) bool {
+	var blockType, signature string
+	json.Unmarshal(block["type"], &blockType)
+	json.Unmarshal(block["signature"], &signature)
+	return blockType == "thinking" && signature == thinkingSignaturePlaceholder
+}
@@ -0,0 +1,166 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStripPlaceholderThinking(t *testing.T) {
+	request := `{
+		"model": "claude-sonnet-4",
+		"max_tokens": 1024,
+		"thinking": {"type": "enabled", "budget_tokens": 512},
+		"messages": [
+			{"role": "user", "content": "list files"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "I should call LS", "signature": "openai-reasoning-no-signature"},
+				{"type": "tool_use", "id": "toolu_1", "name": "LS", "input": {"path": "."}}
+			]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "toolu_1", "content": "a.go"}]},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "only reasoning", "signature": "openai-reasoning-no-signature"}
+			]},
+			{"role": "user", "content": "continue"}
+		]
+	}`
+
+	result, strip, err := StripPlaceholderThinking([]byte(request))
+	if err != nil {
+		t.Fatalf("StripPlaceholderThinking failed: %v", err)
+	}
+	if !strip.Changed || strip.RemovedBlocks != 2 {
+		t.Fatalf("expected 2 thinking blocks to be removed, got %+v", strip)
+	}
+	if strings.Contains(string(result), thinkingSignaturePlaceholder) || strings.Contains(string(result), "I should call LS") || strings.Contains(string(result), "only reasoning") {
+		t.Errorf("placeholder thinking still present: %s", result)
+	}
+
+	var req AnthropicRequest
+	if err := json.Unmarshal(result, &req); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+	if req.MaxTokens == nil || *req.MaxTokens != 1024 {
+		t.Errorf("expected other fields to be preserved")
+	}
+	// 最后一条 assistant 消息只有 thinking 块，整条删除后剩下的最后一条 assistant 消息也失去了开头的 thinking 块
+	if req.Thinking != nil || !strip.ThinkingDisabled {
+		t.Errorf("expected thinking to be disabled when the last remaining assistant message lost its thinking block")
+	}
+	if len(req.Messages) != 4 {
+		t.Fatalf("expected thinking-only message to be dropped, got %d messages", len(req.Messages))
+	}
+	toolTurn := req.Messages[1].GetContentBlocks()
+	if len(toolTurn) != 1 || toolTurn[0].Type != "tool_use" || toolTurn[0].ID != "toolu_1" {
+		t.Errorf("expected only the tool_use block to remain, got %+v", toolTurn)
+	}
+}
+
+func TestStripPlaceholderThinking_NoReasoningInContent(t *testing.T) {
+	request := `{"model":"claude-sonnet-4","messages":[` +
+		`{"role":"user","content":"hi"},` +
+		`{"role":"assistant","content":[{"type":"thinking","thinking":"secret plan A","signature":"openai-reasoning-no-signature"}]},` +
+		`{"role":"user","content":"again"},` +
+		`{"role":"assistant","content":[{"type":"thinking","thinking":"secret plan B","signature":"openai-reasoning-no-signature"},{"type":"text","text":"hello"}]},` +
+		`{"role":"user","content":"more"}]}`
+
+	result, strip, err := StripPlaceholderThinking([]byte(request))
+	if err != nil {
+		t.Fatalf("StripPlaceholderThinking failed: %v", err)
+	}
+	if !strip.Changed || strip.ThinkingDisabled {
+		t.Fatalf("unexpected strip result %+v", strip)
+	}
+
+	// 思考内容不能以文本或其他形式出现在转换后的消息中
+	var req AnthropicRequest
+	if err := json.Unmarshal(result, &req); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+	for i, message := range req.Messages {
+		raw, _ := json.Marshal(message.Content)
+		if strings.Contains(string(raw), "secret plan") {
+			t.Errorf("message %d contains reasoning content: %s", i, raw)
+		}
+		for _, block := range message.GetContentBlocks() {
+			if block.Type == "thinking" {
+				t.Errorf("message %d still contains a thinking block", i)
+			}
+		}
+	}
+	if len(req.Messages) != 4 {
+		t.Errorf("expected 4 messages after dropping the thinking-only message, got %d", len(req.Messages))
+	}
+}
+
+func TestStripPlaceholderThinking_DisablesThinkingConsistently(t *testing.T) {
+	request := `{"model":"claude-sonnet-4","thinking":{"type":"enabled","budget_tokens":512},"messages":[` +
+		`{"role":"user","content":"hi"},` +
+		`{"role":"assistant","content":[{"type":"thinking","thinking":"real","signature":"EqQBCkYIBxgCKkA"},{"type":"redacted_thinking","data":"abc"},{"type":"text","text":"hello"}]},` +
+		`{"role":"user","content":"again"},` +
+		`{"role":"assistant","content":[{"type":"thinking","thinking":"converted","signature":"openai-reasoning-no-signature"},{"type":"tool_use","id":"toolu_2","name":"LS","input":{}}]},` +
+		`{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":"ok"}]}]}`
+
+	result, strip, err := StripPlaceholderThinking([]byte(request))
+	if err != nil {
+		t.Fatalf("StripPlaceholderThinking failed: %v", err)
+	}
+	if !strip.Changed || !strip.ThinkingDisabled || strip.RemovedBlocks != 1 {
+		t.Fatalf("expected thinking to be disabled, got %+v", strip)
+	}
+
+	// 关闭 thinking 后请求中不再有任何 thinking 块
+	var req AnthropicRequest
+	if err := json.Unmarshal(result, &req); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+	if req.Thinking != nil {
+		t.Errorf("expected thinking parameter to be removed")
+	}
+	if strings.Contains(string(result), `"thinking"`) || strings.Contains(string(result), "redacted_thinking") {
+		t.Errorf("expected no thinking content left, got %s", result)
+	}
+	if blocks := req.Messages[1].GetContentBlocks(); len(blocks) != 1 || blocks[0].Type != "text" {
+		t.Errorf("expected only the text block to remain, got %+v", blocks)
+	}
+	if blocks := req.Messages[3].GetContentBlocks(); len(blocks) != 1 || blocks[0].Type != "tool_use" {
+		t.Errorf("expected only the tool_use block to remain, got %+v", blocks)
+	}
+}
+
+func TestStripPlaceholderThinking_KeepsSignedThinking(t *testing.T) {
+	request := `{"model":"claude-sonnet-4","thinking":{"type":"enabled","budget_tokens":512},"messages":[` +
+		`{"role":"user","content":"hi"},` +
+		`{"role":"assistant","content":[{"type":"thinking","thinking":"old","signature":"openai-reasoning-no-signature"},{"type":"text","text":"hello"}]},` +
+		`{"role":"user","content":"again"},` +
+		`{"role":"assistant","content":[{"type":"thinking","thinking":"real","signature":"EqQBCkYIBxgCKkA"},{"type":"tool_use","id":"toolu_2","name":"LS","input":{}}]},` +
+		`{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":"ok"}]}]}`
+
+	result, strip, err := StripPlaceholderThinking([]byte(request))
+	if err != nil {
+		t.Fatalf("StripPlaceholderThinking failed: %v", err)
+	}
+	if !strip.Changed || strip.ThinkingDisabled {
+		t.Fatalf("unexpected strip result %+v", strip)
+	}
+
+	var req AnthropicRequest
+	if err := json.Unmarshal(result, &req); err != nil {
+		t.Fatalf("failed to parse result: %v", err)
+	}
+	if req.Thinking == nil {
+		t.Errorf("expected thinking to stay enabled when the last assistant message keeps a signed thinking block")
+	}
+	if blocks := req.Messages[1].GetContentBlocks(); len(blocks) != 1 || blocks[0].Type != "text" {
+		t.Errorf("expected placeholder thinking block to be removed, got %+v", blocks)
+	}
+	if blocks := req.Messages[3].GetContentBlocks(); len(blocks) != 2 || blocks[0].Type != "thinking" || !strings.Contains(string(result), `"signature":"EqQBCkYIBxgCKkA"`) {
+		t.Errorf("expected signed thinking block to be kept, got %s", result)
+	}
+
+	// 没有占位签名时原样返回
+	plain := []byte(`{"model":"claude-sonnet-4","messages":[{"role":"user","content":"hi"}]}`)
+	if result, strip, err := StripPlaceholderThinking(plain); err != nil || strip.Changed || string(result) != string(plain) {
+		t.Errorf("expected request without placeholder to be returned unchanged, got %s (%+v, err=%v)", result, strip, err)
+	}
+}
@@ -16,15 +16,29 @@ type Converter interface {
 	
 	// 检查是否需要转换
 	ShouldConvert(endpointType string) bool
+
+	// 创建增量流式响应转换器（逐个事件转换，用于流式透传）
+	NewStreamConverter(ctx *ConversionContext) StreamConverter
+}
+
+// StreamConverter 增量流式响应转换器
+// 逐个接收上游SSE事件，立即返回对应的 Anthropic SSE 事件，不需要等待完整响应体
+type StreamConverter interface {
+	// ProcessEvent 处理一个上游SSE事件（包含 event:/data: 行的原始字节）
+	ProcessEvent(event []byte) ([]AnthropicSSEEvent, error)
+
+	// Finish 上游流结束时调用，关闭未结束的内容块并返回 message_delta/message_stop
+	Finish() ([]AnthropicSSEEvent, error)
 }
 
 // ConversionContext 转换上下文
 type ConversionContext struct {
-	EndpointType    string                 // "anthropic" | "openai"
+	EndpointType    string                 // "anthropic" | "openai" | "openai_responses" | "gemini"
 	ToolCallIDMap   map[string]string      // 工具调用ID映射 (Anthropic ID -> OpenAI ID)
 	IsStreaming     bool                   // 是否为流式请求
 	RequestHeaders  map[string]string      // 原始请求头
 	StopSequences   []string               // 请求中的停止序列，用于响应时检测
+	Model           string                 // 转换后的目标模型名（仅 Gemini 使用，模型名需要放在URL路径中）
 	// 注意：不包含模型映射，因为转换发生在模型重写之后
 }
 
@@ -45,6 +45,7 @@ func (c *UnifiedConverter) ConvertAggregatedMessage(msg *AggregatedMessage) (*Co
 			"message_id": msg.ID,
 			"event_count": len(events),
 			"has_text": len(msg.TextContent) > 0,
+			"has_thinking": len(msg.ThinkingContent) > 0,
 			"tool_calls": len(msg.ToolCalls),
 		})
 	}
@@ -85,10 +86,15 @@ func (c *UnifiedConverter) generateMessageStartEvent(msg *AggregatedMessage) (An
 	}, nil
 }
 
-// generateContentEvents creates content block events for text and tool calls
+// generateContentEvents creates content block events for thinking, text and tool calls
 func (c *UnifiedConverter) generateContentEvents(msg *AggregatedMessage, blockIndex *int) ([]AnthropicSSEEvent, error) {
 	var events []AnthropicSSEEvent
 	
+	// Generate thinking content events first (Anthropic places thinking before text)
+	if len(msg.ThinkingContent) > 0 {
+		events = append(events, c.generateThinkingEvents(msg.ThinkingContent, blockIndex)...)
+	}
+
 	// Generate text content events if present
 	if len(msg.TextContent) > 0 {
 		textEvents, err := c.generateTextEvents(msg.TextContent, blockIndex)
@@ -122,6 +128,52 @@ func (c *UnifiedConverter) generateContentEvents(msg *AggregatedMessage, blockIn
 	return events, nil
 }
 
+// generateThinkingEvents creates events for reasoning content as a thinking block
+func (c *UnifiedConverter) generateThinkingEvents(thinkingContent string, blockIndex *int) []AnthropicSSEEvent {
+	currentIndex := *blockIndex
+	*blockIndex++
+
+	return []AnthropicSSEEvent{
+		{
+			Type: "content_block_start",
+			Data: &AnthropicContentBlockStart{
+				Type:         "content_block_start",
+				Index:        currentIndex,
+				ContentBlock: &AnthropicContentBlockForStart{Type: "thinking"},
+			},
+		},
+		{
+			Type: "content_block_delta",
+			Data: &AnthropicContentBlockDelta{
+				Type:  "content_block_delta",
+				Index: currentIndex,
+				Delta: &AnthropicContentBlock{
+					Type:     "thinking_delta",
+					Thinking: thinkingContent,
+				},
+			},
+		},
+		{
+			Type: "content_block_delta",
+			Data: &AnthropicContentBlockDelta{
+				Type:  "content_block_delta",
+				Index: currentIndex,
+				Delta: &AnthropicContentBlock{
+					Type:      "signature_delta",
+					Signature: thinkingSignaturePlaceholder,
+				},
+			},
+		},
+		{
+			Type: "content_block_stop",
+			Data: &AnthropicContentBlockStop{
+				Type:  "content_block_stop",
+				Index: currentIndex,
+			},
+		},
+	}
+}
+
 // generateTextEvents creates events for text content
 func (c *UnifiedConverter) generateTextEvents(textContent string, blockIndex *int) ([]AnthropicSSEEvent, error) {
 	var events []AnthropicSSEEvent
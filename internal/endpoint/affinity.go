@@ -0,0 +1,194 @@
+package endpoint
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/utils"
+)
+
+// sessionPruneInterval 清理过期会话绑定的最小间隔
+const sessionPruneInterval = time.Minute
+
+// SessionBinding 会话与端点的绑定
+type SessionBinding struct {
+	SessionID    string    `json:"session_id"`
+	EndpointID   string    `json:"endpoint_id"`
+	EndpointName string    `json:"endpoint_name"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastUsed     time.Time `json:"last_used"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Requests     int       `json:"requests"`
+}
+
+// SessionAffinity 会话粘性路由：记录每个会话最后一次成功处理它的端点，绑定在最后一次使用后 TTL 过期
+type SessionAffinity struct {
+	mutex     sync.Mutex
+	enabled   bool
+	ttl       time.Duration
+	bindings  map[string]*SessionBinding
+	lastPrune time.Time
+}
+
+func NewSessionAffinity(cfg config.SessionAffinityConfig) *SessionAffinity {
+	a := &SessionAffinity{
+		bindings: make(map[string]*SessionBinding),
+	}
+	a.UpdateConfig(cfg)
+	return a
+}
+
+// UpdateConfig 热更新配置，关闭时清空所有绑定
+func (a *SessionAffinity) UpdateConfig(cfg config.SessionAffinityConfig) {
+	defaultTTL, _ := time.ParseDuration(config.Default.SessionAffinity.TTL)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.enabled = cfg.Enabled
+	a.ttl = config.GetTimeoutDuration(cfg.TTL, defaultTTL)
+	if !a.enabled {
+		a.bindings = make(map[string]*SessionBinding)
+	}
+}
+
+// IsEnabled 是否启用会话粘性路由
+func (a *SessionAffinity) IsEnabled() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.enabled
+}
+
+// Lookup 返回会话当前绑定的端点ID
+func (a *SessionAffinity) Lookup(sessionID string) (string, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.enabled || sessionID == "" {
+		return "", false
+	}
+	binding, exists := a.bindings[sessionID]
+	if !exists {
+		return "", false
+	}
+	if time.Now().After(binding.ExpiresAt) {
+		delete(a.bindings, sessionID)
+		return "", false
+	}
+	return binding.EndpointID, true
+}
+
+// Bind 把会话绑定到成功处理它的端点，并刷新过期时间
+func (a *SessionAffinity) Bind(sessionID string, ep *Endpoint) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.enabled || sessionID == "" {
+		return
+	}
+
+	now := time.Now()
+	a.pruneLocked(now)
+
+	binding, exists := a.bindings[sessionID]
+	if !exists || binding.EndpointID != ep.ID {
+		binding = &SessionBinding{
+			SessionID: sessionID,
+			CreatedAt: now,
+		}
+		a.bindings[sessionID] = binding
+	}
+	binding.EndpointID = ep.ID
+	binding.EndpointName = ep.Name
+	binding.LastUsed = now
+	binding.ExpiresAt = now.Add(a.ttl)
+	binding.Requests++
+}
+
+// List 返回所有未过期的绑定，最近使用的在前
+func (a *SessionAffinity) List() []SessionBinding {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.pruneLocked(time.Time{})
+
+	bindings := make([]SessionBinding, 0, len(a.bindings))
+	for _, binding := range a.bindings {
+		bindings = append(bindings, *binding)
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].LastUsed.After(bindings[j].LastUsed)
+	})
+	return bindings
+}
+
+// Remove 删除单个会话绑定
+func (a *SessionAffinity) Remove(sessionID string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if _, exists := a.bindings[sessionID]; !exists {
+		return false
+	}
+	delete(a.bindings, sessionID)
+	return true
+}
+
+// Clear 删除所有会话绑定，返回删除的数量
+func (a *SessionAffinity) Clear() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	count := len(a.bindings)
+	a.bindings = make(map[string]*SessionBinding)
+	return count
+}
+
+// pruneLocked 删除过期绑定；now 为零值时强制清理，否则最多每 sessionPruneInterval 清理一次
+func (a *SessionAffinity) pruneLocked(now time.Time) {
+	if now.IsZero() {
+		now = time.Now()
+	} else if now.Sub(a.lastPrune) < sessionPruneInterval {
+		return
+	}
+	a.lastPrune = now
+
+	for sessionID, binding := range a.bindings {
+		if now.After(binding.ExpiresAt) {
+			delete(a.bindings, sessionID)
+		}
+	}
+}
+
+// GetEndpointForSession 返回会话绑定且仍然可用的端点；端点不可用、被禁用或不再匹配请求标签时返回 nil，由调用方重新选择
+func (m *Manager) GetEndpointForSession(sessionID string, tags []string) *Endpoint {
+	endpointID, exists := m.sessions.Lookup(sessionID)
+	if !exists {
+		return nil
+	}
+
+	for _, ep := range m.selector.GetAllEndpoints() {
+		if ep.ID != endpointID {
+			continue
+		}
+		if !ep.IsEnabled() || !ep.IsAvailable() {
+			return nil
+		}
+		if len(utils.FilterEndpointsForTags([]utils.EndpointSorter{ep}, tags)) == 0 {
+			return nil
+		}
+		return ep
+	}
+	return nil
+}
+
+// BindSession 把会话绑定到成功处理它的端点
+func (m *Manager) BindSession(sessionID string, ep *Endpoint) {
+	m.sessions.Bind(sessionID, ep)
+}
+
+// GetSessionAffinity 获取会话粘性路由状态
+func (m *Manager) GetSessionAffinity() *SessionAffinity {
+	return m.sessions
+}
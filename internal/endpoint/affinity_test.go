@@ -0,0 +1,156 @@
+package endpoint
+
+import (
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+)
+
+func TestSessionAffinityBindAndExpire(t *testing.T) {
+	affinity := NewSessionAffinity(config.SessionAffinityConfig{Enabled: true, TTL: "1m"})
+	ep := newTestEndpoint("a")
+
+	affinity.Bind("session-1", ep)
+	if endpointID, ok := affinity.Lookup("session-1"); !ok || endpointID != ep.ID {
+		t.Fatalf("expected session bound to %s, got %q %v", ep.ID, endpointID, ok)
+	}
+	if _, ok := affinity.Lookup(""); ok {
+		t.Errorf("expected empty session ID never to match")
+	}
+
+	// 最后一次使用后超过 TTL 的绑定失效并被删除
+	affinity.mutex.Lock()
+	affinity.bindings["session-1"].ExpiresAt = time.Now().Add(-time.Second)
+	affinity.mutex.Unlock()
+	if _, ok := affinity.Lookup("session-1"); ok {
+		t.Errorf("expected expired binding to be ignored")
+	}
+	if len(affinity.List()) != 0 {
+		t.Errorf("expected expired binding to be removed")
+	}
+}
+
+func TestSessionAffinityRebind(t *testing.T) {
+	affinity := NewSessionAffinity(config.SessionAffinityConfig{Enabled: true, TTL: "1m"})
+	a := newTestEndpoint("a")
+	b := newTestEndpoint("b")
+
+	affinity.Bind("session-1", a)
+	affinity.Bind("session-1", a)
+	bindings := affinity.List()
+	if len(bindings) != 1 || bindings[0].Requests != 2 || bindings[0].EndpointName != "a" {
+		t.Fatalf("expected one binding with two requests, got %+v", bindings)
+	}
+	createdAt := bindings[0].CreatedAt
+
+	// 换到其他端点时重新创建绑定
+	affinity.Bind("session-1", b)
+	bindings = affinity.List()
+	if len(bindings) != 1 || bindings[0].EndpointID != b.ID || bindings[0].Requests != 1 {
+		t.Fatalf("expected session rebound to b with a fresh counter, got %+v", bindings)
+	}
+	if bindings[0].CreatedAt.Before(createdAt) {
+		t.Errorf("expected rebinding to reset creation time")
+	}
+	if !bindings[0].ExpiresAt.After(time.Now().Add(59 * time.Second)) {
+		t.Errorf("expected expiry refreshed to TTL after last use, got %v", bindings[0].ExpiresAt)
+	}
+}
+
+func TestSessionAffinityPrune(t *testing.T) {
+	affinity := NewSessionAffinity(config.SessionAffinityConfig{Enabled: true, TTL: "1h"})
+	ep := newTestEndpoint("a")
+	affinity.Bind("expired", ep)
+	affinity.Bind("live", ep)
+
+	now := time.Now()
+	affinity.mutex.Lock()
+	affinity.bindings["expired"].ExpiresAt = now.Add(-time.Second)
+
+	// 距离上次清理不到 sessionPruneInterval 时跳过
+	affinity.pruneLocked(now)
+	if _, exists := affinity.bindings["expired"]; !exists {
+		t.Errorf("expected prune to be rate limited")
+	}
+	affinity.pruneLocked(now.Add(sessionPruneInterval))
+	_, expiredExists := affinity.bindings["expired"]
+	_, liveExists := affinity.bindings["live"]
+	affinity.mutex.Unlock()
+	if expiredExists || !liveExists {
+		t.Errorf("expected only the expired binding pruned, expired=%v live=%v", expiredExists, liveExists)
+	}
+}
+
+func TestSessionAffinityDisableClearsBindings(t *testing.T) {
+	affinity := NewSessionAffinity(config.SessionAffinityConfig{Enabled: true})
+	affinity.Bind("session-1", newTestEndpoint("a"))
+
+	affinity.UpdateConfig(config.SessionAffinityConfig{Enabled: false})
+	if affinity.IsEnabled() || len(affinity.List()) != 0 {
+		t.Fatalf("expected bindings cleared when session affinity is turned off")
+	}
+	affinity.Bind("session-1", newTestEndpoint("a"))
+	if len(affinity.List()) != 0 {
+		t.Errorf("expected no new bindings while disabled")
+	}
+
+	// 重新开启后从空状态开始，未配置 TTL 时使用默认值
+	affinity.UpdateConfig(config.SessionAffinityConfig{Enabled: true})
+	if _, ok := affinity.Lookup("session-1"); ok {
+		t.Errorf("expected old binding not to come back after re-enabling")
+	}
+	if defaultTTL, _ := time.ParseDuration(config.Default.SessionAffinity.TTL); affinity.ttl != defaultTTL {
+		t.Errorf("expected default TTL %v, got %v", defaultTTL, affinity.ttl)
+	}
+}
+
+func TestGetEndpointForSession(t *testing.T) {
+	untagged := newTestEndpoint("untagged")
+	tagged := newTestEndpoint("tagged", func(cfg *config.EndpointConfig) { cfg.Tags = []string{"pool"} })
+	manager := &Manager{
+		selector: NewSelector([]*Endpoint{untagged, tagged}, config.LoadBalancingConfig{}),
+		sessions: NewSessionAffinity(config.SessionAffinityConfig{Enabled: true, TTL: "1m"}),
+	}
+	manager.BindSession("untagged-session", untagged)
+	manager.BindSession("tagged-session", tagged)
+
+	tests := []struct {
+		name      string
+		sessionID string
+		tags      []string
+		expected  *Endpoint
+	}{
+		{"bound endpoint", "untagged-session", nil, untagged},
+		{"untagged endpoint serves any tags", "untagged-session", []string{"pool"}, untagged},
+		{"tagged endpoint with matching tags", "tagged-session", []string{"pool"}, tagged},
+		{"tagged endpoint for untagged request", "tagged-session", nil, nil},
+		{"tagged endpoint with mismatched tags", "tagged-session", []string{"other"}, nil},
+		{"unknown session", "unknown", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := manager.GetEndpointForSession(tt.sessionID, tt.tags); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	// 端点不可用或被禁用时返回 nil，由调用方重新选择
+	untagged.MarkInactive(statistics.CauseManual, "test")
+	if got := manager.GetEndpointForSession("untagged-session", nil); got != nil {
+		t.Errorf("expected nil for unavailable endpoint, got %s", got.Name)
+	}
+	untagged.MarkActive("test")
+	untagged.mutex.Lock()
+	untagged.Enabled = false
+	untagged.mutex.Unlock()
+	if got := manager.GetEndpointForSession("untagged-session", nil); got != nil {
+		t.Errorf("expected nil for disabled endpoint, got %s", got.Name)
+	}
+
+	// 关闭会话粘性路由后不再返回绑定
+	manager.GetSessionAffinity().UpdateConfig(config.SessionAffinityConfig{Enabled: false})
+	if got := manager.GetEndpointForSession("tagged-session", []string{"pool"}); got != nil {
+		t.Errorf("expected nil after session affinity is turned off, got %s", got.Name)
+	}
+}
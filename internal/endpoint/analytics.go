@@ -0,0 +1,234 @@
+package endpoint
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+)
+
+// AnalyticsSample 一个客户端请求的用量分析样本，由代理在请求结束后记录
+type AnalyticsSample struct {
+	Time        time.Time
+	Endpoint    string   // 最后尝试的端点名，所有端点都失败时为最后失败的端点；没有可用端点时为空
+	Model       string   // 客户端请求的模型
+	Tags        []string // 请求标签
+	SessionID   string
+	ClientToken string // 客户端凭据，汇总时只保留末4位
+	Success     bool
+	Latency     time.Duration
+	Usage       TokenUsage
+	Cost        float64
+}
+
+// analyticsRecorder 在内存中把请求样本汇总到小时和天的时间桶，定期合并写入 statistics.db 的 usage_rollups 表，
+// 并按保留天数清理旧的汇总；时间桶按本地时区划分，以 UTC 存储
+type analyticsRecorder struct {
+	statisticsManager statistics.StatisticsManager
+	mutex             sync.Mutex
+	enabled           bool
+	flushInterval     time.Duration
+	hourlyRetention   int
+	dailyRetention    int
+	pending           map[string]*statistics.UsageRollup
+	kick              chan struct{}
+}
+
+func newAnalyticsRecorder(statisticsManager statistics.StatisticsManager, cfg config.AnalyticsConfig) *analyticsRecorder {
+	r := &analyticsRecorder{
+		statisticsManager: statisticsManager,
+		pending:           make(map[string]*statistics.UsageRollup),
+		kick:              make(chan struct{}, 1),
+	}
+	r.update(cfg)
+	go r.run()
+	return r
+}
+
+func (r *analyticsRecorder) update(cfg config.AnalyticsConfig) {
+	defaultInterval, _ := time.ParseDuration(config.Default.Analytics.FlushInterval)
+
+	r.mutex.Lock()
+	r.enabled = cfg.IsEnabled()
+	r.flushInterval = config.GetTimeoutDuration(cfg.FlushInterval, defaultInterval)
+	r.hourlyRetention = cfg.HourlyRetentionDays
+	if r.hourlyRetention <= 0 {
+		r.hourlyRetention = config.Default.Analytics.HourlyRetentionDays
+	}
+	r.dailyRetention = cfg.DailyRetentionDays
+	if r.dailyRetention <= 0 {
+		r.dailyRetention = config.Default.Analytics.DailyRetentionDays
+	}
+	r.mutex.Unlock()
+
+	select {
+	case r.kick <- struct{}{}:
+	default:
+	}
+}
+
+// record 把样本累加到它所属的每个时间桶和维度
+func (r *analyticsRecorder) record(sample AnalyticsSample) {
+	if sample.Time.IsZero() {
+		sample.Time = time.Now()
+	}
+	local := sample.Time.Local()
+	buckets := map[string]time.Time{
+		statistics.GranularityHour: time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, time.Local).UTC(),
+		statistics.GranularityDay:  time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local).UTC(),
+	}
+
+	keys := map[string][]string{
+		statistics.DimensionTotal:    {""},
+		statistics.DimensionEndpoint: nonEmpty(sample.Endpoint),
+		statistics.DimensionModel:    nonEmpty(sample.Model),
+		statistics.DimensionTag:      sample.Tags,
+		statistics.DimensionSession:  nonEmpty(sample.SessionID),
+	}
+	if sample.ClientToken != "" {
+		keys[statistics.DimensionClientToken] = []string{maskAPIKey(sample.ClientToken)}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.enabled {
+		return
+	}
+
+	for granularity, bucketStart := range buckets {
+		for dimension, values := range keys {
+			for _, key := range values {
+				identity := fmt.Sprintf("%s|%d|%s|%s", granularity, bucketStart.Unix(), dimension, key)
+				rollup, exists := r.pending[identity]
+				if !exists {
+					rollup = &statistics.UsageRollup{
+						Granularity: granularity,
+						BucketStart: bucketStart,
+						Dimension:   dimension,
+						Key:         key,
+					}
+					r.pending[identity] = rollup
+				}
+				rollup.Requests++
+				if !sample.Success {
+					rollup.Errors++
+				}
+				rollup.InputTokens += sample.Usage.InputTokens
+				rollup.OutputTokens += sample.Usage.OutputTokens
+				rollup.CacheCreationInputTokens += sample.Usage.CacheWriteTokens
+				rollup.CacheReadInputTokens += sample.Usage.CacheReadTokens
+				rollup.Cost += sample.Cost
+				rollup.ObserveLatency(sample.Latency.Milliseconds())
+			}
+		}
+	}
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+// flush 把内存中的汇总合并写入数据库；写入失败时放回内存，下次再试
+func (r *analyticsRecorder) flush() {
+	r.mutex.Lock()
+	if len(r.pending) == 0 {
+		r.mutex.Unlock()
+		return
+	}
+	pending := r.pending
+	r.pending = make(map[string]*statistics.UsageRollup)
+	r.mutex.Unlock()
+
+	rollups := make([]*statistics.UsageRollup, 0, len(pending))
+	for _, rollup := range pending {
+		rollups = append(rollups, rollup)
+	}
+	if err := r.statisticsManager.SaveUsageRollups(rollups); err != nil {
+		log.Printf("WARNING: Failed to persist usage rollups: %v", err)
+
+		r.mutex.Lock()
+		for identity, rollup := range pending {
+			if current, exists := r.pending[identity]; exists {
+				rollup.Merge(current)
+			}
+			r.pending[identity] = rollup
+		}
+		r.mutex.Unlock()
+	}
+}
+
+// run 定期写入汇总并清理超过保留天数的汇总，flush_interval 热更新后立即生效
+func (r *analyticsRecorder) run() {
+	r.cleanup()
+	cleanupTicker := time.NewTicker(config.Default.Analytics.CleanupInterval)
+	defer cleanupTicker.Stop()
+
+	for {
+		r.mutex.Lock()
+		interval := r.flushInterval
+		r.mutex.Unlock()
+		flushTimer := time.NewTimer(interval)
+
+		select {
+		case <-flushTimer.C:
+			r.flush()
+		case <-cleanupTicker.C:
+			flushTimer.Stop()
+			r.cleanup()
+		case <-r.kick:
+			flushTimer.Stop()
+		}
+	}
+}
+
+func (r *analyticsRecorder) cleanup() {
+	r.mutex.Lock()
+	retention := map[string]int{
+		statistics.GranularityHour: r.hourlyRetention,
+		statistics.GranularityDay:  r.dailyRetention,
+	}
+	r.mutex.Unlock()
+
+	for granularity, days := range retention {
+		before := time.Now().AddDate(0, 0, -days)
+		if deleted, err := r.statisticsManager.CleanupUsageRollups(granularity, before); err != nil {
+			log.Printf("WARNING: Failed to cleanup %s usage rollups: %v", granularity, err)
+		} else if deleted > 0 {
+			log.Printf("Cleaned up %d %s usage rollups older than %d days", deleted, granularity, days)
+		}
+	}
+}
+
+// RecordAnalytics 记录一个客户端请求的用量分析样本；未启用用量汇总时忽略
+func (m *Manager) RecordAnalytics(sample AnalyticsSample) {
+	m.analytics.record(sample)
+}
+
+// UpdateAnalytics 热更新用量汇总配置
+func (m *Manager) UpdateAnalytics(cfg config.AnalyticsConfig) {
+	m.analytics.update(cfg)
+}
+
+// IsAnalyticsEnabled 是否启用了用量汇总
+func (m *Manager) IsAnalyticsEnabled() bool {
+	m.analytics.mutex.Lock()
+	defer m.analytics.mutex.Unlock()
+	return m.analytics.enabled
+}
+
+// FlushAnalytics 立即把内存中的汇总写入数据库，用于查询前和程序退出时
+func (m *Manager) FlushAnalytics() {
+	m.analytics.flush()
+}
+
+// QueryAnalytics 查询用量汇总，查询前先写入内存中尚未保存的汇总
+func (m *Manager) QueryAnalytics(filter statistics.UsageRollupFilter) ([]*statistics.UsageRollup, error) {
+	m.analytics.flush()
+	return m.statisticsManager.QueryUsageRollups(filter)
+}
@@ -0,0 +1,98 @@
+package endpoint
+
+import (
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+)
+
+// newTestAnalyticsRecorder 创建不启动后台写入的汇总记录器，由测试显式调用 flush
+func newTestAnalyticsRecorder(statisticsManager statistics.StatisticsManager) *analyticsRecorder {
+	r := &analyticsRecorder{
+		statisticsManager: statisticsManager,
+		pending:           make(map[string]*statistics.UsageRollup),
+		kick:              make(chan struct{}, 1),
+	}
+	r.update(config.AnalyticsConfig{})
+	return r
+}
+
+func TestAnalyticsRecorderBuckets(t *testing.T) {
+	statisticsManager := statistics.NewMemoryManager()
+	recorder := newTestAnalyticsRecorder(statisticsManager)
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.Local)
+	}
+	samples := []AnalyticsSample{
+		{Time: at(10, 10, 0), Endpoint: "a", Model: "m", Success: true, Latency: 100 * time.Millisecond},
+		{Time: at(10, 10, 59), Endpoint: "a", Model: "m", Success: false, Latency: 300 * time.Millisecond},
+		{Time: at(10, 11, 0), Endpoint: "b", Model: "m", Tags: []string{"x", "y"}, Success: true},
+		{Time: at(11, 0, 30), Endpoint: "a", Model: "m", Success: true, Usage: TokenUsage{InputTokens: 10, OutputTokens: 5}},
+	}
+	for _, sample := range samples {
+		recorder.record(sample)
+	}
+	recorder.flush()
+
+	query := func(granularity, dimension string) map[time.Time]int64 {
+		rollups, err := statisticsManager.QueryUsageRollups(statistics.UsageRollupFilter{Granularity: granularity, Dimension: dimension})
+		if err != nil {
+			t.Fatalf("QueryUsageRollups failed: %v", err)
+		}
+		requests := make(map[time.Time]int64)
+		for _, rollup := range rollups {
+			requests[rollup.BucketStart.Local()] += rollup.Requests
+		}
+		return requests
+	}
+
+	// 按本地时区的整点划分，以 UTC 存储
+	hourly := query(statistics.GranularityHour, statistics.DimensionTotal)
+	expectedHourly := map[time.Time]int64{at(10, 10, 0): 2, at(10, 11, 0): 1, at(11, 0, 0): 1}
+	if len(hourly) != len(expectedHourly) {
+		t.Fatalf("expected %d hourly buckets, got %v", len(expectedHourly), hourly)
+	}
+	for bucket, count := range expectedHourly {
+		if hourly[bucket] != count {
+			t.Errorf("hour %v: expected %d requests, got %d", bucket, count, hourly[bucket])
+		}
+	}
+
+	daily := query(statistics.GranularityDay, statistics.DimensionTotal)
+	if len(daily) != 2 || daily[at(10, 0, 0)] != 3 || daily[at(11, 0, 0)] != 1 {
+		t.Errorf("expected 3 requests on the 10th and 1 on the 11th, got %v", daily)
+	}
+
+	// 每个标签各计一次
+	tags, _ := statisticsManager.QueryUsageRollups(statistics.UsageRollupFilter{Granularity: statistics.GranularityDay, Dimension: statistics.DimensionTag})
+	if len(tags) != 2 {
+		t.Errorf("expected one rollup per tag, got %d", len(tags))
+	}
+
+	// 同一窗口再次记录并写入时与已保存的汇总合并
+	recorder.record(samples[0])
+	recorder.flush()
+	if hourly := query(statistics.GranularityHour, statistics.DimensionTotal); hourly[at(10, 10, 0)] != 3 {
+		t.Errorf("expected rerun to merge into the existing hour bucket, got %d", hourly[at(10, 10, 0)])
+	}
+	endpoints, _ := statisticsManager.QueryUsageRollups(statistics.UsageRollupFilter{Granularity: statistics.GranularityHour, Dimension: statistics.DimensionEndpoint, Keys: []string{"a"}})
+	if len(endpoints) != 2 || endpoints[0].Requests != 3 || endpoints[0].Errors != 1 {
+		t.Errorf("unexpected endpoint rollups after rerun: %+v", endpoints)
+	}
+}
+
+func TestAnalyticsRecorderDisabled(t *testing.T) {
+	statisticsManager := statistics.NewMemoryManager()
+	recorder := newTestAnalyticsRecorder(statisticsManager)
+	disabled := false
+	recorder.update(config.AnalyticsConfig{Enabled: &disabled})
+
+	recorder.record(AnalyticsSample{Time: time.Now(), Success: true})
+	recorder.flush()
+	if rollups, _ := statisticsManager.QueryUsageRollups(statistics.UsageRollupFilter{Granularity: statistics.GranularityHour, Dimension: statistics.DimensionTotal}); len(rollups) != 0 {
+		t.Errorf("expected no rollups while analytics is disabled, got %d", len(rollups))
+	}
+}
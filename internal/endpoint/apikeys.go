@@ -0,0 +1,246 @@
+package endpoint
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/utils"
+)
+
+// apiKeyState 多凭据端点中单个凭据的状态（内存中，不持久化）
+type apiKeyState struct {
+	value            string
+	history          *utils.CircularBuffer // 与端点 RequestHistory 相同的失败窗口
+	blacklistedUntil time.Time             // 零值或已过去表示可用
+	lastError        string
+	totalRequests    int
+	successRequests  int
+	lastUsed         time.Time
+}
+
+// APIKeyStatus 单个凭据的健康状态，供端点状态API展示；凭据本身只显示末4位
+type APIKeyStatus struct {
+	Index            int        `json:"index"`
+	Key              string     `json:"key"`
+	Available        bool       `json:"available"`
+	BlacklistedUntil *time.Time `json:"blacklisted_until,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+	TotalRequests    int        `json:"total_requests"`
+	SuccessRequests  int        `json:"success_requests"`
+	WindowRequests   int        `json:"window_requests"` // 失败窗口内的请求数
+	WindowFailures   int        `json:"window_failures"` // 失败窗口内的失败数
+	LastUsed         *time.Time `json:"last_used,omitempty"`
+}
+
+// newAPIKeyStates 为多个凭据创建状态；只有一个凭据或使用 OAuth 时返回nil
+func newAPIKeyStates(cfg config.EndpointConfig) []*apiKeyState {
+	credentials := cfg.Credentials()
+	if cfg.AuthType == "oauth" || len(credentials) < 2 {
+		return nil
+	}
+	states := make([]*apiKeyState, 0, len(credentials))
+	for _, value := range credentials {
+		states = append(states, &apiKeyState{
+			value:   value,
+			history: utils.NewCircularBuffer(100, 140*time.Second), // 100个记录，140秒窗口
+		})
+	}
+	return states
+}
+
+// preserveAPIKeyStates 热更新时按凭据值保留已有凭据的状态；调用方需持有两个端点的 mutex
+func (e *Endpoint) preserveAPIKeyStates(existing *Endpoint) {
+	previous := make(map[string]*apiKeyState, len(existing.apiKeys))
+	for _, key := range existing.apiKeys {
+		previous[key.value] = key
+	}
+	for i, key := range e.apiKeys {
+		if state, ok := previous[key.value]; ok {
+			e.apiKeys[i] = state
+		}
+	}
+	if len(e.apiKeys) > 0 {
+		e.nextKey = existing.nextKey % len(e.apiKeys)
+	}
+}
+
+// HasMultipleAPIKeys 端点是否配置了多个轮换的凭据
+func (e *Endpoint) HasMultipleAPIKeys() bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return len(e.apiKeys) > 0
+}
+
+// keyRotationSettings 返回凭据轮换策略和拉黑时长
+func (e *Endpoint) keyRotationSettings() (string, time.Duration) {
+	defaultCooldown, _ := time.ParseDuration(config.Default.KeyRotation.Cooldown)
+	if e.KeyRotation == nil {
+		return config.Default.KeyRotation.Strategy, defaultCooldown
+	}
+	return config.GetStringWithDefault(e.KeyRotation.Strategy, config.Default.KeyRotation.Strategy),
+		config.GetTimeoutDuration(e.KeyRotation.Cooldown, defaultCooldown)
+}
+
+// rotateStatusCodes 返回立即拉黑凭据并换下一个凭据重试的状态码
+func (e *Endpoint) rotateStatusCodes() []int {
+	if e.KeyRotation == nil || len(e.KeyRotation.RotateStatusCodes) == 0 {
+		return config.Default.KeyRotation.RotateStatusCodes
+	}
+	return e.KeyRotation.RotateStatusCodes
+}
+
+// SelectAPIKey 选择本次请求使用的凭据，返回凭据序号和值；单凭据端点返回 -1 和 AuthValue。
+// round_robin 从上次使用的下一个凭据开始，failover 总是从第一个开始，跳过被拉黑的凭据；
+// 所有凭据都被拉黑时使用最早解除拉黑的凭据
+func (e *Endpoint) SelectAPIKey() (int, string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if len(e.apiKeys) == 0 {
+		return -1, e.AuthValue
+	}
+
+	now := time.Now()
+	selected := e.selectAPIKeyLocked(now)
+	e.nextKey = (selected + 1) % len(e.apiKeys)
+	e.apiKeys[selected].lastUsed = now
+	return selected, e.apiKeys[selected].value
+}
+
+// PeekAPIKey 返回下一个请求会使用的凭据，但不推进轮换位置，供健康检查探测使用，避免探测打乱实际请求的轮换
+func (e *Endpoint) PeekAPIKey() (int, string) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if len(e.apiKeys) == 0 {
+		return -1, e.AuthValue
+	}
+	selected := e.selectAPIKeyLocked(time.Now())
+	return selected, e.apiKeys[selected].value
+}
+
+// selectAPIKeyLocked 按轮换策略挑选凭据序号；调用方需持有 mutex 且端点有多个凭据
+func (e *Endpoint) selectAPIKeyLocked(now time.Time) int {
+	strategy, _ := e.keyRotationSettings()
+	start := 0
+	if strategy == "round_robin" {
+		start = e.nextKey
+	}
+
+	for i := 0; i < len(e.apiKeys); i++ {
+		index := (start + i) % len(e.apiKeys)
+		if !now.Before(e.apiKeys[index].blacklistedUntil) {
+			return index
+		}
+	}
+	selected := start
+	for i, key := range e.apiKeys {
+		if key.blacklistedUntil.Before(e.apiKeys[selected].blacklistedUntil) {
+			selected = i
+		}
+	}
+	return selected
+}
+
+// RecordAPIKeyResult 记录凭据的一次请求结果。响应状态码属于 key_rotation.rotate_status_codes（默认 401、429）时立即拉黑该凭据，
+// 否则在失败窗口满足 CircularBuffer.ShouldMarkInactive 时拉黑；拉黑时长为 cooldown 与 retryAfter 中较长者。
+// 返回 true 表示该凭据因轮换状态码被拉黑且还有其他可用凭据，调用方应换凭据重试同一端点
+func (e *Endpoint) RecordAPIKeyResult(index int, success bool, statusCode int, retryAfter time.Duration, requestID, errMsg string) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if index < 0 || index >= len(e.apiKeys) {
+		return false
+	}
+	key := e.apiKeys[index]
+	now := time.Now()
+
+	key.history.Add(utils.RequestRecord{
+		Timestamp: now,
+		Success:   success,
+		RequestID: requestID,
+	})
+	key.totalRequests++
+	if success {
+		key.successRequests++
+		return false
+	}
+	if errMsg == "" && statusCode > 0 {
+		errMsg = fmt.Sprintf("HTTP %d", statusCode)
+	}
+	key.lastError = errMsg
+
+	rotate := false
+	for _, code := range e.rotateStatusCodes() {
+		if statusCode == code {
+			rotate = true
+			break
+		}
+	}
+	if !rotate && !key.history.ShouldMarkInactive(now) {
+		return false
+	}
+
+	_, cooldown := e.keyRotationSettings()
+	if retryAfter > cooldown {
+		cooldown = retryAfter
+	}
+	key.blacklistedUntil = now.Add(cooldown)
+	key.history.Clear()
+	log.Printf("API key #%d of endpoint %s blacklisted for %v: %s", index, e.Name, cooldown, errMsg)
+
+	if !rotate {
+		return false
+	}
+	for i, other := range e.apiKeys {
+		if i != index && !now.Before(other.blacklistedUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAPIKeyStatus 返回每个凭据的健康状态，单凭据端点返回nil
+func (e *Endpoint) GetAPIKeyStatus() []APIKeyStatus {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if len(e.apiKeys) == 0 {
+		return nil
+	}
+	now := time.Now()
+	statuses := make([]APIKeyStatus, 0, len(e.apiKeys))
+	for i, key := range e.apiKeys {
+		total, failed := key.history.GetWindowStats(now)
+		status := APIKeyStatus{
+			Index:           i,
+			Key:             maskAPIKey(key.value),
+			Available:       !now.Before(key.blacklistedUntil),
+			LastError:       key.lastError,
+			TotalRequests:   key.totalRequests,
+			SuccessRequests: key.successRequests,
+			WindowRequests:  total,
+			WindowFailures:  failed,
+		}
+		if !status.Available {
+			until := key.blacklistedUntil
+			status.BlacklistedUntil = &until
+		}
+		if !key.lastUsed.IsZero() {
+			lastUsed := key.lastUsed
+			status.LastUsed = &lastUsed
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// maskAPIKey 只保留凭据的末4位
+func maskAPIKey(value string) string {
+	if len(value) <= 8 {
+		return "****"
+	}
+	return "****" + value[len(value)-4:]
+}
@@ -0,0 +1,181 @@
+package endpoint
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+)
+
+// withAPIKeys 为测试端点配置三把凭据和轮换策略
+func withAPIKeys(rotation *config.KeyRotationConfig) func(*config.EndpointConfig) {
+	return func(cfg *config.EndpointConfig) {
+		cfg.AuthValue = "sk-key-0"
+		cfg.AuthValues = []string{"sk-key-1", "sk-key-2"}
+		cfg.KeyRotation = rotation
+	}
+}
+
+// selectKeys 连续选择 n 次凭据，返回凭据序号
+func selectKeys(ep *Endpoint, n int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i], _ = ep.SelectAPIKey()
+	}
+	return indexes
+}
+
+func equalIndexes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSelectAPIKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		rotation    *config.KeyRotationConfig
+		blacklisted []int
+		expected    []int
+	}{
+		{"round_robin by default", nil, nil, []int{0, 1, 2, 0}},
+		{"round_robin skips blacklisted key", nil, []int{1}, []int{0, 2, 0, 2}},
+		{"failover uses first available key", &config.KeyRotationConfig{Strategy: "failover"}, nil, []int{0, 0, 0}},
+		{"failover skips blacklisted key", &config.KeyRotationConfig{Strategy: "failover"}, []int{0}, []int{1, 1}},
+	}
+	for _, tt := range tests {
+		ep := newTestEndpoint("keys-test", withAPIKeys(tt.rotation))
+		for _, index := range tt.blacklisted {
+			ep.apiKeys[index].blacklistedUntil = time.Now().Add(time.Minute)
+		}
+		if got := selectKeys(ep, len(tt.expected)); !equalIndexes(got, tt.expected) {
+			t.Errorf("%s: expected keys %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	// 单凭据端点返回 -1 和 AuthValue
+	single := NewEndpoint(config.EndpointConfig{Name: "single", AuthType: "api_key", AuthValue: "sk-only", Enabled: true})
+	if index, value := single.SelectAPIKey(); index != -1 || value != "sk-only" {
+		t.Errorf("expected -1 and auth_value for single key endpoint, got %d %q", index, value)
+	}
+}
+
+func TestSelectAPIKeyAllBlacklisted(t *testing.T) {
+	ep := newTestEndpoint("keys-test", withAPIKeys(nil))
+	now := time.Now()
+	ep.apiKeys[0].blacklistedUntil = now.Add(3 * time.Minute)
+	ep.apiKeys[1].blacklistedUntil = now.Add(time.Minute)
+	ep.apiKeys[2].blacklistedUntil = now.Add(2 * time.Minute)
+
+	// 所有凭据都被拉黑时使用最早解除拉黑的凭据
+	if index, value := ep.SelectAPIKey(); index != 1 || value != "sk-key-1" {
+		t.Errorf("expected earliest unblocked key 1, got %d %q", index, value)
+	}
+}
+
+func TestPeekAPIKeyDoesNotAdvanceRotation(t *testing.T) {
+	ep := newTestEndpoint("keys-test", withAPIKeys(nil))
+	ep.SelectAPIKey()
+
+	for i := 0; i < 3; i++ {
+		if index, _ := ep.PeekAPIKey(); index != 1 {
+			t.Fatalf("peek %d: expected next key 1, got %d", i, index)
+		}
+	}
+	if index, _ := ep.SelectAPIKey(); index != 1 {
+		t.Errorf("expected rotation to continue with key 1 after peeks, got %d", index)
+	}
+	if ep.apiKeys[2].lastUsed != (time.Time{}) {
+		t.Errorf("expected peek not to mark key as used")
+	}
+}
+
+func TestRecordAPIKeyResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		rotation    *config.KeyRotationConfig
+		statusCode  int
+		rotate      bool
+		blacklisted bool
+	}{
+		{"401 rotates by default", nil, 401, true, true},
+		{"429 rotates by default", nil, 429, true, true},
+		{"500 keeps key", nil, 500, false, false},
+		{"403 keeps key by default", nil, 403, false, false},
+		{"configured 403 rotates", &config.KeyRotationConfig{RotateStatusCodes: []int{403}}, 403, true, true},
+		{"configured codes replace defaults", &config.KeyRotationConfig{RotateStatusCodes: []int{403}}, 401, false, false},
+	}
+	for _, tt := range tests {
+		ep := newTestEndpoint("keys-test", withAPIKeys(tt.rotation))
+		rotate := ep.RecordAPIKeyResult(0, false, tt.statusCode, 0, "req-1", "")
+		if rotate != tt.rotate {
+			t.Errorf("%s: expected rotate=%v, got %v", tt.name, tt.rotate, rotate)
+		}
+		status := ep.GetAPIKeyStatus()[0]
+		if status.Available == tt.blacklisted {
+			t.Errorf("%s: expected blacklisted=%v, got available=%v", tt.name, tt.blacklisted, status.Available)
+		}
+		if status.LastError != fmt.Sprintf("HTTP %d", tt.statusCode) {
+			t.Errorf("%s: unexpected last error %q", tt.name, status.LastError)
+		}
+	}
+}
+
+func TestRecordAPIKeyResultCooldownAndRetryAfter(t *testing.T) {
+	ep := newTestEndpoint("keys-test", withAPIKeys(&config.KeyRotationConfig{Cooldown: "1m"}))
+
+	before := time.Now()
+	ep.RecordAPIKeyResult(0, false, 401, 0, "req-1", "")
+	until := ep.GetAPIKeyStatus()[0].BlacklistedUntil
+	if until == nil || until.Before(before.Add(time.Minute)) || until.After(time.Now().Add(time.Minute)) {
+		t.Errorf("expected key blacklisted for cooldown, got %v", until)
+	}
+
+	// Retry-After 比 cooldown 更长时以其为准
+	ep.RecordAPIKeyResult(1, false, 429, 10*time.Minute, "req-2", "")
+	until = ep.GetAPIKeyStatus()[1].BlacklistedUntil
+	if until == nil || until.Before(before.Add(10*time.Minute)) {
+		t.Errorf("expected key blacklisted for Retry-After, got %v", until)
+	}
+
+	// 最后一个可用凭据被拉黑时不再要求换凭据重试
+	if ep.RecordAPIKeyResult(2, false, 401, 0, "req-3", "") {
+		t.Errorf("expected no rotation when every key is blacklisted")
+	}
+}
+
+func TestRecordAPIKeyResultFailureWindow(t *testing.T) {
+	ep := newTestEndpoint("keys-test", withAPIKeys(nil))
+
+	// 成功请求不拉黑凭据
+	if ep.RecordAPIKeyResult(0, true, 200, 0, "req-0", "") {
+		t.Errorf("expected no rotation on success")
+	}
+	// 非轮换状态码在失败窗口内全部失败后拉黑，但不换凭据重试
+	ep.RecordAPIKeyResult(1, false, 500, 0, "req-1", "")
+	if !ep.GetAPIKeyStatus()[1].Available {
+		t.Fatalf("expected key to stay available after a single failure")
+	}
+	if ep.RecordAPIKeyResult(1, false, 500, 0, "req-2", "upstream error") {
+		t.Errorf("expected no rotation for non-rotation status code")
+	}
+	status := ep.GetAPIKeyStatus()[1]
+	if status.Available || status.LastError != "upstream error" {
+		t.Errorf("expected key blacklisted after failure window, got %+v", status)
+	}
+	if status.TotalRequests != 2 || status.SuccessRequests != 0 {
+		t.Errorf("unexpected request counts: %+v", status)
+	}
+
+	// 序号越界时忽略
+	if ep.RecordAPIKeyResult(5, false, 401, 0, "req-3", "") {
+		t.Errorf("expected out of range index to be ignored")
+	}
+}
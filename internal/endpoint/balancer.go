@@ -0,0 +1,211 @@
+package endpoint
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"claude-code-companion/internal/config"
+)
+
+// Balancer 按标签组的负载均衡策略从候选端点中挑选首选端点
+// 候选端点已按优先级排序，策略之间的平局按优先级决定
+type Balancer struct {
+	mutex          sync.Mutex
+	config         config.LoadBalancingConfig
+	currentWeights map[string]int // 平滑加权轮询的当前权重，按端点ID
+}
+
+func NewBalancer(cfg config.LoadBalancingConfig) *Balancer {
+	return &Balancer{
+		config:         cfg,
+		currentWeights: make(map[string]int),
+	}
+}
+
+// UpdateConfig 热更新负载均衡配置
+func (b *Balancer) UpdateConfig(cfg config.LoadBalancingConfig) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.config = cfg
+	b.currentWeights = make(map[string]int)
+}
+
+// StrategyFor 返回请求标签对应的策略：第一个标签全部被请求包含的组，空标签组只匹配无标签请求
+func (b *Balancer) StrategyFor(tags []string) string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.strategyFor(tags)
+}
+
+func (b *Balancer) strategyFor(tags []string) string {
+	for _, group := range b.config.Groups {
+		if len(group.Tags) == 0 {
+			if len(tags) == 0 {
+				return group.Strategy
+			}
+			continue
+		}
+		if len(tags) > 0 && containsAllTags(tags, group.Tags) {
+			return group.Strategy
+		}
+	}
+	return config.GetStringWithDefault(b.config.Strategy, config.Default.LoadBalancing.Strategy)
+}
+
+// EWMAAlpha 返回延迟EWMA的平滑系数
+func (b *Balancer) EWMAAlpha() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.config.EWMAAlpha <= 0 {
+		return config.Default.LoadBalancing.EWMAAlpha
+	}
+	return b.config.EWMAAlpha
+}
+
+// FailurePenalty 返回失败请求计入延迟EWMA的最小延迟
+func (b *Balancer) FailurePenalty() time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	defaultPenalty, _ := time.ParseDuration(config.Default.LoadBalancing.FailurePenalty)
+	return config.GetTimeoutDuration(b.config.FailurePenalty, defaultPenalty)
+}
+
+// Pick 从按优先级排序的候选端点中挑选首选端点
+func (b *Balancer) Pick(candidates []*Endpoint, tags []string) *Endpoint {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.strategyFor(tags) {
+	case "weighted":
+		return b.pickWeighted(candidates)
+	case "least_inflight":
+		return pickLeastInFlight(candidates)
+	case "ewma":
+		return pickEWMA(candidates)
+	default:
+		return candidates[0]
+	}
+}
+
+// pickWeighted 平滑加权轮询（nginx 算法）：权重高的端点按比例获得更多请求，且不会连续集中
+func (b *Balancer) pickWeighted(candidates []*Endpoint) *Endpoint {
+	var best *Endpoint
+	total := 0
+	for _, ep := range candidates {
+		weight := ep.GetWeight()
+		total += weight
+		b.currentWeights[ep.ID] += weight
+		if best == nil || b.currentWeights[ep.ID] > b.currentWeights[best.ID] {
+			best = ep
+		}
+	}
+	b.currentWeights[best.ID] -= total
+	return best
+}
+
+// pickLeastInFlight 选择正在进行的请求最少的端点
+func pickLeastInFlight(candidates []*Endpoint) *Endpoint {
+	best := candidates[0]
+	for _, ep := range candidates[1:] {
+		if ep.GetInFlight() < best.GetInFlight() {
+			best = ep
+		}
+	}
+	return best
+}
+
+// pickEWMA 选择 延迟EWMA × (进行中请求数+1) 最小的端点。
+// 还没有延迟样本的端点（如新加入的端点）按其他候选端点EWMA的平均值计分，
+// 避免它因为得分为0而在拿到第一个样本前接收全部请求；所有端点都没有样本时按进行中请求数挑选
+func pickEWMA(candidates []*Endpoint) *Endpoint {
+	var sampled int
+	var total time.Duration
+	for _, ep := range candidates {
+		if latency := ep.GetLatencyEWMA(); latency > 0 {
+			sampled++
+			total += latency
+		}
+	}
+	seed := time.Duration(1)
+	if sampled > 0 {
+		seed = total / time.Duration(sampled)
+	}
+
+	score := func(ep *Endpoint) float64 {
+		latency := ep.GetLatencyEWMA()
+		if latency == 0 {
+			latency = seed
+		}
+		return float64(latency) * float64(ep.GetInFlight()+1)
+	}
+
+	best := candidates[0]
+	bestScore := score(best)
+	for _, ep := range candidates[1:] {
+		if s := score(ep); s < bestScore {
+			best, bestScore = ep, s
+		}
+	}
+	return best
+}
+
+func containsAllTags(tags, required []string) bool {
+	tagSet := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tagSet[tag] = true
+	}
+	for _, tag := range required {
+		if !tagSet[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetWeight 获取 weighted 策略中的权重
+func (e *Endpoint) GetWeight() int {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	if e.Weight <= 0 {
+		return config.Default.LoadBalancing.Weight
+	}
+	return e.Weight
+}
+
+// BeginRequest 记录一个开始的上游请求
+func (e *Endpoint) BeginRequest() {
+	atomic.AddInt64(&e.inFlight, 1)
+}
+
+// EndRequest 记录一个结束的上游请求
+func (e *Endpoint) EndRequest() {
+	atomic.AddInt64(&e.inFlight, -1)
+}
+
+// GetInFlight 获取正在进行的上游请求数
+func (e *Endpoint) GetInFlight() int64 {
+	return atomic.LoadInt64(&e.inFlight)
+}
+
+// RecordLatency 用新的响应头延迟样本（或失败请求的惩罚延迟）更新EWMA
+func (e *Endpoint) RecordLatency(latency time.Duration, alpha float64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.latencyEWMA == 0 {
+		e.latencyEWMA = latency
+		return
+	}
+	e.latencyEWMA = time.Duration(alpha*float64(latency) + (1-alpha)*float64(e.latencyEWMA))
+}
+
+// GetLatencyEWMA 获取响应头延迟的EWMA，0表示还没有样本
+func (e *Endpoint) GetLatencyEWMA() time.Duration {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.latencyEWMA
+}
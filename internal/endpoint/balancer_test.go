@@ -0,0 +1,236 @@
+package endpoint
+
+import (
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+)
+
+// balancerTestEndpoint 创建负载均衡测试用端点，inFlight 和 latency 为0时不设置
+func balancerTestEndpoint(name string, weight int, inFlight int64, latency time.Duration) *Endpoint {
+	ep := NewEndpoint(config.EndpointConfig{
+		Name:         name,
+		URL:          "https://" + name + ".example.com",
+		EndpointType: "anthropic",
+		AuthType:     "api_key",
+		AuthValue:    "sk-test",
+		Enabled:      true,
+		Weight:       weight,
+	})
+	ep.inFlight = inFlight
+	ep.latencyEWMA = latency
+	return ep
+}
+
+func TestBalancerStrategyFor(t *testing.T) {
+	balancer := NewBalancer(config.LoadBalancingConfig{
+		Strategy: "weighted",
+		Groups: []config.LoadBalancingGroupConfig{
+			{Tags: []string{"pool", "fast"}, Strategy: "ewma"},
+			{Tags: []string{"pool"}, Strategy: "least_inflight"},
+			{Tags: []string{}, Strategy: "priority"},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		tags     []string
+		expected string
+	}{
+		{"untagged request matches empty tag group", nil, "priority"},
+		{"all group tags present", []string{"fast", "pool"}, "ewma"},
+		{"first matching group wins", []string{"pool", "fast", "extra"}, "ewma"},
+		{"subset of group tags falls through", []string{"pool"}, "least_inflight"},
+		{"unmatched tags use default strategy", []string{"other"}, "weighted"},
+	}
+	for _, tt := range tests {
+		if got := balancer.StrategyFor(tt.tags); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+
+	// 未配置策略时使用默认的 priority
+	if got := NewBalancer(config.LoadBalancingConfig{}).StrategyFor([]string{"pool"}); got != config.Default.LoadBalancing.Strategy {
+		t.Errorf("expected default strategy %s, got %s", config.Default.LoadBalancing.Strategy, got)
+	}
+}
+
+func TestBalancerPick(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategy   string
+		candidates []*Endpoint
+		expected   string
+	}{
+		{
+			name:     "priority picks first candidate",
+			strategy: "priority",
+			candidates: []*Endpoint{
+				balancerTestEndpoint("a", 1, 5, 0),
+				balancerTestEndpoint("b", 1, 0, 0),
+			},
+			expected: "a",
+		},
+		{
+			name:     "least_inflight picks fewest in-flight requests",
+			strategy: "least_inflight",
+			candidates: []*Endpoint{
+				balancerTestEndpoint("a", 1, 3, 0),
+				balancerTestEndpoint("b", 1, 1, 0),
+				balancerTestEndpoint("c", 1, 2, 0),
+			},
+			expected: "b",
+		},
+		{
+			name:     "least_inflight tie goes to priority order",
+			strategy: "least_inflight",
+			candidates: []*Endpoint{
+				balancerTestEndpoint("a", 1, 1, 0),
+				balancerTestEndpoint("b", 1, 1, 0),
+			},
+			expected: "a",
+		},
+		{
+			name:     "ewma weighs latency by in-flight requests",
+			strategy: "ewma",
+			candidates: []*Endpoint{
+				balancerTestEndpoint("a", 1, 3, 100*time.Millisecond), // 400ms
+				balancerTestEndpoint("b", 1, 0, 300*time.Millisecond), // 300ms
+			},
+			expected: "b",
+		},
+		{
+			name:     "ewma seeds unsampled endpoint with mean latency",
+			strategy: "ewma",
+			candidates: []*Endpoint{
+				balancerTestEndpoint("a", 1, 0, 100*time.Millisecond), // 100ms
+				balancerTestEndpoint("b", 1, 0, 300*time.Millisecond), // 300ms
+				balancerTestEndpoint("new", 1, 0, 0),                  // 200ms
+			},
+			expected: "a",
+		},
+		{
+			name:     "ewma unsampled endpoint wins when busy endpoints are slower",
+			strategy: "ewma",
+			candidates: []*Endpoint{
+				balancerTestEndpoint("a", 1, 2, 200*time.Millisecond), // 600ms
+				balancerTestEndpoint("new", 1, 0, 0),                  // 200ms
+			},
+			expected: "new",
+		},
+		{
+			name:     "ewma without samples falls back to in-flight requests",
+			strategy: "ewma",
+			candidates: []*Endpoint{
+				balancerTestEndpoint("a", 1, 2, 0),
+				balancerTestEndpoint("b", 1, 1, 0),
+			},
+			expected: "b",
+		},
+	}
+
+	for _, tt := range tests {
+		balancer := NewBalancer(config.LoadBalancingConfig{Strategy: tt.strategy})
+		if got := balancer.Pick(tt.candidates, nil); got.Name != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got.Name)
+		}
+	}
+
+	if got := NewBalancer(config.LoadBalancingConfig{Strategy: "ewma"}).Pick(nil, nil); got != nil {
+		t.Errorf("expected nil for no candidates, got %s", got.Name)
+	}
+}
+
+func TestBalancerPickWeighted(t *testing.T) {
+	balancer := NewBalancer(config.LoadBalancingConfig{Strategy: "weighted"})
+	candidates := []*Endpoint{
+		balancerTestEndpoint("a", 3, 0, 0),
+		balancerTestEndpoint("b", 1, 0, 0),
+		balancerTestEndpoint("c", 0, 0, 0), // 未设置权重时默认为1
+	}
+
+	var sequence string
+	counts := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		ep := balancer.Pick(candidates, nil)
+		counts[ep.Name]++
+		sequence += ep.Name
+	}
+	if counts["a"] != 6 || counts["b"] != 2 || counts["c"] != 2 {
+		t.Errorf("expected picks in 3:1:1 ratio, got %v", counts)
+	}
+	// 平滑加权轮询不会连续三次选择同一个端点
+	for i := 0; i+2 < len(sequence); i++ {
+		if sequence[i] == sequence[i+1] && sequence[i] == sequence[i+2] {
+			t.Errorf("expected smooth weighted sequence, got %s", sequence)
+			break
+		}
+	}
+
+	// 热更新配置后重置当前权重
+	balancer.UpdateConfig(config.LoadBalancingConfig{Strategy: "weighted"})
+	if ep := balancer.Pick(candidates, nil); ep.Name != "a" {
+		t.Errorf("expected highest weight endpoint first after config update, got %s", ep.Name)
+	}
+}
+
+func TestBalancerPickUsesGroupStrategy(t *testing.T) {
+	balancer := NewBalancer(config.LoadBalancingConfig{
+		Strategy: "priority",
+		Groups: []config.LoadBalancingGroupConfig{
+			{Tags: []string{"pool"}, Strategy: "least_inflight"},
+		},
+	})
+	candidates := []*Endpoint{
+		balancerTestEndpoint("a", 1, 2, 0),
+		balancerTestEndpoint("b", 1, 0, 0),
+	}
+
+	tests := []struct {
+		tags     []string
+		expected string
+	}{
+		{nil, "a"},
+		{[]string{"pool"}, "b"},
+		{[]string{"other"}, "a"},
+	}
+	for _, tt := range tests {
+		if got := balancer.Pick(candidates, tt.tags); got.Name != tt.expected {
+			t.Errorf("tags %v: expected %s, got %s", tt.tags, tt.expected, got.Name)
+		}
+	}
+}
+
+func TestRecordFailedLatencyPenalizesEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		penalty  string
+		elapsed  time.Duration
+		expected time.Duration
+	}{
+		{"fast failure counts as penalty", "10s", 50 * time.Millisecond, 10 * time.Second},
+		{"slow timeout counts as elapsed time", "10s", 30 * time.Second, 30 * time.Second},
+		{"default penalty", "", time.Millisecond, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		manager := &Manager{selector: NewSelector(nil, config.LoadBalancingConfig{Strategy: "ewma", FailurePenalty: tt.penalty})}
+		ep := balancerTestEndpoint("failing", 1, 0, 0)
+		manager.RecordFailedLatency(ep, tt.elapsed)
+		if got := ep.GetLatencyEWMA(); got != tt.expected {
+			t.Errorf("%s: expected latency %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	// 一直失败的端点不再因为没有样本而被优先挑选
+	manager := &Manager{selector: NewSelector(nil, config.LoadBalancingConfig{Strategy: "ewma"})}
+	healthy := balancerTestEndpoint("healthy", 1, 0, 0)
+	failing := balancerTestEndpoint("failing", 1, 0, 0)
+	manager.RecordLatency(healthy, 500*time.Millisecond)
+	manager.RecordFailedLatency(failing, 100*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if got := manager.selector.GetBalancer().Pick([]*Endpoint{failing, healthy}, nil); got != healthy {
+			t.Fatalf("pick %d: expected healthy endpoint, got %s", i, got.Name)
+		}
+	}
+}
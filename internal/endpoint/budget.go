@@ -0,0 +1,346 @@
+package endpoint
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+)
+
+// budgetPeriods 预算窗口，按本地时间的自然小时/天/月对齐
+var budgetPeriods = []string{"hourly", "daily", "monthly"}
+
+// TokenUsage 一次请求中上游报告的token用量
+type TokenUsage struct {
+	InputTokens      int64 // 不含缓存的输入token
+	OutputTokens     int64
+	CacheReadTokens  int64
+	CacheWriteTokens int64
+}
+
+// TotalInputTokens 返回包含缓存读写的输入token数
+func (u TokenUsage) TotalInputTokens() int64 {
+	return u.InputTokens + u.CacheReadTokens + u.CacheWriteTokens
+}
+
+// IsZero 是否没有任何用量
+func (u TokenUsage) IsZero() bool {
+	return u.TotalInputTokens() == 0 && u.OutputTokens == 0
+}
+
+// Cost 按价格（美元/百万token）计算用量的费用
+func (u TokenUsage) Cost(inputPrice, outputPrice, cacheReadPrice, cacheWritePrice float64) float64 {
+	return (float64(u.InputTokens)*inputPrice +
+		float64(u.OutputTokens)*outputPrice +
+		float64(u.CacheReadTokens)*cacheReadPrice +
+		float64(u.CacheWriteTokens)*cacheWritePrice) / 1e6
+}
+
+// budgetCounter 单个预算窗口的累计用量
+type budgetCounter struct {
+	windowStart  time.Time
+	inputTokens  int64
+	outputTokens int64
+	cost         float64
+}
+
+// BudgetWindow 预算窗口的用量与限制，供管理界面展示
+type BudgetWindow struct {
+	Period          string    `json:"period"`
+	WindowStart     time.Time `json:"window_start"`
+	ResetsAt        time.Time `json:"resets_at"`
+	InputTokens     int64     `json:"input_tokens"`
+	OutputTokens    int64     `json:"output_tokens"`
+	Cost            float64   `json:"cost"`
+	MaxInputTokens  int64     `json:"max_input_tokens,omitempty"`
+	MaxOutputTokens int64     `json:"max_output_tokens,omitempty"`
+	MaxCost         float64   `json:"max_cost,omitempty"`
+	Exceeded        bool      `json:"exceeded"`
+}
+
+// budgetWindowStart 返回 t 所在窗口的起始时间
+func budgetWindowStart(period string, t time.Time) time.Time {
+	t = t.Local()
+	switch period {
+	case "hourly":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case "daily":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// budgetWindowEnd 返回从 start 开始的窗口的重置时间
+func budgetWindowEnd(period string, start time.Time) time.Time {
+	switch period {
+	case "hourly":
+		return start.Add(time.Hour)
+	case "daily":
+		return start.AddDate(0, 0, 1)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+func budgetLimitFor(budget *config.BudgetConfig, period string) *config.BudgetLimitConfig {
+	switch period {
+	case "hourly":
+		return budget.Hourly
+	case "daily":
+		return budget.Daily
+	default:
+		return budget.Monthly
+	}
+}
+
+// budgetCost 按端点预算中的价格（美元/百万token）计算用量的费用
+func budgetCost(budget *config.BudgetConfig, usage TokenUsage) float64 {
+	return usage.Cost(budget.InputPrice, budget.OutputPrice, budget.CacheReadPrice, budget.CacheWritePrice)
+}
+
+// exceededLimits 返回已达到的限制描述，没有达到时返回空
+func (b *budgetCounter) exceededLimits(limit *config.BudgetLimitConfig) []string {
+	var reasons []string
+	if limit.MaxInputTokens > 0 && b.inputTokens >= limit.MaxInputTokens {
+		reasons = append(reasons, fmt.Sprintf("input tokens %d/%d", b.inputTokens, limit.MaxInputTokens))
+	}
+	if limit.MaxOutputTokens > 0 && b.outputTokens >= limit.MaxOutputTokens {
+		reasons = append(reasons, fmt.Sprintf("output tokens %d/%d", b.outputTokens, limit.MaxOutputTokens))
+	}
+	if limit.MaxCost > 0 && b.cost >= limit.MaxCost {
+		reasons = append(reasons, fmt.Sprintf("cost $%.4f/$%.2f", b.cost, limit.MaxCost))
+	}
+	return reasons
+}
+
+// HasBudget 是否配置了端点预算
+func (e *Endpoint) HasBudget() bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.Budget != nil
+}
+
+// BudgetCost 按端点预算中的价格计算用量的费用；没有配置预算价格时返回 false
+func (e *Endpoint) BudgetCost(usage TokenUsage) (float64, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.Budget == nil || (e.Budget.InputPrice == 0 && e.Budget.OutputPrice == 0) {
+		return 0, false
+	}
+	return budgetCost(e.Budget, usage), true
+}
+
+// counterLocked 返回当前窗口的计数器，窗口已重置时清零；调用方需持有 e.mutex
+func (e *Endpoint) counterLocked(period string, now time.Time) *budgetCounter {
+	if e.budgetUsage == nil {
+		e.budgetUsage = make(map[string]*budgetCounter)
+	}
+	start := budgetWindowStart(period, now)
+	counter, exists := e.budgetUsage[period]
+	if !exists || !counter.windowStart.Equal(start) {
+		counter = &budgetCounter{windowStart: start}
+		e.budgetUsage[period] = counter
+	}
+	return counter
+}
+
+// RecordUsage 把一次成功请求的用量累计到各预算窗口，返回需要持久化的窗口记录；
+// 任一限制达到时端点被拉黑直到对应窗口重置
+func (e *Endpoint) RecordUsage(usage TokenUsage, requestID string) []*statistics.BudgetUsage {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.Budget == nil || usage.IsZero() {
+		return nil
+	}
+
+	now := time.Now()
+	cost := budgetCost(e.Budget, usage)
+	records := make([]*statistics.BudgetUsage, 0, len(budgetPeriods))
+	for _, period := range budgetPeriods {
+		if budgetLimitFor(e.Budget, period) == nil {
+			continue
+		}
+		counter := e.counterLocked(period, now)
+		counter.inputTokens += usage.TotalInputTokens()
+		counter.outputTokens += usage.OutputTokens
+		counter.cost += cost
+		records = append(records, &statistics.BudgetUsage{
+			EndpointID:   e.ID,
+			Period:       period,
+			WindowStart:  counter.windowStart,
+			InputTokens:  counter.inputTokens,
+			OutputTokens: counter.outputTokens,
+			Cost:         counter.cost,
+		})
+	}
+
+	e.evaluateBudgetLocked(now, requestID)
+	return records
+}
+
+// restoreBudgetUsage 恢复持久化的窗口用量，只保留仍处于当前窗口的记录
+func (e *Endpoint) restoreBudgetUsage(records []*statistics.BudgetUsage) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.Budget == nil {
+		return
+	}
+
+	now := time.Now()
+	for _, record := range records {
+		if !record.WindowStart.Equal(budgetWindowStart(record.Period, now)) {
+			continue
+		}
+		counter := e.counterLocked(record.Period, now)
+		counter.inputTokens = record.InputTokens
+		counter.outputTokens = record.OutputTokens
+		counter.cost = record.Cost
+	}
+	e.evaluateBudgetLocked(now, "")
+}
+
+// evaluateBudgetLocked 检查当前窗口用量是否达到限制：达到时拉黑端点直到最晚的重置时间，
+// 限制解除（例如配置调高）时恢复因预算被拉黑的端点；调用方需持有 e.mutex
+func (e *Endpoint) evaluateBudgetLocked(now time.Time, requestID string) {
+	var reasons []string
+	var resetsAt time.Time
+	if e.Budget != nil {
+		for _, period := range budgetPeriods {
+			limit := budgetLimitFor(e.Budget, period)
+			if limit == nil {
+				continue
+			}
+			counter := e.counterLocked(period, now)
+			exceeded := counter.exceededLimits(limit)
+			if len(exceeded) == 0 {
+				continue
+			}
+			reasons = append(reasons, fmt.Sprintf("%s %s", period, strings.Join(exceeded, ", ")))
+			if end := budgetWindowEnd(period, counter.windowStart); end.After(resetsAt) {
+				resetsAt = end
+			}
+		}
+	}
+
+	if len(reasons) == 0 {
+		if !e.budgetBlockedUntil.IsZero() {
+			e.budgetBlockedUntil = time.Time{}
+			if e.Status == StatusInactive {
+				e.markActiveLocked("budget no longer exceeded")
+			}
+		}
+		return
+	}
+
+	if e.budgetBlockedUntil.Equal(resetsAt) && e.Status == StatusInactive {
+		return
+	}
+	e.budgetBlockedUntil = resetsAt
+	errorSummary := fmt.Sprintf("Budget exceeded: %s", strings.Join(reasons, "; "))
+	e.setStatusLocked(StatusInactive, statistics.CauseBudget, errorSummary)
+
+	var causingRequestIDs []string
+	if requestID != "" {
+		causingRequestIDs = []string{requestID}
+	}
+	e.blacklistMutex.Lock()
+	e.BlacklistReason = &BlacklistReason{
+		CausingRequestIDs: causingRequestIDs,
+		BlacklistedAt:     now,
+		ErrorSummary:      errorSummary,
+		ResetsAt:          &resetsAt,
+	}
+	e.blacklistMutex.Unlock()
+}
+
+// budgetBlockedLocked 端点是否因预算耗尽被拉黑；调用方需持有 e.mutex
+func (e *Endpoint) budgetBlockedLocked(now time.Time) bool {
+	return now.Before(e.budgetBlockedUntil)
+}
+
+// IsBudgetExceeded 端点当前是否因预算耗尽被拉黑
+func (e *Endpoint) IsBudgetExceeded() bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.budgetBlockedLocked(time.Now())
+}
+
+// ReleaseExpiredBudgetBlock 预算窗口已重置时恢复因预算被拉黑的端点，返回是否恢复
+func (e *Endpoint) ReleaseExpiredBudgetBlock() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.budgetBlockedUntil.IsZero() || e.budgetBlockedLocked(time.Now()) {
+		return false
+	}
+	e.budgetBlockedUntil = time.Time{}
+	if e.Status != StatusInactive {
+		return false
+	}
+	e.markActiveLocked("budget window reset")
+	return true
+}
+
+// GetBudgetStatus 返回各预算窗口的当前用量，未配置预算时返回nil
+func (e *Endpoint) GetBudgetStatus() []BudgetWindow {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.Budget == nil {
+		return nil
+	}
+
+	now := time.Now()
+	windows := make([]BudgetWindow, 0, len(budgetPeriods))
+	for _, period := range budgetPeriods {
+		limit := budgetLimitFor(e.Budget, period)
+		if limit == nil {
+			continue
+		}
+		window := BudgetWindow{
+			Period:          period,
+			WindowStart:     budgetWindowStart(period, now),
+			MaxInputTokens:  limit.MaxInputTokens,
+			MaxOutputTokens: limit.MaxOutputTokens,
+			MaxCost:         limit.MaxCost,
+		}
+		window.ResetsAt = budgetWindowEnd(period, window.WindowStart)
+		if counter, exists := e.budgetUsage[period]; exists && counter.windowStart.Equal(window.WindowStart) {
+			window.InputTokens = counter.inputTokens
+			window.OutputTokens = counter.outputTokens
+			window.Cost = counter.cost
+			window.Exceeded = len(counter.exceededLimits(limit)) > 0
+		}
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// RecordUsage 累计端点预算用量并持久化，达到预算时记录日志；同时扣除 tpm 令牌桶
+func (m *Manager) RecordUsage(ep *Endpoint, usage TokenUsage, requestID string) {
+	ep.GetLimiter().RecordTokens(usage.TotalInputTokens() + usage.OutputTokens)
+
+	wasExceeded := ep.IsBudgetExceeded()
+	records := ep.RecordUsage(usage, requestID)
+
+	if m.statisticsManager != nil {
+		for _, record := range records {
+			if err := m.statisticsManager.SaveBudgetUsage(record); err != nil {
+				log.Printf("WARNING: Failed to persist budget usage for endpoint %s: %v", ep.Name, err)
+			}
+		}
+	}
+
+	if !wasExceeded && ep.IsBudgetExceeded() {
+		if reason := ep.GetBlacklistReason(); reason != nil {
+			log.Printf("Endpoint %s blacklisted: %s", ep.Name, reason.ErrorSummary)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package endpoint
+
+import (
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+)
+
+// withBudget 给测试端点配置预算
+func withBudget(budget *config.BudgetConfig) func(*config.EndpointConfig) {
+	return func(cfg *config.EndpointConfig) { cfg.Budget = budget }
+}
+
+func TestBudgetWindowBoundaries(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, minute, second int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, 0, time.Local)
+	}
+
+	tests := []struct {
+		name   string
+		period string
+		now    time.Time
+		start  time.Time
+		end    time.Time
+	}{
+		{"hourly mid hour", "hourly", at(2026, 3, 10, 10, 30, 0), at(2026, 3, 10, 10, 0, 0), at(2026, 3, 10, 11, 0, 0)},
+		{"hourly last second", "hourly", at(2026, 3, 10, 10, 59, 59), at(2026, 3, 10, 10, 0, 0), at(2026, 3, 10, 11, 0, 0)},
+		{"hourly on boundary", "hourly", at(2026, 3, 10, 11, 0, 0), at(2026, 3, 10, 11, 0, 0), at(2026, 3, 10, 12, 0, 0)},
+		{"hourly before midnight", "hourly", at(2026, 3, 10, 23, 15, 0), at(2026, 3, 10, 23, 0, 0), at(2026, 3, 11, 0, 0, 0)},
+		{"daily", "daily", at(2026, 3, 10, 23, 59, 59), at(2026, 3, 10, 0, 0, 0), at(2026, 3, 11, 0, 0, 0)},
+		{"daily on boundary", "daily", at(2026, 3, 11, 0, 0, 0), at(2026, 3, 11, 0, 0, 0), at(2026, 3, 12, 0, 0, 0)},
+		{"daily end of month", "daily", at(2026, 1, 31, 12, 0, 0), at(2026, 1, 31, 0, 0, 0), at(2026, 2, 1, 0, 0, 0)},
+		{"monthly", "monthly", at(2026, 1, 31, 23, 0, 0), at(2026, 1, 1, 0, 0, 0), at(2026, 2, 1, 0, 0, 0)},
+		{"monthly leap february", "monthly", at(2028, 2, 29, 8, 0, 0), at(2028, 2, 1, 0, 0, 0), at(2028, 3, 1, 0, 0, 0)},
+		{"monthly end of year", "monthly", at(2026, 12, 31, 23, 59, 59), at(2026, 12, 1, 0, 0, 0), at(2027, 1, 1, 0, 0, 0)},
+	}
+	for _, tt := range tests {
+		start := budgetWindowStart(tt.period, tt.now)
+		if !start.Equal(tt.start) {
+			t.Errorf("%s: expected window start %v, got %v", tt.name, tt.start, start)
+		}
+		if end := budgetWindowEnd(tt.period, start); !end.Equal(tt.end) {
+			t.Errorf("%s: expected window end %v, got %v", tt.name, tt.end, end)
+		}
+	}
+}
+
+func TestRecordUsageBlacklistsEndpoint(t *testing.T) {
+	ep := newTestEndpoint("budget-test", withBudget(&config.BudgetConfig{
+		Hourly:      &config.BudgetLimitConfig{MaxInputTokens: 100},
+		Monthly:     &config.BudgetLimitConfig{MaxCost: 100},
+		InputPrice:  3,
+		OutputPrice: 15,
+	}))
+
+	// 输入token包含缓存读写
+	records := ep.RecordUsage(TokenUsage{InputTokens: 40, CacheReadTokens: 20, OutputTokens: 10}, "req-1")
+	if len(records) != 2 || records[0].Period != "hourly" || records[1].Period != "monthly" {
+		t.Fatalf("expected hourly and monthly records, got %+v", records)
+	}
+	if records[0].InputTokens != 60 || records[0].OutputTokens != 10 {
+		t.Errorf("unexpected hourly record: %+v", records[0])
+	}
+	if !ep.IsAvailable() || ep.IsBudgetExceeded() {
+		t.Fatalf("expected endpoint available below budget")
+	}
+	if records := ep.RecordUsage(TokenUsage{}, "req-empty"); records != nil {
+		t.Errorf("expected no records for empty usage, got %+v", records)
+	}
+
+	ep.RecordUsage(TokenUsage{InputTokens: 50}, "req-2")
+	if ep.IsAvailable() || !ep.IsBudgetExceeded() {
+		t.Fatalf("expected endpoint blacklisted after exceeding hourly budget")
+	}
+	reason := ep.GetBlacklistReason()
+	hourlyEnd := budgetWindowEnd("hourly", budgetWindowStart("hourly", time.Now()))
+	if reason == nil || reason.ResetsAt == nil || !reason.ResetsAt.Equal(hourlyEnd) {
+		t.Fatalf("expected blacklist until the hourly window resets at %v, got %+v", hourlyEnd, reason)
+	}
+	if len(reason.CausingRequestIDs) != 1 || reason.CausingRequestIDs[0] != "req-2" {
+		t.Errorf("expected req-2 as causing request, got %v", reason.CausingRequestIDs)
+	}
+
+	windows := ep.GetBudgetStatus()
+	if len(windows) != 2 || !windows[0].Exceeded || windows[1].Exceeded {
+		t.Errorf("expected only the hourly window exceeded, got %+v", windows)
+	}
+}
+
+func TestReleaseExpiredBudgetBlock(t *testing.T) {
+	ep := newTestEndpoint("budget-test", withBudget(&config.BudgetConfig{Hourly: &config.BudgetLimitConfig{MaxOutputTokens: 10}}))
+	if ep.ReleaseExpiredBudgetBlock() {
+		t.Errorf("expected nothing to release without a budget block")
+	}
+
+	ep.RecordUsage(TokenUsage{OutputTokens: 10}, "req-1")
+	if ep.IsAvailable() {
+		t.Fatalf("expected endpoint blacklisted after reaching the output budget")
+	}
+	if ep.ReleaseExpiredBudgetBlock() {
+		t.Errorf("expected block to stay until the window resets")
+	}
+
+	// 模拟窗口重置：计数器属于上一个窗口，拉黑时间已过
+	ep.mutex.Lock()
+	ep.budgetBlockedUntil = time.Now().Add(-time.Second)
+	ep.budgetUsage["hourly"].windowStart = budgetWindowStart("hourly", time.Now()).Add(-time.Hour)
+	ep.mutex.Unlock()
+
+	if !ep.ReleaseExpiredBudgetBlock() {
+		t.Fatalf("expected endpoint released after the window reset")
+	}
+	if !ep.IsAvailable() || ep.IsBudgetExceeded() || ep.GetBlacklistReason() != nil {
+		t.Errorf("expected endpoint active without blacklist reason after release")
+	}
+	if ep.ReleaseExpiredBudgetBlock() {
+		t.Errorf("expected release to happen only once")
+	}
+	if windows := ep.GetBudgetStatus(); windows[0].OutputTokens != 0 || windows[0].Exceeded {
+		t.Errorf("expected new window to start from zero, got %+v", windows[0])
+	}
+
+	// 新窗口重新开始计数
+	if records := ep.RecordUsage(TokenUsage{OutputTokens: 4}, "req-2"); records[0].OutputTokens != 4 {
+		t.Errorf("expected counter reset for the new window, got %+v", records[0])
+	}
+	if !ep.IsAvailable() {
+		t.Errorf("expected endpoint available below budget in the new window")
+	}
+}
+
+func TestRestoreBudgetUsage(t *testing.T) {
+	ep := newTestEndpoint("budget-test", withBudget(&config.BudgetConfig{
+		Hourly: &config.BudgetLimitConfig{MaxInputTokens: 100},
+		Daily:  &config.BudgetLimitConfig{MaxInputTokens: 100},
+	}))
+	now := time.Now()
+	ep.restoreBudgetUsage([]*statistics.BudgetUsage{
+		// 上一个小时窗口的记录被忽略
+		{EndpointID: ep.ID, Period: "hourly", WindowStart: budgetWindowStart("hourly", now).Add(-time.Hour), InputTokens: 500},
+		{EndpointID: ep.ID, Period: "daily", WindowStart: budgetWindowStart("daily", now), InputTokens: 150},
+	})
+
+	windows := ep.GetBudgetStatus()
+	if windows[0].InputTokens != 0 || windows[1].InputTokens != 150 {
+		t.Errorf("expected only the current daily window restored, got %+v", windows)
+	}
+	if ep.IsAvailable() || !ep.IsBudgetExceeded() {
+		t.Errorf("expected restored daily usage over budget to blacklist the endpoint")
+	}
+}
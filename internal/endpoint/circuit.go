@@ -0,0 +1,307 @@
+package endpoint
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+	"claude-code-companion/internal/utils"
+)
+
+// CircuitState 熔断器状态
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // 正常放行
+	CircuitOpen     CircuitState = "open"      // 熔断中，直到 openUntil 不接收请求
+	CircuitHalfOpen CircuitState = "half_open" // 按比例放行少量实时请求试探
+)
+
+// CircuitTransition 熔断器的一次状态转换
+type CircuitTransition struct {
+	From   CircuitState `json:"from"`
+	To     CircuitState `json:"to"`
+	At     time.Time    `json:"at"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+// CircuitStatus 熔断器的当前状态与转换历史，供端点状态API展示
+type CircuitStatus struct {
+	State             CircuitState        `json:"state"`
+	OpenUntil         *time.Time          `json:"open_until,omitempty"`
+	OpenCount         int                 `json:"open_count"`          // 连续打开次数，决定下一次打开时长
+	HalfOpenSuccesses int                 `json:"half_open_successes"` // 半开状态下已成功的试探请求数
+	Transitions       []CircuitTransition `json:"transitions"`         // 最近的状态转换，最新的在前
+}
+
+// circuitSettings 熔断器配置的快照
+type circuitSettings struct {
+	enabled           bool
+	openDuration      time.Duration
+	maxOpenDuration   time.Duration
+	multiplier        float64
+	halfOpenRatio     float64
+	halfOpenSuccesses int
+	historySize       int
+}
+
+// openDurationFor 第 n 次连续打开的时长：open_duration × multiplier^(n-1)，不超过 max_open_duration
+func (s circuitSettings) openDurationFor(openCount int) time.Duration {
+	d := float64(s.openDuration) * math.Pow(s.multiplier, float64(openCount-1))
+	if d > float64(s.maxOpenDuration) {
+		return s.maxOpenDuration
+	}
+	return time.Duration(d)
+}
+
+// CircuitBreakerPolicy 所有端点共享的熔断器配置，支持热更新
+type CircuitBreakerPolicy struct {
+	mutex    sync.RWMutex
+	settings circuitSettings
+}
+
+func NewCircuitBreakerPolicy(cfg config.CircuitBreakerConfig) *CircuitBreakerPolicy {
+	p := &CircuitBreakerPolicy{}
+	p.UpdateConfig(cfg)
+	return p
+}
+
+// UpdateConfig 热更新熔断器配置，已打开的熔断器按原时长继续
+func (p *CircuitBreakerPolicy) UpdateConfig(cfg config.CircuitBreakerConfig) {
+	defaultOpen, _ := time.ParseDuration(config.Default.CircuitBreaker.OpenDuration)
+	defaultMaxOpen, _ := time.ParseDuration(config.Default.CircuitBreaker.MaxOpenDuration)
+
+	settings := circuitSettings{
+		enabled:           cfg.Enabled,
+		openDuration:      config.GetTimeoutDuration(cfg.OpenDuration, defaultOpen),
+		maxOpenDuration:   config.GetTimeoutDuration(cfg.MaxOpenDuration, defaultMaxOpen),
+		multiplier:        cfg.Multiplier,
+		halfOpenRatio:     cfg.HalfOpenRatio,
+		halfOpenSuccesses: config.GetIntWithDefault(cfg.HalfOpenSuccesses, config.Default.CircuitBreaker.HalfOpenSuccesses),
+		historySize:       config.GetIntWithDefault(cfg.HistorySize, config.Default.CircuitBreaker.HistorySize),
+	}
+	if settings.multiplier < 1 {
+		settings.multiplier = config.Default.CircuitBreaker.Multiplier
+	}
+	if settings.halfOpenRatio <= 0 {
+		settings.halfOpenRatio = config.Default.CircuitBreaker.HalfOpenRatio
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.settings = settings
+}
+
+// snapshot 返回当前配置，nil 策略视为未启用
+func (p *CircuitBreakerPolicy) snapshot() circuitSettings {
+	if p == nil {
+		return circuitSettings{}
+	}
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.settings
+}
+
+// IsEnabled 是否启用熔断器
+func (p *CircuitBreakerPolicy) IsEnabled() bool {
+	return p.snapshot().enabled
+}
+
+// circuitBreaker 单个端点的熔断器状态，由 Endpoint.mutex 保护
+type circuitBreaker struct {
+	state             CircuitState
+	openUntil         time.Time
+	openCount         int
+	lastClosedAt      time.Time
+	halfOpenRequests  int // 进入半开后经过的匹配请求数
+	halfOpenProbes    int // 进入半开后放行的试探请求数
+	halfOpenSuccesses int
+	transitions       []CircuitTransition
+}
+
+// circuitStateLocked 返回熔断器状态，打开时长已过时转为半开；调用方需持有 e.mutex
+func (e *Endpoint) circuitStateLocked(now time.Time, settings circuitSettings) CircuitState {
+	cb := &e.circuit
+	if cb.state == "" {
+		cb.state = CircuitClosed
+	}
+	if cb.state == CircuitOpen && !now.Before(cb.openUntil) {
+		cb.halfOpenRequests, cb.halfOpenProbes, cb.halfOpenSuccesses = 0, 0, 0
+		e.transitionCircuitLocked(CircuitHalfOpen, cb.openUntil, "open duration elapsed", settings)
+	}
+	return cb.state
+}
+
+// transitionCircuitLocked 切换熔断器状态并记录转换历史；调用方需持有 e.mutex
+func (e *Endpoint) transitionCircuitLocked(to CircuitState, at time.Time, reason string, settings circuitSettings) {
+	cb := &e.circuit
+	cb.transitions = append(cb.transitions, CircuitTransition{From: cb.state, To: to, At: at, Reason: reason})
+	historySize := settings.historySize
+	if historySize <= 0 {
+		historySize = config.Default.CircuitBreaker.HistorySize
+	}
+	if len(cb.transitions) > historySize {
+		cb.transitions = append([]CircuitTransition(nil), cb.transitions[len(cb.transitions)-historySize:]...)
+	}
+	cb.state = to
+}
+
+// openCircuitLocked 打开熔断器：端点不可用直到打开时长结束，连续打开时时长按指数增长；调用方需持有 e.mutex
+func (e *Endpoint) openCircuitLocked(now time.Time, reason string, settings circuitSettings) {
+	cb := &e.circuit
+	switch {
+	case cb.state == CircuitHalfOpen:
+		cb.openCount++
+	case !cb.lastClosedAt.IsZero() && now.Sub(cb.lastClosedAt) < settings.maxOpenDuration:
+		// 关闭后不久再次熔断视为抖动，继续放大打开时长
+		cb.openCount++
+	default:
+		cb.openCount = 1
+	}
+	openDuration := settings.openDurationFor(cb.openCount)
+	cb.openUntil = now.Add(openDuration)
+	e.transitionCircuitLocked(CircuitOpen, now, reason, settings)
+	errorSummary := fmt.Sprintf("Circuit open for %v: %s", openDuration, reason)
+	e.setStatusLocked(StatusInactive, statistics.CauseCircuit, errorSummary)
+
+	openUntil := cb.openUntil
+	e.blacklistMutex.Lock()
+	e.BlacklistReason = &BlacklistReason{
+		BlacklistedAt:     now,
+		CausingRequestIDs: e.RequestHistory.GetRecentFailureRequestIDs(now),
+		ErrorSummary:      errorSummary,
+		ResetsAt:          &openUntil,
+	}
+	e.blacklistMutex.Unlock()
+}
+
+// recordCircuitResultLocked 把请求结果交给熔断器，返回 true 表示已由熔断器处理状态；
+// 未启用熔断器时返回 false，由调用方按原有逻辑标记端点；调用方需持有 e.mutex
+func (e *Endpoint) recordCircuitResultLocked(success bool, requestID string, now time.Time) bool {
+	settings := e.circuitPolicy.snapshot()
+	if !settings.enabled {
+		return false
+	}
+
+	cb := &e.circuit
+	switch e.circuitStateLocked(now, settings) {
+	case CircuitClosed:
+		if success {
+			// 熔断器关闭时端点因其他原因（如增强保护）不可用，成功请求仍按原有逻辑恢复
+			return false
+		}
+		if e.Status == StatusActive && e.RequestHistory.ShouldMarkInactive(now) {
+			failures := len(e.RequestHistory.GetRecentFailureRequestIDs(now))
+			e.openCircuitLocked(now, fmt.Sprintf("%d consecutive failures", failures), settings)
+		}
+		return true
+	case CircuitHalfOpen:
+		if !success {
+			e.openCircuitLocked(now, fmt.Sprintf("half-open probe %s failed", requestID), settings)
+			return true
+		}
+		cb.halfOpenSuccesses++
+		if cb.halfOpenSuccesses >= settings.halfOpenSuccesses {
+			reason := fmt.Sprintf("%d half-open probes succeeded", cb.halfOpenSuccesses)
+			e.transitionCircuitLocked(CircuitClosed, now, reason, settings)
+			cb.lastClosedAt = now
+			if !e.budgetBlockedLocked(now) {
+				e.markActiveLocked("circuit closed: " + reason)
+			}
+		}
+		return true
+	default:
+		// 打开期间完成的请求（熔断前已发出）不影响熔断器
+		return true
+	}
+}
+
+// closeCircuitLocked 端点被恢复（健康检查、手动重置、预算窗口重置）时关闭熔断器；调用方需持有 e.mutex
+func (e *Endpoint) closeCircuitLocked(reason string) {
+	if e.circuit.state == "" || e.circuit.state == CircuitClosed {
+		return
+	}
+	now := time.Now()
+	e.transitionCircuitLocked(CircuitClosed, now, reason, e.circuitPolicy.snapshot())
+	e.circuit.lastClosedAt = now
+}
+
+// AdmitCircuitProbe 半开状态下按 half_open_ratio 决定是否把这次请求交给端点试探，第一次请求总是放行
+func (e *Endpoint) AdmitCircuitProbe() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	settings := e.circuitPolicy.snapshot()
+	now := time.Now()
+	if !settings.enabled || !e.Enabled || e.budgetBlockedLocked(now) || e.circuitStateLocked(now, settings) != CircuitHalfOpen {
+		return false
+	}
+	cb := &e.circuit
+	cb.halfOpenRequests++
+	if float64(cb.halfOpenProbes) >= float64(cb.halfOpenRequests)*settings.halfOpenRatio {
+		return false
+	}
+	cb.halfOpenProbes++
+	return true
+}
+
+// IsCircuitManaged 端点是否因熔断器打开或半开而不可用，此时由实时请求试探恢复，不再进行合成健康检查
+func (e *Endpoint) IsCircuitManaged() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	settings := e.circuitPolicy.snapshot()
+	return settings.enabled && e.circuitStateLocked(time.Now(), settings) != CircuitClosed
+}
+
+// GetCircuitStatus 返回熔断器状态和转换历史，未启用且从未转换过时返回nil
+func (e *Endpoint) GetCircuitStatus() *CircuitStatus {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	settings := e.circuitPolicy.snapshot()
+	state := e.circuitStateLocked(time.Now(), settings)
+	cb := &e.circuit
+	if !settings.enabled && len(cb.transitions) == 0 {
+		return nil
+	}
+
+	status := &CircuitStatus{
+		State:             state,
+		OpenCount:         cb.openCount,
+		HalfOpenSuccesses: cb.halfOpenSuccesses,
+		Transitions:       make([]CircuitTransition, 0, len(cb.transitions)),
+	}
+	if state == CircuitOpen {
+		openUntil := cb.openUntil
+		status.OpenUntil = &openUntil
+	}
+	for i := len(cb.transitions) - 1; i >= 0; i-- {
+		status.Transitions = append(status.Transitions, cb.transitions[i])
+	}
+	return status
+}
+
+// SelectCircuitProbe 在匹配请求标签的半开端点中选择一个接收本次请求作为试探，没有时返回nil
+func (m *Manager) SelectCircuitProbe(tags []string) *Endpoint {
+	if !m.circuitPolicy.IsEnabled() {
+		return nil
+	}
+	for _, ep := range m.selector.GetAllEndpoints() {
+		if len(utils.FilterEndpointsForTags([]utils.EndpointSorter{ep}, tags)) == 0 {
+			continue
+		}
+		if ep.AdmitCircuitProbe() {
+			return ep
+		}
+	}
+	return nil
+}
+
+// UpdateCircuitBreaker 热更新熔断器配置
+func (m *Manager) UpdateCircuitBreaker(cfg config.CircuitBreakerConfig) {
+	m.circuitPolicy.UpdateConfig(cfg)
+}
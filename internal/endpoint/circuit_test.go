@@ -0,0 +1,241 @@
+package endpoint
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/utils"
+)
+
+// recordAt 以指定时间记录一次请求结果，与 RecordRequest 的流程一致
+func recordAt(ep *Endpoint, success bool, requestID string, at time.Time) {
+	ep.mutex.Lock()
+	defer ep.mutex.Unlock()
+	ep.RequestHistory.Add(utils.RequestRecord{Timestamp: at, Success: success, RequestID: requestID})
+	ep.recordCircuitResultLocked(success, requestID, at)
+}
+
+// circuitStateAt 返回指定时间的熔断器状态
+func circuitStateAt(ep *Endpoint, at time.Time) CircuitState {
+	ep.mutex.Lock()
+	defer ep.mutex.Unlock()
+	return ep.circuitStateLocked(at, ep.circuitPolicy.snapshot())
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	ep := newTestEndpoint("circuit-test")
+	ep.circuitPolicy = NewCircuitBreakerPolicy(config.CircuitBreakerConfig{
+		Enabled:           true,
+		OpenDuration:      "30s",
+		MaxOpenDuration:   "10m",
+		Multiplier:        2,
+		HalfOpenSuccesses: 2,
+	})
+	base := time.Now()
+	at := func(seconds int) time.Time { return base.Add(time.Duration(seconds) * time.Second) }
+
+	// 窗口内只有一次失败时保持关闭
+	recordAt(ep, false, "req-1", at(0))
+	if state := circuitStateAt(ep, at(0)); state != CircuitClosed || !ep.IsAvailable() {
+		t.Fatalf("expected closed circuit after a single failure, got %s", state)
+	}
+
+	// 窗口内全部失败时打开，端点不可用直到打开时长结束
+	recordAt(ep, false, "req-2", at(1))
+	if state := circuitStateAt(ep, at(1)); state != CircuitOpen || ep.IsAvailable() {
+		t.Fatalf("expected open circuit after consecutive failures, got %s", state)
+	}
+	if openUntil := ep.circuit.openUntil; !openUntil.Equal(at(31)) {
+		t.Errorf("expected first open duration of 30s, open until %v", openUntil.Sub(base))
+	}
+	if state := circuitStateAt(ep, at(30)); state != CircuitOpen {
+		t.Errorf("expected circuit to stay open before open duration elapsed, got %s", state)
+	}
+
+	// 打开时长结束后半开，需要连续 half_open_successes 次成功才关闭
+	if state := circuitStateAt(ep, at(31)); state != CircuitHalfOpen {
+		t.Fatalf("expected half-open circuit after open duration, got %s", state)
+	}
+	recordAt(ep, true, "probe-1", at(32))
+	if state := circuitStateAt(ep, at(32)); state != CircuitHalfOpen || ep.IsAvailable() {
+		t.Fatalf("expected circuit to stay half-open after one successful probe, got %s", state)
+	}
+	recordAt(ep, true, "probe-2", at(33))
+	if state := circuitStateAt(ep, at(33)); state != CircuitClosed || !ep.IsAvailable() {
+		t.Fatalf("expected closed circuit after enough successful probes, got %s", state)
+	}
+
+	// 关闭后不久再次熔断，打开时长翻倍
+	recordAt(ep, false, "req-3", at(40))
+	recordAt(ep, false, "req-4", at(41))
+	if state := circuitStateAt(ep, at(41)); state != CircuitOpen {
+		t.Fatalf("expected circuit to reopen, got %s", state)
+	}
+	if openUntil := ep.circuit.openUntil; !openUntil.Equal(at(101)) {
+		t.Errorf("expected second open duration of 60s, open until %v", openUntil.Sub(base))
+	}
+
+	// 半开试探失败时重新打开，打开时长继续翻倍
+	if state := circuitStateAt(ep, at(101)); state != CircuitHalfOpen {
+		t.Fatalf("expected half-open circuit, got %s", state)
+	}
+	recordAt(ep, false, "probe-3", at(102))
+	if state := circuitStateAt(ep, at(102)); state != CircuitOpen || ep.IsAvailable() {
+		t.Fatalf("expected failed probe to reopen the circuit, got %s", state)
+	}
+	if openUntil := ep.circuit.openUntil; !openUntil.Equal(at(222)) {
+		t.Errorf("expected third open duration of 120s, open until %v", openUntil.Sub(base))
+	}
+
+	expected := []struct{ from, to CircuitState }{
+		{CircuitClosed, CircuitOpen},
+		{CircuitOpen, CircuitHalfOpen},
+		{CircuitHalfOpen, CircuitClosed},
+		{CircuitClosed, CircuitOpen},
+		{CircuitOpen, CircuitHalfOpen},
+		{CircuitHalfOpen, CircuitOpen},
+	}
+	transitions := ep.circuit.transitions
+	if len(transitions) != len(expected) {
+		t.Fatalf("expected %d transitions, got %d: %+v", len(expected), len(transitions), transitions)
+	}
+	for i, want := range expected {
+		if transitions[i].From != want.from || transitions[i].To != want.to {
+			t.Errorf("transition %d: expected %s -> %s, got %s -> %s", i, want.from, want.to, transitions[i].From, transitions[i].To)
+		}
+	}
+}
+
+func TestCircuitBreakerOpenDurationResetsAfterStablePeriod(t *testing.T) {
+	ep := newTestEndpoint("circuit-test")
+	ep.circuitPolicy = NewCircuitBreakerPolicy(config.CircuitBreakerConfig{
+		Enabled:           true,
+		OpenDuration:      "30s",
+		MaxOpenDuration:   "5m",
+		Multiplier:        2,
+		HalfOpenSuccesses: 1,
+	})
+	base := time.Now().Add(-time.Hour)
+	at := func(seconds int) time.Time { return base.Add(time.Duration(seconds) * time.Second) }
+
+	recordAt(ep, false, "req-1", at(0))
+	recordAt(ep, false, "req-2", at(1))
+	circuitStateAt(ep, at(31))
+	recordAt(ep, true, "probe-1", at(32))
+	if state := circuitStateAt(ep, at(32)); state != CircuitClosed {
+		t.Fatalf("expected closed circuit, got %s", state)
+	}
+
+	// 关闭超过 max_open_duration 后再次熔断，从第一次的打开时长重新开始
+	recordAt(ep, false, "req-3", at(400))
+	recordAt(ep, false, "req-4", at(401))
+	if ep.circuit.openCount != 1 || !ep.circuit.openUntil.Equal(at(431)) {
+		t.Errorf("expected open duration to reset to 30s, got open count %d, open until %v", ep.circuit.openCount, ep.circuit.openUntil.Sub(base))
+	}
+}
+
+func TestCircuitOpenDurationFor(t *testing.T) {
+	settings := circuitSettings{
+		openDuration:    30 * time.Second,
+		maxOpenDuration: 10 * time.Minute,
+		multiplier:      2,
+	}
+	tests := []struct {
+		openCount int
+		expected  time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 10 * time.Minute}, // 达到上限
+		{20, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := settings.openDurationFor(tt.openCount); got != tt.expected {
+			t.Errorf("openDurationFor(%d) = %v, want %v", tt.openCount, got, tt.expected)
+		}
+	}
+}
+
+func TestAdmitCircuitProbe(t *testing.T) {
+	ep := newTestEndpoint("circuit-test")
+	ep.circuitPolicy = NewCircuitBreakerPolicy(config.CircuitBreakerConfig{
+		Enabled:       true,
+		OpenDuration:  "30s",
+		HalfOpenRatio: 0.25,
+	})
+
+	// 关闭状态不需要试探
+	if ep.AdmitCircuitProbe() {
+		t.Fatal("expected no probe while the circuit is closed")
+	}
+
+	// 一小时前打开，打开时长早已结束，第一次调用时转为半开
+	opened := time.Now().Add(-time.Hour)
+	recordAt(ep, false, "req-1", opened)
+	recordAt(ep, false, "req-2", opened.Add(time.Second))
+	if ep.circuit.state != CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", ep.circuit.state)
+	}
+
+	// 半开时第一次请求总是放行，之后按 half_open_ratio 放行
+	var admitted []bool
+	for i := 0; i < 8; i++ {
+		admitted = append(admitted, ep.AdmitCircuitProbe())
+	}
+	expected := []bool{true, false, false, false, true, false, false, false}
+	for i := range expected {
+		if admitted[i] != expected[i] {
+			t.Fatalf("expected admissions %v, got %v", expected, admitted)
+		}
+	}
+	if ep.circuit.state != CircuitHalfOpen {
+		t.Errorf("expected half-open circuit, got %s", ep.circuit.state)
+	}
+
+	// 禁用熔断器后不再放行试探
+	ep.circuitPolicy.UpdateConfig(config.CircuitBreakerConfig{Enabled: false})
+	if ep.AdmitCircuitProbe() {
+		t.Error("expected no probe when the circuit breaker is disabled")
+	}
+}
+
+func TestAdmitCircuitProbeConcurrent(t *testing.T) {
+	ep := newTestEndpoint("circuit-test")
+	ep.circuitPolicy = NewCircuitBreakerPolicy(config.CircuitBreakerConfig{
+		Enabled:       true,
+		OpenDuration:  "30s",
+		HalfOpenRatio: 0.25,
+	})
+	opened := time.Now().Add(-time.Hour)
+	recordAt(ep, false, "req-1", opened)
+	recordAt(ep, false, "req-2", opened.Add(time.Second))
+
+	// 并发请求同时到达半开端点时，放行的试探数量仍然严格按比例计算
+	const requests = 100
+	var wg sync.WaitGroup
+	var admitted int32
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ep.AdmitCircuitProbe() {
+				atomic.AddInt32(&admitted, 1)
+			}
+			ep.GetCircuitStatus()
+		}()
+	}
+	wg.Wait()
+
+	if admitted != requests/4 {
+		t.Errorf("expected %d probes admitted, got %d", requests/4, admitted)
+	}
+	if ep.circuit.halfOpenRequests != requests || int(ep.circuit.halfOpenProbes) != requests/4 {
+		t.Errorf("expected %d requests and %d probes counted, got %d and %d", requests, requests/4, ep.circuit.halfOpenRequests, ep.circuit.halfOpenProbes)
+	}
+}
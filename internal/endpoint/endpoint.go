@@ -33,6 +33,9 @@ type BlacklistReason struct {
 	
 	// 失效时的错误信息摘要
 	ErrorSummary string `json:"error_summary"`
+	
+	// 预期恢复时间（因预算耗尽被拉黑时为预算窗口重置时间）
+	ResetsAt *time.Time `json:"resets_at,omitempty"`
 }
 
 // 删除不再需要的 RequestRecord 定义，因为已经移到 utils 包
@@ -41,7 +44,7 @@ type Endpoint struct {
 	ID                string                   `json:"id"`
 	Name              string                   `json:"name"`
 	URL               string                   `json:"url"`
-	EndpointType      string                   `json:"endpoint_type"` // "anthropic" | "openai" 等
+	EndpointType      string                   `json:"endpoint_type"` // "anthropic" | "openai" | "openai_responses" | "gemini" 等
 	PathPrefix        string                   `json:"path_prefix,omitempty"` // OpenAI端点的路径前缀
 	AuthType          string                   `json:"auth_type"`
 	AuthValue         string                   `json:"auth_value"`
@@ -57,6 +60,12 @@ type Endpoint struct {
 	RateLimitReset      *int64                 `json:"rate_limit_reset,omitempty"`      // Anthropic-Ratelimit-Unified-Reset
 	RateLimitStatus     *string                `json:"rate_limit_status,omitempty"`     // Anthropic-Ratelimit-Unified-Status
 	EnhancedProtection  bool                   `json:"enhanced_protection,omitempty"`   // 官方帐号增强保护：allowed_warning时即禁用端点
+	RetryPolicy         *config.RetryPolicyConfig `json:"retry_policy,omitempty"`        // 端点级重试策略，覆盖全局 retry_policy
+	Weight              int                    `json:"weight"`                          // weighted 负载均衡策略中的权重
+	Budget              *config.BudgetConfig   `json:"budget,omitempty"`                // 端点预算
+	Limits              *config.EndpointLimitsConfig `json:"limits,omitempty"`          // 客户端并发与速率限制
+	HealthCheck         *config.EndpointHealthCheckConfig `json:"health_check,omitempty"` // 健康检查探测方式
+	KeyRotation         *config.KeyRotationConfig `json:"key_rotation,omitempty"`       // 多凭据轮换策略
 	Status              Status                   `json:"status"`
 	LastCheck           time.Time                `json:"last_check"`
 	FailureCount        int                      `json:"failure_count"`
@@ -75,6 +84,42 @@ type Endpoint struct {
 	// 新增：上次记录跳过健康检查日志的时间（用于减少日志频率）
 	lastSkipLogTime time.Time `json:"-"`
 	
+	// 负载均衡指标（内存中，不持久化）
+	inFlight    int64         // 正在进行的请求数，原子操作
+	latencyEWMA time.Duration // 成功请求响应头延迟的指数加权移动平均，0表示还没有样本
+	
+	// OAuth 刷新结果计数，由 Manager 持有，未设置时不统计
+	oauthRefreshes *oauthRefreshCounters
+	
+	// 预算用量（按窗口持久化到 statistics.db）
+	budgetUsage        map[string]*budgetCounter // 按预算窗口（hourly/daily/monthly）
+	budgetBlockedUntil time.Time                 // 因预算耗尽被拉黑直到该时间，零值表示未拉黑
+	
+	// 客户端并发与速率限制，未配置时为nil
+	limiter *Limiter
+	
+	// 上游速率限制响应头解析出的剩余额度（接近耗尽时持久化到配置文件）
+	rateLimitCapacity       *RateLimitCapacity
+	rateLimitExhaustedUntil time.Time // 剩余额度接近耗尽直到该时间，零值或已过去表示未耗尽
+	rateLimitReason         string
+	
+	// 最近的健康检查结果（内存中，不持久化）
+	healthCheckHistory []HealthCheckResult
+	
+	// 熔断器：策略由 Manager 共享，状态与转换历史在内存中
+	circuitPolicy *CircuitBreakerPolicy
+	circuit       circuitBreaker
+	
+	// 状态变化、健康检查、额度与 OAuth 刷新事件的记录器，由 Manager 设置
+	events *eventRecorder
+	
+	// 多凭据的轮换状态，只有一个凭据时为nil（使用 AuthValue）
+	apiKeys []*apiKeyState
+	nextKey int // round_robin 下一个尝试的凭据序号
+	
+	// 上游模型列表缓存，由 Manager 的模型发现循环刷新
+	modelCatalog *ModelCatalog
+	
 	mutex               sync.RWMutex
 }
 
@@ -82,14 +127,14 @@ func NewEndpoint(cfg config.EndpointConfig) *Endpoint {
 	// 如果没有指定 endpoint_type，使用统一默认值
 	endpointType := config.GetStringWithDefault(cfg.EndpointType, config.Default.Endpoint.Type)
 	
-	return &Endpoint{
+	ep := &Endpoint{
 		ID:                generateID(cfg.Name),
 		Name:              cfg.Name,
 		URL:               cfg.URL,
 		EndpointType:      endpointType,
 		PathPrefix:        cfg.PathPrefix,  // 新增：复制PathPrefix
 		AuthType:          cfg.AuthType,
-		AuthValue:         cfg.AuthValue,
+		AuthValue:         cfg.GetAuthValue(), // 多凭据时为第一个凭据
 		Enabled:           config.GetBoolWithDefault(cfg.Enabled, true, config.Default.Endpoint.Enabled),
 		Priority:          config.GetIntWithDefault(cfg.Priority, config.Default.Endpoint.Priority),
 		Tags:              cfg.Tags,       // 新增：从配置中复制tags
@@ -102,10 +147,20 @@ func NewEndpoint(cfg config.EndpointConfig) *Endpoint {
 		RateLimitReset:      cfg.RateLimitReset,      // 新增：从配置加载rate limit reset状态
 		RateLimitStatus:     cfg.RateLimitStatus,     // 新增：从配置加载rate limit status状态
 		EnhancedProtection:  cfg.EnhancedProtection,  // 新增：从配置加载官方帐号增强保护设置
+		RetryPolicy:         cfg.RetryPolicy,         // 端点级重试策略
+		Weight:              config.GetIntWithDefault(cfg.Weight, config.Default.LoadBalancing.Weight),
+		Budget:              cfg.Budget,              // 端点预算
+		Limits:              cfg.Limits,              // 客户端并发与速率限制
+		HealthCheck:         cfg.HealthCheck,         // 健康检查探测方式
+		KeyRotation:         cfg.KeyRotation,         // 多凭据轮换策略
+		apiKeys:             newAPIKeyStates(cfg),
+		limiter:             NewLimiter(cfg.Limits),
 		Status:            StatusActive,
 		LastCheck:         time.Now(),
 		RequestHistory:    utils.NewCircularBuffer(100, 140*time.Second), // 100个记录，140秒窗口
 	}
+	ep.restoreRateLimitCapacity(cfg.RateLimitCapacity)
+	return ep
 }
 
 // 实现 EndpointSorter 接口
@@ -190,6 +245,13 @@ func (e *Endpoint) GetParameterOverrides() map[string]string {
 	return overrides
 }
 
+// GetRetryPolicy 获取端点级重试策略，未配置时返回nil
+func (e *Endpoint) GetRetryPolicy() *config.RetryPolicyConfig {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.RetryPolicy
+}
+
 // ToTaggedEndpoint 将Endpoint转换为TaggedEndpoint
 func (e *Endpoint) ToTaggedEndpoint() interfaces.TaggedEndpoint {
 	e.mutex.RLock()
@@ -219,15 +281,46 @@ func (e *Endpoint) GetFullURL(path string) string {
 	case "anthropic":
 		// Anthropic 端点需要添加 /v1 前缀，因为路由组已经消费了 /v1
 		return baseURL + "/v1" + path
-	case "openai":
+	case "openai", "openai_responses":
 		// OpenAI 端点使用配置的路径前缀（不需要路径转换）
 		return baseURL + e.PathPrefix
+	case "gemini":
+		// Gemini 的完整URL包含模型名，格式转换后由 GetGeminiURL 构造
+		return baseURL + e.geminiPathPrefix()
 	default:
 		// 向后兼容：默认使用 anthropic 格式，需要添加 /v1 前缀
 		return baseURL + "/v1" + path
 	}
 }
 
+// GetGeminiURL 构造 Gemini 端点的请求URL：模型名位于路径中，流式请求使用 streamGenerateContent?alt=sse
+func (e *Endpoint) GetGeminiURL(model string, stream bool) string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	url := e.URL + e.geminiPathPrefix() + "/" + model
+	if stream {
+		return url + ":streamGenerateContent?alt=sse"
+	}
+	return url + ":generateContent"
+}
+
+// geminiPathPrefix 返回 Gemini 端点的模型路径前缀，未配置时使用 /v1beta/models
+func (e *Endpoint) geminiPathPrefix() string {
+	if e.PathPrefix == "" {
+		return "/v1beta/models"
+	}
+	return strings.TrimSuffix(e.PathPrefix, "/")
+}
+
+// GetAPIKeyHeaderName 返回 api_key 认证使用的头部名称：Gemini 使用 x-goog-api-key，其余使用 x-api-key
+func (e *Endpoint) GetAPIKeyHeaderName() string {
+	if e.EndpointType == "gemini" {
+		return "x-goog-api-key"
+	}
+	return "x-api-key"
+}
+
 // 优化 IsAvailable 方法，减少锁的持有时间
 func (e *Endpoint) IsAvailable() bool {
 	e.mutex.RLock()
@@ -238,6 +331,13 @@ func (e *Endpoint) IsAvailable() bool {
 	return enabled && status == StatusActive
 }
 
+// GetStatus 获取端点的健康状态
+func (e *Endpoint) GetStatus() Status {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.Status
+}
+
 func (e *Endpoint) RecordRequest(success bool, requestID string) {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
@@ -255,20 +355,32 @@ func (e *Endpoint) RecordRequest(success bool, requestID string) {
 	e.TotalRequests++
 	if success {
 		e.SuccessRequests++
-		e.FailureCount = 0 // 重置失败计数
-		e.SuccessiveSuccesses++ // 增加连续成功次数
-		// 如果成功且之前是不可用状态，恢复为可用
-		if e.Status == StatusInactive {
+		e.FailureCount = 0
+		e.SuccessiveSuccesses++
+	} else {
+		e.FailureCount++
+		e.LastFailure = now
+		e.SuccessiveSuccesses = 0
+	}
+	
+	// 启用熔断器时由熔断器决定端点的打开、半开试探和恢复
+	if e.recordCircuitResultLocked(success, requestID, now) {
+		return
+	}
+	
+	if success {
+		// 如果成功且之前是不可用状态，恢复为可用（预算耗尽的端点要等窗口重置）
+		if e.Status == StatusInactive && !e.budgetBlockedLocked(now) {
 			// 释放 mutex 以避免死锁，因为 MarkActive 需要获取 mutex
+			reason := fmt.Sprintf("request %s succeeded", requestID)
+			if requestID == "health-check" {
+				reason = "health check succeeded"
+			}
 			e.mutex.Unlock()
-			e.MarkActive()
+			e.MarkActive(reason)
 			e.mutex.Lock()
 		}
 	} else {
-		e.FailureCount++
-		e.LastFailure = now
-		e.SuccessiveSuccesses = 0 // 重置连续成功次数
-		
 		// 使用环形缓冲区检查是否应该标记为不可用
 		if e.Status == StatusActive && e.RequestHistory.ShouldMarkInactive(now) {
 			// 释放 mutex 以避免死锁，因为 MarkInactiveWithReason 需要获取 mutex
@@ -279,10 +391,11 @@ func (e *Endpoint) RecordRequest(success bool, requestID string) {
 	}
 }
 
-func (e *Endpoint) MarkInactive() {
+// MarkInactive 标记端点为失效，cause（statistics.Cause*）和 reason 记录到端点事件时间线
+func (e *Endpoint) MarkInactive(cause, reason string) {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
-	e.Status = StatusInactive
+	e.setStatusLocked(StatusInactive, cause, reason)
 }
 
 // MarkInactiveWithReason 标记端点为失效并记录原因
@@ -291,26 +404,32 @@ func (e *Endpoint) MarkInactiveWithReason() {
 	defer e.mutex.Unlock()
 	
 	if e.Status == StatusActive {
-		e.Status = StatusInactive
-		
 		// 从循环缓冲区获取导致失效的请求ID
 		failedRequestIDs := e.RequestHistory.GetRecentFailureRequestIDs(time.Now())
+		errorSummary := fmt.Sprintf("Endpoint failed due to %d consecutive failures", len(failedRequestIDs))
+		e.setStatusLocked(StatusInactive, statistics.CauseFailures, errorSummary)
 		
 		// 构建失效原因记录
 		e.blacklistMutex.Lock()
 		e.BlacklistReason = &BlacklistReason{
 			BlacklistedAt:     time.Now(),
 			CausingRequestIDs: failedRequestIDs,
-			ErrorSummary:      fmt.Sprintf("Endpoint failed due to %d consecutive failures", len(failedRequestIDs)),
+			ErrorSummary:      errorSummary,
 		}
 		e.blacklistMutex.Unlock()
 	}
 }
 
-func (e *Endpoint) MarkActive() {
+// MarkActive 恢复端点为可用状态，reason 记录到端点事件时间线
+func (e *Endpoint) MarkActive(reason string) {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
-	e.Status = StatusActive
+	e.markActiveLocked(reason)
+}
+
+// markActiveLocked 恢复端点为可用状态；调用方需持有 e.mutex
+func (e *Endpoint) markActiveLocked(reason string) {
+	e.setStatusLocked(StatusActive, "", reason)
 	e.FailureCount = 0
 	e.SuccessiveSuccesses = 0 // 重置连续成功次数
 	
@@ -319,6 +438,9 @@ func (e *Endpoint) MarkActive() {
 	e.BlacklistReason = nil
 	e.blacklistMutex.Unlock()
 	
+	// 端点被恢复时关闭熔断器
+	e.closeCircuitLocked("endpoint reactivated")
+	
 	// 重置跳过健康检查日志时间，确保下次rate limit时能立即记录
 	e.lastSkipLogTime = time.Time{}
 	
@@ -391,6 +513,35 @@ func (e *Endpoint) CreateHealthClient(timeoutConfig config.HealthCheckTimeoutCon
 	return factory.CreateClient(clientConfig)
 }
 
+// CreateOAuthClient 创建用于 OAuth token 请求的HTTP客户端，使用端点的代理配置
+func (e *Endpoint) CreateOAuthClient(timeoutConfig config.ProxyTimeoutConfig) (*http.Client, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.createOAuthClientLocked(timeoutConfig)
+}
+
+func (e *Endpoint) createOAuthClientLocked(timeoutConfig config.ProxyTimeoutConfig) (*http.Client, error) {
+	factory := httpclient.NewFactory()
+	clientConfig := httpclient.ClientConfig{
+		Type: httpclient.ClientTypeProxy,
+		Timeouts: httpclient.TimeoutConfig{
+			TLSHandshake:   parseDuration(timeoutConfig.TLSHandshake, 10*time.Second),
+			ResponseHeader: parseDuration(timeoutConfig.ResponseHeader, 60*time.Second),
+			IdleConnection: parseDuration(timeoutConfig.IdleConnection, 90*time.Second),
+			OverallRequest: parseDuration(timeoutConfig.OverallRequest, 30*time.Second),
+		},
+		ProxyConfig: e.Proxy,
+	}
+	return factory.CreateClient(clientConfig)
+}
+
+// NeedsOAuthRefresh 检查 OAuth token 是否将在 ahead 时间内过期，供后台主动刷新使用
+func (e *Endpoint) NeedsOAuthRefresh(ahead time.Duration) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.AuthType == "oauth" && oauth.ShouldRefreshTokenWithin(e.OAuthConfig, ahead)
+}
+
 // RefreshOAuthToken 刷新 OAuth token
 func (e *Endpoint) RefreshOAuthToken(timeoutConfig config.ProxyTimeoutConfig) error {
 	return e.RefreshOAuthTokenWithCallback(timeoutConfig, nil)
@@ -410,19 +561,7 @@ func (e *Endpoint) RefreshOAuthTokenWithCallback(timeoutConfig config.ProxyTimeo
 	}
 	
 	// 创建HTTP客户端用于刷新请求
-	factory := httpclient.NewFactory()
-	clientConfig := httpclient.ClientConfig{
-		Type: httpclient.ClientTypeProxy,
-		Timeouts: httpclient.TimeoutConfig{
-			TLSHandshake:   parseDuration(timeoutConfig.TLSHandshake, 10*time.Second),
-			ResponseHeader: parseDuration(timeoutConfig.ResponseHeader, 60*time.Second),
-			IdleConnection: parseDuration(timeoutConfig.IdleConnection, 90*time.Second),
-			OverallRequest: parseDuration(timeoutConfig.OverallRequest, 30*time.Second),
-		},
-		ProxyConfig: e.Proxy,
-	}
-	
-	client, err := factory.CreateClient(clientConfig)
+	client, err := e.createOAuthClientLocked(timeoutConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create http client for token refresh: %v", err)
 	}
@@ -430,8 +569,16 @@ func (e *Endpoint) RefreshOAuthTokenWithCallback(timeoutConfig config.ProxyTimeo
 	// 刷新token
 	newOAuthConfig, err := oauth.RefreshToken(e.OAuthConfig, client)
 	if err != nil {
+		e.oauthRefreshes.record(e.Name, false)
+		e.recordEvent(statistics.EndpointEvent{EventType: statistics.EventOAuthRefresh, Reason: err.Error()})
 		return fmt.Errorf("failed to refresh oauth token: %v", err)
 	}
+	e.oauthRefreshes.record(e.Name, true)
+	e.recordEvent(statistics.EndpointEvent{
+		EventType: statistics.EventOAuthRefresh,
+		Success:   true,
+		Reason:    "token expires at " + time.UnixMilli(newOAuthConfig.ExpiresAt).Format(time.RFC3339),
+	})
 	
 	// 更新配置
 	e.OAuthConfig = newOAuthConfig
@@ -447,6 +594,7 @@ func (e *Endpoint) RefreshOAuthTokenWithCallback(timeoutConfig config.ProxyTimeo
 	return nil
 }
 
+
 // GetAuthHeaderWithRefresh 获取认证头部，如果需要会自动刷新OAuth token
 func (e *Endpoint) GetAuthHeaderWithRefresh(timeoutConfig config.ProxyTimeoutConfig) (string, error) {
 	return e.GetAuthHeaderWithRefreshCallback(timeoutConfig, nil)
@@ -503,6 +651,7 @@ func (e *Endpoint) GetBlacklistReason() *BlacklistReason {
 		CausingRequestIDs: append([]string{}, e.BlacklistReason.CausingRequestIDs...),
 		BlacklistedAt:     e.BlacklistReason.BlacklistedAt,
 		ErrorSummary:      e.BlacklistReason.ErrorSummary,
+		ResetsAt:          e.BlacklistReason.ResetsAt,
 	}
 }
 
@@ -528,8 +677,19 @@ func (e *Endpoint) UpdateRateLimitState(reset *int64, status *string) (bool, err
 		changed = true
 	}
 	
-	// 如果有变化，更新状态
+	// 如果有变化，更新状态；unified status 变化时记录事件
 	if changed {
+		if status != nil && (e.RateLimitStatus == nil || *e.RateLimitStatus != *status) {
+			reason := "anthropic unified status " + *status
+			if reset != nil {
+				reason += ", resets at " + time.Unix(*reset, 0).Format(time.RFC3339)
+			}
+			e.recordEvent(statistics.EndpointEvent{
+				EventType: statistics.EventRateLimit,
+				Success:   *status == "allowed",
+				Reason:    reason,
+			})
+		}
 		e.RateLimitReset = reset
 		e.RateLimitStatus = status
 	}
@@ -0,0 +1,19 @@
+package endpoint
+
+import "claude-code-companion/internal/config"
+
+// newTestEndpoint 创建测试用的 anthropic 端点，opts 在创建前修改端点配置
+func newTestEndpoint(name string, opts ...func(*config.EndpointConfig)) *Endpoint {
+	cfg := config.EndpointConfig{
+		Name:         name,
+		URL:          "https://api.example.com",
+		EndpointType: "anthropic",
+		AuthType:     "api_key",
+		AuthValue:    "sk-test",
+		Enabled:      true,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return NewEndpoint(cfg)
+}
@@ -0,0 +1,146 @@
+package endpoint
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+)
+
+// eventRecorder 把端点事件异步写入 statistics.db 的 endpoint_events 表，
+// 端点在持有 mutex 时也可以记录事件；队列满时丢弃事件而不阻塞请求
+type eventRecorder struct {
+	statisticsManager statistics.StatisticsManager
+	queue             chan *statistics.EndpointEvent
+}
+
+func newEventRecorder(statisticsManager statistics.StatisticsManager) *eventRecorder {
+	r := &eventRecorder{
+		statisticsManager: statisticsManager,
+		queue:             make(chan *statistics.EndpointEvent, config.Default.EndpointEvents.QueueSize),
+	}
+	go r.run()
+	return r
+}
+
+func (r *eventRecorder) record(event *statistics.EndpointEvent) {
+	if r == nil {
+		return
+	}
+	select {
+	case r.queue <- event:
+	default:
+		log.Printf("WARNING: Endpoint event queue full, dropping %s event for endpoint %s", event.EventType, event.EndpointName)
+	}
+}
+
+// run 写入排队的事件，并定期清理超过保留天数的事件
+func (r *eventRecorder) run() {
+	r.cleanup()
+	ticker := time.NewTicker(config.Default.EndpointEvents.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-r.queue:
+			if err := r.statisticsManager.RecordEndpointEvent(event); err != nil {
+				log.Printf("WARNING: Failed to persist endpoint event: %v", err)
+			}
+		case <-ticker.C:
+			r.cleanup()
+		}
+	}
+}
+
+func (r *eventRecorder) cleanup() {
+	before := time.Now().AddDate(0, 0, -config.Default.EndpointEvents.RetentionDays)
+	if deleted, err := r.statisticsManager.CleanupEndpointEvents(before); err != nil {
+		log.Printf("WARNING: Failed to cleanup endpoint events: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Cleaned up %d endpoint events older than %d days", deleted, config.Default.EndpointEvents.RetentionDays)
+	}
+}
+
+// recordEvent 记录一条端点事件，ID、名称和时间由端点填充；未设置事件记录器时忽略
+func (e *Endpoint) recordEvent(event statistics.EndpointEvent) {
+	event.EndpointID = e.ID
+	event.EndpointName = e.Name
+	event.CreatedAt = time.Now().UTC()
+	if len(event.Reason) > 1000 {
+		event.Reason = strings.ToValidUTF8(event.Reason[:1000], "")
+	}
+	e.events.record(&event)
+}
+
+// setStatusLocked 切换端点状态，状态发生变化时记录 inactive / active 事件；调用方需持有 e.mutex
+// cause 是失效的类别（statistics.Cause*），只记录在 inactive 事件上，用于按原因汇总失效
+func (e *Endpoint) setStatusLocked(status Status, cause, reason string) {
+	if e.Status == status {
+		return
+	}
+	e.Status = status
+	event := statistics.EndpointEvent{
+		EventType: statistics.EventInactive,
+		Cause:     cause,
+		Reason:    reason,
+	}
+	if status == StatusActive {
+		event.EventType = statistics.EventActive
+		event.Success = true
+		event.Cause = ""
+	}
+	e.recordEvent(event)
+}
+
+// QueryEvents 查询端点事件时间线，最新的在前；尚在写入队列中的事件不包含在结果中
+func (m *Manager) QueryEvents(filter statistics.EndpointEventFilter) ([]*statistics.EndpointEvent, int64, error) {
+	return m.statisticsManager.QueryEndpointEvents(filter)
+}
+
+// OAuthRefreshCount 一个端点 OAuth 刷新成功和失败的次数
+type OAuthRefreshCount struct {
+	Successes int64
+	Failures  int64
+}
+
+// oauthRefreshCounters 按端点名称统计 OAuth 刷新结果，由 Manager 持有；
+// 端点因配置更新重建后计数不会清零，供 /metrics 导出单调递增的计数器
+type oauthRefreshCounters struct {
+	mutex  sync.Mutex
+	counts map[string]*OAuthRefreshCount
+}
+
+func newOAuthRefreshCounters() *oauthRefreshCounters {
+	return &oauthRefreshCounters{counts: make(map[string]*OAuthRefreshCount)}
+}
+
+func (c *oauthRefreshCounters) record(endpointName string, success bool) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	count := c.counts[endpointName]
+	if count == nil {
+		count = &OAuthRefreshCount{}
+		c.counts[endpointName] = count
+	}
+	if success {
+		count.Successes++
+	} else {
+		count.Failures++
+	}
+}
+
+func (c *oauthRefreshCounters) snapshot() map[string]OAuthRefreshCount {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	result := make(map[string]OAuthRefreshCount, len(c.counts))
+	for name, count := range c.counts {
+		result[name] = *count
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package endpoint
+
+import (
+	"testing"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+)
+
+// drainEvents 取出事件队列中已记录的事件
+func drainEvents(r *eventRecorder) []*statistics.EndpointEvent {
+	var events []*statistics.EndpointEvent
+	for {
+		select {
+		case event := <-r.queue:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestSetStatusLockedRecordsTransitions(t *testing.T) {
+	ep := newTestEndpoint("events-test")
+	recorder := &eventRecorder{queue: make(chan *statistics.EndpointEvent, 10)}
+	ep.events = recorder
+
+	tests := []struct {
+		name     string
+		status   Status
+		cause    string
+		expected string // 期望的事件类型，空表示不记录事件
+	}{
+		{"already active", StatusActive, "", ""},
+		{"becomes inactive", StatusInactive, statistics.CauseFailures, statistics.EventInactive},
+		{"still inactive", StatusInactive, statistics.CauseCircuit, ""},
+		{"restored", StatusActive, statistics.CauseCircuit, statistics.EventActive},
+		{"still active", StatusActive, "", ""},
+	}
+	for _, tt := range tests {
+		ep.mutex.Lock()
+		ep.setStatusLocked(tt.status, tt.cause, tt.name)
+		ep.mutex.Unlock()
+
+		events := drainEvents(recorder)
+		if tt.expected == "" {
+			if len(events) != 0 {
+				t.Errorf("%s: expected no event, got %+v", tt.name, events[0])
+			}
+			continue
+		}
+		if len(events) != 1 {
+			t.Fatalf("%s: expected one event, got %d", tt.name, len(events))
+		}
+		event := events[0]
+		if event.EventType != tt.expected || event.EndpointID != ep.ID || event.Reason != tt.name {
+			t.Errorf("%s: unexpected event %+v", tt.name, event)
+		}
+		// cause 只记录在 inactive 事件上
+		if tt.expected == statistics.EventInactive && event.Cause != tt.cause {
+			t.Errorf("%s: expected cause %q, got %q", tt.name, tt.cause, event.Cause)
+		}
+		if tt.expected == statistics.EventActive && (event.Cause != "" || !event.Success) {
+			t.Errorf("%s: expected a successful active event without cause, got %+v", tt.name, event)
+		}
+	}
+}
+
+func TestOAuthRefreshCountsSurviveEndpointUpdate(t *testing.T) {
+	endpointConfig := config.EndpointConfig{
+		Name:         "oauth-endpoint",
+		URL:          "https://api.anthropic.com",
+		EndpointType: "anthropic",
+		AuthType:     "oauth",
+		Enabled:      true,
+		Priority:     1,
+	}
+	cfg := &config.Config{Endpoints: []config.EndpointConfig{endpointConfig}}
+	cfg.Logging.LogDirectory = t.TempDir()
+	manager, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	ep := manager.GetAllEndpoints()[0]
+	ep.oauthRefreshes.record(ep.Name, true)
+	ep.oauthRefreshes.record(ep.Name, false)
+
+	// 配置更新会重建端点，计数按端点名称保留
+	endpointConfig.Priority = 2
+	manager.UpdateEndpoints([]config.EndpointConfig{endpointConfig})
+	updated := manager.GetAllEndpoints()[0]
+	if updated == ep {
+		t.Fatal("expected endpoint to be recreated by the update")
+	}
+	updated.oauthRefreshes.record(updated.Name, true)
+
+	counts := manager.GetOAuthRefreshCounts()
+	if got := counts["oauth-endpoint"]; got.Successes != 2 || got.Failures != 1 {
+		t.Errorf("expected 2 successes and 1 failure across endpoint updates, got %+v", got)
+	}
+}
@@ -0,0 +1,92 @@
+package endpoint
+
+import (
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+)
+
+// HealthCheckResult 一次健康检查的结果
+type HealthCheckResult struct {
+	At         time.Time `json:"at"`
+	Probe      string    `json:"probe"` // messages | models | passive（关闭主动探测时的直接恢复）
+	Success    bool      `json:"success"`
+	StatusCode int       `json:"status_code,omitempty"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// GetHealthCheckConfig 返回端点的健康检查配置，未配置时返回nil（使用默认的 messages 探测）
+func (e *Endpoint) GetHealthCheckConfig() *config.EndpointHealthCheckConfig {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.HealthCheck
+}
+
+// IsActiveHealthCheckDisabled 是否关闭了主动健康检查
+func (e *Endpoint) IsActiveHealthCheckDisabled() bool {
+	healthCheck := e.GetHealthCheckConfig()
+	return healthCheck != nil && healthCheck.Disabled
+}
+
+// RecordHealthCheck 记录一次健康检查结果，只保留最近的 config.Default.EndpointHealthCheck.HistorySize 条
+func (e *Endpoint) RecordHealthCheck(result HealthCheckResult) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// 错误信息可能包含完整的响应体，截断后保存
+	if len(result.Error) > 500 {
+		result.Error = strings.ToValidUTF8(result.Error[:500], "") + "..."
+	}
+	e.healthCheckHistory = append(e.healthCheckHistory, result)
+	reason := result.Probe
+	if result.Error != "" {
+		reason += ": " + result.Error
+	}
+	e.recordEvent(statistics.EndpointEvent{
+		EventType:  statistics.EventHealthCheck,
+		Success:    result.Success,
+		Reason:     reason,
+		StatusCode: result.StatusCode,
+		LatencyMs:  result.LatencyMs,
+	})
+	if overflow := len(e.healthCheckHistory) - config.Default.EndpointHealthCheck.HistorySize; overflow > 0 {
+		e.healthCheckHistory = append([]HealthCheckResult(nil), e.healthCheckHistory[overflow:]...)
+	}
+}
+
+// GetHealthCheckHistory 返回最近的健康检查结果，最新的在前
+func (e *Endpoint) GetHealthCheckHistory() []HealthCheckResult {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	history := make([]HealthCheckResult, 0, len(e.healthCheckHistory))
+	for i := len(e.healthCheckHistory) - 1; i >= 0; i-- {
+		history = append(history, e.healthCheckHistory[i])
+	}
+	return history
+}
+
+// GetModelsURL 返回模型列表的URL，用于 models 健康检查探测：
+// Anthropic 为 /v1/models；OpenAI 兼容端点把路径前缀中的 /chat/completions 或 /responses 替换为 /models；Gemini 为模型路径前缀
+func (e *Endpoint) GetModelsURL() string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	switch e.EndpointType {
+	case "openai", "openai_responses":
+		prefix := strings.TrimSuffix(e.PathPrefix, "/")
+		for _, suffix := range []string{"/chat/completions", "/responses"} {
+			if strings.HasSuffix(prefix, suffix) {
+				return e.URL + strings.TrimSuffix(prefix, suffix) + "/models"
+			}
+		}
+		return e.URL + "/v1/models"
+	case "gemini":
+		return e.URL + e.geminiPathPrefix()
+	default:
+		return e.URL + "/v1/models"
+	}
+}
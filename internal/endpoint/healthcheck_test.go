@@ -0,0 +1,62 @@
+package endpoint
+
+import (
+	"strings"
+	"testing"
+
+	"claude-code-companion/internal/config"
+)
+
+func TestGetModelsURL(t *testing.T) {
+	tests := []struct {
+		name         string
+		endpointType string
+		url          string
+		pathPrefix   string
+		expected     string
+	}{
+		{"anthropic", "anthropic", "https://api.anthropic.com", "", "https://api.anthropic.com/v1/models"},
+		{"openai without prefix", "openai", "https://api.openai.com", "", "https://api.openai.com/v1/models"},
+		{"openai chat completions prefix", "openai", "https://openrouter.ai", "/api/v1/chat/completions", "https://openrouter.ai/api/v1/models"},
+		{"openai prefix with trailing slash", "openai", "https://example.com", "/v1/chat/completions/", "https://example.com/v1/models"},
+		{"openai custom prefix", "openai", "https://example.com", "/custom", "https://example.com/v1/models"},
+		{"openai responses prefix", "openai_responses", "https://example.com", "/openai/v1/responses", "https://example.com/openai/v1/models"},
+		{"gemini default prefix", "gemini", "https://generativelanguage.googleapis.com", "", "https://generativelanguage.googleapis.com/v1beta/models"},
+		{"gemini custom prefix", "gemini", "https://example.com", "/v1/models/", "https://example.com/v1/models"},
+	}
+	for _, tt := range tests {
+		ep := newTestEndpoint(tt.name, func(cfg *config.EndpointConfig) {
+			cfg.URL = tt.url
+			cfg.EndpointType = tt.endpointType
+			cfg.PathPrefix = tt.pathPrefix
+		})
+		if got := ep.GetModelsURL(); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestRecordHealthCheckHistory(t *testing.T) {
+	ep := newTestEndpoint("health-test")
+	historySize := config.Default.EndpointHealthCheck.HistorySize
+
+	for i := 0; i < historySize+5; i++ {
+		ep.RecordHealthCheck(HealthCheckResult{Probe: "models", Success: true, LatencyMs: int64(i)})
+	}
+
+	// 只保留最近的结果，最新的在前
+	history := ep.GetHealthCheckHistory()
+	if len(history) != historySize {
+		t.Fatalf("expected history truncated to %d results, got %d", historySize, len(history))
+	}
+	if history[0].LatencyMs != int64(historySize+4) || history[historySize-1].LatencyMs != 5 {
+		t.Errorf("expected newest first and oldest results dropped, got first %d last %d",
+			history[0].LatencyMs, history[historySize-1].LatencyMs)
+	}
+
+	// 过长的错误信息被截断
+	ep.RecordHealthCheck(HealthCheckResult{Probe: "messages", Error: strings.Repeat("x", 1000)})
+	if got := ep.GetHealthCheckHistory()[0].Error; len(got) != 503 || !strings.HasSuffix(got, "...") {
+		t.Errorf("expected error truncated to 500 characters, got %d", len(got))
+	}
+}
@@ -0,0 +1,248 @@
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/config"
+)
+
+// Limiter 端点的客户端并发与速率限制
+// max_concurrency 限制同时进行的上游请求数；rpm 和 tpm 是容量为一分钟配额、按时间连续补充的令牌桶，
+// tpm 按响应 usage 事后扣除，允许透支，桶为负时暂停放行直到补充回正
+type Limiter struct {
+	mutex          sync.Mutex
+	maxConcurrency int
+	rpm            int
+	tpm            int
+	queueTimeout   time.Duration
+
+	inUse         int
+	queued        int
+	requestTokens float64
+	tokenTokens   float64
+	lastRefill    time.Time
+	released      chan struct{} // 每次释放许可时关闭并替换，唤醒排队的请求
+	overflows     int64
+}
+
+// LimiterStatus 限流器的当前状态，供端点状态API展示
+type LimiterStatus struct {
+	MaxConcurrency int    `json:"max_concurrency,omitempty"`
+	InUse          int    `json:"in_use"`
+	Queued         int    `json:"queued"`
+	RPM            int    `json:"rpm,omitempty"`
+	RPMAvailable   *int   `json:"rpm_available,omitempty"` // 令牌桶当前余量，未配置 rpm 时为空
+	TPM            int    `json:"tpm,omitempty"`
+	TPMAvailable   *int   `json:"tpm_available,omitempty"` // 可能为负（透支）
+	Saturated      bool   `json:"saturated"`
+	Reason         string `json:"reason,omitempty"`
+	Overflows      int64  `json:"overflows"`
+}
+
+// NewLimiter 根据配置创建限流器，未配置任何限制时返回nil
+func NewLimiter(cfg *config.EndpointLimitsConfig) *Limiter {
+	if cfg == nil || (cfg.MaxConcurrency <= 0 && cfg.RPM <= 0 && cfg.TPM <= 0) {
+		return nil
+	}
+	l := &Limiter{
+		lastRefill: time.Now(),
+		released:   make(chan struct{}),
+	}
+	l.applyConfig(cfg)
+	l.requestTokens = float64(l.rpm)
+	l.tokenTokens = float64(l.tpm)
+	return l
+}
+
+// UpdateConfig 热更新限制，保留进行中的请求数和令牌桶余量
+func (l *Limiter) UpdateConfig(cfg *config.EndpointLimitsConfig) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.refillLocked(time.Now())
+	oldRPM, oldTPM := l.rpm, l.tpm
+	l.applyConfig(cfg)
+	// 新启用的令牌桶从满桶开始，调低的限制截断余量
+	if oldRPM <= 0 || l.requestTokens > float64(l.rpm) {
+		l.requestTokens = float64(l.rpm)
+	}
+	if oldTPM <= 0 || l.tokenTokens > float64(l.tpm) {
+		l.tokenTokens = float64(l.tpm)
+	}
+	l.notifyLocked()
+}
+
+func (l *Limiter) applyConfig(cfg *config.EndpointLimitsConfig) {
+	defaultQueueTimeout, _ := time.ParseDuration(config.Default.EndpointLimits.QueueTimeout)
+	l.maxConcurrency = cfg.MaxConcurrency
+	l.rpm = cfg.RPM
+	l.tpm = cfg.TPM
+	l.queueTimeout = config.GetTimeoutDuration(cfg.QueueTimeout, defaultQueueTimeout)
+}
+
+// refillLocked 按经过的时间补充令牌桶；调用方需持有 l.mutex
+func (l *Limiter) refillLocked(now time.Time) {
+	minutes := now.Sub(l.lastRefill).Minutes()
+	l.lastRefill = now
+	if l.rpm > 0 {
+		l.requestTokens = minFloat(float64(l.rpm), l.requestTokens+minutes*float64(l.rpm))
+	}
+	if l.tpm > 0 {
+		l.tokenTokens = minFloat(float64(l.tpm), l.tokenTokens+minutes*float64(l.tpm))
+	}
+}
+
+// saturationLocked 返回当前无法放行的原因和预计可放行的等待时间；
+// 等待时间为0表示要等其他请求释放并发许可；调用方需持有 l.mutex
+func (l *Limiter) saturationLocked(now time.Time) (string, time.Duration) {
+	l.refillLocked(now)
+	if l.maxConcurrency > 0 && l.inUse >= l.maxConcurrency {
+		return fmt.Sprintf("max_concurrency %d reached", l.maxConcurrency), 0
+	}
+	if l.rpm > 0 && l.requestTokens < 1 {
+		wait := time.Duration((1 - l.requestTokens) / float64(l.rpm) * float64(time.Minute))
+		return fmt.Sprintf("rpm %d exhausted", l.rpm), wait
+	}
+	if l.tpm > 0 && l.tokenTokens <= 0 {
+		wait := time.Duration((1 - l.tokenTokens) / float64(l.tpm) * float64(time.Minute))
+		return fmt.Sprintf("tpm %d exhausted", l.tpm), wait
+	}
+	return "", 0
+}
+
+// Acquire 获取一个上游请求许可，饱和时最多排队 queue_timeout；
+// 成功时返回释放函数，超时返回饱和错误，客户端断开返回 ctx 的错误。nil 限流器总是立即放行
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mutex.Lock()
+	deadline := time.Now().Add(l.queueTimeout)
+	for {
+		now := time.Now()
+		reason, wait := l.saturationLocked(now)
+		if reason == "" {
+			l.inUse++
+			if l.rpm > 0 {
+				l.requestTokens--
+			}
+			l.mutex.Unlock()
+			var once sync.Once
+			return func() { once.Do(l.release) }, nil
+		}
+
+		remaining := deadline.Sub(now)
+		if remaining <= 0 {
+			l.overflows++
+			l.mutex.Unlock()
+			return nil, fmt.Errorf("endpoint saturated: %s", reason)
+		}
+		if wait <= 0 || wait > remaining {
+			wait = remaining
+		}
+
+		released := l.released
+		l.queued++
+		l.mutex.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-released:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+
+		l.mutex.Lock()
+		l.queued--
+		if err := ctx.Err(); err != nil {
+			l.mutex.Unlock()
+			return nil, err
+		}
+	}
+}
+
+func (l *Limiter) release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.inUse--
+	l.notifyLocked()
+}
+
+// notifyLocked 唤醒所有排队的请求重新检查；调用方需持有 l.mutex
+func (l *Limiter) notifyLocked() {
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// TracksTokens 是否配置了 tpm 限制
+func (l *Limiter) TracksTokens() bool {
+	if l == nil {
+		return false
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.tpm > 0
+}
+
+// RecordTokens 从 tpm 令牌桶中扣除一次请求实际使用的token
+func (l *Limiter) RecordTokens(tokens int64) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.tpm <= 0 {
+		return
+	}
+	l.refillLocked(time.Now())
+	l.tokenTokens -= float64(tokens)
+}
+
+// Status 返回限流器的当前状态，nil 限流器返回nil
+func (l *Limiter) Status() *LimiterStatus {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	reason, _ := l.saturationLocked(time.Now())
+	status := &LimiterStatus{
+		MaxConcurrency: l.maxConcurrency,
+		InUse:          l.inUse,
+		Queued:         l.queued,
+		RPM:            l.rpm,
+		TPM:            l.tpm,
+		Saturated:      reason != "",
+		Reason:         reason,
+		Overflows:      l.overflows,
+	}
+	if l.rpm > 0 {
+		available := int(l.requestTokens)
+		status.RPMAvailable = &available
+	}
+	if l.tpm > 0 {
+		available := int(l.tokenTokens)
+		status.TPMAvailable = &available
+	}
+	return status
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// GetLimiter 获取端点的限流器，未配置限制时返回nil（nil 限流器的方法都可以安全调用）
+func (e *Endpoint) GetLimiter() *Limiter {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.limiter
+}
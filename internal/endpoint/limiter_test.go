@@ -0,0 +1,245 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+)
+
+// acquireAsync 在后台获取许可，结果通过返回的 channel 送回
+func acquireAsync(ctx context.Context, l *Limiter) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		release, err := l.Acquire(ctx)
+		if err == nil {
+			release()
+		}
+		result <- err
+	}()
+	return result
+}
+
+// waitQueued 等待指定数量的请求进入排队
+func waitQueued(t *testing.T, l *Limiter, queued int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for l.Status().Queued != queued {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d queued requests, got %d", queued, l.Status().Queued)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitResult(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(2 * time.Second):
+		t.Fatalf("queued request did not finish")
+		return nil
+	}
+}
+
+func TestNewLimiterWithoutLimits(t *testing.T) {
+	if l := NewLimiter(nil); l != nil {
+		t.Errorf("expected nil limiter without config")
+	}
+	l := NewLimiter(&config.EndpointLimitsConfig{QueueTimeout: "1s"})
+	if l != nil {
+		t.Fatalf("expected nil limiter without any limit")
+	}
+	// nil 限流器总是立即放行
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil limiter to admit, got %v", err)
+	}
+	release()
+	if l.Status() != nil || l.TracksTokens() {
+		t.Errorf("expected nil limiter to report no status")
+	}
+}
+
+func TestLimiterQueuesUntilRelease(t *testing.T) {
+	l := NewLimiter(&config.EndpointLimitsConfig{MaxConcurrency: 1, QueueTimeout: "5s"})
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+
+	result := acquireAsync(context.Background(), l)
+	waitQueued(t, l, 1)
+	if status := l.Status(); !status.Saturated || status.InUse != 1 {
+		t.Errorf("expected saturated limiter with one permit in use, got %+v", status)
+	}
+
+	// 释放许可后排队的请求立即放行，重复释放不影响计数
+	release()
+	release()
+	if err := waitResult(t, result); err != nil {
+		t.Fatalf("expected queued request to be admitted, got %v", err)
+	}
+	if status := l.Status(); status.InUse != 0 || status.Queued != 0 || status.Overflows != 0 {
+		t.Errorf("expected idle limiter, got %+v", status)
+	}
+}
+
+func TestLimiterQueueTimeoutOverflow(t *testing.T) {
+	l := NewLimiter(&config.EndpointLimitsConfig{MaxConcurrency: 1, QueueTimeout: "50ms"})
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+	defer release()
+
+	start := time.Now()
+	_, err = l.Acquire(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "max_concurrency 1 reached") {
+		t.Fatalf("expected saturation error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected request to queue for queue_timeout, returned after %v", elapsed)
+	}
+	if status := l.Status(); status.Overflows != 1 || status.Queued != 0 {
+		t.Errorf("expected one overflow and no queued requests, got %+v", status)
+	}
+}
+
+func TestLimiterContextCancellation(t *testing.T) {
+	l := NewLimiter(&config.EndpointLimitsConfig{MaxConcurrency: 1, QueueTimeout: "5s"})
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+	defer release()
+
+	// 客户端断开时返回 ctx 的错误，不计为溢出
+	ctx, cancel := context.WithCancel(context.Background())
+	result := acquireAsync(ctx, l)
+	waitQueued(t, l, 1)
+	cancel()
+	if err := waitResult(t, result); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if status := l.Status(); status.Queued != 0 || status.Overflows != 0 || status.InUse != 1 {
+		t.Errorf("expected cancelled request to leave no trace, got %+v", status)
+	}
+}
+
+func TestLimiterRPMRefill(t *testing.T) {
+	l := NewLimiter(&config.EndpointLimitsConfig{RPM: 60, QueueTimeout: "10ms"})
+
+	// 满桶允许一分钟的突发
+	for i := 0; i < 60; i++ {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("acquire %d failed: %v", i, err)
+		}
+		release()
+	}
+	if _, err := l.Acquire(context.Background()); err == nil || !strings.Contains(err.Error(), "rpm 60 exhausted") {
+		t.Fatalf("expected rpm exhaustion, got %v", err)
+	}
+
+	// 按经过的时间连续补充：60 rpm 每秒补充一个
+	l.mutex.Lock()
+	l.lastRefill = l.lastRefill.Add(-time.Second)
+	l.mutex.Unlock()
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected a refilled token after one second, got %v", err)
+	}
+	release()
+
+	// 补充不超过桶容量
+	l.mutex.Lock()
+	l.lastRefill = l.lastRefill.Add(-time.Hour)
+	l.mutex.Unlock()
+	if available := *l.Status().RPMAvailable; available != 60 {
+		t.Errorf("expected bucket capped at 60, got %d", available)
+	}
+}
+
+func TestLimiterTPMOverdraft(t *testing.T) {
+	l := NewLimiter(&config.EndpointLimitsConfig{TPM: 1000, QueueTimeout: "10ms"})
+	if !l.TracksTokens() {
+		t.Fatalf("expected limiter with tpm to track tokens")
+	}
+
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	release()
+
+	// 按 usage 事后扣除，允许透支
+	l.RecordTokens(1500)
+	if available := *l.Status().TPMAvailable; available > -499 || available < -500 {
+		t.Errorf("expected overdrawn bucket around -500, got %d", available)
+	}
+	if _, err := l.Acquire(context.Background()); err == nil || !strings.Contains(err.Error(), "tpm 1000 exhausted") {
+		t.Fatalf("expected tpm exhaustion while overdrawn, got %v", err)
+	}
+
+	// 补充回正之前一直暂停放行
+	l.mutex.Lock()
+	l.tokenTokens = -500
+	l.lastRefill = time.Now().Add(-29 * time.Second)
+	l.mutex.Unlock()
+	if _, err := l.Acquire(context.Background()); err == nil {
+		t.Fatalf("expected tpm exhaustion until the bucket is positive")
+	}
+	l.mutex.Lock()
+	l.lastRefill = l.lastRefill.Add(-2 * time.Second)
+	l.mutex.Unlock()
+	release, err = l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected admission after refill, got %v", err)
+	}
+	release()
+}
+
+func TestLimiterUpdateConfigKeepsInFlightPermits(t *testing.T) {
+	l := NewLimiter(&config.EndpointLimitsConfig{MaxConcurrency: 2, QueueTimeout: "5s"})
+	first, _ := l.Acquire(context.Background())
+	second, _ := l.Acquire(context.Background())
+
+	// 调低并发上限后，进行中的许可仍然计数
+	l.UpdateConfig(&config.EndpointLimitsConfig{MaxConcurrency: 1, QueueTimeout: "20ms"})
+	if status := l.Status(); status.InUse != 2 || status.MaxConcurrency != 1 {
+		t.Fatalf("expected in-flight permits kept after update, got %+v", status)
+	}
+	if _, err := l.Acquire(context.Background()); err == nil {
+		t.Fatalf("expected saturation with two permits in flight and limit 1")
+	}
+	first()
+	if _, err := l.Acquire(context.Background()); err == nil {
+		t.Fatalf("expected saturation with one permit in flight and limit 1")
+	}
+	second()
+	release, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("expected admission after in-flight permits were released, got %v", err)
+	}
+
+	// 调高上限时唤醒排队的请求
+	l.UpdateConfig(&config.EndpointLimitsConfig{MaxConcurrency: 1, QueueTimeout: "5s"})
+	result := acquireAsync(context.Background(), l)
+	waitQueued(t, l, 1)
+	l.UpdateConfig(&config.EndpointLimitsConfig{MaxConcurrency: 2, QueueTimeout: "5s"})
+	if err := waitResult(t, result); err != nil {
+		t.Fatalf("expected queued request to be admitted after raising the limit, got %v", err)
+	}
+	release()
+
+	// 新启用的 rpm 令牌桶从满桶开始
+	l.UpdateConfig(&config.EndpointLimitsConfig{MaxConcurrency: 2, RPM: 30})
+	if available := *l.Status().RPMAvailable; available != 30 {
+		t.Errorf("expected newly enabled rpm bucket to start full, got %d", available)
+	}
+}
@@ -16,6 +16,12 @@ type HealthChecker interface {
 
 type Manager struct {
 	selector          *Selector
+	sessions          *SessionAffinity
+	circuitPolicy     *CircuitBreakerPolicy
+	events            *eventRecorder
+	oauthRefreshes    *oauthRefreshCounters
+	analytics         *analyticsRecorder
+	modelDiscovery    *modelDiscovery
 	endpoints         []*Endpoint
 	config            *config.Config
 	mutex             sync.RWMutex
@@ -38,9 +44,15 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 		return nil, fmt.Errorf("failed to initialize statistics manager: %w", err)
 	}
 
+	circuitPolicy := NewCircuitBreakerPolicy(cfg.CircuitBreaker)
+	events := newEventRecorder(statisticsManager)
+	oauthRefreshes := newOAuthRefreshCounters()
 	endpoints := make([]*Endpoint, 0, len(cfg.Endpoints))
 	for _, endpointConfig := range cfg.Endpoints {
 		endpoint := NewEndpoint(endpointConfig)
+		endpoint.circuitPolicy = circuitPolicy
+		endpoint.events = events
+		endpoint.oauthRefreshes = oauthRefreshes
 		
 		// Initialize or inherit statistics data
 		if err := initializeEndpointStatistics(endpoint, statisticsManager); err != nil {
@@ -53,7 +65,13 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 	}
 
 	manager := &Manager{
-		selector:          NewSelector(endpoints),
+		selector:          NewSelector(endpoints, cfg.LoadBalancing),
+		sessions:          NewSessionAffinity(cfg.SessionAffinity),
+		circuitPolicy:     circuitPolicy,
+		events:            events,
+		oauthRefreshes:    oauthRefreshes,
+		analytics:         newAnalyticsRecorder(statisticsManager, cfg.Analytics),
+		modelDiscovery:    newModelDiscovery(cfg.ModelDiscovery),
 		endpoints:         endpoints,
 		config:            cfg,
 		healthChecker:     nil, // 稍后设置
@@ -73,10 +91,40 @@ func (m *Manager) GetEndpointWithTags(tags []string) (*Endpoint, error) {
 	return m.selector.SelectEndpointWithTags(tags)
 }
 
+// UpdateLoadBalancing 热更新负载均衡配置
+func (m *Manager) UpdateLoadBalancing(cfg config.LoadBalancingConfig) {
+	m.selector.GetBalancer().UpdateConfig(cfg)
+}
+
+// UpdateSessionAffinity 热更新会话粘性路由配置
+func (m *Manager) UpdateSessionAffinity(cfg config.SessionAffinityConfig) {
+	m.sessions.UpdateConfig(cfg)
+}
+
+// RecordLatency 记录成功请求的响应头延迟，供 ewma 负载均衡策略使用
+func (m *Manager) RecordLatency(ep *Endpoint, latency time.Duration) {
+	ep.RecordLatency(latency, m.selector.GetBalancer().EWMAAlpha())
+}
+
+// RecordFailedLatency 把失败或超时的请求计为至少 failure_penalty 的延迟样本，
+// 使一直失败的端点在 ewma 策略中排到后面，而不是因为没有样本一直被优先挑选
+func (m *Manager) RecordFailedLatency(ep *Endpoint, elapsed time.Duration) {
+	balancer := m.selector.GetBalancer()
+	if penalty := balancer.FailurePenalty(); elapsed < penalty {
+		elapsed = penalty
+	}
+	ep.RecordLatency(elapsed, balancer.EWMAAlpha())
+}
+
 func (m *Manager) GetAllEndpoints() []*Endpoint {
 	return m.selector.GetAllEndpoints()
 }
 
+// GetOAuthRefreshCounts 返回启动以来各端点（按名称）OAuth 刷新成功和失败的次数
+func (m *Manager) GetOAuthRefreshCounts() map[string]OAuthRefreshCount {
+	return m.oauthRefreshes.snapshot()
+}
+
 func (m *Manager) RecordRequest(endpointID string, success bool, requestID string) {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
@@ -120,6 +168,9 @@ func (m *Manager) UpdateEndpoints(endpointConfigs []config.EndpointConfig) {
 		} else {
 			// New endpoint - create fresh with inherited statistics from database
 			endpoint := NewEndpoint(cfg)
+			endpoint.circuitPolicy = m.circuitPolicy
+			endpoint.events = m.events
+			endpoint.oauthRefreshes = m.oauthRefreshes
 			if m.statisticsManager != nil {
 				if err := initializeEndpointStatistics(endpoint, m.statisticsManager); err != nil {
 					log.Printf("WARNING: Failed to load statistics for new endpoint %s: %v", 
@@ -146,6 +197,9 @@ func (m *Manager) UpdateEndpoints(endpointConfigs []config.EndpointConfig) {
 	
 	// 重新启动健康检查
 	m.startHealthChecks()
+	
+	// 新端点立即获取模型列表
+	m.modelDiscovery.trigger()
 }
 
 
@@ -166,7 +220,7 @@ func (m *Manager) ResetEndpointStatus(endpointName string) error {
 
 	for _, endpoint := range m.endpoints {
 		if endpoint.Name == endpointName {
-			endpoint.MarkActive()
+			endpoint.MarkActive("status reset by admin")
 			return nil
 		}
 	}
@@ -203,6 +257,7 @@ func (m *Manager) stopHealthChecks() {
 func (m *Manager) runHealthCheck(endpoint *Endpoint, ticker *time.Ticker) {
 	// 获取恢复阈值配置，使用统一默认值
 	recoveryThreshold := config.GetIntWithDefault(m.config.Timeouts.RecoveryThreshold, config.Default.Timeouts.RecoveryThreshold)
+	interval := config.GetTimeoutDuration(m.config.Timeouts.CheckInterval, config.GetTimeoutDuration(config.Default.Timeouts.CheckInterval, 30*time.Second))
 	
 	for range ticker.C {
 		// 只对不可用的端点进行健康检查
@@ -210,6 +265,20 @@ func (m *Manager) runHealthCheck(endpoint *Endpoint, ticker *time.Ticker) {
 			continue
 		}
 		
+		// 预算耗尽的端点在窗口重置之前跳过健康检查，重置后直接恢复
+		if endpoint.IsBudgetExceeded() {
+			continue
+		}
+		if endpoint.ReleaseExpiredBudgetBlock() {
+			log.Printf("Endpoint %s budget window reset, endpoint restored", endpoint.Name)
+			continue
+		}
+		
+		// 熔断器打开或半开的端点由实时请求试探恢复，不进行合成健康检查
+		if endpoint.IsCircuitManaged() {
+			continue
+		}
+		
 		// Anthropic官方端点特例：在rate limit reset时间之前跳过健康检查
 		if endpoint.ShouldSkipHealthCheckUntilReset() {
 			// 只在合适的时机记录日志，避免过于频繁
@@ -221,6 +290,16 @@ func (m *Manager) runHealthCheck(endpoint *Endpoint, ticker *time.Ticker) {
 			continue
 		}
 		
+		// 关闭主动探测的端点（按量计费帐号）不发送合成请求，失效满一个检查间隔后直接恢复，由实际请求检验
+		if endpoint.IsActiveHealthCheckDisabled() {
+			if reason := endpoint.GetBlacklistReason(); reason == nil || time.Since(reason.BlacklistedAt) >= interval {
+				endpoint.RecordHealthCheck(HealthCheckResult{At: time.Now(), Probe: "passive", Success: true})
+				endpoint.MarkActive("restored without active health check")
+				log.Printf("Endpoint %s restored without active health check (health_check.disabled)", endpoint.Name)
+			}
+			continue
+		}
+		
 		// 如果是Anthropic官方端点且曾经有rate limit信息，记录恢复健康检查的信息
 		if endpoint.IsAnthropicEndpoint() {
 			resetTime, _ := endpoint.GetRateLimitState()
@@ -238,7 +317,7 @@ func (m *Manager) runHealthCheck(endpoint *Endpoint, ticker *time.Ticker) {
 			endpoint.RecordRequest(true, "health-check")
 			if endpoint.GetSuccessiveSuccesses() >= recoveryThreshold {
 				// 达到恢复阈值，恢复为可用状态
-				endpoint.MarkActive()
+				endpoint.MarkActive(fmt.Sprintf("%d successive health checks succeeded", recoveryThreshold))
 			}
 		}
 	}
@@ -262,6 +341,15 @@ func initializeEndpointStatistics(endpoint *Endpoint, statisticsManager statisti
 	endpoint.LastFailure = dbStats.LastFailure
 	endpoint.mutex.Unlock()
 
+	// Restore budget usage of the current windows so restarts don't reset the caps
+	if endpoint.Budget != nil {
+		records, err := statisticsManager.LoadBudgetUsage(endpoint.ID)
+		if err != nil {
+			return err
+		}
+		endpoint.restoreBudgetUsage(records)
+	}
+
 	return nil
 }
 
@@ -283,6 +371,47 @@ func (m *Manager) updateExistingEndpoint(existingEndpoint *Endpoint, newConfig c
 	
 	// Preserve request history for health checking
 	newEndpoint.RequestHistory = existingEndpoint.RequestHistory
+	
+	// Preserve latency estimate for load balancing
+	newEndpoint.latencyEWMA = existingEndpoint.latencyEWMA
+	
+	// Preserve budget usage and re-check it against the new limits
+	newEndpoint.budgetUsage = existingEndpoint.budgetUsage
+	newEndpoint.budgetBlockedUntil = existingEndpoint.budgetBlockedUntil
+	existingEndpoint.blacklistMutex.RLock()
+	newEndpoint.BlacklistReason = existingEndpoint.BlacklistReason
+	existingEndpoint.blacklistMutex.RUnlock()
+	newEndpoint.evaluateBudgetLocked(time.Now(), "")
+	
+	// Preserve health check history
+	newEndpoint.healthCheckHistory = existingEndpoint.healthCheckHistory
+	
+	// Preserve circuit breaker state and transition history
+	newEndpoint.circuitPolicy = m.circuitPolicy
+	newEndpoint.circuit = existingEndpoint.circuit
+	newEndpoint.events = m.events
+	newEndpoint.oauthRefreshes = m.oauthRefreshes
+	
+	// Preserve model catalog while the upstream stays the same
+	if existingEndpoint.URL == newEndpoint.URL && existingEndpoint.EndpointType == newEndpoint.EndpointType {
+		newEndpoint.modelCatalog = existingEndpoint.modelCatalog
+	}
+	
+	// Preserve per-key blacklist and history for credentials that are still configured
+	newEndpoint.preserveAPIKeyStates(existingEndpoint)
+	
+	// Preserve upstream rate limit capacity parsed from response headers
+	if existingEndpoint.rateLimitCapacity != nil {
+		newEndpoint.rateLimitCapacity = existingEndpoint.rateLimitCapacity
+		newEndpoint.rateLimitExhaustedUntil = existingEndpoint.rateLimitExhaustedUntil
+		newEndpoint.rateLimitReason = existingEndpoint.rateLimitReason
+	}
+	
+	// Preserve limiter state (in-flight permits and token buckets) when limits are still configured
+	if existingEndpoint.limiter != nil && newEndpoint.limiter != nil {
+		existingEndpoint.limiter.UpdateConfig(newConfig.Limits)
+		newEndpoint.limiter = existingEndpoint.limiter
+	}
 	newEndpoint.mutex.Unlock()
 	existingEndpoint.mutex.RUnlock()
 
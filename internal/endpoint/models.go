@@ -0,0 +1,266 @@
+package endpoint
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/config"
+)
+
+// ModelLister 调用端点的模型列表API，由健康检查器实现（复用其认证与HTTP客户端）
+type ModelLister interface {
+	ListModels(ep *Endpoint) ([]string, error)
+}
+
+// ModelCatalog 端点上游模型列表的缓存
+type ModelCatalog struct {
+	Models    []string  `json:"models"`
+	FetchedAt time.Time `json:"fetched_at,omitempty"` // 最近一次成功获取的时间
+	CheckedAt time.Time `json:"checked_at"`           // 最近一次尝试获取的时间
+	Error     string    `json:"error,omitempty"`      // 最近一次获取失败的原因，失败时保留上一次成功的模型列表
+}
+
+// CatalogModel 合并模型目录中的一个模型及提供它的端点
+type CatalogModel struct {
+	ID        string   `json:"id"`
+	Endpoints []string `json:"endpoints"`
+}
+
+// GetModelCatalog 返回端点的模型列表缓存副本，尚未获取过时返回nil
+func (e *Endpoint) GetModelCatalog() *ModelCatalog {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.modelCatalog == nil {
+		return nil
+	}
+	catalog := *e.modelCatalog
+	catalog.Models = append([]string(nil), e.modelCatalog.Models...)
+	return &catalog
+}
+
+// setModelCatalog 记录一次模型列表获取的结果
+func (e *Endpoint) setModelCatalog(models []string, err error, now time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.modelCatalog == nil {
+		e.modelCatalog = &ModelCatalog{}
+	}
+	e.modelCatalog.CheckedAt = now
+	if err != nil {
+		e.modelCatalog.Error = err.Error()
+		return
+	}
+	sort.Strings(models)
+	e.modelCatalog.Models = models
+	e.modelCatalog.FetchedAt = now
+	e.modelCatalog.Error = ""
+}
+
+// modelCatalogDue 模型列表是否需要刷新：从未获取、超过刷新间隔，或上次失败后超过重试间隔
+func (e *Endpoint) modelCatalogDue(now time.Time, refreshInterval time.Duration) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.modelCatalog == nil {
+		return true
+	}
+	if e.modelCatalog.Error != "" {
+		return now.Sub(e.modelCatalog.CheckedAt) >= config.Default.ModelDiscovery.RetryInterval
+	}
+	return now.Sub(e.modelCatalog.CheckedAt) >= refreshInterval
+}
+
+// UnknownModels 返回不在上游模型列表中的模型；尚未成功获取模型列表时无法判断，返回nil
+func (e *Endpoint) UnknownModels(models []string) []string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.modelCatalog == nil || e.modelCatalog.FetchedAt.IsZero() {
+		return nil
+	}
+	known := make(map[string]bool, len(e.modelCatalog.Models))
+	for _, model := range e.modelCatalog.Models {
+		known[model] = true
+	}
+	var unknown []string
+	for _, model := range models {
+		if model != "" && !known[model] {
+			unknown = append(unknown, model)
+		}
+	}
+	return unknown
+}
+
+// KnowsModel 模型是否出现在端点的配置或模型列表中：上游模型列表、模型重写规则的目标模型，或不含通配符的源模型
+func (e *Endpoint) KnowsModel(model string) bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.modelCatalog != nil {
+		index := sort.SearchStrings(e.modelCatalog.Models, model)
+		if index < len(e.modelCatalog.Models) && e.modelCatalog.Models[index] == model {
+			return true
+		}
+	}
+	if e.ModelRewrite != nil && e.ModelRewrite.Enabled {
+		for _, rule := range e.ModelRewrite.Rules {
+			if rule.TargetModel == model || (rule.SourcePattern == model && !strings.ContainsAny(rule.SourcePattern, "*?[")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rewriteTargets 返回模型重写规则的目标模型
+func (e *Endpoint) rewriteTargets() []string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.ModelRewrite == nil || !e.ModelRewrite.Enabled {
+		return nil
+	}
+	targets := make([]string, 0, len(e.ModelRewrite.Rules))
+	for _, rule := range e.ModelRewrite.Rules {
+		targets = append(targets, rule.TargetModel)
+	}
+	return targets
+}
+
+// modelDiscovery 定期刷新各端点的模型列表，配置支持热更新
+type modelDiscovery struct {
+	mutex           sync.RWMutex
+	enabled         bool
+	refreshInterval time.Duration
+	lister          ModelLister
+	kick            chan struct{}
+}
+
+func newModelDiscovery(cfg config.ModelDiscoveryConfig) *modelDiscovery {
+	d := &modelDiscovery{kick: make(chan struct{}, 1)}
+	d.update(cfg)
+	return d
+}
+
+func (d *modelDiscovery) update(cfg config.ModelDiscoveryConfig) {
+	defaultInterval, _ := time.ParseDuration(config.Default.ModelDiscovery.RefreshInterval)
+
+	d.mutex.Lock()
+	d.enabled = cfg.Enabled
+	d.refreshInterval = config.GetTimeoutDuration(cfg.RefreshInterval, defaultInterval)
+	d.mutex.Unlock()
+	d.trigger()
+}
+
+func (d *modelDiscovery) settings() (bool, time.Duration, ModelLister) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.enabled, d.refreshInterval, d.lister
+}
+
+// trigger 让后台循环立即检查一次（端点或配置变化后），不阻塞调用方
+func (d *modelDiscovery) trigger() {
+	select {
+	case d.kick <- struct{}{}:
+	default:
+	}
+}
+
+// SetModelLister 设置模型列表获取器并启动模型发现的后台循环
+func (m *Manager) SetModelLister(lister ModelLister) {
+	m.modelDiscovery.mutex.Lock()
+	started := m.modelDiscovery.lister != nil
+	m.modelDiscovery.lister = lister
+	m.modelDiscovery.mutex.Unlock()
+
+	if !started {
+		go m.runModelDiscovery()
+	}
+}
+
+// UpdateModelDiscovery 热更新模型发现配置
+func (m *Manager) UpdateModelDiscovery(cfg config.ModelDiscoveryConfig) {
+	m.modelDiscovery.update(cfg)
+}
+
+// IsModelDiscoveryEnabled 是否启用了模型发现
+func (m *Manager) IsModelDiscoveryEnabled() bool {
+	enabled, _, _ := m.modelDiscovery.settings()
+	return enabled
+}
+
+func (m *Manager) runModelDiscovery() {
+	ticker := time.NewTicker(config.Default.ModelDiscovery.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		enabled, refreshInterval, _ := m.modelDiscovery.settings()
+		if enabled {
+			now := time.Now()
+			for _, ep := range m.GetAllEndpoints() {
+				if ep.IsEnabled() && ep.modelCatalogDue(now, refreshInterval) {
+					m.RefreshModels(ep)
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-m.modelDiscovery.kick:
+		}
+	}
+}
+
+// RefreshModels 立即获取端点的模型列表并更新缓存，记录不在列表中的模型重写目标
+func (m *Manager) RefreshModels(ep *Endpoint) error {
+	_, _, lister := m.modelDiscovery.settings()
+	if lister == nil {
+		return nil
+	}
+
+	models, err := lister.ListModels(ep)
+	ep.setModelCatalog(models, err, time.Now())
+	if err != nil {
+		log.Printf("WARNING: Failed to list models for endpoint %s: %v", ep.Name, err)
+		return err
+	}
+
+	for _, target := range ep.UnknownModels(ep.rewriteTargets()) {
+		log.Printf("WARNING: Model rewrite target %q of endpoint %s is not in the upstream model list", target, ep.Name)
+	}
+	return nil
+}
+
+// GetMergedModelCatalog 合并所有启用端点的模型列表，按模型ID排序
+func (m *Manager) GetMergedModelCatalog() []CatalogModel {
+	byID := make(map[string]*CatalogModel)
+	for _, ep := range m.GetAllEndpoints() {
+		if !ep.IsEnabled() {
+			continue
+		}
+		catalog := ep.GetModelCatalog()
+		if catalog == nil {
+			continue
+		}
+		for _, id := range catalog.Models {
+			model := byID[id]
+			if model == nil {
+				model = &CatalogModel{ID: id}
+				byID[id] = model
+			}
+			model.Endpoints = append(model.Endpoints, ep.Name)
+		}
+	}
+
+	merged := make([]CatalogModel, 0, len(byID))
+	for _, model := range byID {
+		merged = append(merged, *model)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].ID < merged[j].ID })
+	return merged
+}
@@ -0,0 +1,200 @@
+package endpoint
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/statistics"
+)
+
+// RateLimitDimension 上游报告的某一维度（请求数或token数）的额度
+type RateLimitDimension struct {
+	Limit     int64     `json:"limit,omitempty"` // 0 表示上游没有报告上限
+	Remaining int64     `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// RateLimitCapacity 从上游速率限制响应头解析出的剩余额度，各提供商的头部统一为请求数和token数两个维度
+type RateLimitCapacity struct {
+	Source    string              `json:"source"` // 解析出该额度的头部格式，如 "openai"、"anthropic+retry-after"
+	Requests  *RateLimitDimension `json:"requests,omitempty"`
+	Tokens    *RateLimitDimension `json:"tokens,omitempty"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// RateLimitStatus 端点当前的上游额度与耗尽状态，供端点状态API展示
+type RateLimitStatus struct {
+	Capacity       *RateLimitCapacity `json:"capacity,omitempty"`
+	Exhausted      bool               `json:"exhausted"`
+	ExhaustedUntil *time.Time         `json:"exhausted_until,omitempty"`
+	Reason         string             `json:"reason,omitempty"`
+}
+
+// nearExhaustion 该维度在重置之前剩余额度是否为0或低于上限的 minRatio
+func (d *RateLimitDimension) nearExhaustion(now time.Time, minRatio float64) bool {
+	if d == nil || !now.Before(d.ResetAt) {
+		return false
+	}
+	if d.Remaining <= 0 {
+		return true
+	}
+	return d.Limit > 0 && float64(d.Remaining)/float64(d.Limit) < minRatio
+}
+
+func (d *RateLimitDimension) describe(name string) string {
+	if d.Limit > 0 {
+		return fmt.Sprintf("%s %d/%d remaining", name, d.Remaining, d.Limit)
+	}
+	return fmt.Sprintf("%s %d remaining", name, d.Remaining)
+}
+
+// exhaustion 返回接近耗尽的维度中最晚的重置时间和原因，没有接近耗尽时返回零值
+func (c *RateLimitCapacity) exhaustion(now time.Time, minRatio float64) (time.Time, string) {
+	var until time.Time
+	var reasons []string
+	for _, dim := range []struct {
+		name string
+		d    *RateLimitDimension
+	}{{"requests", c.Requests}, {"tokens", c.Tokens}} {
+		if !dim.d.nearExhaustion(now, minRatio) {
+			continue
+		}
+		reasons = append(reasons, dim.d.describe(dim.name))
+		if dim.d.ResetAt.After(until) {
+			until = dim.d.ResetAt
+		}
+	}
+	if len(reasons) == 0 {
+		return time.Time{}, ""
+	}
+	return until, fmt.Sprintf("%s (%s)", strings.Join(reasons, ", "), c.Source)
+}
+
+func copyRateLimitDimension(d *RateLimitDimension) *RateLimitDimension {
+	if d == nil {
+		return nil
+	}
+	dimCopy := *d
+	return &dimCopy
+}
+
+func dimensionFromConfig(cfg *config.RateLimitDimensionConfig) *RateLimitDimension {
+	if cfg == nil {
+		return nil
+	}
+	return &RateLimitDimension{Limit: cfg.Limit, Remaining: cfg.Remaining, ResetAt: time.Unix(cfg.ResetAt, 0)}
+}
+
+func dimensionToConfig(d *RateLimitDimension) *config.RateLimitDimensionConfig {
+	if d == nil {
+		return nil
+	}
+	return &config.RateLimitDimensionConfig{Limit: d.Limit, Remaining: d.Remaining, ResetAt: d.ResetAt.Unix()}
+}
+
+// restoreRateLimitCapacity 从配置恢复持久化的耗尽状态，已过重置时间的快照被忽略；调用方需持有 e.mutex 或独占端点
+func (e *Endpoint) restoreRateLimitCapacity(cfg *config.RateLimitCapacityConfig) {
+	if cfg == nil {
+		return
+	}
+	until := time.Unix(cfg.ExhaustedUntil, 0)
+	if !time.Now().Before(until) {
+		return
+	}
+	e.rateLimitCapacity = &RateLimitCapacity{
+		Source:    cfg.Source,
+		Requests:  dimensionFromConfig(cfg.Requests),
+		Tokens:    dimensionFromConfig(cfg.Tokens),
+		UpdatedAt: time.Unix(cfg.UpdatedAt, 0),
+	}
+	e.rateLimitExhaustedUntil = until
+	e.rateLimitReason = cfg.Reason
+}
+
+// UpdateRateLimitCapacity 记录一次响应中解析出的上游额度，剩余额度低于 minRatio 时端点在重置之前被视为接近耗尽；
+// 返回耗尽状态是否发生变化，调用方据此持久化
+func (e *Endpoint) UpdateRateLimitCapacity(capacity RateLimitCapacity, minRatio float64) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := time.Now()
+	capacity.UpdatedAt = now
+	e.rateLimitCapacity = &capacity
+
+	// 相对时间格式的重置时间每次响应都有毫秒级抖动，相差不到1秒视为未变化，避免频繁写配置
+	until, reason := capacity.exhaustion(now, minRatio)
+	if until.IsZero() == e.rateLimitExhaustedUntil.IsZero() && absDuration(until.Sub(e.rateLimitExhaustedUntil)) < time.Second {
+		e.rateLimitReason = reason
+		return false
+	}
+	e.rateLimitExhaustedUntil = until
+	e.rateLimitReason = reason
+	if until.IsZero() {
+		e.recordEvent(statistics.EndpointEvent{EventType: statistics.EventRateLimit, Success: true, Reason: "upstream rate limit capacity recovered"})
+	} else {
+		e.recordEvent(statistics.EndpointEvent{
+			EventType: statistics.EventRateLimit,
+			Reason:    fmt.Sprintf("nearly exhausted until %s: %s", until.Format(time.RFC3339), reason),
+		})
+	}
+	return true
+}
+
+// RateLimitExhaustion 端点的上游额度接近耗尽时返回恢复时间和原因，否则返回零值
+func (e *Endpoint) RateLimitExhaustion() (time.Time, string) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if !time.Now().Before(e.rateLimitExhaustedUntil) {
+		return time.Time{}, ""
+	}
+	return e.rateLimitExhaustedUntil, e.rateLimitReason
+}
+
+// GetRateLimitStatus 返回上游额度的当前状态，从未收到速率限制响应头时返回nil
+func (e *Endpoint) GetRateLimitStatus() *RateLimitStatus {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.rateLimitCapacity == nil {
+		return nil
+	}
+	capacity := *e.rateLimitCapacity
+	capacity.Requests = copyRateLimitDimension(capacity.Requests)
+	capacity.Tokens = copyRateLimitDimension(capacity.Tokens)
+	status := &RateLimitStatus{Capacity: &capacity}
+	if time.Now().Before(e.rateLimitExhaustedUntil) {
+		until := e.rateLimitExhaustedUntil
+		status.Exhausted = true
+		status.ExhaustedUntil = &until
+		status.Reason = e.rateLimitReason
+	}
+	return status
+}
+
+// RateLimitCapacityConfig 返回需要写回配置文件的额度快照：只在接近耗尽时保存，恢复后返回nil以清除配置
+func (e *Endpoint) RateLimitCapacityConfig() *config.RateLimitCapacityConfig {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.rateLimitCapacity == nil || !time.Now().Before(e.rateLimitExhaustedUntil) {
+		return nil
+	}
+	return &config.RateLimitCapacityConfig{
+		Source:         e.rateLimitCapacity.Source,
+		Reason:         e.rateLimitReason,
+		ExhaustedUntil: e.rateLimitExhaustedUntil.Unix(),
+		UpdatedAt:      e.rateLimitCapacity.UpdatedAt.Unix(),
+		Requests:       dimensionToConfig(e.rateLimitCapacity.Requests),
+		Tokens:         dimensionToConfig(e.rateLimitCapacity.Tokens),
+	}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -4,17 +4,20 @@ import (
 	"fmt"
 	"sync"
 
+	"claude-code-companion/internal/config"
 	"claude-code-companion/internal/utils"
 )
 
 type Selector struct {
 	endpoints []*Endpoint
+	balancer  *Balancer
 	mutex     sync.RWMutex
 }
 
-func NewSelector(endpoints []*Endpoint) *Selector {
+func NewSelector(endpoints []*Endpoint, loadBalancing config.LoadBalancingConfig) *Selector {
 	return &Selector{
 		endpoints: endpoints,
+		balancer:  NewBalancer(loadBalancing),
 	}
 }
 
@@ -28,14 +31,13 @@ func (s *Selector) SelectEndpoint() (*Endpoint, error) {
 		sorterEndpoints[i] = ep
 	}
 
-	// 使用统一的端点选择逻辑
-	selected := utils.SelectBestEndpoint(sorterEndpoints)
+	// 使用统一的端点选择逻辑，由负载均衡策略在最高层级的可用端点中挑选
+	selected := s.balancer.Pick(toEndpoints(utils.SelectTopTierCandidates(sorterEndpoints, []string{})), nil)
 	if selected == nil {
 		return nil, fmt.Errorf("no available endpoints found")
 	}
 
-	// 类型断言转换回 *Endpoint
-	return selected.(*Endpoint), nil
+	return selected, nil
 }
 
 // SelectEndpointWithTags 根据tags选择endpoint
@@ -49,14 +51,27 @@ func (s *Selector) SelectEndpointWithTags(tags []string) (*Endpoint, error) {
 		sorterEndpoints[i] = ep
 	}
 
-	// 使用新的标签匹配选择逻辑
-	selected := utils.SelectBestEndpointWithTags(sorterEndpoints, tags)
+	// 使用新的标签匹配选择逻辑，由标签组的负载均衡策略在最高层级的可用端点中挑选
+	selected := s.balancer.Pick(toEndpoints(utils.SelectTopTierCandidates(sorterEndpoints, tags)), tags)
 	if selected == nil {
 		return nil, fmt.Errorf("no available endpoints match the required tags: %v", tags)
 	}
 
-	// 类型断言转换回 *Endpoint
-	return selected.(*Endpoint), nil
+	return selected, nil
+}
+
+// toEndpoints 类型断言转换回 *Endpoint
+func toEndpoints(sorters []utils.EndpointSorter) []*Endpoint {
+	endpoints := make([]*Endpoint, len(sorters))
+	for i, sorter := range sorters {
+		endpoints[i] = sorter.(*Endpoint)
+	}
+	return endpoints
+}
+
+// GetBalancer 获取负载均衡器
+func (s *Selector) GetBalancer() *Balancer {
+	return s.balancer
 }
 
 func (s *Selector) GetAllEndpoints() []*Endpoint {
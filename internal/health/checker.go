@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"claude-code-companion/internal/config"
 	"claude-code-companion/internal/conversion"
@@ -13,6 +15,9 @@ import (
 	"claude-code-companion/internal/modelrewrite"
 )
 
+// healthCheckRequestID 健康检查探测在凭据失败窗口中使用的请求ID
+const healthCheckRequestID = "health-check"
+
 type Checker struct {
 	extractor       *RequestExtractor
 	healthTimeouts  config.HealthCheckTimeoutConfig
@@ -33,23 +38,77 @@ func (c *Checker) GetExtractor() *RequestExtractor {
 	return c.extractor
 }
 
+// CheckEndpoint 按端点的 health_check 配置探测端点，结果记录到端点的健康检查历史
 func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
+	healthCheck := ep.GetHealthCheckConfig()
+	if healthCheck == nil {
+		healthCheck = &config.EndpointHealthCheckConfig{}
+	}
+	probe := config.GetStringWithDefault(healthCheck.Probe, config.Default.EndpointHealthCheck.Probe)
+
+	startTime := time.Now()
+	var statusCode int
+	var err error
+	if probe == "models" {
+		statusCode, err = c.checkModels(ep, healthCheck)
+	} else {
+		statusCode, err = c.checkMessages(ep, healthCheck)
+	}
+	latency := time.Since(startTime)
+
+	// 延迟 SLO：探测成功但耗时超过 latency_slo 也视为不健康
+	if err == nil && healthCheck.LatencySLO != "" {
+		if slo, parseErr := time.ParseDuration(healthCheck.LatencySLO); parseErr == nil && latency > slo {
+			err = fmt.Errorf("health check latency %v exceeds SLO %v", latency.Round(time.Millisecond), slo)
+		}
+	}
+
+	result := endpoint.HealthCheckResult{
+		At:         startTime,
+		Probe:      probe,
+		Success:    err == nil,
+		StatusCode: statusCode,
+		LatencyMs:  latency.Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	ep.RecordHealthCheck(result)
+	return err
+}
+
+// checkModels 发送 GET 模型列表请求，不消耗token
+func (c *Checker) checkModels(ep *endpoint.Endpoint, healthCheck *config.EndpointHealthCheckConfig) (int, error) {
+	req, err := http.NewRequest("GET", ep.GetModelsURL(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create models health check request: %v", err)
+	}
+	if ep.EndpointType == "anthropic" {
+		req.Header.Set("Anthropic-Version", config.Default.HealthCheck.Headers["Anthropic-Version"])
+	}
+
+	statusCode, body, err := c.doProbe(ep, req)
+	if err != nil {
+		return statusCode, err
+	}
+	if healthCheck.ExpectContains != "" && !bytes.Contains(body, []byte(healthCheck.ExpectContains)) {
+		return statusCode, fmt.Errorf("health check response does not contain %q", healthCheck.ExpectContains)
+	}
+	return statusCode, nil
+}
+
+// checkMessages 发送一条消息请求（默认模拟 Claude Code 的话题检测请求），可配置提示词、模型和 max_tokens
+func (c *Checker) checkMessages(ep *endpoint.Endpoint, healthCheck *config.EndpointHealthCheckConfig) (int, error) {
 	requestInfo := c.extractor.GetRequestInfo()
 	
 	// 构造健康检查请求
 	healthCheckRequest := map[string]interface{}{
-		"model":       requestInfo.Model,
-		"max_tokens":  config.Default.HealthCheck.MaxTokens,
+		"model":       config.GetStringWithDefault(healthCheck.Model, requestInfo.Model),
+		"max_tokens":  config.GetIntWithDefault(healthCheck.MaxTokens, config.Default.HealthCheck.MaxTokens),
 		"messages": []map[string]interface{}{
 			{
 				"role":    "user",
-				"content": "hello",
-			},
-		},
-		"system": []map[string]interface{}{
-			{
-				"type": "text",
-				"text": "Analyze if this message indicates a new conversation topic. If it does, extract a 2-3 word title that captures the new topic. Format your response as a JSON object with two fields: 'isNewTopic' (boolean) and 'title' (string, or null if isNewTopic is false). Only include these fields, no other text.",
+				"content": config.GetStringWithDefault(healthCheck.Prompt, config.Default.EndpointHealthCheck.Prompt),
 			},
 		},
 		"temperature": config.Default.HealthCheck.Temperature,
@@ -58,11 +117,23 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 		},
 		"stream": config.Default.HealthCheck.StreamMode,
 	}
+	systemPrompt := healthCheck.SystemPrompt
+	if systemPrompt == "" && healthCheck.Prompt == "" {
+		systemPrompt = "Analyze if this message indicates a new conversation topic. If it does, extract a 2-3 word title that captures the new topic. Format your response as a JSON object with two fields: 'isNewTopic' (boolean) and 'title' (string, or null if isNewTopic is false). Only include these fields, no other text."
+	}
+	if systemPrompt != "" {
+		healthCheckRequest["system"] = []map[string]interface{}{
+			{
+				"type": "text",
+				"text": systemPrompt,
+			},
+		}
+	}
 
 	// 将请求序列化为JSON
 	requestBody, err := json.Marshal(healthCheckRequest)
 	if err != nil {
-		return fmt.Errorf("failed to marshal health check request: %v", err)
+		return 0, fmt.Errorf("failed to marshal health check request: %v", err)
 	}
 
 	// 获取目标URL（稍后可能会被格式转换修改）
@@ -71,7 +142,7 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 	// 创建临时HTTP请求用于模型重写处理
 	tempReq, err := http.NewRequest("POST", targetURL, bytes.NewReader(requestBody))
 	if err != nil {
-		return fmt.Errorf("failed to create temporary request for model rewrite: %v", err)
+		return 0, fmt.Errorf("failed to create temporary request for model rewrite: %v", err)
 	}
 
 	// 复制从实际请求中提取的头部（用于模型重写）
@@ -82,13 +153,13 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 	// 应用模型重写（如果配置了）
 	_, _, err = c.modelRewriter.RewriteRequestWithTags(tempReq, ep.ModelRewrite, ep.Tags)
 	if err != nil {
-		return fmt.Errorf("model rewrite failed during health check: %v", err)
+		return 0, fmt.Errorf("model rewrite failed during health check: %v", err)
 	}
 
 	// 如果进行了模型重写，获取重写后的请求体
 	finalRequestBody, err := io.ReadAll(tempReq.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read rewritten request body: %v", err)
+		return 0, fmt.Errorf("failed to read rewritten request body: %v", err)
 	}
 
 	// 格式转换（在模型重写之后）
@@ -99,20 +170,24 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 			MaxTokensFieldName: ep.MaxTokensFieldName,
 		}
 		
-		convertedBody, _, err := c.converter.ConvertRequest(finalRequestBody, endpointInfo)
+		convertedBody, ctx, err := c.converter.ConvertRequest(finalRequestBody, endpointInfo)
 		if err != nil {
-			return fmt.Errorf("request format conversion failed during health check: %v", err)
+			return 0, fmt.Errorf("request format conversion failed during health check: %v", err)
 		}
 		finalRequestBody = convertedBody
 		
-		// 对于OpenAI端点，需要更新目标URL
-		targetURL = ep.GetFullURL("/chat/completions")
+		// 对于OpenAI端点，需要更新目标URL；Gemini 的URL包含模型名
+		if ep.EndpointType == "gemini" {
+			targetURL = ep.GetGeminiURL(ctx.Model, ctx.IsStreaming)
+		} else {
+			targetURL = ep.GetFullURL("/chat/completions")
+		}
 	}
 
 	// 构造最终的HTTP请求
 	req, err := http.NewRequest("POST", targetURL, bytes.NewReader(finalRequestBody))
 	if err != nil {
-		return fmt.Errorf("failed to create final health check request: %v", err)
+		return 0, fmt.Errorf("failed to create final health check request: %v", err)
 	}
 
 	// 复制从实际请求中提取的头部（包含默认值）
@@ -120,13 +195,48 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 		req.Header.Set(key, value)
 	}
 
-	// 单独设置认证头部（不包含在默认headers中）
+	statusCode, body, err := c.doProbe(ep, req)
+	if err != nil {
+		return statusCode, err
+	}
+
+	// 简单验证：检查是否包含SSE格式的流式响应
+	if !bytes.Contains(body, []byte("event:")) && !bytes.Contains(body, []byte("data:")) {
+		// 如果不是流式响应，检查是否为有效的JSON响应
+		var jsonResp map[string]interface{}
+		if err := json.Unmarshal(body, &jsonResp); err != nil {
+			return statusCode, fmt.Errorf("health check response is neither valid SSE nor JSON: %v", err)
+		}
+		
+		// 检查是否包含Anthropic响应的基本字段
+		if _, hasContent := jsonResp["content"]; !hasContent {
+			_, hasCandidates := jsonResp["candidates"] // Gemini 非流式响应
+			if _, hasError := jsonResp["error"]; !hasError && !hasCandidates {
+				return statusCode, fmt.Errorf("health check response missing required fields")
+			}
+		}
+	}
+
+	// 文本断言：流式响应的文本分散在多个事件中，先拼接再匹配
+	if healthCheck.ExpectContains != "" && !strings.Contains(extractResponseText(body), healthCheck.ExpectContains) {
+		return statusCode, fmt.Errorf("health check response does not contain %q", healthCheck.ExpectContains)
+	}
+
+	return statusCode, nil
+}
+
+// doProbe 设置认证头部并使用端点的健康检查客户端发送请求，非2xx状态码视为失败
+func (c *Checker) doProbe(ep *endpoint.Endpoint, req *http.Request) (int, []byte, error) {
+	// 单独设置认证头部（不包含在默认headers中），多凭据端点使用下一个请求会用的凭据，但不推进轮换位置
+	keyIndex, authValue := ep.PeekAPIKey()
 	if ep.AuthType == "api_key" {
-		req.Header.Set("x-api-key", ep.AuthValue)
+		req.Header.Set(ep.GetAPIKeyHeaderName(), authValue)
+	} else if keyIndex >= 0 {
+		req.Header.Set("Authorization", "Bearer "+authValue)
 	} else {
 		authHeader, err := ep.GetAuthHeader()
 		if err != nil {
-			return fmt.Errorf("failed to get auth header: %v", err)
+			return 0, nil, fmt.Errorf("failed to get auth header: %v", err)
 		}
 		req.Header.Set("Authorization", authHeader)
 	}
@@ -134,42 +244,75 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 	// 执行请求 - 使用端点特定的HTTP客户端
 	client, err := ep.CreateHealthClient(c.healthTimeouts)
 	if err != nil {
-		return fmt.Errorf("failed to create health client for endpoint: %v", err)
+		return 0, nil, fmt.Errorf("failed to create health client for endpoint: %v", err)
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("health check request failed: %v", err)
+		err = fmt.Errorf("health check request failed: %v", err)
+		ep.RecordAPIKeyResult(keyIndex, false, 0, 0, healthCheckRequestID, err.Error())
+		return 0, nil, err
 	}
 	defer resp.Body.Close()
 	
-	// 检查状态码
+	// 检查状态码；探测结果同时记录到所用的凭据，失效的凭据（如 401）与实际请求一样被拉黑
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("health check failed with status %d: %s", resp.StatusCode, string(body))
+		err = fmt.Errorf("health check failed with status %d: %s", resp.StatusCode, string(body))
+		ep.RecordAPIKeyResult(keyIndex, false, resp.StatusCode, 0, healthCheckRequestID, err.Error())
+		return resp.StatusCode, nil, err
 	}
+	ep.RecordAPIKeyResult(keyIndex, true, resp.StatusCode, 0, healthCheckRequestID, "")
 
 	// 读取响应体验证是否为有效流式响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read health check response: %v", err)
+		return resp.StatusCode, nil, fmt.Errorf("failed to read health check response: %v", err)
 	}
+	return resp.StatusCode, body, nil
+}
 
-	// 简单验证：检查是否包含SSE格式的流式响应
-	if !bytes.Contains(body, []byte("event:")) && !bytes.Contains(body, []byte("data:")) {
-		// 如果不是流式响应，检查是否为有效的JSON响应
-		var jsonResp map[string]interface{}
-		if err := json.Unmarshal(body, &jsonResp); err != nil {
-			return fmt.Errorf("health check response is neither valid SSE nor JSON: %v", err)
+// extractResponseText 拼接响应中的文本内容，支持 JSON 响应和 SSE 流，
+// 覆盖 Anthropic（text）、OpenAI（content）和 Gemini（parts[].text）格式
+func extractResponseText(body []byte) string {
+	var builder strings.Builder
+	collect := func(data []byte) {
+		var value interface{}
+		if json.Unmarshal(data, &value) == nil {
+			collectText(&builder, value)
 		}
-		
-		// 检查是否包含Anthropic响应的基本字段
-		if _, hasContent := jsonResp["content"]; !hasContent {
-			if _, hasError := jsonResp["error"]; !hasError {
-				return fmt.Errorf("health check response missing required fields")
-			}
+	}
+
+	if json.Valid(body) {
+		collect(body)
+		return builder.String()
+	}
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("data:")) {
+			collect(bytes.TrimSpace(line[len("data:"):]))
 		}
 	}
+	return builder.String()
+}
 
-	return nil
-}
\ No newline at end of file
+func collectText(builder *strings.Builder, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{"text", "content"} {
+			if text, ok := v[key].(string); ok {
+				builder.WriteString(text)
+			}
+		}
+		for key, child := range v {
+			if _, isString := child.(string); isString && (key == "text" || key == "content") {
+				continue
+			}
+			collectText(builder, child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectText(builder, child)
+		}
+	}
+}
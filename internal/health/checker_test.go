@@ -0,0 +1,231 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/conversion"
+	"claude-code-companion/internal/endpoint"
+	"claude-code-companion/internal/logger"
+	"claude-code-companion/internal/modelrewrite"
+)
+
+func newTestChecker() *Checker {
+	return NewChecker(config.HealthCheckTimeoutConfig{}, nil, nil)
+}
+
+// newProbeTestEndpoint 创建探测测试用端点，默认使用 models 探测
+func newProbeTestEndpoint(url string, healthCheck *config.EndpointHealthCheckConfig, authValues ...string) *endpoint.Endpoint {
+	if healthCheck == nil {
+		healthCheck = &config.EndpointHealthCheckConfig{Probe: "models"}
+	}
+	return endpoint.NewEndpoint(config.EndpointConfig{
+		Name:         "probe-test",
+		URL:          url,
+		EndpointType: "anthropic",
+		AuthType:     "api_key",
+		AuthValue:    "sk-key-0",
+		AuthValues:   authValues,
+		Enabled:      true,
+		HealthCheck:  healthCheck,
+	})
+}
+
+func TestProbeDoesNotAdvanceKeyRotation(t *testing.T) {
+	var probedKeys []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probedKeys = append(probedKeys, r.Header.Get("x-api-key"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[{"id":"claude-sonnet-4"}]}`))
+	}))
+	defer upstream.Close()
+
+	ep := newProbeTestEndpoint(upstream.URL, nil, "sk-key-1", "sk-key-2")
+	checker := newTestChecker()
+	for i := 0; i < 3; i++ {
+		if err := checker.CheckEndpoint(ep); err != nil {
+			t.Fatalf("probe %d failed: %v", i, err)
+		}
+	}
+
+	// 探测使用下一个请求会用的凭据，不推进轮换
+	for i, key := range probedKeys {
+		if key != "sk-key-0" {
+			t.Errorf("probe %d: expected key sk-key-0, got %s", i, key)
+		}
+	}
+	if index, _ := ep.SelectAPIKey(); index != 0 {
+		t.Errorf("expected live traffic to start with key 0 after probes, got %d", index)
+	}
+	if status := ep.GetAPIKeyStatus()[0]; status.TotalRequests != 3 || status.SuccessRequests != 3 {
+		t.Errorf("expected probes recorded against key 0, got %+v", status)
+	}
+}
+
+func TestProbeRecordsRevokedKey(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") == "sk-key-0" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[{"id":"claude-sonnet-4"}]}`))
+	}))
+	defer upstream.Close()
+
+	ep := newProbeTestEndpoint(upstream.URL, nil, "sk-key-1")
+	checker := newTestChecker()
+
+	// 失效的凭据探测失败后被拉黑，下一次探测使用其他凭据
+	if err := checker.CheckEndpoint(ep); err == nil {
+		t.Fatalf("expected probe with revoked key to fail")
+	}
+	if status := ep.GetAPIKeyStatus()[0]; status.Available {
+		t.Errorf("expected revoked key to be blacklisted after probe, got %+v", status)
+	}
+	if err := checker.CheckEndpoint(ep); err != nil {
+		t.Errorf("expected probe with the remaining key to succeed, got %v", err)
+	}
+}
+
+// newMessagesTestChecker 创建带模型重写器和格式转换器的检查器，用于 messages 探测
+func newMessagesTestChecker(t *testing.T) *Checker {
+	t.Helper()
+	log, err := logger.NewLogger(logger.LogConfig{Level: "error", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	return NewChecker(config.HealthCheckTimeoutConfig{}, modelrewrite.NewRewriter(*log), conversion.NewConverter(log))
+}
+
+func TestModelsProbe(t *testing.T) {
+	var paths []string
+	var versions []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		versions = append(versions, r.Header.Get("Anthropic-Version"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[{"id":"claude-sonnet-4"}]}`))
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name           string
+		expectContains string
+		success        bool
+	}{
+		{"no assertion", "", true},
+		{"assertion matches", "claude-sonnet-4", true},
+		{"assertion does not match", "claude-opus-4", false},
+	}
+	for _, tt := range tests {
+		ep := newProbeTestEndpoint(upstream.URL, &config.EndpointHealthCheckConfig{Probe: "models", ExpectContains: tt.expectContains})
+		err := newTestChecker().CheckEndpoint(ep)
+		if (err == nil) != tt.success {
+			t.Errorf("%s: expected success=%v, got %v", tt.name, tt.success, err)
+		}
+		history := ep.GetHealthCheckHistory()
+		if len(history) != 1 || history[0].Probe != "models" || history[0].Success != tt.success || history[0].StatusCode != http.StatusOK {
+			t.Errorf("%s: unexpected health check history %+v", tt.name, history)
+		}
+	}
+
+	for i := range paths {
+		if paths[i] != "GET /v1/models" || versions[i] == "" {
+			t.Errorf("expected GET /v1/models with Anthropic-Version, got %s %q", paths[i], versions[i])
+		}
+	}
+}
+
+func TestMessagesProbeTextAssertion(t *testing.T) {
+	var body string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected probe request %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(body))
+	}))
+	defer upstream.Close()
+
+	// 流式响应的文本分散在多个事件中
+	streamed := "event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"po"}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ng"}}` + "\n\n"
+
+	tests := []struct {
+		name           string
+		body           string
+		expectContains string
+		errorContains  string
+	}{
+		{"streamed text matches", streamed, "pong", ""},
+		{"streamed text does not match", streamed, "hello", "does not contain"},
+		{"json text matches", `{"content":[{"type":"text","text":"pong"}]}`, "pong", ""},
+		{"json missing content", `{"id":"msg_1"}`, "", "missing required fields"},
+		{"neither sse nor json", "pong", "", "neither valid SSE nor JSON"},
+	}
+	for _, tt := range tests {
+		body = tt.body
+		ep := newProbeTestEndpoint(upstream.URL, &config.EndpointHealthCheckConfig{Probe: "messages", Prompt: "ping", ExpectContains: tt.expectContains})
+		err := newMessagesTestChecker(t).CheckEndpoint(ep)
+		if tt.errorContains == "" {
+			if err != nil {
+				t.Errorf("%s: expected success, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.errorContains, err)
+		}
+	}
+}
+
+func TestProbeLatencySLO(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer upstream.Close()
+
+	// 探测成功但超过 latency_slo 也视为不健康
+	slow := newProbeTestEndpoint(upstream.URL, &config.EndpointHealthCheckConfig{Probe: "models", LatencySLO: "10ms"})
+	err := newTestChecker().CheckEndpoint(slow)
+	if err == nil || !strings.Contains(err.Error(), "exceeds SLO") {
+		t.Fatalf("expected latency SLO violation, got %v", err)
+	}
+	result := slow.GetHealthCheckHistory()[0]
+	if result.Success || result.StatusCode != http.StatusOK || result.LatencyMs < 50 {
+		t.Errorf("expected failed result with measured latency, got %+v", result)
+	}
+
+	fast := newProbeTestEndpoint(upstream.URL, &config.EndpointHealthCheckConfig{Probe: "models", LatencySLO: "5s"})
+	if err := newTestChecker().CheckEndpoint(fast); err != nil {
+		t.Errorf("expected probe within SLO to succeed, got %v", err)
+	}
+}
+
+func TestExtractResponseText(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"anthropic json", `{"content":[{"type":"text","text":"hello"},{"type":"text","text":" world"}]}`, "hello world"},
+		{"openai json", `{"choices":[{"message":{"role":"assistant","content":"hello"}}]}`, "hello"},
+		{"gemini json", `{"candidates":[{"content":{"parts":[{"text":"hel"},{"text":"lo"}]}}]}`, "hello"},
+		{"openai stream", "data: {\"choices\":[{\"delta\":{\"content\":\"hel\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\ndata: [DONE]\n\n", "hello"},
+		{"no text", `{"data":[]}`, ""},
+	}
+	for _, tt := range tests {
+		if got := extractResponseText([]byte(tt.body)); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/endpoint"
+)
+
+// modelListPage 模型列表API的一页响应：Anthropic 与 OpenAI 为 data[].id（Anthropic 按 has_more / last_id 分页），
+// Gemini 为 models[].name（按 nextPageToken 分页）
+type modelListPage struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+	Models  []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// ListModels 调用端点的模型列表API，返回上游模型ID，实现 endpoint.ModelLister
+func (c *Checker) ListModels(ep *endpoint.Endpoint) ([]string, error) {
+	baseURL := ep.GetModelsURL()
+	seen := make(map[string]bool)
+	var models []string
+	query := url.Values{}
+
+	for page := 0; page < config.Default.ModelDiscovery.MaxPages; page++ {
+		pageURL := baseURL
+		if len(query) > 0 {
+			pageURL += "?" + query.Encode()
+		}
+		req, err := http.NewRequest("GET", pageURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create model list request: %v", err)
+		}
+		if ep.EndpointType == "anthropic" {
+			req.Header.Set("Anthropic-Version", config.Default.HealthCheck.Headers["Anthropic-Version"])
+		}
+
+		_, body, err := c.doProbe(ep, req)
+		if err != nil {
+			return nil, err
+		}
+		var result modelListPage
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse model list response: %v", err)
+		}
+
+		for _, model := range result.Data {
+			if model.ID != "" && !seen[model.ID] {
+				seen[model.ID] = true
+				models = append(models, model.ID)
+			}
+		}
+		for _, model := range result.Models {
+			id := strings.TrimPrefix(model.Name, "models/")
+			if id != "" && !seen[id] {
+				seen[id] = true
+				models = append(models, id)
+			}
+		}
+
+		switch {
+		case result.HasMore && result.LastID != "":
+			query.Set("after_id", result.LastID)
+		case result.NextPageToken != "":
+			query.Set("pageToken", result.NextPageToken)
+		default:
+			return models, nil
+		}
+	}
+	return models, nil
+}
@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"gorm.io/gorm"
@@ -31,6 +33,9 @@ func createOptimizedIndexes(db *gorm.DB) error {
 		
 		// 错误字段索引
 		"CREATE INDEX IF NOT EXISTS idx_request_logs_error_time ON request_logs(timestamp DESC) WHERE error != ''",
+		
+		// 用量统计覆盖索引（GetUsage 按时间范围聚合 token 和费用）
+		"CREATE INDEX IF NOT EXISTS idx_request_logs_usage_time ON request_logs(timestamp, endpoint, model, rewritten_model, input_tokens, output_tokens, cache_creation_input_tokens, cache_read_input_tokens, cost)",
 	}
 	
 	for _, sql := range indexes {
@@ -72,6 +77,11 @@ func validateTableCompatibility(db *gorm.DB) error {
 		"blacklist_causing_request_ids": "blacklist_causing_request_ids TEXT DEFAULT '[]'",
 		"endpoint_blacklisted_at": "endpoint_blacklisted_at DATETIME",
 		"endpoint_blacklist_reason": "endpoint_blacklist_reason TEXT DEFAULT ''",
+		"input_tokens": "input_tokens INTEGER DEFAULT 0",
+		"output_tokens": "output_tokens INTEGER DEFAULT 0",
+		"cache_creation_input_tokens": "cache_creation_input_tokens INTEGER DEFAULT 0",
+		"cache_read_input_tokens": "cache_read_input_tokens INTEGER DEFAULT 0",
+		"cost": "cost REAL DEFAULT 0",
 	}
 	
 	for column, definition := range optionalColumns {
@@ -86,4 +96,94 @@ func validateTableCompatibility(db *gorm.DB) error {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// fullTextBackfillBatch 为已有日志建立全文索引时每批处理的日志数，避免长时间占用写锁
+const fullTextBackfillBatch = 500
+
+// errFullTextIndexAborted 存储关闭时中止建立全文索引
+var errFullTextIndexAborted = errors.New("full-text index build aborted")
+
+// fullTextTriggers 保持全文索引与 request_logs 同步的触发器
+var fullTextTriggers = []string{
+	`CREATE TRIGGER IF NOT EXISTS request_logs_fts_insert AFTER INSERT ON request_logs BEGIN
+		INSERT INTO request_logs_fts(rowid, request_body, response_body) VALUES (new.id, new.request_body, new.response_body);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS request_logs_fts_delete AFTER DELETE ON request_logs BEGIN
+		INSERT INTO request_logs_fts(request_logs_fts, rowid, request_body, response_body) VALUES ('delete', old.id, old.request_body, old.response_body);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS request_logs_fts_update AFTER UPDATE OF request_body, response_body ON request_logs BEGIN
+		INSERT INTO request_logs_fts(request_logs_fts, rowid, request_body, response_body) VALUES ('delete', old.id, old.request_body, old.response_body);
+		INSERT INTO request_logs_fts(rowid, request_body, response_body) VALUES (new.id, new.request_body, new.response_body);
+	END`,
+}
+
+// hasFullTextIndex 检查全文索引是否已经建立完成（触发器在回填完成后才创建）
+func hasFullTextIndex(db *gorm.DB) (bool, error) {
+	var count int64
+	if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'request_logs_fts_insert'").Scan(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// dropFullTextIndex 删除全文索引及其触发器
+func dropFullTextIndex(db *gorm.DB) error {
+	statements := []string{
+		"DROP TRIGGER IF EXISTS request_logs_fts_insert",
+		"DROP TRIGGER IF EXISTS request_logs_fts_delete",
+		"DROP TRIGGER IF EXISTS request_logs_fts_update",
+		"DROP TABLE IF EXISTS request_logs_fts",
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createFullTextIndex 创建请求体/响应体的 FTS5 全文索引（trigram 分词，支持中文和任意子串）
+// 先分批为已有日志建立索引，再创建触发器与 request_logs 保持同步，并补上回填期间新增的日志；
+// 上次没有完成的索引从头重新建立。stop 关闭时中止
+func createFullTextIndex(db *gorm.DB, stop <-chan struct{}) error {
+	if err := dropFullTextIndex(db); err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE VIRTUAL TABLE request_logs_fts USING fts5(request_body, response_body, content='request_logs', content_rowid='id', tokenize='trigram')").Error; err != nil {
+		return err
+	}
+
+	var lastID int64
+	for {
+		select {
+		case <-stop:
+			return errFullTextIndexAborted
+		default:
+		}
+
+		var batchEnd sql.NullInt64
+		if err := db.Raw("SELECT MAX(id) FROM (SELECT id FROM request_logs WHERE id > ? ORDER BY id LIMIT ?)", lastID, fullTextBackfillBatch).Scan(&batchEnd).Error; err != nil {
+			return fmt.Errorf("failed to build full-text index: %v", err)
+		}
+		if !batchEnd.Valid {
+			break
+		}
+		if err := db.Exec("INSERT INTO request_logs_fts(rowid, request_body, response_body) SELECT id, request_body, response_body FROM request_logs WHERE id > ? AND id <= ?", lastID, batchEnd.Int64).Error; err != nil {
+			return fmt.Errorf("failed to build full-text index: %v", err)
+		}
+		lastID = batchEnd.Int64
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range fullTextTriggers {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("INSERT INTO request_logs_fts(rowid, request_body, response_body) SELECT id, request_body, response_body FROM request_logs WHERE id > ?", lastID).Error; err != nil {
+			return fmt.Errorf("failed to build full-text index: %v", err)
+		}
+		return nil
+	})
+}
@@ -64,6 +64,13 @@ type GormRequestLog struct {
 	EndpointBlacklistedAt      *time.Time `gorm:"column:endpoint_blacklisted_at"`
 	EndpointBlacklistReason    string     `gorm:"column:endpoint_blacklist_reason;type:text;default:''"`
 	
+	// token 用量与费用字段
+	InputTokens              int64   `gorm:"column:input_tokens;default:0"`
+	OutputTokens             int64   `gorm:"column:output_tokens;default:0"`
+	CacheCreationInputTokens int64   `gorm:"column:cache_creation_input_tokens;default:0"`
+	CacheReadInputTokens     int64   `gorm:"column:cache_read_input_tokens;default:0"`
+	Cost                     float64 `gorm:"column:cost;default:0"`
+	
 	// 创建时间（现有字段）
 	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
 }
@@ -107,6 +114,11 @@ func ConvertToGormRequestLog(log *RequestLog) *GormRequestLog {
 		BlacklistCausingRequestIDs: marshalTagsToJSON(log.BlacklistCausingRequestIDs),
 		EndpointBlacklistedAt:   log.EndpointBlacklistedAt,
 		EndpointBlacklistReason: log.EndpointBlacklistReason,
+		InputTokens:             log.InputTokens,
+		OutputTokens:            log.OutputTokens,
+		CacheCreationInputTokens: log.CacheCreationInputTokens,
+		CacheReadInputTokens:    log.CacheReadInputTokens,
+		Cost:                    log.Cost,
 	}
 	
 	// 转换JSON字段
@@ -155,6 +167,11 @@ func ConvertFromGormRequestLog(gormLog *GormRequestLog) *RequestLog {
 		BlacklistCausingRequestIDs: unmarshalTagsFromJSON(gormLog.BlacklistCausingRequestIDs),
 		EndpointBlacklistedAt:   gormLog.EndpointBlacklistedAt,
 		EndpointBlacklistReason: gormLog.EndpointBlacklistReason,
+		InputTokens:             gormLog.InputTokens,
+		OutputTokens:            gormLog.OutputTokens,
+		CacheCreationInputTokens: gormLog.CacheCreationInputTokens,
+		CacheReadInputTokens:    gormLog.CacheReadInputTokens,
+		Cost:                    gormLog.Cost,
 	}
 	
 	// 转换JSON字段
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"os"
 	"strings"
+	"sync/atomic"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -20,6 +21,9 @@ type GORMStorage struct {
 	config         *GORMConfig
 	cleanupTicker  *time.Ticker
 	stopCleanup    chan struct{}
+	fullTextSearch atomic.Bool   // FTS5 全文索引是否可用
+	stopFullText   chan struct{} // 关闭时中止后台建立全文索引
+	fullTextDone   chan struct{} // 后台建立全文索引结束
 }
 
 // NewGORMStorage 创建一个新的基于GORM的日志存储
@@ -77,7 +81,8 @@ func NewGORMStorage(logDir string) (*GORMStorage, error) {
 	storage := &GORMStorage{
 		db:          db,
 		config:      config,
-		stopCleanup: make(chan struct{}),
+		stopCleanup:  make(chan struct{}),
+		stopFullText: make(chan struct{}),
 	}
 	
 	// 验证表结构兼容性
@@ -99,6 +104,39 @@ func NewGORMStorage(logDir string) (*GORMStorage, error) {
 	return storage, nil
 }
 
+// setupFullTextSearch 按配置启用或删除请求体/响应体全文索引
+// 启用时已经建立完成的索引立即可用，否则在后台建立，完成前全文搜索不可用；关闭时删除已有索引，避免写日志时继续维护索引。
+// 返回的 channel 在索引可用或处理结束时关闭
+func (g *GORMStorage) setupFullTextSearch(enabled bool) <-chan struct{} {
+	done := make(chan struct{})
+	g.fullTextDone = done
+	if !enabled {
+		if err := dropFullTextIndex(g.db); err != nil {
+			fmt.Printf("Warning: Failed to drop full-text index: %v\n", err)
+		}
+		close(done)
+		return done
+	}
+
+	if ready, err := hasFullTextIndex(g.db); err == nil && ready {
+		g.fullTextSearch.Store(true)
+		close(done)
+		return done
+	}
+
+	go func() {
+		defer close(done)
+		if err := createFullTextIndex(g.db, g.stopFullText); err != nil {
+			if err != errFullTextIndexAborted {
+				fmt.Printf("Warning: Full-text search disabled: %v\n", err)
+			}
+			return
+		}
+		g.fullTextSearch.Store(true)
+	}()
+	return done
+}
+
 // SaveLog 保存日志条目到数据库
 // 保持与现有实现相同的错误处理策略：静默失败，不阻塞主流程
 func (g *GORMStorage) SaveLog(log *RequestLog) {
@@ -223,6 +261,16 @@ func (g *GORMStorage) Close() error {
 	default:
 	}
 	
+	// 等待后台建立全文索引的任务中止
+	select {
+	case <-g.stopFullText:
+	default:
+		close(g.stopFullText)
+	}
+	if g.fullTextDone != nil {
+		<-g.fullTextDone
+	}
+	
 	// 关闭数据库连接
 	sqlDB, err := g.db.DB()
 	if err != nil {
@@ -59,6 +59,13 @@ type RequestLog struct {
 	
 	// 新增：端点失效原因摘要
 	EndpointBlacklistReason string `json:"endpoint_blacklist_reason,omitempty"`
+
+	// 上游响应 usage 中的 token 用量（流式响应取最终累计值，OpenAI 格式已统一为 Anthropic 口径）
+	InputTokens              int64   `json:"input_tokens"`                // 不含缓存的输入token
+	OutputTokens             int64   `json:"output_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
+	Cost                     float64 `json:"cost"`                        // 按 pricing 价格表计算的费用（美元）
 }
 
 // StorageInterface defines the interface for log storage backends
@@ -67,6 +74,8 @@ type StorageInterface interface {
 	GetLogs(limit, offset int, failedOnly bool) ([]*RequestLog, int, error)
 	GetAllLogsByRequestID(requestID string) ([]*RequestLog, error)
 	CleanupLogsByDays(days int) (int64, error)
+	GetUsage(filter UsageFilter) (*UsageTotals, []*UsageTotals, error)
+	SearchLogs(filter LogSearchFilter) (*LogSearchResult, error)
 	Close() error
 }
 
@@ -82,6 +91,7 @@ type LogConfig struct {
 	LogRequestBody  string
 	LogResponseBody string
 	LogDirectory    string
+	FullTextSearch  bool // 为请求体/响应体建立全文索引
 }
 
 func NewLogger(config LogConfig) (*Logger, error) {
@@ -102,6 +112,7 @@ func NewLogger(config LogConfig) (*Logger, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize GORM log storage: %v", err)
 	}
+	storage.setupFullTextSearch(config.FullTextSearch)
 
 	return &Logger{
 		logger:  logger,
@@ -217,6 +228,20 @@ func (l *Logger) CleanupLogsByDays(days int) (int64, error) {
 	return l.storage.CleanupLogsByDays(days)
 }
 
+func (l *Logger) GetUsage(filter UsageFilter) (*UsageTotals, []*UsageTotals, error) {
+	if l.storage == nil {
+		return nil, nil, fmt.Errorf("storage not available")
+	}
+	return l.storage.GetUsage(filter)
+}
+
+func (l *Logger) SearchLogs(filter LogSearchFilter) (*LogSearchResult, error) {
+	if l.storage == nil {
+		return &LogSearchResult{Logs: []*RequestLog{}}, nil
+	}
+	return l.storage.SearchLogs(filter)
+}
+
 
 func (l *Logger) CreateRequestLog(requestID, endpoint, method, path string) *RequestLog {
 	return &RequestLog{
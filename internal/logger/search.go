@@ -0,0 +1,239 @@
+package logger
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// LogSortFields SearchLogs 支持的排序字段
+var LogSortFields = []string{"timestamp", "duration", "status_code", "cost", "tokens"}
+
+// logSortExpressions 排序字段对应的 SQL 表达式
+var logSortExpressions = map[string]string{
+	"timestamp":   "timestamp",
+	"duration":    "duration_ms",
+	"status_code": "status_code",
+	"cost":        "cost",
+	"tokens":      "(input_tokens + output_tokens + cache_creation_input_tokens + cache_read_input_tokens)",
+}
+
+// ErrInvalidCursor 游标格式错误、与排序方式不一致，或者游标指向的日志已被清理
+var ErrInvalidCursor = errors.New("invalid or expired cursor")
+
+// ErrFullTextSearchUnavailable 没有启用全文索引、索引尚未建立完成或数据库不支持 FTS5 时使用 Query 过滤返回的错误
+var ErrFullTextSearchUnavailable = errors.New("full-text search is not available")
+
+// LogSearchFilter 日志搜索条件，空字段表示不过滤，各条件之间是 AND 关系
+type LogSearchFilter struct {
+	Since         time.Time
+	Until         time.Time
+	Endpoint      string // 端点URL（与请求日志的 endpoint 字段一致）
+	Model         string // 匹配客户端请求的模型或重写后的模型
+	StatusCodes   []int  // 精确状态码，与 StatusClasses 之间是 OR 关系
+	StatusClasses []int  // 状态码类别，如 5 表示 5xx
+	Tag           string
+	SessionID     string
+	Streaming     *bool
+	Thinking      *bool
+	FailedOnly    bool   // 状态码 >= 400 或记录了错误
+	ErrorText     string // 错误信息包含的文本（不区分大小写）
+	Query         string // 请求体/响应体全文搜索，空格分隔的词都必须出现
+
+	SortBy    string // LogSortFields 之一，默认 timestamp
+	Ascending bool   // 默认降序
+	Limit     int
+	Offset    int    // 未使用游标时的偏移量，兼容旧的分页方式
+	Cursor    string // 上一页返回的 NextCursor
+}
+
+// LogSearchResult 一页搜索结果，NextCursor 为空表示没有更多结果
+type LogSearchResult struct {
+	Logs       []*RequestLog `json:"logs"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// SearchLogs 按条件搜索日志，按 SortBy 排序（相同值按 id 排序），支持游标分页
+func (g *GORMStorage) SearchLogs(filter LogSearchFilter) (*LogSearchResult, error) {
+	if filter.SortBy == "" {
+		filter.SortBy = "timestamp"
+	}
+	sortExpr, ok := logSortExpressions[filter.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field '%s'", filter.SortBy)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+
+	query, err := g.applyLogSearchFilter(g.db.Model(&GormRequestLog{}), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count logs: %v", err)
+	}
+
+	direction, comparison := "DESC", "<"
+	if filter.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	page := query.Session(&gorm.Session{})
+	if filter.Cursor != "" {
+		cursorID, err := decodeLogCursor(filter.Cursor, filter.SortBy, filter.Ascending)
+		if err != nil {
+			return nil, err
+		}
+		var exists int64
+		if err := g.db.Model(&GormRequestLog{}).Where("id = ?", cursorID).Count(&exists).Error; err != nil {
+			return nil, fmt.Errorf("failed to resolve cursor: %v", err)
+		}
+		if exists == 0 {
+			return nil, ErrInvalidCursor
+		}
+		// 与游标所在日志的排序值比较，避免时间等值在 Go 和 SQLite 之间转换带来的误差
+		cursorValue := fmt.Sprintf("(SELECT %s FROM request_logs WHERE id = ?)", sortExpr)
+		page = page.Where(fmt.Sprintf("(%s %s %s OR (%s = %s AND id %s ?))", sortExpr, comparison, cursorValue, sortExpr, cursorValue, comparison),
+			cursorID, cursorID, cursorID)
+	} else if filter.Offset > 0 {
+		page = page.Offset(filter.Offset)
+	}
+
+	// 多取一条用于判断是否还有下一页
+	var gormLogs []GormRequestLog
+	err = page.Order(fmt.Sprintf("%s %s, id %s", sortExpr, direction, direction)).
+		Limit(filter.Limit + 1).
+		Find(&gormLogs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to search logs: %v", err)
+	}
+
+	result := &LogSearchResult{Total: int(total)}
+	if len(gormLogs) > filter.Limit {
+		gormLogs = gormLogs[:filter.Limit]
+		result.NextCursor = encodeLogCursor(filter.SortBy, filter.Ascending, gormLogs[len(gormLogs)-1].ID)
+	}
+	result.Logs = make([]*RequestLog, len(gormLogs))
+	for i := range gormLogs {
+		result.Logs[i] = ConvertFromGormRequestLog(&gormLogs[i])
+	}
+	return result, nil
+}
+
+// applyLogSearchFilter 把搜索条件（不含分页）应用到查询
+func (g *GORMStorage) applyLogSearchFilter(query *gorm.DB, filter LogSearchFilter) (*gorm.DB, error) {
+	if !filter.Since.IsZero() {
+		query = query.Where("timestamp >= ?", storageTime(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("timestamp <= ?", storageTime(filter.Until))
+	}
+	if filter.Endpoint != "" {
+		query = query.Where("endpoint = ?", filter.Endpoint)
+	}
+	if filter.Model != "" {
+		query = query.Where("model = ? OR rewritten_model = ?", filter.Model, filter.Model)
+	}
+	if len(filter.StatusCodes) > 0 || len(filter.StatusClasses) > 0 {
+		var conditions []string
+		var args []interface{}
+		if len(filter.StatusCodes) > 0 {
+			conditions = append(conditions, "status_code IN ?")
+			args = append(args, filter.StatusCodes)
+		}
+		for _, class := range filter.StatusClasses {
+			conditions = append(conditions, "status_code BETWEEN ? AND ?")
+			args = append(args, class*100, class*100+99)
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+	if filter.Tag != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM json_each(request_logs.tags) WHERE json_each.value = ?)", filter.Tag)
+	}
+	if filter.SessionID != "" {
+		query = query.Where("session_id = ?", filter.SessionID)
+	}
+	if filter.Streaming != nil {
+		query = query.Where("is_streaming = ?", *filter.Streaming)
+	}
+	if filter.Thinking != nil {
+		query = query.Where("thinking_enabled = ?", *filter.Thinking)
+	}
+	if filter.FailedOnly {
+		query = query.Where("status_code >= ? OR error != ?", 400, "")
+	}
+	if filter.ErrorText != "" {
+		query = query.Where("error LIKE ? ESCAPE '\\'", "%"+escapeLike(filter.ErrorText)+"%")
+	}
+	if strings.TrimSpace(filter.Query) != "" {
+		if !g.fullTextSearch.Load() {
+			return nil, ErrFullTextSearchUnavailable
+		}
+		matchQuery, shortTerms := buildFullTextQuery(filter.Query)
+		if matchQuery != "" {
+			query = query.Where("id IN (SELECT rowid FROM request_logs_fts WHERE request_logs_fts MATCH ?)", matchQuery)
+		}
+		// trigram 索引无法匹配少于 3 个字符的词，改为直接扫描请求体和响应体
+		for _, term := range shortTerms {
+			pattern := "%" + escapeLike(term) + "%"
+			query = query.Where("(request_body LIKE ? ESCAPE '\\' OR response_body LIKE ? ESCAPE '\\')", pattern, pattern)
+		}
+	}
+	return query, nil
+}
+
+// buildFullTextQuery 把空格分隔的搜索词转换为 FTS5 查询：每个词作为短语（不解释 FTS5 语法），
+// 所有词都必须出现；少于 3 个字符的词单独返回
+func buildFullTextQuery(text string) (string, []string) {
+	var phrases, shortTerms []string
+	for _, term := range strings.Fields(text) {
+		if utf8.RuneCountInString(term) < 3 {
+			shortTerms = append(shortTerms, term)
+			continue
+		}
+		phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(phrases, " AND "), shortTerms
+}
+
+// escapeLike 转义 LIKE 模式中的通配符，配合 ESCAPE '\' 使用
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// encodeLogCursor 游标记录排序方式和上一页最后一条日志的 id
+func encodeLogCursor(sortBy string, ascending bool, id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s:%d", sortBy, sortOrderName(ascending), id)))
+}
+
+func decodeLogCursor(cursor, sortBy string, ascending bool) (uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	parts := strings.Split(string(data), ":")
+	if len(parts) != 3 || parts[0] != sortBy || parts[1] != sortOrderName(ascending) {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return uint(id), nil
+}
+
+func sortOrderName(ascending bool) string {
+	if ascending {
+		return "asc"
+	}
+	return "desc"
+}
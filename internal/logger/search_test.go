@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGORMStorageSearchLogs(t *testing.T) {
+	storage, cleanup := setupGORMStorage()
+	defer cleanup()
+
+	now := time.Now()
+	enabled := true
+	logs := []*RequestLog{
+		{Timestamp: now.Add(-50 * time.Minute), RequestID: "s1", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200, DurationMs: 300,
+			Model: "claude-sonnet", Tags: []string{"coding"}, SessionID: "sess-1", IsStreaming: true,
+			RequestBody: `{"messages":[{"role":"user","content":"请帮我重构数据库连接池"}]}`, ResponseBody: `{"content":[{"type":"tool_use"}]}`},
+		{Timestamp: now.Add(-40 * time.Minute), RequestID: "s2", Endpoint: "ep-b", Method: "POST", Path: "/v1/messages", StatusCode: 529, DurationMs: 100,
+			Model: "claude-sonnet", RewrittenModel: "gpt-4o", Error: "Upstream Overloaded", ResponseBody: `{"error":"overloaded"}`},
+		{Timestamp: now.Add(-30 * time.Minute), RequestID: "s3", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 429, DurationMs: 50,
+			Model: "claude-haiku", Tags: []string{"coding", "fast"}, ThinkingEnabled: true},
+		{Timestamp: now.Add(-20 * time.Minute), RequestID: "s4", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200, DurationMs: 900,
+			Model: "claude-sonnet", RequestBody: `{"messages":[{"role":"user","content":"hello 100% done"}]}`},
+		{Timestamp: now.Add(-48 * time.Hour), RequestID: "s5", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200,
+			Model: "claude-sonnet", RequestBody: "数据库"},
+	}
+	for _, log := range logs {
+		storage.SaveLog(log)
+	}
+
+	// 没有启用全文索引时不能按内容搜索
+	if _, err := storage.SearchLogs(LogSearchFilter{Query: "数据库"}); err != ErrFullTextSearchUnavailable {
+		t.Fatalf("expected ErrFullTextSearchUnavailable before the index is built, got %v", err)
+	}
+	// 启用后在后台为已有日志建立索引
+	<-storage.setupFullTextSearch(true)
+
+	requestIDs := func(filter LogSearchFilter) []string {
+		t.Helper()
+		result, err := storage.SearchLogs(filter)
+		if err != nil {
+			t.Fatalf("SearchLogs(%+v) failed: %v", filter, err)
+		}
+		ids := make([]string, len(result.Logs))
+		for i, log := range result.Logs {
+			ids[i] = log.RequestID
+		}
+		return ids
+	}
+	since := now.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		filter   LogSearchFilter
+		expected []string
+	}{
+		{"time range", LogSearchFilter{Since: since}, []string{"s4", "s3", "s2", "s1"}},
+		{"endpoint", LogSearchFilter{Since: since, Endpoint: "ep-b"}, []string{"s2"}},
+		{"rewritten model", LogSearchFilter{Model: "gpt-4o"}, []string{"s2"}},
+		{"status code and class", LogSearchFilter{StatusCodes: []int{429}, StatusClasses: []int{5}}, []string{"s3", "s2"}},
+		{"tag", LogSearchFilter{Tag: "coding"}, []string{"s3", "s1"}},
+		{"session", LogSearchFilter{SessionID: "sess-1"}, []string{"s1"}},
+		{"streaming", LogSearchFilter{Streaming: &enabled}, []string{"s1"}},
+		{"thinking", LogSearchFilter{Thinking: &enabled}, []string{"s3"}},
+		{"failed only", LogSearchFilter{Since: since, FailedOnly: true}, []string{"s3", "s2"}},
+		{"error text", LogSearchFilter{ErrorText: "overloaded"}, []string{"s2"}},
+		{"full text", LogSearchFilter{Query: "数据库连接"}, []string{"s1"}},
+		{"full text all terms", LogSearchFilter{Query: "tool_use 重构"}, []string{"s1"}},
+		{"full text short term", LogSearchFilter{Since: since, Query: "数据"}, []string{"s1"}},
+		{"like wildcard escaped", LogSearchFilter{Query: "0%"}, []string{"s4"}},
+		{"sort by duration", LogSearchFilter{Since: since, SortBy: "duration", Ascending: true}, []string{"s3", "s2", "s1", "s4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := requestIDs(tt.filter)
+			if len(ids) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, ids)
+				}
+			}
+		})
+	}
+
+	// 游标分页依次返回全部结果，不重复也不遗漏
+	filter := LogSearchFilter{SortBy: "duration", Limit: 2}
+	var pages [][]string
+	for {
+		result, err := storage.SearchLogs(filter)
+		if err != nil {
+			t.Fatalf("SearchLogs page failed: %v", err)
+		}
+		if result.Total != 5 {
+			t.Errorf("expected total 5, got %d", result.Total)
+		}
+		var ids []string
+		for _, log := range result.Logs {
+			ids = append(ids, log.RequestID)
+		}
+		pages = append(pages, ids)
+		if result.NextCursor == "" {
+			break
+		}
+		filter.Cursor = result.NextCursor
+	}
+	if len(pages) != 3 || pages[0][0] != "s4" || pages[0][1] != "s1" || pages[1][0] != "s2" || pages[1][1] != "s3" || len(pages[2]) != 1 || pages[2][0] != "s5" {
+		t.Errorf("unexpected pages: %v", pages)
+	}
+
+	if _, err := storage.SearchLogs(LogSearchFilter{SortBy: "timestamp", Cursor: filter.Cursor}); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor for cursor of another sort, got %v", err)
+	}
+	if _, err := storage.SearchLogs(LogSearchFilter{SortBy: "model"}); err == nil {
+		t.Errorf("expected error for unsupported sort field")
+	}
+
+	// 索引建立完成后新写入的日志通过触发器同步
+	storage.SaveLog(&RequestLog{Timestamp: now, RequestID: "s6", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200, RequestBody: "数据库迁移"})
+	if ids := requestIDs(LogSearchFilter{Query: "数据库迁移"}); len(ids) != 1 || ids[0] != "s6" {
+		t.Errorf("expected newly saved log to be searchable, got %v", ids)
+	}
+
+	// 清理日志时全文索引同步删除
+	if _, err := storage.CleanupLogsByDays(1); err != nil {
+		t.Fatalf("CleanupLogsByDays failed: %v", err)
+	}
+	if ids := requestIDs(LogSearchFilter{Query: "数据库"}); len(ids) != 2 || ids[0] != "s6" || ids[1] != "s1" {
+		t.Errorf("expected s6 and s1 after cleanup, got %v", ids)
+	}
+
+	// 关闭全文搜索时删除索引
+	<-storage.setupFullTextSearch(false)
+	if ready, err := hasFullTextIndex(storage.db); err != nil || ready {
+		t.Errorf("expected full-text index to be dropped, got ready=%v err=%v", ready, err)
+	}
+}
+
+func TestGORMStorageSearchLogsTimeZone(t *testing.T) {
+	// 日志以本地时区保存，查询边界使用 UTC 时仍按实际时刻比较
+	original := time.Local
+	time.Local = time.FixedZone("CST", 8*60*60)
+	defer func() { time.Local = original }()
+
+	storage, cleanup := setupGORMStorage()
+	defer cleanup()
+
+	now := time.Now()
+	storage.SaveLog(&RequestLog{Timestamp: now.Add(-2 * time.Hour), RequestID: "old", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200})
+	storage.SaveLog(&RequestLog{Timestamp: now.Add(-10 * time.Minute), RequestID: "recent", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200})
+
+	boundary := now.Add(-time.Hour).UTC()
+	tests := []struct {
+		name     string
+		filter   LogSearchFilter
+		expected string
+	}{
+		{"since", LogSearchFilter{Since: boundary}, "recent"},
+		{"until", LogSearchFilter{Until: boundary}, "old"},
+	}
+	for _, tt := range tests {
+		result, err := storage.SearchLogs(tt.filter)
+		if err != nil {
+			t.Fatalf("SearchLogs failed: %v", err)
+		}
+		if len(result.Logs) != 1 || result.Logs[0].RequestID != tt.expected {
+			ids := make([]string, len(result.Logs))
+			for i, log := range result.Logs {
+				ids[i] = log.RequestID
+			}
+			t.Errorf("%s: expected [%s], got %v", tt.name, tt.expected, ids)
+		}
+	}
+}
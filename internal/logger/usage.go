@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UsageGroupings GetUsage 支持的分组方式
+var UsageGroupings = []string{"model", "endpoint", "day", "hour"}
+
+// usageGroupExpressions 分组方式对应的 SQL 表达式：model 按上游实际使用的模型（重写后的模型）分组，
+// day / hour 取 timestamp 文本的日期和小时部分（记录时的本地时间）
+var usageGroupExpressions = map[string]string{
+	"model":    "CASE WHEN rewritten_model != '' THEN rewritten_model ELSE model END",
+	"endpoint": "endpoint",
+	"day":      "substr(timestamp, 1, 10)",
+	"hour":     "substr(timestamp, 1, 13) || ':00'",
+}
+
+// UsageFilter token 用量查询条件，空字段表示不过滤
+type UsageFilter struct {
+	Since     time.Time
+	Until     time.Time
+	Endpoint  string // 端点URL（与请求日志的 endpoint 字段一致）
+	Model     string // 匹配客户端请求的模型或重写后的模型
+	SessionID string
+	GroupBy   string // UsageGroupings 之一，为空时只返回合计
+}
+
+// UsageTotals 一组请求的 token 用量与费用合计
+type UsageTotals struct {
+	Key                      string  `json:"key,omitempty"` // 分组值
+	Requests                 int64   `json:"requests"`
+	InputTokens              int64   `json:"input_tokens"`
+	OutputTokens             int64   `json:"output_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
+	Cost                     float64 `json:"cost"`
+}
+
+const usageSelectColumns = "COUNT(*) AS requests, " +
+	"COALESCE(SUM(input_tokens), 0) AS input_tokens, " +
+	"COALESCE(SUM(output_tokens), 0) AS output_tokens, " +
+	"COALESCE(SUM(cache_creation_input_tokens), 0) AS cache_creation_input_tokens, " +
+	"COALESCE(SUM(cache_read_input_tokens), 0) AS cache_read_input_tokens, " +
+	"COALESCE(SUM(cost), 0) AS cost"
+
+// GetUsage 汇总时间范围内记录了 usage 的请求，返回合计和按 GroupBy 分组的结果（按费用降序，时间分组按时间升序）
+func (g *GORMStorage) GetUsage(filter UsageFilter) (*UsageTotals, []*UsageTotals, error) {
+	groupExpr := ""
+	if filter.GroupBy != "" {
+		var ok bool
+		if groupExpr, ok = usageGroupExpressions[filter.GroupBy]; !ok {
+			return nil, nil, fmt.Errorf("unsupported group_by '%s'", filter.GroupBy)
+		}
+	}
+
+	query := g.db.Model(&GormRequestLog{}).
+		Where("input_tokens > 0 OR output_tokens > 0 OR cache_creation_input_tokens > 0 OR cache_read_input_tokens > 0")
+	if !filter.Since.IsZero() {
+		query = query.Where("timestamp >= ?", storageTime(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("timestamp <= ?", storageTime(filter.Until))
+	}
+	if filter.Endpoint != "" {
+		query = query.Where("endpoint = ?", filter.Endpoint)
+	}
+	if filter.Model != "" {
+		query = query.Where("model = ? OR rewritten_model = ?", filter.Model, filter.Model)
+	}
+	if filter.SessionID != "" {
+		query = query.Where("session_id = ?", filter.SessionID)
+	}
+
+	var totals UsageTotals
+	if err := query.Session(&gorm.Session{}).Select(usageSelectColumns).Scan(&totals).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to query usage totals: %v", err)
+	}
+
+	groups := []*UsageTotals{}
+	if groupExpr != "" {
+		order := "cost DESC, requests DESC"
+		if filter.GroupBy == "day" || filter.GroupBy == "hour" {
+			order = "key ASC"
+		}
+		err := query.Select(groupExpr + " AS key, " + usageSelectColumns).
+			Group(groupExpr).
+			Order(order).
+			Scan(&groups).Error
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to query grouped usage: %v", err)
+		}
+	}
+	return &totals, groups, nil
+}
+
+// storageTime 把时间转换为日志存储使用的时区
+// timestamp 以带本地时区偏移的文本保存，按文本比较，查询边界必须使用同一时区
+func storageTime(t time.Time) time.Time {
+	return t.Local()
+}
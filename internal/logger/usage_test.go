@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGORMStorageGetUsage(t *testing.T) {
+	storage, cleanup := setupGORMStorage()
+	defer cleanup()
+
+	now := time.Now()
+	logs := []*RequestLog{
+		{Timestamp: now.Add(-time.Hour), RequestID: "u1", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200,
+			Model: "claude-sonnet", InputTokens: 100, OutputTokens: 50, CacheReadInputTokens: 1000, Cost: 0.5},
+		{Timestamp: now.Add(-30 * time.Minute), RequestID: "u2", Endpoint: "ep-b", Method: "POST", Path: "/v1/messages", StatusCode: 200,
+			Model: "claude-sonnet", RewrittenModel: "gpt-4o", InputTokens: 200, OutputTokens: 20, Cost: 0.25},
+		{Timestamp: now.Add(-10 * time.Minute), RequestID: "u3", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200,
+			Model: "claude-sonnet", InputTokens: 10, OutputTokens: 5, CacheCreationInputTokens: 300, Cost: 1},
+		// 没有 usage 的失败尝试不计入
+		{Timestamp: now, RequestID: "u4", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 500, Model: "claude-sonnet"},
+		// 时间范围之外
+		{Timestamp: now.Add(-48 * time.Hour), RequestID: "u5", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200,
+			Model: "claude-sonnet", InputTokens: 999, OutputTokens: 999, Cost: 9},
+	}
+	for _, log := range logs {
+		storage.SaveLog(log)
+	}
+
+	totals, groups, err := storage.GetUsage(UsageFilter{Since: now.Add(-24 * time.Hour), GroupBy: "model"})
+	if err != nil {
+		t.Fatalf("GetUsage failed: %v", err)
+	}
+	if totals.Requests != 3 || totals.InputTokens != 310 || totals.OutputTokens != 75 ||
+		totals.CacheReadInputTokens != 1000 || totals.CacheCreationInputTokens != 300 {
+		t.Errorf("unexpected totals: %+v", totals)
+	}
+	if math.Abs(totals.Cost-1.75) > 1e-9 {
+		t.Errorf("expected cost 1.75, got %v", totals.Cost)
+	}
+	if len(groups) != 2 || groups[0].Key != "claude-sonnet" || groups[0].Requests != 2 || groups[1].Key != "gpt-4o" {
+		t.Errorf("unexpected model groups: %+v %+v", groups[0], groups[len(groups)-1])
+	}
+
+	totals, groups, err = storage.GetUsage(UsageFilter{Since: now.Add(-24 * time.Hour), Endpoint: "ep-a", GroupBy: "day"})
+	if err != nil {
+		t.Fatalf("GetUsage by day failed: %v", err)
+	}
+	if totals.Requests != 2 || len(groups) == 0 || len(groups[0].Key) != len("2006-01-02") {
+		t.Errorf("unexpected day grouping: %+v %+v", totals, groups)
+	}
+
+	if _, _, err := storage.GetUsage(UsageFilter{GroupBy: "tag"}); err == nil {
+		t.Errorf("expected error for unsupported group_by")
+	}
+}
+
+func TestGORMStorageGetUsageTimeZone(t *testing.T) {
+	// 用量以本地时区保存，查询边界使用 UTC 时仍按实际时刻比较
+	original := time.Local
+	time.Local = time.FixedZone("CST", 8*60*60)
+	defer func() { time.Local = original }()
+
+	storage, cleanup := setupGORMStorage()
+	defer cleanup()
+
+	now := time.Now()
+	storage.SaveLog(&RequestLog{Timestamp: now.Add(-2 * time.Hour), RequestID: "old", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200,
+		Model: "claude-sonnet", InputTokens: 100, OutputTokens: 10})
+	storage.SaveLog(&RequestLog{Timestamp: now.Add(-10 * time.Minute), RequestID: "recent", Endpoint: "ep-a", Method: "POST", Path: "/v1/messages", StatusCode: 200,
+		Model: "claude-sonnet", InputTokens: 1, OutputTokens: 1})
+
+	boundary := now.Add(-time.Hour).UTC()
+	tests := []struct {
+		name     string
+		filter   UsageFilter
+		expected int64
+	}{
+		{"since", UsageFilter{Since: boundary}, 1},
+		{"until", UsageFilter{Until: boundary}, 100},
+	}
+	for _, tt := range tests {
+		totals, _, err := storage.GetUsage(tt.filter)
+		if err != nil {
+			t.Fatalf("GetUsage failed: %v", err)
+		}
+		if totals.Requests != 1 || totals.InputTokens != tt.expected {
+			t.Errorf("%s: expected 1 request with %d input tokens, got %+v", tt.name, tt.expected, totals)
+		}
+	}
+}
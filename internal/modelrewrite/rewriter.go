@@ -63,28 +63,16 @@ func (r *Rewriter) RewriteRequestWithTags(req *http.Request, modelRewriteConfig
 	}
 
 	// 确定重写规则
-	var rules []config.ModelRewriteRule
-	isGenericEndpoint := len(endpointTags) == 0
-	hasExplicitRules := modelRewriteConfig != nil && modelRewriteConfig.Enabled && len(modelRewriteConfig.Rules) > 0
-
-	if hasExplicitRules {
-		// 使用显式配置的规则
-		rules = modelRewriteConfig.Rules
-	} else if isGenericEndpoint && !strings.HasPrefix(originalModel, "claude") {
-		// 通用端点的隐式规则：非claude模型重写为claude-sonnet-4-20250514
-		rules = []config.ModelRewriteRule{
-			{
-				SourcePattern: "*",
-				TargetModel:   "claude-sonnet-4-20250514",
-			},
-		}
+	rules := rewriteRulesFor(originalModel, modelRewriteConfig, endpointTags)
+	if rules == nil {
+		// 没有规则应用
+		return "", "", nil
+	}
+	if modelRewriteConfig == nil || !modelRewriteConfig.Enabled || len(modelRewriteConfig.Rules) == 0 {
 		r.logger.Debug("Applying implicit model rewrite rule for generic endpoint", map[string]interface{}{
 			"original_model": originalModel,
-			"target_model":   "claude-sonnet-4-20250514",
+			"target_model":   implicitRewriteTarget,
 		})
-	} else {
-		// 没有规则应用
-		return "", "", nil
 	}
 
 	// 应用重写规则
@@ -112,6 +100,36 @@ func (r *Rewriter) RewriteRequestWithTags(req *http.Request, modelRewriteConfig
 	return originalModel, newModel, nil
 }
 
+// implicitRewriteTarget 通用端点隐式重写规则的目标模型
+const implicitRewriteTarget = "claude-sonnet-4-20250514"
+
+// rewriteRulesFor 确定模型在端点上适用的重写规则：显式配置的规则优先；
+// 没有显式规则的通用端点（无标签）把非claude模型重写为 implicitRewriteTarget；没有规则应用时返回nil
+func rewriteRulesFor(originalModel string, modelRewriteConfig *config.ModelRewriteConfig, endpointTags []string) []config.ModelRewriteRule {
+	if modelRewriteConfig != nil && modelRewriteConfig.Enabled && len(modelRewriteConfig.Rules) > 0 {
+		return modelRewriteConfig.Rules
+	}
+	if len(endpointTags) == 0 && !strings.HasPrefix(originalModel, "claude") {
+		return []config.ModelRewriteRule{
+			{
+				SourcePattern: "*",
+				TargetModel:   implicitRewriteTarget,
+			},
+		}
+	}
+	return nil
+}
+
+// RewriteModel 返回模型名在端点上重写后的结果，规则与 RewriteRequestWithTags 相同；不需要重写时原样返回
+func (r *Rewriter) RewriteModel(originalModel string, modelRewriteConfig *config.ModelRewriteConfig, endpointTags []string) string {
+	rules := rewriteRulesFor(originalModel, modelRewriteConfig, endpointTags)
+	if rules == nil {
+		return originalModel
+	}
+	target, _, _ := r.TestRewriteRule(originalModel, rules)
+	return target
+}
+
 // RewriteResponse 重写响应中的模型名称（将重写后的模型名改回原始模型名）
 func (r *Rewriter) RewriteResponse(responseBody []byte, originalModel, rewrittenModel string) ([]byte, error) {
 	if originalModel == "" || rewrittenModel == "" {
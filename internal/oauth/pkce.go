@@ -0,0 +1,170 @@
+package oauth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/config"
+)
+
+// PKCE 授权码流程的 code_verifier 与 S256 code_challenge（RFC 7636）
+type PKCE struct {
+	Verifier  string
+	Challenge string
+}
+
+// NewPKCE 生成随机的 code_verifier 及其 code_challenge
+func NewPKCE() (*PKCE, error) {
+	verifier, err := randomURLSafeString(32)
+	if err != nil {
+		return nil, err
+	}
+	return &PKCE{
+		Verifier:  verifier,
+		Challenge: codeChallengeS256(verifier),
+	}, nil
+}
+
+// codeChallengeS256 计算 code_verifier 的 S256 code_challenge：BASE64URL(SHA256(verifier))，不带填充
+func codeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// NewState 生成授权请求的 state 参数，用于关联回调并防止 CSRF
+func NewState() (string, error) {
+	return randomURLSafeString(24)
+}
+
+func randomURLSafeString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random string: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// BuildAuthorizationURL 构造授权页面URL，保留 authorize_url 中已有的查询参数
+func BuildAuthorizationURL(oauthConfig *config.OAuthConfig, redirectURI, state string, pkce *PKCE) (string, error) {
+	if oauthConfig == nil || oauthConfig.AuthorizeURL == "" {
+		return "", fmt.Errorf("oauth authorize_url is not configured")
+	}
+	u, err := url.Parse(oauthConfig.AuthorizeURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid oauth authorize_url: %v", err)
+	}
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", oauthConfig.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	query.Set("code_challenge", pkce.Challenge)
+	query.Set("code_challenge_method", "S256")
+	if len(oauthConfig.Scopes) > 0 {
+		query.Set("scope", strings.Join(oauthConfig.Scopes, " "))
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// ExchangeCode 用授权码和 code_verifier 换取 token，返回包含新token的配置副本。
+// 与 RefreshToken 相同，先尝试 JSON 格式，失败后使用 form 格式
+func ExchangeCode(oauthConfig *config.OAuthConfig, code, state, verifier, redirectURI string, httpClient *http.Client) (*config.OAuthConfig, error) {
+	if oauthConfig == nil {
+		return nil, fmt.Errorf("oauth config is nil")
+	}
+	if oauthConfig.TokenURL == "" {
+		return nil, fmt.Errorf("oauth token_url is not configured")
+	}
+
+	params := map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          code,
+		"redirect_uri":  redirectURI,
+		"client_id":     oauthConfig.ClientID,
+		"code_verifier": verifier,
+		"state":         state,
+	}
+
+	log.Printf("[OAuth] Exchanging authorization code at token_url: %s", oauthConfig.TokenURL)
+	respBody, err := postTokenRequest(oauthConfig.TokenURL, params, true, httpClient)
+	if err != nil {
+		log.Printf("[OAuth] JSON format code exchange failed: %v, trying form format", err)
+		if respBody, err = postTokenRequest(oauthConfig.TokenURL, params, false, httpClient); err != nil {
+			return nil, err
+		}
+	}
+
+	newConfig, err := parseTokenResponse(respBody, oauthConfig)
+	if err != nil {
+		return nil, err
+	}
+	if newConfig.RefreshToken == oauthConfig.RefreshToken {
+		log.Printf("[OAuth] Code exchange response did not include a refresh_token, background refresh will not be possible")
+	}
+	return newConfig, nil
+}
+
+// postTokenRequest 向 token 端点发送请求，返回 200 响应的响应体
+func postTokenRequest(tokenURL string, params map[string]string, asJSON bool, httpClient *http.Client) ([]byte, error) {
+	var body io.Reader
+	contentType := "application/x-www-form-urlencoded"
+	if asJSON {
+		reqBody, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal token request: %v", err)
+		}
+		body = bytes.NewReader(reqBody)
+		contentType = "application/json"
+	} else {
+		form := url.Values{}
+		for key, value := range params {
+			if value != "" {
+				form.Set(key, value)
+			}
+		}
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequest("POST", tokenURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send token request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// ShouldRefreshTokenWithin 检查 token 是否将在 ahead 时间内过期，用于后台主动刷新；
+// 只处理启用了 auto_refresh、有 refresh_token 且过期时间已知的配置
+func ShouldRefreshTokenWithin(oauthConfig *config.OAuthConfig, ahead time.Duration) bool {
+	if oauthConfig == nil || !oauthConfig.AutoRefresh || oauthConfig.RefreshToken == "" || oauthConfig.ExpiresAt <= 0 {
+		return false
+	}
+	return time.Now().Add(ahead).After(time.UnixMilli(oauthConfig.ExpiresAt))
+}
@@ -0,0 +1,163 @@
+package oauth
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"claude-code-companion/internal/config"
+)
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 Appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	expected := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	if got := codeChallengeS256(verifier); got != expected {
+		t.Errorf("expected challenge %s, got %s", expected, got)
+	}
+}
+
+func TestNewPKCE(t *testing.T) {
+	first, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE failed: %v", err)
+	}
+	second, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE failed: %v", err)
+	}
+
+	// RFC 7636 要求 verifier 为 43-128 个非保留字符
+	if len(first.Verifier) < 43 || len(first.Verifier) > 128 || strings.ContainsAny(first.Verifier, "+/=") {
+		t.Errorf("invalid code_verifier %q", first.Verifier)
+	}
+	if first.Challenge != codeChallengeS256(first.Verifier) {
+		t.Errorf("expected S256 challenge of the verifier, got %s", first.Challenge)
+	}
+	if first.Verifier == second.Verifier {
+		t.Errorf("expected a random verifier per flow")
+	}
+}
+
+func TestBuildAuthorizationURL(t *testing.T) {
+	pkce := &PKCE{Verifier: "verifier", Challenge: "challenge"}
+	oauthConfig := &config.OAuthConfig{
+		AuthorizeURL: "https://auth.example.com/oauth/authorize?code=true&client_id=stale",
+		ClientID:     "client-1",
+		Scopes:       []string{"user:profile", "user:inference"},
+	}
+
+	authorizationURL, err := BuildAuthorizationURL(oauthConfig, "http://localhost:8080/admin/oauth/callback", "state-1", pkce)
+	if err != nil {
+		t.Fatalf("BuildAuthorizationURL failed: %v", err)
+	}
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("invalid authorization URL %q: %v", authorizationURL, err)
+	}
+	if u.Host != "auth.example.com" || u.Path != "/oauth/authorize" {
+		t.Errorf("unexpected authorization endpoint %s", authorizationURL)
+	}
+
+	expected := map[string]string{
+		"code":                  "true", // authorize_url 中已有的参数被保留
+		"response_type":         "code",
+		"client_id":             "client-1",
+		"redirect_uri":          "http://localhost:8080/admin/oauth/callback",
+		"state":                 "state-1",
+		"code_challenge":        "challenge",
+		"code_challenge_method": "S256",
+		"scope":                 "user:profile user:inference",
+	}
+	query := u.Query()
+	for key, value := range expected {
+		if got := query[key]; len(got) != 1 || got[0] != value {
+			t.Errorf("query %s: expected [%s], got %v", key, value, got)
+		}
+	}
+	if query.Get("code_verifier") != "" {
+		t.Errorf("code_verifier must not be sent to the authorization endpoint")
+	}
+
+	if _, err := BuildAuthorizationURL(&config.OAuthConfig{}, "http://localhost", "state", pkce); err == nil {
+		t.Errorf("expected error without authorize_url")
+	}
+}
+
+func TestExchangeCode(t *testing.T) {
+	var contentTypes []string
+	var formParams url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+		// 只接受 form 格式，JSON 请求失败后应回退
+		if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			w.Write([]byte(`{"error":"unsupported_content_type"}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		formParams, _ = url.ParseQuery(string(body))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "new-access",
+			"refresh_token": "new-refresh",
+			"expires_in":    3600,
+		})
+	}))
+	defer server.Close()
+
+	oauthConfig := &config.OAuthConfig{TokenURL: server.URL, ClientID: "client-1", RefreshToken: "old-refresh"}
+	newConfig, err := ExchangeCode(oauthConfig, "code-1", "state-1", "verifier-1", "http://localhost/callback", server.Client())
+	if err != nil {
+		t.Fatalf("ExchangeCode failed: %v", err)
+	}
+
+	if len(contentTypes) != 2 || contentTypes[0] != "application/json" || contentTypes[1] != "application/x-www-form-urlencoded" {
+		t.Errorf("expected JSON attempt followed by form fallback, got %v", contentTypes)
+	}
+	expected := map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          "code-1",
+		"state":         "state-1",
+		"code_verifier": "verifier-1",
+		"redirect_uri":  "http://localhost/callback",
+		"client_id":     "client-1",
+	}
+	for key, value := range expected {
+		if got := formParams.Get(key); got != value {
+			t.Errorf("form %s: expected %q, got %q", key, value, got)
+		}
+	}
+	if newConfig.AccessToken != "new-access" || newConfig.RefreshToken != "new-refresh" || newConfig.ExpiresAt == 0 {
+		t.Errorf("unexpected exchanged config: %+v", newConfig)
+	}
+	if oauthConfig.AccessToken != "" {
+		t.Errorf("expected original config to be left unchanged")
+	}
+}
+
+func TestExchangeCodeFailure(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer server.Close()
+
+	_, err := ExchangeCode(&config.OAuthConfig{TokenURL: server.URL}, "code", "state", "verifier", "http://localhost/callback", server.Client())
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("expected invalid_grant error, got %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected one JSON and one form attempt, got %d", attempts)
+	}
+
+	if _, err := ExchangeCode(&config.OAuthConfig{}, "code", "state", "verifier", "", server.Client()); err == nil {
+		t.Errorf("expected error without token_url")
+	}
+}
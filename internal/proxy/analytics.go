@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/endpoint"
+
+	"github.com/gin-gonic/gin"
+)
+
+// analyticsMiddleware 在代理请求结束后把请求结果、延迟、用量和费用记录到用量汇总
+func (s *Server) analyticsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if !s.endpointManager.IsAnalyticsEnabled() {
+			return
+		}
+		// 本地应答的模型列表不计入用量
+		if _, exists := c.Get("original_model"); !exists {
+			return
+		}
+
+		sample := endpoint.AnalyticsSample{
+			Time:        time.Now(),
+			Endpoint:    c.GetString("served_endpoint"),
+			Model:       c.GetString("original_model"),
+			Tags:        c.GetStringSlice("request_tags"),
+			SessionID:   c.GetString("session_id"),
+			ClientToken: clientCredential(c),
+			Success:     c.Writer.Status() < 400,
+		}
+		if start, ok := c.Get("start_time"); ok {
+			sample.Latency = time.Since(start.(time.Time))
+		}
+		if value, exists := c.Get("token_usage"); exists {
+			if usage, ok := value.(endpoint.TokenUsage); ok {
+				sample.Usage = usage
+			}
+		}
+		if cost, exists := c.Get("request_cost"); exists {
+			sample.Cost, _ = cost.(float64)
+		}
+		s.endpointManager.RecordAnalytics(sample)
+	}
+}
+
+// clientCredential 返回客户端请求使用的凭据（x-api-key 或 Bearer 令牌）
+func clientCredential(c *gin.Context) string {
+	if apiKey := c.GetHeader("x-api-key"); apiKey != "" {
+		return apiKey
+	}
+	return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
@@ -0,0 +1,26 @@
+package proxy
+
+import (
+	"claude-code-companion/internal/endpoint"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAPIKeyResult 把本次尝试的结果记录到所用的凭据，返回是否应换凭据重试同一端点；单凭据端点直接返回 false
+func recordAPIKeyResult(c *gin.Context, ep *endpoint.Endpoint, success bool, requestID string) bool {
+	keyIndex := c.GetInt("api_key_index")
+	if keyIndex < 0 {
+		return false
+	}
+
+	statusCode := c.GetInt("last_status_code")
+	var errMsg string
+	if !success {
+		if err, ok := c.Value("last_error").(error); ok && err != nil {
+			errMsg = err.Error()
+		}
+	}
+	retryAfter, _ := parseRetryAfter(c.GetString("last_retry_after"))
+
+	return ep.RecordAPIKeyResult(keyIndex, success, statusCode, retryAfter, requestID, errMsg)
+}
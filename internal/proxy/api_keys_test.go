@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// keyRecordingUpstream 记录每个凭据收到的请求数，validKey 之外的凭据返回 401
+func keyRecordingUpstream(validKey string) (*httptest.Server, func() map[string]int) {
+	var mutex sync.Mutex
+	hits := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("x-api-key")
+		mutex.Lock()
+		hits[key]++
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if key != validKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+			return
+		}
+		w.Write([]byte(testAnthropicResponse))
+	}))
+	return server, func() map[string]int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		snapshot := make(map[string]int, len(hits))
+		for key, count := range hits {
+			snapshot[key] = count
+		}
+		return snapshot
+	}
+}
+
+// serveWithTimeout 发送请求，超时视为轮换循环没有结束
+func serveWithTimeout(t *testing.T, server *Server) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		server.GetRouter().ServeHTTP(recorder, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("request did not finish, key rotation loop did not terminate")
+	}
+	return recorder
+}
+
+func keyRotationTestConfig(url string) string {
+	return fmt.Sprintf(`endpoints:
+    - name: keys
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-key-0
+      auth_values: [sk-key-1, sk-key-2]
+      key_rotation:
+          strategy: failover
+      enabled: true
+      priority: 1
+`, url)
+}
+
+func TestKeyRotationRetriesWithNextKey(t *testing.T) {
+	upstream, hits := keyRecordingUpstream("sk-key-1")
+	defer upstream.Close()
+	server := newTestServer(t, keyRotationTestConfig(upstream.URL))
+
+	recorder := serveWithTimeout(t, server)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected success with the second key, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if got := hits(); got["sk-key-0"] != 1 || got["sk-key-1"] != 1 || got["sk-key-2"] != 0 {
+		t.Errorf("expected one request with each of the first two keys, got %v", got)
+	}
+
+	ep := server.endpointManager.GetAllEndpoints()[0]
+	statuses := ep.GetAPIKeyStatus()
+	if statuses[0].Available || !statuses[1].Available || statuses[1].SuccessRequests != 1 {
+		t.Errorf("expected first key blacklisted and second key credited, got %+v", statuses)
+	}
+	// 换凭据重试不计入端点健康统计
+	if ep.TotalRequests != 1 || ep.SuccessRequests != 1 {
+		t.Errorf("expected endpoint to record one successful request, got %d/%d", ep.SuccessRequests, ep.TotalRequests)
+	}
+}
+
+func TestKeyRotationStopsWhenEveryKeyIsBlacklisted(t *testing.T) {
+	upstream, hits := keyRecordingUpstream("")
+	defer upstream.Close()
+	server := newTestServer(t, keyRotationTestConfig(upstream.URL))
+
+	// 每个凭据各尝试一次，最后一个凭据被拉黑后不再换凭据
+	recorder := serveWithTimeout(t, server)
+	if recorder.Code == http.StatusOK {
+		t.Fatalf("expected failure when every key is rejected")
+	}
+	if got := hits(); got["sk-key-0"] != 1 || got["sk-key-1"] != 1 || got["sk-key-2"] != 1 {
+		t.Errorf("expected one request with each key, got %v", got)
+	}
+	ep := server.endpointManager.GetAllEndpoints()[0]
+	for _, status := range ep.GetAPIKeyStatus() {
+		if status.Available {
+			t.Errorf("expected key %d to be blacklisted", status.Index)
+		}
+	}
+}
+
+func TestKeyRotationWithEveryKeyAlreadyBlacklisted(t *testing.T) {
+	upstream, hits := keyRecordingUpstream("")
+	defer upstream.Close()
+	server := newTestServer(t, keyRotationTestConfig(upstream.URL))
+
+	ep := server.endpointManager.GetAllEndpoints()[0]
+	for i := 0; i < 3; i++ {
+		ep.RecordAPIKeyResult(i, false, http.StatusUnauthorized, 0, "earlier", "")
+	}
+
+	// 使用最早解除拉黑的凭据尝试一次，失败后不再换凭据
+	serveWithTimeout(t, server)
+	if got := hits(); got["sk-key-0"] != 1 || len(got) != 1 {
+		t.Errorf("expected a single attempt with the earliest unblocked key, got %v", got)
+	}
+}
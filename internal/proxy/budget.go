@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+
+	"claude-code-companion/internal/endpoint"
+	"claude-code-companion/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordEndpointUsage 从上游响应中提取 usage，保存到 context 供请求日志记录，并累计到端点预算和 tpm 限制
+func (s *Server) recordEndpointUsage(c *gin.Context, ep *endpoint.Endpoint, requestID string, upstreamBody []byte) {
+	usage := extractTokenUsage(upstreamBody)
+	c.Set("token_usage", usage)
+	if usage.IsZero() || (!ep.HasBudget() && !ep.GetLimiter().TracksTokens()) {
+		return
+	}
+	s.endpointManager.RecordUsage(ep, usage, requestID)
+}
+
+// setRequestLogUsage 把用量写入请求日志，并按上游实际使用的模型计算费用：
+// 优先使用 pricing 价格表，没有匹配的模型时使用端点预算中的价格
+func (s *Server) setRequestLogUsage(requestLog *logger.RequestLog, ep *endpoint.Endpoint, usage endpoint.TokenUsage) {
+	requestLog.InputTokens = usage.InputTokens
+	requestLog.OutputTokens = usage.OutputTokens
+	requestLog.CacheCreationInputTokens = usage.CacheWriteTokens
+	requestLog.CacheReadInputTokens = usage.CacheReadTokens
+	if usage.IsZero() {
+		return
+	}
+
+	model := requestLog.Model
+	if requestLog.RewrittenModel != "" {
+		model = requestLog.RewrittenModel
+	}
+	if price := s.config.Pricing.FindModelPrice(model); price != nil {
+		requestLog.Cost = usage.Cost(price.InputPrice, price.OutputPrice, price.CacheReadPrice, price.CacheWritePrice)
+	} else if cost, ok := ep.BudgetCost(usage); ok {
+		requestLog.Cost = cost
+	}
+}
+
+// extractTokenUsage 从上游响应体中提取 token 用量，支持 JSON 响应和 SSE 流；
+// 流式 usage 是累计值，各字段取所有事件中的最大值
+func extractTokenUsage(body []byte) endpoint.TokenUsage {
+	var usage endpoint.TokenUsage
+	if len(body) == 0 {
+		return usage
+	}
+
+	if json.Valid(body) {
+		mergeUsageFromJSON(&usage, body)
+		return usage
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		mergeUsageFromJSON(&usage, bytes.TrimSpace(line[len("data:"):]))
+	}
+	return usage
+}
+
+func mergeUsageFromJSON(usage *endpoint.TokenUsage, data []byte) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return
+	}
+	mergeUsage(usage, value)
+}
+
+// mergeUsage 递归查找 usage / usageMetadata 对象（Anthropic message_start 的 usage 位于 message 内）
+func mergeUsage(usage *endpoint.TokenUsage, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if object, ok := child.(map[string]interface{}); ok && (key == "usage" || key == "usageMetadata") {
+				maxUsage(usage, parseUsageObject(object))
+				continue
+			}
+			mergeUsage(usage, child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			mergeUsage(usage, child)
+		}
+	}
+}
+
+// parseUsageObject 把各上游格式的 usage 统一为不含缓存的输入、缓存读、缓存写和输出 token：
+// Anthropic input_tokens 不含缓存；OpenAI prompt_tokens / Responses input_tokens 和 Gemini promptTokenCount 含缓存读取
+func parseUsageObject(object map[string]interface{}) endpoint.TokenUsage {
+	number := func(m map[string]interface{}, key string) int64 {
+		if n, ok := m[key].(float64); ok {
+			return int64(n)
+		}
+		return 0
+	}
+	nested := func(key string) map[string]interface{} {
+		if m, ok := object[key].(map[string]interface{}); ok {
+			return m
+		}
+		return map[string]interface{}{}
+	}
+
+	var usage endpoint.TokenUsage
+	switch {
+	case object["prompt_tokens"] != nil || object["completion_tokens"] != nil:
+		// OpenAI Chat Completions
+		usage.CacheReadTokens = number(nested("prompt_tokens_details"), "cached_tokens")
+		usage.InputTokens = number(object, "prompt_tokens") - usage.CacheReadTokens
+		usage.OutputTokens = number(object, "completion_tokens")
+	case object["input_tokens_details"] != nil || object["output_tokens_details"] != nil:
+		// OpenAI Responses
+		usage.CacheReadTokens = number(nested("input_tokens_details"), "cached_tokens")
+		usage.InputTokens = number(object, "input_tokens") - usage.CacheReadTokens
+		usage.OutputTokens = number(object, "output_tokens")
+	case object["promptTokenCount"] != nil || object["candidatesTokenCount"] != nil:
+		// Gemini：思考 token 按输出计费
+		usage.CacheReadTokens = number(object, "cachedContentTokenCount")
+		usage.InputTokens = number(object, "promptTokenCount") - usage.CacheReadTokens
+		usage.OutputTokens = number(object, "candidatesTokenCount") + number(object, "thoughtsTokenCount")
+	default:
+		// Anthropic
+		usage.InputTokens = number(object, "input_tokens")
+		usage.OutputTokens = number(object, "output_tokens")
+		usage.CacheReadTokens = number(object, "cache_read_input_tokens")
+		usage.CacheWriteTokens = number(object, "cache_creation_input_tokens")
+	}
+	if usage.InputTokens < 0 {
+		usage.InputTokens = 0
+	}
+	return usage
+}
+
+func maxUsage(usage *endpoint.TokenUsage, other endpoint.TokenUsage) {
+	if other.InputTokens > usage.InputTokens {
+		usage.InputTokens = other.InputTokens
+	}
+	if other.OutputTokens > usage.OutputTokens {
+		usage.OutputTokens = other.OutputTokens
+	}
+	if other.CacheReadTokens > usage.CacheReadTokens {
+		usage.CacheReadTokens = other.CacheReadTokens
+	}
+	if other.CacheWriteTokens > usage.CacheWriteTokens {
+		usage.CacheWriteTokens = other.CacheWriteTokens
+	}
+}
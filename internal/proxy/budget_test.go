@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"testing"
+
+	"claude-code-companion/internal/endpoint"
+)
+
+func TestExtractTokenUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected endpoint.TokenUsage
+	}{
+		{
+			name:     "empty body",
+			body:     "",
+			expected: endpoint.TokenUsage{},
+		},
+		{
+			name:     "anthropic message",
+			body:     `{"type":"message","usage":{"input_tokens":12,"output_tokens":34,"cache_read_input_tokens":100,"cache_creation_input_tokens":50}}`,
+			expected: endpoint.TokenUsage{InputTokens: 12, OutputTokens: 34, CacheReadTokens: 100, CacheWriteTokens: 50},
+		},
+		{
+			name: "anthropic stream takes maximum of cumulative usage",
+			body: "event: message_start\n" +
+				`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":12,"output_tokens":1,"cache_read_input_tokens":100}}}` + "\n\n" +
+				"event: content_block_delta\n" +
+				`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}` + "\n\n" +
+				"event: message_delta\n" +
+				`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":34}}` + "\n\n",
+			expected: endpoint.TokenUsage{InputTokens: 12, OutputTokens: 34, CacheReadTokens: 100},
+		},
+		{
+			name:     "openai chat completions subtracts cached prompt tokens",
+			body:     `{"object":"chat.completion","usage":{"prompt_tokens":120,"completion_tokens":30,"total_tokens":150,"prompt_tokens_details":{"cached_tokens":100}}}`,
+			expected: endpoint.TokenUsage{InputTokens: 20, OutputTokens: 30, CacheReadTokens: 100},
+		},
+		{
+			name: "openai stream with usage in final chunk",
+			body: `data: {"choices":[{"index":0,"delta":{"content":"hi"}}]}` + "\n\n" +
+				`data: {"choices":[],"usage":{"prompt_tokens":15,"completion_tokens":5}}` + "\n\n" +
+				"data: [DONE]\n\n",
+			expected: endpoint.TokenUsage{InputTokens: 15, OutputTokens: 5},
+		},
+		{
+			name:     "openai responses subtracts cached input tokens",
+			body:     `{"object":"response","usage":{"input_tokens":80,"output_tokens":20,"input_tokens_details":{"cached_tokens":30},"output_tokens_details":{"reasoning_tokens":5}}}`,
+			expected: endpoint.TokenUsage{InputTokens: 50, OutputTokens: 20, CacheReadTokens: 30},
+		},
+		{
+			name:     "openai responses stream completed event",
+			body:     "event: response.completed\n" + `data: {"type":"response.completed","response":{"usage":{"input_tokens":80,"output_tokens":20,"input_tokens_details":{"cached_tokens":0}}}}` + "\n\n",
+			expected: endpoint.TokenUsage{InputTokens: 80, OutputTokens: 20},
+		},
+		{
+			name:     "gemini counts thoughts as output",
+			body:     `{"candidates":[],"usageMetadata":{"promptTokenCount":200,"candidatesTokenCount":40,"thoughtsTokenCount":10,"cachedContentTokenCount":150}}`,
+			expected: endpoint.TokenUsage{InputTokens: 50, OutputTokens: 50, CacheReadTokens: 150},
+		},
+		{
+			name:     "cached tokens above prompt tokens clamp input to zero",
+			body:     `{"usage":{"prompt_tokens":10,"completion_tokens":1,"prompt_tokens_details":{"cached_tokens":20}}}`,
+			expected: endpoint.TokenUsage{InputTokens: 0, OutputTokens: 1, CacheReadTokens: 20},
+		},
+		{
+			name:     "invalid body",
+			body:     "upstream error",
+			expected: endpoint.TokenUsage{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractTokenUsage([]byte(tt.body)); got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondCountTokensLocally 没有端点能处理 count_tokens 时，使用本地估算器直接应答
+// 返回 false 表示不是 count_tokens 请求、估算器被禁用或估算失败，调用方应继续原有的错误处理
+func (s *Server) respondCountTokensLocally(c *gin.Context, path string, requestBody []byte, requestID string) bool {
+	if s.tokenCounter == nil || !strings.Contains(path, "/count_tokens") {
+		return false
+	}
+
+	inputTokens, err := s.tokenCounter.CountRequest(requestBody)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Request %s: local count_tokens estimation failed", requestID), err)
+		return false
+	}
+
+	s.logger.Info(fmt.Sprintf("Request %s: count_tokens answered locally by %s estimator: %d tokens", requestID, s.tokenCounter.Name(), inputTokens))
+	c.JSON(http.StatusOK, gin.H{"input_tokens": inputTokens})
+	return true
+}
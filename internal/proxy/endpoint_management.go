@@ -24,13 +24,10 @@ const (
 
 // EndpointFilterResult 和相关类型定义不再需要，因为现在直接在尝试时处理被拉黑端点
 
-// MaxEndpointRetries 单个端点最大重试次数
-const MaxEndpointRetries = 2
-
 // tryProxyRequestWithRetry 尝试向端点发送请求，支持单端点重试
 func (s *Server) tryProxyRequestWithRetry(c *gin.Context, ep *endpoint.Endpoint, requestBody []byte, requestID string, startTime time.Time, path string, taggedRequest *tagging.TaggedRequest, globalAttemptNumber int) (success bool, shouldTryNextEndpoint bool) {
-	// 检查端点是否被拉黑，如果是则记录虚拟日志并跳过
-	if !ep.IsAvailable() {
+	// 检查端点是否被拉黑，如果是则记录虚拟日志并跳过（熔断器半开试探除外）
+	if !ep.IsAvailable() && !isCircuitProbe(c, ep) {
 		duration := time.Since(startTime)
 		blacklistReason := ep.GetBlacklistReason()
 		var errorMsg string
@@ -52,15 +49,47 @@ func (s *Server) tryProxyRequestWithRetry(c *gin.Context, ep *endpoint.Endpoint,
 		return false, true
 	}
 
-	for endpointAttempt := 1; endpointAttempt <= MaxEndpointRetries; endpointAttempt++ {
+	policy := s.retryPolicyFor(ep)
+	for endpointAttempt := 1; endpointAttempt <= policy.maxAttempts; endpointAttempt++ {
 		currentGlobalAttempt := globalAttemptNumber + endpointAttempt - 1
-		s.logger.Debug(fmt.Sprintf("Trying endpoint %s (endpoint attempt %d/%d, global attempt %d)", ep.Name, endpointAttempt, MaxEndpointRetries, currentGlobalAttempt))
+		s.logger.Debug(fmt.Sprintf("Trying endpoint %s (endpoint attempt %d/%d, global attempt %d)", ep.Name, endpointAttempt, policy.maxAttempts, currentGlobalAttempt))
+		
+		// 清除上一次尝试留下的响应信息，避免影响本次的重试判断
+		c.Set("last_response_body", nil)
+		c.Set("last_retry_after", "")
+		c.Set("api_key_index", -1)
+		
+		// 上游速率限制头部显示额度接近耗尽时直接溢出到下一个端点，同样不计入端点健康统计
+		if err := s.rateLimitExhaustionError(ep); err != nil {
+			s.logger.Info(fmt.Sprintf("Skipping endpoint %s: %v", ep.Name, err))
+			c.Set("saturated_endpoint", ep.ID)
+			c.Set("last_error", err)
+			c.Set("last_status_code", http.StatusTooManyRequests)
+			return false, true
+		}
+		
+		// 端点达到并发或速率限制时短暂排队，超时后溢出到下一个端点，不计入端点健康统计
+		release, err := ep.GetLimiter().Acquire(c.Request.Context())
+		if err != nil {
+			if c.Request.Context().Err() != nil {
+				s.logger.Debug(fmt.Sprintf("Request context cancelled while queued for endpoint %s", ep.Name))
+				return false, false
+			}
+			s.logger.Info(fmt.Sprintf("Endpoint %s is saturated, overflowing to next endpoint: %v", ep.Name, err))
+			c.Set("saturated_endpoint", ep.ID)
+			c.Set("last_error", err)
+			c.Set("last_status_code", http.StatusTooManyRequests)
+			return false, true
+		}
 		
 		success, shouldRetryAnywhere := s.proxyToEndpoint(c, ep, path, requestBody, requestID, startTime, taggedRequest, currentGlobalAttempt)
+		release()
+		rotateKey := recordAPIKeyResult(c, ep, success, requestID)
 		if success {
-			// 检查是否应该跳过健康统计记录
+			// 检查是否应该跳过健康统计记录；断流续传成功时成功属于续写的端点，已在续写请求中记录
 			skipHealthRecord, _ := c.Get("skip_health_record")
-			if skipHealthRecord != true {
+			_, resumed := c.Get("resumed_endpoint")
+			if skipHealthRecord != true && !resumed {
 				s.endpointManager.RecordRequest(ep.ID, true, requestID)
 			}
 			
@@ -72,10 +101,22 @@ func (s *Server) tryProxyRequestWithRetry(c *gin.Context, ep *endpoint.Endpoint,
 				}
 			}
 			
-			s.logger.Debug(fmt.Sprintf("Request succeeded on endpoint %s (endpoint attempt %d/%d)", ep.Name, endpointAttempt, MaxEndpointRetries))
+			// 会话粘性路由：会话固定到成功处理它的端点
+			s.bindSession(c, ep, path)
+			
+			s.logger.Debug(fmt.Sprintf("Request succeeded on endpoint %s (endpoint attempt %d/%d)", ep.Name, endpointAttempt, policy.maxAttempts))
 			return true, false
 		}
 		
+		// 凭据无效或超出配额时换下一个凭据重试同一端点，不占用重试次数，也不计入端点健康统计
+		if rotateKey && shouldRetryAnywhere && c.Request.Context().Err() == nil && !isCircuitProbe(c, ep) {
+			s.logger.Info(fmt.Sprintf("Rotating API key for endpoint %s after HTTP %d", ep.Name, c.GetInt("last_status_code")))
+			s.metrics.retries.WithLabelValues(ep.Name, "key_rotation").Inc()
+			s.rebuildRequestBody(c, requestBody)
+			endpointAttempt--
+			continue
+		}
+		
 		// 记录失败，但检查是否为 count_tokens 请求，如果是则不计入健康统计
 		skipHealthRecord, _ := c.Get("skip_health_record")
 		isCountTokensRequest := strings.Contains(path, "/count_tokens")
@@ -84,12 +125,24 @@ func (s *Server) tryProxyRequestWithRetry(c *gin.Context, ep *endpoint.Endpoint,
 			s.endpointManager.RecordRequest(ep.ID, false, requestID)
 		}
 		
+		// 对冲请求落败被取消，不再重试
+		if _, hedged := hedgeAttemptFromContext(c); hedged && c.Request.Context().Err() != nil {
+			s.logger.Debug(fmt.Sprintf("Hedged attempt on endpoint %s was cancelled", ep.Name))
+			return false, false
+		}
+		
 		// 如果明确指示不应重试任何地方，直接返回
 		if !shouldRetryAnywhere {
 			s.logger.Debug(fmt.Sprintf("Endpoint %s indicated no retry should be attempted", ep.Name))
 			return false, false
 		}
 		
+		// 半开试探只尝试一次，失败后熔断器重新打开，直接切换到其他端点
+		if isCircuitProbe(c, ep) {
+			s.logger.Debug(fmt.Sprintf("Half-open circuit probe on endpoint %s failed, switching to next endpoint", ep.Name))
+			return false, true
+		}
+		
 		// 从context中获取最后一次的错误信息和状态码（如果有的话）
 		var lastError error
 		var lastStatusCode int
@@ -104,18 +157,30 @@ func (s *Server) tryProxyRequestWithRetry(c *gin.Context, ep *endpoint.Endpoint,
 			}
 		}
 		
-		// 根据错误类型确定重试行为
-		retryBehavior := s.determineRetryBehaviorFromError(lastError, lastStatusCode, endpointAttempt)
+		// 根据错误类型和重试策略确定重试行为
+		retryBehavior := s.determineRetryBehaviorFromError(lastError, lastStatusCode, lastFailureBody(c, lastError), endpointAttempt, policy)
 		
 		switch retryBehavior {
 		case RetryBehaviorReturnError:
 			s.logger.Debug(fmt.Sprintf("Endpoint %s: RetryBehaviorReturnError - stopping all retries", ep.Name))
+			s.sendUpstreamError(c, requestID)
 			return false, false
 			
 		case RetryBehaviorRetryEndpoint:
-			if endpointAttempt < MaxEndpointRetries {
-				s.logger.Debug(fmt.Sprintf("Endpoint %s: RetryBehaviorRetryEndpoint - retrying same endpoint (attempt %d/%d)", ep.Name, endpointAttempt+1, MaxEndpointRetries))
+			if endpointAttempt < policy.maxAttempts {
+				// 指数退避，上游返回 Retry-After 时至少等待该时间
+				delay, ok := s.retryDelay(c, policy, endpointAttempt)
+				if !ok {
+					s.logger.Debug(fmt.Sprintf("Endpoint %s: Retry-After exceeds max_retry_after, switching to next endpoint", ep.Name))
+					return false, true
+				}
+				s.logger.Debug(fmt.Sprintf("Endpoint %s: RetryBehaviorRetryEndpoint - retrying same endpoint in %v (attempt %d/%d)", ep.Name, delay, endpointAttempt+1, policy.maxAttempts))
+				if !sleepWithContext(c.Request.Context(), delay) {
+					s.logger.Debug(fmt.Sprintf("Request context cancelled while waiting to retry endpoint %s", ep.Name))
+					return false, false
+				}
 				// 重新构建请求体，继续循环
+				s.metrics.retries.WithLabelValues(ep.Name, "error").Inc()
 				s.rebuildRequestBody(c, requestBody)
 				continue
 			} else {
@@ -130,7 +195,7 @@ func (s *Server) tryProxyRequestWithRetry(c *gin.Context, ep *endpoint.Endpoint,
 	}
 	
 	// 如果所有重试都失败了，切换到下一个端点
-	s.logger.Debug(fmt.Sprintf("All %d attempts failed on endpoint %s, switching to next endpoint", MaxEndpointRetries, ep.Name))
+	s.logger.Debug(fmt.Sprintf("All %d attempts failed on endpoint %s, switching to next endpoint", policy.maxAttempts, ep.Name))
 	return false, true
 }
 
@@ -148,7 +213,8 @@ const (
 )
 
 // determineRetryBehaviorFromError 根据错误信息确定重试行为
-func (s *Server) determineRetryBehaviorFromError(err error, statusCode int, currentAttempt int) RetryBehavior {
+// 先匹配重试策略中的规则（端点级优先于全局），未命中时使用内置的错误分类
+func (s *Server) determineRetryBehaviorFromError(err error, statusCode int, body string, currentAttempt int, policy *retryPolicy) RetryBehavior {
 	if err == nil && statusCode >= 200 && statusCode < 300 {
 		// 成功情况，不需要重试
 		return RetryBehaviorReturnError
@@ -156,6 +222,21 @@ func (s *Server) determineRetryBehaviorFromError(err error, statusCode int, curr
 
 	errorCategory := s.categorizeError(err, statusCode)
 	
+	if action, matched := policy.matchRule(errorCategory, statusCode, body); matched {
+		s.logger.Debug(fmt.Sprintf("Retry policy rule matched (status %d, category %s): %s", statusCode, errorCategory, action))
+		switch action {
+		case "return":
+			return RetryBehaviorReturnError
+		case "retry":
+			if currentAttempt < policy.maxAttempts {
+				return RetryBehaviorRetryEndpoint
+			}
+			return RetryBehaviorSwitchEndpoint
+		default:
+			return RetryBehaviorSwitchEndpoint
+		}
+	}
+	
 	switch errorCategory {
 	case ErrorCategoryClientError:
 		// 客户端错误（4xx状态码），直接尝试下一个端点
@@ -164,28 +245,28 @@ func (s *Server) determineRetryBehaviorFromError(err error, statusCode int, curr
 		
 	case ErrorCategoryNetworkError:
 		// 网络错误（连接失败、超时等），在同一端点重试
-		if currentAttempt < MaxEndpointRetries {
+		if currentAttempt < policy.maxAttempts {
 			return RetryBehaviorRetryEndpoint
 		}
 		return RetryBehaviorSwitchEndpoint
 		
 	case ErrorCategoryServerError:
 		// 服务器错误（5xx状态码），在同一端点重试
-		if currentAttempt < MaxEndpointRetries {
+		if currentAttempt < policy.maxAttempts {
 			return RetryBehaviorRetryEndpoint
 		}
 		return RetryBehaviorSwitchEndpoint
 		
 	case ErrorCategoryUsageValidationError:
 		// Usage验证失败，原地重试
-		if currentAttempt < MaxEndpointRetries {
+		if currentAttempt < policy.maxAttempts {
 			return RetryBehaviorRetryEndpoint
 		}
 		return RetryBehaviorSwitchEndpoint
 		
 	case ErrorCategorySSEValidationError:
 		// SSE流不完整验证失败，原地重试
-		if currentAttempt < MaxEndpointRetries {
+		if currentAttempt < policy.maxAttempts {
 			return RetryBehaviorRetryEndpoint
 		}
 		return RetryBehaviorSwitchEndpoint
@@ -200,7 +281,7 @@ func (s *Server) determineRetryBehaviorFromError(err error, statusCode int, curr
 		
 	default:
 		// 未知错误，在同一端点重试
-		if currentAttempt < MaxEndpointRetries {
+		if currentAttempt < policy.maxAttempts {
 			return RetryBehaviorRetryEndpoint
 		}
 		return RetryBehaviorSwitchEndpoint
@@ -274,7 +355,7 @@ func (s *Server) categorizeError(err error, statusCode int) ErrorCategory {
 // determineRetryBehavior 根据当前情况确定重试行为（保持向后兼容）
 func (s *Server) determineRetryBehavior(c *gin.Context, ep *endpoint.Endpoint, currentAttempt int) RetryBehavior {
 	// 临时实现：默认在同一端点重试，最后一次尝试时切换端点
-	if currentAttempt < MaxEndpointRetries {
+	if currentAttempt < s.retryPolicyFor(ep).maxAttempts {
 		return RetryBehaviorRetryEndpoint
 	}
 	return RetryBehaviorSwitchEndpoint
@@ -285,28 +366,50 @@ func (s *Server) tryProxyRequest(c *gin.Context, ep *endpoint.Endpoint, requestB
 	return s.tryProxyRequestWithRetry(c, ep, requestBody, requestID, startTime, path, taggedRequest, attemptNumber)
 }
 
-// tryEndpointList 尝试端点列表，返回(成功, 尝试次数)
-func (s *Server) tryEndpointList(c *gin.Context, endpoints []utils.EndpointSorter, path string, requestBody []byte, requestID string, startTime time.Time, taggedRequest *tagging.TaggedRequest, phase string, startingAttemptNumber int) (bool, int) {
+// tryEndpointList 尝试端点列表，返回(成功, 是否停止回退, 尝试次数)
+// 端点指示不应再重试（如重试规则要求直接返回错误、客户端已断开）时停止回退，调用方不应再尝试其他端点
+func (s *Server) tryEndpointList(c *gin.Context, endpoints []utils.EndpointSorter, path string, requestBody []byte, requestID string, startTime time.Time, taggedRequest *tagging.TaggedRequest, phase string, startingAttemptNumber int) (success bool, stop bool, attempts int) {
 	totalAttempts := 0
+	hedgingDelay := s.hedgingDelay(path)
 	
-	for _, epInterface := range endpoints {
+	for i, epInterface := range endpoints {
 		ep := epInterface.(*endpoint.Endpoint)
+		// 已经作为对冲目标尝试过的端点不再重复尝试
+		if s.isHedgedEndpoint(c, ep) {
+			continue
+		}
 		currentGlobalAttempt := startingAttemptNumber + totalAttempts
 		s.logger.Debug(fmt.Sprintf("%s: Attempting endpoint %s (starting from global attempt #%d)", phase, ep.Name, currentGlobalAttempt))
+		// tryEndpointList 只在首选端点失败后调用，实际尝试的每个端点都是一次故障转移
+		if ep.IsAvailable() || isCircuitProbe(c, ep) {
+			s.metrics.failovers.WithLabelValues(ep.Name).Inc()
+		}
 		
-		success, shouldTryNextEndpoint := s.tryProxyRequestWithRetry(c, ep, requestBody, requestID, startTime, path, taggedRequest, currentGlobalAttempt)
+		var success, shouldTryNextEndpoint bool
+		if hedgingDelay > 0 && ep.IsAvailable() {
+			// 启用对冲时，当前端点响应过慢会同时尝试列表中的下一个可用端点
+			hedgeEndpoint := s.nextUnhedgedEndpoint(c, endpoints, i+1)
+			var hedged bool
+			success, shouldTryNextEndpoint, hedged = s.tryHedgedEndpoints(c, ep, hedgeEndpoint, requestBody, requestID, startTime, path, taggedRequest, currentGlobalAttempt, currentGlobalAttempt+s.retryPolicyFor(ep).maxAttempts, hedgingDelay)
+			if hedged {
+				s.markHedgedEndpoint(c, hedgeEndpoint)
+				totalAttempts += s.retryPolicyFor(hedgeEndpoint).maxAttempts
+			}
+		} else {
+			success, shouldTryNextEndpoint = s.tryProxyRequestWithRetry(c, ep, requestBody, requestID, startTime, path, taggedRequest, currentGlobalAttempt)
+		}
 		
 		// 更新总尝试次数（包括该端点的所有重试）
-		totalAttempts += MaxEndpointRetries
+		totalAttempts += s.retryPolicyFor(ep).maxAttempts
 		
 		if success {
 			s.logger.Debug(fmt.Sprintf("%s: Request succeeded on endpoint %s", phase, ep.Name))
-			return true, totalAttempts
+			return true, false, totalAttempts
 		}
 		
 		if !shouldTryNextEndpoint {
 			s.logger.Debug("Endpoint indicated no retry should be attempted, stopping fallback")
-			break
+			return false, true, totalAttempts
 		}
 		
 		s.logger.Debug(fmt.Sprintf("%s: All attempts failed on endpoint %s, trying next endpoint", phase, ep.Name))
@@ -315,7 +418,7 @@ func (s *Server) tryEndpointList(c *gin.Context, endpoints []utils.EndpointSorte
 		s.rebuildRequestBody(c, requestBody)
 	}
 	
-	return false, totalAttempts
+	return false, false, totalAttempts
 }
 
 // filterAndSortEndpoints 过滤并排序端点（包括被拉黑端点，用于在实际轮到时记录虚拟日志）
@@ -347,6 +450,30 @@ func (s *Server) filterAndSortEndpoints(allEndpoints []*endpoint.Endpoint, faile
 	return sorter
 }
 
+// fallbackCandidates 按回退顺序返回除 exclude 外当前可用的端点：
+// 有标签请求先是标签匹配的端点，再是万用端点；无标签请求只有万用端点
+func (s *Server) fallbackCandidates(exclude *endpoint.Endpoint, requestTags []string) []*endpoint.Endpoint {
+	allEndpoints := s.endpointManager.GetAllEndpoints()
+
+	var sorted []utils.EndpointSorter
+	if len(requestTags) > 0 {
+		sorted = s.filterAndSortEndpoints(allEndpoints, exclude, func(ep *endpoint.Endpoint) bool {
+			return len(ep.Tags) > 0 && s.endpointContainsAllTags(ep.Tags, requestTags)
+		})
+	}
+	sorted = append(sorted, s.filterAndSortEndpoints(allEndpoints, exclude, func(ep *endpoint.Endpoint) bool {
+		return len(ep.Tags) == 0
+	})...)
+
+	var candidates []*endpoint.Endpoint
+	for _, item := range sorted {
+		if ep := item.(*endpoint.Endpoint); ep.IsAvailable() {
+			candidates = append(candidates, ep)
+		}
+	}
+	return candidates
+}
+
 // endpointContainsAllTags 检查endpoint的标签是否包含请求的所有标签
 func (s *Server) endpointContainsAllTags(endpointTags, requestTags []string) bool {
 	if len(requestTags) == 0 {
@@ -372,8 +499,9 @@ func (s *Server) endpointContainsAllTags(endpointTags, requestTags []string) boo
 func (s *Server) fallbackToOtherEndpoints(c *gin.Context, path string, requestBody []byte, requestID string, startTime time.Time, failedEndpoint *endpoint.Endpoint, taggedRequest *tagging.TaggedRequest) {
 	// 记录失败的endpoint，但检查是否为 count_tokens 请求，如果是则不计入健康统计
 	skipHealthRecord, _ := c.Get("skip_health_record")
+	saturatedEndpoint, _ := c.Get("saturated_endpoint") // 因饱和溢出的端点并没有失败
 	isCountTokensRequest := strings.Contains(path, "/count_tokens")
-	shouldSkip := (skipHealthRecord == true) || isCountTokensRequest
+	shouldSkip := (skipHealthRecord == true) || isCountTokensRequest || saturatedEndpoint == failedEndpoint.ID
 	if !shouldSkip {
 		s.endpointManager.RecordRequest(failedEndpoint.ID, false, requestID)
 	}
@@ -384,7 +512,7 @@ func (s *Server) fallbackToOtherEndpoints(c *gin.Context, path string, requestBo
 		requestTags = taggedRequest.Tags
 	}
 	
-	totalAttempted := MaxEndpointRetries // 包括最初失败的endpoint的所有重试
+	totalAttempted := s.retryPolicyFor(failedEndpoint).maxAttempts + s.hedgedAttemptCount(c) // 包括最初失败的endpoint（以及对冲端点）的所有重试
 	
 	if len(requestTags) > 0 {
 		// 有标签请求：分两阶段尝试
@@ -397,8 +525,8 @@ func (s *Server) fallbackToOtherEndpoints(c *gin.Context, path string, requestBo
 		
 		if len(taggedEndpoints) > 0 {
 			s.logger.Debug(fmt.Sprintf("Phase 1: Trying %d tagged endpoints", len(taggedEndpoints)))
-			success, attemptedCount := s.tryEndpointList(c, taggedEndpoints, path, requestBody, requestID, startTime, taggedRequest, "Phase 1", totalAttempted+1)
-			if success {
+			success, stop, attemptedCount := s.tryEndpointList(c, taggedEndpoints, path, requestBody, requestID, startTime, taggedRequest, "Phase 1", totalAttempted+1)
+			if success || stop {
 				return
 			}
 			totalAttempted += attemptedCount
@@ -411,8 +539,8 @@ func (s *Server) fallbackToOtherEndpoints(c *gin.Context, path string, requestBo
 		
 		if len(universalEndpoints) > 0 {
 			s.logger.Debug(fmt.Sprintf("Phase 2: Trying %d universal endpoints", len(universalEndpoints)))
-			success, attemptedCount := s.tryEndpointList(c, universalEndpoints, path, requestBody, requestID, startTime, taggedRequest, "Phase 2", totalAttempted+1)
-			if success {
+			success, stop, attemptedCount := s.tryEndpointList(c, universalEndpoints, path, requestBody, requestID, startTime, taggedRequest, "Phase 2", totalAttempted+1)
+			if success || stop {
 				return
 			}
 			totalAttempted += attemptedCount
@@ -423,7 +551,11 @@ func (s *Server) fallbackToOtherEndpoints(c *gin.Context, path string, requestBo
 		countTokensOpenAISkip, _ := c.Get("count_tokens_openai_skip")
 		
 		if isCountTokensRequest && countTokensOpenAISkip == true {
-			// 所有端点都因为不支持 count_tokens 而跳过，提供特殊错误消息
+			// 所有端点都因为不支持 count_tokens 而跳过，优先使用本地估算器应答
+			if s.respondCountTokensLocally(c, path, requestBody, requestID) {
+				return
+			}
+			// 本地估算不可用，提供特殊错误消息
 			s.sendProxyError(c, http.StatusNotFound, "count_tokens_unsupported", 
 				fmt.Sprintf("request %s with tag (%s): count_tokens API is not supported by available endpoints. Please use Anthropic-type endpoints for token counting.", requestID, strings.Join(requestTags, ", ")), requestID)
 			return
@@ -442,6 +574,11 @@ func (s *Server) fallbackToOtherEndpoints(c *gin.Context, path string, requestBo
 		})
 		
 		if len(universalEndpoints) == 0 {
+			// 唯一的端点不支持 count_tokens（如只配置了 OpenAI 端点），使用本地估算器应答
+			countTokensOpenAISkip, _ := c.Get("count_tokens_openai_skip")
+			if countTokensOpenAISkip == true && s.respondCountTokensLocally(c, path, requestBody, requestID) {
+				return
+			}
 			s.logger.Error("No universal endpoints available for untagged request", nil)
 			errorMsg := s.generateDetailedEndpointUnavailableMessage(requestID, requestTags)
 			s.sendProxyError(c, http.StatusBadGateway, "no_universal_endpoints", errorMsg, requestID)
@@ -449,8 +586,8 @@ func (s *Server) fallbackToOtherEndpoints(c *gin.Context, path string, requestBo
 		}
 		
 		s.logger.Debug(fmt.Sprintf("Trying %d universal endpoints for untagged request", len(universalEndpoints)))
-		success, attemptedCount := s.tryEndpointList(c, universalEndpoints, path, requestBody, requestID, startTime, taggedRequest, "Universal", totalAttempted+1)
-		if success {
+		success, stop, attemptedCount := s.tryEndpointList(c, universalEndpoints, path, requestBody, requestID, startTime, taggedRequest, "Universal", totalAttempted+1)
+		if success || stop {
 			return
 		}
 		totalAttempted += attemptedCount
@@ -460,7 +597,11 @@ func (s *Server) fallbackToOtherEndpoints(c *gin.Context, path string, requestBo
 		countTokensOpenAISkip, _ := c.Get("count_tokens_openai_skip")
 		
 		if isCountTokensRequest && countTokensOpenAISkip == true {
-			// 所有端点都因为不支持 count_tokens 而跳过，提供特殊错误消息
+			// 所有端点都因为不支持 count_tokens 而跳过，优先使用本地估算器应答
+			if s.respondCountTokensLocally(c, path, requestBody, requestID) {
+				return
+			}
+			// 本地估算不可用，提供特殊错误消息
 			s.sendProxyError(c, http.StatusNotFound, "count_tokens_unsupported", 
 				fmt.Sprintf("request %s: count_tokens API is not supported by available endpoints. Please use Anthropic-type endpoints for token counting.", requestID), requestID)
 			return
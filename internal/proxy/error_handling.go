@@ -1,6 +1,10 @@
 package proxy
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,4 +17,33 @@ func (s *Server) sendProxyError(c *gin.Context, statusCode int, errorType, messa
 			"request_id": requestID,
 		},
 	})
-}
\ No newline at end of file
+}
+
+// sendUpstreamError 重试策略要求直接返回错误时，把上一次尝试的上游状态码和响应体原样返回给客户端；
+// 没有上游错误响应（网络错误、校验失败等）时返回标准代理错误
+func (s *Server) sendUpstreamError(c *gin.Context, requestID string) {
+	statusCode := c.GetInt("last_status_code")
+	var body []byte
+	if value, exists := c.Get("last_response_body"); exists {
+		body, _ = value.([]byte)
+	}
+	if statusCode >= 400 && len(body) > 0 {
+		contentType := "application/json"
+		if !json.Valid(body) {
+			contentType = "text/plain; charset=utf-8"
+		}
+		c.Data(statusCode, contentType, body)
+		return
+	}
+
+	message := fmt.Sprintf("request %s failed on upstream", requestID)
+	if value, exists := c.Get("last_error"); exists {
+		if err, ok := value.(error); ok && err != nil {
+			message = fmt.Sprintf("request %s failed on upstream: %v", requestID, err)
+		}
+	}
+	if statusCode < 400 {
+		statusCode = http.StatusBadGateway
+	}
+	s.sendProxyError(c, statusCode, "upstream_error", message, requestID)
+}
@@ -24,6 +24,11 @@ func (s *Server) handleProxy(c *gin.Context) {
 		return
 	}
 
+	// 启用模型发现时由合并的模型目录应答 GET /v1/models
+	if s.respondModelListLocally(c, path, requestID) {
+		return
+	}
+
 	// 读取请求体
 	requestBody, err := s.readRequestBody(c)
 	if err != nil {
@@ -44,15 +49,30 @@ func (s *Server) handleProxy(c *gin.Context) {
 	// 存储到context中，供后续使用
 	c.Set("thinking_info", thinkingInfo)
 
+	// 提取 Claude Code 会话ID，用于会话粘性路由
+	sessionID := utils.ExtractSessionIDFromRequestBody(string(requestBody))
+	c.Set("session_id", sessionID)
+
 	// 处理请求标签
 	taggedRequest := s.processRequestTags(c.Request)
+	if taggedRequest != nil {
+		c.Set("request_tags", taggedRequest.Tags)
+	}
 
 	// count_tokens 请求将通过统一的端点尝试和回退逻辑处理
-	// OpenAI 端点不支持 count_tokens，但会自动回退到支持的端点
+	// 需要格式转换的端点不支持 count_tokens，会自动回退到支持的端点；
+	// 没有任何端点能处理时由本地估算器应答
 
 	// 选择端点并处理请求
-	selectedEndpoint, err := s.selectEndpointForRequest(taggedRequest)
+	selectedEndpoint, err := s.selectEndpointForRequest(taggedRequest, sessionID)
+	if probe := s.selectCircuitProbe(c, taggedRequest, path); probe != nil {
+		selectedEndpoint, err = probe, nil
+	}
 	if err != nil {
+		// 没有可用端点时，count_tokens 请求仍可由本地估算器应答
+		if s.respondCountTokensLocally(c, path, requestBody, requestID) {
+			return
+		}
 		s.logger.Error("Failed to select endpoint", err)
 		// 获取tags用于日志记录
 		var tags []string
@@ -66,7 +86,18 @@ func (s *Server) handleProxy(c *gin.Context) {
 	}
 
 	// 尝试向选择的端点发送请求，失败时回退到其他端点
-	success, shouldRetry := s.tryProxyRequest(c, selectedEndpoint, requestBody, requestID, startTime, path, taggedRequest, 1)
+	var success, shouldRetry bool
+	if delay := s.hedgingDelay(path); delay > 0 {
+		// 启用对冲时，选择的端点响应过慢会同时尝试下一个可用端点
+		hedgeEndpoint := s.selectHedgeEndpoint(selectedEndpoint, taggedRequest)
+		var hedged bool
+		success, shouldRetry, hedged = s.tryHedgedEndpoints(c, selectedEndpoint, hedgeEndpoint, requestBody, requestID, startTime, path, taggedRequest, 1, 1+s.retryPolicyFor(selectedEndpoint).maxAttempts, delay)
+		if hedged {
+			s.markHedgedEndpoint(c, hedgeEndpoint)
+		}
+	} else {
+		success, shouldRetry = s.tryProxyRequest(c, selectedEndpoint, requestBody, requestID, startTime, path, taggedRequest, 1)
+	}
 	if success {
 		return
 	}
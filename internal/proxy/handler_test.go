@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHandleProxyReturnRuleSendsUpstreamError(t *testing.T) {
+	const upstreamBody = `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long"}}`
+	var primaryHits, backupHits int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&primaryHits, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(upstreamBody))
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&backupHits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer backup.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: primary
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+    - name: backup
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+retry_policy:
+    initial_backoff: 0s
+    rules:
+        - status_codes: [400]
+          body_pattern: "prompt is too long"
+          action: return
+`, primary.URL, backup.URL))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", recorder.Code)
+	}
+	if recorder.Body.String() != upstreamBody {
+		t.Errorf("expected upstream body %q, got %q", upstreamBody, recorder.Body.String())
+	}
+	if hits := atomic.LoadInt32(&primaryHits); hits != 1 {
+		t.Errorf("expected primary endpoint to be tried once, got %d", hits)
+	}
+	if hits := atomic.LoadInt32(&backupHits); hits != 0 {
+		t.Errorf("expected backup endpoint not to be tried, got %d", hits)
+	}
+}
+
+func TestHandleProxyReturnRuleStopsUniversalFallback(t *testing.T) {
+	const upstreamBody = `{"type":"error","error":{"type":"permission_error","message":"account suspended"}}`
+	hits := make(map[string]*int32)
+	newUpstream := func(name string, status int, body string) *httptest.Server {
+		hits[name] = new(int32)
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(hits[name], 1)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+	}
+	primary := newUpstream("primary", http.StatusInternalServerError, `{"type":"error"}`)
+	defer primary.Close()
+	tagged := newUpstream("tagged", http.StatusForbidden, upstreamBody)
+	defer tagged.Close()
+	universal := newUpstream("universal", http.StatusInternalServerError, `{"type":"error"}`)
+	defer universal.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: primary
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+      tags: [coding]
+    - name: tagged
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+      tags: [coding]
+    - name: universal
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 3
+tagging:
+    enabled: true
+    pipeline_timeout: 5s
+    taggers:
+        - name: coding-detector
+          type: builtin
+          builtin_type: path
+          tag: coding
+          enabled: true
+          priority: 1
+          config:
+              path_pattern: /v1/*
+retry_policy:
+    max_attempts: 1
+    rules:
+        - status_codes: [403]
+          action: return
+`, primary.URL, tagged.URL, universal.URL))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", recorder.Code)
+	}
+	if recorder.Body.String() != upstreamBody {
+		t.Errorf("expected upstream body %q, got %q", upstreamBody, recorder.Body.String())
+	}
+	for name, expected := range map[string]int32{"primary": 1, "tagged": 1, "universal": 0} {
+		if got := atomic.LoadInt32(hits[name]); got != expected {
+			t.Errorf("expected endpoint %s to be tried %d times, got %d", name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/endpoint"
+	"claude-code-companion/internal/tagging"
+	"claude-code-companion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 对冲请求
+//
+// 启用 hedging 后，向端点发送请求的同时启动计时器：如果在 delay 内端点既没有返回响应头（非流式），
+// 也没有产生第一个SSE事件（流式），就把同一请求同时发送给下一个可用端点。
+// 两个尝试各自运行在 gin.Context 的副本上，响应写入各自的 hedgeResponseWriter；
+// 第一个向客户端写出 2xx 响应数据的尝试获胜（此时它的响应已经通过了校验），另一个尝试被取消。
+// 错误响应（非 2xx）不参与竞争，先缓存在尝试自己的 writer 中：没有尝试获胜且不再回退时，
+// 才把决定结果的尝试缓存的错误响应写给客户端。
+// 两个尝试都以同一个请求ID、各自的 AttemptNumber 记录日志，落败的尝试不计入端点健康统计。
+
+// errHedgeLost 落败的对冲尝试写响应时返回的错误
+var errHedgeLost = errors.New("hedged request lost the race")
+
+// hedgeRace 一组对冲尝试共享的状态
+type hedgeRace struct {
+	mu       sync.Mutex
+	target   gin.ResponseWriter // 真实的客户端响应
+	winner   *hedgeAttempt
+	attempts []*hedgeAttempt
+	finished []*hedgeAttempt // 按结束顺序排列
+}
+
+// hedgeAttempt 一个对冲尝试
+type hedgeAttempt struct {
+	race          *hedgeRace
+	ctx           *gin.Context
+	cancel        context.CancelFunc
+	endpoint      *endpoint.Endpoint
+	attemptNumber int
+	writer        *hedgeResponseWriter
+	responded     chan struct{} // 收到响应头或第一个SSE事件时关闭
+	respondOnce   sync.Once
+	done          chan struct{}
+	success       bool
+	shouldTryNext bool
+}
+
+// markResponded 标记该尝试已经收到响应
+func (a *hedgeAttempt) markResponded() {
+	a.respondOnce.Do(func() { close(a.responded) })
+}
+
+// claim 尝试成为获胜者：第一个调用的尝试获胜，把缓存的响应头和状态码写入客户端响应，并取消其他尝试
+func (r *hedgeRace) claim(attempt *hedgeAttempt, header http.Header, status int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.winner != nil {
+		return r.winner == attempt
+	}
+	r.winner = attempt
+
+	for key, values := range header {
+		r.target.Header()[key] = values
+	}
+	r.target.WriteHeader(status)
+
+	for _, other := range r.attempts {
+		if other != attempt {
+			// 落败不是端点的问题
+			other.ctx.Set("skip_health_record", true)
+			other.cancel()
+		}
+	}
+	attempt.markResponded()
+	return true
+}
+
+// hedgeResponseWriter 对冲尝试使用的响应写入器：获胜前缓存响应头和状态码，获胜后透传给客户端响应；
+// 非 2xx 的错误响应只缓存，不参与竞争
+type hedgeResponseWriter struct {
+	attempt  *hedgeAttempt
+	header   http.Header
+	status   int
+	claimed  bool
+	lost     bool
+	buffered bytes.Buffer // 缓存的错误响应体
+	errored  bool         // 是否写出过错误响应
+}
+
+func newHedgeResponseWriter(attempt *hedgeAttempt) *hedgeResponseWriter {
+	return &hedgeResponseWriter{
+		attempt: attempt,
+		header:  make(http.Header),
+		status:  http.StatusOK,
+	}
+}
+
+// isError 当前状态码是否为错误响应
+func (w *hedgeResponseWriter) isError() bool {
+	return w.status < 200 || w.status >= 300
+}
+
+// claim 第一次写出 2xx 响应数据时参与竞争
+func (w *hedgeResponseWriter) claim() bool {
+	if !w.claimed && !w.lost {
+		if w.attempt.race.claim(w.attempt, w.header, w.status) {
+			w.claimed = true
+		} else {
+			w.lost = true
+		}
+	}
+	return w.claimed
+}
+
+func (w *hedgeResponseWriter) Header() http.Header {
+	if w.claimed {
+		return w.attempt.race.target.Header()
+	}
+	return w.header
+}
+
+func (w *hedgeResponseWriter) WriteHeader(code int) {
+	if w.claimed {
+		w.attempt.race.target.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *hedgeResponseWriter) WriteHeaderNow() {
+	if !w.claimed && w.isError() {
+		w.errored = true
+		return
+	}
+	if w.claim() {
+		w.attempt.race.target.WriteHeaderNow()
+	}
+}
+
+func (w *hedgeResponseWriter) Write(data []byte) (int, error) {
+	if !w.claimed && w.isError() {
+		w.errored = true
+		return w.buffered.Write(data)
+	}
+	if !w.claim() {
+		return 0, errHedgeLost
+	}
+	return w.attempt.race.target.Write(data)
+}
+
+func (w *hedgeResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// flushBufferedError 把缓存的错误响应写入客户端响应，只能在所有尝试结束且没有获胜者时调用
+func (w *hedgeResponseWriter) flushBufferedError() {
+	if w.claimed || !w.errored {
+		return
+	}
+	target := w.attempt.race.target
+	for key, values := range w.header {
+		target.Header()[key] = values
+	}
+	target.WriteHeader(w.status)
+	target.WriteHeaderNow()
+	target.Write(w.buffered.Bytes())
+}
+
+func (w *hedgeResponseWriter) Flush() {
+	if w.claimed {
+		w.attempt.race.target.Flush()
+	}
+}
+
+func (w *hedgeResponseWriter) Status() int {
+	if w.claimed {
+		return w.attempt.race.target.Status()
+	}
+	return w.status
+}
+
+func (w *hedgeResponseWriter) Size() int {
+	if w.claimed {
+		return w.attempt.race.target.Size()
+	}
+	return -1
+}
+
+func (w *hedgeResponseWriter) Written() bool {
+	if w.claimed {
+		return w.attempt.race.target.Written()
+	}
+	return w.errored
+}
+
+func (w *hedgeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("hijack is not supported for hedged requests")
+}
+
+func (w *hedgeResponseWriter) CloseNotify() <-chan bool {
+	return w.attempt.race.target.CloseNotify()
+}
+
+func (w *hedgeResponseWriter) Pusher() http.Pusher {
+	return nil
+}
+
+// hedgeAttemptFromContext 获取当前 context 所属的对冲尝试
+func hedgeAttemptFromContext(c *gin.Context) (*hedgeAttempt, bool) {
+	value, exists := c.Get("hedge_attempt")
+	if !exists {
+		return nil, false
+	}
+	attempt, ok := value.(*hedgeAttempt)
+	return attempt, ok
+}
+
+// markHedgeResponded 非流式响应收到响应头时调用，停止对冲计时
+func (s *Server) markHedgeResponded(c *gin.Context) {
+	if attempt, ok := hedgeAttemptFromContext(c); ok {
+		attempt.markResponded()
+	}
+}
+
+// hedgingDelay 返回对冲等待时间，未启用或不适用于该请求时返回 0
+func (s *Server) hedgingDelay(path string) time.Duration {
+	if !s.config.Hedging.Enabled || strings.Contains(path, "/count_tokens") {
+		return 0
+	}
+	defaultDelay, _ := time.ParseDuration(config.Default.Hedging.Delay)
+	return config.GetTimeoutDuration(s.config.Hedging.Delay, defaultDelay)
+}
+
+// startHedgeAttempt 在 gin.Context 副本上启动一个尝试
+func (s *Server) startHedgeAttempt(c *gin.Context, race *hedgeRace, ep *endpoint.Endpoint, requestBody []byte, requestID string, startTime time.Time, path string, taggedRequest *tagging.TaggedRequest, attemptNumber int) *hedgeAttempt {
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	attempt := &hedgeAttempt{
+		race:          race,
+		cancel:        cancel,
+		endpoint:      ep,
+		attemptNumber: attemptNumber,
+		responded:     make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	attemptCtx := c.Copy()
+	attemptCtx.Request = c.Request.WithContext(ctx)
+	attempt.writer = newHedgeResponseWriter(attempt)
+	attemptCtx.Writer = attempt.writer
+	attemptCtx.Set("hedge_attempt", attempt)
+	attempt.ctx = attemptCtx
+
+	race.mu.Lock()
+	race.attempts = append(race.attempts, attempt)
+	if race.winner != nil {
+		// 计时器触发的同时已经有尝试获胜，新尝试直接取消
+		attemptCtx.Set("skip_health_record", true)
+		cancel()
+	}
+	race.mu.Unlock()
+
+	go func() {
+		defer close(attempt.done)
+		defer func() {
+			race.mu.Lock()
+			race.finished = append(race.finished, attempt)
+			race.mu.Unlock()
+		}()
+		defer cancel()
+		defer func() {
+			if r := recover(); r != nil {
+				s.logger.Error(fmt.Sprintf("Hedged attempt #%d on endpoint %s panicked: %v", attemptNumber, ep.Name, r), nil)
+				attempt.success, attempt.shouldTryNext = false, false
+			}
+		}()
+		attempt.success, attempt.shouldTryNext = s.tryProxyRequestWithRetry(attemptCtx, ep, requestBody, requestID, startTime, path, taggedRequest, attemptNumber)
+	}()
+
+	return attempt
+}
+
+// tryHedgedEndpoints 向 primary 发送请求，超过对冲等待时间仍未响应时同时向 secondary 发送同一请求
+// 返回值与 tryProxyRequestWithRetry 一致；secondaryUsed 表示 secondary 是否实际参与了对冲
+func (s *Server) tryHedgedEndpoints(c *gin.Context, primary, secondary *endpoint.Endpoint, requestBody []byte, requestID string, startTime time.Time, path string, taggedRequest *tagging.TaggedRequest, primaryAttempt, secondaryAttempt int, delay time.Duration) (success bool, shouldTryNextEndpoint bool, secondaryUsed bool) {
+	race := &hedgeRace{target: c.Writer}
+	first := s.startHedgeAttempt(c, race, primary, requestBody, requestID, startTime, path, taggedRequest, primaryAttempt)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	hedge := false
+	select {
+	case <-first.done:
+	case <-first.responded:
+	case <-timer.C:
+		hedge = secondary != nil
+	}
+
+	if !hedge {
+		<-first.done
+		s.finishHedgeRace(c, race, first)
+		if race.winner == nil && !first.shouldTryNext {
+			first.writer.flushBufferedError()
+		}
+		return first.success, first.shouldTryNext, false
+	}
+
+	s.logger.Info(fmt.Sprintf("Request %s: endpoint %s has not responded within %v, hedging to endpoint %s", requestID, primary.Name, delay, secondary.Name))
+	second := s.startHedgeAttempt(c, race, secondary, requestBody, requestID, startTime, path, taggedRequest, secondaryAttempt)
+
+	// 等待两个尝试都结束：获胜者写完响应，落败者被取消后很快结束
+	<-first.done
+	<-second.done
+
+	if race.winner != nil {
+		result := s.finishHedgeRace(c, race, race.winner)
+		s.logger.Info(fmt.Sprintf("Request %s: hedged attempt #%d on endpoint %s won", requestID, race.winner.attemptNumber, race.winner.endpoint.Name))
+		// 获胜者已经向客户端写出数据，失败时也无法再切换端点
+		return result.success, false, true
+	}
+
+	// 没有获胜者：任一尝试要求停止时不再回退，由它决定结果并返回它缓存的错误响应
+	for _, attempt := range race.finished {
+		if !attempt.shouldTryNext {
+			s.finishHedgeRace(c, race, attempt)
+			attempt.writer.flushBufferedError()
+			return false, false, true
+		}
+	}
+	s.finishHedgeRace(c, race, race.finished[len(race.finished)-1])
+	return false, true, true
+}
+
+// finishHedgeRace 把决定结果的尝试的 context 状态（last_error、last_status_code 等）同步回原始 context
+// 有获胜者时使用获胜者，否则使用 result
+func (s *Server) finishHedgeRace(c *gin.Context, race *hedgeRace, result *hedgeAttempt) *hedgeAttempt {
+	if race.winner != nil {
+		result = race.winner
+	}
+	for key, value := range result.ctx.Keys {
+		if key == "hedge_attempt" {
+			continue
+		}
+		c.Set(key, value)
+	}
+	return result
+}
+
+// markHedgedEndpoint 记录作为对冲目标尝试过的端点及其占用的尝试次数，回退时不再重复尝试
+func (s *Server) markHedgedEndpoint(c *gin.Context, ep *endpoint.Endpoint) {
+	hedged, _ := c.Get("hedged_endpoints")
+	endpoints, _ := hedged.(map[string]int)
+	if endpoints == nil {
+		endpoints = make(map[string]int)
+	}
+	endpoints[ep.ID] = s.retryPolicyFor(ep).maxAttempts
+	c.Set("hedged_endpoints", endpoints)
+}
+
+// isHedgedEndpoint 检查端点是否已经作为对冲目标尝试过
+func (s *Server) isHedgedEndpoint(c *gin.Context, ep *endpoint.Endpoint) bool {
+	hedged, _ := c.Get("hedged_endpoints")
+	endpoints, _ := hedged.(map[string]int)
+	_, exists := endpoints[ep.ID]
+	return exists
+}
+
+// hedgedAttemptCount 对冲目标端点占用的尝试次数
+func (s *Server) hedgedAttemptCount(c *gin.Context) int {
+	hedged, _ := c.Get("hedged_endpoints")
+	endpoints, _ := hedged.(map[string]int)
+	total := 0
+	for _, attempts := range endpoints {
+		total += attempts
+	}
+	return total
+}
+
+// selectHedgeEndpoint 按回退顺序选择第一个可用的对冲目标
+func (s *Server) selectHedgeEndpoint(primary *endpoint.Endpoint, taggedRequest *tagging.TaggedRequest) *endpoint.Endpoint {
+	var requestTags []string
+	if taggedRequest != nil {
+		requestTags = taggedRequest.Tags
+	}
+	if candidates := s.fallbackCandidates(primary, requestTags); len(candidates) > 0 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// nextUnhedgedEndpoint 从 start 开始查找第一个可用且未作为对冲目标尝试过的端点
+func (s *Server) nextUnhedgedEndpoint(c *gin.Context, endpoints []utils.EndpointSorter, start int) *endpoint.Endpoint {
+	for i := start; i < len(endpoints); i++ {
+		if ep := endpoints[i].(*endpoint.Endpoint); ep.IsAvailable() && !s.isHedgedEndpoint(c, ep) {
+			return ep
+		}
+	}
+	return nil
+}
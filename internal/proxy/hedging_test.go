@@ -0,0 +1,291 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testAnthropicResponse = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4","content":[{"type":"text","text":"hello"}],"stop_reason":"end_turn","stop_sequence":null,"usage":{"input_tokens":5,"output_tokens":1}}`
+
+func TestHedgedErrorResponseDoesNotWinRace(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testAnthropicResponse))
+	}))
+	defer slow.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"bad request"}}`))
+	}))
+	defer failing.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: slow
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+    - name: failing
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+hedging:
+    enabled: true
+    delay: 50ms
+retry_policy:
+    max_attempts: 1
+    rules:
+        - status_codes: [400]
+          action: return
+`, slow.URL, failing.URL))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected the slow successful attempt to win with 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if !strings.Contains(recorder.Body.String(), `"text":"hello"`) {
+		t.Errorf("expected successful response body, got %s", recorder.Body.String())
+	}
+}
+
+func TestHedgedErrorResponseIsReturnedWithoutWinner(t *testing.T) {
+	const upstreamBody = `{"type":"error","error":{"type":"invalid_request_error","message":"bad request"}}`
+	newFailing := func(delay time.Duration, status int, body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+	}
+	slow := newFailing(150*time.Millisecond, http.StatusInternalServerError, `{"type":"error"}`)
+	defer slow.Close()
+	failing := newFailing(0, http.StatusBadRequest, upstreamBody)
+	defer failing.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: slow
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+    - name: failing
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+hedging:
+    enabled: true
+    delay: 50ms
+retry_policy:
+    max_attempts: 1
+    rules:
+        - status_codes: [400]
+          action: return
+`, slow.URL, failing.URL))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", recorder.Code)
+	}
+	if recorder.Body.String() != upstreamBody {
+		t.Errorf("expected upstream body %q, got %q", upstreamBody, recorder.Body.String())
+	}
+}
+
+func TestHedgedRequestRacesSecondaryAfterDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	var secondaryStarted time.Time
+	primaryCancelled := make(chan struct{})
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 读完请求体后服务端才能感知客户端断开
+		io.ReadAll(r.Body)
+		select {
+		case <-time.After(2 * time.Second):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(testAnthropicResponse))
+		case <-r.Context().Done():
+			close(primaryCancelled)
+		}
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryStarted = time.Now()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(strings.Replace(testAnthropicResponse, `"text":"hello"`, `"text":"from secondary"`, 1)))
+	}))
+	defer secondary.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: primary
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+    - name: secondary
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+hedging:
+    enabled: true
+    delay: %s
+`, primary.URL, secondary.URL, delay))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	started := time.Now()
+	server.GetRouter().ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200 from the secondary, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if !strings.Contains(recorder.Body.String(), `"text":"from secondary"`) {
+		t.Errorf("expected the secondary's response to win, got %s", recorder.Body.String())
+	}
+	if gap := secondaryStarted.Sub(started); gap < delay {
+		t.Errorf("expected the hedge to fire no earlier than %v, fired after %v", delay, gap)
+	}
+	select {
+	case <-primaryCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the losing primary attempt to be cancelled")
+	}
+
+	for _, ep := range server.endpointManager.GetAllEndpoints() {
+		switch ep.Name {
+		case "primary":
+			// 落败的尝试带有 skip_health_record，不计入端点健康统计
+			if ep.TotalRequests != 0 || ep.FailureCount != 0 {
+				t.Errorf("expected the cancelled primary not to be recorded, got %d requests and %d failures", ep.TotalRequests, ep.FailureCount)
+			}
+			if !ep.IsAvailable() {
+				t.Error("expected the cancelled primary to stay available")
+			}
+		case "secondary":
+			if ep.SuccessRequests != 1 {
+				t.Errorf("expected one successful request on the secondary, got %d", ep.SuccessRequests)
+			}
+		}
+	}
+}
+
+func TestHedgedEndpointIsNotRetriedInFallback(t *testing.T) {
+	var mu sync.Mutex
+	var hits []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits = append(hits, name)
+	}
+	newServer := func(name string, delay time.Duration, status int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			record(name)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			if status == http.StatusOK {
+				w.Write([]byte(testAnthropicResponse))
+			} else {
+				w.Write([]byte(`{"type":"error","error":{"type":"api_error","message":"upstream failed"}}`))
+			}
+		}))
+	}
+	primary := newServer("primary", 300*time.Millisecond, http.StatusInternalServerError)
+	defer primary.Close()
+	secondary := newServer("secondary", 0, http.StatusInternalServerError)
+	defer secondary.Close()
+	third := newServer("third", 0, http.StatusOK)
+	defer third.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: primary
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+    - name: secondary
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+    - name: third
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 3
+hedging:
+    enabled: true
+    delay: 50ms
+`, primary.URL, secondary.URL, third.URL))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected the third endpoint to serve the request, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 对冲阶段结束（primary 的所有尝试都已返回）之后，回退只能落到 third，不能再次尝试 secondary
+	lastPrimary := -1
+	for i, name := range hits {
+		if name == "primary" {
+			lastPrimary = i
+		}
+	}
+	if lastPrimary == -1 {
+		t.Fatalf("expected the primary to be tried, got hits %v", hits)
+	}
+	for _, name := range hits[lastPrimary+1:] {
+		if name == "secondary" {
+			t.Fatalf("expected the hedged secondary not to be retried in fallback, got hits %v", hits)
+		}
+	}
+	if hits[len(hits)-1] != "third" {
+		t.Errorf("expected the fallback to end on the third endpoint, got hits %v", hits)
+	}
+}
@@ -242,4 +242,117 @@ func (s *Server) logBlacklistedEndpointRequest(requestID string, ep *endpoint.En
 	}
 	
 	s.logger.LogRequest(requestLog)
-}
\ No newline at end of file
+}
+
+// logSuccessfulRequest 记录成功请求的完整日志（包含修改前后的请求和响应数据）
+func (s *Server) logSuccessfulRequest(requestID string, ep *endpoint.Endpoint, path string, c *gin.Context, req *http.Request, resp *http.Response, requestBody, finalRequestBody, decompressedBody, finalResponseBody []byte, duration time.Duration, isStreaming bool, tags []string, contentTypeOverride string, originalModel, rewrittenModel string, attemptNumber int) {
+	requestLog := s.logger.CreateRequestLog(requestID, ep.URL, c.Request.Method, path)
+	requestLog.RequestBodySize = len(requestBody)
+	requestLog.Tags = tags
+	requestLog.ContentTypeOverride = contentTypeOverride
+	requestLog.AttemptNumber = attemptNumber
+	
+	// 设置 thinking 信息
+	if thinkingInfo, exists := c.Get("thinking_info"); exists {
+		if info, ok := thinkingInfo.(*utils.ThinkingInfo); ok && info != nil {
+			requestLog.ThinkingEnabled = info.Enabled
+			requestLog.ThinkingBudgetTokens = info.BudgetTokens
+		}
+	}
+	
+	// 记录原始客户端请求数据
+	requestLog.OriginalRequestURL = c.Request.URL.String()
+	requestLog.OriginalRequestHeaders = utils.HeadersToMap(c.Request.Header)
+	if len(requestBody) > 0 {
+		if s.config.Logging.LogRequestBody != "none" {
+			if s.config.Logging.LogRequestBody == "truncated" {
+				requestLog.OriginalRequestBody = utils.TruncateBody(string(requestBody), 1024)
+			} else {
+				requestLog.OriginalRequestBody = string(requestBody)
+			}
+		}
+	}
+	
+	// 记录最终发送给上游的请求数据
+	requestLog.FinalRequestURL = req.URL.String()
+	requestLog.FinalRequestHeaders = utils.HeadersToMap(req.Header)
+	if len(finalRequestBody) > 0 {
+		if s.config.Logging.LogRequestBody != "none" {
+			if s.config.Logging.LogRequestBody == "truncated" {
+				requestLog.FinalRequestBody = utils.TruncateBody(string(finalRequestBody), 1024)
+			} else {
+				requestLog.FinalRequestBody = string(finalRequestBody)
+			}
+		}
+	}
+	
+	// 记录上游原始响应数据
+	requestLog.OriginalResponseHeaders = utils.HeadersToMap(resp.Header)
+	if len(decompressedBody) > 0 {
+		if s.config.Logging.LogResponseBody != "none" {
+			if s.config.Logging.LogResponseBody == "truncated" {
+				requestLog.OriginalResponseBody = utils.TruncateBody(string(decompressedBody), 1024)
+			} else {
+				requestLog.OriginalResponseBody = string(decompressedBody)
+			}
+		}
+	}
+	
+	// 记录最终发送给客户端的响应数据
+	finalHeaders := make(map[string]string)
+	for key := range resp.Header {
+		values := c.Writer.Header().Values(key)
+		if len(values) > 0 {
+			finalHeaders[key] = values[0]
+		}
+	}
+	requestLog.FinalResponseHeaders = finalHeaders
+	if len(finalResponseBody) > 0 {
+		if s.config.Logging.LogResponseBody != "none" {
+			if s.config.Logging.LogResponseBody == "truncated" {
+				requestLog.FinalResponseBody = utils.TruncateBody(string(finalResponseBody), 1024)
+			} else {
+				requestLog.FinalResponseBody = string(finalResponseBody)
+			}
+		}
+	}
+	
+	// 设置兼容性字段
+	requestLog.RequestHeaders = requestLog.FinalRequestHeaders
+	requestLog.RequestBody = requestLog.OriginalRequestBody
+	requestLog.ResponseHeaders = requestLog.OriginalResponseHeaders
+	requestLog.ResponseBody = requestLog.OriginalResponseBody
+	
+	// 设置模型信息
+	if len(requestBody) > 0 {
+		extractedModel := utils.ExtractModelFromRequestBody(string(requestBody))
+		if originalModel != "" {
+			requestLog.Model = originalModel
+			requestLog.OriginalModel = originalModel
+		} else {
+			requestLog.Model = extractedModel
+			requestLog.OriginalModel = extractedModel
+		}
+		
+		if rewrittenModel != "" {
+			requestLog.RewrittenModel = rewrittenModel
+			requestLog.ModelRewriteApplied = rewrittenModel != requestLog.OriginalModel
+		}
+		
+		// 提取 Session ID
+		requestLog.SessionID = utils.ExtractSessionIDFromRequestBody(string(requestBody))
+	}
+	
+	// 记录 token 用量与费用
+	if value, exists := c.Get("token_usage"); exists {
+		if usage, ok := value.(endpoint.TokenUsage); ok {
+			s.setRequestLogUsage(requestLog, ep, usage)
+			c.Set("request_cost", requestLog.Cost)
+		}
+	}
+	
+	// 更新基本字段
+	s.logger.UpdateRequestLog(requestLog, req, resp, decompressedBody, duration, nil)
+	requestLog.IsStreaming = isStreaming
+	s.logger.LogRequest(requestLog)
+}
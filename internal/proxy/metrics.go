@@ -0,0 +1,233 @@
+package proxy
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"claude-code-companion/internal/endpoint"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsPrefix = "claude_code_companion_"
+
+// otherLabel 不在配置中的端点、模型和标签统一归入该值，避免客户端提供的值让指标序列无限增长
+const otherLabel = "other"
+
+// proxyMetrics 代理导出的 Prometheus 指标；请求相关指标由中间件和重试逻辑记录，端点状态在抓取时读取
+type proxyMetrics struct {
+	registry  *prometheus.Registry
+	handler   http.Handler
+	inFlight  int64 // 正在处理的客户端请求数，原子操作
+	requests  *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	ttft      *prometheus.HistogramVec
+	retries   *prometheus.CounterVec
+	failovers *prometheus.CounterVec
+	tokens    *prometheus.CounterVec
+}
+
+func newProxyMetrics(endpointManager *endpoint.Manager) *proxyMetrics {
+	registry := prometheus.NewRegistry()
+	m := &proxyMetrics{registry: registry}
+
+	m.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricsPrefix + "requests_total",
+		Help: "Client requests by the endpoint that handled them last, response status, requested model and request tags.",
+	}, []string{"endpoint", "status", "model", "tag"})
+	m.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    metricsPrefix + "request_duration_seconds",
+		Help:    "Client request duration including retries and failovers.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	}, []string{"endpoint", "model"})
+	m.ttft = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    metricsPrefix + "time_to_first_token_seconds",
+		Help:    "Time from receiving a streaming request to sending the first event to the client.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 20, 30, 60},
+	}, []string{"endpoint", "model"})
+	m.retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricsPrefix + "retries_total",
+		Help: "Retries of the same endpoint, after a retryable error or with the next API key.",
+	}, []string{"endpoint", "reason"})
+	m.failovers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricsPrefix + "failovers_total",
+		Help: "Requests failed over to the endpoint after the previously tried endpoint failed.",
+	}, []string{"endpoint"})
+	m.tokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: metricsPrefix + "tokens_total",
+		Help: "Tokens reported by upstream responses by token type (input, output, cache_read, cache_write).",
+	}, []string{"endpoint", "model", "type"})
+
+	registry.MustRegister(
+		m.requests, m.duration, m.ttft, m.retries, m.failovers, m.tokens,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: metricsPrefix + "requests_in_flight",
+			Help: "Client requests currently being processed.",
+		}, func() float64 {
+			return float64(atomic.LoadInt64(&m.inFlight))
+		}),
+		&endpointCollector{endpointManager: endpointManager},
+	)
+	m.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return m
+}
+
+var (
+	endpointInFlightDesc = prometheus.NewDesc(metricsPrefix+"endpoint_requests_in_flight",
+		"Upstream requests currently in progress per endpoint.", []string{"endpoint"}, nil)
+	endpointUpDesc = prometheus.NewDesc(metricsPrefix+"endpoint_up",
+		"Whether the endpoint status is active (1) or inactive (0).", []string{"endpoint"}, nil)
+	endpointEnabledDesc = prometheus.NewDesc(metricsPrefix+"endpoint_enabled",
+		"Whether the endpoint is enabled in the configuration.", []string{"endpoint"}, nil)
+	oauthRefreshDesc = prometheus.NewDesc(metricsPrefix+"oauth_refresh_total",
+		"OAuth token refresh attempts by endpoint name and result since the proxy started.", []string{"endpoint", "result"}, nil)
+)
+
+// endpointCollector 抓取时读取端点状态；OAuth 刷新计数由端点管理器按端点名称保存，端点重建后不会清零
+type endpointCollector struct {
+	endpointManager *endpoint.Manager
+}
+
+func (c *endpointCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- endpointInFlightDesc
+	ch <- endpointUpDesc
+	ch <- endpointEnabledDesc
+	ch <- oauthRefreshDesc
+}
+
+func (c *endpointCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, ep := range c.endpointManager.GetAllEndpoints() {
+		up, enabled := 0.0, 0.0
+		if ep.GetStatus() == endpoint.StatusActive {
+			up = 1
+		}
+		if ep.IsEnabled() {
+			enabled = 1
+		}
+		ch <- prometheus.MustNewConstMetric(endpointInFlightDesc, prometheus.GaugeValue, float64(ep.GetInFlight()), ep.Name)
+		ch <- prometheus.MustNewConstMetric(endpointUpDesc, prometheus.GaugeValue, up, ep.Name)
+		ch <- prometheus.MustNewConstMetric(endpointEnabledDesc, prometheus.GaugeValue, enabled, ep.Name)
+	}
+	for name, count := range c.endpointManager.GetOAuthRefreshCounts() {
+		ch <- prometheus.MustNewConstMetric(oauthRefreshDesc, prometheus.CounterValue, float64(count.Successes), name, "success")
+		ch <- prometheus.MustNewConstMetric(oauthRefreshDesc, prometheus.CounterValue, float64(count.Failures), name, "failure")
+	}
+}
+
+// metricsEndpointLabel 只保留配置中的端点名称
+func (s *Server) metricsEndpointLabel(name string) string {
+	if name == "" {
+		return ""
+	}
+	for _, ep := range s.endpointManager.GetAllEndpoints() {
+		if ep.Name == name {
+			return name
+		}
+	}
+	return otherLabel
+}
+
+// metricsModelLabel 只保留端点模型列表或模型重写规则中出现的模型，客户端随意填写的模型名归入 other
+func (s *Server) metricsModelLabel(model string) string {
+	if model == "" {
+		return ""
+	}
+	for _, ep := range s.endpointManager.GetAllEndpoints() {
+		if ep.KnowsModel(model) {
+			return model
+		}
+	}
+	return otherLabel
+}
+
+// metricsTagLabel 只保留 tagger 或端点配置中的标签，排序去重后以逗号连接
+func (s *Server) metricsTagLabel(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	known := make(map[string]bool)
+	for _, tagger := range s.config.Tagging.Taggers {
+		known[tagger.Tag] = true
+	}
+	for _, ep := range s.endpointManager.GetAllEndpoints() {
+		for _, tag := range ep.Tags {
+			known[tag] = true
+		}
+	}
+
+	labels := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if known[tag] {
+			labels[tag] = true
+		} else {
+			labels[otherLabel] = true
+		}
+	}
+	values := make([]string, 0, len(labels))
+	for tag := range labels {
+		values = append(values, tag)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+// metricsMiddleware 统计进行中的客户端请求，并在请求结束后记录请求数、延迟、首个 token 时间和 token 用量
+func (s *Server) metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		atomic.AddInt64(&s.metrics.inFlight, 1)
+		defer atomic.AddInt64(&s.metrics.inFlight, -1)
+
+		c.Next()
+
+		endpointName := s.metricsEndpointLabel(c.GetString("served_endpoint"))
+		model := s.metricsModelLabel(c.GetString("original_model"))
+		tag := s.metricsTagLabel(c.GetStringSlice("request_tags"))
+		s.metrics.requests.WithLabelValues(endpointName, strconv.Itoa(c.Writer.Status()), model, tag).Inc()
+
+		start, ok := c.Get("start_time")
+		if !ok {
+			return
+		}
+		startTime := start.(time.Time)
+		s.metrics.duration.WithLabelValues(endpointName, model).Observe(time.Since(startTime).Seconds())
+		if firstToken, ok := c.Get("first_token_time"); ok {
+			s.metrics.ttft.WithLabelValues(endpointName, model).Observe(firstToken.(time.Time).Sub(startTime).Seconds())
+		}
+
+		if value, exists := c.Get("token_usage"); exists {
+			if usage, ok := value.(endpoint.TokenUsage); ok && !usage.IsZero() {
+				s.metrics.tokens.WithLabelValues(endpointName, model, "input").Add(float64(usage.InputTokens))
+				s.metrics.tokens.WithLabelValues(endpointName, model, "output").Add(float64(usage.OutputTokens))
+				s.metrics.tokens.WithLabelValues(endpointName, model, "cache_read").Add(float64(usage.CacheReadTokens))
+				s.metrics.tokens.WithLabelValues(endpointName, model, "cache_write").Add(float64(usage.CacheWriteTokens))
+			}
+		}
+	}
+}
+
+// metricsAuthMiddleware 配置 metrics.require_auth 时要求管理界面的凭据
+func (s *Server) metricsAuthMiddleware() gin.HandlerFunc {
+	basicAuth := s.authManager.BasicAuthMiddleware()
+	return func(c *gin.Context) {
+		if s.config.Metrics.RequireAuth {
+			basicAuth(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleMetrics 以 Prometheus 格式导出指标，未启用时返回 404
+func (s *Server) handleMetrics(c *gin.Context) {
+	if !s.config.Metrics.Enabled {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	s.metrics.handler.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsLabelsAreBounded(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testAnthropicResponse))
+	}))
+	defer upstream.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: primary
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+      model_rewrite:
+          enabled: true
+          rules:
+              - source_pattern: claude-sonnet-4
+                target_model: claude-sonnet-4-5
+metrics:
+    enabled: true
+`, upstream.URL))
+
+	send := func(model string) {
+		t.Helper()
+		body := fmt.Sprintf(`{"model":%q,"max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`, model)
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.GetRouter().ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("request for model %s failed with %d: %s", model, recorder.Code, recorder.Body.String())
+		}
+	}
+	send("claude-sonnet-4")
+	for i := 0; i < 3; i++ {
+		send(fmt.Sprintf("made-up-model-%d", i))
+	}
+
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected metrics to be served, got %d", recorder.Code)
+	}
+	body := recorder.Body.String()
+
+	// 配置中的模型保留原值，客户端随意填写的模型名归入 other
+	if !strings.Contains(body, `claude_code_companion_requests_total{endpoint="primary",model="claude-sonnet-4",status="200",tag=""} 1`) {
+		t.Errorf("expected configured model to keep its label, got:\n%s", body)
+	}
+	if !strings.Contains(body, `claude_code_companion_requests_total{endpoint="primary",model="other",status="200",tag=""} 3`) {
+		t.Errorf("expected unknown models to be counted as other, got:\n%s", body)
+	}
+	if strings.Contains(body, "made-up-model") {
+		t.Errorf("expected client-supplied model names not to become label values, got:\n%s", body)
+	}
+	if !strings.Contains(body, `claude_code_companion_endpoint_up{endpoint="primary"} 1`) {
+		t.Errorf("expected endpoint gauges, got:\n%s", body)
+	}
+}
+
+func TestMetricsTagLabel(t *testing.T) {
+	server := newTestServer(t, `endpoints:
+    - name: primary
+      url: https://api.example.com
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+      tags: [coding]
+tagging:
+    taggers:
+        - name: fast-path
+          type: builtin
+          builtin_type: path
+          tag: fast
+          enabled: true
+          config:
+              path_pattern: /v1/messages
+`)
+
+	tests := []struct {
+		tags     []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{"fast", "coding"}, "coding,fast"},
+		{[]string{"coding", "user-123", "user-456"}, "coding,other"},
+	}
+	for _, tt := range tests {
+		if got := server.metricsTagLabel(tt.tags); got != tt.expected {
+			t.Errorf("metricsTagLabel(%v) = %q, want %q", tt.tags, got, tt.expected)
+		}
+	}
+	if got := server.metricsEndpointLabel("removed-endpoint"); got != otherLabel {
+		t.Errorf("expected unknown endpoint to be labelled other, got %q", got)
+	}
+}
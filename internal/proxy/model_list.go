@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"claude-code-companion/internal/endpoint"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondModelListLocally 启用 model_discovery.serve_model_list 时，用请求可以路由到的端点的模型应答 GET /v1/models；
+// 还没有获取到任何模型列表或没有可路由的模型时返回 false，请求照常转发到上游
+func (s *Server) respondModelListLocally(c *gin.Context, path string, requestID string) bool {
+	if c.Request.Method != http.MethodGet || strings.TrimSuffix(path, "/") != "/models" {
+		return false
+	}
+	if !s.config.ModelDiscovery.Enabled || !s.config.ModelDiscovery.ServeModelList {
+		return false
+	}
+
+	var requestTags []string
+	if taggedRequest := s.processRequestTags(c.Request); taggedRequest != nil {
+		requestTags = taggedRequest.Tags
+	}
+	models := s.routableModels(requestTags)
+	if len(models) == 0 {
+		return false
+	}
+
+	// Anthropic 模型列表格式
+	data := make([]gin.H, 0, len(models))
+	for _, model := range models {
+		data = append(data, gin.H{
+			"type":         "model",
+			"id":           model.id,
+			"display_name": modelDisplayName(model.id),
+			"created_at":   model.createdAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	s.logger.Debug(fmt.Sprintf("Request %s: model list answered locally with %d routable models (tags: %v)", requestID, len(models), requestTags))
+	c.JSON(http.StatusOK, gin.H{
+		"data":     data,
+		"has_more": false,
+		"first_id": models[0].id,
+		"last_id":  models[len(models)-1].id,
+	})
+	return true
+}
+
+// routableModel 客户端可以直接使用的模型名，createdAt 为代理最早从上游模型列表得知该模型的时间
+type routableModel struct {
+	id        string
+	createdAt time.Time
+}
+
+// routableModels 返回客户端可以直接使用的模型名，按模型名排序：
+//   - 只包含带这些标签的请求可以路由到的启用端点（与故障转移候选相同：包含全部请求标签的端点和无标签的通用端点）
+//   - 不含通配符的模型重写源模型
+//   - 不需要格式转换的端点上，经过模型重写后仍保持原名的上游模型；OpenAI、Gemini 端点的上游模型名客户端无法直接使用，不列出
+//
+// 没有任何端点获取过模型列表时返回nil
+func (s *Server) routableModels(requestTags []string) []routableModel {
+	var eligible []*endpoint.Endpoint
+	var latestFetch time.Time
+	for _, ep := range s.endpointManager.GetAllEndpoints() {
+		if !ep.IsEnabled() {
+			continue
+		}
+		if catalog := ep.GetModelCatalog(); catalog != nil && catalog.FetchedAt.After(latestFetch) {
+			latestFetch = catalog.FetchedAt
+		}
+		if len(ep.Tags) == 0 || (len(requestTags) > 0 && s.endpointContainsAllTags(ep.Tags, requestTags)) {
+			eligible = append(eligible, ep)
+		}
+	}
+	if latestFetch.IsZero() {
+		return nil
+	}
+
+	createdAt := make(map[string]time.Time)
+	add := func(id string, fetchedAt time.Time) {
+		if existing, ok := createdAt[id]; !ok || fetchedAt.Before(existing) {
+			createdAt[id] = fetchedAt
+		}
+	}
+	for _, ep := range eligible {
+		catalog := ep.GetModelCatalog()
+		fetchedAt := latestFetch
+		if catalog != nil && !catalog.FetchedAt.IsZero() {
+			fetchedAt = catalog.FetchedAt
+		}
+
+		if ep.ModelRewrite != nil && ep.ModelRewrite.Enabled {
+			for _, rule := range ep.ModelRewrite.Rules {
+				if rule.SourcePattern != "" && !strings.ContainsAny(rule.SourcePattern, "*?[") {
+					add(rule.SourcePattern, fetchedAt)
+				}
+			}
+		}
+		if catalog == nil || ep.EndpointType == "openai" || ep.EndpointType == "openai_responses" || ep.EndpointType == "gemini" {
+			continue
+		}
+		for _, id := range catalog.Models {
+			if s.modelRewriter.RewriteModel(id, ep.ModelRewrite, ep.Tags) == id {
+				add(id, fetchedAt)
+			}
+		}
+	}
+
+	models := make([]routableModel, 0, len(createdAt))
+	for id, fetchedAt := range createdAt {
+		models = append(models, routableModel{id: id, createdAt: fetchedAt})
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].id < models[j].id })
+	return models
+}
+
+// modelDisplayName 由模型名生成展示名称：去掉日期后缀，相邻的版本号用点连接，各段首字母大写，
+// 例如 claude-3-5-sonnet-20241022 显示为 Claude 3.5 Sonnet
+func modelDisplayName(id string) string {
+	parts := strings.Split(id, "-")
+	if last := parts[len(parts)-1]; len(parts) > 1 && len(last) == 8 && isDigits(last) {
+		parts = parts[:len(parts)-1]
+	}
+
+	var words []string
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		if n := len(words); n > 0 && isDigits(part) && len(part) <= 2 && isVersion(words[n-1]) {
+			words[n-1] += "." + part
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		words = append(words, string(runes))
+	}
+	if len(words) == 0 {
+		return id
+	}
+	return strings.Join(words, " ")
+}
+
+// isVersion 判断是否为数字和点组成的版本号
+func isVersion(s string) bool {
+	return isDigits(strings.ReplaceAll(s, ".", ""))
+}
+
+// isDigits 判断字符串是否只包含数字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
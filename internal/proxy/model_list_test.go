@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// modelListUpstream 返回固定模型列表的上游
+func modelListUpstream(t *testing.T, ids ...string) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := make([]map[string]string, 0, len(ids))
+		for _, id := range ids {
+			data = append(data, map[string]string{"id": id})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "has_more": false})
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func TestModelListServedLocally(t *testing.T) {
+	anthropic := modelListUpstream(t, "claude-sonnet-4-5-20250929", "claude-opus-4-1-20250805", "claude-instant-1")
+	openai := modelListUpstream(t, "gpt-4o", "gpt-4o-mini")
+	tagged := modelListUpstream(t, "claude-coding-preview")
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: anthropic
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+      model_rewrite:
+          enabled: true
+          rules:
+              - source_pattern: claude-instant-*
+                target_model: claude-sonnet-4-5-20250929
+    - name: openai
+      url: %s
+      endpoint_type: openai
+      path_prefix: /v1/chat/completions
+      auth_type: auth_token
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+      model_rewrite:
+          enabled: true
+          rules:
+              - source_pattern: claude-3-5-haiku
+                target_model: gpt-4o-mini
+              - source_pattern: claude-*
+                target_model: gpt-4o
+    - name: coding
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 3
+      tags: [coding]
+tagging:
+    taggers:
+        - name: coding-header
+          type: builtin
+          builtin_type: header
+          tag: coding
+          enabled: true
+          config:
+              header_name: X-Project
+              expected_value: coding
+model_discovery:
+    enabled: true
+    serve_model_list: true
+`, anthropic.URL, openai.URL, tagged.URL))
+
+	before := time.Now().Add(-time.Second)
+	for _, ep := range server.endpointManager.GetAllEndpoints() {
+		if err := server.endpointManager.RefreshModels(ep); err != nil {
+			t.Fatalf("failed to refresh models of %s: %v", ep.Name, err)
+		}
+	}
+
+	list := func(project string) []map[string]string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		if project != "" {
+			req.Header.Set("X-Project", project)
+		}
+		recorder := httptest.NewRecorder()
+		server.GetRouter().ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected model list to be served locally, got %d: %s", recorder.Code, recorder.Body.String())
+		}
+		var response struct {
+			Data []map[string]string `json:"data"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to parse model list: %v", err)
+		}
+		return response.Data
+	}
+	ids := func(models []map[string]string) []string {
+		var result []string
+		for _, model := range models {
+			result = append(result, model["id"])
+		}
+		return result
+	}
+
+	// OpenAI 端点的上游模型名和被重写掉的上游模型不会列出，标签端点只对带标签的请求列出
+	models := list("")
+	expected := []string{"claude-3-5-haiku", "claude-opus-4-1-20250805", "claude-sonnet-4-5-20250929"}
+	if got := ids(models); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("expected routable models %v, got %v", expected, got)
+	}
+	for _, model := range models {
+		createdAt, err := time.Parse(time.RFC3339, model["created_at"])
+		if err != nil || createdAt.Before(before.Truncate(time.Second)) {
+			t.Errorf("expected created_at to be the catalog fetch time, got %q", model["created_at"])
+		}
+	}
+	if models[2]["display_name"] != "Claude Sonnet 4.5" {
+		t.Errorf("expected display name without date suffix, got %q", models[2]["display_name"])
+	}
+
+	expected = []string{"claude-3-5-haiku", "claude-coding-preview", "claude-opus-4-1-20250805", "claude-sonnet-4-5-20250929"}
+	if got := ids(list("coding")); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected tagged request to also see the tagged endpoint, got %v", got)
+	}
+}
+
+func TestModelDisplayName(t *testing.T) {
+	tests := map[string]string{
+		"claude-3-5-sonnet-20241022": "Claude 3.5 Sonnet",
+		"claude-sonnet-4-5":          "Claude Sonnet 4.5",
+		"claude-opus-4-1-20250805":   "Claude Opus 4.1",
+		"claude-3-haiku-20240307":    "Claude 3 Haiku",
+		"gpt-4o":                     "Gpt 4o",
+	}
+	for id, expected := range tests {
+		if got := modelDisplayName(id); got != expected {
+			t.Errorf("modelDisplayName(%q) = %q, want %q", id, got, expected)
+		}
+	}
+}
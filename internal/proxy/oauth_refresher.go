@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"fmt"
+	"time"
+
+	"claude-code-companion/internal/config"
+)
+
+// runOAuthRefresher 定期在 OAuth token 过期前主动刷新并持久化，避免请求时才同步刷新；
+// 刷新失败的端点在 RefreshRetryInterval 后再试，请求时的刷新逻辑仍然作为兜底
+func (s *Server) runOAuthRefresher() {
+	ticker := time.NewTicker(config.Default.OAuth.RefreshCheckInterval)
+	defer ticker.Stop()
+
+	lastFailure := make(map[string]time.Time)
+	for range ticker.C {
+		now := time.Now()
+		for _, ep := range s.endpointManager.GetAllEndpoints() {
+			if !ep.NeedsOAuthRefresh(config.Default.OAuth.RefreshAhead) {
+				delete(lastFailure, ep.ID)
+				continue
+			}
+			if failedAt, ok := lastFailure[ep.ID]; ok && now.Sub(failedAt) < config.Default.OAuth.RefreshRetryInterval {
+				continue
+			}
+
+			if err := ep.RefreshOAuthTokenWithCallback(s.config.Timeouts.ToProxyTimeoutConfig(), s.createOAuthTokenRefreshCallback()); err != nil {
+				lastFailure[ep.ID] = now
+				s.logger.Error(fmt.Sprintf("Background OAuth token refresh failed for endpoint %s", ep.Name), err)
+				continue
+			}
+			delete(lastFailure, ep.ID)
+			s.logger.Info(fmt.Sprintf("OAuth token for endpoint %s refreshed in background", ep.Name))
+		}
+	}
+}
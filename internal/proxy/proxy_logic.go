@@ -14,28 +14,45 @@ import (
 
 	"claude-code-companion/internal/conversion"
 	"claude-code-companion/internal/endpoint"
+	"claude-code-companion/internal/statistics"
 	"claude-code-companion/internal/tagging"
-	"claude-code-companion/internal/utils"
+	"claude-code-companion/internal/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
-func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path string, requestBody []byte, requestID string, startTime time.Time, taggedRequest *tagging.TaggedRequest, attemptNumber int) (bool, bool) {
-	// 检查是否为 count_tokens 请求到 OpenAI 端点
+func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path string, requestBody []byte, requestID string, startTime time.Time, taggedRequest *tagging.TaggedRequest, attemptNumber int) (success bool, shouldRetry bool) {
+	// 检查是否为 count_tokens 请求到需要格式转换的端点（OpenAI / Gemini）
 	isCountTokensRequest := strings.Contains(path, "/count_tokens")
-	isOpenAIEndpoint := ep.EndpointType == "openai"
+	isOpenAIEndpoint := ep.EndpointType == "openai" || ep.EndpointType == "openai_responses"
 	
-	// OpenAI 端点不支持 count_tokens，立即尝试下一个端点
-	if isCountTokensRequest && isOpenAIEndpoint {
-		s.logger.Debug(fmt.Sprintf("Skipping count_tokens request on OpenAI endpoint %s", ep.Name))
+	// 需要格式转换的端点不支持 count_tokens，立即尝试下一个端点
+	if isCountTokensRequest && s.converter.ShouldConvert(ep.EndpointType) {
+		s.logger.Debug(fmt.Sprintf("Skipping count_tokens request on %s endpoint %s", ep.EndpointType, ep.Name))
 		// 标记这次尝试为特殊情况，不记录健康统计，不记录日志（除非所有端点都因此失败）
 		c.Set("skip_health_record", true)
 		c.Set("skip_logging", true)
 		c.Set("count_tokens_openai_skip", true)
-		c.Set("last_error", fmt.Errorf("count_tokens not supported on OpenAI endpoint"))
+		c.Set("last_error", fmt.Errorf("count_tokens not supported on %s endpoint", ep.EndpointType))
 		c.Set("last_status_code", http.StatusNotFound)
 		return false, true // 立即尝试下一个端点
 	}
+	// 记录进行中的请求数，供 least_inflight / ewma 负载均衡策略使用
+	ep.BeginRequest()
+	defer ep.EndRequest()
+	// 最后尝试的端点，请求结束后计入该端点的用量汇总
+	c.Set("served_endpoint", ep.Name)
+
+	// 每次端点尝试一个 span，各处理阶段作为它的子 span
+	ctx, attemptSpan := tracing.Start(c.Request.Context(), "endpoint attempt",
+		attribute.String("endpoint.name", ep.Name),
+		attribute.String("endpoint.type", ep.EndpointType),
+		attribute.Int("attempt", attemptNumber),
+	)
+	defer func() { endAttemptSpan(c, attemptSpan, success) }()
+	
 	// 为这个端点记录独立的开始时间
 	endpointStartTime := time.Now()
 	targetURL := ep.GetFullURL(path)
@@ -61,7 +78,12 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	}
 
 	// 应用模型重写（如果配置了）
+	_, rewriteSpan := tracing.Start(ctx, "model rewrite")
 	originalModel, rewrittenModel, err := s.modelRewriter.RewriteRequestWithTags(tempReq, ep.ModelRewrite, ep.Tags)
+	if rewrittenModel != "" {
+		rewriteSpan.SetAttributes(attribute.String("model.original", originalModel), attribute.String("model.rewritten", rewrittenModel))
+	}
+	tracing.EndWithError(rewriteSpan, err)
 	if err != nil {
 		s.logger.Error("Model rewrite failed", err)
 		// 记录模型重写失败的日志
@@ -102,7 +124,9 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 			MaxTokensFieldName: ep.MaxTokensFieldName,
 		}
 		
-		convertedBody, ctx, err := s.converter.ConvertRequest(finalRequestBody, endpointInfo)
+		_, convertSpan := tracing.Start(ctx, "request conversion", attribute.String("endpoint.type", ep.EndpointType))
+		convertedBody, convCtx, err := s.converter.ConvertRequest(finalRequestBody, endpointInfo)
+		tracing.EndWithError(convertSpan, err)
 		if err != nil {
 			s.logger.Error("Request format conversion failed", err)
 			duration := time.Since(endpointStartTime)
@@ -115,7 +139,12 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 			return false, false // 不重试，直接返回
 		}
 		finalRequestBody = convertedBody
-		conversionContext = ctx
+		conversionContext = convCtx
+
+		// Gemini 的模型名和流式方式都体现在URL中，只能在转换之后确定
+		if ep.EndpointType == "gemini" {
+			targetURL = ep.GetGeminiURL(convCtx.Model, convCtx.IsStreaming)
+		}
 		s.logger.Debug("Request format converted successfully", map[string]interface{}{
 			"endpoint_type": ep.EndpointType,
 			"original_size": len(requestBody),
@@ -123,8 +152,25 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		})
 	}
 
+	// Anthropic 端点无法校验由 OpenAI 兼容上游转换出的占位签名，发送前删除这类 thinking 块
+	if !s.converter.ShouldConvert(ep.EndpointType) {
+		strippedBody, strip, err := conversion.StripPlaceholderThinking(finalRequestBody)
+		if err != nil {
+			s.logger.Debug("Failed to strip placeholder thinking blocks", map[string]interface{}{
+				"error": err.Error(),
+			})
+			// 不返回错误，继续使用原始请求体
+		} else if strip.Changed {
+			finalRequestBody = strippedBody
+			s.logger.Debug(fmt.Sprintf("Removed %d placeholder-signed thinking blocks from request to endpoint %s", strip.RemovedBlocks, ep.Name))
+			if strip.ThinkingDisabled {
+				s.logger.Info(fmt.Sprintf("Request %s: extended thinking disabled for endpoint %s because the last assistant message started with a placeholder-signed thinking block", requestID, ep.Name))
+			}
+		}
+	}
+
 	// OpenAI user 参数长度限制 hack（在格式转换之后，参数覆盖之前）
-	if ep.EndpointType == "openai" {
+	if isOpenAIEndpoint {
 		hackedBody, err := s.applyOpenAIUserLengthHack(finalRequestBody)
 		if err != nil {
 			s.logger.Debug("Failed to apply OpenAI user length hack", map[string]interface{}{
@@ -190,8 +236,17 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	}
 
 	// 根据认证类型设置不同的认证头部
+	if ep.EndpointType == "gemini" {
+		// 客户端的 Anthropic 凭据不应转发给 Google
+		req.Header.Del("x-api-key")
+	}
+	// 多凭据端点按 key_rotation 选择本次使用的凭据，结果按凭据记录
+	keyIndex, authValue := ep.SelectAPIKey()
+	c.Set("api_key_index", keyIndex)
 	if ep.AuthType == "api_key" {
-		req.Header.Set("x-api-key", ep.AuthValue)
+		req.Header.Set(ep.GetAPIKeyHeaderName(), authValue)
+	} else if keyIndex >= 0 {
+		req.Header.Set("Authorization", "Bearer "+authValue)
 	} else {
 		authHeader, err := ep.GetAuthHeaderWithRefreshCallback(s.config.Timeouts.ToProxyTimeoutConfig(), s.createOAuthTokenRefreshCallback())
 		if err != nil {
@@ -206,7 +261,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	}
 
 	// Special OAuth header hack for api.anthropic.com with OAuth tokens
-	if strings.Contains(ep.URL, "api.anthropic.com") && ep.AuthType == "auth_token" && strings.HasPrefix(ep.AuthValue, "sk-ant-oat01") {
+	if strings.Contains(ep.URL, "api.anthropic.com") && ep.AuthType == "auth_token" && strings.HasPrefix(authValue, "sk-ant-oat01") {
 		if existingBeta := req.Header.Get("Anthropic-Beta"); existingBeta != "" {
 			// Prepend oauth-2025-04-20 to existing Anthropic-Beta header
 			req.Header.Set("Anthropic-Beta", "oauth-2025-04-20,"+existingBeta)
@@ -231,7 +286,13 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		}
 	}
 
-	if c.Request.URL.RawQuery != "" {
+	// 对冲请求落败时需要能够取消正在进行的上游请求
+	if _, hedged := hedgeAttemptFromContext(c); hedged {
+		req = req.WithContext(c.Request.Context())
+	}
+
+	// Gemini 的查询参数（alt=sse）由 GetGeminiURL 决定，不使用客户端的查询参数
+	if c.Request.URL.RawQuery != "" && ep.EndpointType != "gemini" {
 		req.URL.RawQuery = c.Request.URL.RawQuery
 	}
 
@@ -247,9 +308,25 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		return false, true
 	}
 
+	// 上游请求 span，traceparent 随请求传播到上游
+	_, upstreamSpan := tracing.StartClient(ctx, req, "upstream "+req.Method,
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.full", targetURL),
+	)
 	resp, err := client.Do(req)
+	if err == nil {
+		upstreamSpan.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			upstreamSpan.SetStatus(codes.Error, resp.Status)
+		}
+	}
+	tracing.EndWithError(upstreamSpan, err)
 	if err != nil {
 		duration := time.Since(endpointStartTime)
+		// 连接失败或超时计为惩罚延迟；客户端断开或对冲落败取消的请求不计入
+		if c.Request.Context().Err() == nil {
+			s.endpointManager.RecordFailedLatency(ep, duration)
+		}
 		s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, nil, nil, duration, err, s.isRequestExpectingStream(req), tags, "", originalModel, rewrittenModel, attemptNumber)
 		// 设置错误信息到context中，供重试逻辑使用
 		c.Set("last_error", err)
@@ -289,6 +366,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 				// 设置错误信息到context中
 				c.Set("last_error", fmt.Errorf("OAuth token refresh failed: %v", refreshErr))
 				c.Set("last_status_code", resp.StatusCode)
+				c.Set("last_response_body", decompressedBody)
 				return false, true
 			} else {
 				s.logger.Info(fmt.Sprintf("OAuth token refreshed successfully for endpoint %s, retrying request", ep.Name))
@@ -321,9 +399,26 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		// 设置状态码到context中，供重试逻辑使用
 		c.Set("last_error", nil)
 		c.Set("last_status_code", resp.StatusCode)
+		c.Set("last_response_body", decompressedBody)
+		c.Set("last_retry_after", resp.Header.Get("Retry-After"))
+		s.trackRateLimitHeaders(ep, resp.StatusCode, resp.Header, requestID)
+		s.endpointManager.RecordFailedLatency(ep, duration)
 		return false, true
 	}
 
+	// 记录响应头延迟，供 ewma 负载均衡策略使用
+	s.endpointManager.RecordLatency(ep, time.Since(endpointStartTime))
+
+	// 流式响应：逐事件转发给客户端，不再等待上游完整结束
+	if s.shouldStreamResponse(resp, path) {
+		_, streamSpan := tracing.Start(ctx, "stream response")
+		defer streamSpan.End()
+		return s.streamSSEResponse(c, ep, path, req, resp, requestID, requestBody, finalRequestBody, endpointStartTime, tags, originalModel, rewrittenModel, attemptNumber, conversionContext, taggedRequest)
+	}
+
+	// 非流式响应收到响应头即停止对冲计时
+	s.markHedgeResponded(c)
+
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		s.logger.Error("Failed to read response body", err)
@@ -387,9 +482,14 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 			s.logger.Error("Failed to process rate limit headers", err)
 		}
 	}
+	// 所有提供商的速率限制头部：记录剩余额度，接近耗尽时跳过该端点
+	s.trackRateLimitHeaders(ep, resp.StatusCode, resp.Header, requestID)
 	
 	// 严格 Anthropic 格式验证已永久启用
-	if err := s.validator.ValidateResponseWithPath(decompressedBody, isStreaming, ep.EndpointType, path); err != nil {
+	_, validateSpan := tracing.Start(ctx, "response validation")
+	validationErr := s.validator.ValidateResponseWithPath(decompressedBody, isStreaming, ep.EndpointType, path)
+	tracing.EndWithError(validateSpan, validationErr)
+	if err := validationErr; err != nil {
 		// 如果是usage统计验证失败，尝试下一个endpoint
 		if strings.Contains(err.Error(), "invalid usage stats") {
 			s.logger.Info(fmt.Sprintf("Usage validation failed for endpoint %s: %v", ep.Name, err))
@@ -431,7 +531,9 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	convertedResponseBody := decompressedBody
 	if conversionContext != nil {
 		s.logger.Info(fmt.Sprintf("Starting response conversion. Streaming: %v, OriginalSize: %d", isStreaming, len(decompressedBody)))
+		_, convertSpan := tracing.Start(ctx, "response conversion", attribute.String("endpoint.type", conversionContext.EndpointType))
 		convertedResp, err := s.converter.ConvertResponse(decompressedBody, conversionContext, isStreaming)
+		tracing.EndWithError(convertSpan, err)
 		if err != nil {
 			s.logger.Error("Response format conversion failed", err)
 			// Response转换失败，记录错误并尝试下一个端点
@@ -501,109 +603,11 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	c.Set("last_error", nil)
 	c.Set("last_status_code", resp.StatusCode)
 
+	// 累计端点预算用量
+	s.recordEndpointUsage(c, ep, requestID, decompressedBody)
+
 	duration := time.Since(endpointStartTime)
-	// 创建日志条目，记录修改前后的完整数据
-	requestLog := s.logger.CreateRequestLog(requestID, ep.URL, c.Request.Method, path)
-	requestLog.RequestBodySize = len(requestBody)
-	requestLog.Tags = tags
-	requestLog.ContentTypeOverride = overrideInfo
-	requestLog.AttemptNumber = attemptNumber
-	
-	// 设置 thinking 信息
-	if thinkingInfo, exists := c.Get("thinking_info"); exists {
-		if info, ok := thinkingInfo.(*utils.ThinkingInfo); ok && info != nil {
-			requestLog.ThinkingEnabled = info.Enabled
-			requestLog.ThinkingBudgetTokens = info.BudgetTokens
-		}
-	}
-	
-	// 记录原始客户端请求数据
-	requestLog.OriginalRequestURL = c.Request.URL.String()
-	requestLog.OriginalRequestHeaders = utils.HeadersToMap(c.Request.Header)
-	if len(requestBody) > 0 {
-		if s.config.Logging.LogRequestBody != "none" {
-			if s.config.Logging.LogRequestBody == "truncated" {
-				requestLog.OriginalRequestBody = utils.TruncateBody(string(requestBody), 1024)
-			} else {
-				requestLog.OriginalRequestBody = string(requestBody)
-			}
-		}
-	}
-	
-	// 记录最终发送给上游的请求数据
-	requestLog.FinalRequestURL = req.URL.String()
-	requestLog.FinalRequestHeaders = utils.HeadersToMap(req.Header)
-	if len(finalRequestBody) > 0 {
-		if s.config.Logging.LogRequestBody != "none" {
-			if s.config.Logging.LogRequestBody == "truncated" {
-				requestLog.FinalRequestBody = utils.TruncateBody(string(finalRequestBody), 1024)
-			} else {
-				requestLog.FinalRequestBody = string(finalRequestBody)
-			}
-		}
-	}
-	
-	// 记录上游原始响应数据
-	requestLog.OriginalResponseHeaders = utils.HeadersToMap(resp.Header)
-	if len(decompressedBody) > 0 {
-		if s.config.Logging.LogResponseBody != "none" {
-			if s.config.Logging.LogResponseBody == "truncated" {
-				requestLog.OriginalResponseBody = utils.TruncateBody(string(decompressedBody), 1024)
-			} else {
-				requestLog.OriginalResponseBody = string(decompressedBody)
-			}
-		}
-	}
-	
-	// 记录最终发送给客户端的响应数据
-	finalHeaders := make(map[string]string)
-	for key := range resp.Header {
-		values := c.Writer.Header().Values(key)
-		if len(values) > 0 {
-			finalHeaders[key] = values[0]
-		}
-	}
-	requestLog.FinalResponseHeaders = finalHeaders
-	if len(finalResponseBody) > 0 {
-		if s.config.Logging.LogResponseBody != "none" {
-			if s.config.Logging.LogResponseBody == "truncated" {
-				requestLog.FinalResponseBody = utils.TruncateBody(string(finalResponseBody), 1024)
-			} else {
-				requestLog.FinalResponseBody = string(finalResponseBody)
-			}
-		}
-	}
-	
-	// 设置兼容性字段
-	requestLog.RequestHeaders = requestLog.FinalRequestHeaders
-	requestLog.RequestBody = requestLog.OriginalRequestBody
-	requestLog.ResponseHeaders = requestLog.OriginalResponseHeaders
-	requestLog.ResponseBody = requestLog.OriginalResponseBody
-	
-	// 设置模型信息
-	if len(requestBody) > 0 {
-		extractedModel := utils.ExtractModelFromRequestBody(string(requestBody))
-		if originalModel != "" {
-			requestLog.Model = originalModel
-			requestLog.OriginalModel = originalModel
-		} else {
-			requestLog.Model = extractedModel
-			requestLog.OriginalModel = extractedModel
-		}
-		
-		if rewrittenModel != "" {
-			requestLog.RewrittenModel = rewrittenModel
-			requestLog.ModelRewriteApplied = rewrittenModel != requestLog.OriginalModel
-		}
-		
-		// 提取 Session ID
-		requestLog.SessionID = utils.ExtractSessionIDFromRequestBody(string(requestBody))
-	}
-	
-	// 更新基本字段
-	s.logger.UpdateRequestLog(requestLog, req, resp, decompressedBody, duration, nil)
-	requestLog.IsStreaming = isStreaming
-	s.logger.LogRequest(requestLog)
+	s.logSuccessfulRequest(requestID, ep, path, c, req, resp, requestBody, finalRequestBody, decompressedBody, finalResponseBody, duration, isStreaming, tags, overrideInfo, originalModel, rewrittenModel, attemptNumber)
 
 	return true, false
 }
@@ -860,7 +864,7 @@ func (s *Server) processRateLimitHeaders(ep *endpoint.Endpoint, headers http.Hea
 			"enhanced_protection": true,
 			"request_id": requestID,
 		})
-		ep.MarkInactive()
+		ep.MarkInactive(statistics.CauseRateLimit, "enhanced protection: rate limit status allowed_warning")
 	}
 	
 	return nil
@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/endpoint"
+)
+
+// RateLimitHeaderParser 解析一类上游速率限制响应头，没有该类头部时返回 false
+type RateLimitHeaderParser interface {
+	Name() string
+	Parse(statusCode int, headers http.Header, now time.Time) (endpoint.RateLimitCapacity, bool)
+}
+
+// rateLimitHeaderParsers 已注册的解析器，对每个响应依次执行并合并结果
+var rateLimitHeaderParsers []RateLimitHeaderParser
+
+// RegisterRateLimitHeaderParser 注册一个速率限制响应头解析器
+func RegisterRateLimitHeaderParser(parser RateLimitHeaderParser) {
+	rateLimitHeaderParsers = append(rateLimitHeaderParsers, parser)
+}
+
+func init() {
+	RegisterRateLimitHeaderParser(anthropicRateLimitParser{})
+	RegisterRateLimitHeaderParser(openAIRateLimitParser{})
+	RegisterRateLimitHeaderParser(retryAfterRateLimitParser{})
+}
+
+// parseRateLimitHeaders 用所有解析器解析响应头并合并为一个额度，每个维度取剩余额度更少的结果
+func parseRateLimitHeaders(statusCode int, headers http.Header, now time.Time) (endpoint.RateLimitCapacity, bool) {
+	var merged endpoint.RateLimitCapacity
+	var sources []string
+	for _, parser := range rateLimitHeaderParsers {
+		capacity, ok := parser.Parse(statusCode, headers, now)
+		if !ok {
+			continue
+		}
+		sources = append(sources, parser.Name())
+		merged.Requests = tighterDimension(merged.Requests, capacity.Requests)
+		merged.Tokens = tighterDimension(merged.Tokens, capacity.Tokens)
+	}
+	if len(sources) == 0 {
+		return merged, false
+	}
+	merged.Source = strings.Join(sources, "+")
+	return merged, true
+}
+
+func tighterDimension(current, other *endpoint.RateLimitDimension) *endpoint.RateLimitDimension {
+	if current == nil {
+		return other
+	}
+	if other == nil || other.Remaining > current.Remaining {
+		return current
+	}
+	if other.Remaining == current.Remaining && other.ResetAt.Before(current.ResetAt) {
+		return current
+	}
+	return other
+}
+
+// parseDimension 读取 limit / remaining / reset 三个头部，remaining 缺失或无法解析时返回 nil
+func parseDimension(headers http.Header, limitKey, remainingKey, resetKey string, now time.Time) *endpoint.RateLimitDimension {
+	remaining, err := strconv.ParseInt(strings.TrimSpace(headers.Get(remainingKey)), 10, 64)
+	if err != nil {
+		return nil
+	}
+	dimension := &endpoint.RateLimitDimension{Remaining: remaining}
+	if limit, err := strconv.ParseInt(strings.TrimSpace(headers.Get(limitKey)), 10, 64); err == nil {
+		dimension.Limit = limit
+	}
+	if resetAt, ok := parseRateLimitReset(headers.Get(resetKey), now); ok {
+		dimension.ResetAt = resetAt
+	}
+	return dimension
+}
+
+// parseRateLimitReset 解析重置时间，支持 RFC3339、Go 时长（"1s"、"6m0s"、"20ms"）、
+// Unix 秒/毫秒时间戳以及相对秒数
+func parseRateLimitReset(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		switch {
+		case seconds > 1e12:
+			return time.UnixMilli(int64(seconds)), true
+		case seconds > 1e9:
+			return time.Unix(int64(seconds), 0), true
+		default:
+			return now.Add(time.Duration(seconds * float64(time.Second))), true
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(d), true
+	}
+	return time.Time{}, false
+}
+
+// anthropicRateLimitParser 解析 Anthropic API 的 anthropic-ratelimit-requests-* / tokens-* 头部，
+// 以及订阅帐号的 Anthropic-Ratelimit-Unified-Status（rejected 表示额度耗尽直到 Unified-Reset）
+type anthropicRateLimitParser struct{}
+
+func (anthropicRateLimitParser) Name() string { return "anthropic" }
+
+func (anthropicRateLimitParser) Parse(statusCode int, headers http.Header, now time.Time) (endpoint.RateLimitCapacity, bool) {
+	capacity := endpoint.RateLimitCapacity{
+		Requests: parseDimension(headers, "Anthropic-Ratelimit-Requests-Limit", "Anthropic-Ratelimit-Requests-Remaining", "Anthropic-Ratelimit-Requests-Reset", now),
+		Tokens:   parseDimension(headers, "Anthropic-Ratelimit-Tokens-Limit", "Anthropic-Ratelimit-Tokens-Remaining", "Anthropic-Ratelimit-Tokens-Reset", now),
+	}
+	if strings.EqualFold(headers.Get("Anthropic-Ratelimit-Unified-Status"), "rejected") {
+		if resetAt, ok := parseRateLimitReset(headers.Get("Anthropic-Ratelimit-Unified-Reset"), now); ok {
+			capacity.Requests = tighterDimension(capacity.Requests, &endpoint.RateLimitDimension{Remaining: 0, ResetAt: resetAt})
+		}
+	}
+	return capacity, capacity.Requests != nil || capacity.Tokens != nil
+}
+
+// openAIRateLimitParser 解析 OpenAI 兼容提供商的 x-ratelimit-{limit,remaining,reset}-{requests,tokens} 头部，
+// 以及不区分维度的 x-ratelimit-limit / remaining / reset（按请求数处理）
+type openAIRateLimitParser struct{}
+
+func (openAIRateLimitParser) Name() string { return "openai" }
+
+func (openAIRateLimitParser) Parse(statusCode int, headers http.Header, now time.Time) (endpoint.RateLimitCapacity, bool) {
+	capacity := endpoint.RateLimitCapacity{
+		Requests: parseDimension(headers, "X-Ratelimit-Limit-Requests", "X-Ratelimit-Remaining-Requests", "X-Ratelimit-Reset-Requests", now),
+		Tokens:   parseDimension(headers, "X-Ratelimit-Limit-Tokens", "X-Ratelimit-Remaining-Tokens", "X-Ratelimit-Reset-Tokens", now),
+	}
+	if capacity.Requests == nil {
+		capacity.Requests = parseDimension(headers, "X-Ratelimit-Limit", "X-Ratelimit-Remaining", "X-Ratelimit-Reset", now)
+	}
+	return capacity, capacity.Requests != nil || capacity.Tokens != nil
+}
+
+// retryAfterRateLimitParser 429/503 响应的 Retry-After 表示在该时间之前没有可用额度
+type retryAfterRateLimitParser struct{}
+
+func (retryAfterRateLimitParser) Name() string { return "retry-after" }
+
+func (retryAfterRateLimitParser) Parse(statusCode int, headers http.Header, now time.Time) (endpoint.RateLimitCapacity, bool) {
+	if statusCode != http.StatusTooManyRequests && statusCode != http.StatusServiceUnavailable {
+		return endpoint.RateLimitCapacity{}, false
+	}
+	delay, ok := parseRetryAfter(headers.Get("Retry-After"))
+	if !ok || delay <= 0 {
+		return endpoint.RateLimitCapacity{}, false
+	}
+	return endpoint.RateLimitCapacity{
+		Requests: &endpoint.RateLimitDimension{Remaining: 0, ResetAt: now.Add(delay)},
+	}, true
+}
+
+// trackRateLimitHeaders 把响应中的速率限制头部记录到端点的剩余额度，耗尽状态变化时持久化到配置文件
+func (s *Server) trackRateLimitHeaders(ep *endpoint.Endpoint, statusCode int, headers http.Header, requestID string) {
+	// 多凭据端点的额度属于单个凭据，由凭据轮换处理，不代表整个端点耗尽
+	if ep.HasMultipleAPIKeys() {
+		return
+	}
+	capacity, ok := parseRateLimitHeaders(statusCode, headers, time.Now())
+	if !ok {
+		return
+	}
+	if !ep.UpdateRateLimitCapacity(capacity, s.config.RateLimitTracking.MinRemainingRatio) {
+		return
+	}
+
+	snapshot := ep.RateLimitCapacityConfig()
+	if snapshot != nil {
+		s.logger.Info(fmt.Sprintf("Endpoint %s upstream rate limit nearly exhausted until %s: %s",
+			ep.Name, time.Unix(snapshot.ExhaustedUntil, 0).Format(time.RFC3339), snapshot.Reason), map[string]interface{}{
+			"request_id": requestID,
+		})
+	} else {
+		s.logger.Info(fmt.Sprintf("Endpoint %s upstream rate limit capacity recovered", ep.Name), map[string]interface{}{
+			"request_id": requestID,
+		})
+	}
+	if err := s.persistRateLimitCapacity(ep.ID, snapshot); err != nil {
+		s.logger.Error("Failed to persist rate limit capacity", err)
+	}
+}
+
+// rateLimitExhaustionError 启用 skip_near_exhaustion 且端点的上游额度接近耗尽时返回跳过原因，否则返回nil
+func (s *Server) rateLimitExhaustionError(ep *endpoint.Endpoint) error {
+	if !s.config.RateLimitTracking.SkipNearExhaustion {
+		return nil
+	}
+	until, reason := ep.RateLimitExhaustion()
+	if until.IsZero() {
+		return nil
+	}
+	return fmt.Errorf("endpoint rate limit nearly exhausted until %s: %s", until.Format(time.RFC3339), reason)
+}
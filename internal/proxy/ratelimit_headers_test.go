@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/endpoint"
+)
+
+func TestParseRateLimitReset(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Time
+		ok       bool
+	}{
+		{"RFC3339", "2026-01-02T03:05:00Z", time.Date(2026, 1, 2, 3, 5, 0, 0, time.UTC), true},
+		{"Go duration", "6m0s", now.Add(6 * time.Minute), true},
+		{"Go duration in milliseconds", "20ms", now.Add(20 * time.Millisecond), true},
+		{"relative seconds", "30", now.Add(30 * time.Second), true},
+		{"fractional relative seconds", "1.5", now.Add(1500 * time.Millisecond), true},
+		{"relative seconds at 1e9 threshold", "1000000000", now.Add(1e9 * time.Second), true},
+		{"Unix seconds above 1e9", "1767323160", time.Unix(1767323160, 0), true},
+		{"Unix seconds at 1e12 threshold", "1000000000000", time.Unix(1000000000000, 0), true},
+		{"Unix milliseconds above 1e12", "1767323160500", time.UnixMilli(1767323160500), true},
+		{"surrounding whitespace", " 10 ", now.Add(10 * time.Second), true},
+		{"empty", "", time.Time{}, false},
+		{"negative seconds", "-5", time.Time{}, false},
+		{"negative duration", "-5s", time.Time{}, false},
+		{"garbage", "soon", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRateLimitReset(tt.value, now)
+			if ok != tt.ok || !got.Equal(tt.expected) {
+				t.Errorf("parseRateLimitReset(%q) = (%v, %v), want (%v, %v)", tt.value, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTighterDimension(t *testing.T) {
+	now := time.Now()
+	early := &endpoint.RateLimitDimension{Remaining: 5, ResetAt: now.Add(time.Minute)}
+	late := &endpoint.RateLimitDimension{Remaining: 5, ResetAt: now.Add(time.Hour)}
+	fewer := &endpoint.RateLimitDimension{Remaining: 1, ResetAt: now.Add(time.Second)}
+
+	tests := []struct {
+		name     string
+		current  *endpoint.RateLimitDimension
+		other    *endpoint.RateLimitDimension
+		expected *endpoint.RateLimitDimension
+	}{
+		{"both nil", nil, nil, nil},
+		{"current nil", nil, early, early},
+		{"other nil", early, nil, early},
+		{"fewer remaining wins", early, fewer, fewer},
+		{"more remaining loses", fewer, early, fewer},
+		{"tie keeps later reset", late, early, late},
+		{"tie takes later reset", early, late, late},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tighterDimension(tt.current, tt.other); got != tt.expected {
+				t.Errorf("tighterDimension() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		statusCode int
+		headers    map[string]string
+		ok         bool
+		source     string
+		requests   *endpoint.RateLimitDimension
+		tokens     *endpoint.RateLimitDimension
+	}{
+		{
+			name:       "no rate limit headers",
+			statusCode: 200,
+			headers:    map[string]string{"Content-Type": "application/json"},
+		},
+		{
+			name:       "anthropic requests and tokens",
+			statusCode: 200,
+			headers: map[string]string{
+				"Anthropic-Ratelimit-Requests-Limit":     "50",
+				"Anthropic-Ratelimit-Requests-Remaining": "49",
+				"Anthropic-Ratelimit-Requests-Reset":     "2026-01-02T03:05:00Z",
+				"Anthropic-Ratelimit-Tokens-Limit":       "40000",
+				"Anthropic-Ratelimit-Tokens-Remaining":   "1000",
+				"Anthropic-Ratelimit-Tokens-Reset":       "2026-01-02T03:04:30Z",
+			},
+			ok:       true,
+			source:   "anthropic",
+			requests: &endpoint.RateLimitDimension{Limit: 50, Remaining: 49, ResetAt: time.Date(2026, 1, 2, 3, 5, 0, 0, time.UTC)},
+			tokens:   &endpoint.RateLimitDimension{Limit: 40000, Remaining: 1000, ResetAt: time.Date(2026, 1, 2, 3, 4, 30, 0, time.UTC)},
+		},
+		{
+			name:       "anthropic unified status rejected",
+			statusCode: 429,
+			headers: map[string]string{
+				"Anthropic-Ratelimit-Unified-Status": "rejected",
+				"Anthropic-Ratelimit-Unified-Reset":  "1767330000",
+			},
+			ok:       true,
+			source:   "anthropic",
+			requests: &endpoint.RateLimitDimension{Remaining: 0, ResetAt: time.Unix(1767330000, 0)},
+		},
+		{
+			name:       "anthropic unified status allowed",
+			statusCode: 200,
+			headers: map[string]string{
+				"Anthropic-Ratelimit-Unified-Status": "allowed",
+				"Anthropic-Ratelimit-Unified-Reset":  "1767330000",
+			},
+		},
+		{
+			name:       "openai per dimension headers",
+			statusCode: 200,
+			headers: map[string]string{
+				"X-Ratelimit-Limit-Requests":     "500",
+				"X-Ratelimit-Remaining-Requests": "499",
+				"X-Ratelimit-Reset-Requests":     "120ms",
+				"X-Ratelimit-Limit-Tokens":       "30000",
+				"X-Ratelimit-Remaining-Tokens":   "29000",
+				"X-Ratelimit-Reset-Tokens":       "6m0s",
+			},
+			ok:       true,
+			source:   "openai",
+			requests: &endpoint.RateLimitDimension{Limit: 500, Remaining: 499, ResetAt: now.Add(120 * time.Millisecond)},
+			tokens:   &endpoint.RateLimitDimension{Limit: 30000, Remaining: 29000, ResetAt: now.Add(6 * time.Minute)},
+		},
+		{
+			name:       "openai dimensionless headers count as requests",
+			statusCode: 200,
+			headers: map[string]string{
+				"X-Ratelimit-Limit":     "60",
+				"X-Ratelimit-Remaining": "3",
+				"X-Ratelimit-Reset":     "15",
+			},
+			ok:       true,
+			source:   "openai",
+			requests: &endpoint.RateLimitDimension{Limit: 60, Remaining: 3, ResetAt: now.Add(15 * time.Second)},
+		},
+		{
+			name:       "retry-after on 429",
+			statusCode: 429,
+			headers:    map[string]string{"Retry-After": "30"},
+			ok:         true,
+			source:     "retry-after",
+			requests:   &endpoint.RateLimitDimension{Remaining: 0, ResetAt: now.Add(30 * time.Second)},
+		},
+		{
+			name:       "retry-after on 503",
+			statusCode: 503,
+			headers:    map[string]string{"Retry-After": "5"},
+			ok:         true,
+			source:     "retry-after",
+			requests:   &endpoint.RateLimitDimension{Remaining: 0, ResetAt: now.Add(5 * time.Second)},
+		},
+		{
+			name:       "retry-after ignored on other status codes",
+			statusCode: 500,
+			headers:    map[string]string{"Retry-After": "30"},
+		},
+		{
+			name:       "retry-after merged with anthropic headers",
+			statusCode: 429,
+			headers: map[string]string{
+				"Anthropic-Ratelimit-Requests-Limit":     "50",
+				"Anthropic-Ratelimit-Requests-Remaining": "2",
+				"Anthropic-Ratelimit-Requests-Reset":     "2026-01-02T03:05:00Z",
+				"Retry-After":                            "30",
+			},
+			ok:       true,
+			source:   "anthropic+retry-after",
+			requests: &endpoint.RateLimitDimension{Remaining: 0, ResetAt: now.Add(30 * time.Second)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := make(http.Header)
+			for key, value := range tt.headers {
+				headers.Set(key, value)
+			}
+			capacity, ok := parseRateLimitHeaders(tt.statusCode, headers, now)
+			if ok != tt.ok {
+				t.Fatalf("expected ok=%v, got %v", tt.ok, ok)
+			}
+			if capacity.Source != tt.source {
+				t.Errorf("expected source %q, got %q", tt.source, capacity.Source)
+			}
+			assertDimension(t, "requests", capacity.Requests, tt.requests)
+			assertDimension(t, "tokens", capacity.Tokens, tt.tokens)
+		})
+	}
+}
+
+func assertDimension(t *testing.T, name string, got, expected *endpoint.RateLimitDimension) {
+	t.Helper()
+	if got == nil || expected == nil {
+		if got != expected {
+			t.Errorf("%s: expected %+v, got %+v", name, expected, got)
+		}
+		return
+	}
+	if got.Limit != expected.Limit || got.Remaining != expected.Remaining || !got.ResetAt.Equal(expected.ResetAt) {
+		t.Errorf("%s: expected %+v, got %+v", name, *expected, *got)
+	}
+}
+
+func TestUpdateRateLimitCapacitySuppressesFlaps(t *testing.T) {
+	ep := endpoint.NewEndpoint(config.EndpointConfig{
+		Name:         "ratelimit-test",
+		URL:          "https://api.example.com",
+		EndpointType: "anthropic",
+		AuthType:     "api_key",
+		AuthValue:    "sk-test",
+		Enabled:      true,
+	})
+	exhausted := func(resetAt time.Time) endpoint.RateLimitCapacity {
+		return endpoint.RateLimitCapacity{
+			Source:   "openai",
+			Requests: &endpoint.RateLimitDimension{Limit: 100, Remaining: 1, ResetAt: resetAt},
+		}
+	}
+	resetAt := time.Now().Add(time.Minute)
+
+	if !ep.UpdateRateLimitCapacity(exhausted(resetAt), 0.1) {
+		t.Fatalf("expected change when the endpoint becomes nearly exhausted")
+	}
+	// 相对时间格式的重置时间抖动不到1秒，视为未变化
+	if ep.UpdateRateLimitCapacity(exhausted(resetAt.Add(300*time.Millisecond)), 0.1) {
+		t.Errorf("expected reset time jitter below one second to be ignored")
+	}
+	if ep.UpdateRateLimitCapacity(exhausted(resetAt.Add(-300*time.Millisecond)), 0.1) {
+		t.Errorf("expected earlier reset time jitter below one second to be ignored")
+	}
+	if !ep.UpdateRateLimitCapacity(exhausted(resetAt.Add(2*time.Second)), 0.1) {
+		t.Errorf("expected change when the reset time moves by more than one second")
+	}
+
+	// 剩余额度高于 min_remaining_ratio 时恢复，之后的恢复状态不再重复报告
+	recovered := endpoint.RateLimitCapacity{
+		Source:   "openai",
+		Requests: &endpoint.RateLimitDimension{Limit: 100, Remaining: 80, ResetAt: resetAt},
+	}
+	if !ep.UpdateRateLimitCapacity(recovered, 0.1) {
+		t.Errorf("expected change when capacity recovers")
+	}
+	if ep.UpdateRateLimitCapacity(recovered, 0.1) {
+		t.Errorf("expected no change while capacity stays recovered")
+	}
+	if until, _ := ep.RateLimitExhaustion(); !until.IsZero() {
+		t.Errorf("expected endpoint not exhausted after recovery, got %v", until)
+	}
+}
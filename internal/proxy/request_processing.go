@@ -9,9 +9,11 @@ import (
 
 	"claude-code-companion/internal/endpoint"
 	"claude-code-companion/internal/tagging"
+	"claude-code-companion/internal/tracing"
 	"claude-code-companion/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // readRequestBody reads and buffers the request body
@@ -33,7 +35,12 @@ func (s *Server) readRequestBody(c *gin.Context) ([]byte, error) {
 
 // processRequestTags handles request tagging with error handling
 func (s *Server) processRequestTags(req *http.Request) *tagging.TaggedRequest {
+	_, span := tracing.Start(req.Context(), "tagging")
 	taggedRequest, err := s.taggingManager.ProcessRequest(req)
+	if taggedRequest != nil {
+		span.SetAttributes(attribute.StringSlice("request.tags", taggedRequest.Tags))
+	}
+	tracing.EndWithError(span, err)
 	if err != nil {
 		s.logger.Error("Failed to process request tags", err)
 		return nil
@@ -56,7 +63,17 @@ func (s *Server) processRequestTags(req *http.Request) *tagging.TaggedRequest {
 }
 
 // selectEndpointForRequest selects the appropriate endpoint based on tags
-func (s *Server) selectEndpointForRequest(taggedRequest *tagging.TaggedRequest) (*endpoint.Endpoint, error) {
+// 启用会话粘性路由时，会话绑定的端点仍然可用就直接使用它
+func (s *Server) selectEndpointForRequest(taggedRequest *tagging.TaggedRequest, sessionID string) (*endpoint.Endpoint, error) {
+	var tags []string
+	if taggedRequest != nil {
+		tags = taggedRequest.Tags
+	}
+	if boundEndpoint := s.endpointManager.GetEndpointForSession(sessionID, tags); boundEndpoint != nil {
+		s.logger.Debug(fmt.Sprintf("Session %s is bound to endpoint %s", sessionID, boundEndpoint.Name))
+		return boundEndpoint, nil
+	}
+
 	if taggedRequest != nil && len(taggedRequest.Tags) > 0 {
 		// 使用tag匹配选择endpoint
 		selectedEndpoint, err := s.endpointManager.GetEndpointWithTags(taggedRequest.Tags)
@@ -72,6 +89,29 @@ func (s *Server) selectEndpointForRequest(taggedRequest *tagging.TaggedRequest)
 	}
 }
 
+// selectCircuitProbe 熔断器半开时按比例把本次请求交给半开端点试探（count_tokens 请求不计入健康统计，不用于试探）
+func (s *Server) selectCircuitProbe(c *gin.Context, taggedRequest *tagging.TaggedRequest, path string) *endpoint.Endpoint {
+	if strings.Contains(path, "/count_tokens") {
+		return nil
+	}
+	var tags []string
+	if taggedRequest != nil {
+		tags = taggedRequest.Tags
+	}
+	probe := s.endpointManager.SelectCircuitProbe(tags)
+	if probe == nil {
+		return nil
+	}
+	s.logger.Debug(fmt.Sprintf("Sending request as half-open circuit probe to endpoint %s", probe.Name))
+	c.Set("circuit_probe", probe.ID)
+	return probe
+}
+
+// isCircuitProbe 本次请求是否是发给该端点的半开试探
+func isCircuitProbe(c *gin.Context, ep *endpoint.Endpoint) bool {
+	return c.GetString("circuit_probe") == ep.ID
+}
+
 // extractModelFromRequest extracts the model name from the request body
 func (s *Server) extractModelFromRequest(requestBody []byte) string {
 	if len(requestBody) == 0 {
@@ -80,6 +120,20 @@ func (s *Server) extractModelFromRequest(requestBody []byte) string {
 	return utils.ExtractModelFromRequestBody(string(requestBody))
 }
 
+// bindSession 把会话绑定到成功处理请求的端点（count_tokens 请求不改变绑定）
+func (s *Server) bindSession(c *gin.Context, ep *endpoint.Endpoint, path string) {
+	if strings.Contains(path, "/count_tokens") {
+		return
+	}
+	// 断流续传成功时会话固定到续写的端点
+	if resumed, ok := c.Value("resumed_endpoint").(*endpoint.Endpoint); ok {
+		ep = resumed
+	}
+	if sessionID := c.GetString("session_id"); sessionID != "" {
+		s.endpointManager.BindSession(sessionID, ep)
+	}
+}
+
 // rebuildRequestBody rebuilds the request body from the cached bytes
 func (s *Server) rebuildRequestBody(c *gin.Context, requestBody []byte) {
 	if c.Request.Body != nil {
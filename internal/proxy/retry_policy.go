@@ -0,0 +1,244 @@
+package proxy
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/endpoint"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorCategoryNames 错误类别在 retry_policy 规则中的名称
+var errorCategoryNames = map[ErrorCategory]string{
+	ErrorCategoryClientError:          "client_error",
+	ErrorCategoryServerError:          "server_error",
+	ErrorCategoryNetworkError:         "network_error",
+	ErrorCategoryUsageValidationError: "usage_validation",
+	ErrorCategorySSEValidationError:   "sse_validation",
+	ErrorCategoryOtherValidationError: "other_validation",
+	ErrorCategoryResponseTimeoutError: "response_timeout",
+}
+
+// String 返回错误类别名称
+func (c ErrorCategory) String() string {
+	if name, ok := errorCategoryNames[c]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// bodyPatternCache 缓存已编译的 body_pattern 正则（配置加载时已验证过）
+var bodyPatternCache sync.Map
+
+func compileBodyPattern(pattern string) *regexp.Regexp {
+	if cached, ok := bodyPatternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	bodyPatternCache.Store(pattern, re)
+	return re
+}
+
+// retryPolicy 合并端点级与全局配置后的重试策略
+type retryPolicy struct {
+	maxAttempts       int
+	initialBackoff    time.Duration
+	maxBackoff        time.Duration
+	multiplier        float64
+	jitter            float64
+	respectRetryAfter bool
+	maxRetryAfter     time.Duration
+	rules             []config.RetryRuleConfig // 端点级规则在前，全局规则在后
+}
+
+// retryPolicyFor 返回端点生效的重试策略：端点级已设置的字段覆盖全局配置
+func (s *Server) retryPolicyFor(ep *endpoint.Endpoint) *retryPolicy {
+	defaults := config.Default.RetryPolicy
+	defaultInitial, _ := time.ParseDuration(defaults.InitialBackoff)
+	defaultMax, _ := time.ParseDuration(defaults.MaxBackoff)
+	defaultMaxRetryAfter, _ := time.ParseDuration(defaults.MaxRetryAfter)
+
+	global := s.config.RetryPolicy
+	policy := &retryPolicy{
+		maxAttempts:       config.GetIntWithDefault(global.MaxAttempts, defaults.MaxAttempts),
+		initialBackoff:    config.GetTimeoutDuration(global.InitialBackoff, defaultInitial),
+		maxBackoff:        config.GetTimeoutDuration(global.MaxBackoff, defaultMax),
+		multiplier:        global.BackoffMultiplier,
+		jitter:            defaults.Jitter,
+		respectRetryAfter: defaults.RespectRetryAfter,
+		maxRetryAfter:     config.GetTimeoutDuration(global.MaxRetryAfter, defaultMaxRetryAfter),
+	}
+	if policy.multiplier == 0 {
+		policy.multiplier = defaults.BackoffMultiplier
+	}
+	if global.Jitter != nil {
+		policy.jitter = *global.Jitter
+	}
+	if global.RespectRetryAfter != nil {
+		policy.respectRetryAfter = *global.RespectRetryAfter
+	}
+
+	var endpointRules []config.RetryRuleConfig
+	if override := ep.GetRetryPolicy(); override != nil {
+		policy.maxAttempts = config.GetIntWithDefault(override.MaxAttempts, policy.maxAttempts)
+		policy.initialBackoff = config.GetTimeoutDuration(override.InitialBackoff, policy.initialBackoff)
+		policy.maxBackoff = config.GetTimeoutDuration(override.MaxBackoff, policy.maxBackoff)
+		policy.maxRetryAfter = config.GetTimeoutDuration(override.MaxRetryAfter, policy.maxRetryAfter)
+		if override.BackoffMultiplier != 0 {
+			policy.multiplier = override.BackoffMultiplier
+		}
+		if override.Jitter != nil {
+			policy.jitter = *override.Jitter
+		}
+		if override.RespectRetryAfter != nil {
+			policy.respectRetryAfter = *override.RespectRetryAfter
+		}
+		endpointRules = override.Rules
+	}
+
+	policy.rules = make([]config.RetryRuleConfig, 0, len(endpointRules)+len(global.Rules))
+	policy.rules = append(policy.rules, endpointRules...)
+	policy.rules = append(policy.rules, global.Rules...)
+	return policy
+}
+
+// matchRule 返回第一条命中规则的动作
+func (p *retryPolicy) matchRule(category ErrorCategory, statusCode int, body string) (string, bool) {
+	for _, rule := range p.rules {
+		if len(rule.StatusCodes) > 0 {
+			matched := false
+			for _, code := range rule.StatusCodes {
+				if code == statusCode {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		if len(rule.ErrorCategories) > 0 {
+			matched := false
+			for _, name := range rule.ErrorCategories {
+				if name == category.String() {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+
+		if rule.BodyPattern != "" {
+			re := compileBodyPattern(rule.BodyPattern)
+			if re == nil || !re.MatchString(body) {
+				continue
+			}
+		}
+
+		return rule.Action, true
+	}
+	return "", false
+}
+
+// backoff 计算第 attempt 次尝试失败后原地重试前的等待时间：指数退避并加入随机抖动
+func (p *retryPolicy) backoff(attempt int) time.Duration {
+	if p.initialBackoff <= 0 {
+		return 0
+	}
+
+	delay := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(attempt-1))
+	if p.maxBackoff > 0 && delay > float64(p.maxBackoff) {
+		delay = float64(p.maxBackoff)
+	}
+	if p.jitter > 0 {
+		delay *= 1 + p.jitter*(rand.Float64()*2-1)
+	}
+	return time.Duration(delay)
+}
+
+// parseRetryAfter 解析 Retry-After 头部，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// retryDelay 计算原地重试前的等待时间；Retry-After 超过 max_retry_after 时返回 false，调用方应切换端点
+func (s *Server) retryDelay(c *gin.Context, policy *retryPolicy, attempt int) (time.Duration, bool) {
+	delay := policy.backoff(attempt)
+	if !policy.respectRetryAfter {
+		return delay, true
+	}
+
+	retryAfterHeader, _ := c.Get("last_retry_after")
+	headerValue, _ := retryAfterHeader.(string)
+	retryAfter, ok := parseRetryAfter(headerValue)
+	if !ok {
+		return delay, true
+	}
+	if policy.maxRetryAfter > 0 && retryAfter > policy.maxRetryAfter {
+		return 0, false
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay, true
+}
+
+// sleepWithContext 等待指定时间，客户端断开时提前返回 false
+func sleepWithContext(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// lastFailureBody 返回上一次失败的上游错误响应体，没有响应时返回错误信息，供 body_pattern 匹配
+func lastFailureBody(c *gin.Context, err error) string {
+	if body, exists := c.Get("last_response_body"); exists {
+		if data, ok := body.([]byte); ok && len(data) > 0 {
+			return string(data)
+		}
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
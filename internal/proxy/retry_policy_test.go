@@ -0,0 +1,280 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRetryTestServer 只包含日志器的服务器，用于测试不依赖端点的重试逻辑
+func newRetryTestServer(t *testing.T) *Server {
+	t.Helper()
+	log, err := logger.NewLogger(logger.LogConfig{Level: "error", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	return &Server{logger: log}
+}
+
+func TestRetryPolicyMatchRule(t *testing.T) {
+	policy := &retryPolicy{
+		rules: []config.RetryRuleConfig{
+			{StatusCodes: []int{400}, BodyPattern: "prompt is too long", Action: "return"},
+			{StatusCodes: []int{403}, BodyPattern: "(?i)quota|rate", Action: "retry"},
+			{StatusCodes: []int{429, 529}, Action: "switch"},
+			{ErrorCategories: []string{"network_error"}, BodyPattern: "connection refused", Action: "switch"},
+			{ErrorCategories: []string{"usage_validation", "sse_validation"}, Action: "return"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		category   ErrorCategory
+		statusCode int
+		body       string
+		action     string
+		matched    bool
+	}{
+		{"status and body pattern", ErrorCategoryClientError, 400, `{"error":{"message":"prompt is too long: 210000 tokens"}}`, "return", true},
+		{"status without body pattern match", ErrorCategoryClientError, 400, `{"error":{"message":"invalid model"}}`, "", false},
+		{"case-insensitive body pattern", ErrorCategoryClientError, 403, `Rate limit exceeded`, "retry", true},
+		{"status list", ErrorCategoryServerError, 529, `overloaded`, "switch", true},
+		{"status not in any rule", ErrorCategoryServerError, 500, `internal error`, "", false},
+		{"category and error message", ErrorCategoryNetworkError, 0, "dial tcp: connection refused", "switch", true},
+		{"category without error message match", ErrorCategoryNetworkError, 0, "i/o timeout", "", false},
+		{"one of several categories", ErrorCategorySSEValidationError, 200, "missing message_stop", "return", true},
+		{"category not listed", ErrorCategoryResponseTimeoutError, 200, "Failed to read response body", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, matched := policy.matchRule(tt.category, tt.statusCode, tt.body)
+			if action != tt.action || matched != tt.matched {
+				t.Errorf("matchRule(%s, %d, %q) = (%q, %v), want (%q, %v)", tt.category, tt.statusCode, tt.body, action, matched, tt.action, tt.matched)
+			}
+		})
+	}
+}
+
+func TestDetermineRetryBehaviorFromError(t *testing.T) {
+	s := newRetryTestServer(t)
+	policy := &retryPolicy{
+		maxAttempts: 3,
+		rules: []config.RetryRuleConfig{
+			{StatusCodes: []int{400}, BodyPattern: "prompt is too long", Action: "return"},
+			{StatusCodes: []int{403}, Action: "retry"},
+			{StatusCodes: []int{503}, Action: "switch"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		body       string
+		attempt    int
+		expected   RetryBehavior
+	}{
+		{"return rule", nil, 400, "prompt is too long", 1, RetryBehaviorReturnError},
+		{"retry rule", nil, 403, "forbidden", 1, RetryBehaviorRetryEndpoint},
+		{"retry rule on last attempt", nil, 403, "forbidden", 3, RetryBehaviorSwitchEndpoint},
+		{"switch rule overrides server error retry", nil, 503, "unavailable", 1, RetryBehaviorSwitchEndpoint},
+		// 未命中规则时使用内置分类
+		{"client error falls back to switch", nil, 400, "invalid model", 1, RetryBehaviorSwitchEndpoint},
+		{"server error falls back to retry", nil, 500, "internal error", 1, RetryBehaviorRetryEndpoint},
+		{"server error on last attempt", nil, 500, "internal error", 3, RetryBehaviorSwitchEndpoint},
+		{"network error falls back to retry", errors.New("dial tcp: connection refused"), 0, "", 2, RetryBehaviorRetryEndpoint},
+		{"usage validation falls back to retry", errors.New("Usage validation failed: invalid usage stats"), 200, "", 1, RetryBehaviorRetryEndpoint},
+		{"other validation falls back to switch", errors.New("Response format conversion failed"), 200, "", 1, RetryBehaviorSwitchEndpoint},
+		{"response timeout falls back to switch", errors.New("Failed to read response body: timeout"), 200, "", 1, RetryBehaviorSwitchEndpoint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.determineRetryBehaviorFromError(tt.err, tt.statusCode, tt.body, tt.attempt, policy); got != tt.expected {
+				t.Errorf("expected behavior %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &retryPolicy{
+		initialBackoff: 100 * time.Millisecond,
+		maxBackoff:     time.Second,
+		multiplier:     2,
+	}
+
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second}, // 达到上限
+		{10, time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.backoff(tt.attempt); got != tt.expected {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.expected)
+		}
+	}
+
+	if got := (&retryPolicy{initialBackoff: 0, multiplier: 2}).backoff(3); got != 0 {
+		t.Errorf("expected no backoff when initial_backoff is 0, got %v", got)
+	}
+
+	// 抖动在上限之后应用，结果落在 [delay×(1-jitter), delay×(1+jitter)] 内
+	policy.jitter = 0.2
+	bounds := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 80 * time.Millisecond, 120 * time.Millisecond},
+		{10, 800 * time.Millisecond, 1200 * time.Millisecond},
+	}
+	for _, b := range bounds {
+		for i := 0; i < 200; i++ {
+			if got := policy.backoff(b.attempt); got < b.min || got > b.max {
+				t.Fatalf("backoff(%d) with jitter = %v, want within [%v, %v]", b.attempt, got, b.min, b.max)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"seconds", "5", 5 * time.Second, true},
+		{"seconds with spaces", " 10 ", 10 * time.Second, true},
+		{"zero seconds", "0", 0, true},
+		{"negative seconds", "-1", 0, false},
+		{"empty", "", 0, false},
+		{"invalid", "soon", 0, false},
+		{"date in the past", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("parseRetryAfter(%q) = (%v, %v), want (%v, %v)", tt.value, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+
+	// HTTP 日期精确到秒，只检查范围
+	got, ok := parseRetryAfter(time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat))
+	if !ok || got < 28*time.Second || got > 30*time.Second {
+		t.Errorf("parseRetryAfter(HTTP date in 30s) = (%v, %v), want about 30s", got, ok)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	s := newRetryTestServer(t)
+	httpDate := func(offset time.Duration) string {
+		return time.Now().Add(offset).UTC().Format(http.TimeFormat)
+	}
+
+	tests := []struct {
+		name        string
+		policy      retryPolicy
+		retryAfter  string
+		min, max    time.Duration
+		shouldRetry bool
+	}{
+		{"backoff without Retry-After", retryPolicy{initialBackoff: 100 * time.Millisecond, multiplier: 2, respectRetryAfter: true, maxRetryAfter: 30 * time.Second}, "", 100 * time.Millisecond, 100 * time.Millisecond, true},
+		{"Retry-After seconds longer than backoff", retryPolicy{initialBackoff: 100 * time.Millisecond, multiplier: 2, respectRetryAfter: true, maxRetryAfter: 30 * time.Second}, "3", 3 * time.Second, 3 * time.Second, true},
+		{"backoff longer than Retry-After", retryPolicy{initialBackoff: 2 * time.Second, multiplier: 2, respectRetryAfter: true, maxRetryAfter: 30 * time.Second}, "1", 2 * time.Second, 2 * time.Second, true},
+		{"Retry-After HTTP date", retryPolicy{initialBackoff: 100 * time.Millisecond, multiplier: 2, respectRetryAfter: true, maxRetryAfter: 30 * time.Second}, httpDate(10 * time.Second), 8 * time.Second, 10 * time.Second, true},
+		{"Retry-After seconds over max_retry_after", retryPolicy{initialBackoff: 100 * time.Millisecond, multiplier: 2, respectRetryAfter: true, maxRetryAfter: 30 * time.Second}, "60", 0, 0, false},
+		{"Retry-After HTTP date over max_retry_after", retryPolicy{initialBackoff: 100 * time.Millisecond, multiplier: 2, respectRetryAfter: true, maxRetryAfter: 30 * time.Second}, httpDate(2 * time.Minute), 0, 0, false},
+		{"Retry-After equal to max_retry_after", retryPolicy{initialBackoff: 100 * time.Millisecond, multiplier: 2, respectRetryAfter: true, maxRetryAfter: 30 * time.Second}, "30", 30 * time.Second, 30 * time.Second, true},
+		{"no cutoff when max_retry_after is 0", retryPolicy{initialBackoff: 100 * time.Millisecond, multiplier: 2, respectRetryAfter: true}, "120", 120 * time.Second, 120 * time.Second, true},
+		{"Retry-After ignored when not respected", retryPolicy{initialBackoff: 100 * time.Millisecond, multiplier: 2, maxRetryAfter: 30 * time.Second}, "60", 100 * time.Millisecond, 100 * time.Millisecond, true},
+		{"invalid Retry-After uses backoff", retryPolicy{initialBackoff: 100 * time.Millisecond, multiplier: 2, respectRetryAfter: true, maxRetryAfter: 30 * time.Second}, "later", 100 * time.Millisecond, 100 * time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Set("last_retry_after", tt.retryAfter)
+			delay, ok := s.retryDelay(c, &tt.policy, 1)
+			if ok != tt.shouldRetry {
+				t.Fatalf("expected retry %v, got %v (delay %v)", tt.shouldRetry, ok, delay)
+			}
+			if delay < tt.min || delay > tt.max {
+				t.Errorf("expected delay within [%v, %v], got %v", tt.min, tt.max, delay)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyForDefaultsAndOverrides(t *testing.T) {
+	// 未配置 retry_policy 时与之前一样立即重试
+	server := newTestServer(t, `endpoints:
+    - name: plain
+      url: https://plain.example.com
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+`)
+	policy := server.retryPolicyFor(server.endpointManager.GetAllEndpoints()[0])
+	if policy.initialBackoff != 0 || policy.backoff(1) != 0 {
+		t.Errorf("expected no backoff without retry_policy, got initial_backoff %v", policy.initialBackoff)
+	}
+	if policy.maxAttempts != config.Default.RetryPolicy.MaxAttempts || policy.jitter != config.Default.RetryPolicy.Jitter {
+		t.Errorf("unexpected default policy: %+v", policy)
+	}
+
+	// 端点级显式设置 jitter: 0 时关闭抖动，未设置的端点继承全局配置
+	server = newTestServer(t, `endpoints:
+    - name: no-jitter
+      url: https://no-jitter.example.com
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+      retry_policy:
+          jitter: 0
+    - name: inherit
+      url: https://inherit.example.com
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+retry_policy:
+    initial_backoff: 100ms
+    jitter: 0.5
+`)
+	expected := map[string]float64{"no-jitter": 0, "inherit": 0.5}
+	for _, ep := range server.endpointManager.GetAllEndpoints() {
+		policy := server.retryPolicyFor(ep)
+		if policy.jitter != expected[ep.Name] {
+			t.Errorf("endpoint %s: expected jitter %v, got %v", ep.Name, expected[ep.Name], policy.jitter)
+		}
+		if policy.initialBackoff != 100*time.Millisecond {
+			t.Errorf("endpoint %s: expected inherited initial_backoff 100ms, got %v", ep.Name, policy.initialBackoff)
+		}
+	}
+	noJitter := server.retryPolicyFor(server.endpointManager.GetAllEndpoints()[0])
+	for i := 0; i < 20; i++ {
+		if got := noJitter.backoff(1); got != 100*time.Millisecond {
+			t.Fatalf("expected exact backoff without jitter, got %v", got)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"claude-code-companion/internal/security"
 	"claude-code-companion/internal/statistics"
 	"claude-code-companion/internal/tagging"
+	"claude-code-companion/internal/tokencount"
 	"claude-code-companion/internal/validator"
 	"claude-code-companion/internal/web"
 
@@ -34,6 +35,8 @@ type Server struct {
 	i18nManager     *i18n.Manager            // 新增：国际化管理器
 	sessionManager  *security.SessionManager // 新增：会话管理器
 	authManager     *security.AuthManager    // 新增：身份验证管理器
+	tokenCounter    *tokencount.Counter      // 新增：本地 count_tokens 估算器（disabled 时为 nil）
+	metrics         *proxyMetrics            // Prometheus 指标
 	router          *gin.Engine
 	configFilePath  string
 	configMutex     sync.Mutex             // 新增：保护配置文件操作的互斥锁
@@ -46,6 +49,7 @@ func NewServer(cfg *config.Config, configFilePath string, version string) (*Serv
 		LogRequestBody:  cfg.Logging.LogRequestBody,
 		LogResponseBody: cfg.Logging.LogResponseBody,
 		LogDirectory:    cfg.Logging.LogDirectory,
+		FullTextSearch:  cfg.Logging.FullTextSearch,
 	}
 
 	log, err := logger.NewLogger(logConfig)
@@ -71,6 +75,12 @@ func NewServer(cfg *config.Config, configFilePath string, version string) (*Serv
 	// 初始化格式转换器
 	converter := conversion.NewConverter(log)
 
+	// 初始化本地 count_tokens 估算器
+	tokenCounter, err := tokencount.NewCounter(cfg.TokenCount, log, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize token counter: %v", err)
+	}
+
 	// 初始化健康检查器（需要在模型重写器和转换器之后）
 	healthChecker := health.NewChecker(cfg.Timeouts.ToHealthCheckTimeoutConfig(), modelRewriter, converter)
 
@@ -114,6 +124,8 @@ func NewServer(cfg *config.Config, configFilePath string, version string) (*Serv
 		i18nManager:     i18nManager,    // 新增：设置国际化管理器
 		sessionManager:  sessionManager, // 新增：设置会话管理器
 		authManager:     authManager,    // 新增：设置身份验证管理器
+		tokenCounter:    tokenCounter,   // 新增：设置本地 count_tokens 估算器
+		metrics:         newProxyMetrics(endpointManager),
 		configFilePath:  configFilePath,
 	}
 
@@ -122,6 +134,10 @@ func NewServer(cfg *config.Config, configFilePath string, version string) (*Serv
 
 	// 让端点管理器使用同一个健康检查器
 	endpointManager.SetHealthChecker(healthChecker)
+	endpointManager.SetModelLister(healthChecker)
+
+	// 在 OAuth token 过期前后台主动刷新
+	go server.runOAuthRefresher()
 
 	server.setupRoutes()
 	return server, nil
@@ -138,9 +154,15 @@ func (s *Server) setupRoutes() {
 	// 注册管理界面路由（不需要认证）
 	s.adminServer.RegisterRoutes(s.router)
 
+	// Prometheus 指标，路径修改后需要重启
+	s.router.GET(s.config.Metrics.Path, s.metricsAuthMiddleware(), s.handleMetrics)
+
 	// 为 API 端点添加日志中间件
 	apiGroup := s.router.Group("/v1")
 	apiGroup.Use(s.loggingMiddleware())
+	apiGroup.Use(s.metricsMiddleware())
+	apiGroup.Use(s.tracingMiddleware())
+	apiGroup.Use(s.analyticsMiddleware())
 	{
 		apiGroup.Any("/*path", s.handleProxy)
 	}
@@ -181,6 +203,11 @@ func (s *Server) HotUpdateConfig(newConfig *config.Config) error {
 	if err := s.updateEndpoints(newConfig.Endpoints); err != nil {
 		return fmt.Errorf("failed to update endpoints: %v", err)
 	}
+	s.endpointManager.UpdateLoadBalancing(newConfig.LoadBalancing)
+	s.endpointManager.UpdateSessionAffinity(newConfig.SessionAffinity)
+	s.endpointManager.UpdateCircuitBreaker(newConfig.CircuitBreaker)
+	s.endpointManager.UpdateModelDiscovery(newConfig.ModelDiscovery)
+	s.endpointManager.UpdateAnalytics(newConfig.Analytics)
 
 	// 更新日志配置（如果可能）
 	if err := s.updateLoggingConfig(newConfig.Logging); err != nil {
@@ -296,19 +323,9 @@ func (s *Server) updateAuthConfig(newAuthConfig config.AuthConfig) error {
 
 // persistRateLimitState 持久化endpoint的rate limit状态到配置文件
 func (s *Server) persistRateLimitState(endpointID string, reset *int64, status *string) error {
-	// 首先根据endpoint ID找到对应的endpoint名称
-	var endpointName string
-	s.configMutex.Lock()
-	for _, cfgEndpoint := range s.config.Endpoints {
-		if statistics.GenerateEndpointID(cfgEndpoint.Name) == endpointID {
-			endpointName = cfgEndpoint.Name
-			break
-		}
-	}
-	s.configMutex.Unlock()
-	
-	if endpointName == "" {
-		return fmt.Errorf("endpoint with ID %s not found", endpointID)
+	endpointName, err := s.endpointNameByID(endpointID)
+	if err != nil {
+		return err
 	}
 	
 	// 使用统一的配置更新机制
@@ -318,3 +335,29 @@ func (s *Server) persistRateLimitState(endpointID string, reset *int64, status *
 		return nil
 	})
 }
+
+// persistRateLimitCapacity 持久化通用速率限制头部解析出的耗尽状态到配置文件，snapshot 为nil时清除
+func (s *Server) persistRateLimitCapacity(endpointID string, snapshot *config.RateLimitCapacityConfig) error {
+	endpointName, err := s.endpointNameByID(endpointID)
+	if err != nil {
+		return err
+	}
+	
+	return s.updateEndpointConfig(endpointName, func(cfg *config.EndpointConfig) error {
+		cfg.RateLimitCapacity = snapshot
+		return nil
+	})
+}
+
+// endpointNameByID 根据endpoint ID找到对应的endpoint名称
+func (s *Server) endpointNameByID(endpointID string) (string, error) {
+	s.configMutex.Lock()
+	defer s.configMutex.Unlock()
+	
+	for _, cfgEndpoint := range s.config.Endpoints {
+		if statistics.GenerateEndpointID(cfgEndpoint.Name) == endpointID {
+			return cfgEndpoint.Name, nil
+		}
+	}
+	return "", fmt.Errorf("endpoint with ID %s not found", endpointID)
+}
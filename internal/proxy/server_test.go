@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/webres"
+)
+
+// testAssetProvider 从源码目录读取管理界面资源，测试不依赖 main 包中的嵌入资源
+type testAssetProvider struct{}
+
+func (testAssetProvider) GetTemplateFS() (fs.FS, error) { return os.DirFS("../../web/templates"), nil }
+func (testAssetProvider) GetStaticFS() (fs.FS, error)   { return os.DirFS("../../web/static"), nil }
+func (testAssetProvider) GetLocalesFS() (fs.FS, error)  { return os.DirFS("../../web/locales"), nil }
+
+func (p testAssetProvider) LoadTemplates() (*template.Template, error) {
+	templateFS, _ := p.GetTemplateFS()
+	return template.ParseFS(templateFS, "*.html")
+}
+
+func (p testAssetProvider) ReadLocaleFile(filename string) ([]byte, error) {
+	localesFS, _ := p.GetLocalesFS()
+	return fs.ReadFile(localesFS, filename)
+}
+
+// newTestServer 使用给定的 YAML 配置片段（端点、重试策略等）创建代理服务器，日志写入临时目录
+func newTestServer(t *testing.T, configYAML string) *Server {
+	t.Helper()
+	webres.SetProvider(testAssetProvider{})
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	content := fmt.Sprintf(`server:
+    host: 127.0.0.1
+    port: 8080
+logging:
+    level: error
+    log_directory: %s
+%s`, filepath.Join(dir, "logs"), configYAML)
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	server, err := NewServer(cfg, configPath, "test")
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	t.Cleanup(func() { server.logger.Close() })
+	return server
+}
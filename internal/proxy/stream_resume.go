@@ -0,0 +1,464 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/endpoint"
+	"claude-code-companion/internal/tagging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 断流续传
+//
+// 流式响应已经向客户端写出部分内容后上游断开（读取失败或流在 message_stop 之前结束）时，
+// 原本只能补发 error 事件结束请求。启用 stream_resume 后，代理会根据已经发给客户端的事件
+// 还原出部分 assistant 内容，把它作为预填充的 assistant 消息追加到原始请求中，发送给下一个可用端点，
+// 再把续写的事件拼接进客户端的流：丢弃续写的 message_start，第一个文本块并入被打断的文本块，
+// 其余内容块顺延 index，让客户端看到的仍是一条完整的 message。
+//
+// 只有已发送内容都是完整的 thinking 块或文本块（最后一个文本块可以未结束）时才能续传；
+// 已经发送了 tool_use 等其他内容块，或打断在 thinking 块中间时，仍按原逻辑补发 error 事件。
+// 已发送的 thinking 块连同签名一起放入预填充，续写请求保留 thinking 参数（Anthropic 要求
+// 启用 thinking 时最后一条 assistant 消息以 thinking 块开头）；没有已发送的 thinking 块时去掉 thinking 参数。
+// 续写请求与普通请求一样经过端点可用性、重试策略、限流和预算检查，成功时本次请求记为成功。
+
+// deliveredBlock 已经发送给客户端的内容块
+type deliveredBlock struct {
+	blockType string
+	text      strings.Builder // 文本块的文本或 thinking 块的思考内容
+	signature string          // thinking 块的签名
+	data      string          // redacted_thinking 块的加密内容
+	complete  bool
+}
+
+// deliveredMessage 根据已经发送给客户端的SSE事件还原的消息状态
+type deliveredMessage struct {
+	started bool // 是否已发送 message_start
+	ended   bool // 是否已发送 message_delta（此时只差 message_stop，续传没有意义）
+	blocks  []*deliveredBlock
+}
+
+// parseDeliveredMessage 解析已经发送给客户端的 Anthropic SSE 事件
+func parseDeliveredMessage(clientBody []byte) *deliveredMessage {
+	message := &deliveredMessage{}
+	reader := newSSEEventReader(bytes.NewReader(clientBody))
+	for {
+		event, err := reader.Next()
+		if data := sseEventData(event); data != nil {
+			var payload struct {
+				Type         string `json:"type"`
+				Index        int    `json:"index"`
+				ContentBlock struct {
+					Type      string `json:"type"`
+					Text      string `json:"text"`
+					Thinking  string `json:"thinking"`
+					Signature string `json:"signature"`
+					Data      string `json:"data"`
+				} `json:"content_block"`
+				Delta struct {
+					Type      string `json:"type"`
+					Text      string `json:"text"`
+					Thinking  string `json:"thinking"`
+					Signature string `json:"signature"`
+				} `json:"delta"`
+			}
+			if json.Unmarshal(data, &payload) == nil {
+				switch payload.Type {
+				case "message_start":
+					message.started = true
+				case "content_block_start":
+					block := &deliveredBlock{
+						blockType: payload.ContentBlock.Type,
+						signature: payload.ContentBlock.Signature,
+						data:      payload.ContentBlock.Data,
+					}
+					block.text.WriteString(payload.ContentBlock.Text)
+					block.text.WriteString(payload.ContentBlock.Thinking)
+					message.blocks = append(message.blocks, block)
+				case "content_block_delta":
+					if payload.Index >= 0 && payload.Index < len(message.blocks) {
+						block := message.blocks[payload.Index]
+						block.text.WriteString(payload.Delta.Text)
+						block.text.WriteString(payload.Delta.Thinking)
+						block.signature += payload.Delta.Signature
+					}
+				case "content_block_stop":
+					if payload.Index >= 0 && payload.Index < len(message.blocks) {
+						message.blocks[payload.Index].complete = true
+					}
+				case "message_delta":
+					message.ended = true
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return message
+}
+
+// sseEventData 提取SSE事件的 data 负载
+func sseEventData(event []byte) []byte {
+	for _, line := range bytes.Split(event, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("data:")) {
+			return bytes.TrimSpace(line[5:])
+		}
+	}
+	return nil
+}
+
+// resumable 检查已发送的内容能否续传
+func (m *deliveredMessage) resumable() bool {
+	if !m.started || m.ended {
+		return false
+	}
+	for i, block := range m.blocks {
+		switch block.blockType {
+		case "text":
+		case "thinking":
+			// 没有签名的 thinking 块无法放入预填充
+			if !block.complete || block.signature == "" {
+				return false
+			}
+		case "redacted_thinking":
+			if !block.complete || block.data == "" {
+				return false
+			}
+		default:
+			return false
+		}
+		// 只有最后一个块可以未结束
+		if !block.complete && i != len(m.blocks)-1 {
+			return false
+		}
+	}
+	return true
+}
+
+// openTextIndex 返回未结束的文本块 index，没有时返回 -1
+func (m *deliveredMessage) openTextIndex() int {
+	if len(m.blocks) == 0 {
+		return -1
+	}
+	last := len(m.blocks) - 1
+	if m.blocks[last].blockType == "text" && !m.blocks[last].complete {
+		return last
+	}
+	return -1
+}
+
+// prefillBlocks 按顺序返回已发送的内容块（用作预填充）：thinking 块带上签名，
+// 最后一个文本块去掉末尾空白（Anthropic 不接受以空白结尾的预填充），去掉后为空的文本块不放入预填充
+func (m *deliveredMessage) prefillBlocks() []interface{} {
+	var blocks []interface{}
+	for i, block := range m.blocks {
+		switch block.blockType {
+		case "thinking":
+			blocks = append(blocks, map[string]interface{}{"type": "thinking", "thinking": block.text.String(), "signature": block.signature})
+		case "redacted_thinking":
+			blocks = append(blocks, map[string]interface{}{"type": "redacted_thinking", "data": block.data})
+		case "text":
+			text := block.text.String()
+			if i == len(m.blocks)-1 {
+				text = strings.TrimRight(text, " \t\r\n")
+			}
+			if text != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": text})
+			}
+		}
+	}
+	return blocks
+}
+
+// buildResumeRequestBody 在原始请求后追加预填充的 assistant 消息
+// 预填充以 thinking 块开头时保留 thinking 参数，否则去掉（预填充文本与 extended thinking 不兼容）
+func buildResumeRequestBody(requestBody []byte, prefill []interface{}) ([]byte, error) {
+	var request map[string]interface{}
+	if err := json.Unmarshal(requestBody, &request); err != nil {
+		return nil, fmt.Errorf("failed to parse original request: %v", err)
+	}
+	if len(prefill) == 0 {
+		return requestBody, nil
+	}
+
+	messages, _ := request["messages"].([]interface{})
+
+	// 客户端自己已经预填充了 assistant 消息时，续写内容接在它后面
+	appended := false
+	if len(messages) > 0 {
+		if last, ok := messages[len(messages)-1].(map[string]interface{}); ok && last["role"] == "assistant" {
+			switch content := last["content"].(type) {
+			case string:
+				last["content"] = append([]interface{}{map[string]interface{}{"type": "text", "text": content}}, prefill...)
+			case []interface{}:
+				last["content"] = append(content, prefill...)
+			default:
+				last["content"] = prefill
+			}
+			appended = true
+		}
+	}
+	if !appended {
+		messages = append(messages, map[string]interface{}{
+			"role":    "assistant",
+			"content": prefill,
+		})
+	}
+	request["messages"] = messages
+
+	if first, _ := prefill[0].(map[string]interface{}); first["type"] != "thinking" && first["type"] != "redacted_thinking" {
+		delete(request, "thinking")
+	}
+
+	return json.Marshal(request)
+}
+
+// streamStitchWriter 续写请求使用的响应写入器：把续写的SSE事件改写后拼接进客户端已有的流
+type streamStitchWriter struct {
+	target      gin.ResponseWriter
+	header      http.Header
+	buffer      bytes.Buffer
+	openIndex   int         // 被打断、尚未结束的文本块 index；-1 表示没有
+	mergeIndex  int         // 续写中并入被打断文本块的块 index；-1 表示尚未合并
+	trimLeading bool        // 被打断的文本以空白结尾（预填充去掉了末尾空白），续写开头的空白需要去掉
+	nextIndex   int         // 续写的新内容块从该 index 开始
+	indexMap    map[int]int // 续写块 index → 客户端块 index
+	wroteEvents bool
+}
+
+func newStreamStitchWriter(target gin.ResponseWriter, delivered *deliveredMessage) *streamStitchWriter {
+	openIndex := delivered.openTextIndex()
+	trimLeading := false
+	if openIndex >= 0 {
+		text := delivered.blocks[openIndex].text.String()
+		trimLeading = len(text) > 0 && len(strings.TrimRight(text, " \t\r\n")) < len(text)
+	}
+	return &streamStitchWriter{
+		target:      target,
+		header:      make(http.Header),
+		openIndex:   openIndex,
+		mergeIndex:  -1,
+		trimLeading: trimLeading,
+		nextIndex:   len(delivered.blocks),
+		indexMap:    make(map[int]int),
+	}
+}
+
+// closeOpenBlock 续写没有并入被打断的文本块时，先结束该块
+func (w *streamStitchWriter) closeOpenBlock() []byte {
+	if w.openIndex < 0 {
+		return nil
+	}
+	index := w.openIndex
+	w.openIndex = -1
+	return w.buildEvent("content_block_stop", map[string]interface{}{"type": "content_block_stop", "index": index})
+}
+
+// rewriteEvent 改写单个续写事件，返回 nil 表示丢弃
+func (w *streamStitchWriter) rewriteEvent(event []byte) []byte {
+	data := sseEventData(event)
+	if data == nil {
+		return event
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return event
+	}
+
+	eventType, _ := payload["type"].(string)
+	switch eventType {
+	case "message_start":
+		// 客户端已经收到过 message_start
+		return nil
+
+	case "content_block_start":
+		index, _ := payload["index"].(float64)
+		block, _ := payload["content_block"].(map[string]interface{})
+		if w.openIndex >= 0 && block["type"] == "text" {
+			// 续写的第一个文本块并入被打断的文本块
+			w.indexMap[int(index)] = w.openIndex
+			w.mergeIndex = int(index)
+			w.openIndex = -1
+			return nil
+		}
+		prefix := w.closeOpenBlock()
+		w.indexMap[int(index)] = w.nextIndex
+		payload["index"] = w.nextIndex
+		w.nextIndex++
+		return append(prefix, w.buildEvent(eventType, payload)...)
+
+	case "content_block_delta", "content_block_stop":
+		index, _ := payload["index"].(float64)
+		clientIndex, mapped := w.indexMap[int(index)]
+		if !mapped {
+			return nil
+		}
+		if eventType == "content_block_delta" && w.trimLeading && int(index) == w.mergeIndex {
+			delta, _ := payload["delta"].(map[string]interface{})
+			if text, ok := delta["text"].(string); ok {
+				trimmed := strings.TrimLeft(text, " \t\r\n")
+				if trimmed == "" {
+					return nil
+				}
+				delta["text"] = trimmed
+				w.trimLeading = false
+			}
+		}
+		payload["index"] = clientIndex
+		return w.buildEvent(eventType, payload)
+
+	case "message_delta":
+		return append(w.closeOpenBlock(), event...)
+	}
+	return event
+}
+
+func (w *streamStitchWriter) buildEvent(eventType string, payload map[string]interface{}) []byte {
+	data, _ := json.Marshal(payload)
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", eventType, data))
+}
+
+func (w *streamStitchWriter) Write(data []byte) (int, error) {
+	w.buffer.Write(data)
+	var out bytes.Buffer
+	for {
+		pending := w.buffer.Bytes()
+		end := bytes.Index(pending, []byte("\n\n"))
+		if end < 0 {
+			break
+		}
+		event := append([]byte(nil), pending[:end+2]...)
+		w.buffer.Next(end + 2)
+		out.Write(w.rewriteEvent(event))
+	}
+	if out.Len() > 0 {
+		w.wroteEvents = true
+		if _, err := w.target.Write(out.Bytes()); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *streamStitchWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// 响应头和状态码已经发送给客户端，续写请求的响应头被忽略
+func (w *streamStitchWriter) Header() http.Header  { return w.header }
+func (w *streamStitchWriter) WriteHeader(code int) {}
+func (w *streamStitchWriter) WriteHeaderNow()      {}
+func (w *streamStitchWriter) Flush()               { w.target.Flush() }
+func (w *streamStitchWriter) Status() int          { return w.target.Status() }
+func (w *streamStitchWriter) Size() int            { return w.target.Size() }
+func (w *streamStitchWriter) Written() bool        { return w.wroteEvents }
+func (w *streamStitchWriter) Pusher() http.Pusher  { return nil }
+func (w *streamStitchWriter) CloseNotify() <-chan bool {
+	return w.target.CloseNotify()
+}
+func (w *streamStitchWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, fmt.Errorf("hijack is not supported for resumed streams")
+}
+
+// tryResumeStream 上游在流式响应中途断开时，把请求连同已发送的内容发送给其他端点续写
+// 续写请求使用新的 request 和 context，与普通请求一样经过 tryProxyRequestWithRetry 的可用性、重试、限流和预算检查。
+// 返回续写请求的 context 表示客户端的流已经由续写请求接管（续写成功，或续写请求自己补发了 error 事件），nil 表示没有接管；
+// success 表示续写成功
+func (s *Server) tryResumeStream(c *gin.Context, failedEndpoint *endpoint.Endpoint, path string, requestBody []byte, requestID string, clientBody []byte, taggedRequest *tagging.TaggedRequest, attemptNumber int) (*gin.Context, bool) {
+	if !s.config.StreamResume.Enabled {
+		return nil, false
+	}
+	// 续写请求自己再次断开时不再嵌套续传
+	if resumed, _ := c.Get("stream_resumed"); resumed == true {
+		return nil, false
+	}
+
+	delivered := parseDeliveredMessage(clientBody)
+	if !delivered.resumable() {
+		s.logger.Info(fmt.Sprintf("Request %s: stream from endpoint %s cannot be resumed (partial content contains blocks that cannot be prefilled)", requestID, failedEndpoint.Name))
+		return nil, false
+	}
+
+	resumeBody, err := buildResumeRequestBody(requestBody, delivered.prefillBlocks())
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("Request %s: failed to build resume request", requestID), err)
+		return nil, false
+	}
+
+	var requestTags []string
+	if taggedRequest != nil {
+		requestTags = taggedRequest.Tags
+	}
+
+	candidates := s.resumeCandidates(failedEndpoint, requestTags)
+	if len(candidates) == 0 {
+		s.logger.Info(fmt.Sprintf("Request %s: no Anthropic endpoint available to resume the stream from endpoint %s", requestID, failedEndpoint.Name))
+		return nil, false
+	}
+	maxAttempts := s.config.StreamResume.MaxAttempts
+	startTime := time.Now()
+	nextAttempt := attemptNumber + 1
+	for i, ep := range candidates {
+		if i >= maxAttempts {
+			break
+		}
+		if c.Request.Context().Err() != nil {
+			return nil, false
+		}
+
+		s.logger.Info(fmt.Sprintf("Request %s: resuming interrupted stream from endpoint %s on endpoint %s (%d content blocks already delivered)", requestID, failedEndpoint.Name, ep.Name, len(delivered.blocks)))
+
+		// 原始请求的 body 已经被读取，续写使用新的 request
+		resumeRequest := c.Request.Clone(c.Request.Context())
+		resumeRequest.Body = io.NopCloser(bytes.NewReader(resumeBody))
+		resumeRequest.ContentLength = int64(len(resumeBody))
+
+		stitchWriter := newStreamStitchWriter(c.Writer, delivered)
+		resumeCtx := c.Copy()
+		resumeCtx.Request = resumeRequest
+		resumeCtx.Writer = stitchWriter
+		resumeCtx.Set("stream_resumed", true)
+		// 不继承被打断请求的状态
+		resumeCtx.Set("skip_health_record", false)
+		resumeCtx.Set("saturated_endpoint", "")
+		resumeCtx.Set("circuit_probe", "")
+		resumeCtx.Set("last_error", nil)
+
+		success, shouldTryNext := s.tryProxyRequestWithRetry(resumeCtx, ep, resumeBody, requestID, startTime, path, taggedRequest, nextAttempt)
+		nextAttempt += s.retryPolicyFor(ep).maxAttempts
+		if success {
+			resumeCtx.Set("resumed_endpoint", ep)
+			return resumeCtx, true
+		}
+		// 续写已经向客户端写出数据后失败，无法再换端点
+		if stitchWriter.wroteEvents {
+			return resumeCtx, false
+		}
+		if !shouldTryNext {
+			break
+		}
+	}
+	return nil, false
+}
+
+// resumeCandidates 按回退顺序返回可以续写的端点：只有 Anthropic 端点支持以 assistant 预填充结尾的请求，
+// 需要格式转换的端点（OpenAI、Gemini）会把预填充当作一条完整的历史消息，无法接着已发送的内容续写
+func (s *Server) resumeCandidates(failedEndpoint *endpoint.Endpoint, requestTags []string) []*endpoint.Endpoint {
+	var candidates []*endpoint.Endpoint
+	for _, ep := range s.fallbackCandidates(failedEndpoint, requestTags) {
+		if !s.converter.ShouldConvert(ep.EndpointType) {
+			candidates = append(candidates, ep)
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,520 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseEvent 构造单个 Anthropic SSE 事件
+func sseEvent(data string) string {
+	var payload struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal([]byte(data), &payload)
+	return fmt.Sprintf("event: %s\ndata: %s\n\n", payload.Type, data)
+}
+
+// stitchedEvents 解析拼接后客户端收到的事件，返回每个事件的 data
+func stitchedEvents(t *testing.T, body string) []map[string]interface{} {
+	t.Helper()
+	var events []map[string]interface{}
+	for _, event := range strings.Split(body, "\n\n") {
+		data := sseEventData([]byte(event))
+		if data == nil {
+			continue
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			t.Fatalf("invalid event data %q: %v", data, err)
+		}
+		events = append(events, payload)
+	}
+	return events
+}
+
+const (
+	testResumeMessageStart = `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}`
+	testResumeThinking     = `{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}` + "\n" +
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me "}}` + "\n" +
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"think."}}` + "\n" +
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"EqQBCkYIBxgCKkA"}}` + "\n" +
+		`{"type":"content_block_stop","index":0}`
+)
+
+// deliveredEvents 把多行事件 data 转换为客户端已经收到的 SSE 流
+func deliveredEvents(lines ...string) []byte {
+	var body strings.Builder
+	for _, line := range lines {
+		for _, data := range strings.Split(line, "\n") {
+			body.WriteString(sseEvent(data))
+		}
+	}
+	return []byte(body.String())
+}
+
+func TestParseDeliveredMessage(t *testing.T) {
+	clientBody := deliveredEvents(
+		testResumeMessageStart,
+		testResumeThinking,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"EmwKAhgBEgy3"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"text_delta","text":"Hello "}}`,
+	)
+
+	delivered := parseDeliveredMessage(clientBody)
+	if !delivered.started || delivered.ended {
+		t.Fatalf("expected started and not ended message, got started=%v ended=%v", delivered.started, delivered.ended)
+	}
+	if len(delivered.blocks) != 3 {
+		t.Fatalf("expected 3 delivered blocks, got %d", len(delivered.blocks))
+	}
+	thinking, redacted, text := delivered.blocks[0], delivered.blocks[1], delivered.blocks[2]
+	if thinking.blockType != "thinking" || thinking.text.String() != "Let me think." || thinking.signature != "EqQBCkYIBxgCKkA" || !thinking.complete {
+		t.Errorf("unexpected thinking block: type=%s text=%q signature=%q complete=%v", thinking.blockType, thinking.text.String(), thinking.signature, thinking.complete)
+	}
+	if redacted.blockType != "redacted_thinking" || redacted.data != "EmwKAhgBEgy3" || !redacted.complete {
+		t.Errorf("unexpected redacted_thinking block: type=%s data=%q complete=%v", redacted.blockType, redacted.data, redacted.complete)
+	}
+	if text.blockType != "text" || text.text.String() != "Hello " || text.complete {
+		t.Errorf("unexpected text block: type=%s text=%q complete=%v", text.blockType, text.text.String(), text.complete)
+	}
+	if !delivered.resumable() || delivered.openTextIndex() != 2 {
+		t.Errorf("expected resumable message with open text block 2, got resumable=%v open=%d", delivered.resumable(), delivered.openTextIndex())
+	}
+
+	// 预填充保留 thinking 块及签名，文本去掉末尾空白
+	prefill, _ := json.Marshal(delivered.prefillBlocks())
+	expected := `[{"signature":"EqQBCkYIBxgCKkA","thinking":"Let me think.","type":"thinking"},{"data":"EmwKAhgBEgy3","type":"redacted_thinking"},{"text":"Hello","type":"text"}]`
+	if string(prefill) != expected {
+		t.Errorf("unexpected prefill:\n got %s\nwant %s", prefill, expected)
+	}
+
+	ended := parseDeliveredMessage(append(clientBody, deliveredEvents(
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+	)...))
+	if !ended.ended || ended.resumable() {
+		t.Errorf("expected message_delta to end the message and make it not resumable")
+	}
+}
+
+func TestDeliveredMessageResumable(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    []string
+		resumable bool
+	}{
+		{"nothing delivered", nil, false},
+		{"only message_start", []string{testResumeMessageStart}, true},
+		{"open text block", []string{testResumeMessageStart,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`}, true},
+		{"complete signed thinking", []string{testResumeMessageStart, testResumeThinking}, true},
+		{"interrupted inside thinking", []string{testResumeMessageStart,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me"}}`}, false},
+		{"thinking without signature", []string{testResumeMessageStart,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me"}}`,
+			`{"type":"content_block_stop","index":0}`}, false},
+		{"tool_use delivered", []string{testResumeMessageStart,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"LS","input":{}}}`,
+			`{"type":"content_block_stop","index":0}`}, false},
+		{"message_delta delivered", []string{testResumeMessageStart,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseDeliveredMessage(deliveredEvents(tt.events...)).resumable(); got != tt.resumable {
+				t.Errorf("resumable() = %v, want %v", got, tt.resumable)
+			}
+		})
+	}
+}
+
+func TestBuildResumeRequestBody(t *testing.T) {
+	textPrefill := []interface{}{map[string]interface{}{"type": "text", "text": "Hello"}}
+	thinkingPrefill := []interface{}{
+		map[string]interface{}{"type": "thinking", "thinking": "Let me think.", "signature": "EqQBCkYIBxgCKkA"},
+		map[string]interface{}{"type": "text", "text": "Hello"},
+	}
+	tests := []struct {
+		name          string
+		request       string
+		prefill       []interface{}
+		lastContent   string
+		keepsThinking bool
+	}{
+		{
+			name:        "appends assistant message",
+			request:     `{"model":"claude-sonnet-4","thinking":{"type":"enabled","budget_tokens":512},"messages":[{"role":"user","content":"hi"}]}`,
+			prefill:     textPrefill,
+			lastContent: `[{"text":"Hello","type":"text"}]`,
+		},
+		{
+			name:        "extends client prefill",
+			request:     `{"model":"claude-sonnet-4","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"Sure:"}]}`,
+			prefill:     textPrefill,
+			lastContent: `[{"text":"Sure:","type":"text"},{"text":"Hello","type":"text"}]`,
+		},
+		{
+			name:          "keeps delivered thinking",
+			request:       `{"model":"claude-sonnet-4","thinking":{"type":"enabled","budget_tokens":512},"messages":[{"role":"user","content":"hi"}]}`,
+			prefill:       thinkingPrefill,
+			lastContent:   `[{"signature":"EqQBCkYIBxgCKkA","thinking":"Let me think.","type":"thinking"},{"text":"Hello","type":"text"}]`,
+			keepsThinking: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := buildResumeRequestBody([]byte(tt.request), tt.prefill)
+			if err != nil {
+				t.Fatalf("buildResumeRequestBody failed: %v", err)
+			}
+			var request struct {
+				Thinking json.RawMessage `json:"thinking"`
+				Messages []struct {
+					Role    string          `json:"role"`
+					Content json.RawMessage `json:"content"`
+				} `json:"messages"`
+			}
+			if err := json.Unmarshal(body, &request); err != nil {
+				t.Fatalf("invalid resume request: %v", err)
+			}
+			last := request.Messages[len(request.Messages)-1]
+			if last.Role != "assistant" || string(last.Content) != tt.lastContent {
+				t.Errorf("unexpected last message %s: %s", last.Role, last.Content)
+			}
+			if (request.Thinking != nil) != tt.keepsThinking {
+				t.Errorf("expected thinking kept=%v, got %s", tt.keepsThinking, request.Thinking)
+			}
+		})
+	}
+
+	// 没有可预填充的内容时原样返回
+	original := []byte(`{"model":"claude-sonnet-4","messages":[{"role":"user","content":"hi"}]}`)
+	if body, err := buildResumeRequestBody(original, nil); err != nil || string(body) != string(original) {
+		t.Errorf("expected request to be returned unchanged, got %s (err=%v)", body, err)
+	}
+}
+
+// runStitchWriter 把续写事件写入 streamStitchWriter，返回客户端收到的事件
+func runStitchWriter(t *testing.T, delivered []byte, resumed ...string) []map[string]interface{} {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	writer := newStreamStitchWriter(c.Writer, parseDeliveredMessage(delivered))
+	// 分片写入，模拟事件跨越多次 Write
+	stream := deliveredEvents(resumed...)
+	for len(stream) > 0 {
+		n := 7
+		if n > len(stream) {
+			n = len(stream)
+		}
+		writer.Write(stream[:n])
+		stream = stream[n:]
+	}
+	return stitchedEvents(t, recorder.Body.String())
+}
+
+// describeEvents 把事件简化为 "类型:index" 便于比较
+func describeEvents(events []map[string]interface{}) string {
+	var parts []string
+	for _, event := range events {
+		part := event["type"].(string)
+		if index, ok := event["index"].(float64); ok {
+			part += fmt.Sprintf(":%d", int(index))
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestStreamStitchWriterMergesOpenTextBlock(t *testing.T) {
+	delivered := deliveredEvents(
+		testResumeMessageStart,
+		testResumeThinking,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Hello "}}`,
+	)
+	events := runStitchWriter(t, delivered,
+		testResumeMessageStart,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"LS","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":5}}`,
+		`{"type":"message_stop"}`,
+	)
+
+	// message_start 被丢弃，第一个文本块并入被打断的块 1，tool_use 顺延到块 2
+	expected := "content_block_delta:1 content_block_stop:1 content_block_start:2 content_block_delta:2 content_block_stop:2 message_delta message_stop"
+	if got := describeEvents(events); got != expected {
+		t.Fatalf("unexpected stitched events:\n got %s\nwant %s", got, expected)
+	}
+	// 被打断的文本以空白结尾，续写开头的空白被去掉（只有空白的 delta 整个丢弃）
+	if text := events[0]["delta"].(map[string]interface{})["text"]; text != "world" {
+		t.Errorf("expected leading whitespace to be trimmed, got %q", text)
+	}
+}
+
+func TestStreamStitchWriterShiftsBlocksAfterCompleteContent(t *testing.T) {
+	delivered := deliveredEvents(testResumeMessageStart, testResumeThinking)
+	events := runStitchWriter(t, delivered,
+		testResumeMessageStart,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+	)
+
+	// 没有未结束的文本块，续写的块从已发送块之后开始编号
+	expected := "content_block_start:1 content_block_delta:1 content_block_stop:1 message_delta"
+	if got := describeEvents(events); got != expected {
+		t.Errorf("unexpected stitched events:\n got %s\nwant %s", got, expected)
+	}
+}
+
+func TestStreamStitchWriterClosesOpenBlock(t *testing.T) {
+	delivered := deliveredEvents(
+		testResumeMessageStart,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+	)
+
+	// 续写先返回了非文本块：先结束被打断的文本块
+	events := runStitchWriter(t, delivered,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"LS","input":{}}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":5}}`,
+	)
+	expected := "content_block_stop:0 content_block_start:1 content_block_stop:1 message_delta"
+	if got := describeEvents(events); got != expected {
+		t.Errorf("unexpected stitched events:\n got %s\nwant %s", got, expected)
+	}
+
+	// 续写没有返回任何内容块：message_delta 之前结束被打断的文本块
+	events = runStitchWriter(t, delivered,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+	)
+	if got := describeEvents(events); got != "content_block_stop:0 message_delta" {
+		t.Errorf("unexpected stitched events: %s", got)
+	}
+}
+
+func TestStreamResumeOnAnotherEndpoint(t *testing.T) {
+	interrupted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write(deliveredEvents(
+			testResumeMessageStart,
+			testResumeThinking,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Hello"}}`,
+		))
+		// 在 message_stop 之前结束流
+	}))
+	defer interrupted.Close()
+
+	var resumeRequest []byte
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resumeRequest, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write(deliveredEvents(
+			testResumeMessageStart,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+			`{"type":"message_stop"}`,
+		))
+	}))
+	defer backup.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: interrupted
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+    - name: backup
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+stream_resume:
+    enabled: true
+    max_attempts: 2
+`, interrupted.URL, backup.URL))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":1024,"stream":true,"thinking":{"type":"enabled","budget_tokens":512},"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, req)
+
+	// 续写请求带上已发送的 thinking 块和文本，并保留 thinking 参数
+	if !strings.Contains(string(resumeRequest), `{"content":[{"signature":"EqQBCkYIBxgCKkA","thinking":"Let me think.","type":"thinking"},{"text":"Hello","type":"text"}],"role":"assistant"}`) {
+		t.Errorf("expected delivered thinking and text in the prefill, got %s", resumeRequest)
+	}
+	if !strings.Contains(string(resumeRequest), `"thinking":{"budget_tokens":512,"type":"enabled"}`) {
+		t.Errorf("expected thinking parameter to be kept, got %s", resumeRequest)
+	}
+
+	body := recorder.Body.String()
+	if strings.Contains(body, "event: error") {
+		t.Fatalf("expected resumed stream without error event, got %s", body)
+	}
+	expected := "message_start content_block_start:0 content_block_delta:0 content_block_delta:0 content_block_delta:0 content_block_stop:0 content_block_start:1 content_block_delta:1 content_block_delta:1 content_block_stop:1 message_delta message_stop"
+	if got := describeEvents(stitchedEvents(t, body)); got != expected {
+		t.Errorf("unexpected client stream:\n got %s\nwant %s", got, expected)
+	}
+
+	// 被打断的端点记为失败，续写的端点记为成功
+	for _, ep := range server.endpointManager.GetAllEndpoints() {
+		switch ep.Name {
+		case "interrupted":
+			if ep.TotalRequests != 1 || ep.SuccessRequests != 0 {
+				t.Errorf("expected interrupted endpoint to record one failure, got %d/%d successful", ep.SuccessRequests, ep.TotalRequests)
+			}
+		case "backup":
+			if ep.TotalRequests != 1 || ep.SuccessRequests != 1 {
+				t.Errorf("expected backup endpoint to record one success, got %d/%d successful", ep.SuccessRequests, ep.TotalRequests)
+			}
+		}
+	}
+}
+
+func TestStreamResumeRecordsFailureOnInterruptedEndpoint(t *testing.T) {
+	interrupted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write(deliveredEvents(
+			testResumeMessageStart,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		))
+	}))
+	defer interrupted.Close()
+
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write(deliveredEvents(
+			testResumeMessageStart,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+			`{"type":"message_stop"}`,
+		))
+	}))
+	defer backup.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: interrupted
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-interrupted-1
+      auth_values: [sk-interrupted-2]
+      enabled: true
+      priority: 1
+    - name: backup
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-backup-1
+      auth_values: [sk-backup-2]
+      enabled: true
+      priority: 2
+stream_resume:
+    enabled: true
+    max_attempts: 1
+`, interrupted.URL, backup.URL))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":1024,"stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, req)
+
+	if strings.Contains(recorder.Body.String(), "event: error") {
+		t.Fatalf("expected resumed stream without error event, got %s", recorder.Body.String())
+	}
+
+	// 被打断的端点和它使用的凭据记为失败，续写的端点和它使用的凭据记为成功
+	for _, ep := range server.endpointManager.GetAllEndpoints() {
+		wantSuccess := 0
+		if ep.Name == "backup" {
+			wantSuccess = 1
+		}
+		if ep.TotalRequests != 1 || ep.SuccessRequests != wantSuccess {
+			t.Errorf("endpoint %s: expected %d/1 successful requests, got %d/%d", ep.Name, wantSuccess, ep.SuccessRequests, ep.TotalRequests)
+		}
+		var total, successful int
+		for _, key := range ep.GetAPIKeyStatus() {
+			total += key.TotalRequests
+			successful += key.SuccessRequests
+		}
+		if total != 1 || successful != wantSuccess {
+			t.Errorf("endpoint %s: expected API keys to record %d/1 successful requests, got %d/%d", ep.Name, wantSuccess, successful, total)
+		}
+	}
+}
+
+func TestResumeCandidatesOnlyAnthropicEndpoints(t *testing.T) {
+	server := newTestServer(t, `endpoints:
+    - name: failed
+      url: https://failed.example.com
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+    - name: openai
+      url: https://openai.example.com
+      endpoint_type: openai
+      path_prefix: /v1/chat/completions
+      auth_type: auth_token
+      auth_value: sk-test
+      enabled: true
+      priority: 2
+    - name: gemini
+      url: https://gemini.example.com
+      endpoint_type: gemini
+      auth_type: api_key
+      auth_value: test-key
+      enabled: true
+      priority: 3
+    - name: backup
+      url: https://backup.example.com
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 4
+`)
+
+	failed := server.endpointManager.GetAllEndpoints()[0]
+	candidates := server.resumeCandidates(failed, nil)
+	if len(candidates) != 1 || candidates[0].Name != "backup" {
+		names := make([]string, len(candidates))
+		for i, ep := range candidates {
+			names[i] = ep.Name
+		}
+		t.Errorf("expected only the backup Anthropic endpoint, got %v", names)
+	}
+}
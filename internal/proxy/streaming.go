@@ -0,0 +1,378 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/conversion"
+	"claude-code-companion/internal/endpoint"
+	"claude-code-companion/internal/tagging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 流式响应增量透传
+//
+// 上游返回 text/event-stream 时，不再等待整个响应体读取完毕，而是按SSE事件逐个处理：
+// 每个事件先经过 ResponseValidator.ValidateSSEChunk 校验，需要格式转换的端点再经过 StreamConverter
+// 转换为 Anthropic 事件，然后做模型名还原，最后立即写给客户端并 Flush。
+// 上游原始事件和发给客户端的事件会同时累积，结束后用于完整性校验和请求日志。
+//
+// 校验失败的处理策略以"是否已经向客户端写出字节"为分界：
+//   - 尚未写出任何字节（例如 message_start 的 usage 校验失败、首个事件就是 error、连接在首个事件前中断）：
+//     与非流式路径一致，记录日志并返回 shouldRetry=true，由重试逻辑在当前端点重试或切换端点。
+//   - 已经写出字节：HTTP状态码和部分内容已经发给客户端，无法再切换端点。此时向客户端补发一个
+//     Anthropic 格式的 error 事件（上游自己已发送 error 事件时不重复补发），本次请求记为该端点失败，
+//     并返回 shouldRetry=false 结束本次请求。
+//     启用 stream_resume 时，上游中途断开（读取失败或流不完整）会先尝试在其他端点续写，见 stream_resume.go。
+//   - 客户端主动断开：停止读取上游，记录日志，不计入端点健康统计。
+
+// sseReadBufferSize 读取上游SSE流的缓冲区大小
+const sseReadBufferSize = 64 * 1024
+
+// sseEventReader 按事件读取SSE流（事件之间以空行分隔）
+type sseEventReader struct {
+	reader *bufio.Reader
+}
+
+func newSSEEventReader(r io.Reader) *sseEventReader {
+	return &sseEventReader{reader: bufio.NewReaderSize(r, sseReadBufferSize)}
+}
+
+// Next 返回下一个完整的SSE事件（统一使用 \n 换行，并以空行结尾）
+// 上游结束时返回 io.EOF；如果最后一个事件没有以空行结尾，会把剩余内容和 io.EOF 一起返回
+func (r *sseEventReader) Next() ([]byte, error) {
+	var event bytes.Buffer
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			trimmed := bytes.TrimRight(line, "\r\n")
+			if len(trimmed) == 0 {
+				if event.Len() > 0 {
+					event.WriteString("\n")
+					return event.Bytes(), nil
+				}
+				// 跳过事件之间多余的空行
+			} else {
+				event.Write(trimmed)
+				event.WriteString("\n")
+			}
+		}
+		if err != nil {
+			if event.Len() > 0 {
+				event.WriteString("\n")
+				return event.Bytes(), err
+			}
+			return nil, err
+		}
+	}
+}
+
+// readCloser 组合读取器和原始响应体的关闭方法
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// sseEventType 提取SSE事件的 event 字段
+func sseEventType(event []byte) string {
+	for _, line := range bytes.Split(event, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("event:")) {
+			return strings.TrimSpace(string(line[6:]))
+		}
+	}
+	return ""
+}
+
+// shouldStreamResponse 判断响应是否走增量透传路径
+func (s *Server) shouldStreamResponse(resp *http.Response, path string) bool {
+	if strings.Contains(path, "/count_tokens") {
+		return false
+	}
+	if !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/event-stream") {
+		return false
+	}
+
+	// 部分上游会把JSON响应错误地标记为SSE，这种情况交给完整读取逻辑（SmartDetectContentType）处理
+	if !s.validator.IsGzipContent(resp.Header.Get("Content-Encoding")) {
+		buffered := bufio.NewReaderSize(resp.Body, sseReadBufferSize)
+		resp.Body = readCloser{Reader: buffered, Closer: resp.Body}
+		peek, _ := buffered.Peek(16)
+		if bytes.HasPrefix(bytes.TrimSpace(peek), []byte("{")) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeStreamingHeaders 在第一次写出数据前设置SSE响应头
+func (s *Server) writeStreamingHeaders(c *gin.Context, resp *http.Response) {
+	for key, values := range resp.Header {
+		keyLower := strings.ToLower(key)
+		if keyLower == "content-length" || keyLower == "content-encoding" {
+			// 发送给客户端的是解压后的逐事件数据
+			continue
+		}
+		for _, value := range values {
+			c.Header(key, value)
+		}
+	}
+	c.Header("Content-Type", "text/event-stream; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 防止中间层缓冲
+	c.Status(resp.StatusCode)
+	c.Writer.WriteHeaderNow()
+}
+
+// buildSSEErrorEvent 构造 Anthropic 格式的 error 事件
+func buildSSEErrorEvent(errorType, message string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type": "error",
+		"error": map[string]interface{}{
+			"type":    errorType,
+			"message": message,
+		},
+	})
+	return []byte(fmt.Sprintf("event: error\ndata: %s\n\n", data))
+}
+
+// streamSSEResponse 逐事件读取上游SSE流并立即转发给客户端
+func (s *Server) streamSSEResponse(c *gin.Context, ep *endpoint.Endpoint, path string, req *http.Request, resp *http.Response, requestID string, requestBody, finalRequestBody []byte, endpointStartTime time.Time, tags []string, originalModel, rewrittenModel string, attemptNumber int, conversionContext *conversion.ConversionContext, taggedRequest *tagging.TaggedRequest) (bool, bool) {
+	var bodyReader io.Reader = resp.Body
+	if s.validator.IsGzipContent(resp.Header.Get("Content-Encoding")) {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			s.logger.Error("Failed to decompress response body", err)
+			duration := time.Since(endpointStartTime)
+			decompressError := fmt.Errorf("Failed to decompress response body: %v", err)
+			s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, resp, nil, duration, decompressError, true, tags, "", originalModel, rewrittenModel, attemptNumber)
+			c.Set("last_error", decompressError)
+			c.Set("last_status_code", resp.StatusCode)
+			return false, false
+		}
+		defer gzipReader.Close()
+		bodyReader = gzipReader
+	}
+
+	// 监控Anthropic rate limit headers
+	if ep.ShouldMonitorRateLimit() {
+		if err := s.processRateLimitHeaders(ep, resp.Header, requestID); err != nil {
+			s.logger.Error("Failed to process rate limit headers", err)
+		}
+	}
+	// 所有提供商的速率限制头部：记录剩余额度，接近耗尽时跳过该端点
+	s.trackRateLimitHeaders(ep, resp.StatusCode, resp.Header, requestID)
+
+	// 需要格式转换的端点使用增量转换器，逐个事件转换为 Anthropic 格式
+	var streamConverter conversion.StreamConverter
+	var sseBuilder *conversion.SSEParser
+	if conversionContext != nil {
+		streamConverter = s.converter.NewStreamConverter(conversionContext)
+		sseBuilder = conversion.NewSSEParser(s.logger)
+	}
+
+	reader := newSSEEventReader(bodyReader)
+	var upstreamBody bytes.Buffer // 上游原始事件，用于校验和日志
+	var clientBody bytes.Buffer   // 实际发送给客户端的事件
+	committed := false            // 是否已经向客户端写出字节
+	upstreamErrorSent := false    // 上游是否已经发送过 error 事件
+
+	// writeEvent 向客户端写出事件并立即 Flush，第一次写出前设置响应头
+	writeEvent := func(data []byte) error {
+		if len(data) == 0 {
+			return nil
+		}
+		if !committed {
+			s.writeStreamingHeaders(c, resp)
+			committed = true
+			c.Set("first_token_time", time.Now())
+		}
+		if _, err := c.Writer.Write(data); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		clientBody.Write(data)
+		return nil
+	}
+
+	// clientGone 客户端断开连接，停止读取上游，不计入端点健康统计
+	clientGone := func(cause error) (bool, bool) {
+		s.logger.Info(fmt.Sprintf("Client disconnected during streaming response from endpoint %s: %v", ep.Name, cause))
+		duration := time.Since(endpointStartTime)
+		clientErr := fmt.Errorf("client disconnected during streaming: %v", cause)
+		s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, resp, upstreamBody.Bytes(), duration, clientErr, true, tags, "", originalModel, rewrittenModel, attemptNumber)
+		c.Set("skip_health_record", true) // 客户端断开不是端点的问题
+		c.Set("last_error", clientErr)
+		c.Set("last_status_code", resp.StatusCode)
+		return false, false
+	}
+
+	// failStream 统一处理流式过程中的失败
+	failStream := func(streamErr error) (bool, bool) {
+		duration := time.Since(endpointStartTime)
+		c.Set("last_status_code", resp.StatusCode)
+
+		if !committed {
+			// 客户端尚未收到任何数据，按普通失败处理，允许重试或切换端点
+			s.logger.Info(fmt.Sprintf("Streaming response from endpoint %s failed before first byte: %v", ep.Name, streamErr))
+			s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, resp, upstreamBody.Bytes(), duration, streamErr, true, tags, "", originalModel, rewrittenModel, attemptNumber)
+			c.Set("last_error", streamErr)
+			return false, true
+		}
+
+		// 已经向客户端写出部分内容，无法切换端点，补发error事件后结束
+		abortErr := fmt.Errorf("Stream aborted after partial response: %v", streamErr)
+		s.logger.Error(fmt.Sprintf("Streaming response from endpoint %s aborted after %d bytes sent to client", ep.Name, clientBody.Len()), streamErr)
+		if !upstreamErrorSent {
+			errorEvent := buildSSEErrorEvent("api_error", fmt.Sprintf("upstream stream interrupted (request %s): %v", requestID, streamErr))
+			writeEvent(errorEvent)
+		}
+		s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, resp, upstreamBody.Bytes(), duration, abortErr, true, tags, "", originalModel, rewrittenModel, attemptNumber)
+		c.Set("last_error", abortErr)
+		return false, false
+	}
+
+	// interruptStream 上游中途断开：已经向客户端写出内容时先尝试断流续传，失败再按普通流程处理
+	interruptStream := func(streamErr error) (bool, bool) {
+		if !committed || upstreamErrorSent {
+			return failStream(streamErr)
+		}
+		resumeCtx, resumed := s.tryResumeStream(c, ep, path, requestBody, requestID, clientBody.Bytes(), taggedRequest, attemptNumber)
+		if resumeCtx == nil {
+			return failStream(streamErr)
+		}
+
+		duration := time.Since(endpointStartTime)
+		resumeErr := fmt.Errorf("Stream interrupted after partial response, resumed on another endpoint: %v", streamErr)
+		s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, resp, upstreamBody.Bytes(), duration, resumeErr, true, tags, "", originalModel, rewrittenModel, attemptNumber)
+		c.Set("last_status_code", resp.StatusCode)
+		c.Set("last_error", resumeErr)
+		// 被打断的端点和凭据记为失败，请求本身的结果由续写决定；续写端点的结果已经在续写请求中记录，
+		// 外层重试循环不再为被打断的端点和凭据记录结果
+		s.endpointManager.RecordRequest(ep.ID, false, requestID)
+		recordAPIKeyResult(c, ep, false, requestID)
+		c.Set("api_key_index", -1)
+		c.Set("skip_health_record", true)
+		if !resumed {
+			return false, false
+		}
+
+		// 续写成功：本次请求由续写的端点完成
+		for _, key := range []string{"served_endpoint", "resumed_endpoint", "token_usage", "request_cost"} {
+			if value, exists := resumeCtx.Get(key); exists {
+				c.Set(key, value)
+			}
+		}
+		c.Set("last_error", nil)
+		return true, false
+	}
+
+	for {
+		// 客户端已断开时不再继续读取上游
+		select {
+		case <-c.Request.Context().Done():
+			return clientGone(c.Request.Context().Err())
+		default:
+		}
+
+		event, readErr := reader.Next()
+		if len(event) > 0 {
+			upstreamBody.Write(event)
+
+			if err := s.validator.ValidateSSEChunk(event, ep.EndpointType); err != nil {
+				if strings.Contains(err.Error(), "invalid usage stats") {
+					return failStream(fmt.Errorf("Usage validation failed: %v", err))
+				}
+				return failStream(fmt.Errorf("Response validation failed: %v", err))
+			}
+
+			if sseEventType(event) == "error" {
+				if !committed {
+					// 第一个事件就是错误，客户端还没有收到数据，换端点重试
+					return failStream(fmt.Errorf("upstream returned SSE error event: %s", strings.TrimSpace(string(event))))
+				}
+				upstreamErrorSent = true
+			}
+
+			outEvent := event
+			if streamConverter != nil {
+				convertedEvents, err := streamConverter.ProcessEvent(event)
+				if err != nil {
+					return failStream(fmt.Errorf("Response format conversion failed: %v", err))
+				}
+				outEvent = sseBuilder.BuildAnthropicSSEFromEvents(convertedEvents)
+			}
+
+			// 应用响应模型重写（如果进行了请求模型重写）
+			if len(outEvent) > 0 && originalModel != "" && rewrittenModel != "" {
+				rewrittenEvent, err := s.modelRewriter.RewriteResponse(outEvent, originalModel, rewrittenModel)
+				if err != nil {
+					s.logger.Error("Failed to rewrite response model", err)
+				} else if len(rewrittenEvent) > 0 {
+					outEvent = rewrittenEvent
+				}
+			}
+
+			if err := writeEvent(outEvent); err != nil {
+				return clientGone(err)
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return interruptStream(fmt.Errorf("Failed to read response body: %v", readErr))
+		}
+	}
+
+	if upstreamBody.Len() == 0 {
+		return failStream(fmt.Errorf("Incomplete SSE stream: empty response body"))
+	}
+
+	// 上游结束后验证完整SSE流的完整性
+	if err := s.validator.ValidateCompleteSSEStream(upstreamBody.Bytes(), ep.EndpointType); err != nil {
+		return interruptStream(fmt.Errorf("Incomplete SSE stream: %v", err))
+	}
+
+	if upstreamErrorSent {
+		return failStream(fmt.Errorf("upstream returned SSE error event during streaming"))
+	}
+
+	// 转换器补齐未关闭的内容块和 message_delta/message_stop
+	if streamConverter != nil {
+		finalEvents, err := streamConverter.Finish()
+		if err != nil {
+			return failStream(fmt.Errorf("Response format conversion failed: %v", err))
+		}
+		outEvent := sseBuilder.BuildAnthropicSSEFromEvents(finalEvents)
+		if originalModel != "" && rewrittenModel != "" {
+			if rewrittenEvent, err := s.modelRewriter.RewriteResponse(outEvent, originalModel, rewrittenModel); err == nil && len(rewrittenEvent) > 0 {
+				outEvent = rewrittenEvent
+			}
+		}
+		if err := writeEvent(outEvent); err != nil {
+			return clientGone(err)
+		}
+	}
+
+	// 清除错误信息（成功情况）
+	c.Set("last_error", nil)
+	c.Set("last_status_code", resp.StatusCode)
+
+	// 累计端点预算用量
+	s.recordEndpointUsage(c, ep, requestID, upstreamBody.Bytes())
+
+	duration := time.Since(endpointStartTime)
+	s.logSuccessfulRequest(requestID, ep, path, c, req, resp, requestBody, finalRequestBody, upstreamBody.Bytes(), clientBody.Bytes(), duration, true, tags, "", originalModel, rewrittenModel, attemptNumber)
+
+	return true, false
+}
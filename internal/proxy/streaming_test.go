@@ -0,0 +1,262 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/endpoint"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testSSEMessageStart = "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"model\":\"claude-sonnet-4\",\"content\":[],\"usage\":{\"input_tokens\":10,\"output_tokens\":1}}}\n\n"
+	testSSETextDelta    = "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n"
+	testSSEMessageEnd = "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n" +
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":5}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+)
+
+// fakeSSEBody 模拟上游SSE响应体：依次返回各个分片，读完后返回 err（默认 io.EOF）
+// afterChunk 在返回指定序号的分片后调用，用于模拟读取过程中客户端断开
+type fakeSSEBody struct {
+	chunks     []string
+	err        error
+	afterChunk map[int]func()
+	index      int
+}
+
+func (b *fakeSSEBody) Read(p []byte) (int, error) {
+	if b.index >= len(b.chunks) {
+		if b.err != nil {
+			return 0, b.err
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, b.chunks[b.index])
+	b.chunks[b.index] = b.chunks[b.index][n:]
+	if b.chunks[b.index] == "" {
+		if hook := b.afterChunk[b.index]; hook != nil {
+			hook()
+		}
+		b.index++
+	}
+	return n, nil
+}
+
+func (b *fakeSSEBody) Close() error { return nil }
+
+// streamTestResult 一次 streamSSEResponse 调用的结果
+type streamTestResult struct {
+	success     bool
+	shouldRetry bool
+	recorder    *httptest.ResponseRecorder
+	context     *gin.Context
+}
+
+// runStreamSSEResponse 使用 httptest 记录器和模拟的上游响应体调用 streamSSEResponse
+func runStreamSSEResponse(t *testing.T, ctx context.Context, body *fakeSSEBody) streamTestResult {
+	t.Helper()
+	server := newTestServer(t, `endpoints:
+    - name: primary
+      url: https://api.example.com
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+`)
+	ep := endpoint.NewEndpoint(config.EndpointConfig{
+		Name:         "primary",
+		URL:          "https://api.example.com",
+		EndpointType: "anthropic",
+		AuthType:     "api_key",
+		AuthValue:    "sk-test",
+		Enabled:      true,
+	})
+
+	requestBody := []byte(`{"model":"claude-sonnet-4","max_tokens":16,"stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(string(requestBody))).WithContext(ctx)
+	upstreamReq := httptest.NewRequest(http.MethodPost, ep.URL+"/v1/messages", strings.NewReader(string(requestBody)))
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       body,
+		Request:    upstreamReq,
+	}
+
+	success, shouldRetry := server.streamSSEResponse(c, ep, "/v1/messages", upstreamReq, resp, "req-stream", requestBody, requestBody, time.Now(), nil, "", "", 1, nil, nil)
+	return streamTestResult{success: success, shouldRetry: shouldRetry, recorder: recorder, context: c}
+}
+
+func lastStreamError(c *gin.Context) string {
+	if err, ok := c.Get("last_error"); ok && err != nil {
+		return err.(error).Error()
+	}
+	return ""
+}
+
+func TestStreamSSEResponseSuccess(t *testing.T) {
+	body := &fakeSSEBody{chunks: []string{testSSEMessageStart, testSSETextDelta, testSSEMessageEnd}}
+	result := runStreamSSEResponse(t, context.Background(), body)
+
+	if !result.success || result.shouldRetry {
+		t.Fatalf("expected success, got (%v, %v): %s", result.success, result.shouldRetry, lastStreamError(result.context))
+	}
+	expected := testSSEMessageStart + testSSETextDelta + testSSEMessageEnd
+	if result.recorder.Body.String() != expected {
+		t.Errorf("expected all events to be forwarded, got %q", result.recorder.Body.String())
+	}
+	if contentType := result.recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/event-stream") {
+		t.Errorf("expected SSE content type, got %q", contentType)
+	}
+}
+
+func TestStreamSSEResponseFailureBeforeFirstEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  *fakeSSEBody
+		error string
+	}{
+		{"connection reset", &fakeSSEBody{err: errors.New("connection reset by peer")}, "Failed to read response body"},
+		{"empty body", &fakeSSEBody{}, "empty response body"},
+		{"error event", &fakeSSEBody{chunks: []string{"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"}}, "upstream returned SSE error event"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runStreamSSEResponse(t, context.Background(), tt.body)
+
+			// 客户端还没有收到任何字节，交给重试逻辑重试或切换端点
+			if result.success || !result.shouldRetry {
+				t.Errorf("expected (false, true), got (%v, %v)", result.success, result.shouldRetry)
+			}
+			if result.context.Writer.Written() || result.recorder.Body.Len() != 0 {
+				t.Errorf("expected nothing written to client, got %q", result.recorder.Body.String())
+			}
+			if errText := lastStreamError(result.context); !strings.Contains(errText, tt.error) {
+				t.Errorf("expected last_error to contain %q, got %q", tt.error, errText)
+			}
+		})
+	}
+}
+
+func TestStreamSSEResponseFailureAfterFirstEvent(t *testing.T) {
+	tests := []struct {
+		name              string
+		body              *fakeSSEBody
+		error             string
+		upstreamErrorSent bool
+	}{
+		{"connection reset", &fakeSSEBody{chunks: []string{testSSEMessageStart, testSSETextDelta}, err: errors.New("connection reset by peer")}, "Failed to read response body", false},
+		{"missing message_stop", &fakeSSEBody{chunks: []string{testSSEMessageStart, testSSETextDelta}}, "Incomplete SSE stream", false},
+		{"upstream error event", &fakeSSEBody{chunks: []string{testSSEMessageStart, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"}}, "Stream aborted after partial response", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runStreamSSEResponse(t, context.Background(), tt.body)
+
+			// 已经写出部分内容，不能再切换端点
+			if result.success || result.shouldRetry {
+				t.Errorf("expected (false, false), got (%v, %v)", result.success, result.shouldRetry)
+			}
+			if result.recorder.Code != http.StatusOK {
+				t.Errorf("expected status 200 already sent to client, got %d", result.recorder.Code)
+			}
+			clientBody := result.recorder.Body.String()
+			if !strings.HasPrefix(clientBody, testSSEMessageStart) {
+				t.Errorf("expected partial response to be forwarded, got %q", clientBody)
+			}
+			// 上游自己发送了 error 事件时不重复补发
+			if count := strings.Count(clientBody, "event: error\n"); count != 1 {
+				t.Errorf("expected exactly one error event, got %d in %q", count, clientBody)
+			}
+			if !tt.upstreamErrorSent && !strings.Contains(clientBody, "upstream stream interrupted (request req-stream)") {
+				t.Errorf("expected proxy error event, got %q", clientBody)
+			}
+			errText := lastStreamError(result.context)
+			if !strings.Contains(errText, "Stream aborted after partial response") || !strings.Contains(errText, tt.error) {
+				t.Errorf("expected abort error containing %q, got %q", tt.error, errText)
+			}
+			if skip, _ := result.context.Get("skip_health_record"); skip == true {
+				t.Errorf("expected upstream failure to count against endpoint health")
+			}
+		})
+	}
+}
+
+func TestStreamSSEResponseClientDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	body := &fakeSSEBody{
+		chunks:     []string{testSSEMessageStart, testSSETextDelta, testSSEMessageEnd},
+		afterChunk: map[int]func(){0: cancel},
+	}
+	result := runStreamSSEResponse(t, ctx, body)
+
+	if result.success || result.shouldRetry {
+		t.Errorf("expected (false, false), got (%v, %v)", result.success, result.shouldRetry)
+	}
+	if result.recorder.Body.String() != testSSEMessageStart {
+		t.Errorf("expected only the first event before disconnect, got %q", result.recorder.Body.String())
+	}
+	if strings.Contains(result.recorder.Body.String(), "event: error") {
+		t.Errorf("expected no error event for a disconnected client")
+	}
+	if body.index >= len(body.chunks) {
+		t.Errorf("expected upstream reading to stop after client disconnect")
+	}
+	if skip, _ := result.context.Get("skip_health_record"); skip != true {
+		t.Errorf("expected client disconnect not to count against endpoint health")
+	}
+	if errText := lastStreamError(result.context); !strings.Contains(errText, "client disconnected") {
+		t.Errorf("expected client disconnect error, got %q", errText)
+	}
+}
+
+func TestStreamSSEResponseValidationFailure(t *testing.T) {
+	zeroUsageStart := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"usage\":{\"prompt_tokens\":0,\"completion_tokens\":0,\"total_tokens\":0}}}\n\n"
+	invalidEvent := "event: unknown_event\ndata: {\"type\":\"unknown_event\"}\n\n"
+
+	t.Run("before first event", func(t *testing.T) {
+		result := runStreamSSEResponse(t, context.Background(), &fakeSSEBody{chunks: []string{zeroUsageStart, testSSETextDelta, testSSEMessageEnd}})
+
+		if result.success || !result.shouldRetry {
+			t.Errorf("expected (false, true), got (%v, %v)", result.success, result.shouldRetry)
+		}
+		if result.context.Writer.Written() {
+			t.Errorf("expected nothing written to client, got %q", result.recorder.Body.String())
+		}
+		// usage 校验失败单独分类，由重试策略决定在当前端点重试
+		if errText := lastStreamError(result.context); !strings.HasPrefix(errText, "Usage validation failed") {
+			t.Errorf("expected usage validation error, got %q", errText)
+		}
+	})
+
+	t.Run("after first event", func(t *testing.T) {
+		result := runStreamSSEResponse(t, context.Background(), &fakeSSEBody{chunks: []string{testSSEMessageStart, invalidEvent, testSSEMessageEnd}})
+
+		if result.success || result.shouldRetry {
+			t.Errorf("expected (false, false), got (%v, %v)", result.success, result.shouldRetry)
+		}
+		clientBody := result.recorder.Body.String()
+		if strings.Contains(clientBody, invalidEvent) {
+			t.Errorf("expected invalid event not to be forwarded, got %q", clientBody)
+		}
+		if !strings.HasPrefix(clientBody, testSSEMessageStart) || !strings.HasSuffix(clientBody, "\n\n") || !strings.Contains(clientBody, "event: error\n") {
+			t.Errorf("expected partial response followed by an error event, got %q", clientBody)
+		}
+		if errText := lastStreamError(result.context); !strings.Contains(errText, "Response validation failed") {
+			t.Errorf("expected response validation error, got %q", errText)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+
+	"claude-code-companion/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware 为每个客户端请求创建根 span，并把带 span 的 context 放回请求，
+// 端点尝试和各处理阶段的 span 都挂在它下面
+func (s *Server) tracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := tracing.StartServer(c.Request, c.Request.Method+" "+c.Request.URL.Path,
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("url.path", c.Request.URL.Path),
+			attribute.String("request.id", c.GetString("request_id")),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", status),
+			attribute.String("endpoint.name", c.GetString("served_endpoint")),
+			attribute.String("model", c.GetString("original_model")),
+			attribute.StringSlice("request.tags", c.GetStringSlice("request_tags")),
+		)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// endAttemptSpan 按端点尝试的结果结束 span，失败原因取自重试逻辑使用的 last_error / last_status_code
+func endAttemptSpan(c *gin.Context, span trace.Span, success bool) {
+	statusCode := c.GetInt("last_status_code")
+	if statusCode > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+	if success {
+		span.End()
+		return
+	}
+	var err error
+	if value, exists := c.Get("last_error"); exists {
+		err, _ = value.(error)
+	}
+	if err == nil {
+		err = fmt.Errorf("endpoint attempt failed with HTTP %d", statusCode)
+	}
+	tracing.EndWithError(span, err)
+}
@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"claude-code-companion/internal/tracing/tracingtest"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddlewareContinuesIncomingTrace(t *testing.T) {
+	exporter := tracingtest.UseInMemoryExporter(t)
+
+	var upstreamTraceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testAnthropicResponse))
+	}))
+	defer upstream.Close()
+
+	server := newTestServer(t, fmt.Sprintf(`endpoints:
+    - name: traced
+      url: %s
+      endpoint_type: anthropic
+      auth_type: api_key
+      auth_value: sk-test
+      enabled: true
+      priority: 1
+`, upstream.URL))
+
+	const incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const incomingSpanID = "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"claude-sonnet-4","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+incomingTraceID+"-"+incomingSpanID+"-01")
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		if span.SpanContext.TraceID().String() != incomingTraceID {
+			t.Errorf("span %s is not part of the incoming trace: %s", span.Name, span.SpanContext.TraceID())
+		}
+		byName[span.Name] = span
+	}
+
+	// 根 span 是客户端 traceparent 的子 span
+	root, ok := byName["POST /v1/messages"]
+	if !ok {
+		t.Fatalf("expected a server span for the request, got %d spans", len(spans))
+	}
+	if root.SpanKind != trace.SpanKindServer || root.Parent.SpanID().String() != incomingSpanID || !root.Parent.IsRemote() {
+		t.Errorf("expected server span continuing the incoming trace, got kind %v parent %s", root.SpanKind, root.Parent.SpanID())
+	}
+	attempt, ok := byName["endpoint attempt"]
+	if !ok || attempt.Parent.SpanID() != root.SpanContext.SpanID() {
+		t.Errorf("expected endpoint attempt span under the server span")
+	}
+
+	// 上游请求携带上游 span 的 traceparent
+	upstreamSpan, ok := byName["upstream POST"]
+	if !ok {
+		t.Fatalf("expected an upstream client span")
+	}
+	expected := fmt.Sprintf("00-%s-%s-01", incomingTraceID, upstreamSpan.SpanContext.SpanID())
+	if upstreamTraceparent != expected {
+		t.Errorf("expected upstream traceparent %s, got %s", expected, upstreamTraceparent)
+	}
+}
@@ -68,6 +68,29 @@ func (am *AuthManager) AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// BasicAuthMiddleware 返回接受 HTTP Basic 凭据的身份验证中间件，供 Prometheus 等无法登录的抓取程序使用；
+// 已登录的会话同样可以访问，验证失败时返回 401 而不是重定向到登录页面
+func (am *AuthManager) BasicAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !am.enabled {
+			c.Next()
+			return
+		}
+
+		if username, password, ok := c.Request.BasicAuth(); ok && am.ValidateCredentials(username, password) {
+			c.Next()
+			return
+		}
+		if am.sessionManager.ValidateSession(am.sessionManager.GetSessionFromCookie(c)) {
+			c.Next()
+			return
+		}
+
+		c.Header("WWW-Authenticate", `Basic realm="Claude Code Companion"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
+
 // ValidateCredentials 验证用户凭据
 func (am *AuthManager) ValidateCredentials(username, password string) bool {
 	if !am.enabled {
@@ -0,0 +1,97 @@
+package statistics
+
+import (
+	"testing"
+	"time"
+)
+
+// eventStore 是 Manager 和 MemoryManager 共同的事件接口
+type eventStore interface {
+	RecordEndpointEvent(event *EndpointEvent) error
+	QueryEndpointEvents(filter EndpointEventFilter) ([]*EndpointEvent, int64, error)
+	CleanupEndpointEvents(before time.Time) (int64, error)
+}
+
+func TestEndpointEvents(t *testing.T) {
+	sqliteManager, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create statistics manager: %v", err)
+	}
+	defer sqliteManager.Close()
+
+	stores := []struct {
+		name  string
+		store eventStore
+	}{
+		{"sqlite", sqliteManager},
+		{"memory", NewMemoryManager()},
+	}
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			testEndpointEvents(t, s.store)
+		})
+	}
+}
+
+func testEndpointEvents(t *testing.T, store eventStore) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	seed := []EndpointEvent{
+		{EndpointID: "a", EndpointName: "a", EventType: EventInactive, Cause: CauseFailures, CreatedAt: base},
+		{EndpointID: "b", EndpointName: "b", EventType: EventHealthCheck, Success: true, CreatedAt: base.Add(time.Minute)},
+		{EndpointID: "a", EndpointName: "a", EventType: EventActive, Success: true, CreatedAt: base.Add(2 * time.Minute)},
+		{EndpointID: "b", EndpointName: "b", EventType: EventInactive, Cause: CauseCircuit, CreatedAt: base.Add(3 * time.Minute)},
+		{EndpointID: "a", EndpointName: "a", EventType: EventRateLimit, CreatedAt: base.Add(4 * time.Minute)},
+	}
+	for i := range seed {
+		if err := store.RecordEndpointEvent(&seed[i]); err != nil {
+			t.Fatalf("failed to record event %d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		filter        EndpointEventFilter
+		expectedTotal int64
+		expected      []time.Duration // 返回事件相对 base 的时间，最新在前
+	}{
+		{"all", EndpointEventFilter{}, 5, []time.Duration{4 * time.Minute, 3 * time.Minute, 2 * time.Minute, time.Minute, 0}},
+		{"endpoint", EndpointEventFilter{EndpointIDs: []string{"b"}}, 2, []time.Duration{3 * time.Minute, time.Minute}},
+		{"event types", EndpointEventFilter{EventTypes: []string{EventInactive, EventActive}}, 3, []time.Duration{3 * time.Minute, 2 * time.Minute, 0}},
+		{"since inclusive, until exclusive", EndpointEventFilter{Since: base.Add(time.Minute), Until: base.Add(3 * time.Minute)}, 2, []time.Duration{2 * time.Minute, time.Minute}},
+		{"limit", EndpointEventFilter{Limit: 2}, 5, []time.Duration{4 * time.Minute, 3 * time.Minute}},
+		{"offset and limit", EndpointEventFilter{Limit: 2, Offset: 2}, 5, []time.Duration{2 * time.Minute, time.Minute}},
+		{"offset past the end", EndpointEventFilter{Offset: 10}, 5, nil},
+		{"last transition before", EndpointEventFilter{EndpointIDs: []string{"a"}, EventTypes: []string{EventInactive, EventActive}, Until: base.Add(2 * time.Minute), Limit: 1}, 1, []time.Duration{0}},
+	}
+	for _, tt := range tests {
+		events, total, err := store.QueryEndpointEvents(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: query failed: %v", tt.name, err)
+		}
+		if total != tt.expectedTotal {
+			t.Errorf("%s: expected total %d, got %d", tt.name, tt.expectedTotal, total)
+		}
+		if len(events) != len(tt.expected) {
+			t.Errorf("%s: expected %d events, got %d", tt.name, len(tt.expected), len(events))
+			continue
+		}
+		for i, event := range events {
+			if offset := event.CreatedAt.Sub(base); offset != tt.expected[i] {
+				t.Errorf("%s: expected event %d at +%v, got +%v", tt.name, i, tt.expected[i], offset)
+			}
+		}
+	}
+
+	events, _, _ := store.QueryEndpointEvents(EndpointEventFilter{EndpointIDs: []string{"b"}, EventTypes: []string{EventInactive}})
+	if len(events) != 1 || events[0].Cause != CauseCircuit || events[0].ID == 0 {
+		t.Errorf("expected the inactive event to keep its cause and get an ID, got %+v", events)
+	}
+
+	deleted, err := store.CleanupEndpointEvents(base.Add(2 * time.Minute))
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 events cleaned up, got %d (%v)", deleted, err)
+	}
+	if _, total, _ := store.QueryEndpointEvents(EndpointEventFilter{}); total != 3 {
+		t.Errorf("expected 3 events left after cleanup, got %d", total)
+	}
+}
@@ -1,5 +1,7 @@
 package statistics
 
+import "time"
+
 // StatisticsManager defines the interface for endpoint statistics management
 type StatisticsManager interface {
 	// LoadStatistics loads statistics for a specific endpoint ID
@@ -23,6 +25,30 @@ type StatisticsManager interface {
 	// DeleteStatistics removes statistics record for an endpoint
 	DeleteStatistics(endpointID string) error
 	
+	// LoadBudgetUsage loads the budget window usage records of an endpoint
+	LoadBudgetUsage(endpointID string) ([]*BudgetUsage, error)
+	
+	// SaveBudgetUsage saves or updates a budget window usage record
+	SaveBudgetUsage(usage *BudgetUsage) error
+	
+	// RecordEndpointEvent appends an event to the endpoint availability timeline
+	RecordEndpointEvent(event *EndpointEvent) error
+	
+	// QueryEndpointEvents returns the events matching the filter, newest first, and the total number of matches
+	QueryEndpointEvents(filter EndpointEventFilter) ([]*EndpointEvent, int64, error)
+	
+	// CleanupEndpointEvents deletes events created before the given time
+	CleanupEndpointEvents(before time.Time) (int64, error)
+	
+	// SaveUsageRollups merges the given rollup increments into the stored buckets
+	SaveUsageRollups(rollups []*UsageRollup) error
+	
+	// QueryUsageRollups returns the rollups matching the filter ordered by bucket start
+	QueryUsageRollups(filter UsageRollupFilter) ([]*UsageRollup, error)
+	
+	// CleanupUsageRollups deletes rollups of a granularity whose bucket started before the given time
+	CleanupUsageRollups(granularity string, before time.Time) (int64, error)
+	
 	// GetAllStatistics returns all endpoint statistics
 	GetAllStatistics() ([]*EndpointStatistics, error)
 	
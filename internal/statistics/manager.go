@@ -75,7 +75,7 @@ func NewManager(dataDirectory string) (*Manager, error) {
 	}
 
 	// Auto-migrate the statistics table
-	if err := db.AutoMigrate(&EndpointStatistics{}); err != nil {
+	if err := db.AutoMigrate(&EndpointStatistics{}, &BudgetUsage{}, &EndpointEvent{}, &UsageRollup{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate statistics database: %v", err)
 	}
 
@@ -98,6 +98,7 @@ func createAdditionalIndexes(db *gorm.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_endpoint_stats_name_type ON endpoint_statistics(name, endpoint_type)",
 		"CREATE INDEX IF NOT EXISTS idx_endpoint_stats_updated ON endpoint_statistics(last_updated DESC)",
 		"CREATE INDEX IF NOT EXISTS idx_endpoint_stats_requests ON endpoint_statistics(total_requests DESC)",
+		"CREATE INDEX IF NOT EXISTS idx_usage_rollups_bucket ON usage_rollups(granularity, bucket_start)",
 	}
 
 	for _, idx := range indexes {
@@ -251,9 +252,167 @@ func (m *Manager) DeleteStatistics(endpointID string) error {
 	if result.Error != nil {
 		return fmt.Errorf("failed to delete statistics for endpoint %s: %v", endpointID, result.Error)
 	}
+	if err := m.db.Where("endpoint_id = ?", endpointID).Delete(&BudgetUsage{}).Error; err != nil {
+		return fmt.Errorf("failed to delete budget usage for endpoint %s: %v", endpointID, err)
+	}
 	return nil
 }
 
+// LoadBudgetUsage loads the budget window usage records of an endpoint
+func (m *Manager) LoadBudgetUsage(endpointID string) ([]*BudgetUsage, error) {
+	var records []*BudgetUsage
+	if err := m.db.Where("endpoint_id = ?", endpointID).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load budget usage for endpoint %s: %v", endpointID, err)
+	}
+	return records, nil
+}
+
+// SaveBudgetUsage saves or updates a budget window usage record
+func (m *Manager) SaveBudgetUsage(usage *BudgetUsage) error {
+	if err := m.db.Save(usage).Error; err != nil {
+		return fmt.Errorf("failed to save budget usage for endpoint %s: %v", usage.EndpointID, err)
+	}
+	return nil
+}
+
+// RecordEndpointEvent appends an event to the endpoint availability timeline
+// Events are kept when an endpoint is removed so its outage history stays queryable until retention cleanup
+func (m *Manager) RecordEndpointEvent(event *EndpointEvent) error {
+	if err := m.db.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record %s event for endpoint %s: %v", event.EventType, event.EndpointName, err)
+	}
+	return nil
+}
+
+// QueryEndpointEvents returns the events matching the filter, newest first, and the total number of matches
+func (m *Manager) QueryEndpointEvents(filter EndpointEventFilter) ([]*EndpointEvent, int64, error) {
+	query := m.db.Model(&EndpointEvent{})
+	if len(filter.EndpointIDs) > 0 {
+		query = query.Where("endpoint_id IN ?", filter.EndpointIDs)
+	}
+	if len(filter.EventTypes) > 0 {
+		query = query.Where("event_type IN ?", filter.EventTypes)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until.UTC())
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count endpoint events: %v", err)
+	}
+
+	var events []*EndpointEvent
+	query = query.Order("created_at DESC, id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&events).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to query endpoint events: %v", err)
+	}
+	return events, total, nil
+}
+
+// CleanupEndpointEvents deletes events created before the given time
+func (m *Manager) CleanupEndpointEvents(before time.Time) (int64, error) {
+	result := m.db.Where("created_at < ?", before.UTC()).Delete(&EndpointEvent{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to cleanup endpoint events: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// SaveUsageRollups merges the given rollup increments into the stored buckets in a single transaction
+func (m *Manager) SaveUsageRollups(rollups []*UsageRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, increment := range rollups {
+			var stored UsageRollup
+			result := whereUsageRollup(tx, increment).Limit(1).Find(&stored)
+			if result.Error != nil {
+				return fmt.Errorf("failed to load usage rollup: %v", result.Error)
+			}
+
+			// The total dimension has an empty key, which GORM's Save treats as an unset primary key,
+			// so rows are created and updated explicitly
+			if result.RowsAffected == 0 {
+				stored = UsageRollup{
+					Granularity: increment.Granularity,
+					BucketStart: increment.BucketStart.UTC(),
+					Dimension:   increment.Dimension,
+					Key:         increment.Key,
+				}
+				stored.Merge(increment)
+				stored.encodeHistogram()
+				if err := tx.Create(&stored).Error; err != nil {
+					return fmt.Errorf("failed to create usage rollup: %v", err)
+				}
+				continue
+			}
+
+			stored.Merge(increment)
+			stored.encodeHistogram()
+			err := whereUsageRollup(tx.Model(&UsageRollup{}), increment).Updates(map[string]interface{}{
+				"requests":                    stored.Requests,
+				"errors":                      stored.Errors,
+				"input_tokens":                stored.InputTokens,
+				"output_tokens":               stored.OutputTokens,
+				"cache_creation_input_tokens": stored.CacheCreationInputTokens,
+				"cache_read_input_tokens":     stored.CacheReadInputTokens,
+				"cost":                        stored.Cost,
+				"latency_sum_ms":              stored.LatencySumMs,
+				"latency_max_ms":              stored.LatencyMaxMs,
+				"latency_histogram":           stored.LatencyHistogram,
+				"updated_at":                  time.Now().UTC(),
+			}).Error
+			if err != nil {
+				return fmt.Errorf("failed to update usage rollup: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// whereUsageRollup restricts a query to the row with the rollup's primary key
+func whereUsageRollup(tx *gorm.DB, rollup *UsageRollup) *gorm.DB {
+	return tx.Where("granularity = ? AND bucket_start = ? AND dimension = ? AND key = ?",
+		rollup.Granularity, rollup.BucketStart.UTC(), rollup.Dimension, rollup.Key)
+}
+
+// QueryUsageRollups returns the rollups matching the filter ordered by bucket start
+func (m *Manager) QueryUsageRollups(filter UsageRollupFilter) ([]*UsageRollup, error) {
+	query := m.db.Where("granularity = ? AND dimension = ?", filter.Granularity, filter.Dimension)
+	if len(filter.Keys) > 0 {
+		query = query.Where("key IN ?", filter.Keys)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("bucket_start >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("bucket_start < ?", filter.Until.UTC())
+	}
+
+	var rollups []*UsageRollup
+	if err := query.Order("bucket_start, key").Find(&rollups).Error; err != nil {
+		return nil, fmt.Errorf("failed to query usage rollups: %v", err)
+	}
+	return rollups, nil
+}
+
+// CleanupUsageRollups deletes rollups of a granularity whose bucket started before the given time
+func (m *Manager) CleanupUsageRollups(granularity string, before time.Time) (int64, error) {
+	result := m.db.Where("granularity = ? AND bucket_start < ?", granularity, before.UTC()).Delete(&UsageRollup{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to cleanup %s usage rollups: %v", granularity, result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 // GetAllStatistics returns all endpoint statistics
 func (m *Manager) GetAllStatistics() ([]*EndpointStatistics, error) {
 	var allStats []*EndpointStatistics
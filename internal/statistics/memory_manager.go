@@ -2,21 +2,31 @@ package statistics
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
 
+// maxMemoryEvents caps the in-memory endpoint event timeline
+const maxMemoryEvents = 10000
+
 // MemoryManager is a fallback statistics manager that stores data in memory only
 // This is used when SQLite/CGO is not available
 type MemoryManager struct {
-	statistics map[string]*EndpointStatistics
-	mutex      sync.RWMutex
+	statistics  map[string]*EndpointStatistics
+	budgetUsage map[string]map[string]*BudgetUsage // endpoint ID -> period -> usage
+	events      []*EndpointEvent                   // oldest first, capped at maxMemoryEvents
+	nextEventID uint
+	rollups     map[string]*UsageRollup // rollup identity -> rollup
+	mutex       sync.RWMutex
 }
 
 // NewMemoryManager creates a new memory-only statistics manager
 func NewMemoryManager() *MemoryManager {
 	return &MemoryManager{
-		statistics: make(map[string]*EndpointStatistics),
+		statistics:  make(map[string]*EndpointStatistics),
+		budgetUsage: make(map[string]map[string]*BudgetUsage),
+		rollups:     make(map[string]*UsageRollup),
 	}
 }
 
@@ -142,9 +152,159 @@ func (m *MemoryManager) DeleteStatistics(endpointID string) error {
 	defer m.mutex.Unlock()
 	
 	delete(m.statistics, endpointID)
+	delete(m.budgetUsage, endpointID)
+	return nil
+}
+
+// LoadBudgetUsage loads the budget window usage records of an endpoint from memory
+func (m *MemoryManager) LoadBudgetUsage(endpointID string) ([]*BudgetUsage, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	
+	records := make([]*BudgetUsage, 0, len(m.budgetUsage[endpointID]))
+	for _, usage := range m.budgetUsage[endpointID] {
+		usageCopy := *usage
+		records = append(records, &usageCopy)
+	}
+	return records, nil
+}
+
+// SaveBudgetUsage saves a budget window usage record to memory
+func (m *MemoryManager) SaveBudgetUsage(usage *BudgetUsage) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	if m.budgetUsage[usage.EndpointID] == nil {
+		m.budgetUsage[usage.EndpointID] = make(map[string]*BudgetUsage)
+	}
+	usageCopy := *usage
+	usageCopy.UpdatedAt = time.Now().UTC()
+	m.budgetUsage[usage.EndpointID][usage.Period] = &usageCopy
+	return nil
+}
+
+// RecordEndpointEvent appends an event to the in-memory timeline, dropping the oldest beyond maxMemoryEvents
+func (m *MemoryManager) RecordEndpointEvent(event *EndpointEvent) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	m.nextEventID++
+	event.ID = m.nextEventID
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+	eventCopy := *event
+	m.events = append(m.events, &eventCopy)
+	if overflow := len(m.events) - maxMemoryEvents; overflow > 0 {
+		m.events = append([]*EndpointEvent(nil), m.events[overflow:]...)
+	}
+	return nil
+}
+
+// QueryEndpointEvents returns the in-memory events matching the filter, newest first
+func (m *MemoryManager) QueryEndpointEvents(filter EndpointEventFilter) ([]*EndpointEvent, int64, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	
+	var matched []*EndpointEvent
+	for i := len(m.events) - 1; i >= 0; i-- {
+		if filter.Matches(m.events[i]) {
+			eventCopy := *m.events[i]
+			matched = append(matched, &eventCopy)
+		}
+	}
+	total := int64(len(matched))
+	if filter.Offset >= len(matched) {
+		return []*EndpointEvent{}, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+// CleanupEndpointEvents deletes in-memory events created before the given time
+func (m *MemoryManager) CleanupEndpointEvents(before time.Time) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	kept := m.events[:0]
+	for _, event := range m.events {
+		if !event.CreatedAt.Before(before) {
+			kept = append(kept, event)
+		}
+	}
+	deleted := int64(len(m.events) - len(kept))
+	m.events = kept
+	return deleted, nil
+}
+
+// usageRollupIdentity returns the map key equivalent to the usage_rollups primary key
+func usageRollupIdentity(rollup *UsageRollup) string {
+	return fmt.Sprintf("%s|%d|%s|%s", rollup.Granularity, rollup.BucketStart.Unix(), rollup.Dimension, rollup.Key)
+}
+
+// SaveUsageRollups merges the given rollup increments into the in-memory buckets
+func (m *MemoryManager) SaveUsageRollups(rollups []*UsageRollup) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	for _, increment := range rollups {
+		identity := usageRollupIdentity(increment)
+		stored, exists := m.rollups[identity]
+		if !exists {
+			stored = &UsageRollup{
+				Granularity: increment.Granularity,
+				BucketStart: increment.BucketStart.UTC(),
+				Dimension:   increment.Dimension,
+				Key:         increment.Key,
+			}
+			m.rollups[identity] = stored
+		}
+		stored.Merge(increment)
+		stored.UpdatedAt = time.Now().UTC()
+	}
 	return nil
 }
 
+// QueryUsageRollups returns the in-memory rollups matching the filter ordered by bucket start
+func (m *MemoryManager) QueryUsageRollups(filter UsageRollupFilter) ([]*UsageRollup, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	
+	var matched []*UsageRollup
+	for _, rollup := range m.rollups {
+		if filter.Matches(rollup) {
+			rollupCopy := *rollup
+			rollupCopy.LatencyCounts = rollup.Histogram()
+			matched = append(matched, &rollupCopy)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].BucketStart.Equal(matched[j].BucketStart) {
+			return matched[i].BucketStart.Before(matched[j].BucketStart)
+		}
+		return matched[i].Key < matched[j].Key
+	})
+	return matched, nil
+}
+
+// CleanupUsageRollups deletes in-memory rollups of a granularity whose bucket started before the given time
+func (m *MemoryManager) CleanupUsageRollups(granularity string, before time.Time) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	var deleted int64
+	for identity, rollup := range m.rollups {
+		if rollup.Granularity == granularity && rollup.BucketStart.Before(before) {
+			delete(m.rollups, identity)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
 // GetAllStatistics returns all endpoint statistics from memory
 func (m *MemoryManager) GetAllStatistics() ([]*EndpointStatistics, error) {
 	m.mutex.RLock()
@@ -69,4 +69,181 @@ func (e *EndpointStatistics) IsHealthy() bool {
 	// 1. Has no recent consecutive failures, OR
 	// 2. Has recent consecutive successes
 	return e.FailureCount == 0 || e.SuccessiveSuccesses > 0
-}
\ No newline at end of file
+}
+// BudgetUsage represents the accumulated usage of an endpoint in one budget window
+// This corresponds to the endpoint_budget_usage table in statistics.db
+type BudgetUsage struct {
+	// Composite primary key - one row per endpoint and period
+	EndpointID string `gorm:"primaryKey;column:endpoint_id;size:64;not null"`
+	Period     string `gorm:"primaryKey;column:period;size:16;not null"` // hourly | daily | monthly
+
+	// Start of the window the counters belong to
+	WindowStart time.Time `gorm:"column:window_start;not null"`
+
+	// Accumulated usage within the window
+	InputTokens  int64   `gorm:"column:input_tokens;default:0;not null"`
+	OutputTokens int64   `gorm:"column:output_tokens;default:0;not null"`
+	Cost         float64 `gorm:"column:cost;default:0;not null"`
+
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// TableName specifies the table name for GORM
+func (BudgetUsage) TableName() string {
+	return "endpoint_budget_usage"
+}
+
+// Endpoint event types recorded in the endpoint_events table
+const (
+	EventInactive     = "inactive"      // endpoint became unavailable
+	EventActive       = "active"        // endpoint was restored
+	EventHealthCheck  = "health_check"  // health check result
+	EventRateLimit    = "rate_limit"    // upstream rate limit exhausted or recovered
+	EventOAuthRefresh = "oauth_refresh" // OAuth token refresh attempt
+)
+
+// Outage causes recorded on inactive events; unlike the reason text they do not carry per-incident details
+const (
+	CauseFailures  = "failures"   // too many consecutive request failures
+	CauseCircuit   = "circuit"    // circuit breaker opened
+	CauseBudget    = "budget"     // token or cost budget exceeded
+	CauseRateLimit = "rate_limit" // upstream rate limit protection
+	CauseManual    = "manual"     // marked inactive explicitly
+)
+
+// EndpointEvent represents one entry of an endpoint's availability timeline
+// This corresponds to the endpoint_events table in statistics.db
+type EndpointEvent struct {
+	ID uint `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+
+	// Endpoint the event belongs to; the name is kept so events stay readable after renames
+	EndpointID   string `gorm:"column:endpoint_id;size:64;not null;index:idx_endpoint_events_endpoint_time,priority:1" json:"endpoint_id"`
+	EndpointName string `gorm:"column:endpoint_name;size:100;not null" json:"endpoint_name"`
+
+	EventType string `gorm:"column:event_type;size:32;not null;index:idx_endpoint_events_type" json:"event_type"`
+	Success   bool   `gorm:"column:success;not null" json:"success"`
+	Cause     string `gorm:"column:cause;size:32" json:"cause,omitempty"` // outage cause, set on inactive events
+	Reason    string `gorm:"column:reason;size:1000" json:"reason,omitempty"`
+
+	// Optional details depending on the event type
+	RequestID  string `gorm:"column:request_id;size:100" json:"request_id,omitempty"`
+	StatusCode int    `gorm:"column:status_code" json:"status_code,omitempty"`
+	LatencyMs  int64  `gorm:"column:latency_ms" json:"latency_ms,omitempty"`
+
+	CreatedAt time.Time `gorm:"column:created_at;not null;index:idx_endpoint_events_endpoint_time,priority:2;index:idx_endpoint_events_created" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (EndpointEvent) TableName() string {
+	return "endpoint_events"
+}
+
+// EndpointEventFilter selects endpoint events for the timeline API
+type EndpointEventFilter struct {
+	EndpointIDs []string  // empty means all endpoints
+	EventTypes  []string  // empty means all event types
+	Since       time.Time // zero means no lower bound
+	Until       time.Time // zero means no upper bound
+	Limit       int
+	Offset      int
+}
+
+// Matches reports whether an event satisfies the filter (used by the memory manager)
+func (f *EndpointEventFilter) Matches(event *EndpointEvent) bool {
+	if len(f.EndpointIDs) > 0 && !containsString(f.EndpointIDs, event.EndpointID) {
+		return false
+	}
+	if len(f.EventTypes) > 0 && !containsString(f.EventTypes, event.EventType) {
+		return false
+	}
+	if !f.Since.IsZero() && event.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !event.CreatedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Usage rollup granularities and dimensions stored in the usage_rollups table
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+
+	DimensionTotal       = "total"        // all requests, key is empty
+	DimensionEndpoint    = "endpoint"     // endpoint that served the request
+	DimensionModel       = "model"        // model requested by the client
+	DimensionTag         = "tag"          // one row per request tag
+	DimensionSession     = "session"      // Claude Code session ID
+	DimensionClientToken = "client_token" // masked client credential
+)
+
+// UsageRollupDimensions lists the dimensions accepted by the analytics API
+var UsageRollupDimensions = []string{DimensionTotal, DimensionEndpoint, DimensionModel, DimensionTag, DimensionSession, DimensionClientToken}
+
+// UsageRollup aggregates the requests of one dimension value in one hourly or daily bucket
+// This corresponds to the usage_rollups table in statistics.db, which is independent of the request log retention
+type UsageRollup struct {
+	// Composite primary key - one row per granularity, bucket, dimension and key
+	Granularity string    `gorm:"primaryKey;column:granularity;size:8;not null" json:"granularity"`
+	BucketStart time.Time `gorm:"primaryKey;column:bucket_start;not null" json:"bucket_start"`
+	Dimension   string    `gorm:"primaryKey;column:dimension;size:16;not null" json:"dimension"`
+	Key         string    `gorm:"primaryKey;column:key;size:200;not null" json:"key"`
+
+	Requests int64 `gorm:"column:requests;default:0;not null" json:"requests"`
+	Errors   int64 `gorm:"column:errors;default:0;not null" json:"errors"`
+
+	InputTokens              int64   `gorm:"column:input_tokens;default:0;not null" json:"input_tokens"`
+	OutputTokens             int64   `gorm:"column:output_tokens;default:0;not null" json:"output_tokens"`
+	CacheCreationInputTokens int64   `gorm:"column:cache_creation_input_tokens;default:0;not null" json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `gorm:"column:cache_read_input_tokens;default:0;not null" json:"cache_read_input_tokens"`
+	Cost                     float64 `gorm:"column:cost;default:0;not null" json:"cost"`
+
+	// Latency of the whole client request; percentiles are derived from the histogram
+	LatencySumMs     int64   `gorm:"column:latency_sum_ms;default:0;not null" json:"latency_sum_ms"`
+	LatencyMaxMs     int64   `gorm:"column:latency_max_ms;default:0;not null" json:"latency_max_ms"`
+	LatencyHistogram string  `gorm:"column:latency_histogram;type:text" json:"-"` // JSON counts per LatencyBucketsMs bucket, encoded on save
+	LatencyCounts    []int64 `gorm:"-" json:"-"`                                  // in-memory counts, decoded from LatencyHistogram on first use
+
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"-"`
+}
+
+// TableName specifies the table name for GORM
+func (UsageRollup) TableName() string {
+	return "usage_rollups"
+}
+
+// UsageRollupFilter selects usage rollups for the analytics API
+type UsageRollupFilter struct {
+	Granularity string    // hour or day
+	Dimension   string    // one of UsageRollupDimensions
+	Keys        []string  // empty means all keys
+	Since       time.Time // bucket start lower bound (inclusive), zero means no lower bound
+	Until       time.Time // bucket start upper bound (exclusive), zero means no upper bound
+}
+
+// Matches reports whether a rollup satisfies the filter (used by the memory manager)
+func (f *UsageRollupFilter) Matches(rollup *UsageRollup) bool {
+	if rollup.Granularity != f.Granularity || rollup.Dimension != f.Dimension {
+		return false
+	}
+	if len(f.Keys) > 0 && !containsString(f.Keys, rollup.Key) {
+		return false
+	}
+	if !f.Since.IsZero() && rollup.BucketStart.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !rollup.BucketStart.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package statistics
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// LatencyBucketsMs are the upper bounds of the latency histogram buckets; one more bucket holds slower requests
+var LatencyBucketsMs = []int64{100, 250, 500, 1000, 2000, 5000, 10000, 20000, 30000, 60000, 120000, 300000}
+
+// RollupStats is the API view of a rollup with derived error rate and latency percentiles
+type RollupStats struct {
+	Requests                 int64   `json:"requests"`
+	Errors                   int64   `json:"errors"`
+	ErrorRate                float64 `json:"error_rate"`
+	AvgLatencyMs             int64   `json:"avg_latency_ms"`
+	P50LatencyMs             int64   `json:"p50_latency_ms"`
+	P95LatencyMs             int64   `json:"p95_latency_ms"`
+	P99LatencyMs             int64   `json:"p99_latency_ms"`
+	MaxLatencyMs             int64   `json:"max_latency_ms"`
+	InputTokens              int64   `json:"input_tokens"`
+	OutputTokens             int64   `json:"output_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
+	Cost                     float64 `json:"cost"`
+}
+
+// latencyCounts returns the in-memory histogram counts, decoding the stored column on first use
+func (r *UsageRollup) latencyCounts() []int64 {
+	if r.LatencyCounts == nil {
+		r.LatencyCounts = make([]int64, len(LatencyBucketsMs)+1)
+		var stored []int64
+		if r.LatencyHistogram != "" && json.Unmarshal([]byte(r.LatencyHistogram), &stored) == nil {
+			copy(r.LatencyCounts, stored)
+		}
+	}
+	return r.LatencyCounts
+}
+
+// Histogram returns a copy of the latency histogram, one count per bucket
+func (r *UsageRollup) Histogram() []int64 {
+	return append([]int64(nil), r.latencyCounts()...)
+}
+
+// encodeHistogram writes the in-memory counts to the stored column before the rollup is saved
+func (r *UsageRollup) encodeHistogram() {
+	data, _ := json.Marshal(r.latencyCounts())
+	r.LatencyHistogram = string(data)
+}
+
+// ObserveLatency adds one request latency to the histogram, sum and maximum
+func (r *UsageRollup) ObserveLatency(latencyMs int64) {
+	counts := r.latencyCounts()
+	bucket := len(LatencyBucketsMs)
+	for i, bound := range LatencyBucketsMs {
+		if latencyMs <= bound {
+			bucket = i
+			break
+		}
+	}
+	counts[bucket]++
+	r.LatencySumMs += latencyMs
+	if latencyMs > r.LatencyMaxMs {
+		r.LatencyMaxMs = latencyMs
+	}
+}
+
+// Merge adds the counters and histogram of another rollup of the same key
+func (r *UsageRollup) Merge(other *UsageRollup) {
+	r.Requests += other.Requests
+	r.Errors += other.Errors
+	r.InputTokens += other.InputTokens
+	r.OutputTokens += other.OutputTokens
+	r.CacheCreationInputTokens += other.CacheCreationInputTokens
+	r.CacheReadInputTokens += other.CacheReadInputTokens
+	r.Cost += other.Cost
+	r.LatencySumMs += other.LatencySumMs
+	if other.LatencyMaxMs > r.LatencyMaxMs {
+		r.LatencyMaxMs = other.LatencyMaxMs
+	}
+
+	counts := r.latencyCounts()
+	for i, count := range other.latencyCounts() {
+		counts[i] += count
+	}
+}
+
+// LatencyPercentile estimates the latency percentile (0-100) by linear interpolation within the histogram bucket;
+// the unbounded last bucket is interpolated up to the maximum latency
+func (r *UsageRollup) LatencyPercentile(p float64) int64 {
+	counts := r.latencyCounts()
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := math.Ceil(p / 100 * float64(total))
+	var cumulative int64
+	for i, count := range counts {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		lower := int64(0)
+		if i > 0 {
+			lower = LatencyBucketsMs[i-1]
+		}
+		upper := r.LatencyMaxMs
+		if i < len(LatencyBucketsMs) && LatencyBucketsMs[i] < upper {
+			upper = LatencyBucketsMs[i]
+		}
+		if upper < lower {
+			upper = lower
+		}
+		fraction := (rank - float64(cumulative)) / float64(count)
+		return lower + int64(fraction*float64(upper-lower))
+	}
+	return r.LatencyMaxMs
+}
+
+// Stats returns the counters with derived error rate and latency percentiles
+func (r *UsageRollup) Stats() RollupStats {
+	stats := RollupStats{
+		Requests:                 r.Requests,
+		Errors:                   r.Errors,
+		MaxLatencyMs:             r.LatencyMaxMs,
+		P50LatencyMs:             r.LatencyPercentile(50),
+		P95LatencyMs:             r.LatencyPercentile(95),
+		P99LatencyMs:             r.LatencyPercentile(99),
+		InputTokens:              r.InputTokens,
+		OutputTokens:             r.OutputTokens,
+		CacheCreationInputTokens: r.CacheCreationInputTokens,
+		CacheReadInputTokens:     r.CacheReadInputTokens,
+		Cost:                     r.Cost,
+	}
+	if r.Requests > 0 {
+		stats.ErrorRate = float64(r.Errors) / float64(r.Requests)
+		stats.AvgLatencyMs = r.LatencySumMs / r.Requests
+	}
+	return stats
+}
@@ -0,0 +1,171 @@
+package statistics
+
+import (
+	"testing"
+	"time"
+)
+
+// rollupTestManagers 返回内存和 GORM 两种实现，汇总逻辑应表现一致
+func rollupTestManagers(t *testing.T) map[string]StatisticsManager {
+	t.Helper()
+	gormManager, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create statistics manager: %v", err)
+	}
+	t.Cleanup(func() { gormManager.Close() })
+	return map[string]StatisticsManager{
+		"memory": NewMemoryManager(),
+		"gorm":   gormManager,
+	}
+}
+
+// newTestRollup 创建一个包含若干请求的汇总增量
+func newTestRollup(granularity string, bucketStart time.Time, dimension, key string, latenciesMs ...int64) *UsageRollup {
+	rollup := &UsageRollup{
+		Granularity:  granularity,
+		BucketStart:  bucketStart,
+		Dimension:    dimension,
+		Key:          key,
+		InputTokens:  100,
+		OutputTokens: 10,
+		Cost:         0.5,
+	}
+	for _, latency := range latenciesMs {
+		rollup.Requests++
+		rollup.ObserveLatency(latency)
+	}
+	return rollup
+}
+
+func TestSaveUsageRollupsMergesSameWindow(t *testing.T) {
+	hour := time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC)
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	for name, manager := range rollupTestManagers(t) {
+		// 同一个时间桶写入两次（例如两次 flush），合并为一行而不是重复计数
+		first := []*UsageRollup{
+			newTestRollup(GranularityHour, hour, DimensionTotal, "", 50, 300),
+			newTestRollup(GranularityDay, day, DimensionTotal, "", 50, 300),
+			newTestRollup(GranularityHour, hour, DimensionModel, "claude-sonnet-4", 50),
+		}
+		second := []*UsageRollup{
+			newTestRollup(GranularityHour, hour, DimensionTotal, "", 2000),
+			newTestRollup(GranularityDay, day, DimensionTotal, "", 2000),
+		}
+		second[0].Errors = 1
+		for _, batch := range [][]*UsageRollup{first, second} {
+			if err := manager.SaveUsageRollups(batch); err != nil {
+				t.Fatalf("%s: SaveUsageRollups failed: %v", name, err)
+			}
+		}
+
+		for _, granularity := range []string{GranularityHour, GranularityDay} {
+			rollups, err := manager.QueryUsageRollups(UsageRollupFilter{Granularity: granularity, Dimension: DimensionTotal})
+			if err != nil {
+				t.Fatalf("%s: QueryUsageRollups failed: %v", name, err)
+			}
+			if len(rollups) != 1 {
+				t.Fatalf("%s: expected one %s total rollup, got %d", name, granularity, len(rollups))
+			}
+			stats := rollups[0].Stats()
+			if stats.Requests != 3 || stats.InputTokens != 200 || stats.OutputTokens != 20 || stats.Cost != 1.0 || stats.MaxLatencyMs != 2000 {
+				t.Errorf("%s: unexpected merged %s rollup: %+v", name, granularity, stats)
+			}
+			if granularity == GranularityHour && (stats.Errors != 1 || stats.AvgLatencyMs != 783) {
+				t.Errorf("%s: expected errors and latency merged, got %+v", name, stats)
+			}
+			if histogram := rollups[0].Histogram(); histogram[0] != 1 || histogram[2] != 1 || histogram[4] != 1 {
+				t.Errorf("%s: expected merged latency histogram, got %v", name, histogram)
+			}
+		}
+
+		models, _ := manager.QueryUsageRollups(UsageRollupFilter{Granularity: GranularityHour, Dimension: DimensionModel})
+		if len(models) != 1 || models[0].Key != "claude-sonnet-4" || models[0].Requests != 1 {
+			t.Errorf("%s: expected model rollup kept separate, got %+v", name, models)
+		}
+	}
+}
+
+func TestQueryUsageRollupsFilter(t *testing.T) {
+	start := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	for name, manager := range rollupTestManagers(t) {
+		var rollups []*UsageRollup
+		for i := 0; i < 4; i++ {
+			bucket := start.Add(time.Duration(i) * time.Hour)
+			rollups = append(rollups,
+				newTestRollup(GranularityHour, bucket, DimensionEndpoint, "b", 100),
+				newTestRollup(GranularityHour, bucket, DimensionEndpoint, "a", 100))
+		}
+		if err := manager.SaveUsageRollups(rollups); err != nil {
+			t.Fatalf("%s: SaveUsageRollups failed: %v", name, err)
+		}
+
+		// since 包含，until 不包含，按时间桶和维度值排序
+		matched, err := manager.QueryUsageRollups(UsageRollupFilter{
+			Granularity: GranularityHour,
+			Dimension:   DimensionEndpoint,
+			Since:       start.Add(time.Hour),
+			Until:       start.Add(3 * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("%s: QueryUsageRollups failed: %v", name, err)
+		}
+		var got []string
+		for _, rollup := range matched {
+			got = append(got, rollup.BucketStart.UTC().Format("15")+rollup.Key)
+		}
+		if len(got) != 4 || got[0] != "01a" || got[1] != "01b" || got[2] != "02a" || got[3] != "02b" {
+			t.Errorf("%s: unexpected filtered rollups %v", name, got)
+		}
+
+		matched, _ = manager.QueryUsageRollups(UsageRollupFilter{Granularity: GranularityHour, Dimension: DimensionEndpoint, Keys: []string{"b"}})
+		if len(matched) != 4 || matched[0].Key != "b" {
+			t.Errorf("%s: expected only key b, got %d rollups", name, len(matched))
+		}
+
+		// 清理早于给定时间的时间桶
+		deleted, err := manager.CleanupUsageRollups(GranularityHour, start.Add(2*time.Hour))
+		if err != nil || deleted != 4 {
+			t.Errorf("%s: expected 4 rollups cleaned up, got %d %v", name, deleted, err)
+		}
+	}
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	rollup := &UsageRollup{}
+	if rollup.LatencyPercentile(50) != 0 {
+		t.Errorf("expected 0 for empty histogram")
+	}
+
+	// 90 个请求落在 (0, 100]，10 个落在 (1000, 2000]
+	for i := 0; i < 90; i++ {
+		rollup.ObserveLatency(80)
+	}
+	for i := 0; i < 10; i++ {
+		rollup.ObserveLatency(1500)
+	}
+
+	tests := []struct {
+		percentile float64
+		expected   int64
+	}{
+		{50, 55},
+		{90, 100},
+		{95, 1250}, // (1000, 2000] 桶的插值上限为最大延迟 1500
+		{99, 1450},
+		{100, 1500},
+	}
+	for _, tt := range tests {
+		if got := rollup.LatencyPercentile(tt.percentile); got != tt.expected {
+			t.Errorf("p%.0f: expected %d, got %d", tt.percentile, tt.expected, got)
+		}
+	}
+
+	// 超出最后一个桶上限的延迟插值到最大延迟
+	slow := &UsageRollup{}
+	slow.ObserveLatency(400000)
+	if got := slow.LatencyPercentile(100); got != 400000 {
+		t.Errorf("expected p100 to be the maximum latency, got %d", got)
+	}
+}
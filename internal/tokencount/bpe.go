@@ -0,0 +1,126 @@
+package tokencount
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// bpePretokenizePattern 预分词正则，近似 cl100k_base 的切分规则
+// Go 的 regexp 不支持 \s+(?!\S) 这样的前瞻断言，这里去掉了该分支，对估算结果影响很小
+var bpePretokenizePattern = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// bpeMaxPieceBytes 单个预分词片段的最大长度，超长片段（如长串空白或 base64）分段合并，避免平方级开销
+const bpeMaxPieceBytes = 256
+
+// BPETokenizer 使用磁盘上的 BPE 词表进行真实分词计数
+//
+// 词表采用 tiktoken 格式：每行 "<base64 编码的 token> <rank>"，rank 越小合并优先级越高，
+// 例如 cl100k_base.tiktoken / o200k_base.tiktoken
+type BPETokenizer struct {
+	ranks map[string]int
+}
+
+// LoadBPETokenizer 从 tiktoken 格式的词表文件加载 BPE 分词器
+func LoadBPETokenizer(path string) (*BPETokenizer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open BPE vocab file: %v", err)
+	}
+	defer file.Close()
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid BPE vocab line %d: expected '<base64 token> <rank>'", lineNumber)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 token on BPE vocab line %d: %v", lineNumber, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank on BPE vocab line %d: %v", lineNumber, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read BPE vocab file: %v", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("BPE vocab file %s is empty", path)
+	}
+
+	return &BPETokenizer{ranks: ranks}, nil
+}
+
+// Name 返回估算器名称
+func (b *BPETokenizer) Name() string {
+	return "bpe"
+}
+
+// VocabSize 返回词表大小
+func (b *BPETokenizer) VocabSize() int {
+	return len(b.ranks)
+}
+
+// CountTokens 对文本进行预分词和 BPE 合并，返回 token 数
+func (b *BPETokenizer) CountTokens(text string) int {
+	count := 0
+	for _, piece := range bpePretokenizePattern.FindAllString(text, -1) {
+		for len(piece) > bpeMaxPieceBytes {
+			count += b.countPiece(piece[:bpeMaxPieceBytes])
+			piece = piece[bpeMaxPieceBytes:]
+		}
+		count += b.countPiece(piece)
+	}
+	return count
+}
+
+// countPiece 对单个片段执行字节级 BPE 合并：反复合并 rank 最小的相邻对，直到无法合并
+func (b *BPETokenizer) countPiece(piece string) int {
+	if piece == "" {
+		return 0
+	}
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+
+	parts := make([]string, len(piece))
+	for i := 0; i < len(piece); i++ {
+		parts[i] = piece[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		bestRank := math.MaxInt
+		bestIndex := -1
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := b.ranks[parts[i]+parts[i+1]]; ok && rank < bestRank {
+				bestRank = rank
+				bestIndex = i
+			}
+		}
+		if bestIndex < 0 {
+			break
+		}
+		parts[bestIndex] = parts[bestIndex] + parts[bestIndex+1]
+		parts = append(parts[:bestIndex+1], parts[bestIndex+2:]...)
+	}
+
+	return len(parts)
+}
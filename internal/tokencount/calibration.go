@@ -0,0 +1,258 @@
+package tokencount
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/logger"
+)
+
+// 校准参数
+const (
+	minCalibrationSamples = 3   // 单个模型至少需要的有效样本数
+	minCalibrationRatio   = 0.3 // 校准比例下限，防止异常日志把估算值拉偏
+	maxCalibrationRatio   = 5.0 // 校准比例上限
+)
+
+// LogSource 提供历史请求日志，*logger.Logger 实现了该接口
+type LogSource interface {
+	GetLogs(limit, offset int, failedOnly bool) ([]*logger.RequestLog, int, error)
+}
+
+// estimateFunc 返回请求的模型名和未经校准的估算 token 数
+type estimateFunc func(body []byte) (string, int, error)
+
+// Calibration 从历史日志中成功的 /messages 请求学习"上游实际 input tokens / 本地估算值"的比例，
+// 按模型分别统计，样本不足的模型使用全局比例
+type Calibration struct {
+	source   LogSource
+	estimate estimateFunc
+	samples  int
+	interval time.Duration
+	logger   *logger.Logger
+
+	mu          sync.RWMutex
+	ratios      map[string]float64
+	globalRatio float64
+	lastRun     time.Time
+	running     bool
+}
+
+// NewCalibration 创建校准器并立即执行一次校准
+func NewCalibration(source LogSource, estimate estimateFunc, samples int, interval time.Duration, log *logger.Logger) *Calibration {
+	c := &Calibration{
+		source:      source,
+		estimate:    estimate,
+		samples:     samples,
+		interval:    interval,
+		logger:      log,
+		ratios:      make(map[string]float64),
+		globalRatio: 1.0,
+	}
+	c.Refresh()
+	return c
+}
+
+// Ratio 返回模型的校准比例；校准结果过期时在后台刷新，不阻塞当前请求
+func (c *Calibration) Ratio(model string) float64 {
+	c.mu.Lock()
+	stale := c.interval > 0 && time.Since(c.lastRun) > c.interval && !c.running
+	if stale {
+		c.running = true
+	}
+	ratio, ok := c.ratios[model]
+	if !ok {
+		ratio = c.globalRatio
+	}
+	c.mu.Unlock()
+
+	if stale {
+		go c.Refresh()
+	}
+	return ratio
+}
+
+// Refresh 读取最近的日志重新计算校准比例
+func (c *Calibration) Refresh() {
+	defer func() {
+		c.mu.Lock()
+		c.running = false
+		c.lastRun = time.Now()
+		c.mu.Unlock()
+	}()
+
+	if c.source == nil {
+		return
+	}
+
+	logs, _, err := c.source.GetLogs(c.samples, 0, false)
+	if err != nil {
+		if c.logger != nil {
+			c.logger.Error("Failed to load logs for token count calibration", err)
+		}
+		return
+	}
+
+	actualByModel := make(map[string]int)
+	estimatedByModel := make(map[string]int)
+	countByModel := make(map[string]int)
+	var actualTotal, estimatedTotal, countTotal int
+
+	for _, log := range logs {
+		if log.StatusCode < 200 || log.StatusCode >= 300 || !strings.HasSuffix(log.Path, "/messages") {
+			continue
+		}
+
+		requestBody := log.OriginalRequestBody
+		if requestBody == "" {
+			requestBody = log.RequestBody
+		}
+		// 请求体被截断或未记录时无法估算
+		if !json.Valid([]byte(requestBody)) {
+			continue
+		}
+
+		actual := 0
+		for _, body := range []string{log.OriginalResponseBody, log.ResponseBody, log.FinalResponseBody} {
+			if tokens := extractInputTokens(body); tokens > actual {
+				actual = tokens
+			}
+		}
+		if actual == 0 {
+			continue
+		}
+
+		model, estimated, err := c.estimate([]byte(requestBody))
+		if err != nil || estimated <= 0 {
+			continue
+		}
+		if log.Model != "" {
+			model = log.Model
+		}
+
+		actualByModel[model] += actual
+		estimatedByModel[model] += estimated
+		countByModel[model]++
+		actualTotal += actual
+		estimatedTotal += estimated
+		countTotal++
+	}
+
+	ratios := make(map[string]float64)
+	for model, count := range countByModel {
+		if count >= minCalibrationSamples {
+			ratios[model] = clampRatio(float64(actualByModel[model]) / float64(estimatedByModel[model]))
+		}
+	}
+	globalRatio := 1.0
+	if countTotal >= minCalibrationSamples {
+		globalRatio = clampRatio(float64(actualTotal) / float64(estimatedTotal))
+	}
+
+	c.mu.Lock()
+	c.ratios = ratios
+	c.globalRatio = globalRatio
+	c.mu.Unlock()
+
+	if c.logger != nil {
+		c.logger.Debug("Token count calibration refreshed", map[string]interface{}{
+			"samples":      countTotal,
+			"models":       len(ratios),
+			"global_ratio": globalRatio,
+		})
+	}
+}
+
+func clampRatio(ratio float64) float64 {
+	if ratio < minCalibrationRatio {
+		return minCalibrationRatio
+	}
+	if ratio > maxCalibrationRatio {
+		return maxCalibrationRatio
+	}
+	return ratio
+}
+
+// extractInputTokens 从响应体中提取上游报告的输入 token 数，支持 JSON 响应和 SSE 流
+func extractInputTokens(body string) int {
+	if body == "" {
+		return 0
+	}
+
+	data := []byte(body)
+	if json.Valid(data) {
+		return inputTokensFromJSON(data)
+	}
+
+	// SSE：逐行解析 data: 负载，取最大值（流式 usage 是累计值）
+	maxTokens := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		payload := bytes.TrimSpace(line[len("data:"):])
+		if tokens := inputTokensFromJSON(payload); tokens > maxTokens {
+			maxTokens = tokens
+		}
+	}
+	return maxTokens
+}
+
+func inputTokensFromJSON(data []byte) int {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return 0
+	}
+	return findInputTokens(value)
+}
+
+// findInputTokens 递归查找 usage 对象：
+// Anthropic input_tokens（加上缓存 token）、OpenAI prompt_tokens / input_tokens、Gemini promptTokenCount
+func findInputTokens(value interface{}) int {
+	maxTokens := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			var tokens int
+			if usage, ok := child.(map[string]interface{}); ok && (key == "usage" || key == "usageMetadata") {
+				tokens = usageInputTokens(usage)
+			} else {
+				tokens = findInputTokens(child)
+			}
+			if tokens > maxTokens {
+				maxTokens = tokens
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if tokens := findInputTokens(child); tokens > maxTokens {
+				maxTokens = tokens
+			}
+		}
+	}
+	return maxTokens
+}
+
+func usageInputTokens(usage map[string]interface{}) int {
+	number := func(key string) int {
+		if n, ok := usage[key].(float64); ok {
+			return int(n)
+		}
+		return 0
+	}
+
+	if tokens := number("input_tokens"); tokens > 0 {
+		return tokens + number("cache_read_input_tokens") + number("cache_creation_input_tokens")
+	}
+	if tokens := number("prompt_tokens"); tokens > 0 {
+		return tokens
+	}
+	return number("promptTokenCount")
+}
@@ -0,0 +1,233 @@
+package tokencount
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/logger"
+)
+
+// Anthropic 请求格式带来的固定开销（近似值，来自对官方 count_tokens 的对比）
+const (
+	requestOverheadTokens = 7    // 对话格式的固定开销
+	messageOverheadTokens = 3    // 每条消息的角色标记
+	toolsOverheadTokens   = 346  // 启用工具时注入的工具使用说明
+	imageMaxTokens        = 1600 // 图片缩放到约 1.15MP 后的上限，无法解析尺寸时也使用该值
+	imageMaxEdge          = 1568 // 图片长边超过该值时会被缩放
+	pdfPageTokens         = 2000 // PDF 每页（文本 + 页面图像）的近似 token 数
+)
+
+// pdfPagePattern 匹配 PDF 中的页面对象（排除 /Type /Pages 页面树节点）
+var pdfPagePattern = regexp.MustCompile(`/Type\s*/Page[^s]`)
+
+// Tokenizer 文本 token 计数器，可插拔实现：字节启发式、BPE 词表
+type Tokenizer interface {
+	Name() string
+	CountTokens(text string) int
+}
+
+// Counter 本地 count_tokens 估算器：遍历 Anthropic 请求中的文本、工具定义和图片，
+// 使用 Tokenizer 计数，calibrated 模式下再乘以从历史日志学到的按模型校准比例
+type Counter struct {
+	tokenizer   Tokenizer
+	calibration *Calibration // 仅 calibrated 模式
+	logger      *logger.Logger
+}
+
+// NewCounter 根据配置创建估算器；estimator 为 disabled 时返回 nil
+// logSource 仅 calibrated 模式使用，提供历史请求日志
+func NewCounter(cfg config.TokenCountConfig, logSource LogSource, log *logger.Logger) (*Counter, error) {
+	estimator := config.GetStringWithDefault(cfg.Estimator, config.Default.TokenCount.Estimator)
+	if estimator == "disabled" {
+		return nil, nil
+	}
+
+	var tokenizer Tokenizer = NewHeuristicTokenizer()
+	if cfg.BPEVocabFile != "" && (estimator == "bpe" || estimator == "calibrated") {
+		bpe, err := LoadBPETokenizer(cfg.BPEVocabFile)
+		if err != nil {
+			return nil, err
+		}
+		tokenizer = bpe
+	}
+
+	counter := &Counter{
+		tokenizer: tokenizer,
+		logger:    log,
+	}
+
+	if estimator == "calibrated" {
+		defaultInterval, _ := time.ParseDuration(config.Default.TokenCount.CalibrationInterval)
+		interval := config.GetTimeoutDuration(cfg.CalibrationInterval, defaultInterval)
+		samples := config.GetIntWithDefault(cfg.CalibrationSamples, config.Default.TokenCount.CalibrationSamples)
+		counter.calibration = NewCalibration(logSource, counter.estimateRequest, samples, interval, log)
+	}
+
+	return counter, nil
+}
+
+// Name 返回估算器名称，如 "heuristic"、"bpe"、"calibrated(heuristic)"
+func (c *Counter) Name() string {
+	if c.calibration != nil {
+		return "calibrated(" + c.tokenizer.Name() + ")"
+	}
+	return c.tokenizer.Name()
+}
+
+// CountRequest 估算 count_tokens 请求的 input_tokens
+func (c *Counter) CountRequest(body []byte) (int, error) {
+	model, estimate, err := c.estimateRequest(body)
+	if err != nil {
+		return 0, err
+	}
+	if c.calibration != nil {
+		estimate = int(math.Round(float64(estimate) * c.calibration.Ratio(model)))
+	}
+	if estimate < 1 {
+		estimate = 1
+	}
+	return estimate, nil
+}
+
+// countRequest count_tokens 请求中参与计数的字段
+type countRequest struct {
+	Model    string            `json:"model"`
+	System   interface{}       `json:"system"`
+	Messages []countMessage    `json:"messages"`
+	Tools    []json.RawMessage `json:"tools"`
+}
+
+type countMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+// estimateRequest 未经校准的原始估算，返回请求中的模型名
+func (c *Counter) estimateRequest(body []byte) (string, int, error) {
+	var req countRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "", 0, fmt.Errorf("failed to parse count_tokens request: %v", err)
+	}
+
+	total := requestOverheadTokens
+	total += c.countContent(req.System)
+	for _, message := range req.Messages {
+		total += messageOverheadTokens + c.countContent(message.Content)
+	}
+	if len(req.Tools) > 0 {
+		total += toolsOverheadTokens
+		for _, tool := range req.Tools {
+			total += c.tokenizer.CountTokens(string(tool))
+		}
+	}
+
+	return req.Model, total, nil
+}
+
+// countContent 计算 string 或内容块数组的 token 数
+func (c *Counter) countContent(content interface{}) int {
+	switch v := content.(type) {
+	case nil:
+		return 0
+	case string:
+		return c.tokenizer.CountTokens(v)
+	case []interface{}:
+		total := 0
+		for _, item := range v {
+			if block, ok := item.(map[string]interface{}); ok {
+				total += c.countBlock(block)
+			}
+		}
+		return total
+	case map[string]interface{}:
+		return c.countBlock(v)
+	}
+	return 0
+}
+
+// countBlock 按内容块类型计算 token 数，未知类型按其 JSON 文本计算
+func (c *Counter) countBlock(block map[string]interface{}) int {
+	blockType, _ := block["type"].(string)
+	switch blockType {
+	case "text":
+		text, _ := block["text"].(string)
+		return c.tokenizer.CountTokens(text)
+	case "thinking":
+		thinking, _ := block["thinking"].(string)
+		return c.tokenizer.CountTokens(thinking)
+	case "redacted_thinking":
+		return 0
+	case "image":
+		return imageTokens(block["source"])
+	case "document":
+		return c.documentTokens(block["source"])
+	case "tool_use":
+		name, _ := block["name"].(string)
+		input, _ := json.Marshal(block["input"])
+		return c.tokenizer.CountTokens(name) + c.tokenizer.CountTokens(string(input))
+	case "tool_result":
+		return c.countContent(block["content"])
+	}
+
+	raw, _ := json.Marshal(block)
+	return c.tokenizer.CountTokens(string(raw))
+}
+
+// documentTokens 文本文档按内容计数，PDF 按页数估算
+func (c *Counter) documentTokens(source interface{}) int {
+	src, _ := source.(map[string]interface{})
+	switch src["type"] {
+	case "text":
+		data, _ := src["data"].(string)
+		return c.tokenizer.CountTokens(data)
+	case "content":
+		return c.countContent(src["content"])
+	case "base64":
+		data, _ := src["data"].(string)
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return pdfPageTokens
+		}
+		pages := len(pdfPagePattern.FindAllIndex(decoded, -1))
+		if pages == 0 {
+			pages = 1
+		}
+		return pages * pdfPageTokens
+	}
+	return pdfPageTokens
+}
+
+// imageTokens 按 Anthropic 的规则 (宽 × 高) / 750 估算图片 token，尺寸只解析图片头部
+func imageTokens(source interface{}) int {
+	src, _ := source.(map[string]interface{})
+	data, _ := src["data"].(string)
+	if src["type"] != "base64" || data == "" {
+		return imageMaxTokens
+	}
+
+	cfg, _, err := image.DecodeConfig(base64.NewDecoder(base64.StdEncoding, strings.NewReader(data)))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return imageMaxTokens
+	}
+
+	width, height := float64(cfg.Width), float64(cfg.Height)
+	if longEdge := math.Max(width, height); longEdge > imageMaxEdge {
+		scale := imageMaxEdge / longEdge
+		width, height = width*scale, height*scale
+	}
+	tokens := int(math.Ceil(width * height / 750))
+	if tokens > imageMaxTokens {
+		return imageMaxTokens
+	}
+	return tokens
+}
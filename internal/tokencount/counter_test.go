@@ -0,0 +1,175 @@
+package tokencount
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/logger"
+)
+
+type fakeLogSource struct {
+	logs []*logger.RequestLog
+}
+
+func (f *fakeLogSource) GetLogs(limit, offset int, failedOnly bool) ([]*logger.RequestLog, int, error) {
+	return f.logs, len(f.logs), nil
+}
+
+func TestHeuristicTokenizer(t *testing.T) {
+	tokenizer := NewHeuristicTokenizer()
+
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"hello world", 4}, // 11 / 3.5 向上取整
+		{"你好", 3},          // 2 × 1.2 向上取整
+		{"héllo", 3},       // 4 个 ASCII / 3.5 + 2 字节 / 2
+	}
+
+	for _, tt := range tests {
+		if got := tokenizer.CountTokens(tt.text); got != tt.expected {
+			t.Errorf("CountTokens(%q) = %d, expected %d", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestBPETokenizer(t *testing.T) {
+	// 最小词表：单字节 + "he"、"ll"、"hell"、"hello"
+	path := filepath.Join(t.TempDir(), "tiny.tiktoken")
+	content := ""
+	for i, token := range []string{"h", "e", "l", "o", " ", "w", "r", "d", "he", "ll", "hell", "hello"} {
+		content += base64.StdEncoding.EncodeToString([]byte(token)) + " " + strconv.Itoa(i) + "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write vocab: %v", err)
+	}
+
+	tokenizer, err := LoadBPETokenizer(path)
+	if err != nil {
+		t.Fatalf("LoadBPETokenizer failed: %v", err)
+	}
+	if tokenizer.VocabSize() != 12 {
+		t.Errorf("Expected vocab size 12, got %d", tokenizer.VocabSize())
+	}
+
+	// "hello" 整体命中词表；" world" 中 "w","o","r","l","d" 无可合并对，加上空格共 6 个
+	if got := tokenizer.CountTokens("hello world"); got != 7 {
+		t.Errorf("Expected 7 tokens, got %d", got)
+	}
+
+	if _, err := LoadBPETokenizer(filepath.Join(t.TempDir(), "missing.tiktoken")); err == nil {
+		t.Error("Expected error for missing vocab file")
+	}
+}
+
+func TestCounter_CountRequest(t *testing.T) {
+	counter, err := NewCounter(config.TokenCountConfig{Estimator: "heuristic"}, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCounter failed: %v", err)
+	}
+
+	// 200×100 的 PNG：200 × 100 / 750 向上取整 = 27
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 200, 100))); err != nil {
+		t.Fatalf("Failed to encode png: %v", err)
+	}
+	imageData := base64.StdEncoding.EncodeToString(img.Bytes())
+
+	body := `{
+		"model": "claude-sonnet-4",
+		"system": "Be brief.",
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "text", "text": "hello world"},
+				{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "` + imageData + `"}}
+			]}
+		]
+	}`
+
+	got, err := counter.CountRequest([]byte(body))
+	if err != nil {
+		t.Fatalf("CountRequest failed: %v", err)
+	}
+	// 7 (请求开销) + 3 ("Be brief.") + 3 (消息开销) + 4 ("hello world") + 27 (图片)
+	if got != 44 {
+		t.Errorf("Expected 44 tokens, got %d", got)
+	}
+
+	if _, err := counter.CountRequest([]byte("not json")); err == nil {
+		t.Error("Expected error for invalid request body")
+	}
+
+	disabled, err := NewCounter(config.TokenCountConfig{Estimator: "disabled"}, nil, nil)
+	if err != nil || disabled != nil {
+		t.Errorf("Expected nil counter for disabled estimator, got %v, %v", disabled, err)
+	}
+}
+
+func TestCalibration_LearnsPerModelRatio(t *testing.T) {
+	requestBody := `{"model":"claude-sonnet-4","messages":[{"role":"user","content":"hello world"}]}`
+	// 原始估算：7 + 3 + 4 = 14，上游实际报告 28，比例为 2
+	source := &fakeLogSource{}
+	for i := 0; i < 3; i++ {
+		source.logs = append(source.logs, &logger.RequestLog{
+			Path:         "/messages",
+			StatusCode:   200,
+			Model:        "claude-sonnet-4",
+			RequestBody:  requestBody,
+			ResponseBody: "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":20,\"cache_read_input_tokens\":8}}}\n\n",
+		})
+	}
+	// 失败请求和被截断的请求体不参与校准
+	source.logs = append(source.logs,
+		&logger.RequestLog{Path: "/messages", StatusCode: 500, Model: "claude-sonnet-4", RequestBody: requestBody, ResponseBody: `{"usage":{"input_tokens":1000}}`},
+		&logger.RequestLog{Path: "/messages", StatusCode: 200, Model: "claude-sonnet-4", RequestBody: requestBody[:20], ResponseBody: `{"usage":{"input_tokens":1000}}`},
+	)
+
+	counter, err := NewCounter(config.TokenCountConfig{Estimator: "calibrated", CalibrationInterval: "1h"}, source, nil)
+	if err != nil {
+		t.Fatalf("NewCounter failed: %v", err)
+	}
+	if counter.Name() != "calibrated(heuristic)" {
+		t.Errorf("Unexpected estimator name: %s", counter.Name())
+	}
+
+	got, err := counter.CountRequest([]byte(requestBody))
+	if err != nil {
+		t.Fatalf("CountRequest failed: %v", err)
+	}
+	if got != 28 {
+		t.Errorf("Expected calibrated estimate 28, got %d", got)
+	}
+
+	// 样本不足的模型回退到全局比例
+	if got, _ := counter.CountRequest([]byte(`{"model":"other","messages":[{"role":"user","content":"hello world"}]}`)); got != 28 {
+		t.Errorf("Expected global ratio estimate 28, got %d", got)
+	}
+}
+
+func TestExtractInputTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"anthropic json", `{"usage":{"input_tokens":10,"cache_creation_input_tokens":5}}`, 15},
+		{"openai json", `{"choices":[],"usage":{"prompt_tokens":42}}`, 42},
+		{"gemini sse", "data: {\"usageMetadata\":{\"promptTokenCount\":7}}\n\ndata: {\"usageMetadata\":{\"promptTokenCount\":9}}\n\n", 9},
+		{"no usage", `{"content":[]}`, 0},
+	}
+
+	for _, tt := range tests {
+		if got := extractInputTokens(tt.body); got != tt.expected {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package tokencount
+
+import (
+	"math"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 字节启发式参数：英文和代码平均约 3.5 个字符一个 token，
+// 中日韩文字大多一个字符对应一个以上 token，其他非 ASCII 字符按 UTF-8 字节数折算
+const (
+	asciiCharsPerToken = 3.5
+	cjkTokensPerChar   = 1.2
+	otherBytesPerToken = 2.0
+)
+
+// HeuristicTokenizer 基于字符类别的字节启发式估算，不需要任何外部文件
+type HeuristicTokenizer struct{}
+
+// NewHeuristicTokenizer 创建字节启发式估算器
+func NewHeuristicTokenizer() *HeuristicTokenizer {
+	return &HeuristicTokenizer{}
+}
+
+// Name 返回估算器名称
+func (h *HeuristicTokenizer) Name() string {
+	return "heuristic"
+}
+
+// CountTokens 估算文本的 token 数
+func (h *HeuristicTokenizer) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	var ascii, cjk, otherBytes int
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf:
+			ascii++
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		default:
+			otherBytes += utf8.RuneLen(r)
+		}
+	}
+
+	estimate := float64(ascii)/asciiCharsPerToken + float64(cjk)*cjkTokensPerChar + float64(otherBytes)/otherBytesPerToken
+	return int(math.Ceil(estimate))
+}
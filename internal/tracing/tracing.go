@@ -0,0 +1,109 @@
+// Package tracing 初始化 OpenTelemetry 链路追踪，并提供代理各处理阶段使用的 tracer 和传播器
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"claude-code-companion/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "claude-code-companion/internal/proxy"
+
+// Setup 按配置安装全局 TracerProvider 和 W3C traceparent 传播器，返回用于退出时导出剩余 span 的关闭函数；
+// 未启用时保持 OpenTelemetry 默认的空实现，span 和传播都不产生开销
+func Setup(cfg config.TracingConfig, version string) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	switch cfg.Exporter {
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(cfg.FilePath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create trace file directory: %v", err)
+		}
+		f, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %v", err)
+		}
+		file = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %v", err)
+		}
+	default:
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(cfg.Endpoint)}
+		if len(cfg.Headers) > 0 {
+			options = append(options, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		var err error
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+		}
+	}
+
+	res := resource.NewSchemaless(
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(version),
+	)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}, nil
+}
+
+// Start 创建子 span，父 span 取自 ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer 为客户端请求创建根 span，客户端携带 traceparent 时作为其子 span
+func StartServer(req *http.Request, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// StartClient 为上游请求创建 span，并把 traceparent 写入上游请求头部
+func StartClient(ctx context.Context, req *http.Request, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return ctx, span
+}
+
+// EndWithError 记录错误（如果有）并结束 span
+func EndWithError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"claude-code-companion/internal/tracing/tracingtest"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStartClientInjectsTraceparent(t *testing.T) {
+	exporter := tracingtest.UseInMemoryExporter(t)
+
+	parentCtx, parent := Start(context.Background(), "endpoint attempt")
+	req := httptest.NewRequest(http.MethodPost, "https://api.example.com/v1/messages", nil)
+	_, span := StartClient(parentCtx, req, "upstream POST")
+	span.End()
+	parent.End()
+
+	// 上游请求头部携带客户端 span 的 traceparent
+	extracted := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), propagation.HeaderCarrier(req.Header)))
+	if !extracted.IsValid() {
+		t.Fatalf("expected a valid traceparent header, got %q", req.Header.Get("traceparent"))
+	}
+	if extracted.TraceID() != span.SpanContext().TraceID() || extracted.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("expected traceparent of the client span, got %q", req.Header.Get("traceparent"))
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	client := spans[0]
+	if client.Name != "upstream POST" || client.SpanKind != trace.SpanKindClient || client.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected client span under the attempt span, got %s kind %v parent %s", client.Name, client.SpanKind, client.Parent.SpanID())
+	}
+}
+
+func TestStartServerContinuesIncomingTrace(t *testing.T) {
+	exporter := tracingtest.UseInMemoryExporter(t)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	req.Header.Set("traceparent", traceparent)
+	_, span := StartServer(req, "POST /v1/messages")
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	server := spans[0]
+	if server.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected incoming trace ID, got %s", server.SpanContext.TraceID())
+	}
+	if server.Parent.SpanID().String() != "00f067aa0ba902b7" || !server.Parent.IsRemote() {
+		t.Errorf("expected remote parent from traceparent, got %s", server.Parent.SpanID())
+	}
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("expected server span kind, got %v", server.SpanKind)
+	}
+
+	// 没有 traceparent 时开始新的 trace
+	exporter.Reset()
+	_, root := StartServer(httptest.NewRequest(http.MethodPost, "/v1/messages", nil), "POST /v1/messages")
+	root.End()
+	if parent := exporter.GetSpans()[0].Parent; parent.IsValid() {
+		t.Errorf("expected root span without parent, got %s", parent.SpanID())
+	}
+}
+
+func TestEndWithError(t *testing.T) {
+	exporter := tracingtest.UseInMemoryExporter(t)
+
+	_, ok := Start(context.Background(), "ok")
+	EndWithError(ok, nil)
+	_, failed := Start(context.Background(), "failed")
+	EndWithError(failed, errors.New("upstream timeout"))
+
+	spans := exporter.GetSpans()
+	if spans[0].Status.Code != codes.Unset || len(spans[0].Events) != 0 {
+		t.Errorf("expected successful span without error, got %+v", spans[0].Status)
+	}
+	if spans[1].Status.Code != codes.Error || spans[1].Status.Description != "upstream timeout" || len(spans[1].Events) != 1 {
+		t.Errorf("expected error status and recorded error event, got %+v", spans[1].Status)
+	}
+}
@@ -135,6 +135,16 @@ func SelectBestEndpoint(endpoints []EndpointSorter) EndpointSorter {
 
 // SelectBestEndpointWithTags selects the first available endpoint matching the tags
 func SelectBestEndpointWithTags(endpoints []EndpointSorter, requiredTags []string) EndpointSorter {
+	candidates := SelectTopTierCandidates(endpoints, requiredTags)
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[0]
+}
+
+// SelectTopTierCandidates 返回标签匹配层级最高的所有可用端点（按优先级排序），供负载均衡策略挑选
+// 严格优先级策略直接取第一个，与 SelectBestEndpointWithTags 的结果一致
+func SelectTopTierCandidates(endpoints []EndpointSorter, requiredTags []string) []EndpointSorter {
 	// 首先过滤出启用的端点
 	enabled := FilterEnabledEndpoints(endpoints)
 	if len(enabled) == 0 {
@@ -150,12 +160,22 @@ func SelectBestEndpointWithTags(endpoints []EndpointSorter, requiredTags []strin
 	// 按标签匹配和优先级排序
 	SortEndpointsByTagsAndPriority(filtered, requiredTags)
 	
-	// 选择第一个可用的端点
+	// 收集第一个可用端点所在层级的全部可用端点
+	var candidates []EndpointSorter
+	topTier := -1
 	for _, ep := range filtered {
-		if ep.IsAvailable() {
-			return ep
+		if !ep.IsAvailable() {
+			continue
 		}
+		tier := getEndpointTier(ep.GetTags(), requiredTags)
+		if topTier == -1 {
+			topTier = tier
+		}
+		if tier != topTier {
+			break
+		}
+		candidates = append(candidates, ep)
 	}
 
-	return nil
-}
\ No newline at end of file
+	return candidates
+}
@@ -104,6 +104,31 @@ func (v *ResponseValidator) ValidateStandardResponse(body []byte, endpointType s
 				return fmt.Errorf("invalid object type for OpenAI: expected 'chat.completion' or 'chat.completion.chunk', got '%v'", objectType)
 			}
 		}
+	} else if endpointType == "openai_responses" {
+		// OpenAI Responses API 格式验证
+		if _, hasError := response["error"]; hasError {
+			if _, hasOutput := response["output"]; !hasOutput {
+				return nil
+			}
+		}
+		requiredFields := []string{"id", "output"}
+		for _, field := range requiredFields {
+			if _, exists := response[field]; !exists {
+				return fmt.Errorf("missing required field for OpenAI Responses format: %s", field)
+			}
+		}
+		
+		if objectType, ok := response["object"].(string); ok && objectType != "response" {
+			return fmt.Errorf("invalid object type for OpenAI Responses: expected 'response', got '%v'", objectType)
+		}
+	} else if endpointType == "gemini" {
+		// Gemini generateContent 格式验证：candidates 或 error，输入被拦截时只有 promptFeedback
+		_, hasCandidates := response["candidates"]
+		_, hasError := response["error"]
+		_, hasPromptFeedback := response["promptFeedback"]
+		if !hasCandidates && !hasError && !hasPromptFeedback {
+			return fmt.Errorf("Gemini response missing 'candidates', 'error' and 'promptFeedback' fields")
+		}
 	} else {
 		// 非严格模式：只要是有效JSON且包含content或error字段之一即可
 		if _, hasContent := response["content"]; hasContent {
@@ -186,6 +211,20 @@ func (v *ResponseValidator) ValidateSSEChunk(chunk []byte, endpointType string)
 					return fmt.Errorf("missing 'model' field in OpenAI SSE data")
 				}
 				// OpenAI格式不要求type和object字段
+			} else if endpointType == "openai_responses" {
+				// Responses API 的每个事件都带有 type 字段
+				if _, hasType := data["type"]; !hasType {
+					return fmt.Errorf("missing 'type' field in OpenAI Responses SSE data")
+				}
+			} else if endpointType == "gemini" {
+				// Gemini 的每个事件都是完整的 GenerateContentResponse
+				_, hasCandidates := data["candidates"]
+				_, hasUsage := data["usageMetadata"]
+				_, hasError := data["error"]
+				_, hasPromptFeedback := data["promptFeedback"]
+				if !hasCandidates && !hasUsage && !hasError && !hasPromptFeedback {
+					return fmt.Errorf("missing 'candidates' field in Gemini SSE data")
+				}
 			}
 		}
 	}
@@ -199,10 +238,74 @@ func (v *ResponseValidator) ValidateCompleteSSEStream(body []byte, endpointType
 		return v.validateAnthropicSSECompleteness(body)
 	} else if endpointType == "openai" {
 		return v.validateOpenAISSECompleteness(body)
+	} else if endpointType == "openai_responses" {
+		return v.validateResponsesSSECompleteness(body)
+	} else if endpointType == "gemini" {
+		return v.validateGeminiSSECompleteness(body)
 	}
 	return nil
 }
 
+// validateGeminiSSECompleteness 验证Gemini SSE流的完整性
+// Gemini 流不发送 [DONE]，最后一个事件的 candidate 带有 finishReason
+func (v *ResponseValidator) validateGeminiSSECompleteness(body []byte) error {
+	lines := bytes.Split(body, []byte("\n"))
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data: ")) {
+			continue
+		}
+		
+		var data struct {
+			Candidates []struct {
+				FinishReason string `json:"finishReason"`
+			} `json:"candidates"`
+			PromptFeedback *struct {
+				BlockReason string `json:"blockReason"`
+			} `json:"promptFeedback"`
+		}
+		if err := json.Unmarshal(line[6:], &data); err != nil {
+			continue
+		}
+		
+		for _, candidate := range data.Candidates {
+			if candidate.FinishReason != "" {
+				return nil
+			}
+		}
+		// 输入被拦截时不会有 candidates，流同样已经结束
+		if data.PromptFeedback != nil && data.PromptFeedback.BlockReason != "" {
+			return nil
+		}
+	}
+	
+	return fmt.Errorf("incomplete SSE stream: missing finishReason in Gemini stream")
+}
+
+// validateResponsesSSECompleteness 验证OpenAI Responses SSE流的完整性
+// Responses 流不发送 [DONE]，以 response.completed / response.incomplete / response.failed 事件结束
+func (v *ResponseValidator) validateResponsesSSECompleteness(body []byte) error {
+	lines := bytes.Split(body, []byte("\n"))
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data: ")) {
+			continue
+		}
+		
+		var data map[string]interface{}
+		if err := json.Unmarshal(line[6:], &data); err != nil {
+			continue
+		}
+		
+		switch data["type"] {
+		case "response.completed", "response.incomplete", "response.failed":
+			return nil
+		}
+	}
+	
+	return fmt.Errorf("incomplete SSE stream: missing response.completed event in OpenAI Responses stream")
+}
+
 // validateAnthropicSSECompleteness 验证Anthropic SSE流的完整性
 func (v *ResponseValidator) validateAnthropicSSECompleteness(body []byte) error {
 	lines := bytes.Split(body, []byte("\n"))
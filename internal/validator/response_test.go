@@ -354,6 +354,78 @@ data: [DONE]
 	}
 }
 
+func TestValidateResponsesSSECompleteness(t *testing.T) {
+	validator := NewResponseValidator()
+	
+	// 测试用例1: 以 response.completed 结束的完整流
+	completeSSE := []byte(`event: response.created
+data: {"type":"response.created","response":{"id":"resp_1","status":"in_progress"}}
+
+event: response.output_text.delta
+data: {"type":"response.output_text.delta","output_index":0,"delta":"Hello"}
+
+event: response.completed
+data: {"type":"response.completed","response":{"id":"resp_1","status":"completed"}}
+
+`)
+	
+	if err := validator.ValidateCompleteSSEStream(completeSSE, "openai_responses"); err != nil {
+		t.Errorf("Expected complete Responses SSE to pass validation, got error: %v", err)
+	}
+	
+	// 测试用例2: 缺少结束事件
+	truncatedSSE := []byte(`event: response.output_text.delta
+data: {"type":"response.output_text.delta","output_index":0,"delta":"Hello"}
+
+`)
+	
+	err := validator.ValidateCompleteSSEStream(truncatedSSE, "openai_responses")
+	if err == nil {
+		t.Fatal("Expected Responses SSE without response.completed to fail validation")
+	}
+	if !contains(err.Error(), "incomplete SSE stream") {
+		t.Errorf("Expected error message to contain 'incomplete SSE stream', got: %v", err)
+	}
+	
+	// 测试用例3: 缺少 type 字段的事件
+	if err := validator.ValidateSSEChunk([]byte(`data: {"delta":"Hello"}`), "openai_responses"); err == nil {
+		t.Error("Expected Responses SSE data without type to fail validation")
+	}
+}
+
+func TestValidateGeminiSSECompleteness(t *testing.T) {
+	validator := NewResponseValidator()
+
+	// 测试用例1: 最后一个事件带有 finishReason 的完整流
+	completeSSE := []byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]},"index":0}],"responseId":"r1"}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]},"finishReason":"STOP","index":0}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2}}
+
+`)
+
+	if err := validator.ValidateCompleteSSEStream(completeSSE, "gemini"); err != nil {
+		t.Errorf("Expected complete Gemini SSE to pass validation, got error: %v", err)
+	}
+
+	// 测试用例2: 缺少 finishReason
+	truncatedSSE := []byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]},"index":0}]}
+
+`)
+
+	err := validator.ValidateCompleteSSEStream(truncatedSSE, "gemini")
+	if err == nil {
+		t.Fatal("Expected Gemini SSE without finishReason to fail validation")
+	}
+	if !contains(err.Error(), "incomplete SSE stream") {
+		t.Errorf("Expected error message to contain 'incomplete SSE stream', got: %v", err)
+	}
+
+	// 测试用例3: 既没有 candidates 也没有 usageMetadata 的事件
+	if err := validator.ValidateSSEChunk([]byte(`data: {"text":"Hello"}`), "gemini"); err == nil {
+		t.Error("Expected Gemini SSE data without candidates to fail validation")
+	}
+}
+
 func TestValidateResponseWithPathStreamingIntegration(t *testing.T) {
 	validator := NewResponseValidator()
 	
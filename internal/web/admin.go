@@ -32,6 +32,7 @@ type AdminServer struct {
 	i18nManager      *i18n.Manager
 	csrfManager      *security.CSRFManager
 	authManager      *security.AuthManager
+	oauthFlows       *oauthFlowStore
 }
 
 func NewAdminServer(cfg *config.Config, endpointManager *endpoint.Manager, taggingManager *tagging.Manager, log *logger.Logger, configFilePath string, version string, i18nManager *i18n.Manager, authManager *security.AuthManager) *AdminServer {
@@ -45,6 +46,7 @@ func NewAdminServer(cfg *config.Config, endpointManager *endpoint.Manager, taggi
 		i18nManager:     i18nManager,
 		csrfManager:     security.NewCSRFManager(),
 		authManager:     authManager,
+		oauthFlows:      newOAuthFlowStore(),
 	}
 }
 
@@ -247,6 +249,7 @@ func (s *AdminServer) RegisterRoutes(router *gin.Engine) {
 		adminGroup.GET("/taggers", s.handleTaggersPage)
 		adminGroup.GET("/logs", s.handleLogsPage)
 		adminGroup.GET("/settings", s.handleSettingsPage)
+		adminGroup.GET("/oauth/callback", s.handleOAuthCallback) // OAuth 授权码回调，路径与 config.Default.OAuth.CallbackPath 一致
 	}
 
 	// 注册 API 路由，添加身份验证、UTF-8字符集中间件和CSRF防护
@@ -263,6 +266,9 @@ func (s *AdminServer) RegisterRoutes(router *gin.Engine) {
 		api.GET("/auth/user", s.handleGetCurrentUser)
 
 		api.GET("/endpoints", s.handleGetEndpoints)
+		api.GET("/endpoints/status", s.handleGetEndpointStatus)
+		api.GET("/endpoints/events", s.handleGetEndpointEvents)
+		api.GET("/models", s.handleGetModels)
 		api.PUT("/endpoints", s.handleUpdateEndpoints)
 		api.POST("/endpoints", s.handleCreateEndpoint)
 		api.PUT("/endpoints/:id", s.handleUpdateEndpoint)
@@ -272,8 +278,15 @@ func (s *AdminServer) RegisterRoutes(router *gin.Engine) {
 		api.POST("/endpoints/:id/copy", s.handleCopyEndpoint)
 		api.POST("/endpoints/:id/toggle", s.handleToggleEndpoint)
 		api.POST("/endpoints/:id/reset-status", s.handleResetEndpointStatus)
+		api.POST("/endpoints/:id/oauth/authorize", s.handleStartOAuthLogin)
+		api.GET("/endpoints/:id/models", s.handleGetEndpointModels)
 		api.POST("/endpoints/reorder", s.handleReorderEndpoints)
 
+		// 会话粘性路由绑定
+		api.GET("/sessions", s.handleGetSessionBindings)
+		api.DELETE("/sessions", s.handleClearSessionBindings)
+		api.DELETE("/sessions/:id", s.handleDeleteSessionBinding)
+
 		// 端点向导路由
 		s.registerEndpointWizardRoutes(api)
 
@@ -286,6 +299,8 @@ func (s *AdminServer) RegisterRoutes(router *gin.Engine) {
 		api.GET("/logs", s.handleGetLogs)
 		api.POST("/logs/cleanup", s.handleCleanupLogs)
 		api.GET("/logs/stats", s.handleGetLogStats)
+		api.GET("/usage", s.handleGetUsage)
+		api.GET("/analytics", s.handleGetAnalytics)
 		api.GET("/logs/:request_id/export", s.handleExportDebugInfo)
 		api.PUT("/config", s.handleHotUpdateConfig)
 		api.GET("/config", s.handleGetConfig)
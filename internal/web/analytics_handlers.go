@@ -0,0 +1,168 @@
+package web
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/statistics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAnalyticsBuckets 限制单次查询的时间桶数量，避免按小时查询过长的时间范围
+const maxAnalyticsBuckets = 2000
+
+// analyticsSeries 一个维度值在查询范围内的时间序列，points 与响应中的 buckets 一一对应
+type analyticsSeries struct {
+	Key    string                   `json:"key"`
+	Totals statistics.RollupStats   `json:"totals"`
+	Points []statistics.RollupStats `json:"points"`
+}
+
+// handleGetAnalytics 查询按小时或天汇总的请求数、错误、延迟分位数、token 用量和费用
+// 参数：granularity（hour 或 day，默认 hour）、dimension（total、endpoint、model、tag、session、client_token，默认 total）、
+// since / until（RFC3339 或相对时长；默认按小时为最近24小时，按天为最近30天）、keys（逗号分隔的维度值）、
+// limit（未指定 keys 时按请求数返回前 N 个维度值，默认 10，最大 50）
+func (s *AdminServer) handleGetAnalytics(c *gin.Context) {
+	granularity := c.DefaultQuery("granularity", statistics.GranularityHour)
+	if granularity != statistics.GranularityHour && granularity != statistics.GranularityDay {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid granularity, supported: hour, day"})
+		return
+	}
+	dimension := c.DefaultQuery("dimension", statistics.DimensionTotal)
+	if !slices.Contains(statistics.UsageRollupDimensions, dimension) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dimension, supported: " + strings.Join(statistics.UsageRollupDimensions, ", ")})
+		return
+	}
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 || n > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit, must be between 1 and 50"})
+			return
+		}
+		limit = n
+	}
+
+	now := time.Now()
+	defaultSince := "24h"
+	if granularity == statistics.GranularityDay {
+		defaultSince = "30d"
+	}
+	since, err := parseEventTime(c.DefaultQuery("since", defaultSince), now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since: " + err.Error()})
+		return
+	}
+	until := now
+	if untilStr := c.Query("until"); untilStr != "" {
+		if until, err = parseEventTime(untilStr, now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until: " + err.Error()})
+			return
+		}
+	}
+	if !since.Before(until) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be before until"})
+		return
+	}
+
+	buckets := analyticsBuckets(granularity, since, until)
+	if len(buckets) > maxAnalyticsBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Time range too large for granularity " + granularity})
+		return
+	}
+
+	rollups, err := s.endpointManager.QueryAnalytics(statistics.UsageRollupFilter{
+		Granularity: granularity,
+		Dimension:   dimension,
+		Keys:        splitQueryList(c.Query("keys")),
+		Since:       buckets[0],
+		Until:       until,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query analytics: " + err.Error()})
+		return
+	}
+
+	series := buildAnalyticsSeries(rollups, buckets)
+	if len(series) > limit {
+		series = series[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":     s.endpointManager.IsAnalyticsEnabled(),
+		"granularity": granularity,
+		"dimension":   dimension,
+		"since":       since,
+		"until":       until,
+		"buckets":     buckets,
+		"series":      series,
+	})
+}
+
+// analyticsBuckets 返回覆盖查询范围的时间桶起点，按本地时区的整点或零点划分
+func analyticsBuckets(granularity string, since, until time.Time) []time.Time {
+	local := since.Local()
+	var start time.Time
+	if granularity == statistics.GranularityDay {
+		start = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local)
+	} else {
+		start = time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, time.Local)
+	}
+
+	var buckets []time.Time
+	for bucket := start; bucket.Before(until) && len(buckets) <= maxAnalyticsBuckets; {
+		buckets = append(buckets, bucket)
+		if granularity == statistics.GranularityDay {
+			bucket = bucket.AddDate(0, 0, 1)
+		} else {
+			bucket = bucket.Add(time.Hour)
+		}
+	}
+	return buckets
+}
+
+// buildAnalyticsSeries 按维度值拆分汇总，对齐到时间桶，并按请求数从多到少排序
+func buildAnalyticsSeries(rollups []*statistics.UsageRollup, buckets []time.Time) []*analyticsSeries {
+	bucketIndex := make(map[int64]int, len(buckets))
+	for i, bucket := range buckets {
+		bucketIndex[bucket.Unix()] = i
+	}
+
+	totals := make(map[string]*statistics.UsageRollup)
+	points := make(map[string][]statistics.RollupStats)
+	var keys []string
+	for _, rollup := range rollups {
+		i, ok := bucketIndex[rollup.BucketStart.Unix()]
+		if !ok {
+			continue
+		}
+		if _, exists := totals[rollup.Key]; !exists {
+			totals[rollup.Key] = &statistics.UsageRollup{}
+			points[rollup.Key] = make([]statistics.RollupStats, len(buckets))
+			keys = append(keys, rollup.Key)
+		}
+		totals[rollup.Key].Merge(rollup)
+		points[rollup.Key][i] = rollup.Stats()
+	}
+
+	series := make([]*analyticsSeries, 0, len(keys))
+	for _, key := range keys {
+		series = append(series, &analyticsSeries{
+			Key:    key,
+			Totals: totals[key].Stats(),
+			Points: points[key],
+		})
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		if series[i].Totals.Requests != series[j].Totals.Requests {
+			return series[i].Totals.Requests > series[j].Totals.Requests
+		}
+		return series[i].Key < series[j].Key
+	})
+	return series
+}
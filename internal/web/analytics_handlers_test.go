@@ -0,0 +1,83 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/statistics"
+)
+
+func TestAnalyticsBuckets(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name        string
+		granularity string
+		since       time.Time
+		until       time.Time
+		expected    []time.Time
+	}{
+		{"hour aligned to the hour", statistics.GranularityHour, at(10, 10, 30), at(10, 12, 15), []time.Time{at(10, 10, 0), at(10, 11, 0), at(10, 12, 0)}},
+		{"hour until is exclusive", statistics.GranularityHour, at(10, 10, 0), at(10, 12, 0), []time.Time{at(10, 10, 0), at(10, 11, 0)}},
+		{"hour across midnight", statistics.GranularityHour, at(10, 23, 10), at(11, 0, 10), []time.Time{at(10, 23, 0), at(11, 0, 0)}},
+		{"day aligned to midnight", statistics.GranularityDay, at(10, 15, 0), at(12, 1, 0), []time.Time{at(10, 0, 0), at(11, 0, 0), at(12, 0, 0)}},
+		{"day within one day", statistics.GranularityDay, at(10, 1, 0), at(10, 2, 0), []time.Time{at(10, 0, 0)}},
+	}
+	for _, tt := range tests {
+		got := analyticsBuckets(tt.granularity, tt.since, tt.until)
+		if len(got) != len(tt.expected) {
+			t.Errorf("%s: expected %d buckets, got %v", tt.name, len(tt.expected), got)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(tt.expected[i]) {
+				t.Errorf("%s: bucket %d expected %v, got %v", tt.name, i, tt.expected[i], got[i])
+			}
+		}
+	}
+
+	// 过长的时间范围在超过上限后停止生成
+	if got := analyticsBuckets(statistics.GranularityHour, at(1, 0, 0), at(1, 0, 0).AddDate(1, 0, 0)); len(got) != maxAnalyticsBuckets+1 {
+		t.Errorf("expected bucket generation to stop after the limit, got %d", len(got))
+	}
+}
+
+func TestBuildAnalyticsSeries(t *testing.T) {
+	start := time.Date(2026, 3, 10, 10, 0, 0, 0, time.Local)
+	buckets := []time.Time{start, start.Add(time.Hour), start.Add(2 * time.Hour)}
+	rollup := func(offset int, key string, requests int64) *statistics.UsageRollup {
+		// 数据库中的时间桶以 UTC 存储
+		return &statistics.UsageRollup{
+			Granularity: statistics.GranularityHour,
+			BucketStart: start.Add(time.Duration(offset) * time.Hour).UTC(),
+			Dimension:   statistics.DimensionEndpoint,
+			Key:         key,
+			Requests:    requests,
+		}
+	}
+
+	series := buildAnalyticsSeries([]*statistics.UsageRollup{
+		rollup(0, "a", 1),
+		rollup(0, "b", 5),
+		rollup(2, "a", 2),
+		rollup(5, "c", 100), // 不在查询的时间桶内
+		rollup(1, "d", 3),
+	}, buckets)
+
+	if len(series) != 3 {
+		t.Fatalf("expected 3 series, got %d", len(series))
+	}
+	// 按总请求数从多到少排序，相同时按维度值排序
+	if series[0].Key != "b" || series[1].Key != "a" || series[2].Key != "d" {
+		t.Errorf("unexpected series order: %s %s %s", series[0].Key, series[1].Key, series[2].Key)
+	}
+	a := series[1]
+	if a.Totals.Requests != 3 || len(a.Points) != 3 {
+		t.Fatalf("unexpected series a: %+v", a)
+	}
+	if a.Points[0].Requests != 1 || a.Points[1].Requests != 0 || a.Points[2].Requests != 2 {
+		t.Errorf("expected points aligned to buckets with gaps, got %+v", a.Points)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"claude-code-companion/internal/config"
 	"claude-code-companion/internal/modelrewrite"
@@ -114,6 +115,23 @@ func (s *AdminServer) handleUpdateEndpointModelRewrite(c *gin.Context) {
 		return
 	}
 
+	// 启用模型发现时检查目标模型是否在上游模型列表中，force=true 时跳过
+	if s.endpointManager.IsModelDiscoveryEnabled() && c.Query("force") != "true" && request.Enabled {
+		if ep := s.findRuntimeEndpoint(endpointName); ep != nil {
+			targets := make([]string, 0, len(request.Rules))
+			for _, rule := range request.Rules {
+				targets = append(targets, rule.TargetModel)
+			}
+			if unknown := ep.UnknownModels(targets); len(unknown) > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":          "Target models are not in the upstream model list: " + strings.Join(unknown, ", "),
+					"unknown_models": unknown,
+				})
+				return
+			}
+		}
+	}
+
 	// 获取当前所有端点
 	currentEndpoints := s.config.Endpoints
 	found := false
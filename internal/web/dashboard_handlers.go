@@ -1,6 +1,10 @@
 package web
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"claude-code-companion/internal/endpoint"
 
 	"github.com/gin-gonic/gin"
@@ -15,12 +19,20 @@ func (s *AdminServer) handleDashboard(c *gin.Context) {
 	
 	type EndpointStats struct {
 		*endpoint.Endpoint
-		SuccessRate string
+		SuccessRate     string
+		BlacklistReason *endpoint.BlacklistReason
+		BudgetExceeded  bool
+		BudgetWindows   []budgetWindowDisplay
+		CircuitState    endpoint.CircuitState
 	}
 	
 	endpointStats := make([]EndpointStats, 0)
+	tagSet := make(map[string]bool)
 	
 	for _, ep := range endpoints {
+		for _, tag := range ep.Tags {
+			tagSet[tag] = true
+		}
 		totalRequests += ep.TotalRequests
 		successRequests += ep.SuccessRequests
 		if ep.Status == endpoint.StatusActive {
@@ -29,14 +41,28 @@ func (s *AdminServer) handleDashboard(c *gin.Context) {
 		
 		successRate := calculateSuccessRate(ep.SuccessRequests, ep.TotalRequests)
 		
-		endpointStats = append(endpointStats, EndpointStats{
-			Endpoint:    ep,
-			SuccessRate: successRate,
-		})
+		stats := EndpointStats{
+			Endpoint:        ep,
+			SuccessRate:     successRate,
+			BlacklistReason: ep.GetBlacklistReason(),
+			BudgetExceeded:  ep.IsBudgetExceeded(),
+			BudgetWindows:   formatBudgetWindows(ep.GetBudgetStatus()),
+		}
+		if circuit := ep.GetCircuitStatus(); circuit != nil {
+			stats.CircuitState = circuit.State
+		}
+		endpointStats = append(endpointStats, stats)
 	}
 	
 	overallSuccessRate := calculateSuccessRate(successRequests, totalRequests)
 	
+	// 事件时间线按tag（端点池）筛选
+	endpointTags := make([]string, 0, len(tagSet))
+	for tag := range tagSet {
+		endpointTags = append(endpointTags, tag)
+	}
+	sort.Strings(endpointTags)
+	
 	data := s.mergeTemplateData(c, "dashboard", map[string]interface{}{
 		"Title":             "Claude Proxy Dashboard",
 		"TotalEndpoints":    len(endpoints),
@@ -45,6 +71,7 @@ func (s *AdminServer) handleDashboard(c *gin.Context) {
 		"SuccessRequests":   successRequests,
 		"OverallSuccessRate": overallSuccessRate,
 		"Endpoints":         endpointStats,
+		"EndpointTags":      endpointTags,
 	})
 	s.renderHTML(c, "dashboard.html", data)
 }
@@ -73,4 +100,44 @@ func (s *AdminServer) handleEndpointsPage(c *gin.Context) {
 		"Endpoints": endpointStats,
 	})
 	s.renderHTML(c, "endpoints.html", data)
-}
\ No newline at end of file
+}
+// budgetWindowDisplay 仪表板中一个预算窗口的用量展示
+type budgetWindowDisplay struct {
+	Period   string
+	Usage    string
+	Exceeded bool
+}
+
+// formatBudgetWindows 把预算窗口格式化为 "输入 1.2M/5M · 输出 20K/1M · $3.21/$5.00" 形式，只展示已配置的限制
+func formatBudgetWindows(windows []endpoint.BudgetWindow) []budgetWindowDisplay {
+	displays := make([]budgetWindowDisplay, 0, len(windows))
+	for _, window := range windows {
+		var parts []string
+		if window.MaxInputTokens > 0 {
+			parts = append(parts, fmt.Sprintf("in %s/%s", formatTokenCount(window.InputTokens), formatTokenCount(window.MaxInputTokens)))
+		}
+		if window.MaxOutputTokens > 0 {
+			parts = append(parts, fmt.Sprintf("out %s/%s", formatTokenCount(window.OutputTokens), formatTokenCount(window.MaxOutputTokens)))
+		}
+		if window.MaxCost > 0 {
+			parts = append(parts, fmt.Sprintf("$%.2f/$%.2f", window.Cost, window.MaxCost))
+		}
+		displays = append(displays, budgetWindowDisplay{
+			Period:   window.Period,
+			Usage:    strings.Join(parts, " · "),
+			Exceeded: window.Exceeded,
+		})
+	}
+	return displays
+}
+
+func formatTokenCount(tokens int64) string {
+	switch {
+	case tokens >= 1000000:
+		return fmt.Sprintf("%.1fM", float64(tokens)/1000000)
+	case tokens >= 1000:
+		return fmt.Sprintf("%.1fK", float64(tokens)/1000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+}
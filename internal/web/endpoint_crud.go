@@ -56,7 +56,7 @@ func (s *AdminServer) handleCreateEndpoint(c *gin.Context) {
 	var request struct {
 		Name              string               `json:"name" binding:"required"`
 		URL               string               `json:"url" binding:"required"`
-		EndpointType      string               `json:"endpoint_type"` // "anthropic" | "openai"
+		EndpointType      string               `json:"endpoint_type"` // "anthropic" | "openai" | "openai_responses" | "gemini"
 		PathPrefix        string               `json:"path_prefix"`   // OpenAI 端点的路径前缀
 		AuthType          string               `json:"auth_type" binding:"required"`
 		AuthValue         string               `json:"auth_value"`    // OAuth时不需要
@@ -298,6 +298,7 @@ func (s *AdminServer) handleUpdateEndpoint(c *gin.Context) {
 					// 设置OAuth配置，清空auth_value
 					currentEndpoints[i].OAuthConfig = request.OAuthConfig
 					currentEndpoints[i].AuthValue = ""
+					currentEndpoints[i].AuthValues = nil
 				} else {
 					// 非 OAuth 认证，清空OAuth配置
 					currentEndpoints[i].OAuthConfig = nil
@@ -6,6 +6,7 @@ import (
 	"net/url"
 
 	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/endpoint"
 
 	"github.com/gin-gonic/gin"
 )
@@ -117,6 +118,16 @@ func (s *AdminServer) handleCopyEndpoint(c *gin.Context) {
 	// 深度复制Tags切片
 	copy(newEndpoint.Tags, sourceEndpoint.Tags)
 
+	// 复制多凭据及轮换策略
+	if len(sourceEndpoint.AuthValues) > 0 {
+		newEndpoint.AuthValues = append([]string(nil), sourceEndpoint.AuthValues...)
+	}
+	if sourceEndpoint.KeyRotation != nil {
+		keyRotation := *sourceEndpoint.KeyRotation
+		keyRotation.RotateStatusCodes = append([]int(nil), sourceEndpoint.KeyRotation.RotateStatusCodes...)
+		newEndpoint.KeyRotation = &keyRotation
+	}
+
 	// 深度复制ModelRewrite配置
 	if sourceEndpoint.ModelRewrite != nil {
 		newEndpoint.ModelRewrite = &config.ModelRewriteConfig{
@@ -233,4 +244,45 @@ func (s *AdminServer) handleReorderEndpoints(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Endpoints reordered successfully"})
-}
\ No newline at end of file
+}
+// endpointRuntimeStatus 端点的运行时状态
+type endpointRuntimeStatus struct {
+	Name            string                       `json:"name"`
+	Enabled         bool                         `json:"enabled"`
+	Status          endpoint.Status              `json:"status"`
+	Available       bool                         `json:"available"`
+	InFlight        int64                        `json:"in_flight"`
+	Saturation      *endpoint.LimiterStatus      `json:"saturation,omitempty"`
+	Budget          []endpoint.BudgetWindow      `json:"budget,omitempty"`
+	RateLimit       *endpoint.RateLimitStatus    `json:"rate_limit,omitempty"`
+	Circuit         *endpoint.CircuitStatus      `json:"circuit,omitempty"`
+	HealthChecks    []endpoint.HealthCheckResult `json:"health_checks,omitempty"`
+	APIKeys         []endpoint.APIKeyStatus      `json:"api_keys,omitempty"`
+	BlacklistReason *endpoint.BlacklistReason    `json:"blacklist_reason,omitempty"`
+}
+
+// handleGetEndpointStatus 获取所有端点的运行时状态：可用性、并发与速率限制饱和度、预算用量、上游剩余额度、熔断器状态与转换历史、健康检查历史、各凭据健康状态和拉黑原因
+func (s *AdminServer) handleGetEndpointStatus(c *gin.Context) {
+	endpoints := s.endpointManager.GetAllEndpoints()
+	statuses := make([]endpointRuntimeStatus, 0, len(endpoints))
+	for _, ep := range endpoints {
+		statuses = append(statuses, endpointRuntimeStatus{
+			Name:            ep.Name,
+			Enabled:         ep.IsEnabled(),
+			Status:          ep.Status,
+			Available:       ep.IsAvailable(),
+			InFlight:        ep.GetInFlight(),
+			Saturation:      ep.GetLimiter().Status(),
+			Budget:          ep.GetBudgetStatus(),
+			RateLimit:       ep.GetRateLimitStatus(),
+			Circuit:         ep.GetCircuitStatus(),
+			HealthChecks:    ep.GetHealthCheckHistory(),
+			APIKeys:         ep.GetAPIKeyStatus(),
+			BlacklistReason: ep.GetBlacklistReason(),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"endpoints": statuses,
+	})
+}
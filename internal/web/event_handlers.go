@@ -0,0 +1,242 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/statistics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// endpointOutageSummary 一个端点在查询时间范围内的失效统计
+type endpointOutageSummary struct {
+	EndpointID      string         `json:"endpoint_id"`
+	EndpointName    string         `json:"endpoint_name"`
+	Outages         int            `json:"outages"`          // 范围内开始的失效次数，加上范围开始时尚未恢复的失效
+	DowntimeSeconds int64          `json:"downtime_seconds"` // 这些失效在范围内的累计时长，未恢复的计算到查询结束时间
+	CurrentlyDown   bool           `json:"currently_down"`
+	Causes          map[string]int `json:"causes"` // 失效类别（statistics.Cause*）→ 次数
+}
+
+// handleGetEndpointEvents 查询端点事件时间线（状态变化、健康检查、额度与 OAuth 刷新），并按端点汇总失效次数、时长和原因
+// 参数：endpoint（端点名，逗号分隔）、tag（包含该tag的端点）、type（事件类型，逗号分隔）、
+// since / until（RFC3339 或相对时长，如 24h、7d；默认最近7天）、limit、offset
+func (s *AdminServer) handleGetEndpointEvents(c *gin.Context) {
+	now := time.Now()
+	since, err := parseEventTime(c.DefaultQuery("since", "7d"), now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since: " + err.Error()})
+		return
+	}
+	until := now
+	if untilStr := c.Query("until"); untilStr != "" {
+		if until, err = parseEventTime(untilStr, now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until: " + err.Error()})
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	endpointIDs, matched := s.resolveEventEndpoints(splitQueryList(c.Query("endpoint")), c.Query("tag"))
+	if !matched {
+		c.JSON(http.StatusOK, gin.H{"events": []*statistics.EndpointEvent{}, "total": 0, "summary": []*endpointOutageSummary{}})
+		return
+	}
+
+	filter := statistics.EndpointEventFilter{
+		EndpointIDs: endpointIDs,
+		EventTypes:  splitQueryList(c.Query("type")),
+		Since:       since,
+		Until:       until,
+		Limit:       limit,
+		Offset:      offset,
+	}
+	events, total, err := s.endpointManager.QueryEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query endpoint events: " + err.Error()})
+		return
+	}
+
+	// 汇总需要范围内所有状态变化事件，不受分页和类型过滤影响
+	transitions, _, err := s.endpointManager.QueryEvents(statistics.EndpointEventFilter{
+		EndpointIDs: endpointIDs,
+		EventTypes:  []string{statistics.EventInactive, statistics.EventActive},
+		Since:       since,
+		Until:       until,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query endpoint events: " + err.Error()})
+		return
+	}
+	prior, err := s.lastTransitionsBefore(endpointIDs, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query endpoint events: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":  events,
+		"total":   total,
+		"since":   since,
+		"until":   until,
+		"summary": summarizeOutages(prior, transitions, since, until),
+	})
+}
+
+// lastTransitionsBefore 返回每个端点在 since 之前的最后一条 inactive / active 事件，用于判断范围开始时端点是否已经失效；
+// endpointIDs 为 nil 时使用当前配置的所有端点
+func (s *AdminServer) lastTransitionsBefore(endpointIDs []string, since time.Time) ([]*statistics.EndpointEvent, error) {
+	if endpointIDs == nil {
+		for _, ep := range s.endpointManager.GetAllEndpoints() {
+			endpointIDs = append(endpointIDs, ep.ID)
+		}
+	}
+
+	var prior []*statistics.EndpointEvent
+	for _, id := range endpointIDs {
+		events, _, err := s.endpointManager.QueryEvents(statistics.EndpointEventFilter{
+			EndpointIDs: []string{id},
+			EventTypes:  []string{statistics.EventInactive, statistics.EventActive},
+			Until:       since,
+			Limit:       1,
+		})
+		if err != nil {
+			return nil, err
+		}
+		prior = append(prior, events...)
+	}
+	return prior, nil
+}
+
+// resolveEventEndpoints 把端点名和tag转换为端点ID；都未指定时返回 nil（所有端点），
+// 指定了但没有匹配的端点时 matched 为 false
+func (s *AdminServer) resolveEventEndpoints(names []string, tag string) ([]string, bool) {
+	if len(names) == 0 && tag == "" {
+		return nil, true
+	}
+
+	var ids []string
+	// 按名称生成ID，已删除端点的历史事件也能查询
+	for _, name := range names {
+		ids = append(ids, statistics.GenerateEndpointID(name))
+	}
+	if tag != "" {
+		for _, ep := range s.endpointManager.GetAllEndpoints() {
+			for _, epTag := range ep.Tags {
+				if epTag == tag {
+					ids = append(ids, ep.ID)
+					break
+				}
+			}
+		}
+	}
+	return ids, len(ids) > 0
+}
+
+// summarizeOutages 按端点汇总失效：prior 为每个端点在 since 之前的最后一条状态变化事件，
+// events 为范围内最新在前的 inactive / active 事件；范围开始时已经失效的端点从 since 开始计算失效时长
+func summarizeOutages(prior, events []*statistics.EndpointEvent, since, until time.Time) []*endpointOutageSummary {
+	summaries := make(map[string]*endpointOutageSummary)
+	downSince := make(map[string]time.Time)
+
+	summaryFor := func(event *statistics.EndpointEvent) *endpointOutageSummary {
+		summary := summaries[event.EndpointID]
+		if summary == nil {
+			summary = &endpointOutageSummary{
+				EndpointID: event.EndpointID,
+				Causes:     make(map[string]int),
+			}
+			summaries[event.EndpointID] = summary
+		}
+		summary.EndpointName = event.EndpointName
+		return summary
+	}
+	startOutage := func(event *statistics.EndpointEvent, at time.Time) {
+		summary := summaryFor(event)
+		summary.Outages++
+		summary.Causes[event.Cause]++
+		downSince[event.EndpointID] = at
+	}
+
+	for _, event := range prior {
+		if event.EventType == statistics.EventInactive {
+			startOutage(event, since)
+		}
+	}
+
+	for i := len(events) - 1; i >= 0; i-- {
+		event := events[i]
+		summary := summaryFor(event)
+
+		start, down := downSince[event.EndpointID]
+		switch event.EventType {
+		case statistics.EventInactive:
+			if down {
+				continue
+			}
+			startOutage(event, event.CreatedAt)
+		case statistics.EventActive:
+			if !down {
+				continue
+			}
+			summary.DowntimeSeconds += int64(event.CreatedAt.Sub(start).Seconds())
+			delete(downSince, event.EndpointID)
+		}
+	}
+
+	for id, start := range downSince {
+		summaries[id].DowntimeSeconds += int64(until.Sub(start).Seconds())
+		summaries[id].CurrentlyDown = true
+	}
+
+	result := make([]*endpointOutageSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, summary)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Outages != result[j].Outages {
+			return result[i].Outages > result[j].Outages
+		}
+		return result[i].EndpointName < result[j].EndpointName
+	})
+	return result
+}
+
+// parseEventTime 解析 RFC3339 时间或相对于 now 的时长（支持 d 表示天，如 7d、12h）
+func parseEventTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither an RFC3339 time nor a duration such as 24h or 7d", value)
+}
+
+// splitQueryList 拆分逗号分隔的查询参数，忽略空项
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/statistics"
+)
+
+func TestParseEventTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{"7d", now.AddDate(0, 0, -7), false},
+		{"0d", now, false},
+		{"24h", now.Add(-24 * time.Hour), false},
+		{"90m", now.Add(-90 * time.Minute), false},
+		{"2024-05-01T08:00:00Z", time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), false},
+		{"2024-05-01T08:00:00+08:00", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), false},
+		{"-7d", time.Time{}, true},
+		{"-1h", time.Time{}, true},
+		{"xd", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"2024-05-01", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseEventTime(tt.value, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.value, err)
+		} else if !got.Equal(tt.expected) {
+			t.Errorf("%q: expected %v, got %v", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestSummarizeOutages(t *testing.T) {
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(10 * time.Hour)
+	at := func(hours float64) time.Time { return since.Add(time.Duration(hours * float64(time.Hour))) }
+	event := func(endpoint, eventType, cause string, createdAt time.Time) *statistics.EndpointEvent {
+		return &statistics.EndpointEvent{EndpointID: endpoint, EndpointName: endpoint, EventType: eventType, Cause: cause, CreatedAt: createdAt}
+	}
+
+	prior := []*statistics.EndpointEvent{
+		event("seeded", statistics.EventInactive, statistics.CauseBudget, since.Add(-5*time.Hour)), // 范围开始前已失效
+		event("recovered", statistics.EventActive, "", since.Add(-time.Hour)),                      // 范围开始前已恢复
+	}
+	// 最新在前
+	events := []*statistics.EndpointEvent{
+		event("down", statistics.EventInactive, statistics.CauseCircuit, at(8)),
+		event("flappy", statistics.EventActive, "", at(6)),
+		event("flappy", statistics.EventInactive, statistics.CauseFailures, at(5)),
+		event("flappy", statistics.EventActive, "", at(3)),
+		event("flappy", statistics.EventInactive, statistics.CauseFailures, at(2.5)), // 重复的 inactive 不重新计数
+		event("flappy", statistics.EventInactive, statistics.CauseCircuit, at(2)),
+		event("seeded", statistics.EventActive, "", at(1)),
+		event("recovered", statistics.EventActive, "", at(0.5)), // 没有对应 inactive 的 active 被忽略
+	}
+
+	summaries := make(map[string]*endpointOutageSummary)
+	for _, summary := range summarizeOutages(prior, events, since, until) {
+		summaries[summary.EndpointID] = summary
+	}
+
+	tests := []struct {
+		endpoint        string
+		outages         int
+		downtimeSeconds int64
+		currentlyDown   bool
+		causes          map[string]int
+	}{
+		{"seeded", 1, 3600, false, map[string]int{statistics.CauseBudget: 1}},
+		{"flappy", 2, 2 * 3600, false, map[string]int{statistics.CauseCircuit: 1, statistics.CauseFailures: 1}},
+		{"down", 1, 2 * 3600, true, map[string]int{statistics.CauseCircuit: 1}},
+		{"recovered", 0, 0, false, map[string]int{}},
+	}
+	for _, tt := range tests {
+		summary := summaries[tt.endpoint]
+		if summary == nil {
+			t.Errorf("%s: missing summary", tt.endpoint)
+			continue
+		}
+		if summary.Outages != tt.outages || summary.DowntimeSeconds != tt.downtimeSeconds || summary.CurrentlyDown != tt.currentlyDown {
+			t.Errorf("%s: expected outages=%d downtime=%ds down=%v, got outages=%d downtime=%ds down=%v",
+				tt.endpoint, tt.outages, tt.downtimeSeconds, tt.currentlyDown, summary.Outages, summary.DowntimeSeconds, summary.CurrentlyDown)
+		}
+		if len(summary.Causes) != len(tt.causes) {
+			t.Errorf("%s: expected causes %v, got %v", tt.endpoint, tt.causes, summary.Causes)
+			continue
+		}
+		for cause, count := range tt.causes {
+			if summary.Causes[cause] != count {
+				t.Errorf("%s: expected causes %v, got %v", tt.endpoint, tt.causes, summary.Causes)
+				break
+			}
+		}
+	}
+
+	// 多的排在前面
+	result := summarizeOutages(prior, events, since, until)
+	if result[0].EndpointID != "flappy" {
+		t.Errorf("expected the endpoint with the most outages first, got %s", result[0].EndpointID)
+	}
+}
@@ -3,9 +3,11 @@ package web
 import (
 	"archive/zip"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -77,6 +79,8 @@ func (s *AdminServer) handleLogsPage(c *gin.Context) {
 	s.renderHTML(c, "logs.html", data)
 }
 
+// handleGetLogs 查询请求日志，支持按时间、端点、模型、状态码、标签、会话、流式、thinking、错误文本过滤，
+// q 在请求体/响应体中全文搜索，sort/order 指定排序，cursor 使用上一页返回的 next_cursor 翻页
 func (s *AdminServer) handleGetLogs(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "100")
 	offsetStr := c.DefaultQuery("offset", "0")
@@ -86,6 +90,9 @@ func (s *AdminServer) handleGetLogs(c *gin.Context) {
 	limit, _ := strconv.Atoi(limitStr)
 	offset, _ := strconv.Atoi(offsetStr)
 	failedOnly, _ := strconv.ParseBool(failedOnlyStr)
+	if limit > 1000 {
+		limit = 1000
+	}
 
 	if requestIDStr != "" {
 		// 如果指定了request_id，返回该请求的所有尝试记录
@@ -97,18 +104,108 @@ func (s *AdminServer) handleGetLogs(c *gin.Context) {
 		return
 	}
 
-	logs, total, err := s.logger.GetLogs(limit, offset, failedOnly)
+	filter := logger.LogSearchFilter{
+		Model:      c.Query("model"),
+		Tag:        c.Query("tag"),
+		SessionID:  c.Query("session_id"),
+		FailedOnly: failedOnly,
+		ErrorText:  c.Query("error"),
+		Query:      c.Query("q"),
+		SortBy:     c.DefaultQuery("sort", "timestamp"),
+		Limit:      limit,
+		Offset:     offset,
+		Cursor:     c.Query("cursor"),
+	}
+	now := time.Now()
+	var err error
+	if since := c.Query("since"); since != "" {
+		if filter.Since, err = parseEventTime(since, now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since: " + err.Error()})
+			return
+		}
+	}
+	if until := c.Query("until"); until != "" {
+		if filter.Until, err = parseEventTime(until, now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until: " + err.Error()})
+			return
+		}
+	}
+	// 请求日志按端点URL记录，端点名需要转换；已删除的端点可以直接使用URL
+	if endpoint := c.Query("endpoint"); endpoint != "" {
+		filter.Endpoint = endpoint
+		if ep := s.getEndpointConfigByName(endpoint); ep != nil {
+			filter.Endpoint = ep.URL
+		}
+	}
+	// status 是逗号分隔的状态码或类别，如 429,5xx
+	for _, status := range splitQueryList(c.Query("status")) {
+		if class, ok := strings.CutSuffix(strings.ToLower(status), "xx"); ok {
+			n, err := strconv.Atoi(class)
+			if err != nil || n < 1 || n > 5 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status class: " + status})
+				return
+			}
+			filter.StatusClasses = append(filter.StatusClasses, n)
+			continue
+		}
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status code: " + status})
+			return
+		}
+		filter.StatusCodes = append(filter.StatusCodes, code)
+	}
+	if filter.Streaming, err = parseOptionalBool(c, "streaming"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Thinking, err = parseOptionalBool(c, "thinking"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !slices.Contains(logger.LogSortFields, filter.SortBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort, supported: " + strings.Join(logger.LogSortFields, ", ")})
+		return
+	}
+	switch order := c.DefaultQuery("order", "desc"); order {
+	case "asc":
+		filter.Ascending = true
+	case "desc":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order, must be asc or desc"})
+		return
+	}
+
+	result, err := s.logger.SearchLogs(filter)
 	if err != nil {
+		if errors.Is(err, logger.ErrInvalidCursor) || errors.Is(err, logger.ErrFullTextSearchUnavailable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve logs"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"logs":  logs,
-		"total": total,
+		"logs":        result.Logs,
+		"total":       result.Total,
+		"next_cursor": result.NextCursor,
 	})
 }
 
+// parseOptionalBool 解析可选的布尔查询参数，未提供时返回 nil
+func parseOptionalBool(c *gin.Context, name string) (*bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s: %s", name, value)
+	}
+	return &parsed, nil
+}
+
 // handleCleanupLogs 清理日志
 func (s *AdminServer) handleCleanupLogs(c *gin.Context) {
 	var request struct {
@@ -470,6 +567,12 @@ func (s *AdminServer) addEndpointConfigsToZip(zipWriter *zip.Writer, logs []*log
 			// 清空认证信息
 			sanitizedConfig := *config
 			sanitizedConfig.AuthValue = "[REDACTED]"
+			if len(sanitizedConfig.AuthValues) > 0 {
+				sanitizedConfig.AuthValues = make([]string, len(config.AuthValues))
+				for j := range sanitizedConfig.AuthValues {
+					sanitizedConfig.AuthValues[j] = "[REDACTED]"
+				}
+			}
 			
 			// 清空OAuth配置中的敏感信息
 			if sanitizedConfig.OAuthConfig != nil {
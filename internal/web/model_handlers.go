@@ -0,0 +1,57 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+
+	"claude-code-companion/internal/endpoint"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetModels 返回所有启用端点合并的模型目录
+func (s *AdminServer) handleGetModels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": s.endpointManager.IsModelDiscoveryEnabled(),
+		"models":  s.endpointManager.GetMergedModelCatalog(),
+	})
+}
+
+// handleGetEndpointModels 返回端点缓存的上游模型列表；refresh=true 时先立即从上游获取一次
+func (s *AdminServer) handleGetEndpointModels(c *gin.Context) {
+	endpointName, err := url.PathUnescape(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint name encoding"})
+		return
+	}
+	ep := s.findRuntimeEndpoint(endpointName)
+	if ep == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Endpoint not found"})
+		return
+	}
+
+	if c.Query("refresh") == "true" {
+		if err := s.endpointManager.RefreshModels(ep); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":   "Failed to list upstream models: " + err.Error(),
+				"catalog": ep.GetModelCatalog(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": s.endpointManager.IsModelDiscoveryEnabled(),
+		"catalog": ep.GetModelCatalog(),
+	})
+}
+
+// findRuntimeEndpoint 按名称查找运行中的端点
+func (s *AdminServer) findRuntimeEndpoint(name string) *endpoint.Endpoint {
+	for _, ep := range s.endpointManager.GetAllEndpoints() {
+		if ep.Name == name {
+			return ep
+		}
+	}
+	return nil
+}
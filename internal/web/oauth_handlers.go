@@ -0,0 +1,212 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"claude-code-companion/internal/config"
+	"claude-code-companion/internal/oauth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pendingOAuthFlow 已发起、等待回调的授权码流程
+type pendingOAuthFlow struct {
+	endpointName string
+	verifier     string
+	redirectURI  string
+	expiresAt    time.Time
+}
+
+// oauthFlowStore 按 state 保存等待回调的授权流程（内存中，重启后需要重新发起）
+type oauthFlowStore struct {
+	mutex sync.Mutex
+	flows map[string]*pendingOAuthFlow
+}
+
+func newOAuthFlowStore() *oauthFlowStore {
+	return &oauthFlowStore{flows: make(map[string]*pendingOAuthFlow)}
+}
+
+func (s *oauthFlowStore) add(state string, flow *pendingOAuthFlow) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for key, existing := range s.flows {
+		if now.After(existing.expiresAt) {
+			delete(s.flows, key)
+		}
+	}
+	s.flows[state] = flow
+}
+
+// take 取出并删除 state 对应的流程，每个 state 只能使用一次
+func (s *oauthFlowStore) take(state string) (*pendingOAuthFlow, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	flow, ok := s.flows[state]
+	if !ok {
+		return nil, false
+	}
+	delete(s.flows, state)
+	if time.Now().After(flow.expiresAt) {
+		return nil, false
+	}
+	return flow, true
+}
+
+// handleStartOAuthLogin 为 OAuth 端点发起授权码 + PKCE 流程，返回需要在浏览器中打开的授权页面URL
+func (s *AdminServer) handleStartOAuthLogin(c *gin.Context) {
+	endpointName, err := url.PathUnescape(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint name encoding"})
+		return
+	}
+
+	oauthConfig, err := s.findOAuthConfig(endpointName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if oauthConfig.AuthorizeURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "oauth authorize_url is not configured for this endpoint"})
+		return
+	}
+
+	redirectURI := oauthConfig.RedirectURI
+	if redirectURI == "" {
+		redirectURI = defaultOAuthRedirectURI(c)
+	}
+	pkce, err := oauth.NewPKCE()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	state, err := oauth.NewState()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	authorizationURL, err := oauth.BuildAuthorizationURL(oauthConfig, redirectURI, state, pkce)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expiresAt := time.Now().Add(config.Default.OAuth.FlowTimeout)
+	s.oauthFlows.add(state, &pendingOAuthFlow{
+		endpointName: endpointName,
+		verifier:     pkce.Verifier,
+		redirectURI:  redirectURI,
+		expiresAt:    expiresAt,
+	})
+	s.logger.Info(fmt.Sprintf("Started OAuth login for endpoint %s, redirect_uri: %s", endpointName, redirectURI))
+
+	c.JSON(http.StatusOK, gin.H{
+		"authorization_url": authorizationURL,
+		"redirect_uri":      redirectURI,
+		"expires_at":        expiresAt,
+	})
+}
+
+// handleOAuthCallback 处理授权服务器的重定向：校验 state，用授权码换取token并保存到端点配置
+func (s *AdminServer) handleOAuthCallback(c *gin.Context) {
+	flow, ok := s.oauthFlows.take(c.Query("state"))
+	if !ok {
+		s.renderOAuthCallback(c, "", fmt.Errorf("unknown or expired OAuth state, please start the login again"))
+		return
+	}
+	if errCode := c.Query("error"); errCode != "" {
+		s.renderOAuthCallback(c, flow.endpointName, fmt.Errorf("authorization denied: %s %s", errCode, c.Query("error_description")))
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		s.renderOAuthCallback(c, flow.endpointName, fmt.Errorf("authorization code is missing from the callback"))
+		return
+	}
+
+	s.renderOAuthCallback(c, flow.endpointName, s.completeOAuthLogin(flow, code, c.Query("state")))
+}
+
+// completeOAuthLogin 换取token并通过热更新保存到端点配置
+func (s *AdminServer) completeOAuthLogin(flow *pendingOAuthFlow, code, state string) error {
+	oauthConfig, err := s.findOAuthConfig(flow.endpointName)
+	if err != nil {
+		return err
+	}
+
+	// 使用端点的代理配置请求 token 端点
+	var client *http.Client
+	for _, ep := range s.endpointManager.GetAllEndpoints() {
+		if ep.Name == flow.endpointName {
+			if client, err = ep.CreateOAuthClient(s.config.Timeouts.ToProxyTimeoutConfig()); err != nil {
+				return fmt.Errorf("failed to create http client for token exchange: %v", err)
+			}
+			break
+		}
+	}
+	if client == nil {
+		return fmt.Errorf("endpoint '%s' not found", flow.endpointName)
+	}
+
+	newOAuthConfig, err := oauth.ExchangeCode(oauthConfig, code, state, flow.verifier, flow.redirectURI, client)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("OAuth code exchange failed for endpoint %s", flow.endpointName), err)
+		return err
+	}
+
+	currentEndpoints := make([]config.EndpointConfig, len(s.config.Endpoints))
+	copy(currentEndpoints, s.config.Endpoints)
+	for i := range currentEndpoints {
+		if currentEndpoints[i].Name == flow.endpointName {
+			currentEndpoints[i].OAuthConfig = newOAuthConfig
+			break
+		}
+	}
+	if err := s.hotUpdateEndpoints(currentEndpoints); err != nil {
+		return fmt.Errorf("failed to save oauth tokens: %v", err)
+	}
+
+	s.logger.Info(fmt.Sprintf("OAuth login completed for endpoint %s, token expires at %s",
+		flow.endpointName, time.UnixMilli(newOAuthConfig.ExpiresAt).Format(time.RFC3339)))
+	return nil
+}
+
+// findOAuthConfig 返回端点当前的 OAuth 配置副本
+func (s *AdminServer) findOAuthConfig(endpointName string) (*config.OAuthConfig, error) {
+	ep := s.getEndpointConfigByName(endpointName)
+	if ep == nil {
+		return nil, fmt.Errorf("endpoint '%s' not found", endpointName)
+	}
+	if ep.AuthType != "oauth" || ep.OAuthConfig == nil {
+		return nil, fmt.Errorf("endpoint '%s' is not configured for oauth authentication", endpointName)
+	}
+	oauthConfig := *ep.OAuthConfig
+	return &oauthConfig, nil
+}
+
+func (s *AdminServer) renderOAuthCallback(c *gin.Context, endpointName string, err error) {
+	data := s.getBaseTemplateData(c, "endpoints")
+	data["Title"] = "OAuth Login"
+	data["EndpointName"] = endpointName
+	data["Success"] = err == nil
+	if err != nil {
+		data["Error"] = err.Error()
+	}
+	s.renderHTML(c, "oauth-callback.html", data)
+}
+
+// defaultOAuthRedirectURI 根据当前请求的地址生成管理界面的回调地址
+func defaultOAuthRedirectURI(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + config.Default.OAuth.CallbackPath
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"claude-code-companion/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOAuthFlowStoreTake(t *testing.T) {
+	store := newOAuthFlowStore()
+	store.add("state-1", &pendingOAuthFlow{endpointName: "ep", verifier: "v", expiresAt: time.Now().Add(time.Minute)})
+
+	flow, ok := store.take("state-1")
+	if !ok || flow.endpointName != "ep" || flow.verifier != "v" {
+		t.Fatalf("expected pending flow for state-1, got %+v %v", flow, ok)
+	}
+	// 每个 state 只能使用一次
+	if _, ok := store.take("state-1"); ok {
+		t.Errorf("expected state to be single use")
+	}
+	if _, ok := store.take("unknown"); ok {
+		t.Errorf("expected unknown state to be rejected")
+	}
+
+	// 过期的流程被拒绝，且同样被删除
+	store.add("expired", &pendingOAuthFlow{endpointName: "ep", expiresAt: time.Now().Add(-time.Second)})
+	if _, ok := store.take("expired"); ok {
+		t.Errorf("expected expired state to be rejected")
+	}
+	if len(store.flows) != 0 {
+		t.Errorf("expected no remaining flows, got %d", len(store.flows))
+	}
+}
+
+func TestOAuthFlowStoreAddPrunesExpired(t *testing.T) {
+	store := newOAuthFlowStore()
+	store.add("expired", &pendingOAuthFlow{expiresAt: time.Now().Add(-time.Second)})
+	store.add("live", &pendingOAuthFlow{expiresAt: time.Now().Add(time.Minute)})
+
+	if _, exists := store.flows["expired"]; exists {
+		t.Errorf("expected expired flow pruned when a new flow is added")
+	}
+	if _, exists := store.flows["live"]; !exists {
+		t.Errorf("expected live flow to be kept")
+	}
+}
+
+func TestDefaultOAuthRedirectURI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name     string
+		tls      bool
+		proto    string
+		expected string
+	}{
+		{"plain http", false, "", "http://admin.example.com" + config.Default.OAuth.CallbackPath},
+		{"tls", true, "", "https://admin.example.com" + config.Default.OAuth.CallbackPath},
+		{"behind https proxy", false, "https", "https://admin.example.com" + config.Default.OAuth.CallbackPath},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "http://admin.example.com/admin/api/endpoints/ep/oauth/login", nil)
+		if tt.tls {
+			c.Request.TLS = &tls.ConnectionState{}
+		}
+		if tt.proto != "" {
+			c.Request.Header.Set("X-Forwarded-Proto", tt.proto)
+		}
+		if got := defaultOAuthRedirectURI(c); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
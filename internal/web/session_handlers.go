@@ -0,0 +1,46 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetSessionBindings 获取当前的会话→端点绑定
+func (s *AdminServer) handleGetSessionBindings(c *gin.Context) {
+	affinity := s.endpointManager.GetSessionAffinity()
+	bindings := affinity.List()
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":  affinity.IsEnabled(),
+		"ttl":      s.config.SessionAffinity.TTL,
+		"bindings": bindings,
+		"total":    len(bindings),
+	})
+}
+
+// handleDeleteSessionBinding 删除单个会话绑定，会话的下一个请求重新选择端点
+func (s *AdminServer) handleDeleteSessionBinding(c *gin.Context) {
+	sessionID, err := url.PathUnescape(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session id encoding"})
+		return
+	}
+
+	if !s.endpointManager.GetSessionAffinity().Remove(sessionID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session binding not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session binding cleared successfully"})
+}
+
+// handleClearSessionBindings 清空所有会话绑定
+func (s *AdminServer) handleClearSessionBindings(c *gin.Context) {
+	cleared := s.endpointManager.GetSessionAffinity().Clear()
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session bindings cleared successfully",
+		"cleared": cleared,
+	})
+}
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"claude-code-companion/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetUsage 汇总请求日志中记录的 token 用量和费用
+// 参数：since / until（RFC3339 或相对时长，如 24h、7d；默认最近7天）、endpoint（端点名）、model、session_id、
+// group_by（model、endpoint、day、hour）
+func (s *AdminServer) handleGetUsage(c *gin.Context) {
+	now := time.Now()
+	since, err := parseEventTime(c.DefaultQuery("since", "7d"), now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since: " + err.Error()})
+		return
+	}
+	until := now
+	if untilStr := c.Query("until"); untilStr != "" {
+		if until, err = parseEventTime(untilStr, now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until: " + err.Error()})
+			return
+		}
+	}
+
+	filter := logger.UsageFilter{
+		Since:     since,
+		Until:     until,
+		Model:     c.Query("model"),
+		SessionID: c.Query("session_id"),
+		GroupBy:   c.Query("group_by"),
+	}
+	if filter.GroupBy != "" && !slices.Contains(logger.UsageGroupings, filter.GroupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group_by, supported: " + strings.Join(logger.UsageGroupings, ", ")})
+		return
+	}
+	// 请求日志按端点URL记录，端点名需要转换
+	if name := c.Query("endpoint"); name != "" {
+		ep := s.getEndpointConfigByName(name)
+		if ep == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Endpoint not found"})
+			return
+		}
+		filter.Endpoint = ep.URL
+	}
+
+	totals, groups, err := s.logger.GetUsage(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query usage: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"since":    since,
+		"until":    until,
+		"group_by": filter.GroupBy,
+		"totals":   totals,
+		"groups":   groups,
+	}
+	if filter.GroupBy == "endpoint" {
+		// 分组键是端点URL，附带URL到端点名的映射
+		names := make(map[string][]string)
+		for _, ep := range s.config.Endpoints {
+			names[ep.URL] = append(names[ep.URL], ep.Name)
+		}
+		response["endpoint_names"] = names
+	}
+	c.JSON(http.StatusOK, response)
+}
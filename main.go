@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"html/template"
@@ -14,6 +15,7 @@ import (
 	"claude-code-companion/internal/common/httpclient"
 	"claude-code-companion/internal/config"
 	"claude-code-companion/internal/proxy"
+	"claude-code-companion/internal/tracing"
 	"claude-code-companion/internal/webres"
 )
 
@@ -103,6 +105,12 @@ func main() {
 	if err := initHTTPClientsFromConfig(cfg); err != nil {
 		log.Fatalf("Failed to initialize HTTP clients: %v", err)
 	}
+
+	// Initialize OpenTelemetry tracing (no-op when disabled)
+	shutdownTracing, err := tracing.Setup(cfg.Tracing, Version)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
 	proxyServer, err := proxy.NewServer(cfg, *configFile, Version)
 	if err != nil {
 		log.Fatalf("Failed to create proxy server: %v", err)
@@ -127,7 +135,15 @@ func main() {
 	<-quit
 	fmt.Println("\nShutting down servers...")
 	
-	// Graceful shutdown: close logger and database connections
+	// Graceful shutdown: persist pending usage rollups, export pending spans, then close logger and database connections
+	if endpointManager := proxyServer.GetEndpointManager(); endpointManager != nil {
+		endpointManager.FlushAnalytics()
+	}
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Printf("Error flushing traces: %v", err)
+	}
+	cancelTracing()
 	if logger := proxyServer.GetLogger(); logger != nil {
 		if err := logger.Close(); err != nil {
 			log.Printf("Error closing logger: %v", err)
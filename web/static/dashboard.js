@@ -12,8 +12,362 @@ document.addEventListener('DOMContentLoaded', function() {
         }
     });
     
+    loadSessionBindings();
+    restoreAnalyticsFilters();
+    loadAnalytics();
+    restoreEndpointEventFilters();
+    loadEndpointEvents();
+    
     // Auto-refresh every 30 seconds
     setInterval(function() {
         location.reload();
     }, 30000);
-});
\ No newline at end of file
+});
+
+// Session affinity bindings
+async function loadSessionBindings() {
+    try {
+        const response = await apiRequest('/admin/api/sessions');
+        if (!response.ok) {
+            return;
+        }
+        const data = await response.json();
+        renderSessionBindings(data);
+    } catch (error) {
+        console.error('Failed to load session bindings:', error);
+    }
+}
+
+function renderSessionBindings(data) {
+    const tbody = document.getElementById('session-bindings-body');
+    const bindings = data.bindings || [];
+    
+    document.getElementById('session-bindings-count').textContent = bindings.length;
+    document.getElementById('session-affinity-disabled').style.display = data.enabled ? 'none' : 'block';
+    document.getElementById('clear-session-bindings').disabled = bindings.length === 0;
+    
+    if (bindings.length === 0) {
+        tbody.innerHTML = `<tr><td colspan="6" class="text-center text-muted">${T('no_session_bindings', '暂无会话绑定')}</td></tr>`;
+        return;
+    }
+    
+    tbody.innerHTML = bindings.map(function(binding) {
+        return `<tr>
+            <td><code title="${escapeHtml(binding.session_id)}">${escapeHtml(binding.session_id.substring(0, 8))}…</code></td>
+            <td>${escapeHtml(binding.endpoint_name)}</td>
+            <td>${binding.requests}</td>
+            <td>${new Date(binding.last_used).toLocaleString()}</td>
+            <td>${new Date(binding.expires_at).toLocaleString()}</td>
+            <td><button class="btn btn-sm btn-outline-secondary" onclick="clearSessionBinding('${encodeURIComponent(binding.session_id)}')">${T('clear_binding', '解除绑定')}</button></td>
+        </tr>`;
+    }).join('');
+}
+
+async function clearSessionBinding(encodedSessionId) {
+    try {
+        const response = await apiRequest(`/admin/api/sessions/${encodedSessionId}`, { method: 'DELETE' });
+        const data = await response.json();
+        if (!response.ok) {
+            showAlert(data.error || T('failed_to_clear_session_binding', '解除会话绑定失败'), 'danger');
+            return;
+        }
+        loadSessionBindings();
+    } catch (error) {
+        showAlert(T('failed_to_clear_session_binding', '解除会话绑定失败'), 'danger');
+    }
+}
+
+async function clearAllSessionBindings() {
+    if (!confirm(T('confirm_clear_session_bindings', '确定要清除所有会话绑定吗？会话的下一个请求将重新选择端点。'))) {
+        return;
+    }
+    try {
+        const response = await apiRequest('/admin/api/sessions', { method: 'DELETE' });
+        const data = await response.json();
+        if (!response.ok) {
+            showAlert(data.error || T('failed_to_clear_session_binding', '解除会话绑定失败'), 'danger');
+            return;
+        }
+        showAlert(T('session_bindings_cleared', '会话绑定已清除'), 'success');
+        loadSessionBindings();
+    } catch (error) {
+        showAlert(T('failed_to_clear_session_binding', '解除会话绑定失败'), 'danger');
+    }
+}
+
+// Usage analytics
+const ANALYTICS_COLORS = ['#0d6efd', '#198754', '#dc3545', '#fd7e14', '#6f42c1', '#20c997', '#d63384', '#6c757d', '#ffc107', '#0dcaf0'];
+let analyticsData = null;
+
+function restoreAnalyticsFilters() {
+    const saved = JSON.parse(sessionStorage.getItem('analyticsFilters') || '{}');
+    ['range', 'dimension', 'metric'].forEach(function(name) {
+        const select = document.getElementById(`analytics-${name}`);
+        if (saved[name] !== undefined && select.querySelector(`option[value="${CSS.escape(saved[name])}"]`)) {
+            select.value = saved[name];
+        }
+    });
+}
+
+function saveAnalyticsFilters() {
+    const filters = {};
+    ['range', 'dimension', 'metric'].forEach(function(name) {
+        filters[name] = document.getElementById(`analytics-${name}`).value;
+    });
+    sessionStorage.setItem('analyticsFilters', JSON.stringify(filters));
+}
+
+async function loadAnalytics() {
+    saveAnalyticsFilters();
+    const range = document.getElementById('analytics-range').value.split(':');
+    const params = new URLSearchParams({
+        granularity: range[0],
+        since: range[1],
+        dimension: document.getElementById('analytics-dimension').value,
+        limit: ANALYTICS_COLORS.length
+    });
+    
+    try {
+        const response = await apiRequest(`/admin/api/analytics?${params}`);
+        if (!response.ok) {
+            return;
+        }
+        analyticsData = await response.json();
+        renderAnalytics();
+    } catch (error) {
+        console.error('Failed to load analytics:', error);
+    }
+}
+
+function analyticsMetricValue(point, metric) {
+    if (metric === 'tokens') {
+        return point.input_tokens + point.output_tokens + point.cache_creation_input_tokens + point.cache_read_input_tokens;
+    }
+    return point[metric] || 0;
+}
+
+function formatAnalyticsValue(value, metric) {
+    if (metric === 'cost') return '$' + value.toFixed(value < 1 ? 4 : 2);
+    if (metric === 'p95_latency_ms') return formatLatency(value);
+    if (value >= 1000000) return (value / 1000000).toFixed(1) + 'M';
+    if (value >= 1000) return (value / 1000).toFixed(1) + 'k';
+    return String(Math.round(value));
+}
+
+function formatLatency(ms) {
+    return ms >= 1000 ? (ms / 1000).toFixed(1) + 's' : ms + 'ms';
+}
+
+function analyticsKeyLabel(key) {
+    return key === '' ? T('analytics_dimension_total', '全部请求') : key;
+}
+
+function renderAnalytics() {
+    saveAnalyticsFilters();
+    if (!analyticsData) {
+        return;
+    }
+    document.getElementById('analytics-disabled').style.display = analyticsData.enabled ? 'none' : 'block';
+    const metric = document.getElementById('analytics-metric').value;
+    const series = analyticsData.series || [];
+    renderAnalyticsChart(analyticsData.buckets || [], series, metric, analyticsData.granularity);
+    renderAnalyticsTable(series);
+}
+
+// Line chart drawn as inline SVG, one polyline per series
+function renderAnalyticsChart(buckets, series, metric, granularity) {
+    const chart = document.getElementById('analytics-chart');
+    const legend = document.getElementById('analytics-legend');
+    if (series.length === 0 || buckets.length === 0) {
+        chart.innerHTML = '';
+        legend.innerHTML = '';
+        return;
+    }
+    
+    const width = 800, height = 220, left = 60, right = 10, top = 10, bottom = 30;
+    const plotWidth = width - left - right, plotHeight = height - top - bottom;
+    let maxValue = 0;
+    series.forEach(function(s) {
+        s.points.forEach(function(point) { maxValue = Math.max(maxValue, analyticsMetricValue(point, metric)); });
+    });
+    maxValue = maxValue || 1;
+    
+    const x = function(i) { return left + (buckets.length === 1 ? plotWidth / 2 : i * plotWidth / (buckets.length - 1)); };
+    const y = function(value) { return top + plotHeight - value / maxValue * plotHeight; };
+    const formatBucket = function(bucket) {
+        const date = new Date(bucket);
+        return granularity === 'day' ? date.toLocaleDateString() : date.toLocaleString([], { month: 'numeric', day: 'numeric', hour: '2-digit', minute: '2-digit' });
+    };
+    
+    let svg = `<svg viewBox="0 0 ${width} ${height}" width="100%" preserveAspectRatio="none" style="max-height: ${height}px;">`;
+    [0, 0.5, 1].forEach(function(fraction) {
+        const value = maxValue * fraction;
+        svg += `<line x1="${left}" x2="${width - right}" y1="${y(value)}" y2="${y(value)}" stroke="#dee2e6" stroke-width="1"/>`;
+        svg += `<text x="${left - 5}" y="${y(value) + 4}" font-size="11" text-anchor="end" fill="#6c757d">${escapeHtml(formatAnalyticsValue(value, metric))}</text>`;
+    });
+    [0, Math.floor((buckets.length - 1) / 2), buckets.length - 1].forEach(function(i) {
+        svg += `<text x="${x(i)}" y="${height - 8}" font-size="11" text-anchor="middle" fill="#6c757d">${escapeHtml(formatBucket(buckets[i]))}</text>`;
+    });
+    series.forEach(function(s, index) {
+        const color = ANALYTICS_COLORS[index % ANALYTICS_COLORS.length];
+        const points = s.points.map(function(point, i) { return `${x(i).toFixed(1)},${y(analyticsMetricValue(point, metric)).toFixed(1)}`; }).join(' ');
+        svg += `<polyline fill="none" stroke="${color}" stroke-width="2" points="${points}"><title>${escapeHtml(analyticsKeyLabel(s.key))}</title></polyline>`;
+    });
+    svg += '</svg>';
+    chart.innerHTML = svg;
+    
+    legend.innerHTML = series.map(function(s, index) {
+        const color = ANALYTICS_COLORS[index % ANALYTICS_COLORS.length];
+        return `<span class="me-3 text-nowrap"><span style="display: inline-block; width: 10px; height: 10px; background: ${color};"></span> ${escapeHtml(analyticsKeyLabel(s.key))}</span>`;
+    }).join('');
+}
+
+function renderAnalyticsTable(series) {
+    const tbody = document.getElementById('analytics-body');
+    if (series.length === 0) {
+        tbody.innerHTML = `<tr><td colspan="7" class="text-center text-muted">${T('no_analytics_data', '该时间范围内没有请求')}</td></tr>`;
+        return;
+    }
+    
+    tbody.innerHTML = series.map(function(s) {
+        const totals = s.totals;
+        return `<tr>
+            <td><code>${escapeHtml(analyticsKeyLabel(s.key))}</code></td>
+            <td>${totals.requests}</td>
+            <td class="${totals.error_rate > 0.05 ? 'text-danger' : ''}">${(totals.error_rate * 100).toFixed(1)}%</td>
+            <td>${formatLatency(totals.avg_latency_ms)} / ${formatLatency(totals.p50_latency_ms)}</td>
+            <td>${formatLatency(totals.p95_latency_ms)} / ${formatLatency(totals.p99_latency_ms)}</td>
+            <td>${formatAnalyticsValue(totals.input_tokens + totals.cache_creation_input_tokens + totals.cache_read_input_tokens, 'tokens')} / ${formatAnalyticsValue(totals.output_tokens, 'tokens')}</td>
+            <td>${formatAnalyticsValue(totals.cost, 'cost')}</td>
+        </tr>`;
+    }).join('');
+}
+
+// Endpoint event timeline
+const ENDPOINT_EVENTS_PAGE_SIZE = 50;
+let endpointEventsOffset = 0;
+
+// The dashboard reloads every 30 seconds, keep the selected filters across reloads
+function restoreEndpointEventFilters() {
+    const saved = JSON.parse(sessionStorage.getItem('endpointEventFilters') || '{}');
+    ['endpoint', 'type', 'since'].forEach(function(name) {
+        const select = document.getElementById(`event-filter-${name}`);
+        if (saved[name] !== undefined && select.querySelector(`option[value="${CSS.escape(saved[name])}"]`)) {
+            select.value = saved[name];
+        }
+    });
+}
+
+async function loadEndpointEvents(append) {
+    const filters = {
+        endpoint: document.getElementById('event-filter-endpoint').value,
+        type: document.getElementById('event-filter-type').value,
+        since: document.getElementById('event-filter-since').value
+    };
+    sessionStorage.setItem('endpointEventFilters', JSON.stringify(filters));
+    
+    endpointEventsOffset = append ? endpointEventsOffset + ENDPOINT_EVENTS_PAGE_SIZE : 0;
+    const params = new URLSearchParams({ since: filters.since, limit: ENDPOINT_EVENTS_PAGE_SIZE, offset: endpointEventsOffset });
+    if (filters.type) {
+        params.set('type', filters.type);
+    }
+    if (filters.endpoint.startsWith('endpoint:')) {
+        params.set('endpoint', filters.endpoint.substring('endpoint:'.length));
+    } else if (filters.endpoint.startsWith('tag:')) {
+        params.set('tag', filters.endpoint.substring('tag:'.length));
+    }
+    
+    try {
+        const response = await apiRequest(`/admin/api/endpoints/events?${params}`);
+        if (!response.ok) {
+            return;
+        }
+        const data = await response.json();
+        renderOutageSummary(data.summary || []);
+        renderEndpointEvents(data.events || [], data.total, append);
+    } catch (error) {
+        console.error('Failed to load endpoint events:', error);
+    }
+}
+
+function formatDowntime(seconds) {
+    if (seconds < 60) return seconds + 's';
+    if (seconds < 3600) return Math.round(seconds / 60) + 'm';
+    return (seconds / 3600).toFixed(1) + 'h';
+}
+
+function renderOutageSummary(summary) {
+    const tbody = document.getElementById('outage-summary-body');
+    const rows = summary.filter(function(item) { return item.outages > 0 || item.currently_down; });
+    if (rows.length === 0) {
+        tbody.innerHTML = `<tr><td colspan="4" class="text-center text-muted">${T('no_outages', '该时间范围内没有失效')}</td></tr>`;
+        return;
+    }
+    
+    tbody.innerHTML = rows.map(function(item) {
+        const reasons = Object.entries(item.causes || {})
+            .sort(function(a, b) { return b[1] - a[1]; })
+            .map(function(entry) { return `<div>${escapeHtml(outageCauseLabel(entry[0]))} <span class="text-muted">×${entry[1]}</span></div>`; })
+            .join('');
+        const down = item.currently_down ? ` <span class="badge bg-danger">${T('currently_down', '当前失效')}</span>` : '';
+        return `<tr>
+            <td>${escapeHtml(item.endpoint_name)}${down}</td>
+            <td>${item.outages}</td>
+            <td>${formatDowntime(item.downtime_seconds)}</td>
+            <td class="small">${reasons}</td>
+        </tr>`;
+    }).join('');
+}
+
+function outageCauseLabel(cause) {
+    const labels = {
+        failures: T('outage_cause_failures', '连续失败'),
+        circuit: T('outage_cause_circuit', '熔断'),
+        budget: T('outage_cause_budget', '预算超限'),
+        rate_limit: T('outage_cause_rate_limit', '速率限制'),
+        manual: T('outage_cause_manual', '手动')
+    };
+    return labels[cause] || cause || '-';
+}
+
+function endpointEventBadge(event) {
+    const labels = {
+        inactive: ['bg-danger', T('event_type_inactive', '失效')],
+        active: ['bg-success', T('event_type_active', '恢复')],
+        health_check: [event.success ? 'bg-info' : 'bg-warning text-dark', T('event_type_health_check', '健康检查')],
+        rate_limit: [event.success ? 'bg-info' : 'bg-warning text-dark', T('event_type_rate_limit', '速率限制')],
+        oauth_refresh: [event.success ? 'bg-info' : 'bg-danger', T('event_type_oauth_refresh', 'OAuth 刷新')]
+    };
+    const label = labels[event.event_type] || ['bg-secondary', event.event_type];
+    return `<span class="badge ${label[0]}">${escapeHtml(label[1])}</span>`;
+}
+
+function renderEndpointEvents(events, total, append) {
+    const tbody = document.getElementById('endpoint-events-body');
+    document.getElementById('endpoint-events-count').textContent = total;
+    document.getElementById('load-more-events').style.display = endpointEventsOffset + events.length < total ? 'inline-block' : 'none';
+    
+    if (!append && events.length === 0) {
+        tbody.innerHTML = `<tr><td colspan="4" class="text-center text-muted">${T('no_endpoint_events', '暂无事件')}</td></tr>`;
+        return;
+    }
+    
+    const rows = events.map(function(event) {
+        const details = [];
+        if (event.status_code) details.push(`HTTP ${event.status_code}`);
+        if (event.latency_ms) details.push(`${event.latency_ms}ms`);
+        if (event.request_id) details.push(`<code>${escapeHtml(event.request_id)}</code>`);
+        return `<tr>
+            <td class="text-nowrap">${new Date(event.created_at).toLocaleString()}</td>
+            <td>${escapeHtml(event.endpoint_name)}</td>
+            <td>${endpointEventBadge(event)}</td>
+            <td class="small">${escapeHtml(event.reason || '')} ${details.length ? `<span class="text-muted">(${details.join(', ')})</span>` : ''}</td>
+        </tr>`;
+    }).join('');
+    
+    if (append) {
+        tbody.insertAdjacentHTML('beforeend', rows);
+    } else {
+        tbody.innerHTML = rows;
+    }
+}
@@ -52,7 +52,7 @@ function addRewriteRule(sourcePattern = '', targetModel = '') {
                    placeholder="${wildcardPatternText}" value="${escapeHtml(sourcePattern)}" readonly>
         </div>
         <div class="col-5">
-            <input type="text" class="form-control target-model-input" 
+            <input type="text" class="form-control target-model-input" list="endpoint-model-options"
                    placeholder="${targetModelPlaceholderText}" value="${escapeHtml(targetModel)}" 
                    oninput="onRewriteRuleTargetChange()">
         </div>
@@ -259,16 +259,25 @@ function loadModelRewriteConfig(config) {
 }
 
 // Save model rewrite configuration
-function saveModelRewriteConfig(endpointName, config) {
+function saveModelRewriteConfig(endpointName, config, force = false) {
     if (!config) return Promise.resolve();
 
-    return apiRequest(`/admin/api/endpoints/${encodeURIComponent(endpointName)}/model-rewrite`, {
+    const query = force ? '?force=true' : '';
+    return apiRequest(`/admin/api/endpoints/${encodeURIComponent(endpointName)}/model-rewrite${query}`, {
         method: 'PUT',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(config)
     })
     .then(response => response.json())
     .then(data => {
+        // Target models missing from the upstream model list: let the user decide whether to keep them
+        if (data.unknown_models && !force) {
+            const message = T('confirm_unknown_rewrite_models', '以下目标模型不在端点的上游模型列表中：{0}，仍然保存吗？')
+                .replace('{0}', data.unknown_models.join(', '));
+            if (confirm(message)) {
+                return saveModelRewriteConfig(endpointName, config, true);
+            }
+        }
         if (data.error) {
             throw new Error(data.error);
         }
@@ -276,6 +285,25 @@ function saveModelRewriteConfig(endpointName, config) {
     });
 }
 
+// Fill the model autocomplete list with the endpoint's upstream models
+function loadEndpointModelOptions(endpointName) {
+    const datalist = document.getElementById('endpoint-model-options');
+    datalist.innerHTML = '';
+    if (!endpointName) return;
+
+    apiRequest(`/admin/api/endpoints/${encodeURIComponent(endpointName)}/models`)
+        .then(response => response.json())
+        .then(data => {
+            if (!data.catalog || !data.catalog.models) return;
+            data.catalog.models.forEach(model => {
+                const option = document.createElement('option');
+                option.value = model;
+                datalist.appendChild(option);
+            });
+        })
+        .catch(error => console.error('Failed to load endpoint models:', error));
+}
+
 
 // ===== Default Model Functions =====
 
@@ -58,7 +58,10 @@ function loadOAuthConfig(oauthConfig) {
         document.getElementById('oauth-token-url').value = '';
         document.getElementById('oauth-client-id').value = '';
         document.getElementById('oauth-scopes').value = '';
+        document.getElementById('oauth-authorize-url').value = '';
+        document.getElementById('oauth-redirect-uri').value = '';
         document.getElementById('oauth-auto-refresh').checked = true;
+        updateOAuthRequiredFields();
         return;
     }
     
@@ -68,7 +71,10 @@ function loadOAuthConfig(oauthConfig) {
     document.getElementById('oauth-expires-at').value = oauthConfig.expires_at || '';
     document.getElementById('oauth-token-url').value = oauthConfig.token_url || '';
     document.getElementById('oauth-client-id').value = oauthConfig.client_id || '';
+    document.getElementById('oauth-authorize-url').value = oauthConfig.authorize_url || '';
+    document.getElementById('oauth-redirect-uri').value = oauthConfig.redirect_uri || '';
     document.getElementById('oauth-auto-refresh').checked = oauthConfig.auto_refresh !== false;
+    updateOAuthRequiredFields();
     
     // Load scopes
     if (oauthConfig.scopes && Array.isArray(oauthConfig.scopes)) {
@@ -96,11 +102,20 @@ function togglePathPrefixField() {
     const pathPrefixGroup = document.getElementById('path-prefix-group');
     const pathPrefixInput = document.getElementById('endpoint-path-prefix');
     
-    if (endpointType === 'openai') {
+    if (endpointType === 'openai' || endpointType === 'openai_responses') {
         StyleUtils.show(pathPrefixGroup);
         pathPrefixInput.required = true;
-        if (!pathPrefixInput.value) {
-            pathPrefixInput.value = '/v1/chat/completions'; // Default value
+        const defaultPath = endpointType === 'openai_responses' ? '/v1/responses' : '/v1/chat/completions';
+        const otherDefaultPath = endpointType === 'openai_responses' ? '/v1/chat/completions' : '/v1/responses';
+        if (!pathPrefixInput.value || pathPrefixInput.value === otherDefaultPath) {
+            pathPrefixInput.value = defaultPath; // Default value
+        }
+    } else if (endpointType === 'gemini') {
+        // Gemini: model and method are appended by the proxy, prefix is optional
+        StyleUtils.show(pathPrefixGroup);
+        pathPrefixInput.required = false;
+        if (!pathPrefixInput.value || pathPrefixInput.value.startsWith('/v1/')) {
+            pathPrefixInput.value = '/v1beta/models';
         }
     } else {
         StyleUtils.hide(pathPrefixGroup);
@@ -117,7 +132,7 @@ function toggleAuthTypeForEndpointType() {
     // Clear existing options
     authTypeSelect.innerHTML = '';
     
-    if (endpointType === 'openai') {
+    if (endpointType === 'openai' || endpointType === 'openai_responses') {
         // OpenAI compatible endpoints only support authtoken and oauth
         authTypeSelect.innerHTML = `
             <option value="auth_token">Auth Token (Authorization Bearer)</option>
@@ -130,6 +145,19 @@ function toggleAuthTypeForEndpointType() {
         } else {
             authTypeSelect.value = 'auth_token'; // Default to auth_token
         }
+    } else if (endpointType === 'gemini') {
+        // Gemini API keys are sent via x-goog-api-key
+        authTypeSelect.innerHTML = `
+            <option value="api_key">API Key (x-goog-api-key)</option>
+            <option value="auth_token">Auth Token (Authorization Bearer)</option>
+            <option value="oauth">OAuth 2.0</option>
+        `;
+        
+        if (currentValue === 'auth_token' || currentValue === 'oauth') {
+            authTypeSelect.value = currentValue;
+        } else {
+            authTypeSelect.value = 'api_key'; // Default to api_key
+        }
     } else {
         // Anthropic endpoints support all auth types
         authTypeSelect.innerHTML = `
@@ -164,11 +192,9 @@ function onAuthTypeChange() {
         StyleUtils.show(oauthConfigGroup);
         authValueInput.required = false;
         
-        // OAuth 必填字段设置为必填
-        document.getElementById('oauth-access-token').required = true;
-        document.getElementById('oauth-refresh-token').required = true;
-        document.getElementById('oauth-expires-at').required = true;
+        // OAuth 必填字段设置为必填（设置了 Authorize URL 时令牌可以通过登录获取）
         document.getElementById('oauth-token-url').required = true;
+        updateOAuthRequiredFields();
     } else {
         // 显示认证值输入，隐藏 OAuth 配置
         StyleUtils.show(authValueGroup);
@@ -183,6 +209,58 @@ function onAuthTypeChange() {
     }
 }
 
+// 设置了 Authorize URL 时，令牌和过期时间可以留空，保存后通过 OAuth 登录获取
+function updateOAuthRequiredFields() {
+    const isOAuth = document.getElementById('endpoint-auth-type').value === 'oauth';
+    const canLogin = document.getElementById('oauth-authorize-url').value.trim() !== '';
+    const tokensRequired = isOAuth && !canLogin;
+    document.getElementById('oauth-access-token').required = tokensRequired;
+    document.getElementById('oauth-refresh-token').required = tokensRequired;
+    document.getElementById('oauth-expires-at').required = tokensRequired;
+}
+
+// 发起 OAuth 授权码 + PKCE 登录：使用已保存的端点配置，在新窗口中打开授权页面
+function startOAuthLogin() {
+    if (!editingEndpointName) {
+        showAlert(T('oauth_login_save_first', '请先保存端点，再通过 OAuth 登录'), 'warning');
+        return;
+    }
+
+    apiRequest(`/admin/api/endpoints/${encodeURIComponent(editingEndpointName)}/oauth/authorize`, {
+        method: 'POST'
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            showAlert(data.error, 'danger');
+            return;
+        }
+        const popup = window.open(data.authorization_url, 'oauth-login', 'width=600,height=750');
+        if (!popup) {
+            // 弹出窗口被拦截时在当前窗口打开，回调页面提供返回链接
+            window.location.href = data.authorization_url;
+        }
+    })
+    .catch(error => {
+        console.error('Failed to start OAuth login:', error);
+        showAlert(T('oauth_login_start_failed', '发起 OAuth 登录失败') + ': ' + error.message, 'danger');
+    });
+}
+
+// 回调页面通知登录结果
+window.addEventListener('message', function(e) {
+    if (e.origin !== window.location.origin || !e.data || e.data.type !== 'oauth-login') {
+        return;
+    }
+    if (e.data.success) {
+        endpointModal.hide();
+        showAlert(T('oauth_login_succeeded', 'OAuth 登录成功') + ': ' + e.data.endpoint, 'success');
+        loadEndpoints();
+    } else {
+        showAlert(T('oauth_login_failed', 'OAuth 登录失败'), 'danger');
+    }
+});
+
 // Add event delegation for endpoint modal
 document.addEventListener('click', function(e) {
     const action = e.target.dataset.action || e.target.closest('[data-action]')?.dataset.action;
@@ -203,6 +281,15 @@ document.addEventListener('click', function(e) {
         case 'save-endpoint':
             saveEndpoint();
             break;
+        case 'start-oauth-login':
+            startOAuthLogin();
+            break;
+    }
+});
+
+document.addEventListener('input', function(e) {
+    if (e.target.id === 'oauth-authorize-url') {
+        updateOAuthRequiredFields();
     }
 });
 
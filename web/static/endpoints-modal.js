@@ -25,6 +25,7 @@ function showAddEndpointModal() {
     
     // Clear default model
     document.getElementById('endpoint-default-model').value = '';
+    loadEndpointModelOptions(null);
     
     
     // Clear header override configuration
@@ -101,6 +102,9 @@ function showEditEndpointModal(endpointName) {
     
     // Load default model after loading model rewrite config
     loadDefaultModel(endpoint.model_rewrite);
+
+    // Load upstream models for autocomplete
+    loadEndpointModelOptions(endpoint.name);
     
     
     // Load header override configuration
@@ -178,11 +182,16 @@ function saveEndpoint() {
             token_url: document.getElementById('oauth-token-url').value,
             client_id: document.getElementById('oauth-client-id').value || '',
             scopes: scopes,
-            auto_refresh: document.getElementById('oauth-auto-refresh').checked
+            auto_refresh: document.getElementById('oauth-auto-refresh').checked,
+            authorize_url: document.getElementById('oauth-authorize-url').value.trim(),
+            redirect_uri: document.getElementById('oauth-redirect-uri').value.trim()
         };
+        if (isNaN(oauthConfig.expires_at)) oauthConfig.expires_at = 0;
         
         // Remove empty optional fields
         if (!oauthConfig.client_id) delete oauthConfig.client_id;
+        if (!oauthConfig.authorize_url) delete oauthConfig.authorize_url;
+        if (!oauthConfig.redirect_uri) delete oauthConfig.redirect_uri;
         if (oauthConfig.scopes.length === 0) delete oauthConfig.scopes;
     } else {
         // Get regular auth value
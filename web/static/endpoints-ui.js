@@ -56,9 +56,16 @@ function rebuildTable(endpoints) {
             : `<span class="badge bg-secondary"><i class="fas fa-toggle-off"></i> ${T('disabled', '已禁用')}</span>`;
         
         // Build endpoint type badge
-        const endpointTypeBadge = endpoint.endpoint_type === 'openai' 
-            ? '<span class="badge bg-warning">openai</span>'
-            : '<span class="badge bg-primary">anthropic</span>';
+        let endpointTypeBadge;
+        if (endpoint.endpoint_type === 'openai') {
+            endpointTypeBadge = '<span class="badge bg-warning">openai</span>';
+        } else if (endpoint.endpoint_type === 'openai_responses') {
+            endpointTypeBadge = '<span class="badge bg-warning">openai_responses</span>';
+        } else if (endpoint.endpoint_type === 'gemini') {
+            endpointTypeBadge = '<span class="badge bg-info">gemini</span>';
+        } else {
+            endpointTypeBadge = '<span class="badge bg-primary">anthropic</span>';
+        }
         
         // Build URL display: only show domain, full URL in title, truncate domain if over 25 chars
         const urlFormatted = formatUrlDisplay(endpoint.url);
@@ -66,8 +73,8 @@ function rebuildTable(endpoints) {
         
         // Build path display: truncate if over 10 characters
         let pathDisplay;
-        if (endpoint.endpoint_type === 'openai') {
-            const fullPath = endpoint.path_prefix || '';
+        if (endpoint.endpoint_type === 'openai' || endpoint.endpoint_type === 'openai_responses' || endpoint.endpoint_type === 'gemini') {
+            const fullPath = endpoint.path_prefix || (endpoint.endpoint_type === 'gemini' ? '/v1beta/models' : '');
             const truncatedPath = truncatePath(fullPath, 10);
             pathDisplay = `<code class="path-display" title="${fullPath}">${truncatedPath}</code>`;
         } else {